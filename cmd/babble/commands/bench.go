@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/bench"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchTargets     string
+	benchRate        int
+	benchSize        int
+	benchDuration    time.Duration
+	benchConcurrency int
+	benchTimeout     time.Duration
+	benchToken       string
+)
+
+// NewBenchCmd produces a BenchCmd which submits synthetic transactions to a
+// network and reports its performance.
+func NewBenchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure the throughput and commit latency of a network",
+		Long: `Measure the throughput and commit latency of a network
+
+Random transactions are submitted to the HTTP services of the targets, in turn,
+at a fixed rate, and each transaction waits to be committed in a Block. The
+report gives the number of transactions committed per second, the percentiles
+of the commit latency, and the resources used by every node during the run,
+from its Prometheus metrics.
+
+The transactions are committed to the App of the nodes, so the command should
+only be run against test networks, like the ones produced by babble testnet.`,
+		Args: cobra.NoArgs,
+		RunE: runBench,
+	}
+
+	defaults := bench.DefaultConfig()
+
+	cmd.Flags().StringVar(&benchTargets, "targets", strings.Join(defaults.Targets, ","), "Comma-separated IP:Port of the HTTP services of the nodes")
+	cmd.Flags().IntVar(&benchRate, "rate", defaults.Rate, "Transactions submitted per second")
+	cmd.Flags().IntVar(&benchSize, "size", defaults.Size, "Size of a transaction in bytes")
+	cmd.Flags().DurationVar(&benchDuration, "duration", defaults.Duration, "Time during which transactions are submitted")
+	cmd.Flags().IntVar(&benchConcurrency, "concurrency", defaults.Concurrency, "Maximum number of transactions waiting to be committed")
+	cmd.Flags().DurationVar(&benchTimeout, "timeout", defaults.Timeout, "Time that a transaction waits to be committed (max 30s)")
+	cmd.Flags().StringVar(&benchToken, "token", "", "Token sent in the Authorization header")
+
+	return cmd
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	conf := bench.Config{
+		Rate:        benchRate,
+		Size:        benchSize,
+		Duration:    benchDuration,
+		Concurrency: benchConcurrency,
+		Timeout:     benchTimeout,
+		Token:       benchToken,
+	}
+
+	for _, t := range strings.Split(benchTargets, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			conf.Targets = append(conf.Targets, t)
+		}
+	}
+
+	fmt.Printf("Submitting %d transactions/s of %d bytes to %d nodes for %s\n",
+		conf.Rate, conf.Size, len(conf.Targets), conf.Duration)
+
+	report, err := bench.Run(conf)
+	if err != nil {
+		return err
+	}
+
+	printBenchReport(report)
+
+	if report.Committed == 0 {
+		return fmt.Errorf("No transactions committed")
+	}
+
+	return nil
+}
+
+func printBenchReport(r *bench.Report) {
+	fmt.Printf("\nElapsed: %s\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Printf("Submitted: %d\n", r.Submitted)
+	fmt.Printf("Committed: %d\n", r.Committed)
+	fmt.Printf("Timed out: %d\n", r.TimedOut)
+	fmt.Printf("Rejected: %d\n", r.Rejected)
+	fmt.Printf("Failed: %d\n", r.Failed)
+	fmt.Printf("Dropped: %d\n", r.Dropped)
+	fmt.Printf("Throughput: %.2f tx/s\n", r.Throughput)
+
+	l := r.Latency
+	fmt.Printf("\nCommit latency:\n")
+	fmt.Printf("  min %s, mean %s, max %s\n", ms(l.Min), ms(l.Mean), ms(l.Max))
+	fmt.Printf("  p50 %s, p90 %s, p99 %s\n", ms(l.P50), ms(l.P90), ms(l.P99))
+
+	fmt.Printf("\nNodes:\n")
+	for _, n := range r.Nodes {
+		if n.Error != "" {
+			fmt.Printf("  %s: no metrics (%s)\n", n.Target, n.Error)
+			continue
+		}
+		fmt.Printf("  %s: cpu %s (%.1f%%), memory %.1f MB, %d goroutines, %d blocks\n",
+			n.Target, ms(n.CPU), n.CPUPercent, float64(n.Memory)/(1<<20), n.Goroutines, n.Blocks)
+	}
+
+	if len(r.Errors) > 0 {
+		errors := make([]string, 0, len(r.Errors))
+		for e := range r.Errors {
+			errors = append(errors, e)
+		}
+		sort.Strings(errors)
+
+		fmt.Printf("\nErrors:\n")
+		for _, e := range errors {
+			fmt.Printf("  %d x %s\n", r.Errors[e], e)
+		}
+	}
+}
+
+func ms(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -9,6 +9,8 @@ type CLIConfig struct {
 	Babble     config.Config `mapstructure:",squash"`
 	ProxyAddr  string        `mapstructure:"proxy-listen"`
 	ClientAddr string        `mapstructure:"client-connect"`
+	ABCIAddr   string        `mapstructure:"abci-connect"`
+	ABCIChain  string        `mapstructure:"abci-chain-id"`
 }
 
 // NewDefaultCLIConfig creates a CLIConfig with default values
@@ -17,5 +19,6 @@ func NewDefaultCLIConfig() *CLIConfig {
 		Babble:     *config.NewDefaultConfig(),
 		ProxyAddr:  "127.0.0.1:1338",
 		ClientAddr: "127.0.0.1:1339",
+		ABCIChain:  "babble",
 	}
 }
@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mosaicnetworks/babble/src/babble"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/genesis"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/spf13/cobra"
+)
+
+// configReport collects the problems found by config check. Errors would stop
+// the node from starting, or from working, whereas warnings point at
+// configurations that are valid but often unintended.
+type configReport struct {
+	errors   []string
+	warnings []string
+}
+
+func (r *configReport) errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *configReport) warnf(format string, args ...interface{}) {
+	r.warnings = append(r.warnings, fmt.Sprintf(format, args...))
+}
+
+func newConfigCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "check",
+		Short:   "Check the configuration and the datadir of a node before running it",
+		PreRunE: bindFlagsLoadViper,
+		RunE:    configCheck,
+	}
+
+	// The configuration is read exactly like the run command does
+	AddRunFlags(cmd)
+
+	return cmd
+}
+
+func configCheck(cmd *cobra.Command, args []string) error {
+	c := &_config.Babble
+	r := checkConfig(_config)
+
+	for _, w := range r.warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+	for _, e := range r.errors {
+		fmt.Printf("error: %s\n", e)
+	}
+
+	if len(r.errors) > 0 {
+		return fmt.Errorf("Found %d errors in the configuration", len(r.errors))
+	}
+
+	fmt.Printf("The configuration in %s is valid\n", c.DataDir)
+
+	return nil
+}
+
+// checkConfig runs all the checks of config check on a configuration.
+func checkConfig(cli *CLIConfig) *configReport {
+	c := &cli.Babble
+	r := &configReport{}
+
+	// Check the timeouts before ValidateConfig adjusts them
+	checkTimeouts(c, r)
+
+	if err := babble.NewBabble(c).ValidateConfig(); err != nil {
+		r.errorf("%v", err)
+	}
+
+	key := checkKey(c, r)
+	checkPeers(c, key, r)
+	checkStore(c, r)
+	checkPorts(cli, r)
+
+	return r
+}
+
+// checkKey reads the private key of the node. It returns nil if the key cannot
+// be read.
+func checkKey(c *config.Config, r *configReport) *ecdsa.PrivateKey {
+	key, err := keys.NewSimpleKeyfile(c.Keyfile()).ReadKey()
+	if err != nil {
+		r.errorf("Cannot read the private key %s: %v. Create one with 'babble keygen --priv %s'", c.Keyfile(), err, c.Keyfile())
+		return nil
+	}
+	return key
+}
+
+// checkPeers reads the genesis document, and the peers and genesis peers
+// files, and checks that the key of the node is in the current peer-set. A node
+// that is not in the peer-set is valid, but it will ask to join the network
+// instead of starting with it.
+func checkPeers(c *config.Config, key *ecdsa.PrivateKey, r *configReport) {
+	var peerSet *peers.PeerSet
+
+	if _, err := os.Stat(c.GenesisFile()); err == nil {
+		g, err := genesis.Read(c.GenesisFile())
+		if err != nil {
+			r.errorf("%v. Copy the genesis.json file of the network to %s", err, c.DataDir)
+			return
+		}
+		peerSet = g.PeerSet()
+	}
+
+	peersFile := filepath.Join(c.DataDir, "peers.json")
+
+	if _, err := os.Stat(peersFile); err == nil || peerSet == nil {
+		ps, err := peers.NewJSONPeerSet(c.DataDir, true).PeerSet()
+		if err != nil {
+			r.errorf("Cannot read the peers: %v. Copy the peers.json file of the network to %s", err, c.DataDir)
+			return
+		}
+
+		if ps == nil || ps.Len() == 0 {
+			r.errorf("%s is empty. It should list at least one peer", peersFile)
+			return
+		}
+
+		peerSet = ps
+	}
+
+	genesisFile := filepath.Join(c.DataDir, "peers.genesis.json")
+	if _, err := os.Stat(genesisFile); err == nil {
+		if _, err := os.Stat(c.GenesisFile()); err == nil {
+			r.warnf("%s is ignored because there is a genesis document", genesisFile)
+		} else if _, err := peers.NewJSONPeerSet(c.DataDir, false).PeerSet(); err != nil {
+			r.errorf("Cannot read the genesis peers: %v", err)
+		}
+	}
+
+	if key == nil {
+		return
+	}
+
+	pubKey := keys.PublicKeyHex(&key.PublicKey)
+	if _, ok := peerSet.ByPubKey[strings.ToUpper(pubKey)]; !ok {
+		r.warnf("The public key %s is not in the peer-set. The node will try to join the network through the listed peers",
+			pubKey)
+	}
+}
+
+// checkTimeouts checks that the heartbeats leave time for the syncs to
+// complete before the next gossip round.
+func checkTimeouts(c *config.Config, r *configReport) {
+	if c.HeartbeatTimeout <= 0 {
+		r.errorf("heartbeat (%v) must be positive", c.HeartbeatTimeout)
+	}
+
+	if c.HeartbeatTimeout >= c.TCPTimeout {
+		r.errorf("heartbeat (%v) should be shorter than timeout (%v), otherwise syncs overlap. Decrease --heartbeat or increase --timeout",
+			c.HeartbeatTimeout, c.TCPTimeout)
+	}
+
+	if c.SlowHeartbeatTimeout < c.HeartbeatTimeout {
+		r.warnf("slow-heartbeat (%v) is shorter than heartbeat (%v), and will be raised to heartbeat",
+			c.SlowHeartbeatTimeout, c.HeartbeatTimeout)
+	}
+}
+
+// checkStore checks that the database can be written, or created.
+func checkStore(c *config.Config, r *configReport) {
+	if !c.Store {
+		return
+	}
+
+	dir := c.DatabaseDir
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if c.Bootstrap {
+			r.warnf("bootstrap is set but there is no database in %s. The node will start from a clean state", dir)
+		}
+
+		// The database will be created in the closest existing parent
+		for {
+			if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	} else if !c.Bootstrap {
+		r.warnf("The database in %s will be backed up and replaced, because bootstrap is not set", dir)
+	}
+
+	f, err := ioutil.TempFile(dir, ".babble-check")
+	if err != nil {
+		r.errorf("The database directory %s is not writable: %v. Change --db or its permissions", c.DatabaseDir, err)
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// checkPorts checks that the addresses where the node listens are free.
+func checkPorts(cli *CLIConfig, r *configReport) {
+	c := &cli.Babble
+
+	// flag => address
+	addrs := map[string]string{}
+
+	if !c.WebRTC && !c.MaintenanceMode {
+		addrs["listen"] = c.BindAddr
+	}
+	if !c.NoService {
+		addrs["service-listen"] = c.ServiceAddr
+	}
+	if cli.ABCIAddr == "" {
+		addrs["proxy-listen"] = cli.ProxyAddr
+	}
+
+	for _, flag := range []string{"listen", "service-listen", "proxy-listen"} {
+		addr, ok := addrs[flag]
+		if !ok {
+			continue
+		}
+
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			r.errorf("Cannot listen on %s (%s): %v. Stop the process using it, or change --%s", addr, flag, err, flag)
+			continue
+		}
+		l.Close()
+	}
+}
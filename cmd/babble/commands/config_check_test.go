@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/genesis"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// newCheckedConfig returns a valid configuration, whose datadir contains the
+// private key of the node and a peers.json file which lists it.
+func newCheckedConfig(t *testing.T) *CLIConfig {
+	dir := t.TempDir()
+
+	key, err := keys.GenerateECDSAKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewDefaultCLIConfig()
+	c.Babble = *config.NewTestConfig(t, common.TestLogLevel)
+	c.Babble.SetDataDir(dir)
+	c.Babble.BindAddr = "127.0.0.1:0"
+	c.Babble.NoService = true
+	c.ProxyAddr = "127.0.0.1:0"
+
+	if err := keys.NewSimpleKeyfile(c.Babble.Keyfile()).WriteKey(key); err != nil {
+		t.Fatal(err)
+	}
+
+	peer := peers.NewPeer(keys.PublicKeyHex(&key.PublicKey), "127.0.0.1:1337", "node0")
+	if err := peers.NewJSONPeerSet(dir, true).Write([]*peers.Peer{peer}); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func writeDataFile(t *testing.T, c *CLIConfig, name string, content string) {
+	if err := ioutil.WriteFile(filepath.Join(c.Babble.DataDir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	// An address that is already in use
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Each case breaks one rule, and expects an error or a warning containing
+	// the given text.
+	cases := []struct {
+		name   string
+		breaks func(t *testing.T, c *CLIConfig)
+		err    string
+		warn   string
+	}{
+		{
+			name:   "heartbeat not positive",
+			breaks: func(t *testing.T, c *CLIConfig) { c.Babble.HeartbeatTimeout = 0 },
+			err:    "must be positive",
+		},
+		{
+			name: "heartbeat above timeout",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				c.Babble.HeartbeatTimeout = time.Second
+				c.Babble.TCPTimeout = 500 * time.Millisecond
+			},
+			err: "should be shorter than timeout",
+		},
+		{
+			name: "slow-heartbeat below heartbeat",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				c.Babble.SlowHeartbeatTimeout = c.Babble.HeartbeatTimeout / 2
+			},
+			warn: "will be raised to heartbeat",
+		},
+		{
+			name:   "invalid node option",
+			breaks: func(t *testing.T, c *CLIConfig) { c.Babble.ServiceRateLimit = -1 },
+			err:    "rate limits cannot be negative",
+		},
+		{
+			name: "missing key",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				os.Remove(c.Babble.Keyfile())
+			},
+			err: "Cannot read the private key",
+		},
+		{
+			name: "invalid genesis document",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				writeDataFile(t, c, "genesis.json", "{}")
+			},
+			err: "Copy the genesis.json file",
+		},
+		{
+			name: "invalid peers",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				writeDataFile(t, c, "peers.json", "not json")
+			},
+			err: "Cannot read the peers",
+		},
+		{
+			name: "empty peers",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				writeDataFile(t, c, "peers.json", "[]")
+			},
+			err: "should list at least one peer",
+		},
+		{
+			name: "invalid genesis peers",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				writeDataFile(t, c, "peers.genesis.json", "not json")
+			},
+			err: "Cannot read the genesis peers",
+		},
+		{
+			name: "genesis peers with a genesis document",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				peerSet, err := peers.NewJSONPeerSet(c.Babble.DataDir, true).PeerSet()
+				if err != nil {
+					t.Fatal(err)
+				}
+				g := genesis.NewGenesis("testnet", peerSet, "")
+				if err := g.Write(c.Babble.GenesisFile()); err != nil {
+					t.Fatal(err)
+				}
+				writeDataFile(t, c, "peers.genesis.json", "[]")
+			},
+			warn: "is ignored because there is a genesis document",
+		},
+		{
+			name: "key not in the peer-set",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				other, _ := keys.GenerateECDSAKey()
+				peer := peers.NewPeer(keys.PublicKeyHex(&other.PublicKey), "127.0.0.1:1337", "other")
+				if err := peers.NewJSONPeerSet(c.Babble.DataDir, true).Write([]*peers.Peer{peer}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			warn: "is not in the peer-set",
+		},
+		{
+			name: "database not writable",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				// The closest existing parent of the database is a file
+				writeDataFile(t, c, "file", "")
+				c.Babble.Store = true
+				c.Babble.DatabaseDir = filepath.Join(c.Babble.DataDir, "file", "badger_db")
+			},
+			err: "is not writable",
+		},
+		{
+			name: "bootstrap without database",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				c.Babble.Store = true
+				c.Babble.Bootstrap = true
+			},
+			warn: "there is no database",
+		},
+		{
+			name: "database replaced",
+			breaks: func(t *testing.T, c *CLIConfig) {
+				c.Babble.Store = true
+				if err := os.MkdirAll(c.Babble.DatabaseDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			warn: "will be backed up and replaced",
+		},
+		{
+			name:   "port in use",
+			breaks: func(t *testing.T, c *CLIConfig) { c.Babble.BindAddr = l.Addr().String() },
+			err:    "Cannot listen on",
+		},
+	}
+
+	contains := func(msgs []string, text string) bool {
+		for _, m := range msgs {
+			if strings.Contains(m, text) {
+				return true
+			}
+		}
+		return false
+	}
+
+	valid := checkConfig(newCheckedConfig(t))
+	if len(valid.errors) > 0 || len(valid.warnings) > 0 {
+		t.Fatalf("The valid configuration should pass, not report %v and %v", valid.errors, valid.warnings)
+	}
+
+	for _, tc := range cases {
+		c := newCheckedConfig(t)
+		tc.breaks(t, c)
+
+		r := checkConfig(c)
+
+		if tc.err != "" && !contains(r.errors, tc.err) {
+			t.Fatalf("%s: the errors should contain %q, not %v", tc.name, tc.err, r.errors)
+		}
+		if tc.warn != "" && !contains(r.warnings, tc.warn) {
+			t.Fatalf("%s: the warnings should contain %q, not %v", tc.name, tc.warn, r.warnings)
+		}
+		if tc.warn != "" && len(r.errors) > 0 {
+			t.Fatalf("%s: a warning should not cause errors, not %v", tc.name, r.errors)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/genesis"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envPrefix is the prefix of the environment variables that override the
+// configuration, like BABBLE_SERVICE_LISTEN for service-listen.
+const envPrefix = "babble"
+
+// configFormats are the formats of the config files produced by config init.
+var configFormats = []string{"toml", "yaml", "json"}
+
+// nonConfigKeys are the flags of the run command which cannot be set in a
+// config file, because they are needed to find it. The datadir can be set in
+// a config file given by the config flag.
+var nonConfigKeys = map[string]bool{
+	"config": true,
+}
+
+var (
+	configInitDataDir string
+	configInitFormat  string
+	configInitOut     string
+	configInitForce   bool
+
+	genesisDataDir string
+	genesisChainID string
+	genesisAppHash string
+	genesisForce   bool
+
+	migrateDataDir string
+)
+
+// NewConfigCmd produces a ConfigCmd with subcommands to manage config files
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage config files",
+	}
+
+	cmd.AddCommand(
+		newConfigInitCmd(),
+		newConfigCheckCmd(),
+		newConfigGenesisCmd(),
+		newConfigMigrateCmd(),
+	)
+
+	return cmd
+}
+
+func newConfigInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a config file with the default value of every option",
+		RunE:  configInit,
+	}
+
+	cmd.Flags().StringVar(&configInitDataDir, "datadir", _config.Babble.DataDir, "Top-level directory for configuration and data")
+	cmd.Flags().StringVar(&configInitFormat, "format", "toml", "Format of the config file: toml, yaml or json")
+	cmd.Flags().StringVar(&configInitOut, "out", "", "File where the config will be written. Defaults to [datadir]/babble.[format]")
+	cmd.Flags().BoolVar(&configInitForce, "force", false, "Overwrite an existing config file")
+
+	return cmd
+}
+
+func configInit(cmd *cobra.Command, args []string) error {
+	out := configInitOut
+	if out == "" {
+		out = filepath.Join(configInitDataDir, "babble."+configInitFormat)
+	}
+
+	flags := NewRunCmd().Flags()
+	flags.Set("datadir", configInitDataDir)
+
+	content, err := defaultConfigFile(flags, configInitFormat)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(out); err == nil && !configInitForce {
+		return fmt.Errorf("A config file already exists: %s", out)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(out), 0700); err != nil {
+		return fmt.Errorf("Writing config file: %s", err)
+	}
+
+	if err := ioutil.WriteFile(out, content, 0600); err != nil {
+		return fmt.Errorf("Writing config file: %s", err)
+	}
+
+	fmt.Printf("Your config file has been saved to: %s\n", out)
+
+	return nil
+}
+
+func newConfigGenesisCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "genesis",
+		Short: "Write a genesis document from the genesis peers of a datadir",
+		RunE:  configGenesis,
+	}
+
+	cmd.Flags().StringVar(&genesisDataDir, "datadir", _config.Babble.DataDir, "Top-level directory for configuration and data")
+	cmd.Flags().StringVar(&genesisChainID, "chain-id", "", "Name of the network")
+	cmd.Flags().StringVar(&genesisAppHash, "app-hash", "", "Hexadecimal hash of the initial state of the application")
+	cmd.Flags().BoolVar(&genesisForce, "force", false, "Overwrite an existing genesis document")
+
+	return cmd
+}
+
+// configGenesis writes a genesis document with the peers of peers.genesis.json,
+// or peers.json if there is no peers.genesis.json, like the node would use
+// without a genesis document.
+func configGenesis(cmd *cobra.Command, args []string) error {
+	if genesisChainID == "" {
+		return fmt.Errorf("--chain-id is required")
+	}
+
+	conf := config.NewDefaultConfig()
+	conf.SetDataDir(genesisDataDir)
+
+	out := conf.GenesisFile()
+	if _, err := os.Stat(out); err == nil && !genesisForce {
+		return fmt.Errorf("A genesis document already exists: %s", out)
+	}
+
+	peerSet, err := peers.NewJSONPeerSet(genesisDataDir, false).PeerSet()
+	if err != nil {
+		peerSet, err = peers.NewJSONPeerSet(genesisDataDir, true).PeerSet()
+	}
+	if err != nil {
+		return fmt.Errorf("Reading peers: %s", err)
+	}
+	if peerSet == nil {
+		return fmt.Errorf("No peers in %s", genesisDataDir)
+	}
+
+	g := genesis.NewGenesis(genesisChainID, peerSet, genesisAppHash)
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	if err := g.Write(out); err != nil {
+		return fmt.Errorf("Writing genesis document: %s", err)
+	}
+
+	networkID, err := g.NetworkID()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Your genesis document has been saved to: %s\n", out)
+	fmt.Printf("Network ID: %s\n", networkID)
+
+	return nil
+}
+
+func newConfigMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move the files of a datadir into the subdirectory of its network",
+		Long: `Move the files of a datadir into the subdirectory of its network
+
+The files of the datadir, with its database, are moved to [datadir]/[network-id],
+where the network ID is the hash of its genesis document, so that other networks
+can be added next to it. The node must be stopped. The files are gathered in a
+temporary directory, renamed once complete, and an interrupted migration is
+completed by running the command again.`,
+		Args: cobra.NoArgs,
+		RunE: configMigrate,
+	}
+
+	cmd.Flags().StringVar(&migrateDataDir, "datadir", _config.Babble.DataDir, "Top-level directory for configuration and data")
+
+	return cmd
+}
+
+func configMigrate(cmd *cobra.Command, args []string) error {
+	conf := config.NewDefaultConfig()
+	conf.SetDataDir(migrateDataDir)
+
+	networkID, err := migrationNetworkID(conf)
+	if err != nil {
+		return err
+	}
+
+	out := filepath.Join(migrateDataDir, networkID)
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("The network subdirectory already exists: %s", out)
+	}
+
+	tmp := out + config.NetworkInitSuffix
+	if err := os.MkdirAll(tmp, 0700); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(migrateDataDir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if config.IsNetworkID(name) || config.IsNetworkID(strings.TrimSuffix(name, config.NetworkInitSuffix)) {
+			continue
+		}
+
+		if err := os.Rename(filepath.Join(migrateDataDir, name), filepath.Join(tmp, name)); err != nil {
+			return fmt.Errorf("Moving %s: %s", name, err)
+		}
+	}
+
+	if err := os.Rename(tmp, out); err != nil {
+		return err
+	}
+
+	if err := common.SyncDir(migrateDataDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("Your datadir has been migrated to: %s\n", out)
+
+	for _, format := range configFormats {
+		file := filepath.Join(out, "babble."+format)
+
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			continue
+		}
+
+		for _, key := range []string{"datadir", "db"} {
+			if v.IsSet(key) {
+				fmt.Printf("%s sets %s to %s: update it, or remove it\n", file, key, v.GetString(key))
+			}
+		}
+	}
+
+	return nil
+}
+
+// migrationNetworkID returns the network ID of a datadir, from its genesis
+// document, or from the directory of an interrupted migration.
+func migrationNetworkID(conf *config.Config) (string, error) {
+	entries, err := ioutil.ReadDir(conf.DataDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, e := range entries {
+		if name := strings.TrimSuffix(e.Name(), config.NetworkInitSuffix); name != e.Name() && config.IsNetworkID(name) {
+			fmt.Printf("Completing the interrupted migration to %s\n", name)
+			return name, nil
+		}
+	}
+
+	if _, err := os.Stat(conf.GenesisFile()); os.IsNotExist(err) {
+		return "", fmt.Errorf("The network ID of %s is defined by its genesis document, which is missing. Write one with babble config genesis first", conf.DataDir)
+	}
+
+	g, err := genesis.Read(conf.GenesisFile())
+	if err != nil {
+		return "", err
+	}
+
+	return g.NetworkID()
+}
+
+// defaultConfigFile returns a config file, in the given format, setting every
+// option of the run command to the value of its flag. TOML and YAML files carry
+// the usage of each option as a comment.
+func defaultConfigFile(flags *pflag.FlagSet, format string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case "toml", "yaml":
+		separator := " = "
+		if format == "yaml" {
+			separator = ": "
+		}
+
+		buf.WriteString("# Babble configuration. Options can be overridden by\n")
+		buf.WriteString("# command-line flags, and by BABBLE_* environment variables.\n")
+
+		flags.VisitAll(func(f *pflag.Flag) {
+			if nonConfigKeys[f.Name] {
+				return
+			}
+			fmt.Fprintf(&buf, "\n# %s\n%s%s%s\n", f.Usage, f.Name, separator, configValue(f))
+		})
+	case "json":
+		values := make(map[string]json.RawMessage)
+
+		flags.VisitAll(func(f *pflag.Flag) {
+			if nonConfigKeys[f.Name] {
+				return
+			}
+			values[f.Name] = json.RawMessage(configValue(f))
+		})
+
+		content, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(content)
+		buf.WriteString("\n")
+	default:
+		return nil, fmt.Errorf("Unknown format %q, expected one of %s", format, strings.Join(configFormats, ", "))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// configValue returns the value of a flag as a literal, which has the same
+// syntax in TOML, YAML and JSON. Durations are written as strings, like
+// "10ms".
+func configValue(f *pflag.Flag) string {
+	switch f.Value.Type() {
+	case "bool", "int", "int64", "uint32", "float64":
+		return f.Value.String()
+	default:
+		return strconv.Quote(f.Value.String())
+	}
+}
+
+// checkConfigKeys returns an error if the config file contains keys which do
+// not correspond to any option, which would otherwise be ignored silently.
+func checkConfigKeys(cmd *cobra.Command, file string) error {
+	v := viper.New()
+	v.SetConfigFile(file)
+
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+
+	unknown := []string{}
+	for _, key := range v.AllKeys() {
+		if nonConfigKeys[key] || cmd.Flags().Lookup(key) == nil {
+			unknown = append(unknown, key)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("Unknown keys in config file %s: %s", file, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
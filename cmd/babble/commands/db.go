@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbDataDir string
+	dbPath    string
+)
+
+// NewDBCmd produces a DBCmd with subcommands to inspect the database of a node
+func NewDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Inspect the database of a node",
+		Long: `Inspect the database of a node
+
+The database is opened in read-only mode, so the node must be stopped. Events,
+Rounds, Blocks and Frames are printed in JSON, like the HTTP service does.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&dbDataDir, "datadir", _config.Babble.DataDir, "Top-level directory for configuration and data")
+	cmd.PersistentFlags().StringVar(&dbPath, "db", "", "Database directory. Defaults to [datadir]/badger_db")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "stats",
+			Short: "Count the items of the database",
+			Args:  cobra.NoArgs,
+			RunE:  dbStats,
+		},
+		&cobra.Command{
+			Use:   "peers",
+			Short: "Print the history of peer-sets",
+			Args:  cobra.NoArgs,
+			RunE:  dbPeers,
+		},
+		&cobra.Command{
+			Use:   "event [hash|topological index]",
+			Short: "Print an Event by hash, or by topological index",
+			Args:  cobra.ExactArgs(1),
+			RunE:  dbEvent,
+		},
+		&cobra.Command{
+			Use:   "round [index]",
+			Short: "Print a Round",
+			Args:  cobra.ExactArgs(1),
+			RunE:  dbRound,
+		},
+		&cobra.Command{
+			Use:   "block [index]",
+			Short: "Print a Block",
+			Args:  cobra.ExactArgs(1),
+			RunE:  dbBlock,
+		},
+		&cobra.Command{
+			Use:   "frame [round-received]",
+			Short: "Print a Frame",
+			Args:  cobra.ExactArgs(1),
+			RunE:  dbFrame,
+		},
+	)
+
+	return cmd
+}
+
+// withDatabase opens the database selected by the datadir and db flags, and
+// calls fn with it.
+func withDatabase(fn func(store *hg.BadgerStore) error) error {
+	conf := config.NewDefaultConfig()
+	conf.LogLevel = "warn"
+	conf.SetDataDir(dbDataDir)
+
+	if dbPath != "" {
+		conf.DatabaseDir = dbPath
+	}
+
+	store, closeStore, err := openDatabase(conf)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	return fn(store)
+}
+
+func dbStats(cmd *cobra.Command, args []string) error {
+	return withDatabase(func(store *hg.BadgerStore) error {
+		stats, err := store.PersistedStats()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("events: %d\n", stats.Events)
+		fmt.Printf("rounds: %d (last %d)\n", stats.Rounds, stats.LastRound)
+		fmt.Printf("blocks: %d (last %d)\n", stats.Blocks, stats.LastBlock)
+		fmt.Printf("frames: %d (last %d)\n", stats.Frames, stats.LastFrame)
+		fmt.Printf("transactions: %d\n", stats.Transactions)
+		fmt.Printf("peer-sets: %d\n", stats.PeerSets)
+		fmt.Printf("participants: %d\n", stats.Participants)
+
+		return nil
+	})
+}
+
+func dbPeers(cmd *cobra.Command, args []string) error {
+	return withDatabase(func(store *hg.BadgerStore) error {
+		peerSets, err := store.PersistedPeerSets()
+		if err != nil {
+			return err
+		}
+
+		rounds := make([]int, 0, len(peerSets))
+		for r := range peerSets {
+			rounds = append(rounds, r)
+		}
+		sort.Ints(rounds)
+
+		for _, r := range rounds {
+			ps := peerSets[r]
+			fmt.Printf("Round %d: %d peers, hash %s\n", r, ps.Len(), ps.Hex())
+			for _, p := range ps.Peers {
+				fmt.Printf("  %s %s %s\n", p.PubKeyString(), p.NetAddr, p.Moniker)
+			}
+		}
+
+		return nil
+	})
+}
+
+func dbEvent(cmd *cobra.Command, args []string) error {
+	return withDatabase(func(store *hg.BadgerStore) error {
+		var event *hg.Event
+		var err error
+
+		if index, perr := strconv.Atoi(args[0]); perr == nil {
+			event, err = store.PersistedTopologicalEvent(index)
+		} else {
+			event, err = store.PersistedEvent("0X" + strings.TrimPrefix(strings.ToUpper(args[0]), "0X"))
+		}
+		if err != nil {
+			return err
+		}
+
+		return printJSON(event)
+	})
+}
+
+func dbRound(cmd *cobra.Command, args []string) error {
+	return withIndex(args[0], func(store *hg.BadgerStore, index int) (interface{}, error) {
+		return store.PersistedRound(index)
+	})
+}
+
+func dbBlock(cmd *cobra.Command, args []string) error {
+	return withIndex(args[0], func(store *hg.BadgerStore, index int) (interface{}, error) {
+		return store.PersistedBlock(index)
+	})
+}
+
+func dbFrame(cmd *cobra.Command, args []string) error {
+	return withIndex(args[0], func(store *hg.BadgerStore, index int) (interface{}, error) {
+		return store.PersistedFrame(index)
+	})
+}
+
+// withIndex prints the item returned by get for an index.
+func withIndex(arg string, get func(store *hg.BadgerStore, index int) (interface{}, error)) error {
+	index, err := strconv.Atoi(arg)
+	if err != nil {
+		return fmt.Errorf("Invalid index %q", arg)
+	}
+
+	return withDatabase(func(store *hg.BadgerStore) error {
+		item, err := get(store, index)
+		if err != nil {
+			return err
+		}
+		return printJSON(item)
+	})
+}
+
+func printJSON(item interface{}) error {
+	content, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(content))
+	return nil
+}
+
+// openDatabase opens the database of a node in read-only mode. It returns a
+// function which closes it.
+func openDatabase(conf *config.Config) (*hg.BadgerStore, func(), error) {
+	store, err := hg.NewReadOnlyBadgerStore(conf.CacheSize, conf.DatabaseDir, conf.ModuleLogger("store"))
+	if err == nil {
+		return store, func() { store.Close() }, nil
+	}
+
+	if _, serr := os.Stat(conf.DatabaseDir); serr != nil {
+		return nil, nil, fmt.Errorf("Opening database %s: %s", conf.DatabaseDir, err)
+	}
+
+	// A database which was not closed properly, like the database of a node
+	// that crashed, cannot be opened in read-only mode, because its log must
+	// be replayed first. Open a copy instead.
+	fmt.Fprintf(os.Stderr, "Cannot open %s in read-only mode (%s). Using a copy\n", conf.DatabaseDir, err)
+
+	tmp, err := copyDatabase(conf.DatabaseDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Copying database %s: %s", conf.DatabaseDir, err)
+	}
+
+	store, err = hg.NewBadgerStore(conf.CacheSize, tmp, true, conf.ModuleLogger("store"))
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, nil, fmt.Errorf("Opening database %s: %s", conf.DatabaseDir, err)
+	}
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(tmp)
+	}, nil
+}
+
+// copyDatabase copies the files of a database to a temporary directory, except
+// its lock file.
+func copyDatabase(dir string) (string, error) {
+	tmp, err := ioutil.TempDir("", "babble-db")
+	if err != nil {
+		return "", err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || f.Name() == "LOCK" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			os.RemoveAll(tmp)
+			return "", err
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(tmp, f.Name()), data, 0600); err != nil {
+			os.RemoveAll(tmp)
+			return "", err
+		}
+	}
+
+	return tmp, nil
+}
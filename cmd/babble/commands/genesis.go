@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/genesis"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	genesisSigDataDir string
+	genesisSigKey     string
+)
+
+// NewGenesisCmd produces a GenesisCmd with subcommands to sign and verify the
+// genesis document and peers.json file of a network
+func NewGenesisCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "genesis",
+		Short: "Sign and verify the genesis document and peers.json",
+		Long: `Sign and verify the genesis document and peers.json
+
+The genesis.json and peers.json files of the datadir are signed by each genesis
+validator in turn, offline, with its private key. The signatures are kept in
+genesis.sig.json, which is passed on from one validator to the next with the
+files, and distributed to all the nodes. Nodes check the signatures at startup,
+and refuse to start if the files were tampered with.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&genesisSigDataDir, "datadir", _config.Babble.DataDir, "Top-level directory for configuration and data")
+
+	signCmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Sign the genesis document and peers.json with the private key of a validator",
+		Args:  cobra.NoArgs,
+		RunE:  genesisSign,
+	}
+
+	signCmd.Flags().StringVar(&genesisSigKey, "key", "", "File containing the private key of the validator. Defaults to [datadir]/priv_key")
+
+	cmd.AddCommand(
+		signCmd,
+		&cobra.Command{
+			Use:   "verify",
+			Short: "Verify that the genesis document and peers.json are signed by all the genesis validators",
+			Args:  cobra.NoArgs,
+			RunE:  genesisVerify,
+		},
+	)
+
+	return cmd
+}
+
+// readBootstrapFiles reads the genesis document and the optional peers.json
+// file of the datadir, like the node does at startup, and returns the
+// document with the hash of the files.
+func readBootstrapFiles(conf *config.Config) (*genesis.Genesis, []byte, error) {
+	g, err := genesis.Read(conf.GenesisFile())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	peerSet, err := peers.NewJSONPeerSet(conf.DataDir, true).PeerSet()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	hash, err := genesis.BootstrapHash(g, peerSet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return g, hash, nil
+}
+
+func genesisSign(cmd *cobra.Command, args []string) error {
+	conf := config.NewDefaultConfig()
+	conf.SetDataDir(genesisSigDataDir)
+
+	keyFile := genesisSigKey
+	if keyFile == "" {
+		keyFile = conf.Keyfile()
+	}
+
+	key, err := keys.NewSimpleKeyfile(keyFile).ReadKey()
+	if err != nil {
+		return fmt.Errorf("Reading private key: %s", err)
+	}
+
+	g, hash, err := readBootstrapFiles(conf)
+	if err != nil {
+		return err
+	}
+
+	signatures, err := genesis.ReadSignatures(conf.GenesisSignaturesFile())
+	if os.IsNotExist(err) {
+		signatures, err = &genesis.Signatures{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := signatures.Sign(hash, key); err != nil {
+		return err
+	}
+
+	missing, err := signatures.Verify(g, hash)
+	if err != nil {
+		return err
+	}
+
+	if err := signatures.Write(conf.GenesisSignaturesFile()); err != nil {
+		return fmt.Errorf("Writing signatures: %s", err)
+	}
+
+	fmt.Printf("Your signature has been saved to: %s\n", conf.GenesisSignaturesFile())
+	fmt.Printf("Signed by %d of %d genesis validators\n", len(g.Peers)-len(missing), len(g.Peers))
+
+	return nil
+}
+
+func genesisVerify(cmd *cobra.Command, args []string) error {
+	conf := config.NewDefaultConfig()
+	conf.SetDataDir(genesisSigDataDir)
+
+	g, hash, err := readBootstrapFiles(conf)
+	if err != nil {
+		return err
+	}
+
+	signatures, err := genesis.ReadSignatures(conf.GenesisSignaturesFile())
+	if err != nil {
+		return err
+	}
+
+	missing, err := signatures.Verify(g, hash)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range missing {
+		fmt.Printf("missing: %s %s\n", p.Moniker, p.PubKeyHex)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%d of the %d genesis validators have not signed", len(missing), len(g.Peers))
+	}
+
+	fmt.Printf("Signed by all %d genesis validators: %s\n", len(g.Peers), signatures.Hash)
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphService string
+	graphRange   string
+	graphFormat  string
+	graphOut     string
+	graphAPIKey  string
+)
+
+// NewGraphCmd produces a GraphCmd which exports a window of the hashgraph of a
+// running node, from its HTTP service, as Graphviz DOT or a JSON graph.
+func NewGraphCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Export the hashgraph of a running node as DOT or JSON",
+		Example: "  babble graph --range 10-20 --out graph.dot\n" +
+			"  dot -Tsvg graph.dot -o graph.svg",
+		RunE: exportGraph,
+	}
+
+	AddGraphFlags(cmd)
+
+	return cmd
+}
+
+// AddGraphFlags adds flags to the graph command
+func AddGraphFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&graphService, "service", config.DefaultServiceAddr, "IP:Port, or URL, of the HTTP service of the node")
+	cmd.Flags().StringVar(&graphRange, "range", "", "Inclusive range of rounds, like 10-20. Defaults to the last rounds")
+	cmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format: dot or json")
+	cmd.Flags().StringVar(&graphOut, "out", "", "File where the graph will be written. Defaults to stdout")
+	cmd.Flags().StringVar(&graphAPIKey, "api-key", "", "API key, if the service requires authentication")
+}
+
+func exportGraph(cmd *cobra.Command, args []string) error {
+	if graphFormat != "dot" && graphFormat != "json" {
+		return fmt.Errorf("Unknown format %q, expected dot or json", graphFormat)
+	}
+
+	base := graphService
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+
+	query := url.Values{}
+	query.Set("format", graphFormat)
+	if graphRange != "" {
+		query.Set("range", graphRange)
+	}
+
+	req, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("%s/v1/graph/export?%s", strings.TrimSuffix(base, "/"), query.Encode()),
+		nil)
+	if err != nil {
+		return err
+	}
+
+	if graphAPIKey != "" {
+		req.Header.Set("X-API-Key", graphAPIKey)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Fetching graph: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Fetching graph: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if graphOut == "" {
+		_, err = io.Copy(os.Stdout, resp.Body)
+		return err
+	}
+
+	file, err := os.Create(graphOut)
+	if err != nil {
+		return fmt.Errorf("Writing graph: %s", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("Writing graph: %s", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "The graph has been saved to: %s\n", graphOut)
+
+	return nil
+}
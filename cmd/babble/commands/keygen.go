@@ -2,10 +2,10 @@ package commands
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path"
 
+	"github.com/mosaicnetworks/babble/src/common"
 	"github.com/mosaicnetworks/babble/src/crypto/keys"
 	"github.com/spf13/cobra"
 )
@@ -64,7 +64,7 @@ func keygen(cmd *cobra.Command, args []string) error {
 
 	pub := keys.PublicKeyHex(&key.PublicKey)
 
-	if err := ioutil.WriteFile(pubKeyFile, []byte(pub), 0600); err != nil {
+	if err := common.WriteFileAtomic(pubKeyFile, []byte(pub), 0600); err != nil {
 		return fmt.Errorf("Writing public key: %s", err)
 	}
 
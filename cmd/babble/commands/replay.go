@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/genesis"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/mosaicnetworks/babble/src/proxy/abci"
+	aproxy "github.com/mosaicnetworks/babble/src/proxy/socket/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayDataDir    string
+	replayDB         string
+	replayBlock      int
+	replayCacheSize  int
+	replayClientAddr string
+	replayProxyAddr  string
+	replayABCIAddr   string
+	replayABCIChain  string
+	replayLogLevel   string
+)
+
+// NewReplayCmd produces a ReplayCmd which re-runs consensus from the database
+// of a node.
+func NewReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-run consensus from the database of a node",
+		Long: `Re-run consensus from the database of a node
+
+The Events of the database are inserted again in a new hashgraph, from the
+genesis peer-set, and the resulting Blocks are compared with the Blocks of the
+database. The database is opened in read-only mode, so the node must be
+stopped. A database which was not closed properly is copied to a temporary
+directory first.
+
+Without --client-connect or --abci-connect, the Blocks are committed to a no-op
+App which replies with the state hashes and receipts recorded in the database.
+Otherwise they are committed to the App, which must start from a clean state.
+
+The command fails if any replayed Block differs from the database.`,
+		RunE: replay,
+	}
+
+	defaultConfig := NewDefaultCLIConfig()
+
+	cmd.Flags().StringVar(&replayDataDir, "datadir", _config.Babble.DataDir, "Top-level directory for configuration and data")
+	cmd.Flags().StringVar(&replayDB, "db", "", "Database directory. Defaults to [datadir]/badger_db")
+	cmd.Flags().IntVar(&replayBlock, "block", -1, "Index of the last Block to replay. All the Blocks are replayed if negative")
+	cmd.Flags().IntVar(&replayCacheSize, "cache-size", _config.Babble.CacheSize, "Number of items in LRU caches")
+	cmd.Flags().StringVar(&replayClientAddr, "client-connect", "", "IP:Port of a socket App to commit the Blocks to")
+	cmd.Flags().StringVar(&replayProxyAddr, "proxy-listen", defaultConfig.ProxyAddr, "Listen IP:Port for babble proxy, with --client-connect")
+	cmd.Flags().StringVar(&replayABCIAddr, "abci-connect", "", "Address of an ABCI application to commit the Blocks to (ex: tcp://127.0.0.1:26658)")
+	cmd.Flags().StringVar(&replayABCIChain, "abci-chain-id", defaultConfig.ABCIChain, "Chain ID passed to the ABCI application")
+	cmd.Flags().StringVar(&replayLogLevel, "log", "warn", "debug, info, warn, error, fatal, panic")
+
+	return cmd
+}
+
+func replay(cmd *cobra.Command, args []string) error {
+	conf := config.NewDefaultConfig()
+	conf.LogLevel = replayLogLevel
+	conf.CacheSize = replayCacheSize
+	conf.SetDataDir(replayDataDir)
+
+	if replayDB != "" {
+		conf.DatabaseDir = replayDB
+	}
+
+	store, closeStore, err := openDatabase(conf)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	var appProxy proxy.AppProxy
+
+	switch {
+	case replayABCIAddr != "":
+		appProxy = abci.NewABCIProxy(replayABCIAddr, replayABCIChain, conf.TCPTimeout, conf.ModuleLogger("proxy"))
+	case replayClientAddr != "":
+		appProxy, err = aproxy.NewSocketAppProxy(replayClientAddr, replayProxyAddr, conf.TCPTimeout, conf.ModuleLogger("proxy"))
+		if err != nil {
+			return fmt.Errorf("Cannot initialize AppProxy: %s", err)
+		}
+	}
+
+	thresholds, peerSetInterval, err := replayGenesis(conf)
+	if err != nil {
+		return err
+	}
+
+	blocks, err := node.Replay(store, appProxy, replayBlock, thresholds, peerSetInterval, conf.ModuleLogger("node"))
+	if err != nil {
+		return fmt.Errorf("Replay failed after %d blocks: %s", len(blocks), err)
+	}
+
+	diverged := 0
+	for _, b := range blocks {
+		fmt.Printf("Block %d: round %d, %d transactions, %d internal transactions\n",
+			b.Index, b.RoundReceived, b.Transactions, b.InternalTransactions)
+
+		if b.Divergence != "" {
+			fmt.Printf("  differs from the database: %s\n", b.Divergence)
+			diverged++
+		}
+	}
+
+	fmt.Printf("Replayed %d blocks from %s\n", len(blocks), conf.DatabaseDir)
+
+	if replayBlock >= 0 && (len(blocks) == 0 || blocks[len(blocks)-1].Index < replayBlock) {
+		fmt.Printf("The database does not produce block %d\n", replayBlock)
+	}
+
+	if diverged > 0 {
+		return fmt.Errorf("%d blocks differ from the database", diverged)
+	}
+
+	return nil
+}
+
+// replayGenesis returns the thresholds and peer-set interval of the genesis
+// document of the data directory, if there is one. Otherwise, the replay uses
+// the default parameters.
+func replayGenesis(conf *config.Config) (peers.Thresholds, int, error) {
+	if _, err := os.Stat(conf.GenesisFile()); os.IsNotExist(err) {
+		return peers.DefaultThresholds, 0, nil
+	}
+
+	g, err := genesis.Read(conf.GenesisFile())
+	if err != nil {
+		return peers.Thresholds{}, 0, err
+	}
+
+	thresholds, err := g.Consensus.Thresholds()
+	if err != nil {
+		return peers.Thresholds{}, 0, err
+	}
+
+	return thresholds, g.Consensus.PeerSetInterval, nil
+}
@@ -1,9 +1,16 @@
 package commands
 
 import (
+	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/mosaicnetworks/babble/src/babble"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/mosaicnetworks/babble/src/proxy/abci"
 	aproxy "github.com/mosaicnetworks/babble/src/proxy/socket/app"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -28,20 +35,9 @@ func NewRunCmd() *cobra.Command {
 
 func runBabble(cmd *cobra.Command, args []string) error {
 
-	_config.Babble.Logger().WithFields(logrus.Fields{
-		"ProxyAddr":  _config.ProxyAddr,
-		"ClientAddr": _config.ClientAddr,
-	}).Debug("Config Proxy")
-
-	p, err := aproxy.NewSocketAppProxy(
-		_config.ClientAddr,
-		_config.ProxyAddr,
-		_config.Babble.HeartbeatTimeout,
-		_config.Babble.Logger(),
-	)
-
+	p, err := newAppProxy()
 	if err != nil {
-		_config.Babble.Logger().Error("Cannot initialize socket AppProxy:", err)
+		_config.Babble.Logger().Error("Cannot initialize AppProxy:", err)
 		return err
 	}
 
@@ -49,16 +45,52 @@ func runBabble(cmd *cobra.Command, args []string) error {
 
 	engine := babble.NewBabble(&_config.Babble)
 
+	engine.ConfigLoader = func() (*config.Config, error) {
+		return loadConfig(cmd)
+	}
+
 	if err := engine.Init(); err != nil {
 		_config.Babble.Logger().Error("Cannot initialize engine:", err)
 		return err
 	}
 
+	go reloadOnSighup(engine)
+
 	engine.Run()
 
 	return nil
 }
 
+// newAppProxy creates an ABCI AppProxy if an ABCI application address is
+// configured, and a socket AppProxy otherwise.
+func newAppProxy() (proxy.AppProxy, error) {
+	if _config.ABCIAddr != "" {
+		_config.Babble.Logger().WithFields(logrus.Fields{
+			"ABCIAddr":  _config.ABCIAddr,
+			"ABCIChain": _config.ABCIChain,
+		}).Debug("Config ABCI Proxy")
+
+		return abci.NewABCIProxy(
+			_config.ABCIAddr,
+			_config.ABCIChain,
+			_config.Babble.TCPTimeout,
+			_config.Babble.ModuleLogger("proxy"),
+		), nil
+	}
+
+	_config.Babble.Logger().WithFields(logrus.Fields{
+		"ProxyAddr":  _config.ProxyAddr,
+		"ClientAddr": _config.ClientAddr,
+	}).Debug("Config Proxy")
+
+	return aproxy.NewSocketAppProxy(
+		_config.ClientAddr,
+		_config.ProxyAddr,
+		_config.Babble.HeartbeatTimeout,
+		_config.Babble.ModuleLogger("proxy"),
+	)
+}
+
 /*******************************************************************************
 * CONFIG
 *******************************************************************************/
@@ -66,14 +98,32 @@ func runBabble(cmd *cobra.Command, args []string) error {
 //AddRunFlags adds flags to the Run command
 func AddRunFlags(cmd *cobra.Command) {
 
+	cmd.Flags().String("config", "", "Config file (.toml, .yaml or .json). Defaults to [datadir]/babble.toml")
 	cmd.Flags().String("datadir", _config.Babble.DataDir, "Top-level directory for configuration and data")
+	cmd.Flags().String("network", _config.Babble.Network, "Network ID, or a unique prefix of it, of the network subdirectory of the datadir to use")
 	cmd.Flags().String("log", _config.Babble.LogLevel, "debug, info, warn, error, fatal, panic")
+	cmd.Flags().String("log-format", _config.Babble.LogFormat, "Log output format: text or json")
+	cmd.Flags().String("log-modules", _config.Babble.LogModules, "Per-module log levels (ex: node=debug,transport=warn)")
+	cmd.Flags().String("log-file", _config.Babble.LogFile, "Write logs to this file instead of stderr")
 	cmd.Flags().String("moniker", _config.Babble.Moniker, "Optional name")
 	cmd.Flags().BoolP("maintenance-mode", "R", _config.Babble.MaintenanceMode, "Start Babble in a suspended (non-gossipping) state")
 
 	// Network
 	cmd.Flags().StringP("listen", "l", _config.Babble.BindAddr, "Listen IP:Port for babble node")
 	cmd.Flags().StringP("advertise", "a", _config.Babble.AdvertiseAddr, "Advertise IP:Port for babble node")
+	cmd.Flags().String("nat", _config.Babble.NAT, "Port mapping with the router (none|any|upnp|pmp|pmp:<gateway ip>)")
+	cmd.Flags().Bool("announce-addr", _config.Babble.AnnounceAddr, "Announce the advertised address to the other validators when it changes")
+	cmd.Flags().String("allow-ips", _config.Babble.AllowIPs, "Comma-separated IPs or CIDR ranges allowed to connect (all if empty)")
+	cmd.Flags().String("block-ips", _config.Babble.BlockIPs, "Comma-separated IPs or CIDR ranges refused by the transport")
+	cmd.Flags().String("allow-pubkeys", _config.Babble.AllowPubKeys, "Comma-separated public keys allowed to send RPCs (all if empty)")
+	cmd.Flags().String("block-pubkeys", _config.Babble.BlockPubKeys, "Comma-separated public keys refused by the transport")
+	cmd.Flags().Bool("relay", _config.Babble.Relay, "Listen on both TCP and WebRTC, and relay RPCs between peers that cannot reach each other")
+	cmd.Flags().String("relay-routes", _config.Babble.RelayRoutes, "Comma-separated target=relay pairs of peers reached through a relay")
+	cmd.Flags().String("tor-proxy", _config.Babble.TorProxy, "IP:Port of the Tor SOCKS5 proxy used to dial .onion addresses")
+	cmd.Flags().String("tor-control", _config.Babble.TorControl, "IP:Port of the Tor control port used to publish the node as an onion service")
+	cmd.Flags().String("tor-control-password", _config.Babble.TorControlPassword, "Password of the Tor control port (defaults to cookie authentication)")
+	cmd.Flags().String("record-rpc", _config.Babble.RecordRPC, "Record all the RPCs of the node to this file, for debugging")
+	cmd.Flags().String("replay-rpc", _config.Babble.ReplayRPC, "Replay the RPCs recorded in this file instead of connecting to other nodes")
 	cmd.Flags().DurationP("timeout", "t", _config.Babble.TCPTimeout, "TCP Timeout")
 	cmd.Flags().DurationP("join-timeout", "j", _config.Babble.JoinTimeout, "Join Timeout")
 	cmd.Flags().Int("max-pool", _config.Babble.MaxPool, "Connection pool size max")
@@ -82,17 +132,44 @@ func AddRunFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("webrtc", _config.Babble.WebRTC, "Use WebRTC transport")
 	cmd.Flags().String("signal-addr", _config.Babble.SignalAddr, "IP:Port of WebRTC signaling server")
 	cmd.Flags().Bool("signal-skip-verify", _config.Babble.SignalSkipVerify, "(Insecure) Accept any certificate presented by the signal server")
+	cmd.Flags().Bool("signal-encrypt", _config.Babble.SignalEncrypt, "Encrypt the WebRTC offers and answers end-to-end between peers")
 	cmd.Flags().String("ice-addr", _config.Babble.ICEAddress, "URI of a server providing ICE services such as STUN and TURN")
 	cmd.Flags().String("ice-username", _config.Babble.ICEUsername, "Username to authenticate to the ICE server")
 	cmd.Flags().String("ice-password", _config.Babble.ICEPassword, "Password to authenticate to the ICE server")
+	cmd.Flags().Bool("ice-host-only", _config.Babble.ICEHostOnly, "Restrict ICE to host candidates, without STUN or TURN, for air-gapped local networks")
+	cmd.Flags().Bool("webrtc-unordered", _config.Babble.WebRTCUnordered, "Let the messages of the WebRTC data channels be delivered out of order")
+	cmd.Flags().Int("webrtc-max-retransmits", _config.Babble.WebRTCMaxRetransmits, "Max retransmissions of a lost message on the WebRTC data channels (-1 = unlimited)")
+	cmd.Flags().Int64("webrtc-buffered-high", _config.Babble.WebRTCBufferedHigh, "Bytes buffered on a WebRTC data channel above which writes wait (0 = unlimited)")
+	cmd.Flags().Int64("webrtc-buffered-low", _config.Babble.WebRTCBufferedLow, "Bytes buffered on a WebRTC data channel under which waiting writes resume")
 
 	// Proxy
 	cmd.Flags().StringP("proxy-listen", "p", _config.ProxyAddr, "Listen IP:Port for babble proxy")
 	cmd.Flags().StringP("client-connect", "c", _config.ClientAddr, "IP:Port to connect to client")
+	cmd.Flags().String("abci-connect", _config.ABCIAddr, "Address of an ABCI application (ex: tcp://127.0.0.1:26658). Replaces the socket proxy")
+	cmd.Flags().String("abci-chain-id", _config.ABCIChain, "Chain ID passed to the ABCI application")
 
 	// Service
 	cmd.Flags().Bool("no-service", _config.Babble.NoService, "Disable HTTP service")
 	cmd.Flags().StringP("service-listen", "s", _config.Babble.ServiceAddr, "Listen IP:Port for HTTP service")
+	cmd.Flags().String("admin-token", _config.Babble.AdminToken, "Token required by the /admin and /debug endpoints of the HTTP service. They are disabled if empty")
+	cmd.Flags().String("service-api-keys", _config.Babble.ServiceAPIKeys, "Comma-separated key:role pairs (roles: read, admin) granting access to the HTTP service")
+	cmd.Flags().String("service-jwt-secret", _config.Babble.ServiceJWTSecret, "Secret of the HS256 JSON Web Tokens accepted by the HTTP service")
+	cmd.Flags().Bool("service-read-auth", _config.Babble.ServiceReadAuth, "Require the read role on the read-only endpoints of the HTTP service")
+	cmd.Flags().String("service-cors-origins", _config.Babble.ServiceCORSOrigins, "Comma-separated origins allowed to make cross-origin requests to the HTTP service")
+	cmd.Flags().String("service-tls-cert", _config.Babble.ServiceTLSCert, "PEM certificate for serving the HTTP service over HTTPS")
+	cmd.Flags().String("service-tls-key", _config.Babble.ServiceTLSKey, "PEM private key for serving the HTTP service over HTTPS")
+	cmd.Flags().Float64("service-rate-limit", _config.Babble.ServiceRateLimit, "Requests per second allowed from each client IP by the HTTP service (0 = unlimited)")
+	cmd.Flags().Int("service-rate-burst", _config.Babble.ServiceRateBurst, "Burst of requests allowed from each client IP by the HTTP service")
+	cmd.Flags().Float64("service-tx-rate-limit", _config.Babble.ServiceTxRateLimit, "Transactions per second accepted from each client IP on the /tx endpoints (0 = unlimited)")
+	cmd.Flags().Int("service-tx-rate-burst", _config.Babble.ServiceTxRateBurst, "Burst of transactions accepted from each client IP on the /tx endpoints")
+	cmd.Flags().Int64("service-max-body-bytes", _config.Babble.ServiceMaxBodyBytes, "Maximum size of the request bodies accepted by the HTTP service")
+	cmd.Flags().Int64("service-max-tx-bytes", _config.Babble.ServiceMaxTxBytes, "Maximum size of the transactions accepted on the /tx endpoints")
+	cmd.Flags().String("tx-api-keys", _config.Babble.TxAPIKeys, "Comma-separated API keys authorizing the transactions submitted on the /tx endpoints")
+	cmd.Flags().String("tx-client-keys", _config.Babble.TxClientKeys, "File of client public keys whose signed transactions are authorized, one per line")
+	cmd.Flags().Duration("idempotency-window", _config.Babble.IdempotencyWindow, "Period during which the transactions resubmitted with the same idempotency key are not submitted again (0 = disabled)")
+	cmd.Flags().Bool("graphql", _config.Babble.GraphQL, "Enable the /graphql endpoint of the HTTP service")
+	cmd.Flags().Int("ready-max-event-lag", _config.Babble.ReadyMaxEventLag, "Number of events behind other nodes above which /readyz reports the node as not ready")
+	cmd.Flags().Int("ready-max-round-lag", _config.Babble.ReadyMaxRoundLag, "Number of undecided rounds above which /readyz reports the node as not ready")
 
 	// Store
 	cmd.Flags().Bool("store", _config.Babble.Store, "Use badgerDB instead of in-mem DB")
@@ -104,11 +181,53 @@ func AddRunFlags(cmd *cobra.Command) {
 	cmd.Flags().Duration("heartbeat", _config.Babble.HeartbeatTimeout, "Timer frequency when there is something to gossip about")
 	cmd.Flags().Duration("slow-heartbeat", _config.Babble.SlowHeartbeatTimeout, "Timer frequency when there is nothing to gossip about")
 	cmd.Flags().Int("sync-limit", _config.Babble.SyncLimit, "Max number of events for sync")
+	cmd.Flags().Duration("anti-entropy-interval", _config.Babble.AntiEntropyInterval, "Period of the repair of missing events with a random peer (0 = disabled)")
+	cmd.Flags().Int("sync-chunk-size", _config.Babble.SyncChunkSize, "Max size in bytes of the events of a SyncResponse (0 = unlimited)")
+	cmd.Flags().Duration("sync-dedup-window", _config.Babble.SyncDedupWindow, "Period during which events sent to a peer are not sent to it again (0 = disabled)")
+	cmd.Flags().Bool("push-pull", _config.Babble.PushPull, "Include events in SyncRequests instead of pushing them with EagerSyncRequests")
+	cmd.Flags().Bool("commit-barrier", _config.Babble.CommitBarrier, "Only sign blocks that the application applied and returned a state hash for")
+	cmd.Flags().Bool("private-transactions", _config.Babble.PrivateTransactions, "Decrypt the private transactions addressed to this node before committing them to the application")
+	cmd.Flags().Bool("shadow", _config.Babble.Shadow, "Join as a shadow validator, whose votes and signatures do not count towards quorums")
+	cmd.Flags().Duration("ping-interval", _config.Babble.PingInterval, "Period of the pings measuring the round-trip time to each peer (0 = disabled)")
+	cmd.Flags().Duration("keepalive-interval", _config.Babble.KeepaliveInterval, "Period of the keepalives checking the connection to each peer, independently of gossip (0 = disabled)")
+	cmd.Flags().String("peer-selector", _config.Babble.PeerSelector, "Strategy for selecting gossip peers (random|latency)")
+	cmd.Flags().Int("rpc-workers", _config.Babble.RPCWorkers, "Number of incoming RPCs processed concurrently")
+	cmd.Flags().Int("history-workers", _config.Babble.HistoryWorkers, "Number of RPCs serving history to nodes that are behind processed concurrently, apart from the other RPCs (0 = shared with the other RPCs)")
+	cmd.Flags().Int("history-queue", _config.Babble.HistoryQueue, "Number of RPCs serving history waiting for a worker, above which they are refused")
+	cmd.Flags().Int("join-admission-limit", _config.Babble.JoinAdmissionLimit, "Number of join requests admitted per join-admission-window, the others being told to retry later (0 = unlimited)")
+	cmd.Flags().Duration("join-admission-window", _config.Babble.JoinAdmissionWindow, "Period during which at most join-admission-limit join requests are admitted")
 	cmd.Flags().Bool("fast-sync", _config.Babble.EnableFastSync, "Enable FastSync")
+	cmd.Flags().String("trusted-block", _config.Babble.TrustedBlock, "Fast-forward from this trusted block, given as INDEX:HASH, without downloading the history before it")
+	cmd.Flags().String("trusted-validators", _config.Babble.TrustedValidators, "Peers file with the validator-set of the trusted block")
 	cmd.Flags().Int("suspend-limit", _config.Babble.SuspendLimit, "Limit of undetermined events (per node) before entering suspended state")
+	cmd.Flags().Int64("max-bytes-per-hour", _config.Babble.MaxBytesPerHour, "Maximum traffic of the node per hour (0 = unlimited)")
+	cmd.Flags().Int64("peer-quota", _config.Babble.PeerQuota, "Maximum traffic with each peer per hour (0 = unlimited)")
+	cmd.Flags().String("peer-quota-action", _config.Babble.PeerQuotaAction, "Action against the peers exceeding peer-quota (throttle|ban)")
+	cmd.Flags().Int64("max-memory", _config.Babble.MaxMemory, "Memory budget of the node in bytes, near which it sheds load (0 = unlimited)")
+	cmd.Flags().Bool("metered", _config.Babble.Metered, "Reduce gossip and defer FastForward on a metered connection")
+	cmd.Flags().Bool("offline-mode", _config.Babble.OfflineMode, "Experimental: hold the transactions submitted while cut off from the quorum, and resubmit them when back in contact")
+	cmd.Flags().Duration("offline-timeout", _config.Babble.OfflineTimeout, "Period without gossip with a validator after which it is considered unreachable in offline-mode")
+	cmd.Flags().Duration("watchdog-timeout", _config.Babble.WatchdogTimeout, "Period without new rounds, while transactions are pending, after which consensus is reported as stalled (0 = disabled)")
+	cmd.Flags().Bool("watchdog-redial", _config.Babble.WatchdogRedial, "Redial the peers and repair missing events when consensus is stalled")
+	cmd.Flags().Bool("watchdog-fast-forward", _config.Babble.WatchdogFastForward, "Fast-forward when consensus is stalled")
+	cmd.Flags().Bool("watchdog-alert", _config.Babble.WatchdogAlert, "Raise an alert and write diagnostics when consensus is stalled")
+	cmd.Flags().String("alert-webhooks", _config.Babble.AlertWebhooks, "Comma-separated URLs receiving the alerts of the node (prefix Slack webhooks with slack:)")
+	cmd.Flags().Int("alert-block-lag", _config.Babble.AlertBlockLag, "Number of Blocks behind the other validators above which the node raises an alert (0 = disabled)")
+	cmd.Flags().Duration("audit-interval", _config.Babble.AuditInterval, "Period of the re-derivation of the last Blocks from the stored Events, which raises an alert on divergence (0 = disabled)")
+	cmd.Flags().Int("audit-blocks", _config.Babble.AuditBlocks, "Number of Blocks re-derived by each audit")
+	cmd.Flags().Bool("signed-genesis", _config.Babble.SignedGenesis, "Refuse to start unless the genesis document and peers.json are signed by all the genesis validators")
+	cmd.Flags().Bool("snapshot-delta", _config.Babble.SnapshotDelta, "Transfer the difference between App snapshots, instead of full snapshots, when fast-forwarding from an older Block")
+	cmd.Flags().Bool("verify-snapshots", _config.Babble.VerifySnapshots, "Check App snapshots against the state hash of their Block before restoring them")
+
+	// Tracing
+	cmd.Flags().String("tracing-endpoint", _config.Babble.TracingEndpoint, "IP:Port of an OpenTelemetry collector receiving OTLP traces over gRPC")
+	cmd.Flags().Bool("tracing-insecure", _config.Babble.TracingInsecure, "Disable TLS on the connection to the OpenTelemetry collector")
+	cmd.Flags().Float64("tracing-sample-ratio", _config.Babble.TracingSampleRatio, "Fraction of traces to sample")
 }
 
-// Bind all flags and read the config into viper
+// Bind all flags and read the config into viper. Values are taken, in order of
+// precedence, from the flags set on the command line, from BABBLE_* environment
+// variables, from the config file, and from the defaults of the flags.
 func bindFlagsLoadViper(cmd *cobra.Command, args []string) error {
 	// Register flags with viper. Include flags from this command and all other
 	// persistent flags from the parent
@@ -116,14 +235,29 @@ func bindFlagsLoadViper(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// BABBLE_SERVICE_LISTEN overrides service-listen, etc.
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
 	// first unmarshal to read from CLI flags
 	if err := viper.Unmarshal(_config); err != nil {
 		return err
 	}
 
-	// look for config file in [datadir]/babble.toml (.json, .yaml also work)
-	viper.SetConfigName("babble")               // name of config file (without extension)
-	viper.AddConfigPath(_config.Babble.DataDir) // search root directory
+	if configFile := viper.GetString("config"); configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		// look for config file in [datadir]/babble.toml (.json, .yaml also work)
+		viper.SetConfigName("babble") // name of config file (without extension)
+
+		// the config file of a network subdirectory takes precedence
+		if networkDir, err := _config.Babble.NetworkDataDir(); err == nil && networkDir != _config.Babble.DataDir {
+			viper.AddConfigPath(networkDir)
+		}
+
+		viper.AddConfigPath(_config.Babble.DataDir) // search root directory
+	}
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
@@ -134,10 +268,52 @@ func bindFlagsLoadViper(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if viper.ConfigFileUsed() != "" {
+		if err := checkConfigKeys(cmd, viper.ConfigFileUsed()); err != nil {
+			return err
+		}
+	}
+
 	// second unmarshal to read from config file
 	return viper.Unmarshal(_config)
 }
 
+// loadConfig reads the configuration again, from the flags, the environment
+// and the config file, without modifying the configuration of the running
+// node.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	err := viper.ReadInConfig()
+	if _, ok := err.(viper.ConfigFileNotFoundError); err != nil && !ok {
+		return nil, err
+	}
+
+	if viper.ConfigFileUsed() != "" {
+		if err := checkConfigKeys(cmd, viper.ConfigFileUsed()); err != nil {
+			return nil, err
+		}
+	}
+
+	c := NewDefaultCLIConfig()
+	if err := viper.Unmarshal(c); err != nil {
+		return nil, err
+	}
+
+	return &c.Babble, nil
+}
+
+// reloadOnSighup reloads the configuration of the node every time the process
+// receives a SIGHUP.
+func reloadOnSighup(engine *babble.Babble) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	for range sigCh {
+		if _, _, err := engine.ReloadConfig(); err != nil {
+			_config.Babble.Logger().WithError(err).Error("Cannot reload config")
+		}
+	}
+}
+
 func logLevel(l string) logrus.Level {
 	switch l {
 	case "debug":
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newTestRunCmd resets the global configuration and viper, and returns a run
+// command whose datadir contains a babble.toml file with the given content.
+func newTestRunCmd(t *testing.T, toml string) *cobra.Command {
+	viper.Reset()
+	_config = NewDefaultCLIConfig()
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "babble.toml"), []byte(toml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewRunCmd()
+	if err := cmd.Flags().Set("datadir", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	defer viper.Reset()
+
+	cmd := newTestRunCmd(t, `
+moniker = "file"
+heartbeat = "50ms"
+max-pool = 5
+`)
+
+	// Flags take precedence over the environment, which takes precedence over
+	// the config file, which takes precedence over the defaults.
+	if err := cmd.Flags().Set("moniker", "flag"); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("BABBLE_MONIKER", "env")
+	defer os.Unsetenv("BABBLE_MONIKER")
+	os.Setenv("BABBLE_HEARTBEAT", "70ms")
+	defer os.Unsetenv("BABBLE_HEARTBEAT")
+
+	if err := bindFlagsLoadViper(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c := _config.Babble
+
+	if c.Moniker != "flag" {
+		t.Fatalf("The moniker should be set by the flag, not %q", c.Moniker)
+	}
+	if c.HeartbeatTimeout != 70*time.Millisecond {
+		t.Fatalf("The heartbeat should be set by the environment, not %v", c.HeartbeatTimeout)
+	}
+	if c.MaxPool != 5 {
+		t.Fatalf("The max-pool should be set by the config file, not %d", c.MaxPool)
+	}
+	if c.TCPTimeout != NewDefaultCLIConfig().Babble.TCPTimeout {
+		t.Fatalf("The timeout should keep its default value, not %v", c.TCPTimeout)
+	}
+}
+
+func TestUnknownConfigKeys(t *testing.T) {
+	defer viper.Reset()
+
+	cases := map[string]string{
+		"misspelled key":    `hearbeat = "50ms"`,
+		"config key":        `config = "other.toml"`,
+		"unknown table key": "[service]\nlisten = \"127.0.0.1:8000\"",
+	}
+
+	for name, toml := range cases {
+		cmd := newTestRunCmd(t, toml)
+
+		err := bindFlagsLoadViper(cmd, nil)
+		if err == nil {
+			t.Fatalf("%s: the config file should be rejected", name)
+		}
+		if !strings.Contains(err.Error(), "Unknown keys") {
+			t.Fatalf("%s: the error should list the unknown keys, not %v", name, err)
+		}
+	}
+
+	cmd := newTestRunCmd(t, `heartbeat = "50ms"`)
+	if err := bindFlagsLoadViper(cmd, nil); err != nil {
+		t.Fatalf("A config file with known keys should be accepted: %v", err)
+	}
+}
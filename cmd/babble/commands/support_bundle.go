@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	supportBundleDataDir string
+	supportBundleOut     string
+)
+
+// supportBundleFiles are the files of the data directory, besides the
+// diagnostics, which are added to a support bundle. Keys, the database, and
+// the configuration file, which may contain secrets, are never included.
+var supportBundleFiles = []string{
+	config.DefaultGenesisFile,
+	"peers.json",
+	"peers.genesis.json",
+}
+
+// NewSupportBundleCmd produces a SupportBundleCmd which archives the
+// diagnostics written by a node.
+func NewSupportBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Archive the diagnostics of a node to attach to a bug report",
+		Long: `Archive the diagnostics of a node to attach to a bug report
+
+A node writes a diagnostics file to [datadir]/diagnostics when it panics or
+when it is suspended because consensus is stalled. The file contains the state
+of the node, the last rounds, the undetermined events, the peer table, and the
+configuration with its secrets redacted.
+
+The archive contains the diagnostics files, the genesis file and the peer
+files. The keys, the database and babble.toml are left out.`,
+		Args: cobra.NoArgs,
+		RunE: supportBundle,
+	}
+
+	cmd.Flags().StringVar(&supportBundleDataDir, "datadir", _config.Babble.DataDir, "Top-level directory for configuration and data")
+	cmd.Flags().StringVar(&supportBundleOut, "out", "babble-support.tar.gz", "Path of the archive")
+
+	return cmd
+}
+
+func supportBundle(cmd *cobra.Command, args []string) error {
+	conf := config.NewDefaultConfig()
+	conf.SetDataDir(supportBundleDataDir)
+
+	diagnostics, err := filepath.Glob(filepath.Join(conf.DiagnosticsDir(), "*.json"))
+	if err != nil {
+		return err
+	}
+
+	if len(diagnostics) == 0 {
+		fmt.Printf("No diagnostics in %s\n", conf.DiagnosticsDir())
+	}
+
+	paths := diagnostics
+	for _, name := range supportBundleFiles {
+		path := filepath.Join(conf.DataDir, name)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+
+	if err := writeTarGz(supportBundleOut, conf.DataDir, paths); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d files to %s\n", len(paths), supportBundleOut)
+
+	return nil
+}
+
+// writeTarGz archives the files, with their paths relative to base, into a
+// gzipped tarball.
+func writeTarGz(out string, base string, paths []string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	for _, path := range paths {
+		if err := addToTar(tw, base, path); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	if err := gw.Close(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func addToTar(tw *tar.Writer, base string, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	name, err := filepath.Rel(base, path)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,382 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/genesis"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// dockerNodeIP and dockerClientIP are the first addresses of the nodes and
+	// of the Dummy clients in the docker network, as in the demo.
+	dockerNodeIP   = "172.77.5.1"
+	dockerClientIP = "172.77.10.1"
+	dockerSubnet   = "172.77.0.0/16"
+	dockerDataDir  = "/.babble"
+)
+
+var (
+	testnetNodes       int
+	testnetOut         string
+	testnetChainID     string
+	testnetHost        string
+	testnetPort        int
+	testnetServicePort int
+	testnetIncrementIP bool
+	testnetDocker      bool
+	testnetSystemd     bool
+	testnetBinary      string
+	testnetForce       bool
+)
+
+// testnetNode is the configuration of a node of the testnet.
+type testnetNode struct {
+	moniker     string
+	dir         string
+	dataDir     string
+	pubKey      string
+	listen      string
+	proxyListen string
+	client      string
+	service     string
+}
+
+// NewTestnetCmd produces a TestnetCmd which generates the configuration of a
+// local or cloud test network.
+func NewTestnetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "testnet",
+		Short: "Generate the keys, peers, genesis and config files of a testnet",
+		Long: `Generate the configuration of a testnet
+
+Every node gets a directory, [out]/node1 to [out]/node[nodes], with a private
+key, the peers.json, peers.genesis.json and genesis.json files of the network,
+and a babble.toml config file. The directory is the datadir of the node.
+
+By default all the nodes run on the same host and use different ports. With
+--increment-ip, the last byte of the host address is incremented for every
+node, and all the nodes use the same ports. --docker writes a docker-compose
+file running every node next to a Dummy client, in the network of the demo.
+--systemd writes a systemd unit for every node.`,
+		RunE: testnet,
+	}
+
+	cmd.Flags().IntVar(&testnetNodes, "nodes", 4, "Number of nodes")
+	cmd.Flags().StringVar(&testnetOut, "out", "testnet", "Directory where the configuration of the nodes will be written")
+	cmd.Flags().StringVar(&testnetChainID, "chain-id", "babble-testnet", "Name of the network")
+	cmd.Flags().StringVar(&testnetHost, "host", "127.0.0.1", "IP of the first node. Defaults to "+dockerNodeIP+" with --docker")
+	cmd.Flags().IntVar(&testnetPort, "port", 1337, "Gossip port of the first node. The proxy and client ports follow it")
+	cmd.Flags().IntVar(&testnetServicePort, "service-port", 8000, "HTTP service port of the first node")
+	cmd.Flags().BoolVar(&testnetIncrementIP, "increment-ip", false, "Give every node its own IP instead of its own ports")
+	cmd.Flags().BoolVar(&testnetDocker, "docker", false, "Write a docker-compose.yml file. Implies --increment-ip")
+	cmd.Flags().BoolVar(&testnetSystemd, "systemd", false, "Write a systemd unit for every node")
+	cmd.Flags().StringVar(&testnetBinary, "binary", "/usr/local/bin/babble", "Path of the babble binary in the systemd units")
+	cmd.Flags().BoolVar(&testnetForce, "force", false, "Overwrite an existing testnet")
+
+	return cmd
+}
+
+func testnet(cmd *cobra.Command, args []string) error {
+	if testnetNodes < 1 {
+		return fmt.Errorf("--nodes must be at least 1")
+	}
+
+	if testnetDocker {
+		testnetIncrementIP = true
+		if !cmd.Flags().Changed("host") {
+			testnetHost = dockerNodeIP
+		}
+	}
+
+	if _, err := os.Stat(testnetOut); err == nil && !testnetForce {
+		return fmt.Errorf("The output directory already exists: %s", testnetOut)
+	}
+
+	out, err := filepath.Abs(testnetOut)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := testnetNodeConfigs(out)
+	if err != nil {
+		return err
+	}
+
+	// The testnet is written to a temporary directory, renamed to the output
+	// directory once complete, so that a failure does not leave a partial
+	// testnet behind. The configs still refer to the final directories.
+	tmp := out + ".init"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, n := range nodes {
+		n.dir = filepath.Join(tmp, n.moniker)
+	}
+
+	peerList := []*peers.Peer{}
+
+	for _, n := range nodes {
+		if err := os.MkdirAll(n.dir, 0700); err != nil {
+			return fmt.Errorf("Creating node directory: %s", err)
+		}
+
+		key, err := keys.GenerateECDSAKey()
+		if err != nil {
+			return fmt.Errorf("Generating key: %s", err)
+		}
+
+		if err := keys.NewSimpleKeyfile(filepath.Join(n.dir, "priv_key")).WriteKey(key); err != nil {
+			return fmt.Errorf("Writing private key: %s", err)
+		}
+
+		n.pubKey = keys.PublicKeyHex(&key.PublicKey)
+
+		if err := ioutil.WriteFile(filepath.Join(n.dir, "key.pub"), []byte(n.pubKey), 0600); err != nil {
+			return fmt.Errorf("Writing public key: %s", err)
+		}
+
+		peerList = append(peerList, peers.NewPeer(n.pubKey, n.listen, n.moniker))
+	}
+
+	peerSet := peers.NewPeerSet(peerList)
+
+	g := genesis.NewGenesis(testnetChainID, peerSet, "")
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	for _, n := range nodes {
+		if err := peers.NewJSONPeerSet(n.dir, true).Write(peerList); err != nil {
+			return fmt.Errorf("Writing peers: %s", err)
+		}
+
+		if err := peers.NewJSONPeerSet(n.dir, false).Write(peerList); err != nil {
+			return fmt.Errorf("Writing genesis peers: %s", err)
+		}
+
+		if err := g.Write(filepath.Join(n.dir, "genesis.json")); err != nil {
+			return fmt.Errorf("Writing genesis document: %s", err)
+		}
+
+		if err := writeTestnetConfig(n); err != nil {
+			return err
+		}
+
+		if testnetSystemd {
+			if err := writeTestnetUnit(n); err != nil {
+				return err
+			}
+		}
+	}
+
+	if testnetDocker {
+		if err := writeTestnetCompose(tmp, nodes); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(out); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, out); err != nil {
+		return fmt.Errorf("Writing testnet: %s", err)
+	}
+
+	networkID, err := g.NetworkID()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Your testnet of %d nodes has been saved to: %s\n", len(nodes), out)
+	fmt.Printf("Network ID: %s\n", networkID)
+
+	for _, n := range nodes {
+		fmt.Printf("%s: listen %s, service %s\n", n.moniker, n.listen, n.service)
+	}
+
+	return nil
+}
+
+// testnetNodeConfigs computes the directories and addresses of the nodes.
+// Without --increment-ip, the ports of node i are offset by 10*i from the ports
+// of the first node, and its service port by i. In docker, every node is
+// paired with a Dummy client with its own IP.
+func testnetNodeConfigs(out string) ([]*testnetNode, error) {
+	nodeIP := net.ParseIP(testnetHost).To4()
+	if nodeIP == nil {
+		return nil, fmt.Errorf("--host must be an IPv4 address, not %q", testnetHost)
+	}
+
+	clientIP := nodeIP
+	if testnetDocker {
+		clientIP = net.ParseIP(dockerClientIP).To4()
+	}
+
+	if testnetIncrementIP && int(nodeIP[3])+testnetNodes > 255 {
+		return nil, fmt.Errorf("Not enough addresses after %s for %d nodes", testnetHost, testnetNodes)
+	}
+
+	nodes := make([]*testnetNode, 0, testnetNodes)
+
+	for i := 0; i < testnetNodes; i++ {
+		ip, cip := nodeIP, clientIP
+		port, servicePort := testnetPort+10*i, testnetServicePort+i
+
+		if testnetIncrementIP {
+			ip, cip = nextIP(nodeIP, i), nextIP(clientIP, i)
+			port, servicePort = testnetPort, testnetServicePort
+		}
+
+		if port+2 > 65535 || servicePort > 65535 {
+			return nil, fmt.Errorf("Not enough ports after %d for %d nodes", testnetPort, testnetNodes)
+		}
+
+		moniker := fmt.Sprintf("node%d", i+1)
+		dir := filepath.Join(out, moniker)
+
+		dataDir := dir
+		if testnetDocker {
+			dataDir = dockerDataDir
+		}
+
+		nodes = append(nodes, &testnetNode{
+			moniker:     moniker,
+			dir:         dir,
+			dataDir:     dataDir,
+			listen:      net.JoinHostPort(ip.String(), strconv.Itoa(port)),
+			proxyListen: net.JoinHostPort(ip.String(), strconv.Itoa(port+1)),
+			client:      net.JoinHostPort(cip.String(), strconv.Itoa(port+2)),
+			service:     net.JoinHostPort(ip.String(), strconv.Itoa(servicePort)),
+		})
+	}
+
+	return nodes, nil
+}
+
+// nextIP returns the IPv4 address n after ip, within the same /24 network.
+func nextIP(ip net.IP, n int) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	next[3] += byte(n)
+	return next
+}
+
+// writeTestnetConfig writes the babble.toml config file of a node, with the
+// default value of every other option.
+func writeTestnetConfig(n *testnetNode) error {
+	flags := NewRunCmd().Flags()
+
+	for flag, value := range map[string]string{
+		"datadir":        n.dataDir,
+		"db":             filepath.Join(n.dataDir, config.DefaultBadgerFile),
+		"moniker":        n.moniker,
+		"listen":         n.listen,
+		"proxy-listen":   n.proxyListen,
+		"client-connect": n.client,
+		"service-listen": n.service,
+	} {
+		if err := flags.Set(flag, value); err != nil {
+			return err
+		}
+	}
+
+	content, err := defaultConfigFile(flags, "toml")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(n.dir, "babble.toml"), content, 0600); err != nil {
+		return fmt.Errorf("Writing config file: %s", err)
+	}
+
+	return nil
+}
+
+// writeTestnetUnit writes the babble.service systemd unit of a node.
+func writeTestnetUnit(n *testnetNode) error {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "[Unit]\n")
+	fmt.Fprintf(&buf, "Description=Babble %s (%s)\n", n.moniker, testnetChainID)
+	fmt.Fprintf(&buf, "After=network-online.target\n")
+	fmt.Fprintf(&buf, "Wants=network-online.target\n\n")
+	fmt.Fprintf(&buf, "[Service]\n")
+	fmt.Fprintf(&buf, "ExecStart=%s run --datadir %s\n", testnetBinary, n.dataDir)
+	fmt.Fprintf(&buf, "ExecReload=/bin/kill -HUP $MAINPID\n")
+	fmt.Fprintf(&buf, "Restart=on-failure\n")
+	fmt.Fprintf(&buf, "LimitNOFILE=65536\n\n")
+	fmt.Fprintf(&buf, "[Install]\n")
+	fmt.Fprintf(&buf, "WantedBy=multi-user.target\n")
+
+	if err := ioutil.WriteFile(filepath.Join(n.dir, "babble.service"), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("Writing systemd unit: %s", err)
+	}
+
+	return nil
+}
+
+// writeTestnetCompose writes a docker-compose.yml file running every node with
+// a Dummy client, like the demo. The node directories are mounted as the
+// datadirs of the containers, and the HTTP services are published on the
+// host, from the service port upwards.
+func writeTestnetCompose(out string, nodes []*testnetNode) error {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "# Babble testnet %s\n", testnetChainID)
+	fmt.Fprintf(&buf, "version: \"3\"\n\n")
+	fmt.Fprintf(&buf, "services:\n")
+
+	for i, n := range nodes {
+		nodeIP, _, _ := net.SplitHostPort(n.listen)
+		clientIP, _, _ := net.SplitHostPort(n.client)
+
+		fmt.Fprintf(&buf, "  %s:\n", n.moniker)
+		fmt.Fprintf(&buf, "    image: mosaicnetworks/babble:latest\n")
+		fmt.Fprintf(&buf, "    container_name: %s\n", n.moniker)
+		fmt.Fprintf(&buf, "    command: [\"run\"]\n")
+		fmt.Fprintf(&buf, "    volumes:\n")
+		fmt.Fprintf(&buf, "      - ./%s:%s\n", n.moniker, dockerDataDir)
+		fmt.Fprintf(&buf, "    ports:\n")
+		fmt.Fprintf(&buf, "      - \"%d:%d\"\n", testnetServicePort+i, testnetServicePort)
+		fmt.Fprintf(&buf, "    networks:\n")
+		fmt.Fprintf(&buf, "      babblenet:\n")
+		fmt.Fprintf(&buf, "        ipv4_address: %s\n\n", nodeIP)
+
+		fmt.Fprintf(&buf, "  client%d:\n", i+1)
+		fmt.Fprintf(&buf, "    image: mosaicnetworks/dummy:latest\n")
+		fmt.Fprintf(&buf, "    container_name: client%d\n", i+1)
+		fmt.Fprintf(&buf, "    command:\n")
+		fmt.Fprintf(&buf, "      - --name=client %d\n", i+1)
+		fmt.Fprintf(&buf, "      - --client-listen=%s\n", n.client)
+		fmt.Fprintf(&buf, "      - --proxy-connect=%s\n", n.proxyListen)
+		fmt.Fprintf(&buf, "      - --discard\n")
+		fmt.Fprintf(&buf, "    networks:\n")
+		fmt.Fprintf(&buf, "      babblenet:\n")
+		fmt.Fprintf(&buf, "        ipv4_address: %s\n\n", clientIP)
+	}
+
+	fmt.Fprintf(&buf, "networks:\n")
+	fmt.Fprintf(&buf, "  babblenet:\n")
+	fmt.Fprintf(&buf, "    ipam:\n")
+	fmt.Fprintf(&buf, "      config:\n")
+	fmt.Fprintf(&buf, "        - subnet: %s\n", dockerSubnet)
+
+	if err := ioutil.WriteFile(filepath.Join(out, "docker-compose.yml"), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("Writing docker-compose file: %s", err)
+	}
+
+	return nil
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	_ "net/http/pprof"
 	"os"
 
 	cmd "github.com/mosaicnetworks/babble/cmd/babble/commands"
@@ -13,6 +12,14 @@ func main() {
 	rootCmd.AddCommand(
 		cmd.VersionCmd,
 		cmd.NewKeygenCmd(),
+		cmd.NewGraphCmd(),
+		cmd.NewConfigCmd(),
+		cmd.NewGenesisCmd(),
+		cmd.NewTestnetCmd(),
+		cmd.NewReplayCmd(),
+		cmd.NewDBCmd(),
+		cmd.NewBenchCmd(),
+		cmd.NewSupportBundleCmd(),
 		cmd.NewRunCmd())
 
 	//Do not print usage when error occurs
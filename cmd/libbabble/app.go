@@ -0,0 +1,82 @@
+package main
+
+/*
+#include <stdlib.h>
+#include "callbacks.h"
+
+static char* call_commit(babble_commit_callback cb, void* ctx, const char* block, int block_len, int* state_hash_len) {
+	return cb(ctx, block, block_len, state_hash_len);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/proxy"
+)
+
+// cApp implements the ProxyHandler interface with the callbacks of a C
+// application.
+type cApp struct {
+	commit C.babble_commit_callback
+	ctx    unsafe.Pointer
+}
+
+// CommitHandler implements the ProxyHandler interface. It encodes the Block
+// with JSON to pass it to the application, and accepts its
+// InternalTransactions.
+func (a *cApp) CommitHandler(block hashgraph.Block) (proxy.CommitResponse, error) {
+	blockBytes, err := block.Marshal()
+	if err != nil {
+		return proxy.CommitResponse{}, err
+	}
+
+	cBlock := C.CBytes(blockBytes)
+	defer C.free(cBlock)
+
+	var stateHashLen C.int
+	cStateHash := C.call_commit(a.commit, a.ctx, (*C.char)(cBlock), C.int(len(blockBytes)), &stateHashLen)
+	if cStateHash != nil {
+		defer C.free(unsafe.Pointer(cStateHash))
+	}
+
+	if stateHashLen < 0 {
+		return proxy.CommitResponse{}, fmt.Errorf("Commit callback failed on block %d", block.Index())
+	}
+
+	receipts := []hashgraph.InternalTransactionReceipt{}
+	for _, itx := range block.InternalTransactions() {
+		receipts = append(receipts, itx.AsAccepted())
+	}
+
+	response := proxy.CommitResponse{
+		StateHash:                   []byte{},
+		InternalTransactionReceipts: receipts,
+	}
+
+	if cStateHash != nil && stateHashLen > 0 {
+		response.StateHash = C.GoBytes(unsafe.Pointer(cStateHash), stateHashLen)
+	}
+
+	return response, nil
+}
+
+// SnapshotHandler implements the ProxyHandler interface. Snapshots are not
+// supported yet, so C applications cannot serve fast-forwards.
+func (a *cApp) SnapshotHandler(blockIndex int) ([]byte, error) {
+	return []byte{}, nil
+}
+
+// RestoreHandler implements the ProxyHandler interface.
+func (a *cApp) RestoreHandler(snapshot []byte) ([]byte, error) {
+	return []byte{}, nil
+}
+
+// StateChangeHandler implements the ProxyHandler interface.
+func (a *cApp) StateChangeHandler(state state.State) error {
+	return nil
+}
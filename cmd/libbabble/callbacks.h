@@ -0,0 +1,18 @@
+#ifndef BABBLE_CALLBACKS_H
+#define BABBLE_CALLBACKS_H
+
+#include <stddef.h>
+
+/*
+ * babble_commit_callback is called by Babble, from its own thread, to commit
+ * a Block to the application. The Block is encoded in JSON, and is only valid
+ * during the call. The callback returns the state hash of the application
+ * after applying the Block, allocated with malloc, and sets state_hash_len to
+ * its length. Babble frees the state hash. The callback may return NULL with a
+ * state_hash_len of 0 for an empty state hash, or NULL with a negative
+ * state_hash_len to report an error. The InternalTransactions of the Block are
+ * accepted.
+ */
+typedef char* (*babble_commit_callback)(void* ctx, const char* block, int block_len, int* state_hash_len);
+
+#endif
@@ -0,0 +1,251 @@
+// Command libbabble builds Babble as a C shared library, so that C, C++, Rust,
+// or Python applications can embed a Babble node without a socket proxy:
+//
+//	go build -buildmode=c-shared -o libbabble.so ./cmd/libbabble
+//
+// The build also produces libbabble.h, which declares the functions below.
+// Nodes are referred to by integer handles, because C code cannot hold Go
+// pointers. Strings returned by the library are allocated with malloc, and
+// must be released with babble_free.
+package main
+
+/*
+#include <stdlib.h>
+#include "callbacks.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/mosaicnetworks/babble/src/babble"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/proxy/inmem"
+	"github.com/spf13/viper"
+)
+
+var (
+	nodesLock  sync.Mutex
+	nodes      = make(map[C.int]*babble.Babble)
+	nextHandle = C.int(1)
+
+	lastErrorLock sync.Mutex
+	lastError     string
+)
+
+func main() {}
+
+// setError records the error returned by babble_last_error.
+func setError(err error) {
+	lastErrorLock.Lock()
+	defer lastErrorLock.Unlock()
+	lastError = err.Error()
+}
+
+func getNode(handle C.int) (*babble.Babble, bool) {
+	nodesLock.Lock()
+	defer nodesLock.Unlock()
+
+	engine, ok := nodes[handle]
+	if !ok {
+		setError(fmt.Errorf("Unknown node handle %d", handle))
+	}
+	return engine, ok
+}
+
+// toJSON returns the JSON encoding of v in a C string, or NULL.
+func toJSON(v interface{}) *C.char {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		setError(err)
+		return nil
+	}
+	return C.CString(buf.String())
+}
+
+// babble_new creates a node from the configuration in datadir, like babble run
+// with --datadir, and the babble.toml file of datadir if there is one. Blocks
+// are committed with the commit callback, which receives ctx. It returns a
+// handle to the node, or 0 on error.
+//
+//export babble_new
+func babble_new(datadir *C.char, commit C.babble_commit_callback, ctx unsafe.Pointer) C.int {
+	if commit == nil {
+		setError(fmt.Errorf("No commit callback"))
+		return 0
+	}
+
+	conf := config.NewDefaultConfig()
+	conf.SetDataDir(C.GoString(datadir))
+
+	v := viper.New()
+	v.SetConfigName("babble")
+	v.AddConfigPath(conf.DataDir)
+
+	if err := v.ReadInConfig(); err == nil {
+		if err := v.Unmarshal(conf); err != nil {
+			setError(fmt.Errorf("Reading %s: %v", v.ConfigFileUsed(), err))
+			return 0
+		}
+	} else if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+		setError(fmt.Errorf("Loading config file: %v", err))
+		return 0
+	}
+
+	conf.Proxy = inmem.NewInmemProxy(&cApp{commit: commit, ctx: ctx}, conf.ModuleLogger("proxy"))
+
+	engine := babble.NewBabble(conf)
+	if err := engine.Init(); err != nil {
+		setError(fmt.Errorf("Cannot initialize engine: %v", err))
+		return 0
+	}
+
+	nodesLock.Lock()
+	defer nodesLock.Unlock()
+
+	handle := nextHandle
+	nextHandle++
+	nodes[handle] = engine
+
+	return handle
+}
+
+// babble_run starts the node in the background. It returns 0, or -1 on error.
+//
+//export babble_run
+func babble_run(handle C.int) C.int {
+	engine, ok := getNode(handle)
+	if !ok {
+		return -1
+	}
+
+	go engine.Run()
+
+	return 0
+}
+
+// babble_submit_tx submits a transaction to the node for consensus ordering.
+// It returns 0, or -1 on error.
+//
+//export babble_submit_tx
+func babble_submit_tx(handle C.int, tx unsafe.Pointer, txLen C.int) C.int {
+	engine, ok := getNode(handle)
+	if !ok {
+		return -1
+	}
+
+	if err := engine.Node.SubmitTx(C.GoBytes(tx, txLen)); err != nil {
+		setError(err)
+		return -1
+	}
+
+	return 0
+}
+
+// babble_stats returns the stats of the node in JSON, like the /v1/stats
+// endpoint of the HTTP service, or NULL on error.
+//
+//export babble_stats
+func babble_stats(handle C.int) *C.char {
+	engine, ok := getNode(handle)
+	if !ok {
+		return nil
+	}
+	return toJSON(engine.Node.Stats())
+}
+
+// babble_peers returns the current peers of the node in JSON, or NULL on
+// error.
+//
+//export babble_peers
+func babble_peers(handle C.int) *C.char {
+	engine, ok := getNode(handle)
+	if !ok {
+		return nil
+	}
+	return toJSON(engine.Node.GetPeers())
+}
+
+// babble_pub_key returns the public key of the validator in hex, or NULL on
+// error.
+//
+//export babble_pub_key
+func babble_pub_key(handle C.int) *C.char {
+	engine, ok := getNode(handle)
+	if !ok {
+		return nil
+	}
+	return C.CString(engine.Node.GetPubKey())
+}
+
+// babble_leave politely leaves the network, waiting for the validator to be
+// removed from the validator-set, and shuts the node down. The handle is
+// released. It returns 0, or -1 on error.
+//
+//export babble_leave
+func babble_leave(handle C.int) C.int {
+	engine, ok := releaseNode(handle)
+	if !ok {
+		return -1
+	}
+
+	if err := engine.Node.Leave(); err != nil {
+		setError(err)
+		return -1
+	}
+
+	return 0
+}
+
+// babble_shutdown shuts the node down, without leaving the validator-set, and
+// releases the handle. It returns 0, or -1 on error.
+//
+//export babble_shutdown
+func babble_shutdown(handle C.int) C.int {
+	engine, ok := releaseNode(handle)
+	if !ok {
+		return -1
+	}
+
+	engine.Node.Shutdown()
+
+	return 0
+}
+
+func releaseNode(handle C.int) (*babble.Babble, bool) {
+	engine, ok := getNode(handle)
+	if !ok {
+		return nil, false
+	}
+
+	nodesLock.Lock()
+	delete(nodes, handle)
+	nodesLock.Unlock()
+
+	return engine, true
+}
+
+// babble_last_error returns the error of the last call that failed, or NULL.
+// It is shared by all the threads.
+//
+//export babble_last_error
+func babble_last_error() *C.char {
+	lastErrorLock.Lock()
+	defer lastErrorLock.Unlock()
+
+	if lastError == "" {
+		return nil
+	}
+	return C.CString(lastError)
+}
+
+// babble_free releases a string returned by the library.
+//
+//export babble_free
+func babble_free(p unsafe.Pointer) {
+	C.free(p)
+}
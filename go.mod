@@ -7,6 +7,8 @@ require (
 	github.com/btcsuite/fastsha256 v0.0.0-20160815193821-637e65642941 // indirect
 	github.com/dgraph-io/badger v1.6.0
 	github.com/gammazero/nexus/v3 v3.0.0
+	github.com/gorilla/websocket v1.4.1
+	github.com/graphql-go/graphql v0.7.9
 	github.com/jonknight73/badger v0.0.0-20200218142835-fa9c019859f6
 	github.com/konsorten/go-windows-terminal-sequences v1.0.2 // indirect
 	github.com/libp2p/go-tcp-transport v0.1.1 // indirect
@@ -16,14 +18,20 @@ require (
 	github.com/onsi/gomega v1.9.0 // indirect
 	github.com/pion/datachannel v1.4.14
 	github.com/pion/webrtc/v2 v2.2.0
+	github.com/prometheus/client_golang v1.7.1
 	github.com/rifflock/lfshook v0.0.0-20180920164130-b9218ef580f5
-	github.com/sirupsen/logrus v1.2.0
+	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/afero v1.2.2 // indirect
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.3.2
 	github.com/ugorji/go/codec v1.1.7
 	github.com/x-cray/logrus-prefixed-formatter v0.5.2
+	go.opentelemetry.io/otel v0.20.0
+	go.opentelemetry.io/otel/exporters/otlp v0.20.0
+	go.opentelemetry.io/otel/sdk v0.20.0
+	go.opentelemetry.io/otel/trace v0.20.0
 	golang.org/x/mobile v0.0.0-20200212152714-2b26a4705d24 // indirect
 	golang.org/x/tools v0.0.0-20200410040751-3bd20875a2eb // indirect
 )
@@ -0,0 +1,44 @@
+// Package babble is the public API to embed a Babble node in a Go application.
+//
+// The packages under src/ are the internals of Babble, which change between
+// releases as the implementation evolves. This package exposes the subset that
+// applications need: the configuration, the Node, the interfaces through which
+// Babble uses a Store, a Transport and an application Proxy, and the types of
+// the Blocks committed to the application. Applications should import it
+// rather than the packages under src/.
+//
+// Stability
+//
+// This package follows semantic versioning. Within a major version, the
+// exported identifiers of this package are not removed or renamed, the
+// signatures of its functions and methods do not change, and the methods of
+// its interfaces are neither removed nor added, so that custom
+// implementations keep compiling. New functions, methods, options and
+// configuration fields may be added in minor versions. The type aliases give
+// access to the fields and methods of the underlying types of src/, but only
+// the ones documented here are covered by these guarantees.
+//
+// Usage
+//
+// An application implements the ProxyHandler interface, whose callbacks are
+// called by Babble to commit Blocks, and submits transactions through the
+// InmemProxy that wraps it:
+//
+//	proxy := babble.NewInmemProxy(handler, nil)
+//
+//	conf := babble.DefaultConfig()
+//	conf.SetDataDir("/var/lib/myapp/babble")
+//
+//	node, err := babble.NewNode(conf, proxy)
+//	if err != nil {
+//		return err
+//	}
+//
+//	go node.Run()
+//	defer node.Leave()
+//
+//	proxy.SubmitTx([]byte("tx"))
+//
+// The Store and the Transport are chosen by the configuration, unless custom
+// implementations are given with the WithStore and WithTransport options.
+package babble
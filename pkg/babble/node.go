@@ -0,0 +1,115 @@
+package babble
+
+import (
+	"github.com/mosaicnetworks/babble/src/babble"
+)
+
+// Node is a Babble node embedded in an application.
+type Node struct {
+	engine *babble.Babble
+}
+
+// Option customises a Node before it is initialised.
+type Option func(*babble.Babble)
+
+// WithStore makes the Node use a Store instead of the one selected by the
+// configuration.
+func WithStore(store Store) Option {
+	return func(b *babble.Babble) {
+		b.Store = store
+	}
+}
+
+// WithTransport makes the Node use a Transport instead of the one selected by
+// the configuration.
+func WithTransport(trans Transport) Option {
+	return func(b *babble.Babble) {
+		b.Transport = trans
+	}
+}
+
+// NewNode creates and initialises a Node which commits Blocks to an
+// application through a proxy. The key and the peers of the Node are read
+// from the data directory of the configuration, unless the key is set in the
+// configuration. The configuration must not be modified afterwards.
+func NewNode(conf *Config, proxy AppProxy, opts ...Option) (*Node, error) {
+	conf.Proxy = proxy
+
+	engine := babble.NewBabble(conf)
+
+	for _, opt := range opts {
+		opt(engine)
+	}
+
+	if err := engine.Init(); err != nil {
+		return nil, err
+	}
+
+	return &Node{engine: engine}, nil
+}
+
+// Run runs the Node until it is shut down, or leaves the network.
+func (n *Node) Run() {
+	n.engine.Run()
+}
+
+// Leave makes the Node politely leave the network, by asking the other
+// validators to remove it from the validator-set, and shuts it down.
+func (n *Node) Leave() error {
+	return n.engine.Node.Leave()
+}
+
+// Shutdown stops the Node without leaving the network, which keeps counting it
+// as a validator.
+func (n *Node) Shutdown() {
+	n.engine.Node.Shutdown()
+}
+
+// State returns the current state of the Node.
+func (n *Node) State() State {
+	return n.engine.Node.GetState()
+}
+
+// ID returns the numeric ID of the Node, derived from its public key.
+func (n *Node) ID() uint32 {
+	return n.engine.Node.GetID()
+}
+
+// PubKey returns the hexadecimal public key of the Node.
+func (n *Node) PubKey() string {
+	return n.engine.Node.GetPubKey()
+}
+
+// Block returns a committed Block by index.
+func (n *Node) Block(index int) (*Block, error) {
+	return n.engine.Node.GetBlock(index)
+}
+
+// LastBlockIndex returns the index of the last Block, or -1 if there is none.
+func (n *Node) LastBlockIndex() int {
+	return n.engine.Node.GetLastBlockIndex()
+}
+
+// Peers returns the current validators.
+func (n *Node) Peers() []*Peer {
+	return n.engine.Node.GetPeers()
+}
+
+// SubmitTx submits a transaction directly to the Node, instead of through the
+// AppProxy.
+func (n *Node) SubmitTx(tx []byte) error {
+	return n.engine.Node.SubmitTx(tx)
+}
+
+// AppWatermark returns the index of the last Block acknowledged with
+// AdvanceAppWatermark, or -1.
+func (n *Node) AppWatermark() (int, error) {
+	return n.engine.Node.GetAppWatermark()
+}
+
+// AdvanceAppWatermark acknowledges the Blocks up to index, once the App has
+// persisted their effects. When the Node restarts, it delivers the Blocks
+// after the watermark again, but not those up to it.
+func (n *Node) AdvanceAppWatermark(index int) error {
+	return n.engine.Node.AdvanceAppWatermark(index)
+}
@@ -0,0 +1,148 @@
+package babble_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/pkg/babble"
+)
+
+// handler is a ProxyHandler written against the public API only.
+type handler struct {
+	sync.Mutex
+	txs [][]byte
+}
+
+func (h *handler) CommitHandler(block babble.Block) (babble.CommitResponse, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.txs = append(h.txs, block.Transactions()...)
+
+	receipts := []babble.InternalTransactionReceipt{}
+	for _, it := range block.InternalTransactions() {
+		receipts = append(receipts, it.AsAccepted())
+	}
+
+	return babble.CommitResponse{
+		StateHash:                   []byte("statehash"),
+		InternalTransactionReceipts: receipts,
+	}, nil
+}
+
+func (h *handler) SnapshotHandler(blockIndex int) ([]byte, error) {
+	return []byte("snapshot"), nil
+}
+
+func (h *handler) RestoreHandler(snapshot []byte) ([]byte, error) {
+	return []byte("statehash"), nil
+}
+
+func (h *handler) StateChangeHandler(state babble.State) error {
+	return nil
+}
+
+func (h *handler) committed() [][]byte {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.txs
+}
+
+func TestNode(t *testing.T) {
+	dir := "test_data"
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+
+	addrs := make([]string, 2)
+	transports := make([]*babble.InmemTransport, 2)
+	for i := range transports {
+		addrs[i], transports[i] = babble.NewInmemTransport("")
+	}
+	transports[0].Connect(addrs[1], transports[1])
+	transports[1].Connect(addrs[0], transports[0])
+
+	confs := make([]*babble.Config, 2)
+	peerSlice := []*babble.Peer{}
+
+	for i := range confs {
+		key, err := babble.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		moniker := fmt.Sprintf("node%d", i)
+		peerSlice = append(peerSlice, babble.NewPeer(babble.PublicKeyHex(&key.PublicKey), addrs[i], moniker))
+
+		conf := babble.DefaultConfig()
+		conf.SetDataDir(filepath.Join(dir, moniker))
+		conf.Key = key
+		conf.Moniker = moniker
+		conf.NoService = true
+		conf.HeartbeatTimeout = 10 * time.Millisecond
+		conf.LogLevel = "error"
+		confs[i] = conf
+	}
+
+	handlers := make([]*handler, 2)
+	proxies := make([]*babble.InmemProxy, 2)
+	nodes := make([]*babble.Node, 2)
+
+	for i, conf := range confs {
+		if err := os.MkdirAll(conf.DataDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := babble.WritePeers(conf.DataDir, peerSlice); err != nil {
+			t.Fatal(err)
+		}
+
+		handlers[i] = &handler{}
+		proxies[i] = babble.NewInmemProxy(handlers[i], conf.Logger().WithField("node", i))
+
+		node, err := babble.NewNode(conf, proxies[i],
+			babble.WithStore(babble.NewInmemStore(conf.CacheSize)),
+			babble.WithTransport(transports[i]),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i] = node
+
+		go node.Run()
+		defer node.Shutdown()
+	}
+
+	if len(nodes[0].Peers()) != 2 || nodes[0].PubKey() != peerSlice[0].PubKeyHex {
+		t.Fatalf("node0 should have the peers of peers.json, not %v", nodes[0].Peers())
+	}
+
+	go proxies[0].SubmitTx([]byte("the test transaction"))
+
+	timeout := time.After(10 * time.Second)
+	for _, h := range handlers {
+		for len(h.committed()) == 0 {
+			select {
+			case <-timeout:
+				t.Fatal("Timeout waiting for the transaction to be committed")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+
+		if txs := h.committed(); len(txs) != 1 || !bytes.Equal(txs[0], []byte("the test transaction")) {
+			t.Fatalf("The committed transactions should be the test transaction, not %q", txs)
+		}
+	}
+
+	if nodes[1].LastBlockIndex() < 0 {
+		t.Fatal("node1 should have a Block")
+	}
+
+	if _, err := nodes[1].Block(0); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,126 @@
+package babble
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/mosaicnetworks/babble/src/proxy/inmem"
+	"github.com/sirupsen/logrus"
+)
+
+// The implementations of package babble satisfy its interfaces.
+var (
+	_ Store     = (*hashgraph.InmemStore)(nil)
+	_ Store     = (*hashgraph.BadgerStore)(nil)
+	_ Transport = (*InmemTransport)(nil)
+	_ AppProxy  = (*InmemProxy)(nil)
+)
+
+// Config is the configuration of a Node.
+type Config = config.Config
+
+// Store is the interface of the storage of the hashgraph and the Blocks of a
+// Node.
+type Store = hashgraph.Store
+
+// Transport is the interface of the network layer through which a Node
+// communicates with its peers.
+type Transport = net.Transport
+
+// InmemTransport is a Transport that connects Nodes of the same process,
+// without a network, for tests and simulations.
+type InmemTransport = net.InmemTransport
+
+// AppProxy is the interface through which a Node communicates with the
+// application: it receives transactions from it, and commits Blocks to it.
+type AppProxy = proxy.AppProxy
+
+// ProxyHandler is the interface of the callbacks that the InmemProxy calls to
+// commit Blocks to the application, and to notify it of state changes.
+type ProxyHandler = proxy.ProxyHandler
+
+// InmemProxy is the AppProxy of applications that run Babble in the same
+// process. It calls the callbacks of a ProxyHandler.
+type InmemProxy = inmem.InmemProxy
+
+// CommitResponse is the response of the application to a committed Block.
+type CommitResponse = proxy.CommitResponse
+
+// Block is a batch of transactions that reached consensus.
+type Block = hashgraph.Block
+
+// InternalTransaction is a request to add or remove a validator, which the
+// application accepts or refuses when it is committed.
+type InternalTransaction = hashgraph.InternalTransaction
+
+// InternalTransactionReceipt is the decision of the application on an
+// InternalTransaction.
+type InternalTransactionReceipt = hashgraph.InternalTransactionReceipt
+
+// Peer is a participant of a network.
+type Peer = peers.Peer
+
+// PeerSet is a set of Peers, like the validator-set of a network.
+type PeerSet = peers.PeerSet
+
+// State is the state of a Node: Babbling, CatchingUp, Joining, Leaving,
+// Shutdown or Suspended.
+type State = state.State
+
+// DefaultConfig returns the default configuration.
+func DefaultConfig() *Config {
+	return config.NewDefaultConfig()
+}
+
+// NewInmemProxy creates an InmemProxy calling the callbacks of a handler. If
+// logger is nil, a new one is created.
+func NewInmemProxy(handler ProxyHandler, logger *logrus.Entry) *InmemProxy {
+	return inmem.NewInmemProxy(handler, logger)
+}
+
+// NewInmemStore creates a Store that keeps at most cacheSize items of each
+// kind in memory, and nothing on disk.
+func NewInmemStore(cacheSize int) Store {
+	return hashgraph.NewInmemStore(cacheSize)
+}
+
+// NewInmemTransport creates an InmemTransport with an address, or a random one
+// if addr is empty. It returns the address of the Transport, which is
+// connected to the other InmemTransports with their Connect method.
+func NewInmemTransport(addr string) (string, *InmemTransport) {
+	return net.NewInmemTransport(addr)
+}
+
+// NewPeer creates a Peer from its hexadecimal public key, its network address,
+// and its friendly name.
+func NewPeer(pubKeyHex, netAddr, moniker string) *Peer {
+	return peers.NewPeer(pubKeyHex, netAddr, moniker)
+}
+
+// NewPeerSet creates a PeerSet.
+func NewPeerSet(peerSlice []*Peer) *PeerSet {
+	return peers.NewPeerSet(peerSlice)
+}
+
+// WritePeers writes the peers.json file of a data directory, which lists the
+// validators that a Node contacts when it starts.
+func WritePeers(dataDir string, peerSlice []*Peer) error {
+	return peers.NewJSONPeerSet(dataDir, true).Write(peerSlice)
+}
+
+// GenerateKey generates the private key of a Node.
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	return keys.GenerateECDSAKey()
+}
+
+// PublicKeyHex returns the hexadecimal public key of a Node, which identifies
+// it in PeerSets.
+func PublicKeyHex(pub *ecdsa.PublicKey) string {
+	return keys.PublicKeyHex(pub)
+}
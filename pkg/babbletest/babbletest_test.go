@@ -0,0 +1,119 @@
+package babbletest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/pkg/babbletest"
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/net/signal"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestCluster(t *testing.T) {
+	c := babbletest.NewCluster(t, 3)
+	defer c.Shutdown()
+
+	c.Run()
+	c.Submit(0, []byte("tx0"))
+	c.Submit(2, []byte("tx2"))
+
+	if err := c.WaitTransactions(2, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := c.Proxies[0].Transactions()
+	for i, proxy := range c.Proxies[1:] {
+		other := proxy.Transactions()
+		for j := range txs[:2] {
+			if string(other[j]) != string(txs[j]) {
+				t.Fatalf("node%d committed %q, not %q", i+1, other[j], txs[j])
+			}
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	tr := babbletest.NewTransport("addr")
+
+	var resp net.SyncResponse
+	if err := tr.Sync("peer", &net.SyncRequest{FromID: 1}, &resp); err == nil {
+		t.Fatal("Sync should fail without Respond")
+	}
+
+	tr.Respond = func(target string, args interface{}) (interface{}, error) {
+		return &net.SyncResponse{FromID: 2}, nil
+	}
+
+	if err := tr.Sync("peer", &net.SyncRequest{FromID: 1}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.FromID != 2 {
+		t.Fatalf("resp.FromID should be 2, not %d", resp.FromID)
+	}
+
+	var ping net.PingResponse
+	if err := tr.Ping("peer", &net.PingRequest{}, &ping); err == nil {
+		t.Fatal("Ping should fail when Respond returns a SyncResponse")
+	}
+
+	if calls := tr.Calls(); len(calls) != 3 || calls[0].Target != "peer" {
+		t.Fatalf("calls should be the 3 RPCs to peer, not %v", calls)
+	}
+
+	go func() {
+		rpc := <-tr.Consumer()
+		rpc.Respond(&net.PingResponse{}, nil)
+	}()
+
+	if _, err := tr.Inject(&net.PingRequest{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := babbletest.NewStore(100)
+
+	block := hashgraph.NewBlock(0, 1, []byte("framehash"), nil, [][]byte{[]byte("tx")}, nil)
+
+	store.FailWrites(errors.New("disk full"))
+	if err := store.SetBlock(block); err == nil {
+		t.Fatal("SetBlock should fail")
+	}
+
+	store.FailWrites(nil)
+	if err := store.SetBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetBlock(0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignalHub(t *testing.T) {
+	hub := babbletest.NewSignalHub()
+	alice := hub.Signal("alice")
+	bob := hub.Signal("bob")
+
+	go func() {
+		promise := <-bob.Consumer()
+		promise.Respond(&webrtc.SessionDescription{SDP: "answer"}, nil)
+	}()
+
+	answer, err := alice.Offer("bob", webrtc.SessionDescription{SDP: "offer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.SDP != "answer" {
+		t.Fatalf("answer should be the answer of bob, not %q", answer.SDP)
+	}
+
+	bob.Close()
+	if _, err := alice.Offer("bob", webrtc.SessionDescription{}); err == nil {
+		t.Fatal("Offer should fail after bob closes")
+	}
+
+	var _ signal.Signal = alice
+}
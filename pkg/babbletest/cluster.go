@@ -0,0 +1,133 @@
+package babbletest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/pkg/babble"
+)
+
+// Cluster is a set of nodes connected by InmemTransports, each with a Store
+// and an AppProxy from this package, all in the same process.
+type Cluster struct {
+	Nodes   []*babble.Node
+	Proxies []*AppProxy
+	Stores  []*Store
+
+	dir string
+}
+
+// NewCluster creates a Cluster of n nodes in a temporary directory. The nodes
+// do not run until Run is called. The test fails if a node cannot be created.
+func NewCluster(t testing.TB, n int) *Cluster {
+	dir, err := ioutil.TempDir("", "babbletest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Cluster{dir: dir}
+
+	addrs := make([]string, n)
+	transports := make([]*babble.InmemTransport, n)
+	for i := range transports {
+		addrs[i], transports[i] = babble.NewInmemTransport("")
+	}
+	for i, tr := range transports {
+		for j, peer := range transports {
+			if i != j {
+				tr.Connect(addrs[j], peer)
+			}
+		}
+	}
+
+	confs := make([]*babble.Config, n)
+	peers := []*babble.Peer{}
+
+	for i := range confs {
+		key, err := babble.GenerateKey()
+		if err != nil {
+			c.Shutdown()
+			t.Fatal(err)
+		}
+
+		moniker := fmt.Sprintf("node%d", i)
+		peers = append(peers, babble.NewPeer(babble.PublicKeyHex(&key.PublicKey), addrs[i], moniker))
+
+		conf := babble.DefaultConfig()
+		conf.SetDataDir(filepath.Join(dir, moniker))
+		conf.Key = key
+		conf.Moniker = moniker
+		conf.NoService = true
+		conf.HeartbeatTimeout = 10 * time.Millisecond
+		conf.LogLevel = "error"
+		confs[i] = conf
+	}
+
+	for i, conf := range confs {
+		if err := os.MkdirAll(conf.DataDir, 0700); err != nil {
+			c.Shutdown()
+			t.Fatal(err)
+		}
+		if err := babble.WritePeers(conf.DataDir, peers); err != nil {
+			c.Shutdown()
+			t.Fatal(err)
+		}
+
+		proxy := NewAppProxy()
+		store := NewStore(conf.CacheSize)
+
+		node, err := babble.NewNode(conf, proxy,
+			babble.WithStore(store),
+			babble.WithTransport(transports[i]),
+		)
+		if err != nil {
+			c.Shutdown()
+			t.Fatal(err)
+		}
+
+		c.Nodes = append(c.Nodes, node)
+		c.Proxies = append(c.Proxies, proxy)
+		c.Stores = append(c.Stores, store)
+	}
+
+	return c
+}
+
+// Run runs all the nodes in the background.
+func (c *Cluster) Run() {
+	for _, node := range c.Nodes {
+		go node.Run()
+	}
+}
+
+// Submit submits a transaction through the AppProxy of node i.
+func (c *Cluster) Submit(i int, tx []byte) {
+	go c.Proxies[i].SubmitTx(tx)
+}
+
+// WaitTransactions waits until all the nodes have committed at least n
+// transactions.
+func (c *Cluster) WaitTransactions(n int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for i, proxy := range c.Proxies {
+		if err := proxy.WaitTransactions(n, time.Until(deadline)); err != nil {
+			return fmt.Errorf("node%d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown shuts the nodes down and removes their data directories.
+func (c *Cluster) Shutdown() {
+	for _, node := range c.Nodes {
+		node.Shutdown()
+	}
+
+	os.RemoveAll(c.dir)
+}
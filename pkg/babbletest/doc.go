@@ -0,0 +1,21 @@
+// Package babbletest provides fakes of the interfaces of package babble, and
+// an in-memory Cluster, to unit-test applications which embed Babble without
+// running real nodes.
+//
+// Transport records the RPCs sent by a node and answers them with a function,
+// Store is an in-memory Store whose writes can be made to fail, AppProxy
+// records the Blocks committed by a node, and SignalHub connects WebRTC
+// Signals without a signaling server.
+//
+//	c := babbletest.NewCluster(t, 3)
+//	defer c.Shutdown()
+//
+//	c.Run()
+//	c.Submit(0, []byte("tx"))
+//
+//	if err := c.WaitTransactions(1, 10*time.Second); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// Like package babble, babbletest follows semantic versioning.
+package babbletest
@@ -0,0 +1,177 @@
+package babbletest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/pkg/babble"
+	"github.com/mosaicnetworks/babble/src/crypto"
+)
+
+var _ babble.AppProxy = (*AppProxy)(nil)
+
+// AppProxy is an AppProxy which records the Blocks committed by the node, and
+// the states it goes through, in place of an application. Its state hash is
+// the cumulative hash of the committed transactions, and its snapshots are
+// the state hashes, like the dummy application.
+type AppProxy struct {
+	// CommitFunc, if set, replaces the default commit, which accepts all the
+	// InternalTransactions and returns the cumulative state hash.
+	CommitFunc func(block babble.Block) (babble.CommitResponse, error)
+
+	submitCh chan []byte
+
+	lock      sync.Mutex
+	blocks    []babble.Block
+	txs       [][]byte
+	stateHash []byte
+	snapshots map[int][]byte
+	states    []babble.State
+}
+
+// NewAppProxy creates an AppProxy.
+func NewAppProxy() *AppProxy {
+	return &AppProxy{
+		submitCh:  make(chan []byte),
+		stateHash: []byte{},
+		snapshots: make(map[int][]byte),
+	}
+}
+
+// SubmitTx submits a transaction to the node, as the application would. It
+// blocks until the node receives it.
+func (p *AppProxy) SubmitTx(tx []byte) {
+	p.submitCh <- tx
+}
+
+// Blocks returns the Blocks committed so far.
+func (p *AppProxy) Blocks() []babble.Block {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]babble.Block(nil), p.blocks...)
+}
+
+// Transactions returns the transactions committed so far, in consensus order.
+func (p *AppProxy) Transactions() [][]byte {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([][]byte(nil), p.txs...)
+}
+
+// States returns the states that the node went through.
+func (p *AppProxy) States() []babble.State {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]babble.State(nil), p.states...)
+}
+
+// WaitTransactions waits until at least n transactions are committed.
+func (p *AppProxy) WaitTransactions(n int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		count := len(p.Transactions())
+		if count >= n {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for %d transactions, %d committed", n, count)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// SubmitCh implements the AppProxy interface.
+func (p *AppProxy) SubmitCh() chan []byte {
+	return p.submitCh
+}
+
+// CommitBlock implements the AppProxy interface.
+func (p *AppProxy) CommitBlock(block babble.Block) (babble.CommitResponse, error) {
+	commit := p.CommitFunc
+	if commit == nil {
+		commit = p.commit
+	}
+
+	resp, err := commit(block)
+	if err != nil {
+		return resp, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.blocks = append(p.blocks, block)
+	p.txs = append(p.txs, block.Transactions()...)
+	p.stateHash = resp.StateHash
+	p.snapshots[block.Index()] = resp.StateHash
+
+	return resp, nil
+}
+
+// commit accepts all the InternalTransactions of a Block, and returns the
+// cumulative hash of the transactions.
+func (p *AppProxy) commit(block babble.Block) (babble.CommitResponse, error) {
+	p.lock.Lock()
+	hash := p.stateHash
+	p.lock.Unlock()
+
+	for _, tx := range block.Transactions() {
+		hash = crypto.SimpleHashFromTwoHashes(hash, crypto.SHA256(tx))
+	}
+
+	receipts := []babble.InternalTransactionReceipt{}
+	for _, it := range block.InternalTransactions() {
+		receipts = append(receipts, it.AsAccepted())
+	}
+
+	return babble.CommitResponse{
+		StateHash:                   hash,
+		InternalTransactionReceipts: receipts,
+	}, nil
+}
+
+// GetSnapshot implements the AppProxy interface.
+func (p *AppProxy) GetSnapshot(blockIndex int) ([]byte, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	snapshot, ok := p.snapshots[blockIndex]
+	if !ok {
+		return nil, fmt.Errorf("Snapshot %d not found", blockIndex)
+	}
+
+	return snapshot, nil
+}
+
+// Restore implements the AppProxy interface.
+func (p *AppProxy) Restore(snapshot []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.stateHash = snapshot
+
+	return nil
+}
+
+// HashSnapshot implements the SnapshotHasher interface of the verify-snapshots
+// option. The snapshots are the state hashes.
+func (p *AppProxy) HashSnapshot(snapshot []byte) ([]byte, error) {
+	return snapshot, nil
+}
+
+// OnStateChanged implements the AppProxy interface.
+func (p *AppProxy) OnStateChanged(state babble.State) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.states = append(p.states, state)
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package babbletest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mosaicnetworks/babble/src/net/signal"
+	"github.com/pion/webrtc/v2"
+)
+
+var _ signal.Signal = (*Signal)(nil)
+
+// SignalHub routes the WebRTC offers and answers between its Signals in
+// memory, in place of a signaling server.
+type SignalHub struct {
+	lock    sync.Mutex
+	signals map[string]*Signal
+}
+
+// NewSignalHub creates a SignalHub.
+func NewSignalHub() *SignalHub {
+	return &SignalHub{
+		signals: make(map[string]*Signal),
+	}
+}
+
+// Signal creates the Signal of a peer, identified by its public key like with
+// a signaling server.
+func (h *SignalHub) Signal(id string) *Signal {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s := &Signal{
+		id:       id,
+		hub:      h,
+		consumer: make(chan signal.OfferPromise),
+	}
+
+	h.signals[id] = s
+
+	return s
+}
+
+func (h *SignalHub) get(id string) (*Signal, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s, ok := h.signals[id]
+
+	return s, ok
+}
+
+func (h *SignalHub) remove(id string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.signals, id)
+}
+
+// Signal is a Signal connected to a SignalHub, which records the offers that
+// it sends.
+type Signal struct {
+	id       string
+	hub      *SignalHub
+	consumer chan signal.OfferPromise
+
+	lock   sync.Mutex
+	offers []webrtc.SessionDescription
+}
+
+// Offers returns the offers sent through the Signal so far.
+func (s *Signal) Offers() []webrtc.SessionDescription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]webrtc.SessionDescription(nil), s.offers...)
+}
+
+// ID implements the Signal interface.
+func (s *Signal) ID() string {
+	return s.id
+}
+
+// Listen implements the Signal interface.
+func (s *Signal) Listen() error {
+	return nil
+}
+
+// Consumer implements the Signal interface.
+func (s *Signal) Consumer() <-chan signal.OfferPromise {
+	return s.consumer
+}
+
+// Offer implements the Signal interface. It fails if the target is not
+// connected to the hub.
+func (s *Signal) Offer(target string, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	s.lock.Lock()
+	s.offers = append(s.offers, offer)
+	s.lock.Unlock()
+
+	peer, ok := s.hub.get(target)
+	if !ok {
+		return nil, fmt.Errorf("no signal %s", target)
+	}
+
+	respCh := make(chan signal.OfferPromiseResponse, 1)
+	peer.consumer <- signal.OfferPromise{
+		From:     s.id,
+		Offer:    offer,
+		RespChan: respCh,
+	}
+
+	resp := <-respCh
+
+	return resp.Answer, resp.Error
+}
+
+// Close implements the Signal interface. It disconnects the Signal from the
+// hub.
+func (s *Signal) Close() error {
+	s.hub.remove(s.id)
+
+	return nil
+}
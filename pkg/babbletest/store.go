@@ -0,0 +1,74 @@
+package babbletest
+
+import (
+	"sync"
+
+	"github.com/mosaicnetworks/babble/pkg/babble"
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+var _ babble.Store = (*Store)(nil)
+
+// Store is an in-memory Store whose writes can be made to fail, to test how a
+// node and its application handle a failing disk.
+type Store struct {
+	*hashgraph.InmemStore
+
+	lock sync.Mutex
+	err  error
+}
+
+// NewStore creates a Store which keeps at most cacheSize items of each kind.
+func NewStore(cacheSize int) *Store {
+	return &Store{
+		InmemStore: hashgraph.NewInmemStore(cacheSize),
+	}
+}
+
+// FailWrites makes the writes of Events, Rounds, Blocks and Frames fail with
+// err, or succeed again if err is nil.
+func (s *Store) FailWrites(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = err
+}
+
+func (s *Store) writeErr() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.err
+}
+
+// SetEvent implements the Store interface.
+func (s *Store) SetEvent(event *hashgraph.Event) error {
+	if err := s.writeErr(); err != nil {
+		return err
+	}
+	return s.InmemStore.SetEvent(event)
+}
+
+// SetRound implements the Store interface.
+func (s *Store) SetRound(roundIndex int, roundInfo *hashgraph.RoundInfo) error {
+	if err := s.writeErr(); err != nil {
+		return err
+	}
+	return s.InmemStore.SetRound(roundIndex, roundInfo)
+}
+
+// SetBlock implements the Store interface.
+func (s *Store) SetBlock(block *hashgraph.Block) error {
+	if err := s.writeErr(); err != nil {
+		return err
+	}
+	return s.InmemStore.SetBlock(block)
+}
+
+// SetFrame implements the Store interface.
+func (s *Store) SetFrame(frame *hashgraph.Frame) error {
+	if err := s.writeErr(); err != nil {
+		return err
+	}
+	return s.InmemStore.SetFrame(frame)
+}
@@ -0,0 +1,163 @@
+package babbletest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/pkg/babble"
+	"github.com/mosaicnetworks/babble/src/net"
+)
+
+var _ babble.Transport = (*Transport)(nil)
+
+// Call is an RPC sent through a Transport.
+type Call struct {
+	Target string
+	Args   interface{}
+}
+
+// Transport is a Transport which records the RPCs sent by the node, and
+// answers them with Respond, without any network. Inbound RPCs are injected
+// with Inject, as if a peer had sent them.
+type Transport struct {
+	// Respond answers the RPCs sent by the node, with a response of the type
+	// expected by the Transport method, like *net.SyncResponse for Sync. If it
+	// is nil, the RPCs fail as if the peers were unreachable.
+	Respond func(target string, args interface{}) (interface{}, error)
+
+	// Timeout is how long Inject waits for the node to respond.
+	Timeout time.Duration
+
+	addr     string
+	consumer chan net.RPC
+
+	lock   sync.Mutex
+	calls  []Call
+	closed bool
+}
+
+// NewTransport creates a Transport with an address.
+func NewTransport(addr string) *Transport {
+	return &Transport{
+		Timeout:  time.Second,
+		addr:     addr,
+		consumer: make(chan net.RPC, 16),
+	}
+}
+
+// Calls returns the RPCs sent by the node so far.
+func (t *Transport) Calls() []Call {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return append([]Call(nil), t.calls...)
+}
+
+// Inject delivers an RPC to the node, as if a peer had sent it, and returns
+// the response of the node.
+func (t *Transport) Inject(args interface{}) (interface{}, error) {
+	respCh := make(chan net.RPCResponse, 1)
+
+	t.consumer <- net.RPC{
+		Command:  args,
+		RespChan: respCh,
+	}
+
+	select {
+	case resp := <-respCh:
+		return resp.Response, resp.Error
+	case <-time.After(t.Timeout):
+		return nil, fmt.Errorf("command timed out")
+	}
+}
+
+// Listen implements the Transport interface.
+func (t *Transport) Listen() {}
+
+// Consumer implements the Transport interface.
+func (t *Transport) Consumer() <-chan net.RPC {
+	return t.consumer
+}
+
+// LocalAddr implements the Transport interface.
+func (t *Transport) LocalAddr() string {
+	return t.addr
+}
+
+// AdvertiseAddr implements the Transport interface.
+func (t *Transport) AdvertiseAddr() string {
+	return t.addr
+}
+
+// Sync implements the Transport interface.
+func (t *Transport) Sync(target string, args *net.SyncRequest, resp *net.SyncResponse) error {
+	return t.call(target, args, resp)
+}
+
+// EagerSync implements the Transport interface.
+func (t *Transport) EagerSync(target string, args *net.EagerSyncRequest, resp *net.EagerSyncResponse) error {
+	return t.call(target, args, resp)
+}
+
+// FastForward implements the Transport interface.
+func (t *Transport) FastForward(target string, args *net.FastForwardRequest, resp *net.FastForwardResponse) error {
+	return t.call(target, args, resp)
+}
+
+// Join implements the Transport interface.
+func (t *Transport) Join(target string, args *net.JoinRequest, resp *net.JoinResponse) error {
+	return t.call(target, args, resp)
+}
+
+// Ping implements the Transport interface.
+func (t *Transport) Ping(target string, args *net.PingRequest, resp *net.PingResponse) error {
+	return t.call(target, args, resp)
+}
+
+// Info implements the Transport interface.
+func (t *Transport) Info(target string, args *net.InfoRequest, resp *net.InfoResponse) error {
+	return t.call(target, args, resp)
+}
+
+// Close implements the Transport interface.
+func (t *Transport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.closed = true
+
+	return nil
+}
+
+// call records an RPC, and copies the response of Respond into resp.
+func (t *Transport) call(target string, args interface{}, resp interface{}) error {
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return fmt.Errorf("transport is closed")
+	}
+	t.calls = append(t.calls, Call{Target: target, Args: args})
+	respond := t.Respond
+	t.lock.Unlock()
+
+	if respond == nil {
+		return fmt.Errorf("failed to connect to peer: %v", target)
+	}
+
+	out, err := respond(target, args)
+	if err != nil {
+		return err
+	}
+
+	dst := reflect.ValueOf(resp)
+	src := reflect.ValueOf(out)
+	if out == nil || src.Type() != dst.Type() {
+		return fmt.Errorf("Respond returned a %T instead of a %T", out, resp)
+	}
+
+	dst.Elem().Set(src.Elem())
+
+	return nil
+}
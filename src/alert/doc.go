@@ -0,0 +1,8 @@
+// Package alert sends the alerts of a Babble node to webhooks.
+//
+// The node raises alerts when it suspends itself, when it detects a fork or a
+// state-hash divergence, when a peer is evicted, and when it falls behind the
+// other validators. A Dispatcher subscribes to these alerts and posts them to
+// generic HTTP webhooks, in JSON, or to Slack incoming webhooks, as messages,
+// so that operators hear about problems before users do.
+package alert
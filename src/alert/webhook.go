@@ -0,0 +1,158 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/node"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// slackPrefix marks the webhooks that are Slack incoming webhooks.
+	slackPrefix = "slack:"
+
+	// webhookTimeout is the time allowed for a webhook to respond.
+	webhookTimeout = 10 * time.Second
+
+	// subscriptionBuffer is the number of alerts buffered while the previous
+	// ones are being posted.
+	subscriptionBuffer = 100
+)
+
+// Webhook is an HTTP endpoint which receives alerts in POST requests.
+type Webhook struct {
+	URL string
+
+	// Slack indicates a Slack incoming webhook, which receives the alerts as
+	// Slack messages instead of JSON Alerts.
+	Slack bool
+}
+
+// ParseWebhooks parses a comma-separated list of webhook URLs. URLs prefixed
+// with "slack:" are Slack incoming webhooks.
+func ParseWebhooks(list string) ([]Webhook, error) {
+	webhooks := []Webhook{}
+
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		wh := Webhook{URL: s}
+		if strings.HasPrefix(s, slackPrefix) {
+			wh.URL = strings.TrimPrefix(s, slackPrefix)
+			wh.Slack = true
+		}
+
+		u, err := url.Parse(wh.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			// The URL is not printed, because it may contain a secret token
+			return nil, fmt.Errorf("Invalid webhook URL, it must be an http or https URL")
+		}
+
+		webhooks = append(webhooks, wh)
+	}
+
+	return webhooks, nil
+}
+
+// payload returns the body of the request posting an alert to the webhook.
+func (wh Webhook) payload(alert *node.Alert) ([]byte, error) {
+	if !wh.Slack {
+		return json.Marshal(alert)
+	}
+
+	return json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s* on %s (%d): %s", alert.Kind, alert.Moniker, alert.NodeID, alert.Message),
+	})
+}
+
+// Dispatcher posts the alerts of a node to webhooks.
+type Dispatcher struct {
+	webhooks []Webhook
+	client   *http.Client
+	logger   *logrus.Entry
+}
+
+// NewDispatcher creates a Dispatcher posting to the given webhooks.
+func NewDispatcher(webhooks []Webhook, logger *logrus.Entry) *Dispatcher {
+	if logger == nil {
+		log := logrus.New()
+		log.Level = logrus.DebugLevel
+		logger = logrus.NewEntry(log)
+	}
+
+	return &Dispatcher{
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: webhookTimeout},
+		logger:   logger,
+	}
+}
+
+// Send posts an alert to every webhook. It returns the last error, after
+// trying all the webhooks.
+func (d *Dispatcher) Send(alert *node.Alert) error {
+	var lastErr error
+
+	for i, wh := range d.webhooks {
+		if err := d.post(wh, alert); err != nil {
+			d.logger.WithError(err).WithField("webhook", i).Warn("Posting alert")
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+func (d *Dispatcher) post(wh Webhook, alert *node.Alert) error {
+	body, err := wh.payload(alert)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.client.Post(wh.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The error contains the URL, which may contain a secret token
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Run posts the alerts of the node to the webhooks, until the node shuts down.
+func (d *Dispatcher) Run(n *node.Node) {
+	sub := n.Subscribe(subscriptionBuffer, node.AlertNotification)
+	defer func() { sub.Unsubscribe() }()
+
+	for {
+		note, ok := <-sub.C()
+		if !ok {
+			// The subscription is closed when the node shuts down, or when
+			// the webhooks do not keep up.
+			if n.GetState() == _state.Shutdown {
+				return
+			}
+			d.logger.Warn("Alerts were dropped because the webhooks are too slow")
+			sub = n.Subscribe(subscriptionBuffer, node.AlertNotification)
+			continue
+		}
+
+		d.Send(note.Alert)
+	}
+}
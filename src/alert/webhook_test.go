@@ -0,0 +1,94 @@
+package alert
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/node"
+)
+
+func TestParseWebhooks(t *testing.T) {
+	webhooks, err := ParseWebhooks(" http://127.0.0.1:9000/alerts, slack:https://hooks.slack.com/services/T0/B0/secret ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Webhook{
+		{URL: "http://127.0.0.1:9000/alerts"},
+		{URL: "https://hooks.slack.com/services/T0/B0/secret", Slack: true},
+	}
+
+	if len(webhooks) != len(expected) {
+		t.Fatalf("There should be %d webhooks, not %d", len(expected), len(webhooks))
+	}
+	for i, wh := range webhooks {
+		if wh != expected[i] {
+			t.Fatalf("Webhook %d should be %#v, not %#v", i, expected[i], wh)
+		}
+	}
+
+	if webhooks, err := ParseWebhooks(""); err != nil || len(webhooks) != 0 {
+		t.Fatalf("An empty list should not have webhooks: %v %v", webhooks, err)
+	}
+
+	for _, list := range []string{"127.0.0.1:9000", "ftp://host/alerts", "slack:hooks.slack.com"} {
+		if _, err := ParseWebhooks(list); err == nil {
+			t.Fatalf("%q should be refused", list)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	bodies := make(chan []byte, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	alert := &node.Alert{
+		Kind:    node.ForkAlert,
+		Message: "Validator node2 created two Events with index 4",
+		NodeID:  1,
+		Moniker: "node1",
+		Time:    time.Unix(0, 0).UTC(),
+	}
+
+	d := NewDispatcher([]Webhook{
+		{URL: server.URL},
+		{URL: server.URL, Slack: true},
+		{URL: failing.URL},
+	}, common.NewTestEntry(t, common.TestLogLevel))
+
+	if err := d.Send(alert); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("Send should report the status of the failing webhook, not %v", err)
+	}
+
+	var received node.Alert
+	if err := json.Unmarshal(<-bodies, &received); err != nil {
+		t.Fatal(err)
+	}
+	if received != *alert {
+		t.Fatalf("The generic webhook should receive %#v, not %#v", *alert, received)
+	}
+
+	var message map[string]string
+	if err := json.Unmarshal(<-bodies, &message); err != nil {
+		t.Fatal(err)
+	}
+	if text := message["text"]; !strings.Contains(text, "fork") || !strings.Contains(text, alert.Message) {
+		t.Fatalf("The Slack webhook should receive the kind and the message, not %q", text)
+	}
+}
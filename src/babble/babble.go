@@ -1,19 +1,31 @@
 package babble
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/mosaicnetworks/babble/src/alert"
 	"github.com/mosaicnetworks/babble/src/config"
 	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/genesis"
 	h "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/logging"
 	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/net/nat"
+	"github.com/mosaicnetworks/babble/src/net/signal"
 	"github.com/mosaicnetworks/babble/src/net/signal/wamp"
+	"github.com/mosaicnetworks/babble/src/net/tor"
 	"github.com/mosaicnetworks/babble/src/node"
 	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/proxy"
 	"github.com/mosaicnetworks/babble/src/service"
+	"github.com/mosaicnetworks/babble/src/tracing"
 	"github.com/sirupsen/logrus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // Babble encapsulates the components that make up a Babble node.
@@ -24,8 +36,21 @@ type Babble struct {
 	Store        h.Store
 	Peers        *peers.PeerSet
 	GenesisPeers *peers.PeerSet
+	Genesis      *genesis.Genesis
 	Service      *service.Service
-	logger       *logrus.Entry
+
+	// ConfigLoader, if set, reads the configuration again when it is reloaded
+	// by ReloadConfig or the /admin/reload endpoint.
+	ConfigLoader func() (*config.Config, error)
+
+	reloadLock     sync.Mutex
+	tracerProvider *sdktrace.TracerProvider
+	alerts         *alert.Dispatcher
+	txAuthorizer   proxy.TxAuthorizer
+	idempotency    *proxy.IdempotencyCache
+	natMapping     *nat.Mapping
+	onion          *tor.OnionService
+	logger         *logrus.Entry
 }
 
 // NewBabble returns a new Babble instance.
@@ -41,9 +66,21 @@ func NewBabble(c *config.Config) *Babble {
 // Init initialises Babble based on its configuration.
 func (b *Babble) Init() error {
 
-	b.logger.Debug("validateConfig")
-	if err := b.validateConfig(); err != nil {
-		b.logger.WithError(err).Error("babble.go:Init() validateConfig")
+	b.logger.Debug("ValidateConfig")
+	if err := b.ValidateConfig(); err != nil {
+		b.logger.WithError(err).Error("babble.go:Init() ValidateConfig")
+	}
+
+	b.logger.Debug("initDataDir")
+	if err := b.initDataDir(); err != nil {
+		b.logger.WithError(err).Error("babble.go:Init() initDataDir")
+		return err
+	}
+
+	b.logger.Debug("checkDataDir")
+	if err := b.checkDataDir(); err != nil {
+		b.logger.WithError(err).Error("babble.go:Init() checkDataDir")
+		return err
 	}
 
 	b.logger.Debug("initKey")
@@ -70,12 +107,36 @@ func (b *Babble) Init() error {
 		return err
 	}
 
+	b.logger.Debug("initTracing")
+	if err := b.initTracing(); err != nil {
+		b.logger.WithError(err).Error("babble.go:Init() initTracing")
+		return err
+	}
+
 	b.logger.Debug("initNode")
 	if err := b.initNode(); err != nil {
 		b.logger.WithError(err).Error("babble.go:Init() initNode")
 		return err
 	}
 
+	b.logger.Debug("initAlerts")
+	if err := b.initAlerts(); err != nil {
+		b.logger.WithError(err).Error("babble.go:Init() initAlerts")
+		return err
+	}
+
+	b.logger.Debug("initTxAuthorizer")
+	if err := b.initTxAuthorizer(); err != nil {
+		b.logger.WithError(err).Error("babble.go:Init() initTxAuthorizer")
+		return err
+	}
+
+	b.logger.Debug("initIdempotency")
+	if err := b.initIdempotency(); err != nil {
+		b.logger.WithError(err).Error("babble.go:Init() initIdempotency")
+		return err
+	}
+
 	b.logger.Debug("initService")
 	if err := b.initService(); err != nil {
 		b.logger.WithError(err).Error("babble.go:Init() initService")
@@ -91,29 +152,93 @@ func (b *Babble) Run() {
 		go b.Service.Serve()
 	}
 
+	if b.alerts != nil {
+		go b.alerts.Run(b.Node)
+	}
+
 	b.Node.Run(true)
+
+	// Remove the port mapping from the router
+	if b.natMapping != nil {
+		if err := b.natMapping.Close(); err != nil {
+			b.logger.WithError(err).Warn("Deleting port mapping")
+		}
+	}
+
+	// Remove the onion service
+	if b.onion != nil {
+		if err := b.onion.Close(); err != nil {
+			b.logger.WithError(err).Warn("Removing onion service")
+		}
+	}
+
+	// Flush the spans that have not been exported yet
+	if b.tracerProvider != nil {
+		if err := b.tracerProvider.Shutdown(context.Background()); err != nil {
+			b.logger.WithError(err).Error("Shutting down TracerProvider")
+		}
+	}
 }
 
-func (b *Babble) validateConfig() error {
+// ValidateConfig normalises the configuration, like enabling the store when
+// bootstrapping, and returns an error if some options are invalid.
+func (b *Babble) ValidateConfig() error {
 	// If --datadir was explicitly set, but not --db, the following line will
 	// update the default database dir to be inside the new datadir
 	b.Config.SetDataDir(b.Config.DataDir)
 
 	logFields := logrus.Fields{
 		"babble.DataDir":          b.Config.DataDir,
+		"babble.Network":          b.Config.Network,
 		"babble.ServiceAddr":      b.Config.ServiceAddr,
 		"babble.NoService":        b.Config.NoService,
 		"babble.MaxPool":          b.Config.MaxPool,
 		"babble.LogLevel":         b.Config.LogLevel,
+		"babble.LogFormat":        b.Config.LogFormat,
+		"babble.LogModules":       b.Config.LogModules,
 		"babble.Moniker":          b.Config.Moniker,
 		"babble.HeartbeatTimeout": b.Config.HeartbeatTimeout,
 		"babble.TCPTimeout":       b.Config.TCPTimeout,
 		"babble.JoinTimeout":      b.Config.JoinTimeout,
+		"babble.JoinAdmission":    b.Config.JoinAdmissionLimit,
 		"babble.CacheSize":        b.Config.CacheSize,
 		"babble.SyncLimit":        b.Config.SyncLimit,
+		"babble.SyncChunkSize":    b.Config.SyncChunkSize,
+		"babble.AntiEntropy":      b.Config.AntiEntropyInterval,
+		"babble.SyncDedupWindow":  b.Config.SyncDedupWindow,
+		"babble.PushPull":         b.Config.PushPull,
+		"babble.CommitBarrier":    b.Config.CommitBarrier,
+		"babble.PrivateTxs":       b.Config.PrivateTransactions,
+		"babble.TxClientKeys":     b.Config.TxClientKeys,
+		"babble.Idempotency":      b.Config.IdempotencyWindow,
+		"babble.Shadow":           b.Config.Shadow,
+		"babble.PingInterval":     b.Config.PingInterval,
+		"babble.Keepalive":        b.Config.KeepaliveInterval,
+		"babble.PeerSelector":     b.Config.PeerSelector,
+		"babble.RPCWorkers":       b.Config.RPCWorkers,
+		"babble.HistoryWorkers":   b.Config.HistoryWorkers,
+		"babble.Relay":            b.Config.Relay,
+		"babble.RelayRoutes":      b.Config.RelayRoutes,
+		"babble.TorProxy":         b.Config.TorProxy,
+		"babble.TorControl":       b.Config.TorControl,
+		"babble.RecordRPC":        b.Config.RecordRPC,
+		"babble.ReplayRPC":        b.Config.ReplayRPC,
 		"babble.EnableFastSync":   b.Config.EnableFastSync,
+		"babble.TrustedBlock":     b.Config.TrustedBlock,
 		"babble.MaintenanceMode":  b.Config.MaintenanceMode,
 		"babble.SuspendLimit":     b.Config.SuspendLimit,
+		"babble.MaxBytesPerHour":  b.Config.MaxBytesPerHour,
+		"babble.PeerQuota":        b.Config.PeerQuota,
+		"babble.PeerQuotaAction":  b.Config.PeerQuotaAction,
+		"babble.MaxMemory":        b.Config.MaxMemory,
+		"babble.Metered":          b.Config.Metered,
+		"babble.OfflineMode":      b.Config.OfflineMode,
+		"babble.WatchdogTimeout":  b.Config.WatchdogTimeout,
+		"babble.AlertBlockLag":    b.Config.AlertBlockLag,
+		"babble.AuditInterval":    b.Config.AuditInterval,
+		"babble.SignedGenesis":    b.Config.SignedGenesis,
+		"babble.SnapshotDelta":    b.Config.SnapshotDelta,
+		"babble.VerifySnapshots":  b.Config.VerifySnapshots,
 	}
 
 	// WebRTC requires signaling and ICE servers
@@ -122,8 +247,13 @@ func (b *Babble) validateConfig() error {
 		logFields["babble.SignalAddr"] = b.Config.SignalAddr
 		logFields["babble.SignalRealm"] = b.Config.SignalRealm
 		logFields["babble.SignalSkipVerify"] = b.Config.SignalSkipVerify
+		logFields["babble.SignalEncrypt"] = b.Config.SignalEncrypt
 		logFields["babble.ICEAddress"] = b.Config.ICEAddress
 		logFields["babble.ICEUsername"] = b.Config.ICEUsername
+		logFields["babble.ICEHostOnly"] = b.Config.ICEHostOnly
+		logFields["babble.WebRTCUnordered"] = b.Config.WebRTCUnordered
+		logFields["babble.WebRTCMaxRetransmits"] = b.Config.WebRTCMaxRetransmits
+		logFields["babble.WebRTCBufferedHigh"] = b.Config.WebRTCBufferedHigh
 
 	} else {
 		logFields["babble.BindAddr"] = b.Config.BindAddr
@@ -159,6 +289,171 @@ func (b *Babble) validateConfig() error {
 
 	b.logger.WithFields(logFields).Debug("Config")
 
+	if _, err := logging.NewFormatter(b.Config.LogFormat); err != nil {
+		return err
+	}
+
+	if _, err := logging.ParseModuleLevels(b.Config.LogModules); err != nil {
+		return err
+	}
+
+	if b.Config.LogFile != "" {
+		if _, ok := b.Config.Logger().Logger.Out.(*logging.FileWriter); !ok {
+			// The logger falls back to stderr if the file cannot be opened.
+			// Open it again to report the error.
+			fw, err := logging.NewFileWriter(b.Config.LogFile)
+			if err != nil {
+				return err
+			}
+			fw.Close()
+		}
+	}
+
+	if _, err := service.ParseAPIKeys(b.Config.ServiceAPIKeys); err != nil {
+		return err
+	}
+
+	if b.Config.ServiceRateLimit < 0 || b.Config.ServiceTxRateLimit < 0 {
+		return fmt.Errorf("service rate limits cannot be negative")
+	}
+
+	if _, err := net.NewFilter(b.filterRules()); err != nil {
+		return err
+	}
+
+	if err := nat.Check(b.Config.NAT); err != nil {
+		return err
+	}
+
+	if _, err := net.ParseRoutes(b.Config.RelayRoutes); err != nil {
+		return err
+	}
+
+	if b.Config.ICEHostOnly && !b.Config.WebRTC && !b.Config.Relay {
+		return fmt.Errorf("ice-host-only only applies to the WebRTC transport")
+	}
+
+	if b.Config.WebRTCMaxRetransmits < -1 || b.Config.WebRTCMaxRetransmits > math.MaxUint16 {
+		return fmt.Errorf("webrtc-max-retransmits must be between -1 and %d", math.MaxUint16)
+	}
+
+	if b.Config.WebRTCBufferedHigh < 0 || b.Config.WebRTCBufferedLow < 0 {
+		return fmt.Errorf("webrtc-buffered-high and webrtc-buffered-low cannot be negative")
+	}
+
+	if b.Config.WebRTCBufferedHigh > 0 && b.Config.WebRTCBufferedLow >= b.Config.WebRTCBufferedHigh {
+		return fmt.Errorf("webrtc-buffered-low must be lower than webrtc-buffered-high")
+	}
+
+	if b.Config.TorControl != "" && b.Config.WebRTC && !b.Config.Relay {
+		return fmt.Errorf("tor-control only applies to the TCP transport")
+	}
+
+	if b.Config.MaxBytesPerHour < 0 {
+		return fmt.Errorf("max-bytes-per-hour cannot be negative")
+	}
+
+	if b.Config.PeerQuota < 0 {
+		return fmt.Errorf("peer-quota cannot be negative")
+	}
+
+	switch b.Config.PeerQuotaAction {
+	case "throttle", "ban":
+	default:
+		return fmt.Errorf("peer-quota-action must be throttle or ban, not %q", b.Config.PeerQuotaAction)
+	}
+
+	if b.Config.MaxMemory < 0 {
+		return fmt.Errorf("max-memory cannot be negative")
+	}
+
+	if b.Config.AntiEntropyInterval < 0 {
+		return fmt.Errorf("anti-entropy-interval cannot be negative")
+	}
+
+	if _, err := alert.ParseWebhooks(b.Config.AlertWebhooks); err != nil {
+		return fmt.Errorf("alert-webhooks: %v", err)
+	}
+
+	if b.Config.AlertBlockLag < 0 {
+		return fmt.Errorf("alert-block-lag cannot be negative")
+	}
+
+	if b.Config.WatchdogTimeout < 0 {
+		return fmt.Errorf("watchdog-timeout cannot be negative")
+	}
+
+	if b.Config.AuditInterval < 0 {
+		return fmt.Errorf("audit-interval cannot be negative")
+	}
+
+	if b.Config.AuditInterval > 0 && b.Config.AuditBlocks < 1 {
+		return fmt.Errorf("audit-blocks must be positive")
+	}
+
+	if b.Config.PingInterval < 0 {
+		return fmt.Errorf("ping-interval cannot be negative")
+	}
+
+	if b.Config.KeepaliveInterval < 0 {
+		return fmt.Errorf("keepalive-interval cannot be negative")
+	}
+
+	switch b.Config.PeerSelector {
+	case "random":
+	case "latency":
+		if b.Config.PingInterval == 0 {
+			return fmt.Errorf("peer-selector latency requires a ping-interval")
+		}
+	default:
+		return fmt.Errorf("peer-selector must be random or latency, not %q", b.Config.PeerSelector)
+	}
+
+	if b.Config.RPCWorkers <= 0 {
+		return fmt.Errorf("rpc-workers must be positive")
+	}
+
+	if b.Config.HistoryWorkers < 0 {
+		return fmt.Errorf("history-workers must not be negative")
+	}
+
+	if b.Config.HistoryQueue < 0 {
+		return fmt.Errorf("history-queue must not be negative")
+	}
+
+	if b.Config.IdempotencyWindow < 0 {
+		return fmt.Errorf("idempotency-window must not be negative")
+	}
+
+	if b.Config.OfflineMode && b.Config.OfflineTimeout <= 0 {
+		return fmt.Errorf("offline-mode requires a positive offline-timeout")
+	}
+
+	if b.Config.JoinAdmissionLimit < 0 {
+		return fmt.Errorf("join-admission-limit must not be negative")
+	}
+
+	if b.Config.JoinAdmissionLimit > 0 && b.Config.JoinAdmissionWindow <= 0 {
+		return fmt.Errorf("join-admission-limit requires a positive join-admission-window")
+	}
+
+	if b.Config.SyncChunkSize < 0 {
+		return fmt.Errorf("sync-chunk-size cannot be negative")
+	}
+
+	if b.Config.SyncDedupWindow < 0 {
+		return fmt.Errorf("sync-dedup-window cannot be negative")
+	}
+
+	if b.Config.TrustedBlock != "" && b.Config.TrustedValidators == "" {
+		return fmt.Errorf("trusted-block requires trusted-validators")
+	}
+
+	// TLS requires both the certificate and the key
+	if (b.Config.ServiceTLSCert == "") != (b.Config.ServiceTLSKey == "") {
+		return fmt.Errorf("service-tls-cert and service-tls-key must be set together")
+	}
+
 	return nil
 }
 
@@ -168,45 +463,56 @@ func (b *Babble) initTransport() error {
 		return nil
 	}
 
-	if b.Config.WebRTC {
-		signal, err := wamp.NewClient(
-			b.Config.SignalAddr,
-			b.Config.SignalRealm,
-			keys.PublicKeyHex(&b.Config.Key.PublicKey),
-			b.Config.CertFile(),
-			b.Config.SignalSkipVerify,
-			b.Config.TCPTimeout,
-			b.Config.Logger().WithField("component", "webrtc-signal"),
-		)
+	// Keep the transport set before Init, like the ChannelTransports given by
+	// Channels
+	if b.Transport != nil {
+		return nil
+	}
+
+	switch {
+	case b.Config.ReplayRPC != "":
+		addr := b.Config.AdvertiseAddr
+		if addr == "" {
+			addr = b.Config.BindAddr
+		}
 
+		replayTransport, err := net.NewReplayTransport(
+			b.Config.ReplayRPC,
+			addr,
+			b.Config.ModuleLogger("replay"),
+		)
 		if err != nil {
 			return err
 		}
 
-		webRTCTransport, err := net.NewWebRTCTransport(
-			signal,
-			b.Config.ICEServers(),
-			b.Config.MaxPool,
-			b.Config.TCPTimeout,
-			b.Config.JoinTimeout,
-			b.Config.Logger().WithField("component", "webrtc-transport"),
-		)
+		b.Transport = replayTransport
+	case b.Config.Relay:
+		webRTCTransport, err := b.newWebRTCTransport()
+		if err != nil {
+			return err
+		}
 
+		tcpTransport, err := b.newTCPTransport()
 		if err != nil {
+			webRTCTransport.Close()
 			return err
 		}
 
-		b.Transport = webRTCTransport
-	} else {
-		tcpTransport, err := net.NewTCPTransport(
-			b.Config.BindAddr,
-			b.Config.AdvertiseAddr,
-			b.Config.MaxPool,
-			b.Config.TCPTimeout,
-			b.Config.JoinTimeout,
-			b.Config.Logger(),
+		b.Transport = net.NewRelayTransport(
+			tcpTransport,
+			webRTCTransport,
+			b.Config.WebRTC,
+			b.Config.ModuleLogger("relay"),
 		)
+	case b.Config.WebRTC:
+		webRTCTransport, err := b.newWebRTCTransport()
+		if err != nil {
+			return err
+		}
 
+		b.Transport = webRTCTransport
+	default:
+		tcpTransport, err := b.newTCPTransport()
 		if err != nil {
 			return err
 		}
@@ -214,21 +520,207 @@ func (b *Babble) initTransport() error {
 		b.Transport = tcpTransport
 	}
 
+	if ft, ok := b.Transport.(net.FilteredTransport); ok {
+		if err := ft.Filter().SetRules(b.filterRules()); err != nil {
+			return err
+		}
+	}
+
+	if rt, ok := b.Transport.(net.RoutedTransport); ok {
+		routes, err := net.ParseRoutes(b.Config.RelayRoutes)
+		if err != nil {
+			return err
+		}
+		rt.SetRoutes(routes)
+	}
+
+	if b.Config.RecordRPC != "" {
+		recordingTransport, err := net.NewRecordingTransport(
+			b.Transport,
+			b.Config.RecordRPC,
+			b.Config.ModuleLogger("record"),
+		)
+		if err != nil {
+			b.Transport.Close()
+			return err
+		}
+
+		b.Transport = recordingTransport
+	}
+
 	return nil
 }
 
+func (b *Babble) newWebRTCTransport() (*net.NetworkTransport, error) {
+	client, err := wamp.NewClient(
+		b.Config.SignalAddr,
+		b.Config.SignalRealm,
+		keys.PublicKeyHex(&b.Config.Key.PublicKey),
+		b.Config.CertFile(),
+		b.Config.SignalSkipVerify,
+		b.Config.TCPTimeout,
+		b.Config.ModuleLogger("webrtc-signal"),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The signal ID is the public key, with which the peers encrypt the
+	// offers and answers
+	var sig signal.Signal = client
+	if b.Config.SignalEncrypt {
+		sig = signal.NewSealedSignal(client, b.Config.Key)
+	}
+
+	return net.NewWebRTCTransport(
+		sig,
+		b.Config.ICEServers(),
+		b.dataChannelConfig(),
+		b.Config.MaxPool,
+		b.Config.TCPTimeout,
+		b.Config.JoinTimeout,
+		b.Config.ModuleLogger("webrtc-transport"),
+	)
+}
+
+// dataChannelConfig returns the configuration of the WebRTC data channels.
+func (b *Babble) dataChannelConfig() net.DataChannelConfig {
+	conf := net.DataChannelConfig{
+		Unordered:          b.Config.WebRTCUnordered,
+		BufferedAmountHigh: uint64(b.Config.WebRTCBufferedHigh),
+		BufferedAmountLow:  uint64(b.Config.WebRTCBufferedLow),
+	}
+
+	if b.Config.WebRTCMaxRetransmits >= 0 {
+		retransmits := uint16(b.Config.WebRTCMaxRetransmits)
+		conf.MaxRetransmits = &retransmits
+	}
+
+	return conf
+}
+
+func (b *Babble) newTCPTransport() (*net.NetworkTransport, error) {
+	advertise := b.Config.AdvertiseAddr
+	if addr := b.initNAT(); addr != "" && advertise == "" {
+		advertise = addr
+	}
+
+	if b.Config.TorControl != "" {
+		onion, err := tor.Publish(
+			b.Config.TorControl,
+			b.Config.TorControlPassword,
+			b.Config.OnionKeyFile(),
+			b.Config.BindAddr,
+			b.Config.TCPTimeout,
+			b.Config.ModuleLogger("tor"),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		b.onion = onion
+
+		if advertise == "" {
+			advertise = onion.Addr()
+		}
+	}
+
+	tcpTransport, err := net.NewTCPTransport(
+		b.Config.BindAddr,
+		advertise,
+		b.Config.MaxPool,
+		b.Config.TCPTimeout,
+		b.Config.JoinTimeout,
+		b.Config.ModuleLogger("transport"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Config.TorProxy != "" {
+		if err := tcpTransport.SetTorProxy(b.Config.TorProxy); err != nil {
+			return nil, err
+		}
+	}
+
+	return tcpTransport, nil
+}
+
+// initNAT maps the port of the BindAddr on the router, if the nat option is
+// set, and returns the external address of the mapping. Nodes can still run
+// without a mapping, for example behind a router which forwards the port, so
+// failures are only logged.
+func (b *Babble) initNAT() string {
+	n, err := nat.Parse(b.Config.NAT)
+	if err != nil {
+		b.logger.WithError(err).Warn("Cannot find a router to map the port")
+		return ""
+	}
+	if n == nil {
+		return ""
+	}
+
+	mapping, err := nat.Map(n, b.Config.BindAddr, nat.DefaultLifetime, b.Config.ModuleLogger("nat"))
+	if err != nil {
+		b.logger.WithError(err).Warn("Cannot map the port")
+		return ""
+	}
+
+	b.natMapping = mapping
+
+	return mapping.Addr()
+}
+
+// filterRules returns the allowlists and blocklists of the configuration.
+func (b *Babble) filterRules() net.FilterRules {
+	return net.FilterRules{
+		AllowIPs:     net.ParseFilterList(b.Config.AllowIPs),
+		BlockIPs:     net.ParseFilterList(b.Config.BlockIPs),
+		AllowPubKeys: net.ParseFilterList(b.Config.AllowPubKeys),
+		BlockPubKeys: net.ParseFilterList(b.Config.BlockPubKeys),
+	}
+}
+
 func (b *Babble) initPeers() error {
+	if _, err := os.Stat(b.Config.GenesisFile()); err == nil {
+		if err := b.initGenesis(); err != nil {
+			return err
+		}
+	}
+
+	if b.Config.SignedGenesis && b.Genesis == nil {
+		return fmt.Errorf("signed-genesis requires a genesis document: %s", b.Config.GenesisFile())
+	}
+
 	peerStore := peers.NewJSONPeerSet(b.Config.DataDir, true)
 
 	participants, err := peerStore.PeerSet()
+
+	if b.Genesis != nil {
+		if err := b.verifyGenesis(participants); err != nil {
+			return err
+		}
+	}
+
 	if err != nil {
-		return err
+		if b.Genesis == nil {
+			return err
+		}
+		// Without a peers.json file, the node starts with the genesis peers
+		b.logger.Debugf("could not read peers.json: %v", err)
+		participants = b.GenesisPeers
 	}
 
 	b.Peers = participants
 
 	b.logger.Debug("Loaded Peers")
 
+	// The genesis document takes precedence over peers.genesis.json
+	if b.Genesis != nil {
+		return nil
+	}
+
 	// Set Genesis Peer Set from peers.genesis.json
 	genesisPeerStore := peers.NewJSONPeerSet(b.Config.DataDir, false)
 
@@ -243,7 +735,123 @@ func (b *Babble) initPeers() error {
 	return nil
 }
 
+// initGenesis reads the genesis document, which defines the genesis peers and
+// the network ID of the node.
+func (b *Babble) initGenesis() error {
+	g, err := genesis.Read(b.Config.GenesisFile())
+	if err != nil {
+		return err
+	}
+
+	networkID, err := g.NetworkID()
+	if err != nil {
+		return err
+	}
+
+	thresholds, err := g.Consensus.Thresholds()
+	if err != nil {
+		return err
+	}
+
+	b.Genesis = g
+	b.GenesisPeers = g.PeerSet()
+	b.Config.NetworkID = networkID
+	b.Config.SuperMajority = g.Consensus.SuperMajority
+	b.Config.Trust = g.Consensus.Trust
+	b.Config.PeerSetInterval = g.Consensus.PeerSetInterval
+	b.Config.GenesisAppHash = g.AppHash
+
+	b.applyConsensusParams(b.Config)
+
+	b.logger.WithFields(logrus.Fields{
+		"chain_id":          g.ChainID,
+		"network_id":        networkID,
+		"super_majority":    thresholds.SuperMajority,
+		"trust":             thresholds.Trust,
+		"peer_set_interval": g.Consensus.PeerSetInterval,
+		"app_hash":          g.AppHash,
+		"sync_limit":        b.Config.SyncLimit,
+		"cache_size":        b.Config.CacheSize,
+		"suspend_limit":     b.Config.SuspendLimit,
+	}).Debug("Loaded Genesis")
+
+	return nil
+}
+
+// applyConsensusParams sets the options of a configuration which are defined
+// by the consensus parameters of the genesis document, so that all the
+// validators run with the same values. They take precedence over the options
+// of the node, which are reported when they were set to other values.
+func (b *Babble) applyConsensusParams(c *config.Config) {
+	if b.Genesis == nil {
+		return
+	}
+
+	params := []struct {
+		option  string
+		genesis int
+		def     int
+		value   *int
+	}{
+		{"sync-limit", b.Genesis.Consensus.SyncLimit, config.DefaultSyncLimit, &c.SyncLimit},
+		{"cache-size", b.Genesis.Consensus.CacheSize, config.DefaultCacheSize, &c.CacheSize},
+		{"suspend-limit", b.Genesis.Consensus.SuspendLimit, config.DefaultSuspendLimit, &c.SuspendLimit},
+	}
+
+	for _, p := range params {
+		if p.genesis == 0 || p.genesis == *p.value {
+			continue
+		}
+
+		if *p.value != p.def {
+			b.logger.WithFields(logrus.Fields{
+				"option":  p.option,
+				"node":    *p.value,
+				"genesis": p.genesis,
+			}).Warn("The genesis document overrides the option of the node")
+		}
+
+		*p.value = p.genesis
+	}
+}
+
+// verifyGenesis checks that the genesis document, and the peers of the
+// peers.json file, if any, are signed by all the genesis validators. Without a
+// signatures file, the files are only refused if SignedGenesis is set.
+func (b *Babble) verifyGenesis(peerSet *peers.PeerSet) error {
+	signatures, err := genesis.ReadSignatures(b.Config.GenesisSignaturesFile())
+	if os.IsNotExist(err) && !b.Config.SignedGenesis {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	hash, err := genesis.BootstrapHash(b.Genesis, peerSet)
+	if err != nil {
+		return err
+	}
+
+	missing, err := signatures.Verify(b.Genesis, hash)
+	if err != nil {
+		return fmt.Errorf("Verifying the signatures of the genesis document and peers.json: %v", err)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%d of the %d genesis validators have not signed the genesis document and peers.json", len(missing), len(b.Genesis.Peers))
+	}
+
+	b.logger.WithField("hash", signatures.Hash).Debug("Verified the signatures of the genesis document and peers.json")
+
+	return nil
+}
+
 func (b *Babble) initStore() error {
+	// Keep the store set before Init, like the stores given by embedders
+	if b.Store != nil {
+		return nil
+	}
+
 	if !b.Config.Store {
 		b.logger.Debug("Creating InmemStore")
 		b.Store = h.NewInmemStore(b.Config.CacheSize)
@@ -271,11 +879,7 @@ func (b *Babble) initStore() error {
 
 		b.logger.WithField("path", dbPath).Debug("Opening BadgerStore")
 
-		dbStore, err := h.NewBadgerStore(
-			b.Config.CacheSize,
-			dbPath,
-			b.Config.MaintenanceMode,
-			b.logger)
+		dbStore, err := b.openBadgerStore(dbPath)
 		if err != nil {
 			return err
 		}
@@ -332,12 +936,139 @@ func (b *Babble) initNode() error {
 		b.Config.Proxy,
 	)
 
+	// The address book only helps to reconnect, so the node starts with an
+	// in-memory address book if the file cannot be loaded.
+	addrBook, err := peers.NewAddressBook(b.Config.DataDir)
+	if err != nil {
+		b.logger.WithError(err).Warn("Cannot load address book")
+		addrBook, _ = peers.NewAddressBook("")
+	}
+	b.Node.SetAddressBook(addrBook)
+
+	if b.Config.TrustedBlock != "" {
+		trustedBlock, err := b.readTrustedBlock()
+		if err != nil {
+			return err
+		}
+		b.Node.SetTrustedBlock(trustedBlock)
+	}
+
 	return b.Node.Init()
 }
 
+// readTrustedBlock parses the trusted block, and reads its validator-set.
+func (b *Babble) readTrustedBlock() (*node.TrustedBlock, error) {
+	validators, err := peers.ReadPeerSet(b.Config.TrustedValidators)
+	if err != nil {
+		return nil, fmt.Errorf("trusted-validators: %v", err)
+	}
+
+	return node.ParseTrustedBlock(b.Config.TrustedBlock, validators)
+}
+
+func (b *Babble) initTracing() error {
+	if b.Config.TracingEndpoint == "" {
+		return nil
+	}
+
+	b.logger.WithField("endpoint", b.Config.TracingEndpoint).Debug("Exporting traces")
+
+	provider, err := tracing.NewTracerProvider(
+		b.Config.TracingEndpoint,
+		b.Config.TracingInsecure,
+		b.Config.TracingSampleRatio,
+		fmt.Sprintf("babble-%s", b.Config.Moniker),
+	)
+	if err != nil {
+		return err
+	}
+
+	b.tracerProvider = provider
+
+	return nil
+}
+
+func (b *Babble) initAlerts() error {
+	webhooks, err := alert.ParseWebhooks(b.Config.AlertWebhooks)
+	if err != nil {
+		return err
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	b.logger.WithField("webhooks", len(webhooks)).Debug("Sending alerts")
+
+	b.alerts = alert.NewDispatcher(webhooks, b.Config.ModuleLogger("alert"))
+
+	return nil
+}
+
+// initTxAuthorizer combines the TxAuthorizer of the configuration with the
+// authorizers of the TxAPIKeys and TxClientKeys. The service is given the
+// combination by initService. The AppProxy, whose transactions carry no API
+// key, is only given the TxAuthorizer of the configuration and the client keys.
+func (b *Babble) initTxAuthorizer() error {
+	proxyAuthorizers := []proxy.TxAuthorizer{}
+
+	if b.Config.TxAuthorizer != nil {
+		proxyAuthorizers = append(proxyAuthorizers, b.Config.TxAuthorizer)
+	}
+
+	if b.Config.TxClientKeys != "" {
+		clients, err := proxy.ReadClientKeys(b.Config.TxClientKeys)
+		if err != nil {
+			return err
+		}
+		b.logger.WithField("clients", len(clients)).Debug("Authorizing signed transactions")
+		proxyAuthorizers = append(proxyAuthorizers, proxy.NewSignatureAuthorizer(clients))
+	}
+
+	serviceAuthorizers := proxyAuthorizers
+	if b.Config.TxAPIKeys != "" {
+		serviceAuthorizers = append(serviceAuthorizers, proxy.NewAPIKeyAuthorizer(b.Config.TxAPIKeys))
+	}
+
+	if len(serviceAuthorizers) > 0 {
+		b.txAuthorizer = proxy.AnyTxAuthorizer(serviceAuthorizers...)
+	}
+
+	if len(proxyAuthorizers) == 0 {
+		return nil
+	}
+
+	if ta, ok := b.Config.Proxy.(proxy.TxAuthorizable); ok {
+		ta.SetTxAuthorizer(proxy.AnyTxAuthorizer(proxyAuthorizers...))
+	} else if b.Config.Proxy != nil {
+		b.logger.Warn("The AppProxy does not authorize transactions")
+	}
+
+	return nil
+}
+
+// initIdempotency creates the cache of idempotency keys shared by the service
+// and the AppProxy, unless the IdempotencyWindow is 0.
+func (b *Babble) initIdempotency() error {
+	if b.Config.IdempotencyWindow == 0 {
+		return nil
+	}
+
+	b.idempotency = proxy.NewIdempotencyCache(b.Config.IdempotencyWindow)
+
+	if ia, ok := b.Config.Proxy.(proxy.IdempotencyAware); ok {
+		ia.SetIdempotencyCache(b.idempotency)
+	}
+
+	return nil
+}
+
 func (b *Babble) initService() error {
 	if !b.Config.NoService {
-		b.Service = service.NewService(b.Config.ServiceAddr, b.Node, b.Config.Logger())
+		b.Service = service.NewService(b.Config, b.Node)
+		b.Service.SetReloader(b.reloadService)
+		b.Service.SetTxAuthorizer(b.txAuthorizer)
+		b.Service.SetIdempotencyCache(b.idempotency)
 	}
 	return nil
 }
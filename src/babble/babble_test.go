@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/mosaicnetworks/babble/src/common"
 	"github.com/mosaicnetworks/babble/src/config"
 	bkeys "github.com/mosaicnetworks/babble/src/crypto/keys"
 	"github.com/mosaicnetworks/babble/src/dummy"
+	"github.com/mosaicnetworks/babble/src/genesis"
 	"github.com/mosaicnetworks/babble/src/peers"
 )
 
@@ -115,3 +118,286 @@ func TestMaintenanceMode(t *testing.T) {
 
 	babble.Node.Shutdown()
 }
+
+func TestReload(t *testing.T) {
+	os.RemoveAll("test_data")
+	os.Mkdir("test_data", os.ModeDir|0777)
+	defer os.RemoveAll("test_data")
+
+	key, _ := bkeys.GenerateECDSAKey()
+	peer := peers.NewPeer(bkeys.PublicKeyHex(&key.PublicKey), "addr0", "peer0")
+
+	if err := peers.NewJSONPeerSet("test_data", true).Write([]*peers.Peer{peer}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	newConfig := func() *config.Config {
+		c := config.NewDefaultConfig()
+		c.SetDataDir("test_data")
+		c.MaintenanceMode = true
+		c.NoService = true
+		return c
+	}
+
+	conf := newConfig()
+	conf.Key = key
+	conf.Proxy = dummy.NewInmemDummyClient(conf.Logger())
+
+	babble := NewBabble(conf)
+
+	if err := babble.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer babble.Node.Shutdown()
+
+	// An invalid config is not applied at all
+	invalid := newConfig()
+	invalid.HeartbeatTimeout = 2 * conf.HeartbeatTimeout
+	invalid.LogModules = "node"
+
+	if _, _, err := babble.Reload(invalid); err == nil {
+		t.Fatal("Reloading an invalid config should fail")
+	}
+	if conf.HeartbeatTimeout == invalid.HeartbeatTimeout {
+		t.Fatal("The heartbeat of an invalid config should not be applied")
+	}
+
+	c := newConfig()
+	c.LogLevel = "warn"
+	c.HeartbeatTimeout = 2 * conf.HeartbeatTimeout
+	c.SlowHeartbeatTimeout = 2 * conf.SlowHeartbeatTimeout
+	c.CacheSize = conf.CacheSize / 2
+	c.Moniker = "new-moniker"
+	c.Metered = true
+
+	applied, ignored, err := babble.Reload(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedApplied := []string{"log", "heartbeat", "slow-heartbeat", "cache-size", "metered"}
+	if fmt.Sprint(applied) != fmt.Sprint(expectedApplied) {
+		t.Fatalf("Applied options should be %v, not %v", expectedApplied, applied)
+	}
+	if fmt.Sprint(ignored) != "[moniker]" {
+		t.Fatalf("Ignored options should be [moniker], not %v", ignored)
+	}
+
+	if conf.HeartbeatTimeout != c.HeartbeatTimeout || conf.CacheSize != c.CacheSize || !conf.Metered {
+		t.Fatal("The config of the node should have been updated")
+	}
+	if level, _ := conf.Logging().Levels(); level.String() != "warning" {
+		t.Fatalf("The log level should be warning, not %s", level)
+	}
+	if conf.Moniker == c.Moniker {
+		t.Fatal("The moniker should not have been reloaded")
+	}
+
+	// Reloading the same config does not change anything
+	applied, ignored, err = babble.Reload(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 || len(ignored) != 1 {
+		t.Fatalf("Reloading the same config should only report the moniker, not %v %v", applied, ignored)
+	}
+}
+
+func TestInitGenesis(t *testing.T) {
+	os.RemoveAll("test_data")
+	os.Mkdir("test_data", os.ModeDir|0777)
+	defer os.RemoveAll("test_data")
+
+	peerSlice := []*peers.Peer{}
+	for i := 0; i < 3; i++ {
+		key, _ := bkeys.GenerateECDSAKey()
+		peerSlice = append(peerSlice, peers.NewPeer(
+			bkeys.PublicKeyHex(&key.PublicKey),
+			fmt.Sprintf("addr%d", i),
+			fmt.Sprintf("peer%d", i),
+		))
+	}
+
+	conf := config.NewDefaultConfig()
+	conf.SetDataDir("test_data")
+
+	g := genesis.NewGenesis("testnet", peers.NewPeerSet(peerSlice), "")
+	if err := g.Write(conf.GenesisFile()); err != nil {
+		t.Fatal(err)
+	}
+
+	babble := NewBabble(conf)
+
+	// Without a peers.json file, the node starts with the genesis peers
+	if err := babble.initPeers(); err != nil {
+		t.Fatal(err)
+	}
+
+	if babble.Peers.Hex() != babble.GenesisPeers.Hex() || babble.Peers.Len() != 3 {
+		t.Fatal("The peers should be the genesis peers")
+	}
+
+	networkID, _ := babble.Genesis.NetworkID()
+	if conf.NetworkID == "" || conf.NetworkID != networkID {
+		t.Fatalf("The network ID should be %s, not %s", networkID, conf.NetworkID)
+	}
+
+	// The limits of the genesis document override the options of the node,
+	// also when the configuration is reloaded
+	g.Consensus.SyncLimit = 500
+	g.Consensus.SuspendLimit = 200
+	if err := g.Write(conf.GenesisFile()); err != nil {
+		t.Fatal(err)
+	}
+
+	conf = config.NewDefaultConfig()
+	conf.SetDataDir("test_data")
+	conf.SuspendLimit = 50
+
+	babble = NewBabble(conf)
+	if err := babble.initGenesis(); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.SyncLimit != 500 || conf.SuspendLimit != 200 || conf.CacheSize != config.DefaultCacheSize {
+		t.Fatalf("The genesis limits should be applied, not %d %d %d", conf.SyncLimit, conf.SuspendLimit, conf.CacheSize)
+	}
+
+	reloaded := config.NewDefaultConfig()
+	babble.applyConsensusParams(reloaded)
+	if reloaded.SyncLimit != 500 || reloaded.SuspendLimit != 200 {
+		t.Fatalf("The genesis limits should be applied to reloaded configurations, not %d %d", reloaded.SyncLimit, reloaded.SuspendLimit)
+	}
+}
+
+func TestCheckDataDir(t *testing.T) {
+	os.RemoveAll("test_data")
+	os.Mkdir("test_data", os.ModeDir|0777)
+	defer os.RemoveAll("test_data")
+
+	conf := config.NewDefaultConfig()
+	conf.SetDataDir("test_data")
+
+	// Without a private key, the datadir is refused
+	if err := NewBabble(conf).checkDataDir(); err == nil {
+		t.Fatal("A datadir without a private key should be refused")
+	}
+
+	key, _ := bkeys.GenerateECDSAKey()
+	if err := bkeys.NewSimpleKeyfile(conf.Keyfile()).WriteKey(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// The temporary file of an interrupted write is removed, and the missing
+	// public key rewritten
+	tmp := filepath.Join("test_data", "peers.json"+common.TempFileSuffix)
+	if err := ioutil.WriteFile(tmp, []byte("[{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewBabble(conf).checkDataDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Fatal("The temporary file should be removed")
+	}
+
+	pub, err := ioutil.ReadFile(filepath.Join("test_data", publicKeyFile))
+	if err != nil || string(pub) != bkeys.PublicKeyHex(&key.PublicKey) {
+		t.Fatalf("The public key file should be rewritten: %v", err)
+	}
+
+	// A database is created in a temporary directory, which replaces the
+	// leftovers of an interrupted creation
+	babble := NewBabble(conf)
+
+	if err := os.MkdirAll(conf.DatabaseDir+databaseInitSuffix, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := babble.openBadgerStore(conf.DatabaseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if _, err := os.Stat(conf.DatabaseDir + databaseInitSuffix); !os.IsNotExist(err) {
+		t.Fatal("The temporary database directory should be removed")
+	}
+
+	if _, err := os.Stat(filepath.Join(conf.DatabaseDir, databaseManifest)); err != nil {
+		t.Fatalf("The database should be initialized: %v", err)
+	}
+
+	// A partial database is refused
+	if err := os.Remove(filepath.Join(conf.DatabaseDir, databaseManifest)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := babble.openBadgerStore(conf.DatabaseDir); err == nil {
+		t.Fatal("A database without a manifest should be refused")
+	}
+}
+
+func TestInitDataDir(t *testing.T) {
+	os.RemoveAll("test_data")
+	os.Mkdir("test_data", os.ModeDir|0777)
+	defer os.RemoveAll("test_data")
+
+	net1 := "0X" + strings.Repeat("AB", 32)
+	net2 := "0X" + strings.Repeat("CD", 32)
+
+	newConf := func(network string) *config.Config {
+		conf := config.NewDefaultConfig()
+		conf.SetDataDir("test_data")
+		conf.Network = network
+		return conf
+	}
+
+	// With the flat layout, the datadir is used
+	babble := NewBabble(newConf(""))
+	if err := babble.initDataDir(); err != nil || babble.Config.DataDir != "test_data" {
+		t.Fatalf("The flat datadir should be used: %s, %v", babble.Config.DataDir, err)
+	}
+
+	// The only network subdirectory is selected, with its database
+	os.Mkdir(filepath.Join("test_data", net1), 0700)
+
+	babble = NewBabble(newConf(""))
+	if err := babble.initDataDir(); err != nil {
+		t.Fatal(err)
+	}
+	if babble.Config.DataDir != filepath.Join("test_data", net1) ||
+		babble.Config.DatabaseDir != filepath.Join("test_data", net1, config.DefaultBadgerFile) {
+		t.Fatalf("The network subdirectory should be used, not %s, %s", babble.Config.DataDir, babble.Config.DatabaseDir)
+	}
+
+	// Several networks require a selection, by a prefix of the network ID
+	os.Mkdir(filepath.Join("test_data", net2), 0700)
+
+	if err := NewBabble(newConf("")).initDataDir(); err == nil {
+		t.Fatal("A datadir with several networks should require a selection")
+	}
+
+	babble = NewBabble(newConf("cdcd"))
+	if err := babble.initDataDir(); err != nil || babble.Config.DataDir != filepath.Join("test_data", net2) {
+		t.Fatalf("The network should be selected by prefix: %s, %v", babble.Config.DataDir, err)
+	}
+
+	// Selecting it again is a no-op
+	if err := babble.initDataDir(); err != nil || babble.Config.DataDir != filepath.Join("test_data", net2) {
+		t.Fatalf("Selecting the network again should not change it: %s, %v", babble.Config.DataDir, err)
+	}
+
+	if err := NewBabble(newConf("0XEF")).initDataDir(); err == nil {
+		t.Fatal("An unknown network should be refused")
+	}
+
+	// An interrupted migration is refused
+	os.Mkdir(filepath.Join("test_data", "0X"+strings.Repeat("EF", 32)+config.NetworkInitSuffix), 0700)
+
+	if err := NewBabble(newConf("0XAB")).initDataDir(); err == nil {
+		t.Fatal("An interrupted migration should be refused")
+	}
+}
@@ -0,0 +1,132 @@
+package babble
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/sirupsen/logrus"
+)
+
+// Channels runs several independent Babble engines, the channels, in one
+// process, over a single Transport. Each channel has its own configuration,
+// which gives its key, its AppProxy, and its data directory, where its peers
+// and its database are. The RPCs of the channels are addressed by their
+// channel ID, so the nodes of a channel only gossip with the same channel on
+// their peers.
+type Channels struct {
+	mux *net.ChannelMux
+
+	enginesLock sync.Mutex
+	engines     map[string]*Babble
+
+	logger *logrus.Entry
+}
+
+// NewChannels creates the Channels sharing a Transport, which is closed by
+// Shutdown.
+func NewChannels(trans net.Transport, logger *logrus.Entry) *Channels {
+	if logger == nil {
+		log := logrus.New()
+		log.Level = logrus.DebugLevel
+		logger = logrus.NewEntry(log)
+	}
+
+	return &Channels{
+		mux:     net.NewChannelMux(trans, logger.WithField("component", "channels")),
+		engines: make(map[string]*Babble),
+		logger:  logger,
+	}
+}
+
+// Add creates and initialises the Babble engine of a channel. The data
+// directory and the database of the channel must not be shared with another
+// channel. Only one channel can run the HTTP service, because the service
+// registers its handlers with the DefaultServeMux, so the other channels must
+// set NoService.
+func (c *Channels) Add(id string, conf *config.Config) (*Babble, error) {
+	c.enginesLock.Lock()
+	defer c.enginesLock.Unlock()
+
+	for other, engine := range c.engines {
+		if filepath.Clean(engine.Config.DataDir) == filepath.Clean(conf.DataDir) {
+			return nil, fmt.Errorf("channel %q has the same data directory as channel %q", id, other)
+		}
+		if engine.Config.Store && conf.Store &&
+			filepath.Clean(engine.Config.DatabaseDir) == filepath.Clean(conf.DatabaseDir) {
+			return nil, fmt.Errorf("channel %q has the same database as channel %q", id, other)
+		}
+		if !engine.Config.NoService && !conf.NoService {
+			return nil, fmt.Errorf("channel %q cannot run a service, channel %q already runs one", id, other)
+		}
+	}
+
+	trans, err := c.mux.Channel(id)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := NewBabble(conf)
+	engine.Transport = trans
+
+	if err := engine.Init(); err != nil {
+		trans.Close()
+		return nil, err
+	}
+
+	c.engines[id] = engine
+
+	c.logger.WithField("channel", id).Debug("Added channel")
+
+	return engine, nil
+}
+
+// Get returns the Babble engine of a channel, or nil if there is none.
+func (c *Channels) Get(id string) *Babble {
+	c.enginesLock.Lock()
+	defer c.enginesLock.Unlock()
+
+	return c.engines[id]
+}
+
+// IDs returns the IDs of the channels, sorted.
+func (c *Channels) IDs() []string {
+	return c.mux.Channels()
+}
+
+// Run runs all the channels, and returns when they have all stopped.
+func (c *Channels) Run() {
+	c.enginesLock.Lock()
+	engines := make([]*Babble, 0, len(c.engines))
+	for _, engine := range c.engines {
+		engines = append(engines, engine)
+	}
+	c.enginesLock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, engine := range engines {
+		wg.Add(1)
+		go func(engine *Babble) {
+			defer wg.Done()
+			engine.Run()
+		}(engine)
+	}
+	wg.Wait()
+}
+
+// Shutdown stops the nodes of all the channels, and closes the shared
+// Transport.
+func (c *Channels) Shutdown() {
+	c.enginesLock.Lock()
+	defer c.enginesLock.Unlock()
+
+	for _, engine := range c.engines {
+		engine.Node.Shutdown()
+	}
+
+	if err := c.mux.Close(); err != nil {
+		c.logger.WithError(err).Warn("Closing transport")
+	}
+}
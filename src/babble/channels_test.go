@@ -0,0 +1,116 @@
+package babble
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/config"
+	bkeys "github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/dummy"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestChannels(t *testing.T) {
+	os.RemoveAll("test_data")
+	defer os.RemoveAll("test_data")
+
+	// Two hosts, connected by a single transport each
+	addrs := make([]string, 2)
+	transports := make([]*net.InmemTransport, 2)
+	for i := range transports {
+		addrs[i], transports[i] = net.NewInmemTransport("")
+	}
+	transports[0].Connect(addrs[1], transports[1])
+	transports[1].Connect(addrs[0], transports[0])
+
+	hosts := make([]*Channels, 2)
+	for i := range hosts {
+		hosts[i] = NewChannels(transports[i], common.NewTestEntry(t, common.TestLogLevel))
+	}
+
+	// Both hosts run both channels, with different keys and peer-sets
+	channelIDs := []string{"a", "b"}
+	apps := map[string][]*dummy.InmemDummyClient{}
+
+	for _, id := range channelIDs {
+		confs := make([]*config.Config, 2)
+		peerSlice := []*peers.Peer{}
+
+		for i := range hosts {
+			key, _ := bkeys.GenerateECDSAKey()
+			peerSlice = append(peerSlice, peers.NewPeer(
+				bkeys.PublicKeyHex(&key.PublicKey),
+				addrs[i],
+				fmt.Sprintf("%s%d", id, i),
+			))
+
+			conf := config.NewTestConfig(t, common.TestLogLevel)
+			conf.SetDataDir(filepath.Join("test_data", id, fmt.Sprintf("host%d", i)))
+			conf.Key = key
+			conf.Moniker = fmt.Sprintf("%s%d", id, i)
+			conf.NoService = true
+			conf.HeartbeatTimeout = 10 * time.Millisecond
+			confs[i] = conf
+		}
+
+		for i, conf := range confs {
+			if err := os.MkdirAll(conf.DataDir, 0777); err != nil {
+				t.Fatal(err)
+			}
+			if err := peers.NewJSONPeerSet(conf.DataDir, true).Write(peerSlice); err != nil {
+				t.Fatal(err)
+			}
+
+			app := dummy.NewInmemDummyClient(conf.Logger())
+			conf.Proxy = app
+			apps[id] = append(apps[id], app)
+
+			if _, err := hosts[i].Add(id, conf); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// A channel cannot be added twice, nor share the data of another channel
+	conf := config.NewTestConfig(t, common.TestLogLevel)
+	conf.SetDataDir(filepath.Join("test_data", "a", "host0"))
+	conf.NoService = true
+	if _, err := hosts[0].Add("c", conf); err == nil {
+		t.Fatal("A channel should not share the data directory of another")
+	}
+
+	for _, host := range hosts {
+		go host.Run()
+		defer host.Shutdown()
+	}
+
+	// Each channel commits its own transactions only
+	apps["a"][0].SubmitTx([]byte("tx-a"))
+	apps["b"][1].SubmitTx([]byte("tx-b"))
+
+	expected := map[string][]byte{"a": []byte("tx-a"), "b": []byte("tx-b")}
+
+	timeout := time.After(10 * time.Second)
+	for _, id := range channelIDs {
+		for _, app := range apps[id] {
+			for len(app.GetCommittedTransactions()) == 0 {
+				select {
+				case <-timeout:
+					t.Fatalf("Timeout waiting for channel %q to commit its transaction", id)
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+
+			txs := app.GetCommittedTransactions()
+			if len(txs) != 1 || !bytes.Equal(txs[0], expected[id]) {
+				t.Fatalf("Channel %q should only commit %q, not %q", id, expected[id], txs)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package babble
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	h "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/sirupsen/logrus"
+)
+
+// publicKeyFile is the name of the file, next to the private key, where babble
+// keygen writes the public key.
+const publicKeyFile = "key.pub"
+
+// databaseInitSuffix is the suffix of the directory where a new database is
+// created, before it is renamed into place.
+const databaseInitSuffix = ".init"
+
+// databaseManifest is the file that Badger creates first in a database. A
+// database directory with files but no manifest was not fully created.
+const databaseManifest = "MANIFEST"
+
+// bootstrapFiles are the files of the datadir written through temporary files.
+var bootstrapFiles = []string{
+	config.DefaultKeyfile,
+	publicKeyFile,
+	"peers.json",
+	"peers.genesis.json",
+	config.DefaultGenesisFile,
+	config.DefaultGenesisSignaturesFile,
+}
+
+// initDataDir selects the subdirectory of the network, when the datadir has a
+// subdirectory per network. The other files of the node, like the database,
+// are then found in that subdirectory.
+func (b *Babble) initDataDir() error {
+	dataDir := b.Config.DataDir
+
+	if err := b.Config.UseNetworkDataDir(); err != nil {
+		return err
+	}
+
+	if b.Config.DataDir != dataDir {
+		b.logger.WithFields(logrus.Fields{
+			"datadir": b.Config.DataDir,
+			"db":      b.Config.DatabaseDir,
+		}).Info("Using network subdirectory")
+	}
+
+	return nil
+}
+
+// checkDataDir detects a datadir left half-initialized by a crash or a failed
+// setup. It repairs what can be recomputed: it removes the temporary files of
+// interrupted writes, and rewrites a missing public key file. It returns a
+// clear error for what cannot, a missing or incomplete private key.
+func (b *Babble) checkDataDir() error {
+	dataDir := b.Config.DataDir
+
+	for _, name := range bootstrapFiles {
+		tmp := filepath.Join(dataDir, name+common.TempFileSuffix)
+
+		if _, err := os.Stat(tmp); err != nil {
+			continue
+		}
+
+		b.logger.WithField("file", tmp).Warn("Removing the temporary file of an interrupted write")
+
+		if err := os.Remove(tmp); err != nil {
+			return err
+		}
+	}
+
+	// The key was passed in the configuration, for example by the mobile
+	// bindings, so the datadir has no key files.
+	if b.Config.Key != nil {
+		return nil
+	}
+
+	keyfile := b.Config.Keyfile()
+
+	key, err := keys.NewSimpleKeyfile(keyfile).ReadKey()
+	if err != nil {
+		return fmt.Errorf("The datadir %s is incomplete, the private key %s is missing or unreadable: %v. Create it with babble keygen, or restore it from a backup", dataDir, keyfile, err)
+	}
+
+	pubKeyFile := filepath.Join(filepath.Dir(keyfile), publicKeyFile)
+
+	if _, err := os.Stat(pubKeyFile); os.IsNotExist(err) {
+		b.logger.WithField("file", pubKeyFile).Warn("Rewriting the missing public key file")
+
+		if err := common.WriteFileAtomic(pubKeyFile, []byte(keys.PublicKeyHex(&key.PublicKey)), 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openBadgerStore opens the database, creating it first in a temporary
+// directory, renamed into place once Badger has initialized it, so that a
+// crash during its creation does not leave a partial database. The temporary
+// directory of an interrupted creation is removed. A database that was left
+// partial by an older version is refused.
+func (b *Babble) openBadgerStore(dbPath string) (*h.BadgerStore, error) {
+	tmp := dbPath + databaseInitSuffix
+
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dbPath)
+
+	switch {
+	case os.IsNotExist(err) || (err == nil && len(files) == 0):
+		b.logger.WithField("path", tmp).Debug("Creating BadgerStore")
+
+		store, err := h.NewBadgerStore(b.Config.CacheSize, tmp, false, b.logger)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := store.Close(); err != nil {
+			return nil, err
+		}
+
+		if err := os.RemoveAll(dbPath); err != nil {
+			return nil, err
+		}
+
+		if err := os.Rename(tmp, dbPath); err != nil {
+			return nil, err
+		}
+
+		if err := common.SyncDir(filepath.Dir(dbPath)); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if _, err := os.Stat(filepath.Join(dbPath, databaseManifest)); os.IsNotExist(err) {
+			return nil, fmt.Errorf("The database %s is incomplete, it has no %s. Remove it to start afresh, or restore it from a backup", dbPath, databaseManifest)
+		}
+	}
+
+	return h.NewBadgerStore(
+		b.Config.CacheSize,
+		dbPath,
+		b.Config.MaintenanceMode,
+		b.logger)
+}
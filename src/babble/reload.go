@@ -0,0 +1,215 @@
+package babble
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/logging"
+	"github.com/mosaicnetworks/babble/src/service"
+	"github.com/sirupsen/logrus"
+)
+
+// reloadableKeys are the options that can be changed while the node is
+// running. They do not affect consensus, nor the identity and connections of
+// the node.
+var reloadableKeys = map[string]bool{
+	"log":                   true,
+	"log-modules":           true,
+	"heartbeat":             true,
+	"slow-heartbeat":        true,
+	"service-rate-limit":    true,
+	"service-rate-burst":    true,
+	"service-tx-rate-limit": true,
+	"service-tx-rate-burst": true,
+	"cache-size":            true,
+	"max-bytes-per-hour":    true,
+	"max-memory":            true,
+	"metered":               true,
+	"sync-dedup-window":     true,
+}
+
+// ReloadConfig loads the configuration with ConfigLoader and applies it with
+// Reload.
+func (b *Babble) ReloadConfig() (applied []string, ignored []string, err error) {
+	if b.ConfigLoader == nil {
+		return nil, nil, fmt.Errorf("No config loader")
+	}
+
+	c, err := b.ConfigLoader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return b.Reload(c)
+}
+
+// Reload applies the options of a new configuration that can be changed
+// without restarting the node: the log levels, the heartbeat timeouts, the rate
+// limits of the service, the data and memory budgets, metered mode, and the
+// size of the consensus caches. The new
+// configuration is validated as a whole before any option is applied, so that
+// an invalid configuration leaves the node unchanged. It returns the options
+// that were applied, and the ones that changed but require a restart, which
+// are ignored.
+func (b *Babble) Reload(c *config.Config) (applied []string, ignored []string, err error) {
+	b.reloadLock.Lock()
+	defer b.reloadLock.Unlock()
+
+	// Normalise the new configuration like ValidateConfig, so that only
+	// actual changes are reported
+	c.SetDataDir(c.DataDir)
+	if c.MaintenanceMode {
+		c.Bootstrap = true
+	}
+	if c.Bootstrap {
+		c.Store = true
+	}
+	if c.SlowHeartbeatTimeout < c.HeartbeatTimeout {
+		c.SlowHeartbeatTimeout = c.HeartbeatTimeout
+	}
+
+	// The options defined by the genesis document cannot be changed
+	b.applyConsensusParams(c)
+
+	newModules, err := logging.ParseModuleLevels(c.LogModules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.HeartbeatTimeout <= 0 {
+		return nil, nil, fmt.Errorf("heartbeat must be positive")
+	}
+
+	if c.ServiceRateLimit < 0 || c.ServiceTxRateLimit < 0 {
+		return nil, nil, fmt.Errorf("service rate limits cannot be negative")
+	}
+
+	if c.CacheSize <= 0 {
+		return nil, nil, fmt.Errorf("cache-size must be positive")
+	}
+
+	if c.MaxBytesPerHour < 0 {
+		return nil, nil, fmt.Errorf("max-bytes-per-hour cannot be negative")
+	}
+
+	if c.MaxMemory < 0 {
+		return nil, nil, fmt.Errorf("max-memory cannot be negative")
+	}
+
+	if c.SyncDedupWindow < 0 {
+		return nil, nil, fmt.Errorf("sync-dedup-window cannot be negative")
+	}
+
+	applied, ignored = configChanges(b.Config, c)
+
+	changed := make(map[string]bool)
+	for _, key := range applied {
+		changed[key] = true
+	}
+
+	if changed["log"] {
+		b.Config.Logging().SetLevel("", config.LogLevel(c.LogLevel))
+		b.Config.LogLevel = c.LogLevel
+	}
+
+	if changed["log-modules"] {
+		oldModules, _ := logging.ParseModuleLevels(b.Config.LogModules)
+		for module := range oldModules {
+			if _, ok := newModules[module]; !ok {
+				b.Config.Logging().ResetLevel(module)
+			}
+		}
+		for module, level := range newModules {
+			b.Config.Logging().SetLevel(module, level)
+		}
+		b.Config.LogModules = c.LogModules
+	}
+
+	if changed["heartbeat"] || changed["slow-heartbeat"] {
+		b.Node.SetHeartbeatTimeouts(c.HeartbeatTimeout, c.SlowHeartbeatTimeout)
+	}
+
+	if changed["service-rate-limit"] || changed["service-rate-burst"] ||
+		changed["service-tx-rate-limit"] || changed["service-tx-rate-burst"] {
+		b.Config.ServiceRateLimit = c.ServiceRateLimit
+		b.Config.ServiceRateBurst = c.ServiceRateBurst
+		b.Config.ServiceTxRateLimit = c.ServiceTxRateLimit
+		b.Config.ServiceTxRateBurst = c.ServiceTxRateBurst
+
+		if b.Service != nil {
+			b.Service.SetRateLimits(
+				c.ServiceRateLimit,
+				c.ServiceRateBurst,
+				c.ServiceTxRateLimit,
+				c.ServiceTxRateBurst,
+			)
+		}
+	}
+
+	if changed["cache-size"] {
+		b.Node.SetCacheSize(c.CacheSize)
+	}
+
+	if changed["max-bytes-per-hour"] {
+		b.Node.SetMaxBytesPerHour(c.MaxBytesPerHour)
+	}
+
+	if changed["max-memory"] {
+		b.Node.SetMaxMemory(c.MaxMemory)
+	}
+
+	if changed["metered"] {
+		b.Node.SetMetered(c.Metered)
+	}
+
+	if changed["sync-dedup-window"] {
+		b.Node.SetSyncDedupWindow(c.SyncDedupWindow)
+	}
+
+	b.logger.WithFields(logrus.Fields{
+		"applied": applied,
+		"ignored": ignored,
+	}).Info("Reloaded config")
+
+	if len(ignored) > 0 {
+		b.logger.WithField("ignored", ignored).Warn("Some options require a restart to take effect")
+	}
+
+	return applied, ignored, nil
+}
+
+// reloadService is the function called by the /admin/reload endpoint.
+func (b *Babble) reloadService() (service.ConfigReload, error) {
+	applied, ignored, err := b.ReloadConfig()
+	return service.ConfigReload{Applied: applied, Ignored: ignored}, err
+}
+
+// configChanges compares two configurations and returns the options that
+// changed, split between those that can be reloaded and the others. Options are
+// identified by their names in config files.
+func configChanges(old, new *config.Config) (reloadable []string, other []string) {
+	reloadable, other = []string{}, []string{}
+
+	ov := reflect.ValueOf(old).Elem()
+	nv := reflect.ValueOf(new).Elem()
+
+	for i := 0; i < ov.NumField(); i++ {
+		key := ov.Type().Field(i).Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+
+		if reloadableKeys[key] {
+			reloadable = append(reloadable, key)
+		} else {
+			other = append(other, key)
+		}
+	}
+
+	return reloadable, other
+}
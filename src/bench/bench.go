@@ -0,0 +1,430 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/service"
+)
+
+// minTxSize is the minimum size of a transaction. The first bytes of a
+// transaction are an identifier of the run and a sequence number, so that
+// every transaction is unique; the service identifies transactions by their
+// content.
+const minTxSize = 16
+
+// Metrics scraped from the nodes.
+const (
+	metricCPU        = "process_cpu_seconds_total"
+	metricMemory     = "process_resident_memory_bytes"
+	metricGoroutines = "go_goroutines"
+	metricBlocks     = "babble_node_blocks_committed_total"
+)
+
+// Config is the configuration of a run.
+type Config struct {
+	// Targets are the addresses of the HTTP services of the nodes, like
+	// 127.0.0.1:8000 or http://127.0.0.1:8000. Transactions are submitted to
+	// the targets in turn.
+	Targets []string
+
+	// Rate is the number of transactions submitted per second.
+	Rate int
+
+	// Size is the size of a transaction, in bytes.
+	Size int
+
+	// Duration is the time during which transactions are submitted.
+	Duration time.Duration
+
+	// Concurrency is the maximum number of transactions waiting to be
+	// committed. Transactions are dropped, and not submitted, when the limit
+	// is reached, so that a slow network does not lower the rate of the
+	// following transactions.
+	Concurrency int
+
+	// Timeout is the time that each transaction waits to be committed. It is
+	// capped at 30 seconds by the service.
+	Timeout time.Duration
+
+	// Token is sent in the Authorization header, if the service requires
+	// authentication.
+	Token string
+}
+
+// DefaultConfig returns the default configuration of a run, against a local
+// node.
+func DefaultConfig() Config {
+	return Config{
+		Targets:     []string{"127.0.0.1:8000"},
+		Rate:        100,
+		Size:        100,
+		Duration:    10 * time.Second,
+		Concurrency: 1000,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Latency summarises the commit latencies of a run.
+type Latency struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// NodeUsage is the resources used by a node during a run, from its
+// Prometheus metrics.
+type NodeUsage struct {
+	Target string
+
+	// CPU is the CPU time used during the run, and CPUPercent the same time
+	// relative to the duration of the run.
+	CPU        time.Duration
+	CPUPercent float64
+
+	// Memory is the resident memory of the node at the end of the run, in
+	// bytes.
+	Memory int64
+
+	// Goroutines is the number of goroutines at the end of the run.
+	Goroutines int
+
+	// Blocks is the number of Blocks committed during the run.
+	Blocks int
+
+	// Error is set if the metrics of the node could not be scraped.
+	Error string
+}
+
+// Report is the result of a run.
+type Report struct {
+	// Elapsed is the time from the first submission to the last response.
+	Elapsed time.Duration
+
+	// Submitted is the number of transactions submitted. Every submitted
+	// transaction is either Committed, TimedOut, Rejected by the service (for
+	// example by a rate limit), or Failed.
+	Submitted int
+	Committed int
+	TimedOut  int
+	Rejected  int
+	Failed    int
+
+	// Dropped is the number of transactions that were not submitted because
+	// Concurrency transactions were already waiting.
+	Dropped int
+
+	// Throughput is the number of transactions committed per second.
+	Throughput float64
+
+	// Latency is the commit latency of the committed transactions.
+	Latency Latency
+
+	// Nodes is the resource usage of every target.
+	Nodes []NodeUsage
+
+	// Errors counts the distinct errors of the failed and rejected
+	// transactions.
+	Errors map[string]int
+}
+
+// result is the outcome of a single transaction.
+type result struct {
+	latency time.Duration
+	status  int
+	err     string
+}
+
+// Run submits transactions to the targets, as configured, and returns a
+// report once all the submitted transactions are committed or have timed out.
+func Run(conf Config) (*Report, error) {
+	if err := validate(&conf); err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, len(conf.Targets))
+	for i, t := range conf.Targets {
+		targets[i] = targetURL(t)
+	}
+
+	client := &http.Client{
+		// Leave time for the service to respond with a timeout
+		Timeout: conf.Timeout + 5*time.Second,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: conf.Concurrency,
+		},
+	}
+
+	before := scrapeAll(client, targets)
+
+	runID := make([]byte, 8)
+	if _, err := rand.Read(runID); err != nil {
+		return nil, err
+	}
+
+	report := &Report{Errors: make(map[string]int)}
+	latencies := []time.Duration{}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	inflight := make(chan struct{}, conf.Concurrency)
+
+	record := func(r result) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.err != "":
+			report.Failed++
+			report.Errors[r.err]++
+		case r.status == http.StatusOK:
+			report.Committed++
+			latencies = append(latencies, r.latency)
+		case r.status == http.StatusGatewayTimeout:
+			report.TimedOut++
+		default:
+			report.Rejected++
+			report.Errors[fmt.Sprintf("%d %s", r.status, http.StatusText(r.status))]++
+		}
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(conf.Rate))
+	defer ticker.Stop()
+
+	start := time.Now()
+	deadline := start.Add(conf.Duration)
+
+	for seq := uint64(0); ; seq++ {
+		if !time.Now().Before(deadline) {
+			break
+		}
+
+		select {
+		case inflight <- struct{}{}:
+		default:
+			report.Dropped++
+			<-ticker.C
+			continue
+		}
+
+		report.Submitted++
+		wg.Add(1)
+
+		go func(target string, tx []byte) {
+			defer wg.Done()
+			defer func() { <-inflight }()
+			record(submit(client, target, tx, conf))
+		}(targets[seq%uint64(len(targets))], newTx(runID, seq, conf.Size))
+
+		<-ticker.C
+	}
+
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+
+	after := scrapeAll(client, targets)
+
+	if report.Elapsed > 0 {
+		report.Throughput = float64(report.Committed) / report.Elapsed.Seconds()
+	}
+
+	report.Latency = summarise(latencies)
+
+	for i, t := range conf.Targets {
+		report.Nodes = append(report.Nodes, usage(t, before[i], after[i], report.Elapsed))
+	}
+
+	return report, nil
+}
+
+func validate(conf *Config) error {
+	if len(conf.Targets) == 0 {
+		return fmt.Errorf("No targets")
+	}
+	if conf.Rate <= 0 {
+		return fmt.Errorf("Rate must be positive")
+	}
+	if conf.Size < minTxSize {
+		return fmt.Errorf("Size must be at least %d bytes", minTxSize)
+	}
+	if conf.Size > service.MAXTXBYTES {
+		return fmt.Errorf("Size must be at most %d bytes", service.MAXTXBYTES)
+	}
+	if conf.Duration <= 0 {
+		return fmt.Errorf("Duration must be positive")
+	}
+	if conf.Concurrency <= 0 {
+		return fmt.Errorf("Concurrency must be positive")
+	}
+	if conf.Timeout <= 0 {
+		return fmt.Errorf("Timeout must be positive")
+	}
+	return nil
+}
+
+// targetURL reads a target as the base URL of a service.
+func targetURL(target string) string {
+	target = strings.TrimSuffix(target, "/")
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		target = "http://" + target
+	}
+	return target
+}
+
+// newTx creates a random transaction, which starts with the identifier of the
+// run and a sequence number.
+func newTx(runID []byte, seq uint64, size int) []byte {
+	tx := make([]byte, size)
+	rand.Read(tx[minTxSize:])
+	copy(tx, runID)
+	binary.BigEndian.PutUint64(tx[8:], seq)
+	return tx
+}
+
+// submit submits a transaction to /tx/sync and waits for the response.
+func submit(client *http.Client, target string, tx []byte, conf Config) result {
+	url := fmt.Sprintf("%s%s/tx/sync?timeout=%s", target, service.APIPrefix, conf.Timeout)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(tx))
+	if err != nil {
+		return result{err: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if conf.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+conf.Token)
+	}
+
+	start := time.Now()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{err: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	// Read the body so that the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	return result{latency: time.Since(start), status: resp.StatusCode}
+}
+
+// summarise computes the statistics of a set of latencies.
+func summarise(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+
+	return Latency{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(latencies, 50),
+		P90:  percentile(latencies, 90),
+		P99:  percentile(latencies, 99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies, with the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// scrape is the result of scraping the metrics of a node.
+type scrape struct {
+	values map[string]float64
+	err    error
+}
+
+func scrapeAll(client *http.Client, targets []string) []scrape {
+	scrapes := make([]scrape, len(targets))
+	for i, t := range targets {
+		values, err := scrapeMetrics(client, t)
+		scrapes[i] = scrape{values, err}
+	}
+	return scrapes
+}
+
+// scrapeMetrics reads the metrics used in the report from the Prometheus text
+// format.
+func scrapeMetrics(client *http.Client, target string) (map[string]float64, error) {
+	resp, err := client.Get(target + "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /metrics: %s", resp.Status)
+	}
+
+	values := make(map[string]float64)
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case metricCPU, metricMemory, metricGoroutines, metricBlocks:
+			if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				values[fields[0]] = v
+			}
+		}
+	}
+
+	return values, scanner.Err()
+}
+
+// usage computes the resources used by a node between two scrapes.
+func usage(target string, before, after scrape, elapsed time.Duration) NodeUsage {
+	u := NodeUsage{Target: target}
+
+	switch {
+	case before.err != nil:
+		u.Error = before.err.Error()
+		return u
+	case after.err != nil:
+		u.Error = after.err.Error()
+		return u
+	}
+
+	cpu := after.values[metricCPU] - before.values[metricCPU]
+	u.CPU = time.Duration(cpu * float64(time.Second))
+	if elapsed > 0 {
+		u.CPUPercent = 100 * cpu / elapsed.Seconds()
+	}
+
+	u.Memory = int64(after.values[metricMemory])
+	u.Goroutines = int(after.values[metricGoroutines])
+	u.Blocks = int(after.values[metricBlocks] - before.values[metricBlocks])
+
+	return u
+}
@@ -0,0 +1,200 @@
+package bench
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeService commits every transaction after a short delay, except every
+// fifth transaction which times out, and exposes metrics which increase with
+// every scrape.
+type fakeService struct {
+	sync.Mutex
+	txs     map[string]bool
+	count   int32
+	scrapes int
+}
+
+func newFakeService() *fakeService {
+	return &fakeService{txs: make(map[string]bool)}
+}
+
+func (f *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v1/tx/sync":
+		tx, _ := ioutil.ReadAll(r.Body)
+
+		f.Lock()
+		duplicate := f.txs[string(tx)]
+		f.txs[string(tx)] = true
+		f.Unlock()
+
+		if duplicate || len(tx) != 32 {
+			http.Error(w, "bad transaction", http.StatusBadRequest)
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		if atomic.AddInt32(&f.count, 1)%5 == 0 {
+			http.Error(w, "timeout", http.StatusGatewayTimeout)
+			return
+		}
+
+		fmt.Fprint(w, `{"hash":"0X01","block":1,"index":0}`)
+	case "/metrics":
+		f.Lock()
+		f.scrapes++
+		n := f.scrapes
+		f.Unlock()
+
+		fmt.Fprintln(w, "# HELP process_cpu_seconds_total Total user and system CPU time spent in seconds.")
+		fmt.Fprintln(w, "# TYPE process_cpu_seconds_total counter")
+		fmt.Fprintf(w, "process_cpu_seconds_total %d\n", n)
+		fmt.Fprintln(w, "process_resident_memory_bytes 1.048576e+06")
+		fmt.Fprintln(w, "go_goroutines 42")
+		fmt.Fprintf(w, "babble_node_blocks_committed_total %d\n", 10*n)
+		fmt.Fprintln(w, `go_gc_duration_seconds{quantile="0.5"} 0.001`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	fake := newFakeService()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.Targets = []string{server.URL, server.URL + "/"}
+	conf.Rate = 200
+	conf.Size = 32
+	conf.Duration = 500 * time.Millisecond
+
+	report, err := Run(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Submitted == 0 {
+		t.Fatal("No transactions submitted")
+	}
+
+	if report.Failed != 0 || report.Rejected != 0 {
+		t.Fatalf("%d failed and %d rejected transactions: %v", report.Failed, report.Rejected, report.Errors)
+	}
+
+	if report.Committed+report.TimedOut != report.Submitted {
+		t.Fatalf("%d committed and %d timed out transactions, out of %d", report.Committed, report.TimedOut, report.Submitted)
+	}
+
+	if expected := report.Submitted / 5; report.TimedOut != expected {
+		t.Fatalf("TimedOut should be %d, not %d", expected, report.TimedOut)
+	}
+
+	if report.Throughput <= 0 {
+		t.Fatalf("Throughput should be positive, not %f", report.Throughput)
+	}
+
+	l := report.Latency
+	if l.Min < 5*time.Millisecond || l.Min > l.P50 || l.P50 > l.P90 || l.P90 > l.P99 || l.P99 > l.Max {
+		t.Fatalf("Inconsistent latencies: %+v", l)
+	}
+
+	if len(report.Nodes) != 2 {
+		t.Fatalf("Report should have 2 nodes, not %d", len(report.Nodes))
+	}
+
+	for _, n := range report.Nodes {
+		if n.Error != "" {
+			t.Fatalf("Node %s: %s", n.Target, n.Error)
+		}
+		// Each node is scraped twice before, and twice after, the run
+		if n.CPU != 2*time.Second {
+			t.Fatalf("CPU should be 2s, not %v", n.CPU)
+		}
+		if n.Blocks != 20 {
+			t.Fatalf("Blocks should be 20, not %d", n.Blocks)
+		}
+		if n.Memory != 1<<20 {
+			t.Fatalf("Memory should be %d, not %d", 1<<20, n.Memory)
+		}
+		if n.Goroutines != 42 {
+			t.Fatalf("Goroutines should be 42, not %d", n.Goroutines)
+		}
+	}
+}
+
+func TestRunUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	conf := DefaultConfig()
+	conf.Targets = []string{server.URL}
+	conf.Rate = 50
+	conf.Duration = 100 * time.Millisecond
+
+	report, err := Run(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Submitted == 0 || report.Failed != report.Submitted {
+		t.Fatalf("All %d transactions should fail, not %d", report.Submitted, report.Failed)
+	}
+
+	if report.Nodes[0].Error == "" {
+		t.Fatal("Node usage should have an error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []func(*Config){
+		func(c *Config) { c.Targets = nil },
+		func(c *Config) { c.Rate = 0 },
+		func(c *Config) { c.Size = minTxSize - 1 },
+		func(c *Config) { c.Duration = 0 },
+		func(c *Config) { c.Concurrency = 0 },
+		func(c *Config) { c.Timeout = 0 },
+	}
+
+	for i, modify := range cases {
+		conf := DefaultConfig()
+		modify(&conf)
+		if _, err := Run(conf); err == nil {
+			t.Fatalf("Case %d: Run should fail", i)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	l := summarise(latencies)
+
+	expected := Latency{
+		Min:  1 * time.Millisecond,
+		Mean: 50500 * time.Microsecond,
+		P50:  50 * time.Millisecond,
+		P90:  90 * time.Millisecond,
+		P99:  99 * time.Millisecond,
+		Max:  100 * time.Millisecond,
+	}
+
+	if l != expected {
+		t.Fatalf("Latency should be %+v, not %+v", expected, l)
+	}
+
+	if p := percentile([]time.Duration{time.Second}, 99); p != time.Second {
+		t.Fatalf("Percentile of a single latency should be 1s, not %v", p)
+	}
+}
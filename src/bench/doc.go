@@ -0,0 +1,18 @@
+// Package bench is a load generator for Babble networks.
+//
+// It submits synthetic transactions, at a fixed rate and size, to the HTTP
+// service of one or more nodes, and measures how long each transaction takes
+// to be committed in a Block. Transactions are submitted to the /tx/sync
+// endpoint, which only responds once the transaction is committed, so the
+// commit latency includes gossip, consensus, and the processing of the Block
+// by the application.
+//
+// Before and after the run, the Prometheus metrics of every node are scraped
+// to report the resources used by the nodes during the run: CPU time, memory,
+// goroutines, and the number of Blocks committed.
+//
+// The report gives users a standard way to size a deployment: run the load
+// generator against a test network with the same number of nodes, and the same
+// hardware, and increase the rate until the latency or the failures exceed the
+// requirements of the application.
+package bench
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/service"
+)
+
+// Error is returned when the service responds with an error status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound returns true if the error is a 404 response of the service.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// Client calls the HTTP service of a Babble node.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sets the API key, or JWT, sent with every request.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for the requests, for example to
+// configure TLS or timeouts. The default client has no timeout; requests are
+// bounded by their contexts.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// New creates a Client of the service at addr, which is either a URL, like
+// https://node1.example.com, or a host:port, which is reached over plain HTTP.
+func New(addr string, opts ...Option) *Client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(addr, "/") + service.APIPrefix,
+		httpClient: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// SubmitTx submits a transaction, and returns as soon as the node has queued
+// it. The response only contains the hash of the transaction.
+func (c *Client) SubmitTx(ctx context.Context, tx []byte) (*service.TxResponse, error) {
+	var resp service.TxResponse
+	err := c.do(ctx, http.MethodPost, "/tx", nil, tx, &resp)
+	return &resp, err
+}
+
+// SubmitTxAndWait submits a transaction, and waits until it is committed in a
+// block, whose index, and the position of the transaction in it, are in the
+// response. The service waits at most 30 seconds; a shorter wait is set with
+// the deadline of the context.
+func (c *Client) SubmitTxAndWait(ctx context.Context, tx []byte) (*service.TxResponse, error) {
+	query := url.Values{}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline).Truncate(time.Millisecond)
+		if timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		query.Set("timeout", timeout.String())
+	}
+
+	var resp service.TxResponse
+	err := c.do(ctx, http.MethodPost, "/tx/sync", query, tx, &resp)
+	return &resp, err
+}
+
+// GetTx returns the location of a committed transaction by hash. It returns an
+// error for which IsNotFound is true if the transaction is not committed.
+func (c *Client) GetTx(ctx context.Context, hash string) (*service.TxResponse, error) {
+	var resp service.TxResponse
+	err := c.do(ctx, http.MethodGet, "/tx/"+url.PathEscape(hash), nil, nil, &resp)
+	return &resp, err
+}
+
+// GetStats returns statistics about the internal state of the node.
+func (c *Client) GetStats(ctx context.Context) (*node.Stats, error) {
+	var stats node.Stats
+	err := c.do(ctx, http.MethodGet, "/stats", nil, nil, &stats)
+	return &stats, err
+}
+
+// GetInfo returns the identity of the node, the build that it runs, and its
+// features, transports and store backend.
+func (c *Client) GetInfo(ctx context.Context) (*net.NodeInfo, error) {
+	var info net.NodeInfo
+	err := c.do(ctx, http.MethodGet, "/info", nil, nil, &info)
+	return &info, err
+}
+
+// GetBlock returns a block by index.
+func (c *Client) GetBlock(ctx context.Context, index int) (*hg.Block, error) {
+	var block hg.Block
+	err := c.do(ctx, http.MethodGet, "/block/"+strconv.Itoa(index), nil, nil, &block)
+	return &block, err
+}
+
+// ListBlocks returns a page of at most count blocks, starting at start. Next
+// is the start of the following page, or nil if there are no more blocks.
+func (c *Client) ListBlocks(ctx context.Context, start, count int) (*service.BlockPage, error) {
+	query := url.Values{}
+	query.Set("start", strconv.Itoa(start))
+	query.Set("count", strconv.Itoa(count))
+
+	var page service.BlockPage
+	err := c.do(ctx, http.MethodGet, "/blocks", query, nil, &page)
+	return &page, err
+}
+
+// GetPeers returns the current peers of the node.
+func (c *Client) GetPeers(ctx context.Context) ([]*peers.Peer, error) {
+	var res []*peers.Peer
+	err := c.do(ctx, http.MethodGet, "/peers", nil, nil, &res)
+	return res, err
+}
+
+// GetValidators returns the validator-set of a round.
+func (c *Client) GetValidators(ctx context.Context, round int) ([]*peers.Peer, error) {
+	var res []*peers.Peer
+	err := c.do(ctx, http.MethodGet, "/validators/"+strconv.Itoa(round), nil, nil, &res)
+	return res, err
+}
+
+// GetValidatorHistory returns the entire validator-set history, by round.
+func (c *Client) GetValidatorHistory(ctx context.Context) (map[int][]*peers.Peer, error) {
+	var res map[int][]*peers.Peer
+	err := c.do(ctx, http.MethodGet, "/history", nil, nil, &res)
+	return res, err
+}
+
+// ListPeersHashes returns a page of the validator-set hashes committed in at
+// most count blocks, starting at start, each with the hash of the previous
+// block.
+func (c *Client) ListPeersHashes(ctx context.Context, start, count int) (*service.BlockPeersHashPage, error) {
+	query := url.Values{}
+	query.Set("start", strconv.Itoa(start))
+	query.Set("count", strconv.Itoa(count))
+
+	var page service.BlockPeersHashPage
+	err := c.do(ctx, http.MethodGet, "/validators/hashes", query, nil, &page)
+	return &page, err
+}
+
+// ListEvidence returns a page of at most count proofs of misbehaviour
+// collected by the node, starting at position start. The proofs can be checked
+// with their Verify method.
+func (c *Client) ListEvidence(ctx context.Context, start, count int) (*service.EvidencePage, error) {
+	query := url.Values{}
+	query.Set("start", strconv.Itoa(start))
+	query.Set("count", strconv.Itoa(count))
+
+	var page service.EvidencePage
+	err := c.do(ctx, http.MethodGet, "/evidence", query, nil, &page)
+	return &page, err
+}
+
+// do sends a request to the service, and decodes the JSON response into res.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte, res interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(msg)),
+		}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
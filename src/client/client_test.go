@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/service"
+	"github.com/mosaicnetworks/babble/src/testapp"
+)
+
+func TestClient(t *testing.T) {
+	cluster := testapp.NewCluster(t, 2)
+	cluster.Run()
+	defer cluster.Shutdown()
+
+	// The service registers its handlers with the DefaultServeMux
+	service.NewService(config.NewTestConfig(t, common.TestLogLevel), cluster.Nodes[0])
+	server := httptest.NewServer(http.DefaultServeMux)
+	defer server.Close()
+
+	c := New(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	// Stream the blocks from the start, before and after the transactions
+	stream := c.StreamBlocks(ctx, 0)
+	defer stream.Close()
+
+	txs := [][]byte{testapp.NewSetTx("k1", "v1"), testapp.NewSetTx("k2", "v2")}
+
+	var last *service.TxResponse
+	for _, tx := range txs {
+		resp, err := c.SubmitTxAndWait(ctx, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Block == nil || resp.Index == nil {
+			t.Fatalf("The response should contain the location of the transaction: %+v", resp)
+		}
+		last = resp
+	}
+
+	loc, err := c.GetTx(ctx, last.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loc.Block != *last.Block || *loc.Index != *last.Index {
+		t.Fatalf("GetTx should return the location returned by SubmitTxAndWait")
+	}
+
+	if _, err := c.GetTx(ctx, "0X00"); !IsNotFound(err) {
+		t.Fatalf("An unknown transaction should not be found, not %v", err)
+	}
+
+	block, err := c.GetBlock(ctx, *last.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(block.Transactions()[*last.Index], txs[1]) {
+		t.Fatalf("Block %d should contain the transaction at index %d", *last.Block, *last.Index)
+	}
+
+	info, err := c.GetInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PubKey != cluster.Nodes[0].GetPubKey() {
+		t.Fatalf("The info should describe node %s, not %s", cluster.Nodes[0].GetPubKey(), info.PubKey)
+	}
+
+	validators, err := c.GetValidators(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 2 {
+		t.Fatalf("There should be 2 validators, not %d", len(validators))
+	}
+
+	// The stream delivers every block, in order, up to the last transaction
+	seen := 0
+	for seen <= *last.Block {
+		select {
+		case b, ok := <-stream.C():
+			if !ok {
+				t.Fatalf("The stream should not end: %v", stream.Err())
+			}
+			if b.Index() != seen {
+				t.Fatalf("The stream should deliver block %d, not %d", seen, b.Index())
+			}
+			seen++
+		case <-ctx.Done():
+			t.Fatalf("Timeout waiting for block %d", seen)
+		}
+	}
+
+	stream.Close()
+	for range stream.C() {
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("A closed stream should not report an error: %v", err)
+	}
+}
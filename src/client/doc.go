@@ -0,0 +1,25 @@
+// Package client is a Go client of the HTTP service of a Babble node.
+//
+// It wraps the /v1 endpoints of the service, so that applications do not have
+// to build the HTTP requests themselves. Transactions can be submitted without
+// waiting, or submitted and awaited until they are committed in a block.
+// Blocks can be fetched one by one, by page, or streamed from a given index,
+// in which case the client catches up with the past blocks before following
+// the new ones over a WebSocket.
+//
+//  c := client.New("127.0.0.1:8000", client.WithAPIKey("k3y"))
+//
+//  resp, err := c.SubmitTxAndWait(ctx, []byte("tx"))
+//  if err != nil {
+//      return err
+//  }
+//  fmt.Println("committed in block", *resp.Block)
+//
+//  stream := c.StreamBlocks(ctx, 0)
+//  for block := range stream.C() {
+//      fmt.Println(block.Index(), len(block.Transactions()))
+//  }
+//  if err := stream.Err(); err != nil {
+//      return err
+//  }
+package client
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/service"
+)
+
+// streamPageSize is the number of blocks requested per page while a stream
+// catches up with the past blocks.
+const streamPageSize = service.MAXBLOCKS
+
+// errResubscribe is returned by follow when the stream must catch up again,
+// because the service closed the subscription, or a block was skipped.
+var errResubscribe = errors.New("resubscribe")
+
+// BlockStream delivers consecutive blocks, in order, until it is closed or
+// fails.
+type BlockStream struct {
+	ch     chan *hg.Block
+	cancel context.CancelFunc
+	err    error
+}
+
+// C returns the channel where blocks are delivered. It is closed when the
+// stream is closed, when its context is cancelled, or when it fails.
+func (s *BlockStream) C() <-chan *hg.Block {
+	return s.ch
+}
+
+// Err returns the error that ended the stream, or nil if it was closed or
+// cancelled. It must only be called once C is closed.
+func (s *BlockStream) Err() error {
+	return s.err
+}
+
+// Close stops the stream and closes C.
+func (s *BlockStream) Close() {
+	s.cancel()
+}
+
+// StreamBlocks streams the blocks from index from onwards. It first fetches
+// the past blocks page by page, and then follows the new blocks over a
+// WebSocket. It catches up again if the service drops the subscription
+// because the consumer is too slow.
+func (c *Client) StreamBlocks(ctx context.Context, from int) *BlockStream {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s := &BlockStream{
+		ch:     make(chan *hg.Block),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(s.ch)
+		defer cancel()
+
+		err := c.stream(ctx, from, s.ch)
+		if ctx.Err() == nil {
+			s.err = err
+		}
+	}()
+
+	return s
+}
+
+// stream delivers blocks until ctx is done or an error occurs.
+func (c *Client) stream(ctx context.Context, next int, ch chan<- *hg.Block) error {
+	for {
+		// Subscribe before catching up, so as not to miss the blocks committed
+		// in between
+		conn, err := c.subscribe(ctx, node.BlockNotification)
+		if err != nil {
+			return err
+		}
+
+		next, err = c.catchUp(ctx, next, ch)
+		if err == nil {
+			err = c.follow(ctx, conn, &next, ch)
+		}
+
+		conn.Close()
+
+		if err != errResubscribe {
+			return err
+		}
+	}
+}
+
+// catchUp delivers the committed blocks from next onwards, and returns the
+// index of the next block.
+func (c *Client) catchUp(ctx context.Context, next int, ch chan<- *hg.Block) (int, error) {
+	for {
+		page, err := c.ListBlocks(ctx, next, streamPageSize)
+		if err != nil {
+			return next, err
+		}
+
+		for _, block := range page.Blocks {
+			if err := deliver(ctx, block, ch); err != nil {
+				return next, err
+			}
+			next = block.Index() + 1
+		}
+
+		if page.Next == nil {
+			return next, nil
+		}
+	}
+}
+
+// follow delivers the blocks published on the WebSocket, from next onwards.
+func (c *Client) follow(ctx context.Context, conn *websocket.Conn, next *int, ch chan<- *hg.Block) error {
+	// Unblock the reads when the stream is closed
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		var note node.Notification
+		if err := conn.ReadJSON(&note); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+				return errResubscribe
+			}
+			return err
+		}
+
+		if note.Block == nil || note.Block.Index() < *next {
+			continue
+		}
+
+		if note.Block.Index() > *next {
+			return errResubscribe
+		}
+
+		if err := deliver(ctx, note.Block, ch); err != nil {
+			return err
+		}
+		*next++
+	}
+}
+
+// subscribe opens a WebSocket streaming the notifications of the given types.
+func (c *Client) subscribe(ctx context.Context, types ...node.NotificationType) (*websocket.Conn, error) {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+
+	u := c.baseURL + "/ws?subscribe=" + strings.Join(names, ",")
+	if strings.HasPrefix(u, "https://") {
+		u = "wss://" + strings.TrimPrefix(u, "https://")
+	} else {
+		u = "ws://" + strings.TrimPrefix(u, "http://")
+	}
+
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	dialer := *websocket.DefaultDialer
+	if t, ok := c.httpClient.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = t.TLSClientConfig
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, u, header)
+	if err != nil {
+		if resp != nil {
+			return nil, &Error{StatusCode: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func deliver(ctx context.Context, block *hg.Block, ch chan<- *hg.Block) error {
+	select {
+	case ch <- block:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
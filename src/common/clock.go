@@ -0,0 +1,251 @@
+package common
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and creates timers. The node and the transports read
+// the time through a Clock, so that tests and simulations can control it with
+// a FakeClock instead of waiting for real timeouts.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// Sleep blocks until the duration has elapsed.
+	Sleep(d time.Duration)
+
+	// NewTicker returns a Ticker that sends the time on its channel after each
+	// period d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the Clock counterpart of time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker. No more ticks are sent after Stop returns.
+	Stop()
+}
+
+// RealClock is the Clock of the operating system, as provided by the time
+// package.
+type RealClock struct{}
+
+// Now implements the Clock interface.
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// Since implements the Clock interface.
+func (RealClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// After implements the Clock interface.
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Sleep implements the Clock interface.
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// NewTicker implements the Clock interface.
+func (RealClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// FakeClock is a Clock whose time only moves when Advance is called. The
+// timers, sleeps and ticks which expire are released in order of expiry, so
+// tests which drive a FakeClock do not depend on the speed of the machine.
+type FakeClock struct {
+	sync.Mutex
+
+	now     time.Time
+	waiters []*fakeWaiter
+	added   chan struct{}
+}
+
+// fakeWaiter is a pending timer of a FakeClock. Tickers have a period, and are
+// rescheduled after each tick.
+type fakeWaiter struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewFakeClock returns a FakeClock which starts at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:   now,
+		added: make(chan struct{}),
+	}
+}
+
+// Now implements the Clock interface.
+func (c *FakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+// Since implements the Clock interface.
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After implements the Clock interface.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.wait(d, 0).ch
+}
+
+// Sleep implements the Clock interface. It returns when another goroutine
+// advances the clock by d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// NewTicker implements the Clock interface.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return &fakeTicker{
+		clock:  c,
+		waiter: c.wait(d, d),
+	}
+}
+
+// wait registers a waiter which expires after d. Like a time.Timer, its
+// channel has room for one value, so that an expiry is never blocked by a
+// receiver which went away.
+func (c *FakeClock) wait(d time.Duration, period time.Duration) *fakeWaiter {
+	c.Lock()
+	defer c.Unlock()
+
+	w := &fakeWaiter{
+		at:     c.now.Add(d),
+		period: period,
+		ch:     make(chan time.Time, 1),
+	}
+
+	if d <= 0 && period == 0 {
+		w.ch <- c.now
+		return w
+	}
+
+	c.waiters = append(c.waiters, w)
+
+	// Wake up the goroutines blocked in BlockUntil
+	close(c.added)
+	c.added = make(chan struct{})
+
+	return w
+}
+
+// remove unregisters a waiter.
+func (c *FakeClock) remove(w *fakeWaiter) {
+	c.Lock()
+	defer c.Unlock()
+
+	for i, o := range c.waiters {
+		if o == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Advance moves the time forward by d, and releases the timers, sleeps and
+// ticks which expire in the meantime, in order of expiry. Like a time.Ticker,
+// a ticker whose channel is full drops the tick.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	end := c.now.Add(d)
+
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool {
+			return c.waiters[i].at.Before(c.waiters[j].at)
+		})
+
+		if len(c.waiters) == 0 || c.waiters[0].at.After(end) {
+			break
+		}
+
+		w := c.waiters[0]
+		c.now = w.at
+
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+
+	c.now = end
+}
+
+// Waiters returns the number of pending timers, sleeps and tickers.
+func (c *FakeClock) Waiters() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until at least n timers, sleeps or tickers are pending. It
+// lets a test wait for the goroutines under test to reach their timers before
+// advancing the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.Lock()
+		if len(c.waiters) >= n {
+			c.Unlock()
+			return
+		}
+		added := c.added
+		c.Unlock()
+
+		<-added
+	}
+}
+
+type fakeTicker struct {
+	clock  *FakeClock
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.remove(t.waiter)
+}
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockAfter(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := NewFakeClock(start)
+
+	late := c.After(2 * time.Second)
+	early := c.After(time.Second)
+
+	c.Advance(999 * time.Millisecond)
+
+	select {
+	case <-early:
+		t.Fatal("The timer should not expire before its duration")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+
+	if at := <-early; !at.Equal(start.Add(time.Second)) {
+		t.Fatalf("The timer should expire at %v, not %v", start.Add(time.Second), at)
+	}
+
+	select {
+	case <-late:
+		t.Fatal("The later timer should not expire yet")
+	default:
+	}
+
+	c.Advance(time.Hour)
+
+	if at := <-late; !at.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("The timer should expire at %v, not %v", start.Add(2*time.Second), at)
+	}
+
+	if now := c.Now(); !now.Equal(start.Add(time.Hour + time.Second)) {
+		t.Fatalf("The clock should be advanced to %v, not %v", start.Add(time.Hour+time.Second), now)
+	}
+
+	if c.Waiters() != 0 {
+		t.Fatalf("All the timers should have expired, %d are pending", c.Waiters())
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+
+	ticker := c.NewTicker(time.Second)
+
+	for i := 1; i <= 3; i++ {
+		c.Advance(time.Second)
+		if at := <-ticker.C(); at.Unix() != int64(i) {
+			t.Fatalf("Tick %d should be at %ds, not %v", i, i, at)
+		}
+	}
+
+	// Ticks are dropped when the channel is full
+	c.Advance(5 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("The ticks should be dropped when nobody receives them")
+	default:
+	}
+
+	ticker.Stop()
+	c.Advance(time.Second)
+
+	select {
+	case <-ticker.C():
+		t.Fatal("A stopped ticker should not tick")
+	default:
+	}
+}
+
+func TestFakeClockSleep(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(time.Minute)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+
+	<-done
+}
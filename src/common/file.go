@@ -0,0 +1,59 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// TempFileSuffix is the suffix of the temporary files written by
+// WriteFileAtomic. A temporary file left behind is the trace of a write
+// interrupted by a crash.
+const TempFileSuffix = ".tmp"
+
+// WriteFileAtomic writes data to a file through a temporary file in the same
+// directory, which is synced to disk and renamed over the file, so that a crash
+// leaves either the previous content or the new one, never a truncated file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + TempFileSuffix
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return SyncDir(filepath.Dir(path))
+}
+
+// SyncDir syncs a directory to disk, so that the files created, renamed or
+// removed in it survive a crash.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
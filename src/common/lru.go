@@ -2,13 +2,18 @@ package common
 
 // TAKEN FROM HASHICORP LRU
 
-import "container/list"
+import (
+	"container/list"
+	"sync"
+)
 
 // EvictCallback is used to get a callback when a cache entry is evicted
 type EvictCallback func(key interface{}, value interface{})
 
-// LRU implements a non-thread safe fixed size LRU cache
+// LRU implements a thread safe fixed size LRU cache. Get updates the
+// recent-ness of the entries, so concurrent readers need the lock as well.
 type LRU struct {
+	lock      sync.Mutex
 	size      int
 	evictList *list.List
 	items     map[interface{}]*list.Element
@@ -34,6 +39,9 @@ func NewLRU(size int, onEvict EvictCallback) *LRU {
 
 // Purge is used to completely clear the cache
 func (c *LRU) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	for k, v := range c.items {
 		if c.onEvict != nil {
 			c.onEvict(k, v.Value.(*entry).value)
@@ -45,6 +53,9 @@ func (c *LRU) Purge() {
 
 // Add adds a value to the cache.  Returns true if an eviction occurred.
 func (c *LRU) Add(key, value interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	// Check for existing item
 	if ent, ok := c.items[key]; ok {
 		c.evictList.MoveToFront(ent)
@@ -67,6 +78,9 @@ func (c *LRU) Add(key, value interface{}) bool {
 
 // Get looks up a key's value from the cache.
 func (c *LRU) Get(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	if ent, ok := c.items[key]; ok {
 		c.evictList.MoveToFront(ent)
 		return ent.Value.(*entry).value, true
@@ -77,6 +91,9 @@ func (c *LRU) Get(key interface{}) (value interface{}, ok bool) {
 // Contains checks if a key is in the cache, without updating the recent-ness
 // or deleting it for being stale.
 func (c *LRU) Contains(key interface{}) (ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	_, ok = c.items[key]
 	return ok
 }
@@ -84,6 +101,9 @@ func (c *LRU) Contains(key interface{}) (ok bool) {
 // Peek returns the key value (or undefined if not found) without updating
 // the "recently used"-ness of the key.
 func (c *LRU) Peek(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	if ent, ok := c.items[key]; ok {
 		return ent.Value.(*entry).value, true
 	}
@@ -93,6 +113,9 @@ func (c *LRU) Peek(key interface{}) (value interface{}, ok bool) {
 // Remove removes the provided key from the cache, returning if the
 // key was contained.
 func (c *LRU) Remove(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	if ent, ok := c.items[key]; ok {
 		c.removeElement(ent)
 		return true
@@ -102,6 +125,9 @@ func (c *LRU) Remove(key interface{}) bool {
 
 // RemoveOldest removes the oldest item from the cache.
 func (c *LRU) RemoveOldest() (interface{}, interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	ent := c.evictList.Back()
 	if ent != nil {
 		c.removeElement(ent)
@@ -113,6 +139,9 @@ func (c *LRU) RemoveOldest() (interface{}, interface{}, bool) {
 
 // GetOldest returns the oldest entry
 func (c *LRU) GetOldest() (interface{}, interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	ent := c.evictList.Back()
 	if ent != nil {
 		kv := ent.Value.(*entry)
@@ -123,6 +152,9 @@ func (c *LRU) GetOldest() (interface{}, interface{}, bool) {
 
 // Keys returns a slice of the keys in the cache, from oldest to newest.
 func (c *LRU) Keys() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	keys := make([]interface{}, len(c.items))
 	i := 0
 	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
@@ -134,9 +166,29 @@ func (c *LRU) Keys() []interface{} {
 
 // Len returns the number of items in the cache.
 func (c *LRU) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	return c.evictList.Len()
 }
 
+// Resize changes the size of the cache, evicting the oldest items if it
+// contains more than size items. It returns the number of evicted items.
+func (c *LRU) Resize(size int) (evicted int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	diff := c.evictList.Len() - size
+	if diff < 0 {
+		diff = 0
+	}
+	for i := 0; i < diff; i++ {
+		c.removeOldest()
+	}
+	c.size = size
+	return diff
+}
+
 // removeOldest removes the oldest item from the cache.
 func (c *LRU) removeOldest() {
 	ent := c.evictList.Back()
@@ -1,6 +1,9 @@
 package common
 
-import "testing"
+import (
+	"sync"
+	"testing"
+)
 
 func TestLRU(t *testing.T) {
 	evictCounter := 0
@@ -151,3 +154,49 @@ func TestLRU_Peek(t *testing.T) {
 		t.Errorf("should not have updated recent-ness of 1")
 	}
 }
+
+// Test that Resize evicts the oldest items
+func TestLRU_Resize(t *testing.T) {
+	l := NewLRU(4, nil)
+
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+
+	if evicted := l.Resize(2); evicted != 2 {
+		t.Errorf("2 items should have been evicted, not %d", evicted)
+	}
+	if l.Contains(0) || l.Contains(1) {
+		t.Errorf("the oldest items should have been evicted")
+	}
+
+	l.Resize(3)
+	l.Add(4, 4)
+	if l.Len() != 3 {
+		t.Errorf("the cache should contain 3 items, not %d", l.Len())
+	}
+}
+
+func TestLRUConcurrentAccess(t *testing.T) {
+	l := NewLRU(64, nil)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if g%2 == 0 {
+					l.Add(i%128, i)
+				} else {
+					l.Get(i % 128)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if l.Len() != 64 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 	"time"
 
 	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/logging"
 	"github.com/mosaicnetworks/babble/src/proxy"
 	webrtc "github.com/pion/webrtc/v2"
 	"github.com/sirupsen/logrus"
@@ -29,30 +31,127 @@ const (
 	// DefaultCertFile is the default name of the file containing the TLS
 	// certificate for connecting to the signaling server.
 	DefaultCertFile = "cert.pem"
+
+	// DefaultGenesisFile is the default name of the file containing the
+	// genesis document of the network.
+	DefaultGenesisFile = "genesis.json"
+
+	// DefaultGenesisSignaturesFile is the default name of the file containing
+	// the signatures of the genesis document and peers.json file by the
+	// genesis validators.
+	DefaultGenesisSignaturesFile = "genesis.sig.json"
+
+	// DefaultOnionKeyFile is the default name of the file containing the
+	// private key of the onion service of the node.
+	DefaultOnionKeyFile = "onion_key"
+
+	// DefaultDiagnosticsDir is the default name of the folder containing the
+	// diagnostics bundles written when the node crashes.
+	DefaultDiagnosticsDir = "diagnostics"
 )
 
 // Default configuration values.
 const (
+	DefaultNetwork              = ""
 	DefaultLogLevel             = "debug"
+	DefaultLogFormat            = "text"
+	DefaultLogModules           = ""
+	DefaultLogFile              = ""
 	DefaultBindAddr             = "127.0.0.1:1337"
 	DefaultServiceAddr          = "127.0.0.1:8000"
 	DefaultHeartbeatTimeout     = 10 * time.Millisecond
 	DefaultSlowHeartbeatTimeout = 1000 * time.Millisecond
 	DefaultTCPTimeout           = 1000 * time.Millisecond
 	DefaultJoinTimeout          = 10000 * time.Millisecond
+	DefaultJoinAdmissionLimit   = 0
+	DefaultJoinAdmissionWindow  = 5 * time.Second
 	DefaultCacheSize            = 10000
 	DefaultSyncLimit            = 1000
+	DefaultSyncDedupWindow      = 0
+	DefaultPushPull             = false
+	DefaultSyncChunkSize        = 0
+	DefaultAntiEntropyInterval  = time.Minute
+	DefaultCommitBarrier        = false
+	DefaultPrivateTransactions  = false
+	DefaultShadow               = false
+	DefaultPingInterval         = 0
+	DefaultKeepaliveInterval    = 0
+	DefaultPeerSelector         = "random"
+	DefaultRPCWorkers           = 20
+	DefaultHistoryWorkers       = 2
+	DefaultHistoryQueue         = 10
+	DefaultTrustedBlock         = ""
+	DefaultTrustedValidators    = ""
+	DefaultRelay                = false
+	DefaultRelayRoutes          = ""
+	DefaultTorProxy             = ""
+	DefaultTorControl           = ""
+	DefaultTorControlPassword   = ""
+	DefaultRecordRPC            = ""
+	DefaultReplayRPC            = ""
 	DefaultMaxPool              = 2
 	DefaultStore                = false
 	DefaultMaintenanceMode      = false
 	DefaultSuspendLimit         = 100
+	DefaultMaxBytesPerHour      = 0
+	DefaultPeerQuota            = 0
+	DefaultPeerQuotaAction      = "throttle"
+	DefaultMaxMemory            = 0
+	DefaultMetered              = false
+	DefaultOfflineMode          = false
+	DefaultOfflineTimeout       = 30 * time.Second
+	DefaultWatchdogTimeout      = 0
+	DefaultWatchdogRedial       = false
+	DefaultWatchdogFastForward  = false
+	DefaultWatchdogAlert        = false
+	DefaultAlertWebhooks        = ""
+	DefaultAlertBlockLag        = 10
+	DefaultAuditInterval        = 0
+	DefaultAuditBlocks          = 10
+	DefaultSignedGenesis        = false
+	DefaultSnapshotDelta        = false
+	DefaultVerifySnapshots      = false
 	DefaultWebRTC               = false
 	DefaultSignalAddr           = "127.0.0.1:2443"
 	DefaultSignalRealm          = "main"
 	DefaultSignalSkipVerify     = false
+	DefaultSignalEncrypt        = false
 	DefaultICEAddress           = "stun:stun.l.google.com:19302"
 	DefaultICEUsername          = ""
 	DefaultICEPassword          = ""
+	DefaultICEHostOnly          = false
+	DefaultWebRTCUnordered      = false
+	DefaultWebRTCMaxRetransmits = -1
+	DefaultWebRTCBufferedHigh   = 1 << 20
+	DefaultWebRTCBufferedLow    = 256 << 10
+	DefaultAdminToken           = ""
+	DefaultAnnounceAddr         = false
+	DefaultNAT                  = "none"
+	DefaultAllowIPs             = ""
+	DefaultBlockIPs             = ""
+	DefaultAllowPubKeys         = ""
+	DefaultBlockPubKeys         = ""
+	DefaultServiceAPIKeys       = ""
+	DefaultServiceJWTSecret     = ""
+	DefaultServiceReadAuth      = false
+	DefaultServiceCORSOrigins   = "*"
+	DefaultServiceTLSCert       = ""
+	DefaultServiceTLSKey        = ""
+	DefaultServiceRateLimit     = 0
+	DefaultServiceRateBurst     = 20
+	DefaultServiceTxRateLimit   = 0
+	DefaultServiceTxRateBurst   = 10
+	DefaultServiceMaxBodyBytes  = 1 << 20
+	DefaultServiceMaxTxBytes    = 1 << 20
+	DefaultTxAPIKeys            = ""
+	DefaultTxClientKeys         = ""
+	DefaultIdempotencyWindow    = 10 * time.Minute
+	DefaultReadyMaxEventLag     = 100
+	DefaultGraphQL              = false
+	DefaultReadyMaxRoundLag     = 10
+	DefaultTracingEndpoint      = ""
+	DefaultTracingInsecure      = false
+	DefaultTracingSampleRatio   = 1.0
 )
 
 // Config contains all the configuration properties of a Babble node.
@@ -61,9 +160,28 @@ type Config struct {
 	// data
 	DataDir string `mapstructure:"datadir"`
 
+	// Network selects the subdirectory of the datadir holding the files of a
+	// network, when the datadir has a subdirectory per network, named by its
+	// network ID. It is the network ID, or a unique prefix of it. It can be
+	// left empty when the datadir holds a single network.
+	Network string `mapstructure:"network"`
+
 	// LogLevel determines the chattiness of the log output.
 	LogLevel string `mapstructure:"log"`
 
+	// LogFormat is the format of the log output; text or json.
+	LogFormat string `mapstructure:"log-format"`
+
+	// LogModules overrides the log level of specific modules. It is a
+	// comma-separated list of module=level pairs, like
+	// "node=debug,transport=warn". Modules that are not listed use LogLevel.
+	LogModules string `mapstructure:"log-modules"`
+
+	// LogFile is the path of a file where logs are written, instead of
+	// stderr. The file can be rotated at runtime through the /admin/logrotate
+	// endpoint of the HTTP service.
+	LogFile string `mapstructure:"log-file"`
+
 	// BindAddr is the local address:port where this node gossips with other
 	// nodes. in some cases, there may be a routable address that cannot be
 	// bound. Use AdvertiseAddr to advertise a different address to support
@@ -76,6 +194,75 @@ type Config struct {
 	// nodes.
 	AdvertiseAddr string `mapstructure:"advertise"`
 
+	// NAT maps the port of BindAddr on the router with UPnP or NAT-PMP: "none",
+	// "any", "upnp", "pmp", or "pmp:<gateway ip>". When AdvertiseAddr is not
+	// set, the external address of the router is advertised instead. It only
+	// applies to TCP.
+	NAT string `mapstructure:"nat"`
+
+	// AnnounceAddr makes a validator announce its advertised address to the
+	// other validators, with a PEER_ADDRESS InternalTransaction, when it
+	// differs from its address in the validator-set. This keeps the peer-sets
+	// up to date when the public address of the node changes.
+	AnnounceAddr bool `mapstructure:"announce-addr"`
+
+	// AllowIPs and BlockIPs are comma-separated lists of IP addresses or CIDR
+	// ranges. Connections from blocked addresses, or from addresses that are
+	// not allowed when AllowIPs is not empty, are closed before any RPC
+	// reaches the node. They only apply to TCP.
+	AllowIPs string `mapstructure:"allow-ips"`
+	BlockIPs string `mapstructure:"block-ips"`
+
+	// AllowPubKeys and BlockPubKeys are comma-separated lists of hex public
+	// keys. The RPCs of blocked peers, or of peers that are not allowed when
+	// AllowPubKeys is not empty, are refused by the transport. The lists can
+	// be changed at runtime through the /admin/filter endpoint.
+	AllowPubKeys string `mapstructure:"allow-pubkeys"`
+	BlockPubKeys string `mapstructure:"block-pubkeys"`
+
+	// Relay opens both a TCP transport, on BindAddr, and a WebRTC transport,
+	// through the signaling server, so that the node is reachable from both
+	// networks, and forwards the RPCs that other peers send through it to
+	// targets they cannot reach directly. The WebRTC option selects the
+	// transport whose address is advertised.
+	Relay bool `mapstructure:"relay"`
+
+	// RelayRoutes is a comma-separated list of target=relay pairs, like
+	// "0X04AB...=10.0.0.1:1337". RPCs to the target address are sent to the
+	// relay address instead, which forwards them, over TCP if the target is
+	// an IP:PORT address, and over WebRTC if it is a public key.
+	RelayRoutes string `mapstructure:"relay-routes"`
+
+	// TorProxy is the IP:PORT of the SOCKS5 proxy of a Tor client, like
+	// 127.0.0.1:9050. Peer addresses ending in .onion are dialed through it,
+	// and are never resolved locally. Other addresses are still dialed
+	// directly.
+	TorProxy string `mapstructure:"tor-proxy"`
+
+	// TorControl is the IP:PORT of the control port of a Tor client, like
+	// 127.0.0.1:9051. When it is set, the node publishes BindAddr as an onion
+	// service, and advertises its onion address unless AdvertiseAddr is set.
+	// The key of the service is kept in the data directory, so the onion
+	// address does not change across restarts.
+	TorControl string `mapstructure:"tor-control"`
+
+	// TorControlPassword authenticates the node to the control port. Without
+	// it, the node uses the cookie file of Tor, which it must be allowed to
+	// read.
+	TorControlPassword string `mapstructure:"tor-control-password"`
+
+	// RecordRPC is the path of a file where the node records all its inbound
+	// and outbound RPCs, with their responses and timestamps, to replay them
+	// later with ReplayRPC. The file grows quickly, so it should only be set
+	// while investigating a problem.
+	RecordRPC string `mapstructure:"record-rpc"`
+
+	// ReplayRPC is the path of a file recorded with RecordRPC. The node then
+	// does not communicate with other nodes. It receives the recorded inbound
+	// RPCs, at the recorded pace, and its outbound RPCs receive the recorded
+	// responses. It must start from the same state as the recorded node.
+	ReplayRPC string `mapstructure:"replay-rpc"`
+
 	// NoService disables the HTTP API service.
 	NoService bool `mapstructure:"no-service"`
 
@@ -88,6 +275,92 @@ type Config struct {
 	// to use the same endpoint (address:port) as the application's API.
 	ServiceAddr string `mapstructure:"service-listen"`
 
+	// AdminToken protects the administrative endpoints of the HTTP service,
+	// under /admin and /debug. Requests must carry it in an
+	// "Authorization: Bearer <token>" header. The administrative endpoints are
+	// disabled when AdminToken is empty and no other admin credential is
+	// configured.
+	AdminToken string `mapstructure:"admin-token"`
+
+	// ServiceAPIKeys is a comma-separated list of key:role pairs, like
+	// "k3y1:read,k3y2:admin", granting roles to the clients of the HTTP
+	// service. The read role gives access to the read-only endpoints, and the
+	// admin role to all the endpoints. Keys are carried in an "X-API-Key" or
+	// "Authorization: Bearer <key>" header.
+	ServiceAPIKeys string `mapstructure:"service-api-keys"`
+
+	// ServiceJWTSecret enables authentication with JSON Web Tokens signed with
+	// HMAC-SHA256 and this secret. The role is taken from the "role" claim.
+	ServiceJWTSecret string `mapstructure:"service-jwt-secret"`
+
+	// ServiceReadAuth requires the read role, or higher, on all the endpoints
+	// of the HTTP service, except the health probes. When it is false, the
+	// read-only endpoints are public.
+	ServiceReadAuth bool `mapstructure:"service-read-auth"`
+
+	// ServiceCORSOrigins is a comma-separated list of the origins allowed to
+	// make cross-origin requests to the HTTP service. "*" allows all origins.
+	ServiceCORSOrigins string `mapstructure:"service-cors-origins"`
+
+	// ServiceTLSCert and ServiceTLSKey are the paths of a PEM certificate and
+	// private key. When they are set, the HTTP service is served over HTTPS.
+	ServiceTLSCert string `mapstructure:"service-tls-cert"`
+	ServiceTLSKey  string `mapstructure:"service-tls-key"`
+
+	// ServiceRateLimit is the number of requests per second that each client
+	// IP can make to the HTTP service, after an initial burst of
+	// ServiceRateBurst requests. The health probes and metrics are not
+	// limited. 0 disables the limit.
+	ServiceRateLimit float64 `mapstructure:"service-rate-limit"`
+	ServiceRateBurst int     `mapstructure:"service-rate-burst"`
+
+	// ServiceTxRateLimit and ServiceTxRateBurst further limit the rate of the
+	// transactions submitted by each client IP through the /tx endpoints. 0
+	// disables the limit.
+	ServiceTxRateLimit float64 `mapstructure:"service-tx-rate-limit"`
+	ServiceTxRateBurst int     `mapstructure:"service-tx-rate-burst"`
+
+	// ServiceMaxBodyBytes caps the size of the request bodies accepted by the
+	// HTTP service, except on the /tx endpoints, which are capped by
+	// ServiceMaxTxBytes.
+	ServiceMaxBodyBytes int64 `mapstructure:"service-max-body-bytes"`
+	ServiceMaxTxBytes   int64 `mapstructure:"service-max-tx-bytes"`
+
+	// TxAPIKeys is a comma-separated list of API keys that authorize the
+	// transactions submitted through the /tx endpoints of the HTTP service.
+	// They are carried like the ServiceAPIKeys. When it is set, the /tx
+	// endpoints reject the transactions that are neither presented with one
+	// of the keys, nor authorized by TxClientKeys or TxAuthorizer.
+	TxAPIKeys string `mapstructure:"tx-api-keys"`
+
+	// TxClientKeys is the path of a file listing the public keys of the
+	// clients whose signed transactions are authorized, one per line. When it
+	// is set, the HTTP service and the AppProxy reject the transactions that
+	// are not signed by one of the clients, unless another authorizer accepts
+	// them.
+	TxClientKeys string `mapstructure:"tx-client-keys"`
+
+	// IdempotencyWindow is the period during which the transactions submitted
+	// with the idempotency key of a previous transaction, through the /tx
+	// endpoints or the socket AppProxy, are not submitted again. The
+	// submitter gets the result of the original transaction instead. 0
+	// disables idempotency keys.
+	IdempotencyWindow time.Duration `mapstructure:"idempotency-window"`
+
+	// GraphQL enables the /graphql endpoint of the HTTP service, which exposes
+	// events, rounds, blocks and validators to GraphQL queries.
+	GraphQL bool `mapstructure:"graphql"`
+
+	// ReadyMaxEventLag is the maximum number of events, known to other nodes
+	// but not yet received by this node, above which the /readyz endpoint of
+	// the HTTP service reports that the node is not ready.
+	ReadyMaxEventLag int `mapstructure:"ready-max-event-lag"`
+
+	// ReadyMaxRoundLag is the maximum number of undecided rounds above which
+	// the /readyz endpoint of the HTTP service reports that the node is not
+	// ready.
+	ReadyMaxRoundLag int `mapstructure:"ready-max-round-lag"`
+
 	// HeartbeatTimeout is the frequency of the gossip timer when the node has
 	// something to gossip about.
 	HeartbeatTimeout time.Duration `mapstructure:"heartbeat"`
@@ -107,13 +380,120 @@ type Config struct {
 	// JoinTimeout is the timeout of Join Requests
 	JoinTimeout time.Duration `mapstructure:"join_timeout"`
 
+	// JoinAdmissionLimit is the number of JoinRequests admitted per
+	// JoinAdmissionWindow, whose PEER_ADD transactions go through consensus.
+	// The other requesters are given a later window, and told to retry then,
+	// so that many nodes joining together do not thrash the peer-set. 0 admits
+	// all the JoinRequests.
+	JoinAdmissionLimit int `mapstructure:"join-admission-limit"`
+
+	// JoinAdmissionWindow is the period during which at most
+	// JoinAdmissionLimit JoinRequests are admitted.
+	JoinAdmissionWindow time.Duration `mapstructure:"join-admission-window"`
+
 	// SyncLimit defines the max number of hashgraph events to include in a
 	// SyncResponse or EagerSyncRequest
 	SyncLimit int `mapstructure:"sync-limit"`
 
+	// SyncChunkSize is the max size in bytes of the events of a SyncResponse.
+	// A node that is far behind then receives its events in several
+	// responses, instead of one large payload that could exceed the timeout.
+	// The smallest non-zero value of the two nodes is used. 0 means
+	// unlimited.
+	SyncChunkSize int `mapstructure:"sync-chunk-size"`
+
+	// SyncDedupWindow is the period during which an event sent to a peer is
+	// not sent to it again, in a SyncResponse or EagerSyncRequest, even if
+	// the known map of the peer does not include it yet. It saves bandwidth
+	// when many nodes gossip with the same peer at the same time. It should
+	// stay short, a few heartbeats, because a peer that lost the first copy
+	// waits for the window to expire. 0 disables it.
+	SyncDedupWindow time.Duration `mapstructure:"sync-dedup-window"`
+
+	// PushPull enables push-pull gossip, where the node includes its Events in
+	// the SyncRequest, instead of pushing them with an EagerSyncRequest after
+	// the response. It halves the number of round trips of a gossip exchange.
+	// Peers that do not support it ignore the Events, and the node falls back
+	// to the EagerSyncRequest.
+	PushPull bool `mapstructure:"push-pull"`
+
+	// AntiEntropyInterval is the period of the anti-entropy task, which
+	// compares the known events of the node with those of a random peer, and
+	// exchanges the missing events in both directions, bypassing the sync
+	// deduplication. It guarantees that events lost by a lossy transport are
+	// eventually delivered. 0 disables it.
+	AntiEntropyInterval time.Duration `mapstructure:"anti-entropy-interval"`
+
+	// CommitBarrier withholds the signature of a Block until the App has
+	// applied it and returned a state hash. Once the App fails to apply a
+	// Block, the node stops signing Blocks until it restarts, because the state
+	// of the App no longer results from the whole blockchain. A Block signed by
+	// a quorum of validators is then a Block executed by a quorum of Apps.
+	CommitBarrier bool `mapstructure:"commit-barrier"`
+
+	// PrivateTransactions replaces the envelopes addressed to this node, which
+	// are transactions encrypted to a set of validators, with their payloads in
+	// the Blocks committed to the App. Envelopes addressed to other validators
+	// are passed sealed. The Blocks themselves, which are hashed and signed,
+	// always contain the envelopes.
+	PrivateTransactions bool `mapstructure:"private-transactions"`
+
+	// Shadow makes the node join as a shadow validator, which gossips and
+	// verifies blocks like the others, but whose witnesses and signatures are
+	// excluded from quorums. It is used to rehearse a new validator before it
+	// affects the liveness of the network. It is only announced when joining,
+	// and has no effect on genesis validators.
+	Shadow bool `mapstructure:"shadow"`
+
+	// PingInterval is the period of the latency probes, which measure the
+	// round-trip time to every peer with a lightweight PingRequest. 0 disables
+	// them.
+	PingInterval time.Duration `mapstructure:"ping-interval"`
+
+	// KeepaliveInterval is the period of the keepalives, which check the
+	// connection to every peer at the transport level, independently of the
+	// gossip, and evict the pooled connections that are dead. 0 disables
+	// them.
+	KeepaliveInterval time.Duration `mapstructure:"keepalive-interval"`
+
+	// PeerSelector is the strategy used to select the next gossip peer:
+	// "random" selects peers at random, and "latency" favours the peers with
+	// the lowest round-trip time, as measured by the latency probes, while
+	// still selecting every peer regularly. "latency" requires a PingInterval.
+	PeerSelector string `mapstructure:"peer-selector"`
+
+	// RPCWorkers is the number of incoming RPCs processed concurrently. They
+	// are processed independently of the outbound gossip, so that a slow
+	// outbound sync does not delay the responses to other peers. Additional
+	// RPCs wait for a free worker.
+	RPCWorkers int `mapstructure:"rpc-workers"`
+
+	// HistoryWorkers is the number of RPCs serving the history of the
+	// hashgraph to nodes that are behind, FastForwardRequests and the
+	// SyncRequests of peers lagging by more than SyncLimit events, which are
+	// processed concurrently. They are processed by their own pool of workers,
+	// so that serving history does not take the RPC workers from consensus.
+	// 0 processes them with the other RPCs.
+	HistoryWorkers int `mapstructure:"history-workers"`
+
+	// HistoryQueue is the number of RPCs serving history which wait for a
+	// history worker. Further ones are refused, and the requesters try another
+	// peer.
+	HistoryQueue int `mapstructure:"history-queue"`
+
 	// EnableFastSync enables the FastSync protocol.
 	EnableFastSync bool `mapstructure:"fast-sync"`
 
+	// TrustedBlock is a recent block, given as INDEX:HASH, obtained out of
+	// band from a trusted source. A new node fast-forwards from this block,
+	// instead of the latest block of the peer that is the most ahead, and
+	// never downloads the history before it. It requires TrustedValidators.
+	TrustedBlock string `mapstructure:"trusted-block"`
+
+	// TrustedValidators is the path of a peers file containing the
+	// validator-set of the TrustedBlock.
+	TrustedValidators string `mapstructure:"trusted-validators"`
+
 	// Store activates persistant storage.
 	Store bool `mapstructure:"store"`
 
@@ -141,6 +521,112 @@ type Config struct {
 	// node will suspend itself after registering 400 undetermined events.
 	SuspendLimit int `mapstructure:"suspend-limit"`
 
+	// MaxBytesPerHour is the maximum number of bytes that the node sends and
+	// receives over the network in an hour. When the budget is used up, the
+	// node stops initiating gossip until the next hour, but keeps responding to
+	// other nodes. 0 means unlimited.
+	MaxBytesPerHour int64 `mapstructure:"max-bytes-per-hour"`
+
+	// PeerQuota is the maximum number of bytes that the node exchanges with
+	// each peer in an hour. A peer which exceeds it is dealt with according to
+	// PeerQuotaAction until the next hour. 0 means unlimited.
+	PeerQuota int64 `mapstructure:"peer-quota"`
+
+	// PeerQuotaAction is the action taken against the peers which exceed the
+	// PeerQuota: "throttle" limits the RPCs with the peer to one per second,
+	// and "ban" refuses them.
+	PeerQuotaAction string `mapstructure:"peer-quota-action"`
+
+	// MaxMemory is the memory budget of the node, in bytes. When the memory
+	// used by the node approaches the budget, it rejects new transactions,
+	// shrinks its consensus caches, and defers serving FastForward requests,
+	// rather than being killed by the operating system. 0 means unlimited.
+	MaxMemory int64 `mapstructure:"max-memory"`
+
+	// Metered indicates that the node is on a metered connection, like a
+	// cellular network. The node gossips less frequently, and defers
+	// fast-forwarding, which downloads a snapshot, until the connection is not
+	// metered anymore.
+	Metered bool `mapstructure:"metered"`
+
+	// OfflineMode is an experimental mode for nodes that are often cut off
+	// from the network, like mobile meshes. While the node has not gossiped
+	// with a super-majority of the validators within OfflineTimeout, the
+	// submitted transactions are held in a local log instead of Events, and
+	// they are resubmitted automatically when the node is back in contact
+	// with the quorum. They are not final until then.
+	OfflineMode bool `mapstructure:"offline-mode"`
+
+	// OfflineTimeout is the period without a successful gossip with a
+	// validator, after which the validator is considered unreachable in
+	// OfflineMode.
+	OfflineTimeout time.Duration `mapstructure:"offline-timeout"`
+
+	// WatchdogTimeout is the period without new consensus rounds, while
+	// transactions are pending, after which the watchdog reports that
+	// consensus is stalled. It logs a diagnosis of the stall, with the
+	// unreachable peers and the undetermined events, and takes the enabled
+	// recovery actions. 0 disables the watchdog.
+	WatchdogTimeout time.Duration `mapstructure:"watchdog-timeout"`
+
+	// WatchdogRedial makes the watchdog close the pooled connections of the
+	// transport, and repair the missing events with every peer, when consensus
+	// is stalled.
+	WatchdogRedial bool `mapstructure:"watchdog-redial"`
+
+	// WatchdogFastForward makes the watchdog fast-forward the node when
+	// consensus is stalled.
+	WatchdogFastForward bool `mapstructure:"watchdog-fast-forward"`
+
+	// WatchdogAlert makes the watchdog publish an error notification and an
+	// alert, and write a diagnostics bundle, when consensus is stalled.
+	WatchdogAlert bool `mapstructure:"watchdog-alert"`
+
+	// AlertWebhooks is a comma-separated list of URLs which receive the alerts
+	// of the node, like suspensions, forks, state-hash divergences, and peer
+	// evictions, in HTTP POST requests. URLs prefixed with "slack:" are Slack
+	// incoming webhooks, which receive Slack messages; the others receive the
+	// alerts in JSON.
+	AlertWebhooks string `mapstructure:"alert-webhooks"`
+
+	// AlertBlockLag is the number of Blocks that the node can be behind the
+	// other validators, according to the Block signatures that it receives,
+	// before it raises an alert. 0 disables the alert.
+	AlertBlockLag int `mapstructure:"alert-block-lag"`
+
+	// AuditInterval is the period of the integrity audit, which re-derives the
+	// last Blocks, and their Frames, from the consensus Events in the store,
+	// and raises an alert when they differ from the stored values, as a result
+	// of a corrupted store or of a bug. 0 disables the audit.
+	AuditInterval time.Duration `mapstructure:"audit-interval"`
+
+	// AuditBlocks is the number of Blocks, counting back from the last one,
+	// re-derived by each integrity audit.
+	AuditBlocks int `mapstructure:"audit-blocks"`
+
+	// SignedGenesis makes the node refuse to start unless the genesis document
+	// and the peers.json file are signed by all the genesis validators, with
+	// babble genesis sign. The signatures are checked whenever the signatures
+	// file exists, but without this option a node whose file was deleted would
+	// start.
+	SignedGenesis bool `mapstructure:"signed-genesis"`
+
+	// SnapshotDelta determines whether a node that fast-forwards from an older
+	// Block asks its peers for the difference between the snapshot of the App
+	// at that Block and the snapshot it fast-forwards to, instead of the full
+	// snapshot, and whether it serves such differences to its peers. It saves
+	// a lot of traffic with large Apps whose state changes slowly. The App must
+	// implement the SnapshotDeltaHandler interface.
+	SnapshotDelta bool `mapstructure:"snapshot-delta"`
+
+	// VerifySnapshots determines whether a node that fast-forwards, or restores
+	// a Checkpoint, checks that the snapshot of the App matches the state hash
+	// of the signed Block, before restoring it, so that a malicious peer cannot
+	// poison the state of the App. The App must implement the
+	// SnapshotHashHandler interface. Snapshot differences cannot be verified
+	// before they are applied, so the node always requests full snapshots.
+	VerifySnapshots bool `mapstructure:"verify-snapshots"`
+
 	// Moniker defines the friendly name of this node
 	Moniker string `mapstructure:"moniker"`
 
@@ -170,6 +656,13 @@ type Config struct {
 	// attacks. This should be used only for testing.
 	SignalSkipVerify bool `mapstructure:"signal-skip-verify"`
 
+	// SignalEncrypt encrypts the WebRTC offers and answers end-to-end, with
+	// keys derived from the public keys of the peers, so that the signaling
+	// server cannot read or tamper with them. All the WebRTC nodes of the
+	// network must enable it, since the other nodes cannot read the encrypted
+	// offers, and unencrypted offers are refused.
+	SignalEncrypt bool `mapstructure:"signal-encrypt"`
+
 	// ICE address is the URI of a server providing services for ICE, such as
 	// STUN and TURN. The server should support password-based authentication,
 	// as Babble will try to connect with the username and password provided in
@@ -186,6 +679,39 @@ type Config struct {
 	// ICE server defined in ICEAddress.
 	ICEPassword string `mapstructure:"ice-password"`
 
+	// ICEHostOnly restricts ICE to host candidates, ie. the addresses of the
+	// local network interfaces, without any STUN or TURN server. It is meant
+	// for air-gapped local networks, where the ICE servers are unreachable.
+	// ICEAddress, ICEUsername and ICEPassword are ignored.
+	ICEHostOnly bool `mapstructure:"ice-host-only"`
+
+	// WebRTCUnordered lets the messages of the WebRTC data channels opened by
+	// this node be delivered out of order, and WebRTCMaxRetransmits limits the
+	// number of retransmissions of a lost message, -1 meaning unlimited. The
+	// RPCs whose messages are reordered or lost fail, instead of waiting for
+	// retransmissions, which can pay off on lossy links.
+	WebRTCUnordered      bool `mapstructure:"webrtc-unordered"`
+	WebRTCMaxRetransmits int  `mapstructure:"webrtc-max-retransmits"`
+
+	// WebRTCBufferedHigh is the number of bytes waiting to be sent on a WebRTC
+	// data channel above which the writes wait, until the amount falls under
+	// WebRTCBufferedLow, so that large syncs to slow peers do not make the
+	// buffers grow without bounds. 0 disables the backpressure.
+	WebRTCBufferedHigh int64 `mapstructure:"webrtc-buffered-high"`
+	WebRTCBufferedLow  int64 `mapstructure:"webrtc-buffered-low"`
+
+	// TracingEndpoint is the address:port of an OpenTelemetry collector
+	// receiving OTLP traces over gRPC. Tracing is disabled when it is empty.
+	TracingEndpoint string `mapstructure:"tracing-endpoint"`
+
+	// TracingInsecure disables TLS on the connection to the OpenTelemetry
+	// collector.
+	TracingInsecure bool `mapstructure:"tracing-insecure"`
+
+	// TracingSampleRatio is the fraction of traces that are sampled and
+	// exported, between 0 and 1.
+	TracingSampleRatio float64 `mapstructure:"tracing-sample-ratio"`
+
 	// Proxy is the application proxy that enables Babble to communicate with
 	// the application.
 	Proxy proxy.AppProxy
@@ -193,7 +719,40 @@ type Config struct {
 	// Key is the private key of the validator.
 	Key *ecdsa.PrivateKey
 
-	logger *logrus.Logger
+	// TxAuthorizer, if set, authorizes the transactions submitted through the
+	// HTTP service and the AppProxy. The transactions it rejects are still
+	// accepted if TxAPIKeys or TxClientKeys authorize them.
+	TxAuthorizer proxy.TxAuthorizer
+
+	// NetworkID identifies the network of the node. It is the hash of the
+	// genesis document, or empty if there is none. Nodes refuse the RPCs of
+	// nodes that belong to another network.
+	NetworkID string
+
+	// SuperMajority and Trust are the thresholds of the network, as defined
+	// by the genesis document. Empty means the defaults, 2/3 and 1/3.
+	SuperMajority string
+	Trust         string
+
+	// PeerSetInterval, as defined by the genesis document, delays the
+	// membership changes to the next round that is a multiple of it. 0 means
+	// that they take effect 6 rounds after the round in which they were
+	// accepted.
+	PeerSetInterval int
+
+	// GenesisAppHash is the hash of the initial state of the App, in
+	// hexadecimal with the 0X prefix, as defined by the genesis document. When
+	// it is set, the node checks that the App reports the same hash, and
+	// records it in the first Block. The App must implement the
+	// GenesisHandler interface.
+	GenesisAppHash string
+
+	// Clock is the clock of the node timers. Tests and simulations set it to a
+	// common.FakeClock to control time. Nil means the real clock.
+	Clock common.Clock
+
+	logger  *logrus.Logger
+	logging *logging.Registry
 }
 
 // NewDefaultConfig returns a config object with default values. All the default
@@ -203,27 +762,107 @@ type Config struct {
 func NewDefaultConfig() *Config {
 	config := &Config{
 		DataDir:              DefaultDataDir(),
+		Network:              DefaultNetwork,
 		LogLevel:             DefaultLogLevel,
+		LogFormat:            DefaultLogFormat,
+		LogModules:           DefaultLogModules,
+		LogFile:              DefaultLogFile,
 		BindAddr:             DefaultBindAddr,
+		AnnounceAddr:         DefaultAnnounceAddr,
+		NAT:                  DefaultNAT,
+		AllowIPs:             DefaultAllowIPs,
+		BlockIPs:             DefaultBlockIPs,
+		AllowPubKeys:         DefaultAllowPubKeys,
+		BlockPubKeys:         DefaultBlockPubKeys,
 		ServiceAddr:          DefaultServiceAddr,
+		AdminToken:           DefaultAdminToken,
+		ServiceAPIKeys:       DefaultServiceAPIKeys,
+		ServiceJWTSecret:     DefaultServiceJWTSecret,
+		ServiceReadAuth:      DefaultServiceReadAuth,
+		ServiceCORSOrigins:   DefaultServiceCORSOrigins,
+		ServiceTLSCert:       DefaultServiceTLSCert,
+		ServiceTLSKey:        DefaultServiceTLSKey,
+		ServiceRateLimit:     DefaultServiceRateLimit,
+		ServiceRateBurst:     DefaultServiceRateBurst,
+		ServiceTxRateLimit:   DefaultServiceTxRateLimit,
+		ServiceTxRateBurst:   DefaultServiceTxRateBurst,
+		ServiceMaxBodyBytes:  DefaultServiceMaxBodyBytes,
+		ServiceMaxTxBytes:    DefaultServiceMaxTxBytes,
+		TxAPIKeys:            DefaultTxAPIKeys,
+		TxClientKeys:         DefaultTxClientKeys,
+		IdempotencyWindow:    DefaultIdempotencyWindow,
+		GraphQL:              DefaultGraphQL,
+		ReadyMaxEventLag:     DefaultReadyMaxEventLag,
+		ReadyMaxRoundLag:     DefaultReadyMaxRoundLag,
 		HeartbeatTimeout:     DefaultHeartbeatTimeout,
 		SlowHeartbeatTimeout: DefaultSlowHeartbeatTimeout,
 		TCPTimeout:           DefaultTCPTimeout,
 		JoinTimeout:          DefaultJoinTimeout,
+		JoinAdmissionLimit:   DefaultJoinAdmissionLimit,
+		JoinAdmissionWindow:  DefaultJoinAdmissionWindow,
 		CacheSize:            DefaultCacheSize,
 		SyncLimit:            DefaultSyncLimit,
+		SyncDedupWindow:      DefaultSyncDedupWindow,
+		PushPull:             DefaultPushPull,
+		SyncChunkSize:        DefaultSyncChunkSize,
+		AntiEntropyInterval:  DefaultAntiEntropyInterval,
+		CommitBarrier:        DefaultCommitBarrier,
+		PrivateTransactions:  DefaultPrivateTransactions,
+		Shadow:               DefaultShadow,
+		PingInterval:         DefaultPingInterval,
+		KeepaliveInterval:    DefaultKeepaliveInterval,
+		PeerSelector:         DefaultPeerSelector,
+		RPCWorkers:           DefaultRPCWorkers,
+		HistoryWorkers:       DefaultHistoryWorkers,
+		HistoryQueue:         DefaultHistoryQueue,
+		TrustedBlock:         DefaultTrustedBlock,
+		TrustedValidators:    DefaultTrustedValidators,
+		Relay:                DefaultRelay,
+		RelayRoutes:          DefaultRelayRoutes,
+		TorProxy:             DefaultTorProxy,
+		TorControl:           DefaultTorControl,
+		TorControlPassword:   DefaultTorControlPassword,
+		RecordRPC:            DefaultRecordRPC,
+		ReplayRPC:            DefaultReplayRPC,
 		MaxPool:              DefaultMaxPool,
 		Store:                DefaultStore,
 		MaintenanceMode:      DefaultMaintenanceMode,
 		DatabaseDir:          DefaultDatabaseDir(),
 		SuspendLimit:         DefaultSuspendLimit,
+		MaxBytesPerHour:      DefaultMaxBytesPerHour,
+		PeerQuota:            DefaultPeerQuota,
+		PeerQuotaAction:      DefaultPeerQuotaAction,
+		MaxMemory:            DefaultMaxMemory,
+		Metered:              DefaultMetered,
+		OfflineMode:          DefaultOfflineMode,
+		OfflineTimeout:       DefaultOfflineTimeout,
+		WatchdogTimeout:      DefaultWatchdogTimeout,
+		WatchdogRedial:       DefaultWatchdogRedial,
+		WatchdogFastForward:  DefaultWatchdogFastForward,
+		WatchdogAlert:        DefaultWatchdogAlert,
+		AlertWebhooks:        DefaultAlertWebhooks,
+		AlertBlockLag:        DefaultAlertBlockLag,
+		AuditInterval:        DefaultAuditInterval,
+		AuditBlocks:          DefaultAuditBlocks,
+		SignedGenesis:        DefaultSignedGenesis,
+		SnapshotDelta:        DefaultSnapshotDelta,
+		VerifySnapshots:      DefaultVerifySnapshots,
 		WebRTC:               DefaultWebRTC,
 		SignalAddr:           DefaultSignalAddr,
 		SignalRealm:          DefaultSignalRealm,
 		SignalSkipVerify:     DefaultSignalSkipVerify,
+		SignalEncrypt:        DefaultSignalEncrypt,
 		ICEAddress:           DefaultICEAddress,
 		ICEUsername:          DefaultICEUsername,
 		ICEPassword:          DefaultICEPassword,
+		ICEHostOnly:          DefaultICEHostOnly,
+		WebRTCUnordered:      DefaultWebRTCUnordered,
+		WebRTCMaxRetransmits: DefaultWebRTCMaxRetransmits,
+		WebRTCBufferedHigh:   DefaultWebRTCBufferedHigh,
+		WebRTCBufferedLow:    DefaultWebRTCBufferedLow,
+		TracingEndpoint:      DefaultTracingEndpoint,
+		TracingInsecure:      DefaultTracingInsecure,
+		TracingSampleRatio:   DefaultTracingSampleRatio,
 	}
 
 	return config
@@ -253,17 +892,46 @@ func (c *Config) Keyfile() string {
 	return filepath.Join(c.DataDir, DefaultKeyfile)
 }
 
+// OnionKeyFile returns the full path of the file containing the private key of
+// the onion service of the node.
+func (c *Config) OnionKeyFile() string {
+	return filepath.Join(c.DataDir, DefaultOnionKeyFile)
+}
+
+// DiagnosticsDir returns the full path of the folder containing the
+// diagnostics bundles.
+func (c *Config) DiagnosticsDir() string {
+	return filepath.Join(c.DataDir, DefaultDiagnosticsDir)
+}
+
 // CertFile returns the full path of the file containing the signal-server TLS
 // certificate.
 func (c *Config) CertFile() string {
 	return filepath.Join(c.DataDir, DefaultCertFile)
 }
 
+// GenesisFile returns the full path of the file containing the genesis
+// document.
+func (c *Config) GenesisFile() string {
+	return filepath.Join(c.DataDir, DefaultGenesisFile)
+}
+
+// GenesisSignaturesFile returns the full path of the file containing the
+// signatures of the genesis document and peers.json file.
+func (c *Config) GenesisSignaturesFile() string {
+	return filepath.Join(c.DataDir, DefaultGenesisSignaturesFile)
+}
+
 // ICEServers returns a list of ICE servers used by the WebRTCStreamLayer to
 // connect to peers. The list contains a single item which is based on the
 // configuration passed through the config object. This configuration is limited
-// to a single server, with password-based authentication.
+// to a single server, with password-based authentication. The list is empty
+// when ICEHostOnly is set, so that only host candidates are gathered.
 func (c *Config) ICEServers() []webrtc.ICEServer {
+	if c.ICEHostOnly {
+		return nil
+	}
+
 	return []webrtc.ICEServer{
 		{
 			URLs:           []string{c.ICEAddress},
@@ -274,16 +942,85 @@ func (c *Config) ICEServers() []webrtc.ICEServer {
 	}
 }
 
+// secretKeys are the options that Redacted hides.
+var secretKeys = map[string]bool{
+	"admin-token":          true,
+	"service-api-keys":     true,
+	"service-jwt-secret":   true,
+	"tor-control-password": true,
+	"ice-password":         true,
+	"alert-webhooks":       true,
+	"tx-api-keys":          true,
+}
+
+// Redacted returns the options of the configuration, by their names in config
+// files, with the value of the secrets replaced by "REDACTED", so that it can
+// be attached to bug reports. Secrets that are not set are left empty.
+func (c *Config) Redacted() map[string]interface{} {
+	res := make(map[string]interface{})
+
+	v := reflect.ValueOf(c).Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		value := v.Field(i).Interface()
+		if secretKeys[key] && value != "" {
+			value = "REDACTED"
+		}
+
+		res[key] = value
+	}
+
+	return res
+}
+
 // Logger returns a formatted logrus Entry, with prefix set to "babble".
 func (c *Config) Logger() *logrus.Entry {
 	if c.logger == nil {
 		c.logger = logrus.New()
 		c.logger.Level = LogLevel(c.LogLevel)
-		c.logger.Formatter = new(prefixed.TextFormatter)
+		formatter, err := logging.NewFormatter(c.LogFormat)
+		if err != nil {
+			formatter = new(prefixed.TextFormatter)
+		}
+		c.logger.Formatter = formatter
+		if c.LogFile != "" {
+			// Errors opening the file are reported by babble.ValidateConfig
+			if fw, err := logging.NewFileWriter(c.LogFile); err == nil {
+				c.logger.Out = fw
+			}
+		}
 	}
 	return c.logger.WithField("prefix", "babble")
 }
 
+// Logging returns the Registry of module loggers. The loggers share the output
+// and format of the main logger, and their levels are set by LogLevel and
+// LogModules.
+func (c *Config) Logging() *logging.Registry {
+	if c.logging == nil {
+		c.Logger()
+		// Invalid module levels are reported by babble.ValidateConfig
+		modules, _ := logging.ParseModuleLevels(c.LogModules)
+		c.logging = logging.NewRegistry(c.logger, modules)
+	}
+	return c.logging
+}
+
+// ModuleLogger returns a formatted logrus Entry for a specific module, with
+// prefix set to "babble" and component set to the module's name. Its level can
+// be changed independently of the other modules.
+func (c *Config) ModuleLogger(module string) *logrus.Entry {
+	return logrus.NewEntry(c.Logging().Logger(module)).WithFields(logrus.Fields{
+		"prefix":    "babble",
+		"component": module,
+	})
+}
+
 // DefaultDatabaseDir returns the default path for the badger database files.
 func DefaultDatabaseDir() string {
 	return filepath.Join(DefaultDataDir(), DefaultBadgerFile)
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetworkInitSuffix is the suffix of the directory where babble config migrate
+// gathers the files of a flat datadir, before renaming it to the network
+// subdirectory. A directory left with this suffix is an interrupted migration.
+const NetworkInitSuffix = ".init"
+
+// flatLayoutFiles are the files which mark a datadir with the flat layout,
+// where the files of a single network are directly in the datadir.
+var flatLayoutFiles = []string{
+	DefaultKeyfile,
+	DefaultGenesisFile,
+	"peers.json",
+}
+
+// IsNetworkID returns true if a name is a network ID, the 0X-prefixed
+// hexadecimal hash of a genesis document, which names the network
+// subdirectories of a datadir.
+func IsNetworkID(name string) bool {
+	if len(name) != 66 || !strings.EqualFold(name[:2], "0X") {
+		return false
+	}
+
+	_, err := hex.DecodeString(name[2:])
+	return err == nil
+}
+
+// NetworkDirs returns the network subdirectories of a datadir, with the
+// layout where each network has its own subdirectory, named by its network
+// ID. It returns an error if a migration to that layout was interrupted.
+func NetworkDirs(dataDir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var networks []string
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		if strings.HasSuffix(e.Name(), NetworkInitSuffix) && IsNetworkID(strings.TrimSuffix(e.Name(), NetworkInitSuffix)) {
+			return nil, fmt.Errorf("The migration of %s to network subdirectories was interrupted. Run babble config migrate again to complete it", dataDir)
+		}
+
+		if IsNetworkID(e.Name()) {
+			networks = append(networks, e.Name())
+		}
+	}
+
+	return networks, nil
+}
+
+// NetworkDataDir returns the directory holding the files of the selected
+// network. A datadir holds either the files of a single network, with the flat
+// layout, or a subdirectory per network, named by its network ID. Network
+// selects a subdirectory by its network ID, or a unique prefix of it. Without
+// Network, the datadir is used if it has the flat layout, or its only network
+// subdirectory.
+func (c *Config) NetworkDataDir() (string, error) {
+	// Already resolved
+	if c.Network != "" && matchNetwork(filepath.Base(c.DataDir), c.Network) {
+		return c.DataDir, nil
+	}
+
+	networks, err := NetworkDirs(c.DataDir)
+	if err != nil {
+		return "", err
+	}
+
+	if c.Network != "" {
+		var matches []string
+		for _, n := range networks {
+			if matchNetwork(n, c.Network) {
+				matches = append(matches, n)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return "", fmt.Errorf("No network %s in %s", c.Network, c.DataDir)
+		case 1:
+			return filepath.Join(c.DataDir, matches[0]), nil
+		default:
+			return "", fmt.Errorf("Network %s is ambiguous in %s: %s", c.Network, c.DataDir, strings.Join(matches, ", "))
+		}
+	}
+
+	if len(networks) == 0 {
+		return c.DataDir, nil
+	}
+
+	flat := false
+	for _, f := range flatLayoutFiles {
+		if _, err := os.Stat(filepath.Join(c.DataDir, f)); err == nil {
+			flat = true
+		}
+	}
+
+	if flat {
+		return "", fmt.Errorf("%s holds the files of a network and network subdirectories, select one with network: %s", c.DataDir, strings.Join(networks, ", "))
+	}
+
+	if len(networks) == 1 {
+		return filepath.Join(c.DataDir, networks[0]), nil
+	}
+
+	return "", fmt.Errorf("%s holds several networks, select one with network: %s", c.DataDir, strings.Join(networks, ", "))
+}
+
+// UseNetworkDataDir sets the datadir to the directory of the selected network,
+// and the database to its database, unless it was set to another directory.
+func (c *Config) UseNetworkDataDir() error {
+	dir, err := c.NetworkDataDir()
+	if err != nil {
+		return err
+	}
+
+	if dir == c.DataDir {
+		return nil
+	}
+
+	if c.DatabaseDir == filepath.Join(c.DataDir, DefaultBadgerFile) {
+		c.DatabaseDir = filepath.Join(dir, DefaultBadgerFile)
+	}
+
+	c.SetDataDir(dir)
+
+	return nil
+}
+
+// matchNetwork returns true if a network ID starts with a prefix, with or
+// without the 0X.
+func matchNetwork(networkID, prefix string) bool {
+	if !IsNetworkID(networkID) {
+		return false
+	}
+
+	networkID = strings.ToUpper(networkID)
+	prefix = strings.ToUpper(prefix)
+
+	return strings.HasPrefix(networkID, prefix) || strings.HasPrefix(networkID[2:], prefix)
+}
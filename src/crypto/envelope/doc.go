@@ -0,0 +1,14 @@
+// Package envelope encrypts transaction payloads to a set of recipient
+// validators, for private transactions on a shared network.
+//
+// An envelope is an ordinary transaction: it is gossiped, ordered and committed
+// like the others, and every validator signs the Blocks that contain it. Only
+// the payload is confidential. The recipients, the size of the payload, and the
+// position of the transaction in the blockchain remain public.
+//
+// The payload is encrypted with AES-256-GCM under a random content key. The
+// content key is wrapped for every recipient with a key derived from an
+// ephemeral ECDH exchange with the recipient's public key, on the secp256k1
+// curve of the validator keys, so any of the recipients can open the envelope
+// with its private key.
+package envelope
@@ -0,0 +1,219 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+)
+
+// magic prefixes the envelopes, to distinguish them from other transactions.
+var magic = []byte("BBLENV\x01")
+
+// ErrNotRecipient is returned by Open when the key is not one of the
+// recipients of the envelope.
+var ErrNotRecipient = errors.New("Not a recipient of the envelope")
+
+// Recipient is a recipient of an envelope, with the content key wrapped for
+// it.
+type Recipient struct {
+	// ID is the ID of the recipient's public key, as returned by
+	// keys.PublicKeyID
+	ID uint32 `json:"id"`
+
+	// Key is the content key, encrypted with the key shared with the recipient
+	Key []byte `json:"key"`
+}
+
+// Envelope is the decoded form of an encrypted transaction.
+type Envelope struct {
+	// EphemeralKey is the uncompressed public key of the ephemeral key-pair
+	// used to derive the keys shared with the recipients
+	EphemeralKey []byte      `json:"ephemeral_key"`
+	Recipients   []Recipient `json:"recipients"`
+	Nonce        []byte      `json:"nonce"`
+	Ciphertext   []byte      `json:"ciphertext"`
+}
+
+// IsEnvelope returns true if the transaction is an envelope.
+func IsEnvelope(tx []byte) bool {
+	return bytes.HasPrefix(tx, magic)
+}
+
+// Decode decodes an envelope, without opening it.
+func Decode(tx []byte) (*Envelope, error) {
+	if !IsEnvelope(tx) {
+		return nil, fmt.Errorf("Not an envelope")
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(tx[len(magic):], &env); err != nil {
+		return nil, err
+	}
+
+	return &env, nil
+}
+
+// Marshal encodes the envelope into a transaction.
+func (e *Envelope) Marshal() ([]byte, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, magic...), body...), nil
+}
+
+// Seal encrypts a payload to the given recipients, and returns the envelope.
+// The sender must include itself in the recipients to open the envelope later.
+func Seal(payload []byte, recipients []*ecdsa.PublicKey) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("An envelope needs at least one recipient")
+	}
+
+	ephemeral, err := keys.GenerateECDSAKey()
+	if err != nil {
+		return nil, err
+	}
+
+	contentKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, contentKey); err != nil {
+		return nil, err
+	}
+
+	env := Envelope{
+		EphemeralKey: keys.FromPublicKey(&ephemeral.PublicKey),
+	}
+
+	seen := make(map[uint32]bool)
+
+	for _, pub := range recipients {
+		pubBytes := keys.FromPublicKey(pub)
+		if pubBytes == nil {
+			return nil, fmt.Errorf("Invalid recipient public key")
+		}
+
+		id := keys.PublicKeyID(pubBytes)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		kek, err := sharedKey(ephemeral, pub, env.EphemeralKey, pubBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		// Every shared key is only used once, so the nonce can be constant
+		wrapped, err := encrypt(kek, make([]byte, 12), contentKey, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		env.Recipients = append(env.Recipients, Recipient{ID: id, Key: wrapped})
+	}
+
+	env.Nonce = make([]byte, 12)
+	if _, err := io.ReadFull(rand.Reader, env.Nonce); err != nil {
+		return nil, err
+	}
+
+	env.Ciphertext, err = encrypt(contentKey, env.Nonce, payload, env.EphemeralKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return env.Marshal()
+}
+
+// Open decrypts the payload of an envelope with the private key of one of its
+// recipients. It returns ErrNotRecipient if the key is not a recipient.
+func Open(tx []byte, priv *ecdsa.PrivateKey) ([]byte, error) {
+	env, err := Decode(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	pubBytes := keys.FromPublicKey(&priv.PublicKey)
+	id := keys.PublicKeyID(pubBytes)
+
+	ephemeral := keys.ToPublicKey(env.EphemeralKey)
+	if ephemeral == nil || ephemeral.X == nil || !ephemeral.Curve.IsOnCurve(ephemeral.X, ephemeral.Y) {
+		return nil, fmt.Errorf("Invalid ephemeral key")
+	}
+
+	for _, r := range env.Recipients {
+		if r.ID != id {
+			continue
+		}
+
+		kek, err := sharedKey(priv, ephemeral, env.EphemeralKey, pubBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		contentKey, err := decrypt(kek, make([]byte, 12), r.Key, nil)
+		if err != nil {
+			// Another recipient with the same ID
+			continue
+		}
+
+		return decrypt(contentKey, env.Nonce, env.Ciphertext, env.EphemeralKey)
+	}
+
+	return nil, ErrNotRecipient
+}
+
+// sharedKey derives the key shared by the owner of priv and the owner of pub,
+// from their ECDH secret and both the ephemeral and the recipient public keys.
+func sharedKey(priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey, ephemeralKey, recipientKey []byte) ([]byte, error) {
+	x, _ := priv.Curve.ScalarMult(pub.X, pub.Y, priv.D.Bytes())
+	if x == nil || x.Sign() == 0 {
+		return nil, fmt.Errorf("Invalid shared secret")
+	}
+
+	secret := make([]byte, (priv.Curve.Params().BitSize+7)/8)
+	xBytes := x.Bytes()
+	copy(secret[len(secret)-len(xBytes):], xBytes)
+
+	hasher := sha256.New()
+	hasher.Write(secret)
+	hasher.Write(ephemeralKey)
+	hasher.Write(recipientKey)
+	return hasher.Sum(nil), nil
+}
+
+func encrypt(key, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+func decrypt(key, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("Invalid nonce")
+	}
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
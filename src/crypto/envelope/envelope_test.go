@@ -0,0 +1,103 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+)
+
+func generateKeys(t *testing.T, n int) []*ecdsa.PrivateKey {
+	privs := make([]*ecdsa.PrivateKey, n)
+	for i := range privs {
+		priv, err := keys.GenerateECDSAKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		privs[i] = priv
+	}
+	return privs
+}
+
+func TestSealOpen(t *testing.T) {
+	privs := generateKeys(t, 3)
+	payload := []byte("the private transaction")
+
+	tx, err := Seal(payload, []*ecdsa.PublicKey{&privs[0].PublicKey, &privs[1].PublicKey, &privs[1].PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsEnvelope(tx) {
+		t.Fatalf("The sealed transaction should be an envelope")
+	}
+
+	if bytes.Contains(tx, payload) {
+		t.Fatalf("The envelope should not contain the payload")
+	}
+
+	env, err := Decode(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Recipients) != 2 {
+		t.Fatalf("The envelope should have 2 recipients, not %d", len(env.Recipients))
+	}
+
+	for i := 0; i < 2; i++ {
+		opened, err := Open(tx, privs[i])
+		if err != nil {
+			t.Fatalf("Recipient %d: %v", i, err)
+		}
+		if !bytes.Equal(opened, payload) {
+			t.Fatalf("Recipient %d should open %q, not %q", i, payload, opened)
+		}
+	}
+
+	if _, err := Open(tx, privs[2]); err != ErrNotRecipient {
+		t.Fatalf("Open should return ErrNotRecipient, not %v", err)
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	privs := generateKeys(t, 1)
+
+	tx, err := Seal([]byte("the private transaction"), []*ecdsa.PublicKey{&privs[0].PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := Decode(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := append([]byte{}, env.Ciphertext...)
+	env.Ciphertext[0] ^= 0xff
+
+	// The ciphertext of another envelope cannot be opened with this one's keys
+	tx, err = Seal([]byte("another transaction"), []*ecdsa.PublicKey{&privs[0].PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Decode(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Ciphertext = ciphertext
+	other.Nonce = env.Nonce
+
+	for _, e := range []*Envelope{env, other} {
+		tx, err := e.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(tx, privs[0]); err == nil {
+			t.Fatalf("Opening a tampered envelope should fail")
+		}
+	}
+
+	if _, err := Open([]byte("not an envelope"), privs[0]); err == nil {
+		t.Fatalf("Opening a plain transaction should fail")
+	}
+}
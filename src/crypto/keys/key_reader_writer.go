@@ -8,6 +8,8 @@ import (
 	"path"
 	"strings"
 	"sync"
+
+	"github.com/mosaicnetworks/babble/src/common"
 )
 
 // KeyReaderWriter reads and writes ecdsa keys from/to any format or support.
@@ -66,5 +68,5 @@ func (k *SimpleKeyfile) WriteKey(key *ecdsa.PrivateKey) error {
 		return err
 	}
 
-	return ioutil.WriteFile(k.keyfile, []byte(rawKey), 0600)
+	return common.WriteFileAtomic(k.keyfile, []byte(rawKey), 0600)
 }
@@ -1,7 +1,10 @@
 package dummy
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/mosaicnetworks/babble/src/crypto"
 	"github.com/mosaicnetworks/babble/src/hashgraph"
@@ -15,21 +18,35 @@ import (
 // anything useful but save and logs block transactions. The state hash is
 // computed by cumulatively hashing transactions together as they come in.
 // Snapshots correspond to the state hash resulting from executing a block's
-// transactions.
+// transactions. Snapshot deltas are the transactions committed between two
+// blocks.
 type State struct {
+	// txLock guards committedTxs, which the application reads while Babble
+	// commits blocks.
+	txLock       sync.RWMutex
 	committedTxs [][]byte
 	stateHash    []byte
 	snapshots    map[int][]byte
+	txCounts     map[int]int
 	babbleState  state.State
 	logger       *logrus.Entry
 }
 
+// snapshotDelta is the difference between two snapshots of the dummy
+// application: the transactions committed between the two blocks, and the
+// state hash to which they are applied.
+type snapshotDelta struct {
+	FromStateHash []byte
+	Transactions  [][]byte
+}
+
 // NewState creates a new dummy state.
 func NewState(logger *logrus.Entry) *State {
 	state := &State{
 		committedTxs: [][]byte{},
 		stateHash:    []byte{},
 		snapshots:    make(map[int][]byte),
+		txCounts:     make(map[int]int),
 		logger:       logger,
 	}
 
@@ -54,7 +71,10 @@ func (a *State) CommitHandler(block hashgraph.Block) (proxy.CommitResponse, erro
 
 	// Block transactions are ordered. Every Babble node will receive the same
 	// transactions in the same order.
+	a.txLock.Lock()
 	a.committedTxs = append(a.committedTxs, block.Transactions()...)
+	txCount := len(a.committedTxs)
+	a.txLock.Unlock()
 
 	// The state hash is computed by hashing all transactions together.
 	hash := a.stateHash
@@ -67,6 +87,7 @@ func (a *State) CommitHandler(block hashgraph.Block) (proxy.CommitResponse, erro
 	// Store the snapshot (which in the dummy application is the state hash) for
 	// use by the SnapshotHandler.
 	a.snapshots[block.Index()] = hash
+	a.txCounts[block.Index()] = txCount
 
 	// Internal transactions represent requests to add or remove participants
 	// from the Babble peer-set. This decision can be based on the application
@@ -119,9 +140,91 @@ func (a *State) SnapshotHandler(blockIndex int) ([]byte, error) {
 func (a *State) RestoreHandler(snapshot []byte) ([]byte, error) {
 	a.stateHash = snapshot
 
+	// The transactions of the blocks before the snapshot are unknown, so no
+	// delta can start before it.
+	a.txCounts = make(map[int]int)
+
+	return a.stateHash, nil
+}
+
+// HashSnapshotHandler implements the SnapshotHashHandler interface. It is
+// called by Babble, when the verify-snapshots option is set, to check a
+// snapshot before restoring it. The snapshots of the dummy application are
+// its state hashes.
+func (a *State) HashSnapshotHandler(snapshot []byte) ([]byte, error) {
+	return snapshot, nil
+}
+
+// SnapshotDeltaHandler implements the SnapshotDeltaHandler interface. It is
+// called by Babble, when the snapshot-delta option is set, to retrieve the
+// transactions committed between two blocks, which a node whose state
+// corresponds to the first block applies to reach the second.
+func (a *State) SnapshotDeltaHandler(fromIndex, toIndex int) ([]byte, error) {
+	a.logger.WithFields(logrus.Fields{
+		"from": fromIndex,
+		"to":   toIndex,
+	}).Debug("GetSnapshotDelta")
+
+	fromHash, ok := a.snapshots[fromIndex]
+	if !ok {
+		return nil, fmt.Errorf("Snapshot %d not found", fromIndex)
+	}
+
+	from, ok := a.txCounts[fromIndex]
+	if !ok {
+		return nil, fmt.Errorf("Transactions of block %d not found", fromIndex)
+	}
+
+	to, ok := a.txCounts[toIndex]
+	if !ok {
+		return nil, fmt.Errorf("Transactions of block %d not found", toIndex)
+	}
+
+	a.txLock.RLock()
+	defer a.txLock.RUnlock()
+
+	return json.Marshal(snapshotDelta{
+		FromStateHash: fromHash,
+		Transactions:  a.committedTxs[from:to],
+	})
+}
+
+// RestoreDeltaHandler implements the SnapshotDeltaHandler interface. It is
+// called by Babble to apply the transactions returned by the
+// SnapshotDeltaHandler of another node. It fails if the state does not
+// correspond to the block from which they were committed.
+func (a *State) RestoreDeltaHandler(fromIndex int, delta []byte) ([]byte, error) {
+	var d snapshotDelta
+	if err := json.Unmarshal(delta, &d); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(a.stateHash, d.FromStateHash) {
+		return nil, fmt.Errorf("State does not correspond to block %d", fromIndex)
+	}
+
+	hash := a.stateHash
+	for _, tx := range d.Transactions {
+		hash = crypto.SimpleHashFromTwoHashes(hash, crypto.SHA256(tx))
+	}
+
+	a.txLock.Lock()
+	a.committedTxs = append(a.committedTxs, d.Transactions...)
+	a.txLock.Unlock()
+
+	a.stateHash = hash
+	a.txCounts = make(map[int]int)
+
 	return a.stateHash, nil
 }
 
+// GenesisHandler implements the GenesisHandler interface. It is called by
+// Babble, when the genesis document defines an app_hash, to get the hash of the
+// initial state of the application, which is the hash of an empty state.
+func (a *State) GenesisHandler() ([]byte, error) {
+	return crypto.SHA256([]byte{}), nil
+}
+
 // StateChangedHandler implements the ProxyHandler interface. It is called by
 // Babble to notify the application that the node has entered a new state (ex
 // Babbling, Joining, Suspended, etc.).
@@ -131,7 +234,14 @@ func (a *State) StateChangeHandler(state state.State) error {
 	return nil
 }
 
-// GetCommittedTransactions returns the list of committed transactions
+// GetCommittedTransactions returns a copy of the list of committed
+// transactions, which is safe to read while Babble commits blocks.
 func (a *State) GetCommittedTransactions() [][]byte {
-	return a.committedTxs
+	a.txLock.RLock()
+	defer a.txLock.RUnlock()
+
+	txs := make([][]byte, len(a.committedTxs))
+	copy(txs, a.committedTxs)
+
+	return txs
 }
@@ -0,0 +1,34 @@
+// Package genesis defines the genesis document of a Babble network.
+//
+// The genesis document is the explicit definition of a network: a chain ID, the
+// initial validator-set with the voting weight of each validator, the
+// consensus parameters, and the hash of the initial state of the application.
+// It is stored in a genesis.json file in the datadir of every node, for
+// example:
+//
+//	{
+//	  "chain_id": "babble-testnet-1",
+//	  "peers": [
+//	    {"NetAddr": "172.77.5.1:1337", "PubKeyHex": "0X04...", "Moniker": "node0", "Weight": 1},
+//	    {"NetAddr": "172.77.5.2:1337", "PubKeyHex": "0X04...", "Moniker": "node1", "Weight": 1}
+//	  ],
+//	  "consensus": {"root_depth": 10, "coin_round_frequency": 4, "super_majority": "3/4"},
+//	  "app_hash": ""
+//	}
+//
+// The optional super_majority and trust thresholds of the consensus parameters,
+// 2/3 and 1/3 by default, are the fractions of the validators that
+// super-majorities and trusted block signatures must exceed. The optional
+// sync_limit, cache_size and suspend_limit set the options of the same names of
+// every node, so that all the validators run with the same values.
+//
+// The hash of the document, which does not cover the network addresses and
+// monikers of the peers, is the network ID. Nodes attach their network ID to
+// every RPC, and refuse the RPCs of nodes with a different network ID, so that
+// nodes from different networks cannot gossip with each other.
+//
+// The genesis document and the peers.json file can also be signed offline by
+// each genesis validator. The Signatures, kept in a genesis.sig.json file,
+// cover the whole content of both files, so that nodes can refuse bootstrap
+// files that were tampered with.
+package genesis
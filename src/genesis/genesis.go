@@ -0,0 +1,291 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// Peer is a validator of the initial validator-set, with its voting weight.
+type Peer struct {
+	peers.Peer
+
+	// Weight is the voting weight of the validator. Babble does not support
+	// weighted voting yet, so all the validators must have a weight of 1. A
+	// weight of 0 is read as 1.
+	Weight int `json:"Weight,omitempty"`
+}
+
+// ConsensusParams are the parameters of the consensus algorithm, which must
+// be the same for all the nodes of a network.
+type ConsensusParams struct {
+	// RootDepth is the number of events of each participant included in the
+	// Roots of the Frames.
+	RootDepth int `json:"root_depth"`
+
+	// CoinRoundFrequency is the frequency of the coin rounds in the election
+	// of famous witnesses.
+	CoinRoundFrequency int `json:"coin_round_frequency"`
+
+	// SuperMajority is the fraction of the validators that a super-majority
+	// must exceed, in strongly-seeing, fame decisions, and upgrade votes. It
+	// is 2/3 by default, which tolerates f faulty validators out of 3f+1.
+	// Networks can require a larger fraction, like 3/4, for a wider safety
+	// margin at the cost of liveness.
+	SuperMajority string `json:"super_majority,omitempty"`
+
+	// Trust is the fraction of the validators whose signatures a block must
+	// exceed to be trusted, for example by fast-sync. It is 1/3 by default,
+	// and cannot exceed SuperMajority.
+	Trust string `json:"trust,omitempty"`
+
+	// PeerSetInterval, when set, delays the membership changes to the next
+	// round that is a multiple of it, so that the validator-set only changes
+	// at predictable rounds, and the changes accepted in between are applied
+	// together. 0, the default, applies each change 6 rounds after the round
+	// in which it was accepted.
+	PeerSetInterval int `json:"peer_set_interval,omitempty"`
+
+	// SyncLimit, when set, is the max number of events in a SyncResponse or
+	// EagerSyncRequest, in place of the sync-limit option of each node.
+	SyncLimit int `json:"sync_limit,omitempty"`
+
+	// CacheSize, when set, is the max number of items in the caches of the
+	// hashgraph, in place of the cache-size option of each node.
+	CacheSize int `json:"cache_size,omitempty"`
+
+	// SuspendLimit, when set, is the number of undetermined events per
+	// validator above which a node suspends itself, in place of the
+	// suspend-limit option of each node.
+	SuspendLimit int `json:"suspend_limit,omitempty"`
+}
+
+// Thresholds parses the super-majority and trust thresholds, which default to
+// 2/3 and 1/3.
+func (c ConsensusParams) Thresholds() (peers.Thresholds, error) {
+	return peers.ParseThresholds(c.SuperMajority, c.Trust)
+}
+
+// DefaultConsensusParams returns the consensus parameters of this version of
+// Babble. The thresholds, the peer-set interval, and the limits that replace
+// the options of the nodes can be changed, but the other parameters are the
+// only ones currently supported.
+func DefaultConsensusParams() ConsensusParams {
+	return ConsensusParams{
+		RootDepth:          hashgraph.ROOT_DEPTH,
+		CoinRoundFrequency: int(hashgraph.COIN_ROUND_FREQ),
+	}
+}
+
+// Genesis is the genesis document of a network.
+type Genesis struct {
+	// ChainID is the human-readable name of the network.
+	ChainID string `json:"chain_id"`
+
+	// Peers is the initial validator-set.
+	Peers []*Peer `json:"peers"`
+
+	Consensus ConsensusParams `json:"consensus"`
+
+	// AppHash is the hexadecimal hash of the initial state of the application,
+	// with the 0X prefix. It is optional.
+	AppHash string `json:"app_hash"`
+}
+
+// NewGenesis creates a genesis document with the default consensus parameters,
+// where all the peers of a peer-set have a weight of 1.
+func NewGenesis(chainID string, peerSet *peers.PeerSet, appHash string) *Genesis {
+	g := &Genesis{
+		ChainID:   chainID,
+		Peers:     make([]*Peer, 0, len(peerSet.Peers)),
+		Consensus: DefaultConsensusParams(),
+		AppHash:   appHash,
+	}
+
+	for _, p := range peerSet.Peers {
+		g.Peers = append(g.Peers, &Peer{
+			Peer:   *peers.NewPeer(p.PubKeyHex, p.NetAddr, p.Moniker),
+			Weight: 1,
+		})
+	}
+
+	return g
+}
+
+// Read reads and validates a genesis document from a JSON file.
+func Read(path string) (*Genesis, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var g Genesis
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("Decoding genesis document %s: %v", path, err)
+	}
+
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid genesis document %s: %v", path, err)
+	}
+
+	return &g, nil
+}
+
+// Write writes the genesis document to a JSON file.
+func (g *Genesis) Write(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return common.WriteFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Validate checks that the genesis document has a chain ID, at least one peer,
+// no duplicate public keys, no shadow peers, the consensus parameters of this
+// version of Babble, safe thresholds, and a positive peer-set interval and
+// limits. It also standardises the public keys and weights of the peers, and
+// the thresholds.
+func (g *Genesis) Validate() error {
+	if g.ChainID == "" {
+		return fmt.Errorf("chain_id is missing")
+	}
+
+	if len(g.Peers) == 0 {
+		return fmt.Errorf("the genesis validator-set is empty")
+	}
+
+	pubKeys := make(map[string]bool)
+
+	for _, p := range g.Peers {
+		p.PubKeyHex = "0X" + strings.TrimPrefix(strings.ToUpper(p.PubKeyHex), "0X")
+
+		if pub := keys.ToPublicKey(p.PubKeyBytes()); pub == nil || pub.X == nil {
+			return fmt.Errorf("invalid public key %s", p.PubKeyHex)
+		}
+
+		if pubKeys[p.PubKeyHex] {
+			return fmt.Errorf("public key %s is listed more than once", p.PubKeyHex)
+		}
+		pubKeys[p.PubKeyHex] = true
+
+		if p.Weight == 0 {
+			p.Weight = 1
+		}
+		if p.Weight != 1 {
+			return fmt.Errorf("peer %s has a weight of %d, but weighted voting is not supported", p.PubKeyHex, p.Weight)
+		}
+
+		if p.Shadow {
+			return fmt.Errorf("peer %s is a shadow, but shadow validators must join", p.PubKeyHex)
+		}
+	}
+
+	params := g.Consensus
+	params.SuperMajority, params.Trust, params.PeerSetInterval = "", "", 0
+	params.SyncLimit, params.CacheSize, params.SuspendLimit = 0, 0, 0
+	if params != DefaultConsensusParams() {
+		return fmt.Errorf("consensus parameters %+v are not supported, expected %+v", params, DefaultConsensusParams())
+	}
+
+	thresholds, err := g.Consensus.Thresholds()
+	if err != nil {
+		return err
+	}
+	g.Consensus.SuperMajority = standardThreshold(thresholds.SuperMajority, peers.DefaultSuperMajority)
+	g.Consensus.Trust = standardThreshold(thresholds.Trust, peers.DefaultTrust)
+
+	if g.Consensus.PeerSetInterval < 0 {
+		return fmt.Errorf("peer_set_interval cannot be negative")
+	}
+
+	if g.Consensus.SyncLimit < 0 || g.Consensus.CacheSize < 0 || g.Consensus.SuspendLimit < 0 {
+		return fmt.Errorf("sync_limit, cache_size and suspend_limit cannot be negative")
+	}
+
+	if g.AppHash != "" {
+		g.AppHash = "0X" + strings.TrimPrefix(strings.ToUpper(g.AppHash), "0X")
+		if _, err := common.DecodeFromString(g.AppHash); err != nil {
+			return fmt.Errorf("app_hash is not hexadecimal: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// standardThreshold returns the string of a threshold, or an empty string for
+// the default value, so that the network ID only depends on the actual values.
+func standardThreshold(t peers.Threshold, def peers.Threshold) string {
+	if t.Num*def.Den == def.Num*t.Den {
+		return ""
+	}
+	return t.String()
+}
+
+// PeerSet returns the genesis validator-set.
+func (g *Genesis) PeerSet() *peers.PeerSet {
+	ps := make([]*peers.Peer, 0, len(g.Peers))
+	for _, p := range g.Peers {
+		ps = append(ps, peers.NewPeer(p.PubKeyHex, p.NetAddr, p.Moniker))
+	}
+	return peers.NewPeerSet(ps)
+}
+
+// hashedGenesis is the part of a genesis document covered by its hash. The
+// network addresses and monikers are left out because they do not define the
+// network, and may legitimately differ between the copies of the document.
+type hashedGenesis struct {
+	ChainID   string
+	Peers     []hashedPeer
+	Consensus ConsensusParams
+	AppHash   string
+}
+
+type hashedPeer struct {
+	PubKeyHex string
+	Weight    int
+}
+
+// Hash returns the SHA256 hash of the genesis document, which does not depend
+// on the order of the peers. The document must be validated first.
+func (g *Genesis) Hash() ([]byte, error) {
+	h := hashedGenesis{
+		ChainID:   g.ChainID,
+		Peers:     make([]hashedPeer, 0, len(g.Peers)),
+		Consensus: g.Consensus,
+		AppHash:   g.AppHash,
+	}
+
+	for _, p := range g.Peers {
+		h.Peers = append(h.Peers, hashedPeer{PubKeyHex: p.PubKeyHex, Weight: p.Weight})
+	}
+
+	sort.Slice(h.Peers, func(i, j int) bool {
+		return h.Peers[i].PubKeyHex < h.Peers[j].PubKeyHex
+	})
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.SHA256(data), nil
+}
+
+// NetworkID returns the hexadecimal hash of the genesis document, which
+// identifies the network.
+func (g *Genesis) NetworkID() (string, error) {
+	hash, err := g.Hash()
+	if err != nil {
+		return "", err
+	}
+	return common.EncodeToString(hash), nil
+}
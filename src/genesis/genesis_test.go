@@ -0,0 +1,187 @@
+package genesis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func testPeerSet(t *testing.T, n int) *peers.PeerSet {
+	ps := []*peers.Peer{}
+	for i := 0; i < n; i++ {
+		key, err := keys.GenerateECDSAKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps = append(ps, peers.NewPeer(
+			keys.PublicKeyHex(&key.PublicKey),
+			fmt.Sprintf("127.0.0.1:%d", 1337+i),
+			fmt.Sprintf("node%d", i),
+		))
+	}
+	return peers.NewPeerSet(ps)
+}
+
+func TestGenesisRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "babble-genesis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	peerSet := testPeerSet(t, 3)
+
+	g := NewGenesis("testnet", peerSet, "0xabcd")
+
+	path := filepath.Join(dir, "genesis.json")
+	if err := g.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	g2, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g2.AppHash != "0XABCD" {
+		t.Fatalf("AppHash should be standardised to 0XABCD, not %s", g2.AppHash)
+	}
+
+	if g2.PeerSet().Hex() != peerSet.Hex() {
+		t.Fatal("The genesis peer-set should be the peer-set of the document")
+	}
+
+	if err := g.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := g.NetworkID()
+	id2, _ := g2.NetworkID()
+	if id != id2 {
+		t.Fatalf("The network ID should survive a round trip: %s != %s", id, id2)
+	}
+}
+
+func TestNetworkID(t *testing.T) {
+	peerSet := testPeerSet(t, 3)
+
+	networkID := func(g *Genesis) string {
+		if err := g.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		id, err := g.NetworkID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	id := networkID(NewGenesis("testnet", peerSet, ""))
+
+	// The order, the addresses and the monikers of the peers do not matter
+	g := NewGenesis("testnet", peerSet, "")
+	g.Peers[0], g.Peers[2] = g.Peers[2], g.Peers[0]
+	g.Peers[1].NetAddr = "10.0.0.1:1337"
+	g.Peers[1].Moniker = "renamed"
+	g.Peers[1].PubKeyHex = strings.ToLower(g.Peers[1].PubKeyHex)
+	if networkID(g) != id {
+		t.Fatal("The network ID should not depend on the order, addresses and monikers of the peers")
+	}
+
+	// The chain ID, the validators and the app hash do
+	if networkID(NewGenesis("mainnet", peerSet, "")) == id {
+		t.Fatal("The network ID should depend on the chain ID")
+	}
+
+	if networkID(NewGenesis("testnet", testPeerSet(t, 3), "")) == id {
+		t.Fatal("The network ID should depend on the validators")
+	}
+
+	if networkID(NewGenesis("testnet", peerSet, "0X01")) == id {
+		t.Fatal("The network ID should depend on the app hash")
+	}
+
+	// Explicit default thresholds are equivalent to the defaults, but stricter
+	// ones define another network
+	g = NewGenesis("testnet", peerSet, "")
+	g.Consensus.SuperMajority = "4/6"
+	g.Consensus.Trust = "1/3"
+	if networkID(g) != id {
+		t.Fatal("The network ID should not depend on explicit default thresholds")
+	}
+
+	g = NewGenesis("testnet", peerSet, "")
+	g.Consensus.SuperMajority = "3/4"
+	if networkID(g) == id {
+		t.Fatal("The network ID should depend on the thresholds")
+	}
+
+	g = NewGenesis("testnet", peerSet, "")
+	g.Consensus.PeerSetInterval = 100
+	if networkID(g) == id {
+		t.Fatal("The network ID should depend on the peer-set interval")
+	}
+
+	g = NewGenesis("testnet", peerSet, "")
+	g.Consensus.SyncLimit = 500
+	if networkID(g) == id {
+		t.Fatal("The network ID should depend on the sync limit")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	peerSet := testPeerSet(t, 2)
+
+	cases := map[string]func(g *Genesis){
+		"missing chain ID":    func(g *Genesis) { g.ChainID = "" },
+		"no peers":            func(g *Genesis) { g.Peers = nil },
+		"invalid public key":  func(g *Genesis) { g.Peers[0].PubKeyHex = "0X1234" },
+		"duplicate peer":      func(g *Genesis) { g.Peers[1] = g.Peers[0] },
+		"weighted peer":       func(g *Genesis) { g.Peers[0].Weight = 2 },
+		"shadow peer":         func(g *Genesis) { g.Peers[0].Shadow = true },
+		"consensus params":    func(g *Genesis) { g.Consensus.RootDepth = 5 },
+		"app hash":            func(g *Genesis) { g.AppHash = "0Xnothex" },
+		"weak majority":       func(g *Genesis) { g.Consensus.SuperMajority = "1/2" },
+		"unreachable":         func(g *Genesis) { g.Consensus.SuperMajority = "1/1" },
+		"weak trust":          func(g *Genesis) { g.Consensus.Trust = "1/4" },
+		"trust above quorum":  func(g *Genesis) { g.Consensus.Trust = "4/5" },
+		"not a fraction":      func(g *Genesis) { g.Consensus.Trust = "0.5" },
+		"negative interval":   func(g *Genesis) { g.Consensus.PeerSetInterval = -1 },
+		"negative sync limit": func(g *Genesis) { g.Consensus.SyncLimit = -1 },
+		"negative cache size": func(g *Genesis) { g.Consensus.CacheSize = -1 },
+		"negative suspend":    func(g *Genesis) { g.Consensus.SuspendLimit = -1 },
+	}
+
+	for name, invalidate := range cases {
+		g := NewGenesis("testnet", peerSet, "")
+		invalidate(g)
+		if err := g.Validate(); err == nil {
+			t.Fatalf("%s: Validate should fail", name)
+		}
+	}
+
+	g := NewGenesis("testnet", peerSet, "")
+	g.Peers[0].Weight = 0
+	if err := g.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if g.Peers[0].Weight != 1 {
+		t.Fatalf("A weight of 0 should be read as 1, not %d", g.Peers[0].Weight)
+	}
+
+	g.Consensus.SuperMajority = "3/4"
+	g.Consensus.Trust = "1/2"
+	g.Consensus.PeerSetInterval = 100
+	g.Consensus.SyncLimit = 500
+	g.Consensus.CacheSize = 20000
+	g.Consensus.SuspendLimit = 200
+	if err := g.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
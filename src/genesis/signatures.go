@@ -0,0 +1,169 @@
+package genesis
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// Signature is the signature of the bootstrap files by a genesis validator.
+type Signature struct {
+	// PubKeyHex is the public key of the validator.
+	PubKeyHex string `json:"pub_key"`
+	// Signature is the signature of the hash of the bootstrap files, encoded
+	// by keys.EncodeSignature.
+	Signature string `json:"signature"`
+}
+
+// Signatures are the signatures of the bootstrap files of a network, the
+// genesis document and the peers.json file, by the genesis validators. Each
+// validator signs the files offline, and passes the signatures file on to the
+// next, so that the nodes can check at startup that their bootstrap files are
+// those that all the validators agreed on.
+type Signatures struct {
+	// Hash is the hexadecimal hash of the signed bootstrap files.
+	Hash string `json:"hash"`
+	// Signatures are the signatures of the validators, in signing order.
+	Signatures []Signature `json:"signatures"`
+}
+
+// signedFiles is the content covered by the hash of the bootstrap files.
+// Unlike the network ID, it covers the network addresses and monikers of the
+// peers, which could otherwise be changed to redirect the nodes.
+type signedFiles struct {
+	Genesis *Genesis
+	Peers   []*peers.Peer
+}
+
+// BootstrapHash returns the SHA256 hash of a genesis document and of the peers
+// of the peers.json file, which is optional. The document must be validated
+// first.
+func BootstrapHash(g *Genesis, peerSet *peers.PeerSet) ([]byte, error) {
+	files := signedFiles{Genesis: g}
+	if peerSet != nil {
+		files.Peers = peerSet.Peers
+	}
+
+	data, err := json.Marshal(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.SHA256(data), nil
+}
+
+// ReadSignatures reads a signatures file.
+func ReadSignatures(path string) (*Signatures, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Signatures
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("Decoding signatures %s: %v", path, err)
+	}
+
+	return &s, nil
+}
+
+// Write writes the signatures to a JSON file.
+func (s *Signatures) Write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return common.WriteFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Sign adds the signature of the hash of the bootstrap files by a key, or
+// replaces the previous signature by the same key. It returns an error if the
+// other validators signed different files.
+func (s *Signatures) Sign(hash []byte, key *ecdsa.PrivateKey) error {
+	hashHex := common.EncodeToString(hash)
+
+	if s.Hash != "" && !strings.EqualFold(s.Hash, hashHex) && len(s.Signatures) > 0 {
+		return fmt.Errorf("the bootstrap files, with hash %s, differ from those signed by the other validators, with hash %s", hashHex, s.Hash)
+	}
+
+	r, sig, err := keys.Sign(key, hash)
+	if err != nil {
+		return err
+	}
+
+	signature := Signature{
+		PubKeyHex: keys.PublicKeyHex(&key.PublicKey),
+		Signature: keys.EncodeSignature(r, sig),
+	}
+
+	s.Hash = hashHex
+
+	for i, other := range s.Signatures {
+		if strings.EqualFold(other.PubKeyHex, signature.PubKeyHex) {
+			s.Signatures[i] = signature
+			return nil
+		}
+	}
+
+	s.Signatures = append(s.Signatures, signature)
+
+	return nil
+}
+
+// Verify checks that the signatures are valid signatures of the hash of the
+// bootstrap files by genesis validators. It returns the genesis validators
+// which have not signed yet, or an error if the files were tampered with, or
+// if a signature is invalid or not from a genesis validator.
+func (s *Signatures) Verify(g *Genesis, hash []byte) ([]*Peer, error) {
+	if hashHex := common.EncodeToString(hash); !strings.EqualFold(s.Hash, hashHex) {
+		return nil, fmt.Errorf("the bootstrap files, with hash %s, differ from those signed, with hash %s", hashHex, s.Hash)
+	}
+
+	validators := make(map[string]*Peer)
+	for _, p := range g.Peers {
+		validators[strings.ToUpper(p.PubKeyHex)] = p
+	}
+
+	signed := make(map[string]bool)
+
+	for _, sig := range s.Signatures {
+		pubKeyHex := strings.ToUpper(sig.PubKeyHex)
+
+		p, ok := validators[pubKeyHex]
+		if !ok {
+			return nil, fmt.Errorf("signature by %s, which is not a genesis validator", sig.PubKeyHex)
+		}
+
+		if signed[pubKeyHex] {
+			return nil, fmt.Errorf("%s signed more than once", sig.PubKeyHex)
+		}
+
+		r, rs, err := keys.DecodeSignature(sig.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature by %s: %v", sig.PubKeyHex, err)
+		}
+
+		if !keys.Verify(keys.ToPublicKey(p.PubKeyBytes()), hash, r, rs) {
+			return nil, fmt.Errorf("invalid signature by %s", sig.PubKeyHex)
+		}
+
+		signed[pubKeyHex] = true
+	}
+
+	var missing []*Peer
+	for _, p := range g.Peers {
+		if !signed[strings.ToUpper(p.PubKeyHex)] {
+			missing = append(missing, p)
+		}
+	}
+
+	return missing, nil
+}
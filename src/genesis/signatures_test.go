@@ -0,0 +1,112 @@
+package genesis
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestSignatures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "babble-genesis-signatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var validatorKeys []*ecdsa.PrivateKey
+	ps := []*peers.Peer{}
+	for i := 0; i < 3; i++ {
+		key, _ := keys.GenerateECDSAKey()
+		validatorKeys = append(validatorKeys, key)
+		ps = append(ps, peers.NewPeer(
+			keys.PublicKeyHex(&key.PublicKey),
+			fmt.Sprintf("127.0.0.1:%d", 1337+i),
+			fmt.Sprintf("node%d", i),
+		))
+	}
+	peerSet := peers.NewPeerSet(ps)
+
+	g := NewGenesis("testnet", peerSet, "")
+	if err := g.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := BootstrapHash(g, peerSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each validator signs in turn, passing the file on
+	path := filepath.Join(dir, "genesis.sig.json")
+	signatures := &Signatures{}
+
+	for i, key := range validatorKeys {
+		if err := signatures.Sign(hash, key); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := signatures.Write(path); err != nil {
+			t.Fatal(err)
+		}
+
+		if signatures, err = ReadSignatures(path); err != nil {
+			t.Fatal(err)
+		}
+
+		missing, err := signatures.Verify(g, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(missing) != len(validatorKeys)-i-1 {
+			t.Fatalf("%d validators should be missing, not %d", len(validatorKeys)-i-1, len(missing))
+		}
+	}
+
+	// Signing again replaces the signature
+	if err := signatures.Sign(hash, validatorKeys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(signatures.Signatures) != 3 {
+		t.Fatalf("Signing again should replace the signature, not add one")
+	}
+
+	// Changing the address of a peer changes the hash, though not the network
+	// ID, so the signatures no longer match
+	peerSet.Peers[1].NetAddr = "10.0.0.1:1337"
+
+	tampered, err := BootstrapHash(g, peerSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := signatures.Verify(g, tampered); err == nil {
+		t.Fatal("The signatures should not verify tampered files")
+	}
+
+	if err := signatures.Sign(tampered, validatorKeys[1]); err == nil {
+		t.Fatal("Signing files which differ from those signed by the others should fail")
+	}
+
+	// Only genesis validators can sign
+	other, _ := keys.GenerateECDSAKey()
+	signatures.Signatures[0].PubKeyHex = keys.PublicKeyHex(&other.PublicKey)
+
+	if _, err := signatures.Verify(g, hash); err == nil {
+		t.Fatal("A signature by another key should be refused")
+	}
+
+	// And forged signatures are refused
+	signatures.Signatures[0] = signatures.Signatures[2]
+	signatures.Signatures[0].PubKeyHex = ps[0].PubKeyHex
+
+	if _, err := signatures.Verify(g, hash); err == nil {
+		t.Fatal("A forged signature should be refused")
+	}
+}
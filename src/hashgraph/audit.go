@@ -0,0 +1,148 @@
+package hashgraph
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// AuditError is returned by AuditBlock when a Block, or its Frame, differs from
+// the one re-derived from the Events and Rounds in the Store. It indicates that
+// the Store is corrupted, or that the consensus methods have a bug.
+type AuditError struct {
+	Index  int
+	Reason string
+}
+
+// Error implements the error interface.
+func (e AuditError) Error() string {
+	return fmt.Sprintf("Block %d: %s", e.Index, e.Reason)
+}
+
+/*
+AuditBlock re-derives a Block, and its Frame, from the consensus Events of its
+RoundReceived, and compares them with the values in the Store. It returns an
+AuditError when they differ:
+
+  - the stored Frame does not hash to the FrameHash of the Block
+  - the Events received in the Round differ from the Events of the Frame
+  - the validator-set of the Round does not hash to the PeersHash of the Block
+  - the transactions of the Events differ from those of the Block
+
+Other errors mean that the Block cannot be audited, for example because its
+Events were evicted from the caches, or because it was received by
+fast-forward, in which case the Events of its Frame were never received
+locally.
+*/
+func (h *Hashgraph) AuditBlock(index int) error {
+	block, err := h.Store.GetBlock(index)
+	if err != nil {
+		return err
+	}
+
+	roundReceived := block.RoundReceived()
+
+	if h.roundLowerBound != nil && roundReceived <= *h.roundLowerBound {
+		return fmt.Errorf("Round %d precedes the last fast-forward", roundReceived)
+	}
+
+	frame, err := h.Store.GetFrame(roundReceived)
+	if err != nil {
+		return err
+	}
+
+	frameHash, err := frame.Hash()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(frameHash, block.FrameHash()) {
+		return AuditError{index, fmt.Sprintf("the stored Frame of Round %d does not match the FrameHash", roundReceived)}
+	}
+
+	round, err := h.Store.GetRound(roundReceived)
+	if err != nil {
+		return err
+	}
+
+	events := []*FrameEvent{}
+	for _, eh := range round.ReceivedEvents {
+		fe, err := h.createFrameEvent(eh)
+		if err != nil {
+			return err
+		}
+		events = append(events, fe)
+	}
+
+	sort.Sort(SortedFrameEvents(events))
+
+	if len(events) != len(frame.Events) {
+		return AuditError{index, fmt.Sprintf("Round %d received %d Events, but the Frame has %d", roundReceived, len(events), len(frame.Events))}
+	}
+
+	// The Roots depend on the Events known when the Frame was created, so they
+	// are not re-derived.
+	derived := &Frame{
+		Round:    roundReceived,
+		Peers:    frame.Peers,
+		Roots:    frame.Roots,
+		Events:   events,
+		PeerSets: frame.PeerSets,
+	}
+
+	derivedHash, err := derived.Hash()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(derivedHash, frameHash) {
+		return AuditError{index, fmt.Sprintf("the Events received in Round %d differ from those of the Frame", roundReceived)}
+	}
+
+	peerSet, err := h.Store.GetPeerSet(roundReceived)
+	if err != nil {
+		return err
+	}
+
+	peersHash, err := peerSet.Hash()
+	if err != nil {
+		return err
+	}
+
+	framePeersHash, err := peers.NewPeerSet(frame.Peers).Hash()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(peersHash, block.PeersHash()) || !bytes.Equal(framePeersHash, peersHash) {
+		return AuditError{index, fmt.Sprintf("the validator-set of Round %d does not match the PeersHash", roundReceived)}
+	}
+
+	derivedBlock, err := h.newBlockFromFrame(index, derived)
+	if err != nil {
+		return err
+	}
+
+	// The state hash and the receipts are returned by the application, which
+	// is not audited.
+	derivedBlock.Body.StateHash = block.StateHash()
+	derivedBlock.Body.InternalTransactionReceipts = block.InternalTransactionReceipts()
+
+	blockHash, err := block.Body.Hash()
+	if err != nil {
+		return err
+	}
+
+	derivedBlockHash, err := derivedBlock.Body.Hash()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(derivedBlockHash, blockHash) {
+		return AuditError{index, fmt.Sprintf("the transactions of the Events received in Round %d differ from those of the Block", roundReceived)}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package hashgraph
+
+import (
+	"testing"
+)
+
+func TestAuditBlock(t *testing.T) {
+	h, _ := initFunkyHashgraph(true, t)
+
+	if err := h.DivideRounds(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DecideFame(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DecideRoundReceived(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.ProcessDecidedRounds(); err != nil {
+		t.Fatal(err)
+	}
+
+	for bi := 0; bi < 3; bi++ {
+		if err := h.AuditBlock(bi); err != nil {
+			t.Fatalf("Block %d should pass the audit: %v", bi, err)
+		}
+	}
+
+	if err := h.AuditBlock(10); err == nil {
+		t.Fatal("An unknown Block should not be audited")
+	} else if _, ok := err.(AuditError); ok {
+		t.Fatalf("An unknown Block should not fail the audit: %v", err)
+	}
+
+	// Corrupt the Round of Block 1 with an Event received in Block 0
+	block0, _ := h.Store.GetBlock(0)
+	block1, _ := h.Store.GetBlock(1)
+
+	round0, err := h.Store.GetRound(block0.RoundReceived())
+	if err != nil {
+		t.Fatal(err)
+	}
+	round1, err := h.Store.GetRound(block1.RoundReceived())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	round1.ReceivedEvents[0] = round0.ReceivedEvents[0]
+	if err := h.Store.SetRound(block1.RoundReceived(), round1); err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.AuditBlock(1)
+	if ae, ok := err.(AuditError); !ok || ae.Index != 1 {
+		t.Fatalf("Block 1 should fail the audit, not return %v", err)
+	}
+
+	if err := h.AuditBlock(0); err != nil {
+		t.Fatalf("Block 0 should still pass the audit: %v", err)
+	}
+}
@@ -4,6 +4,10 @@ package hashgraph
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/dgraph-io/badger"
 	badger_options "github.com/dgraph-io/badger/options"
@@ -20,6 +24,11 @@ const (
 	topoPrefix       = "topo"
 	blockPrefix      = "block"
 	framePrefix      = "frame"
+	healthKey        = "health"
+	txPrefix         = "tx"
+	membershipPrefix = "membership"
+	pinPrefix        = "pin"
+	watermarkKey     = "app_watermark"
 )
 
 // BadgerStore contains references to the Badger database and inmem store. If
@@ -36,10 +45,30 @@ type BadgerStore struct {
 // found in path. The maintenanceMode option deactivates writing to the
 // persistant database, but adding/updating the inmem-store is preserved.
 func NewBadgerStore(cacheSize int, path string, maintenanceMode bool, logger *logrus.Entry) (*BadgerStore, error) {
-
 	opts := badger.DefaultOptions(path).
 		WithSyncWrites(false).
-		WithTruncate(true).
+		WithTruncate(true)
+
+	return openBadgerStore(cacheSize, path, maintenanceMode, opts, logger)
+}
+
+// NewReadOnlyBadgerStore opens an existing database in read-only mode, which
+// can be shared with other read-only processes. The store is in
+// maintenance-mode, so Events, Rounds and Blocks are only added to the
+// inmem-store.
+func NewReadOnlyBadgerStore(cacheSize int, path string, logger *logrus.Entry) (*BadgerStore, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	opts := badger.DefaultOptions(path).
+		WithReadOnly(true)
+
+	return openBadgerStore(cacheSize, path, true, opts, logger)
+}
+
+func openBadgerStore(cacheSize int, path string, maintenanceMode bool, opts badger.Options, logger *logrus.Entry) (*BadgerStore, error) {
+	opts = opts.
 		WithTableLoadingMode(badger_options.FileIO).
 		WithValueLogLoadingMode(badger_options.FileIO)
 
@@ -94,10 +123,22 @@ func blockKey(index int) []byte {
 	return []byte(fmt.Sprintf("%s_%09d", blockPrefix, index))
 }
 
+func txKey(hash string) []byte {
+	return []byte(fmt.Sprintf("%s_%s", txPrefix, hash))
+}
+
+func membershipKey(block int, index int) []byte {
+	return []byte(fmt.Sprintf("%s_%09d_%06d", membershipPrefix, block, index))
+}
+
 func frameKey(index int) []byte {
 	return []byte(fmt.Sprintf("%s_%09d", framePrefix, index))
 }
 
+func pinKey(index int) []byte {
+	return []byte(fmt.Sprintf("%s_%09d", pinPrefix, index))
+}
+
 /*******************************************************************************
 Implement the Store interface
 
@@ -253,6 +294,31 @@ func (s *BadgerStore) SetPeerSet(round int, peerSet *peers.PeerSet) error {
 	return nil
 }
 
+// ReplacePeerSet replaces the peer-set set at a given round.
+func (s *BadgerStore) ReplacePeerSet(round int, peerSet *peers.PeerSet) error {
+	// Update the cache
+	if err := s.inmemStore.ReplacePeerSet(round, peerSet); err != nil {
+		return err
+	}
+
+	// Update the db
+	if !s.maintenanceMode {
+		if err := s.dbSetPeerSet(round, peerSet); err != nil {
+			return err
+		}
+	}
+
+	// Extend Repertoire and Roots
+	for _, p := range peerSet.Peers {
+		err := s.addParticipant(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // addParticipant adds a participant and a corresponding Root to the database.
 func (s *BadgerStore) addParticipant(p *peers.Peer) error {
 	if s.maintenanceMode {
@@ -346,8 +412,13 @@ func (s *BadgerStore) GetBlock(rr int) (*Block, error) {
 	return res, mapError(err, "Block", string(blockKey(rr)))
 }
 
-// SetBlock creates or updates a Block in the Store.
+// SetBlock creates or updates a Block in the Store. The block's transactions
+// are indexed the first time it is stored, or if it has been evicted from the
+// cache since.
 func (s *BadgerStore) SetBlock(block *Block) error {
+	_, err := s.inmemStore.GetBlock(block.Index())
+	indexTxs := err != nil
+
 	if err := s.inmemStore.SetBlock(block); err != nil {
 		return err
 	}
@@ -355,7 +426,56 @@ func (s *BadgerStore) SetBlock(block *Block) error {
 	if s.maintenanceMode {
 		return nil
 	}
-	return s.dbSetBlock(block)
+	return s.dbSetBlock(block, indexTxs)
+}
+
+// GetTx returns the location of a committed transaction by hash.
+func (s *BadgerStore) GetTx(hash string) (TxLocation, error) {
+	res, err := s.inmemStore.GetTx(hash)
+	if err != nil {
+		res, err = s.dbGetTx(hash)
+	}
+	return res, mapError(err, "Tx", string(txKey(hash)))
+}
+
+// SetMembershipChange records an accepted PEER_ADD, PEER_REMOVE, PEER_RENAME or
+// PEER_ADDRESS.
+func (s *BadgerStore) SetMembershipChange(change MembershipChange) error {
+	if err := s.inmemStore.SetMembershipChange(change); err != nil {
+		return err
+	}
+
+	if s.maintenanceMode {
+		return nil
+	}
+	return s.dbSetMembershipChange(change)
+}
+
+// GetMembershipChanges returns the history of membership changes recorded in
+// the database, which survives restarts, merged with those of the cache.
+func (s *BadgerStore) GetMembershipChanges() ([]MembershipChange, error) {
+	cached, err := s.inmemStore.GetMembershipChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	persisted, err := s.dbGetMembershipChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[[2]int]MembershipChange)
+	for _, c := range append(persisted, cached...) {
+		all[[2]int{c.Block, c.Index}] = c
+	}
+
+	res := make([]MembershipChange, 0, len(all))
+	for _, c := range all {
+		res = append(res, c)
+	}
+	sortMembershipChanges(res)
+
+	return res, nil
 }
 
 // SetFrame creates or updates a Frame in the Store.
@@ -370,6 +490,89 @@ func (s *BadgerStore) SetFrame(frame *Frame) error {
 	return s.dbSetFrame(frame)
 }
 
+// PinBlock protects a Block, and the Frame of its round-received, from being
+// evicted from the cache. The pin is also recorded in the database, which keeps
+// all the Blocks, so that the pinned set survives restarts.
+func (s *BadgerStore) PinBlock(index int) error {
+	block, err := s.GetBlock(index)
+	if err != nil {
+		return err
+	}
+
+	frame, err := s.inmemStore.GetFrame(block.RoundReceived())
+	if err != nil {
+		frame, _ = s.dbGetFrame(block.RoundReceived())
+	}
+
+	s.inmemStore.pin(block, frame)
+
+	if s.maintenanceMode {
+		return nil
+	}
+	return s.dbSetPin(index)
+}
+
+// UnpinBlock lifts the protection of a pinned Block.
+func (s *BadgerStore) UnpinBlock(index int) error {
+	pinned, err := s.PinnedBlocks()
+	if err != nil {
+		return err
+	}
+
+	i := sort.SearchInts(pinned, index)
+	if i == len(pinned) || pinned[i] != index {
+		return cm.NewStoreErr("PinnedBlocks", cm.KeyNotFound, strconv.Itoa(index))
+	}
+
+	// The Block is not pinned in the cache after a restart
+	s.inmemStore.UnpinBlock(index)
+
+	if s.maintenanceMode {
+		return nil
+	}
+	return s.dbDeletePin(index)
+}
+
+// AppWatermark implements the AppWatermarkStore interface. The watermark is
+// read from the database.
+func (s *BadgerStore) AppWatermark() (int, error) {
+	return s.dbGetAppWatermark()
+}
+
+// SetAppWatermark implements the AppWatermarkStore interface. Unlike the other
+// writes, the watermark is written to the database in maintenance-mode too,
+// because the App acknowledges the Blocks delivered while bootstrapping.
+func (s *BadgerStore) SetAppWatermark(index int) error {
+	return s.dbSetAppWatermark(index)
+}
+
+// PinnedBlocks returns the sorted indexes of the Blocks pinned in the database,
+// merged with those of the cache.
+func (s *BadgerStore) PinnedBlocks() ([]int, error) {
+	cached, err := s.inmemStore.PinnedBlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	persisted, err := s.dbPinnedBlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[int]bool)
+	for _, index := range append(persisted, cached...) {
+		all[index] = true
+	}
+
+	res := make([]int, 0, len(all))
+	for index := range all {
+		res = append(res, index)
+	}
+	sort.Ints(res)
+
+	return res, nil
+}
+
 // Reset resets the Store from a given Frame.
 func (s *BadgerStore) Reset(frame *Frame) error {
 	// Reset InmemStore
@@ -413,6 +616,171 @@ func (s *BadgerStore) StorePath() string {
 	return s.path
 }
 
+// CheckWritable implements the HealthChecker interface. It verifies that the
+// Badger database accepts writes by updating a dedicated key. Nothing is
+// written in maintenance-mode.
+func (s *BadgerStore) CheckWritable() error {
+	if s.maintenanceMode {
+		return nil
+	}
+
+	tx := s.db.NewTransaction(true)
+	defer tx.Discard()
+
+	val := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	if err := tx.Set([]byte(healthKey), val); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PersistedPeerSet returns the peer-set effective from a given round from the
+// database, bypassing the cache.
+func (s *BadgerStore) PersistedPeerSet(round int) (*peers.PeerSet, error) {
+	peerSet, err := s.dbGetPeerSet(round)
+	return peerSet, mapError(err, "PeerSet", string(peerSetKey(round)))
+}
+
+// PersistedBlock returns a Block by index from the database, bypassing the
+// cache.
+func (s *BadgerStore) PersistedBlock(index int) (*Block, error) {
+	block, err := s.dbGetBlock(index)
+	return block, mapError(err, "Block", string(blockKey(index)))
+}
+
+// PersistedEvent returns an Event by hash from the database, bypassing the
+// cache.
+func (s *BadgerStore) PersistedEvent(hash string) (*Event, error) {
+	event, err := s.dbGetEvent(hash)
+	return event, mapError(err, "Event", hash)
+}
+
+// PersistedTopologicalEvent returns the Event with a given topological index
+// from the database.
+func (s *BadgerStore) PersistedTopologicalEvent(index int) (*Event, error) {
+	events, err := s.dbTopologicalEvents(index, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, cm.NewStoreErr("Event", cm.KeyNotFound, string(topologicalEventKey(index)))
+	}
+	return events[0], nil
+}
+
+// PersistedRound returns a Round by index from the database, bypassing the
+// cache.
+func (s *BadgerStore) PersistedRound(index int) (*RoundInfo, error) {
+	round, err := s.dbGetRound(index)
+	return round, mapError(err, "Round", string(roundKey(index)))
+}
+
+// PersistedFrame returns the Frame corresponding to a round-received from the
+// database, bypassing the cache.
+func (s *BadgerStore) PersistedFrame(index int) (*Frame, error) {
+	frame, err := s.dbGetFrame(index)
+	return frame, mapError(err, "Frame", string(frameKey(index)))
+}
+
+// PersistedPeerSets returns the history of peer-sets recorded in the database,
+// by the round from which they are effective.
+func (s *BadgerStore) PersistedPeerSets() (map[int]*peers.PeerSet, error) {
+	peerSets := make(map[int]*peers.PeerSet)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(peerSetPrefix + "_")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var round int
+			if _, err := fmt.Sscanf(string(it.Item().Key()), peerSetPrefix+"_%d", &round); err != nil {
+				return err
+			}
+
+			err := it.Item().Value(func(data []byte) error {
+				peerSet := new(peers.PeerSet)
+				if err := peerSet.Unmarshal(data); err != nil {
+					return err
+				}
+				peerSets[round] = peerSet
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return peerSets, err
+}
+
+// PersistedStats summarises the content of the database. The last indexes are
+// -1 if there are no items of that type.
+type PersistedStats struct {
+	Events       int
+	Rounds       int
+	LastRound    int
+	Blocks       int
+	LastBlock    int
+	Frames       int
+	LastFrame    int
+	Transactions int
+	PeerSets     int
+	Participants int
+}
+
+// PersistedStats counts the items of the database.
+func (s *BadgerStore) PersistedStats() (PersistedStats, error) {
+	stats := PersistedStats{}
+
+	counts := []struct {
+		prefix string
+		count  *int
+		last   *int
+	}{
+		{topoPrefix, &stats.Events, nil},
+		{roundPrefix, &stats.Rounds, &stats.LastRound},
+		{blockPrefix, &stats.Blocks, &stats.LastBlock},
+		{framePrefix, &stats.Frames, &stats.LastFrame},
+		{txPrefix, &stats.Transactions, nil},
+		{peerSetPrefix, &stats.PeerSets, nil},
+		{repertoirePrefix, &stats.Participants, nil},
+	}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		for _, c := range counts {
+			it := txn.NewIterator(opts)
+
+			last := -1
+			prefix := []byte(c.prefix + "_")
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				*c.count++
+
+				// Indexed keys are zero-padded, so the last key has the
+				// highest index
+				if c.last != nil {
+					fmt.Sscanf(string(it.Item().Key()), c.prefix+"_%d", &last)
+				}
+			}
+
+			if c.last != nil {
+				*c.last = last
+			}
+
+			it.Close()
+		}
+		return nil
+	})
+
+	return stats, err
+}
+
 /*******************************************************************************
 DB Methods
 *******************************************************************************/
@@ -769,7 +1137,7 @@ func (s *BadgerStore) dbGetBlock(index int) (*Block, error) {
 	return block, nil
 }
 
-func (s *BadgerStore) dbSetBlock(block *Block) error {
+func (s *BadgerStore) dbSetBlock(block *Block, indexTxs bool) error {
 	tx := s.db.NewTransaction(true)
 	defer tx.Discard()
 
@@ -784,6 +1152,200 @@ func (s *BadgerStore) dbSetBlock(block *Block) error {
 		return err
 	}
 
+	if indexTxs {
+		for i, t := range block.Transactions() {
+			txk := txKey(TxHash(t))
+
+			// keep the first location of duplicate transactions
+			_, err := tx.Get(txk)
+			if err == nil {
+				continue
+			}
+			if err != badger.ErrKeyNotFound {
+				return err
+			}
+
+			loc := TxLocation{Block: block.Index(), Index: i}
+			locVal, err := loc.Marshal()
+			if err != nil {
+				return err
+			}
+
+			//insert [tx hash] => [location bytes]
+			if err := tx.Set(txk, locVal); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *BadgerStore) dbGetTx(hash string) (TxLocation, error) {
+	var locBytes []byte
+	key := txKey(hash)
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		locBytes, err = item.ValueCopy(nil)
+		return err
+	})
+
+	if err != nil {
+		return TxLocation{}, err
+	}
+
+	var loc TxLocation
+	if err := loc.Unmarshal(locBytes); err != nil {
+		return TxLocation{}, err
+	}
+
+	return loc, nil
+}
+
+func (s *BadgerStore) dbSetMembershipChange(change MembershipChange) error {
+	tx := s.db.NewTransaction(true)
+	defer tx.Discard()
+
+	val, err := change.Marshal()
+	if err != nil {
+		return err
+	}
+
+	//insert [block_index] => [MembershipChange bytes]
+	if err := tx.Set(membershipKey(change.Block, change.Index), val); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *BadgerStore) dbGetMembershipChanges() ([]MembershipChange, error) {
+	changes := []MembershipChange{}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(membershipPrefix + "_")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(data []byte) error {
+				var change MembershipChange
+				if err := change.Unmarshal(data); err != nil {
+					return err
+				}
+				changes = append(changes, change)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return changes, err
+}
+
+func (s *BadgerStore) dbSetPin(index int) error {
+	tx := s.db.NewTransaction(true)
+	defer tx.Discard()
+
+	//insert [pin_index] => []
+	if err := tx.Set(pinKey(index), []byte{}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *BadgerStore) dbDeletePin(index int) error {
+	tx := s.db.NewTransaction(true)
+	defer tx.Discard()
+
+	if err := tx.Delete(pinKey(index)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *BadgerStore) dbPinnedBlocks() ([]int, error) {
+	res := []int{}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(pinPrefix + "_")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var index int
+			if _, err := fmt.Sscanf(string(it.Item().Key()), pinPrefix+"_%d", &index); err != nil {
+				return err
+			}
+			res = append(res, index)
+		}
+		return nil
+	})
+
+	return res, err
+}
+
+func (s *BadgerStore) dbGetAppWatermark() (int, error) {
+	watermark := -1
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(watermarkKey))
+		if err != nil {
+			if isDBKeyNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+
+		watermark, err = strconv.Atoi(string(val))
+		return err
+	})
+
+	return watermark, err
+}
+
+func (s *BadgerStore) dbSetAppWatermark(index int) error {
+	tx := s.db.NewTransaction(true)
+	defer tx.Discard()
+
+	current := -1
+	item, err := tx.Get([]byte(watermarkKey))
+	if err == nil {
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if current, err = strconv.Atoi(string(val)); err != nil {
+			return err
+		}
+	} else if !isDBKeyNotFound(err) {
+		return err
+	}
+
+	if index < current {
+		return fmt.Errorf("App watermark %d is lower than %d", index, current)
+	}
+
+	//insert [app_watermark] => index
+	if err := tx.Set([]byte(watermarkKey), []byte(strconv.Itoa(index))); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"testing"
 
+	cm "github.com/mosaicnetworks/babble/src/common"
 	"github.com/mosaicnetworks/babble/src/peers"
 )
 
@@ -51,6 +52,21 @@ func TestNewBadgerStore(t *testing.T) {
 	}
 }
 
+func TestBadgerCheckWritable(t *testing.T) {
+	store := initBadgerStore(1000, t)
+	defer os.RemoveAll(store.path)
+
+	if err := store.CheckWritable(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	store.db.Close()
+
+	if err := store.CheckWritable(); err == nil {
+		t.Fatal("CheckWritable should fail on a closed database")
+	}
+}
+
 /*******************************************************************************
 Call DB methods directly
 *******************************************************************************/
@@ -290,7 +306,7 @@ func TestDBBlockMethods(t *testing.T) {
 	block.SetSignature(sig2)
 
 	t.Run("Store Block", func(t *testing.T) {
-		if err := store.dbSetBlock(block); err != nil {
+		if err := store.dbSetBlock(block, true); err != nil {
 			t.Fatal(err)
 		}
 
@@ -330,6 +346,23 @@ func TestDBBlockMethods(t *testing.T) {
 			t.Fatal("Validator2 block signatures differ")
 		}
 	})
+
+	t.Run("Check transaction index", func(t *testing.T) {
+		for i, tx := range transactions {
+			loc, err := store.dbGetTx(TxHash(tx))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if loc.Block != index || loc.Index != i {
+				t.Fatalf("tx %d should be located at (%d, %d), not %+v", i, index, i, loc)
+			}
+		}
+
+		if _, err := store.GetTx(TxHash([]byte("unknown"))); !cm.IsStore(err, cm.KeyNotFound) {
+			t.Fatalf("unknown tx should return KeyNotFound, not %v", err)
+		}
+	})
 }
 
 func TestDBFrameMethods(t *testing.T) {
@@ -751,3 +784,239 @@ func TestBadgerFrames(t *testing.T) {
 		}
 	})
 }
+
+/*******************************************************************************
+Read a closed database
+*******************************************************************************/
+
+func TestBadgerPersisted(t *testing.T) {
+	store := initBadgerStore(10, t)
+	path := store.path
+	defer os.RemoveAll(path)
+
+	peerSet, participants := initPeers(3)
+
+	if err := store.SetPeerSet(0, peerSet); err != nil {
+		t.Fatal(err)
+	}
+
+	newPeerSet := peerSet.WithNewPeer(peers.NewPeer("0X0400", "127.0.0.1:1340", "new"))
+	if err := store.SetPeerSet(6, newPeerSet); err != nil {
+		t.Fatal(err)
+	}
+
+	events := []*Event{}
+	for i, p := range participants {
+		event := NewEvent([][]byte{[]byte(fmt.Sprintf("tx%d", i))},
+			[]InternalTransaction{},
+			[]BlockSignature{},
+			[]string{"", ""},
+			p.pubKey,
+			0)
+		event.topologicalIndex = i
+		if err := store.SetEvent(event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := store.SetRound(i, NewRoundInfo()); err != nil {
+			t.Fatal(err)
+		}
+
+		block := NewBlock(i, i, []byte("frame"), peerSet.Peers, [][]byte{[]byte(fmt.Sprintf("block%d", i))}, []InternalTransaction{})
+		if err := store.SetBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewReadOnlyBadgerStore(10, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	stats, err := store.PersistedStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := PersistedStats{
+		Events:       3,
+		Rounds:       3,
+		LastRound:    2,
+		Blocks:       3,
+		LastBlock:    2,
+		Frames:       0,
+		LastFrame:    -1,
+		Transactions: 3,
+		PeerSets:     2,
+		Participants: 4,
+	}
+	if stats != expected {
+		t.Fatalf("Stats should be %+v, not %+v", expected, stats)
+	}
+
+	peerSets, err := store.PersistedPeerSets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peerSets) != 2 || peerSets[0].Hex() != peerSet.Hex() || peerSets[6].Hex() != newPeerSet.Hex() {
+		t.Fatalf("PeerSets should be recorded at rounds 0 and 6, not %v", peerSets)
+	}
+
+	event, err := store.PersistedTopologicalEvent(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Hex() != events[1].Hex() {
+		t.Fatalf("Topological Event 1 should be %s, not %s", events[1].Hex(), event.Hex())
+	}
+
+	if _, err := store.PersistedEvent(events[2].Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PersistedTopologicalEvent(3); !cm.IsStore(err, cm.KeyNotFound) {
+		t.Fatalf("Topological Event 3 should not be found, not %v", err)
+	}
+
+	block, err := store.PersistedBlock(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(block.Transactions()[0]) != "block2" {
+		t.Fatalf("Block 2 should contain block2, not %s", block.Transactions()[0])
+	}
+
+	if _, err := store.PersistedRound(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PersistedFrame(0); !cm.IsStore(err, cm.KeyNotFound) {
+		t.Fatalf("Frame 0 should not be found, not %v", err)
+	}
+}
+
+func TestBadgerMembershipChanges(t *testing.T) {
+	store := initBadgerStore(10, t)
+	path := store.path
+	defer os.RemoveAll(path)
+
+	peer := *peers.NewPeer("0X0400", "127.0.0.1:1340", "new")
+
+	changes := []MembershipChange{
+		{Block: 5, Index: 0, RoundReceived: 10, Round: 16, Type: PEER_REMOVE, Peer: peer},
+		{Block: 2, Index: 0, RoundReceived: 4, Round: 10, Type: PEER_ADD, Peer: peer},
+	}
+
+	for _, c := range changes {
+		if err := store.SetMembershipChange(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The history survives restarts
+	store, err := NewBadgerStore(10, path, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	res, err := store.GetMembershipChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []MembershipChange{changes[1], changes[0]}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("Membership changes should be %v, not %v", expected, res)
+	}
+}
+
+func TestBadgerPinnedBlocks(t *testing.T) {
+	store := initBadgerStore(10, t)
+	path := store.path
+	defer os.RemoveAll(path)
+
+	block := NewBlock(0, 1, []byte("frame"), []*peers.Peer{}, [][]byte{[]byte("block0")}, nil)
+	if err := store.SetBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.PinBlock(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pins survive restarts
+	store, err := NewBadgerStore(10, path, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if pinned, err := store.PinnedBlocks(); err != nil || !reflect.DeepEqual(pinned, []int{0}) {
+		t.Fatalf("Pinned blocks should be [0], not %v (%v)", pinned, err)
+	}
+
+	if err := store.UnpinBlock(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if pinned, err := store.PinnedBlocks(); err != nil || len(pinned) != 0 {
+		t.Fatalf("No block should be pinned, not %v (%v)", pinned, err)
+	}
+
+	if err := store.UnpinBlock(0); !cm.IsStore(err, cm.KeyNotFound) {
+		t.Fatalf("Unpinning a block twice should fail with KeyNotFound, not %v", err)
+	}
+}
+
+func TestBadgerAppWatermark(t *testing.T) {
+	store := initBadgerStore(10, t)
+	path := store.path
+	defer os.RemoveAll(path)
+
+	if w, err := store.AppWatermark(); err != nil || w != -1 {
+		t.Fatalf("The App watermark should be -1, not %d (%v)", w, err)
+	}
+
+	// The watermark is written in maintenance-mode too
+	store.SetMaintenanceMode(true)
+	if err := store.SetAppWatermark(5); err != nil {
+		t.Fatal(err)
+	}
+	store.SetMaintenanceMode(false)
+
+	if err := store.SetAppWatermark(4); err == nil {
+		t.Fatal("The App watermark should not go back")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The watermark survives restarts
+	store, err := NewBadgerStore(10, path, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if w, err := store.AppWatermark(); err != nil || w != 5 {
+		t.Fatalf("The App watermark should be 5, not %d (%v)", w, err)
+	}
+}
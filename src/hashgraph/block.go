@@ -12,7 +12,10 @@ import (
 	"github.com/mosaicnetworks/babble/src/peers"
 )
 
-// BlockBody is the content of a Block.
+// BlockBody is the content of a Block. GenesisStateHash is the hash of the
+// initial state of the App, which is only recorded in the first Block, when
+// the genesis document defines it. It is omitted when empty, so that the hashes of the
+// other Blocks do not change.
 type BlockBody struct {
 	Index                       int
 	RoundReceived               int
@@ -22,6 +25,7 @@ type BlockBody struct {
 	Transactions                [][]byte
 	InternalTransactions        []InternalTransaction
 	InternalTransactionReceipts []InternalTransactionReceipt
+	GenesisStateHash            []byte `json:",omitempty"`
 }
 
 // Marshal produces the JSON encoding of a BlockBody.
@@ -215,6 +219,12 @@ func (b *Block) FrameHash() []byte {
 	return b.Body.FrameHash
 }
 
+// GenesisStateHash returns the hash of the initial state of the App, recorded in
+// the first block, or nil.
+func (b *Block) GenesisStateHash() []byte {
+	return b.Body.GenesisStateHash
+}
+
 // PeersHash returns the block's peers hash.
 func (b *Block) PeersHash() []byte {
 	return b.Body.PeersHash
@@ -153,6 +153,29 @@ func (c *PeerSetCache) Set(round int, peerSet *peers.PeerSet) error {
 	c.rounds = append(c.rounds, round)
 	c.rounds.Sort()
 
+	c.addRepertoire(round, peerSet)
+
+	return nil
+}
+
+// Replace replaces the peer-set recorded at a given round, which must exist,
+// and updates internal information. It is used to accumulate the membership
+// changes scheduled for the same round, before that round is reached.
+func (c *PeerSetCache) Replace(round int, peerSet *peers.PeerSet) error {
+	if _, ok := c.peerSets[round]; !ok {
+		return cm.NewStoreErr("PeerSetCache", cm.KeyNotFound, strconv.Itoa(round))
+	}
+
+	c.peerSets[round] = peerSet
+
+	c.addRepertoire(round, peerSet)
+
+	return nil
+}
+
+// addRepertoire adds the peers of a peer-set, effective at a given round, to
+// the repertoire.
+func (c *PeerSetCache) addRepertoire(round int, peerSet *peers.PeerSet) {
 	for _, p := range peerSet.Peers {
 		c.repertoireByPubKey[p.PubKeyString()] = p
 		c.repertoireByID[p.ID()] = p
@@ -161,8 +184,6 @@ func (c *PeerSetCache) Set(round int, peerSet *peers.PeerSet) error {
 			c.firstRounds[p.ID()] = round
 		}
 	}
-
-	return nil
 }
 
 // Get returns the peer-set corresponding to a given round.
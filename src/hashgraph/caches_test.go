@@ -244,4 +244,22 @@ func TestPeerSetCache(t *testing.T) {
 	if err == nil || !cm.IsStore(err, cm.KeyAlreadyExists) {
 		t.Fatalf("Resetting PeerSet 2 should throw a KeyAlreadyExists error")
 	}
+
+	/**************************************************************************/
+
+	replaced := peerSet3.WithNewPeer(peers.NewPeer("0xCC", "", ""))
+	if err := peerSetCache.Replace(3, replaced); err != nil {
+		t.Fatal(err)
+	}
+	if ps3, _ := peerSetCache.Get(3); !reflect.DeepEqual(ps3, replaced) {
+		t.Fatalf("PeerSet 3 should be %v, not %v", replaced, ps3)
+	}
+	if fr, ok := peerSetCache.FirstRound(replaced.Peers[len(replaced.Peers)-1].ID()); !ok || fr != 3 {
+		t.Fatalf("The first round of the new peer should be 3, not %d", fr)
+	}
+
+	err = peerSetCache.Replace(4, replaced)
+	if err == nil || !cm.IsStore(err, cm.KeyNotFound) {
+		t.Fatalf("Replacing PeerSet 4 should throw a KeyNotFound error")
+	}
 }
@@ -1,5 +1,7 @@
 package hashgraph
 
+import "fmt"
+
 // SelfParentError is used to differentiate errors that are normal when the
 // hashgraph is being used corrently by multiple go-routines, from errors that
 // should not be occuring event in a concurrent context.
@@ -30,3 +32,19 @@ func IsNormalSelfParentError(err error) bool {
 	spErr, ok := err.(SelfParentError)
 	return ok && spErr.normal
 }
+
+// ForkError is returned when an Event has the same creator and index as a
+// different Event in the hashgraph. Both Events are signed by the creator, so
+// they are evidence that it forked its chain of Events.
+type ForkError struct {
+	Creator string
+	Index   int
+	Known   string
+	Event   string
+}
+
+// Error implements the Error interface
+func (e ForkError) Error() string {
+	return fmt.Sprintf("Fork: events %s and %s of %s have the same index %d",
+		e.Known, e.Event, e.Creator, e.Index)
+}
@@ -341,6 +341,11 @@ func (e *Event) SetLamportTimestamp(t int) {
 	*e.lamportTimestamp = t
 }
 
+// GetLamportTimestamp gets the Event's Lamport Timestamp
+func (e *Event) GetLamportTimestamp() *int {
+	return e.lamportTimestamp
+}
+
 // SetRoundReceived sets the Event's round-received (different from round)
 func (e *Event) SetRoundReceived(rr int) {
 	if e.roundReceived == nil {
@@ -350,6 +355,11 @@ func (e *Event) SetRoundReceived(rr int) {
 	*e.roundReceived = rr
 }
 
+// GetRoundReceived gets the Event's round-received
+func (e *Event) GetRoundReceived() *int {
+	return e.roundReceived
+}
+
 // SetWireInfo sets the private fields in the Event's body which are used by the
 // wire representation.
 func (e *Event) SetWireInfo(selfParentIndex int,
@@ -0,0 +1,291 @@
+package hashgraph
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+)
+
+// EvidenceKind identifies the misbehaviour proven by an Evidence.
+type EvidenceKind string
+
+const (
+	// ForkEvidence proves that a validator created two different Events with
+	// the same index.
+	ForkEvidence EvidenceKind = "fork"
+	// DoubleSignEvidence proves that a validator signed two different Blocks
+	// with the same index.
+	DoubleSignEvidence EvidenceKind = "double_sign"
+	// EquivocationEvidence proves that a peer signed two different
+	// InternalTransactions of the same type about itself, like two different
+	// network addresses, which were accepted in the same Block.
+	EquivocationEvidence EvidenceKind = "equivocation"
+)
+
+/*
+Evidence is a self-contained proof that a validator misbehaved. It only
+contains objects signed by the Offender, so it can be verified without access to
+the hashgraph, for example by a staking contract that slashes the Offender:
+
+  - fork: the two Events, with the same creator and index
+  - double_sign: the bodies of the two Blocks, with the same index, and the
+    signatures of the Offender
+  - equivocation: the two InternalTransactions, of the same type, and the
+    index of the Block that accepted them
+
+Index is the index of the Events for a fork, and the index of the Block
+otherwise.
+*/
+type Evidence struct {
+	Kind                 EvidenceKind          `json:"kind"`
+	Offender             string                `json:"offender"`
+	Index                int                   `json:"index"`
+	Events               []*Event              `json:"events,omitempty"`
+	Blocks               []BlockBody           `json:"blocks,omitempty"`
+	BlockSignatures      []string              `json:"block_signatures,omitempty"`
+	InternalTransactions []InternalTransaction `json:"internal_transactions,omitempty"`
+}
+
+// NewForkEvidence creates the Evidence of a fork from two Events of the same
+// creator with the same index.
+func NewForkEvidence(a, b *Event) (*Evidence, error) {
+	ev := &Evidence{
+		Kind:     ForkEvidence,
+		Offender: a.Creator(),
+		Index:    a.Index(),
+		Events:   []*Event{a, b},
+	}
+
+	if err := ev.Verify(); err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+// NewDoubleSignEvidence creates the Evidence that a validator signed two
+// Blocks with the same index. Both Blocks must contain a valid signature of the
+// validator.
+func NewDoubleSignEvidence(validator string, a, b *Block) (*Evidence, error) {
+	ev := &Evidence{
+		Kind:            DoubleSignEvidence,
+		Offender:        validator,
+		Index:           a.Index(),
+		Blocks:          []BlockBody{a.Body, b.Body},
+		BlockSignatures: []string{a.Signatures[validator], b.Signatures[validator]},
+	}
+
+	if err := ev.Verify(); err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+// NewEquivocationEvidence creates the Evidence that a peer signed two different
+// InternalTransactions of the same type about itself, which were accepted in the
+// Block with the given index.
+func NewEquivocationEvidence(blockIndex int, a, b InternalTransaction) (*Evidence, error) {
+	ev := &Evidence{
+		Kind:                 EquivocationEvidence,
+		Offender:             a.Body.Peer.PubKeyString(),
+		Index:                blockIndex,
+		InternalTransactions: []InternalTransaction{a, b},
+	}
+
+	if err := ev.Verify(); err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+// Verify checks that the Evidence proves the misbehaviour of the Offender.
+func (e *Evidence) Verify() error {
+	if _, err := parsePublicKey(e.Offender); err != nil {
+		return fmt.Errorf("offender: %v", err)
+	}
+
+	switch e.Kind {
+	case ForkEvidence:
+		return e.verifyFork()
+	case DoubleSignEvidence:
+		return e.verifyDoubleSign()
+	case EquivocationEvidence:
+		return e.verifyEquivocation()
+	default:
+		return fmt.Errorf("unknown evidence kind %q", e.Kind)
+	}
+}
+
+func (e *Evidence) verifyFork() error {
+	if len(e.Events) != 2 {
+		return fmt.Errorf("fork evidence requires 2 events, not %d", len(e.Events))
+	}
+
+	for _, ev := range e.Events {
+		if ev == nil {
+			return fmt.Errorf("fork evidence requires 2 events")
+		}
+		if ev.Creator() != e.Offender || ev.Index() != e.Index {
+			return fmt.Errorf("event %s is not event %d of %s", ev.Hex(), e.Index, e.Offender)
+		}
+		if ok, err := ev.Verify(); err != nil || !ok {
+			return fmt.Errorf("invalid signature on event %s", ev.Hex())
+		}
+	}
+
+	if e.Events[0].Hex() == e.Events[1].Hex() {
+		return fmt.Errorf("fork evidence requires different events")
+	}
+
+	return nil
+}
+
+func (e *Evidence) verifyDoubleSign() error {
+	if len(e.Blocks) != 2 || len(e.BlockSignatures) != 2 {
+		return fmt.Errorf("double-sign evidence requires 2 blocks and 2 signatures")
+	}
+
+	validator, _ := common.DecodeFromString(e.Offender)
+
+	hashes := make([][]byte, 2)
+
+	for i, body := range e.Blocks {
+		if body.Index != e.Index {
+			return fmt.Errorf("block %d is not block %d", body.Index, e.Index)
+		}
+
+		block := &Block{Body: body}
+
+		ok, err := block.Verify(BlockSignature{
+			Validator: validator,
+			Index:     body.Index,
+			Signature: e.BlockSignatures[i],
+		})
+		if err != nil || !ok {
+			return fmt.Errorf("invalid signature of %s on block %d", e.Offender, body.Index)
+		}
+
+		if hashes[i], err = body.Hash(); err != nil {
+			return err
+		}
+	}
+
+	if bytes.Equal(hashes[0], hashes[1]) {
+		return fmt.Errorf("double-sign evidence requires different blocks")
+	}
+
+	return nil
+}
+
+func (e *Evidence) verifyEquivocation() error {
+	if len(e.InternalTransactions) != 2 {
+		return fmt.Errorf("equivocation evidence requires 2 internal transactions, not %d", len(e.InternalTransactions))
+	}
+
+	for _, itx := range e.InternalTransactions {
+		if itx.Body.Peer.PubKeyString() != e.Offender {
+			return fmt.Errorf("internal transaction %s is not signed by %s", itx.HashString(), e.Offender)
+		}
+		if ok, err := itx.Verify(); err != nil || !ok {
+			return fmt.Errorf("invalid signature on internal transaction %s", itx.HashString())
+		}
+	}
+
+	if e.InternalTransactions[0].Body.Type != e.InternalTransactions[1].Body.Type {
+		return fmt.Errorf("equivocation evidence requires internal transactions of the same type")
+	}
+
+	if e.InternalTransactions[0].HashString() == e.InternalTransactions[1].HashString() {
+		return fmt.Errorf("equivocation evidence requires different internal transactions")
+	}
+
+	return nil
+}
+
+// Hash returns the SHA256 hash of the JSON encoding of the Evidence.
+func (e *Evidence) Hash() ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.SHA256(data), nil
+}
+
+// Sign returns the Evidence signed by the node that collected it.
+func (e *Evidence) Sign(privKey *ecdsa.PrivateKey) (*SignedEvidence, error) {
+	hash, err := e.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	R, S, err := keys.Sign(privKey, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedEvidence{
+		Evidence:  *e,
+		Reporter:  keys.PublicKeyHex(&privKey.PublicKey),
+		Signature: keys.EncodeSignature(R, S),
+	}, nil
+}
+
+// SignedEvidence is an Evidence signed by the node that collected it, the
+// Reporter, which is the format in which Evidence is exported.
+type SignedEvidence struct {
+	Evidence  Evidence `json:"evidence"`
+	Reporter  string   `json:"reporter"`
+	Signature string   `json:"signature"`
+}
+
+// Verify checks the signature of the Reporter, and the Evidence.
+func (se *SignedEvidence) Verify() error {
+	hash, err := se.Evidence.Hash()
+	if err != nil {
+		return err
+	}
+
+	reporter, err := parsePublicKey(se.Reporter)
+	if err != nil {
+		return fmt.Errorf("reporter: %v", err)
+	}
+
+	r, s, err := keys.DecodeSignature(se.Signature)
+	if err != nil {
+		return err
+	}
+
+	if !keys.Verify(reporter, hash, r, s) {
+		return fmt.Errorf("invalid signature of reporter %s", se.Reporter)
+	}
+
+	return se.Evidence.Verify()
+}
+
+// parsePublicKey parses a public key in the 0X-prefixed hex format of the
+// Evidence, which comes from untrusted sources.
+func parsePublicKey(hexKey string) (*ecdsa.PublicKey, error) {
+	if !strings.HasPrefix(hexKey, "0X") {
+		return nil, fmt.Errorf("public key %q should start with 0X", hexKey)
+	}
+
+	pubBytes, err := common.DecodeFromString(hexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey := keys.ToPublicKey(pubBytes)
+	if pubKey == nil || pubKey.X == nil {
+		return nil, fmt.Errorf("invalid public key %s", hexKey)
+	}
+
+	return pubKey, nil
+}
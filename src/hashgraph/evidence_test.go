@@ -0,0 +1,128 @@
+package hashgraph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestForkEvidence(t *testing.T) {
+	key, _ := keys.GenerateECDSAKey()
+	creator := keys.FromPublicKey(&key.PublicKey)
+
+	a := NewEvent([][]byte{[]byte("a")}, nil, nil, []string{"", ""}, creator, 0)
+	b := NewEvent([][]byte{[]byte("b")}, nil, nil, []string{"", ""}, creator, 0)
+	a.Sign(key)
+	b.Sign(key)
+
+	evidence, err := NewForkEvidence(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewForkEvidence(a, a); err == nil {
+		t.Fatal("The same Event twice is not a fork")
+	}
+
+	c := NewEvent([][]byte{[]byte("c")}, nil, nil, []string{"", ""}, creator, 1)
+	c.Sign(key)
+	if _, err := NewForkEvidence(a, c); err == nil {
+		t.Fatal("Events with different indexes are not a fork")
+	}
+
+	// The signed evidence survives a JSON round trip
+	reporter, _ := keys.GenerateECDSAKey()
+
+	signed, err := evidence.Sign(reporter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded SignedEvidence
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := decoded.Verify(); err != nil {
+		t.Fatalf("The decoded evidence should be valid: %v", err)
+	}
+
+	// Tampering breaks the signature of the reporter or of the offender
+	decoded.Evidence.Index = 1
+	if err := decoded.Verify(); err == nil {
+		t.Fatal("Tampered evidence should be invalid")
+	}
+
+	decoded.Reporter = "0X"
+	if err := decoded.Verify(); err == nil {
+		t.Fatal("Evidence with an invalid reporter should be invalid")
+	}
+}
+
+func TestDoubleSignEvidence(t *testing.T) {
+	key, _ := keys.GenerateECDSAKey()
+	validator := keys.PublicKeyHex(&key.PublicKey)
+
+	a := createTestBlock()
+	b := createTestBlock()
+	b.Body.StateHash = []byte("other state")
+
+	for _, block := range []*Block{a, b} {
+		sig, err := block.Sign(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.SetSignature(sig)
+	}
+
+	if _, err := NewDoubleSignEvidence(validator, a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewDoubleSignEvidence(validator, a, a); err == nil {
+		t.Fatal("Signing the same Block twice is not a double sign")
+	}
+
+	// The signature of one version does not match the other
+	b.Signatures[validator] = a.Signatures[validator]
+	if _, err := NewDoubleSignEvidence(validator, a, b); err == nil {
+		t.Fatal("Evidence with an invalid signature should be invalid")
+	}
+}
+
+func TestEquivocationEvidence(t *testing.T) {
+	key, _ := keys.GenerateECDSAKey()
+	peer := peers.NewPeer(keys.PublicKeyHex(&key.PublicKey), "paris", "peer1")
+
+	newTx := func(itx InternalTransaction) InternalTransaction {
+		itx.Sign(key)
+		return itx
+	}
+
+	a := newTx(NewInternalTransactionAddress(*peer, "london"))
+	b := newTx(NewInternalTransactionAddress(*peer, "berlin"))
+	c := newTx(NewInternalTransactionRename(*peer, "peer2"))
+
+	evidence, err := NewEquivocationEvidence(3, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evidence.Offender != peer.PubKeyString() || evidence.Index != 3 {
+		t.Fatalf("The evidence should be against %s in Block 3, not %s in Block %d", peer.PubKeyString(), evidence.Offender, evidence.Index)
+	}
+
+	if _, err := NewEquivocationEvidence(3, a, c); err == nil {
+		t.Fatal("InternalTransactions of different types are not an equivocation")
+	}
+
+	if _, err := NewEquivocationEvidence(3, a, a); err == nil {
+		t.Fatal("The same InternalTransaction twice is not an equivocation")
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/metrics"
 	"github.com/mosaicnetworks/babble/src/peers"
 	"github.com/sirupsen/logrus"
 )
@@ -41,6 +44,9 @@ type Hashgraph struct {
 	PendingLoadedEvents     int                    // number of loaded events that are not yet committed
 	commitCallback          InternalCommitCallback // commit block callback
 	topologicalIndex        int                    // counter used to order events in topological order (only local)
+	thresholds              peers.Thresholds       // super-majority and trust thresholds of the network
+	replayStore             *BadgerStore           // database being replayed, from which round timelines are restored
+	genesisStateHash        []byte                 // hash of the initial state of the App, recorded in Block 0
 
 	ancestorCache     *common.LRU
 	selfAncestorCache *common.LRU
@@ -48,6 +54,10 @@ type Hashgraph struct {
 	roundCache        *common.LRU
 	timestampCache    *common.LRU
 	witnessCache      *common.LRU
+	signatureCache    *common.LRU // [event hash] => verified signature
+	cacheSize         int
+
+	invalidSigCallback InvalidSignatureCallback // invalid block signature callback
 
 	logger *logrus.Entry
 }
@@ -73,12 +83,27 @@ func NewHashgraph(store Store, commitCallback InternalCommitCallback, logger *lo
 		roundCache:        common.NewLRU(cacheSize, nil),
 		timestampCache:    common.NewLRU(cacheSize, nil),
 		witnessCache:      common.NewLRU(cacheSize, nil),
+		signatureCache:    common.NewLRU(cacheSize, nil),
+		cacheSize:         cacheSize,
+		thresholds:        peers.DefaultThresholds,
 		logger:            logger,
 	}
 
 	return &hashgraph
 }
 
+// SetThresholds changes the super-majority and trust thresholds of the
+// Hashgraph. They are defined by the genesis document, and must be set before
+// any Event is inserted.
+func (h *Hashgraph) SetThresholds(thresholds peers.Thresholds) {
+	h.thresholds = thresholds
+}
+
+// Thresholds returns the super-majority and trust thresholds of the Hashgraph.
+func (h *Hashgraph) Thresholds() peers.Thresholds {
+	return h.thresholds
+}
+
 // Init sets the initial PeerSet, which also creates the corresponding Roots and
 // updates the Repertoire.
 func (h *Hashgraph) Init(peerSet *peers.PeerSet) error {
@@ -193,8 +218,12 @@ func (h *Hashgraph) _stronglySee(x, y string, peers *peers.PeerSet) (bool, error
 		return false, err
 	}
 
+	// Only the voters count, shadow peers do not form super-majorities
 	c := 0
-	for p := range peers.ByPubKey {
+	for p, peer := range peers.ByPubKey {
+		if peer.Shadow {
+			continue
+		}
 		xla, xlaok := ex.lastAncestors[p]
 		yfd, yfdok := ey.firstDescendants[p]
 		if xlaok && yfdok && xla.Index >= yfd.Index {
@@ -202,7 +231,7 @@ func (h *Hashgraph) _stronglySee(x, y string, peers *peers.PeerSet) (bool, error
 		}
 	}
 
-	return c >= peers.SuperMajority(), nil
+	return c >= h.thresholds.SuperMajorityOf(peers), nil
 }
 
 func (h *Hashgraph) round(x string) (int, error) {
@@ -274,7 +303,7 @@ func (h *Hashgraph) _round(x string) (int, error) {
 
 	// If there is a super-majority of strongly-seen witnesses, increment the
 	// round
-	if c >= parentRoundPeerSet.SuperMajority() {
+	if c >= h.thresholds.SuperMajorityOf(parentRoundPeerSet) {
 		round++
 	}
 
@@ -311,7 +340,8 @@ func (h *Hashgraph) _witness(x string) (bool, error) {
 		return false, err
 	}
 
-	if _, ok := peerSet.ByPubKey[ex.Creator()]; !ok {
+	//shadow peers do not create witnesses
+	if !peerSet.IsVoter(ex.Creator()) {
 		return false, nil
 	}
 
@@ -422,6 +452,17 @@ func (h *Hashgraph) checkSelfParent(event *Event) error {
 	// of concern. It can arrise when the hashgraph is being accessed
 	// concurrently by multiple go-routines.
 	if !selfParentLegit {
+		// A different Event with the same index is a fork
+		known, err := h.Store.ParticipantEvent(creator, event.Index())
+		if err == nil && known != event.Hex() {
+			return ForkError{
+				Creator: creator,
+				Index:   event.Index(),
+				Known:   known,
+				Event:   event.Hex(),
+			}
+		}
+
 		return NewSelfParentError("Self-parent not last known event by creator", true)
 	}
 
@@ -648,30 +689,47 @@ func (h *Hashgraph) InsertEventAndRunConsensus(event *Event, setWireInfo bool) e
 		}
 		return err
 	}
+	start := time.Now()
 	if err := h.DivideRounds(); err != nil {
 		h.logger.WithError(err).Errorf("DivideRounds")
 		return err
 	}
+	start = observePhase(metrics.PhaseDivideRounds, start)
+
 	if err := h.DecideFame(); err != nil {
 		h.logger.WithError(err).Errorf("DecideFame")
 		return err
 	}
+	start = observePhase(metrics.PhaseDecideFame, start)
+
 	if err := h.DecideRoundReceived(); err != nil {
 		h.logger.WithError(err).Errorf("DecideRoundReceived")
 		return err
 	}
+	start = observePhase(metrics.PhaseDecideRoundReceived, start)
+
 	if err := h.ProcessDecidedRounds(); err != nil {
 		h.logger.WithError(err).Errorf("ProcessDecidedRounds")
 		return err
 	}
+	observePhase(metrics.PhaseProcessDecidedRounds, start)
+
 	return nil
 }
 
+// observePhase records the duration of a consensus phase which started at
+// start, and returns the start of the next phase.
+func observePhase(phase string, start time.Time) time.Time {
+	now := time.Now()
+	metrics.ConsensusPhaseDuration.WithLabelValues(phase).Observe(now.Sub(start).Seconds())
+	return now
+}
+
 //InsertEvent attempts to insert an Event in the DAG. It verifies the signature,
 //checks the ancestors are known, and prevents the introduction of forks.
 func (h *Hashgraph) InsertEvent(event *Event, setWireInfo bool) error {
 	//verify signature
-	if ok, err := event.Verify(); !ok {
+	if ok, err := h.verifyEvent(event); !ok {
 		if err != nil {
 			return err
 		}
@@ -746,9 +804,37 @@ func (h *Hashgraph) InsertEvent(event *Event, setWireInfo bool) error {
 		h.PendingSignatures.Add(bs)
 	}
 
+	metrics.EventsInserted.Inc()
+
 	return nil
 }
 
+// verifyEvent verifies the signatures of an Event, unless the same signature
+// of the same Event was already verified. Events are verified again when they
+// are received from more than one peer, or inserted again after a Reset, so
+// the verified signatures are cached by Event hash. The signature itself is
+// compared, so that a cached hash does not validate another signature.
+func (h *Hashgraph) verifyEvent(event *Event) (bool, error) {
+	if h.isVerified(event) {
+		metrics.SignatureCacheHits.Inc()
+		return true, nil
+	}
+
+	ok, err := event.Verify()
+	if ok {
+		h.signatureCache.Add(event.Hex(), event.Signature)
+	}
+
+	return ok, err
+}
+
+// isVerified returns true if the signature of the Event is in the cache of
+// verified signatures.
+func (h *Hashgraph) isVerified(event *Event) bool {
+	sig, ok := h.signatureCache.Get(event.Hex())
+	return ok && sig.(string) == event.Signature
+}
+
 //InsertFrameEvent inserts the FrameEvent's core Event, without checking its
 //parents or signature. It doesnt add the Event to UndeterminedEvents either.
 func (h *Hashgraph) InsertFrameEvent(frameEvent *FrameEvent) error {
@@ -829,9 +915,11 @@ func (h *Hashgraph) DivideRounds() error {
 				if !common.IsStore(err, common.KeyNotFound) {
 					return err
 				}
-				roundInfo = NewRoundInfo()
+				roundInfo = h.newRoundInfo(roundNumber)
 			}
 
+			h.recordStage(roundInfo, &roundInfo.Timeline.Created, "")
+
 			if !h.PendingRounds.Queued(roundNumber) &&
 				!roundInfo.decided &&
 				(h.roundLowerBound == nil || roundNumber > *h.roundLowerBound) {
@@ -963,7 +1051,7 @@ func (h *Hashgraph) DecideFame() error {
 
 						//normal round
 						if math.Mod(float64(diff), COIN_ROUND_FREQ) > 0 {
-							if t >= jPeerSet.SuperMajority() {
+							if t >= h.thresholds.SuperMajorityOf(jPeerSet) {
 								rRoundInfo.SetFame(x, v)
 								setVote(votes, y, x, v)
 								break VOTE_LOOP //break out of j loop
@@ -971,7 +1059,7 @@ func (h *Hashgraph) DecideFame() error {
 								setVote(votes, y, x, v)
 							}
 						} else { //coin round
-							if t >= jPeerSet.SuperMajority() {
+							if t >= h.thresholds.SuperMajorityOf(jPeerSet) {
 								setVote(votes, y, x, v)
 							} else {
 								setVote(votes, y, x, middleBit(y)) //middle bit of y's hash
@@ -982,8 +1070,9 @@ func (h *Hashgraph) DecideFame() error {
 			}
 		}
 
-		if rRoundInfo.WitnessesDecided(rPeerSet) {
+		if rRoundInfo.WitnessesDecided(h.thresholds.SuperMajorityOf(rPeerSet)) {
 			decidedRounds = append(decidedRounds, roundIndex)
+			h.recordStage(rRoundInfo, &rRoundInfo.Timeline.Decided, metrics.StageDecided)
 		}
 
 		err = h.Store.SetRound(roundIndex, rRoundInfo)
@@ -1038,7 +1127,7 @@ func (h *Hashgraph) DecideRoundReceived() error {
 				below this round are either already committed or will be
 				received later, so just continue through the i loop.
 			*/
-			if !(tr.WitnessesDecided(tPeers)) {
+			if !(tr.WitnessesDecided(h.thresholds.SuperMajorityOf(tPeers))) {
 				if h.roundLowerBound == nil || *h.roundLowerBound < i {
 					break
 				} else {
@@ -1059,7 +1148,7 @@ func (h *Hashgraph) DecideRoundReceived() error {
 				}
 			}
 
-			if len(s) == len(fws) && len(s) >= tPeers.SuperMajority() {
+			if len(s) == len(fws) && len(s) >= h.thresholds.SuperMajorityOf(tPeers) {
 				received = true
 
 				ex, err := h.Store.GetEvent(x)
@@ -1125,6 +1214,8 @@ func (h *Hashgraph) ProcessDecidedRounds() error {
 			return fmt.Errorf("Getting Frame %d: %v", r.Index, err)
 		}
 
+		h.recordStage(round, &round.Timeline.Received, metrics.StageReceived)
+
 		h.logger.WithFields(logrus.Fields{
 			"round_received": r.Index,
 			"witnesses":      round.FamousWitnesses(),
@@ -1150,7 +1241,7 @@ func (h *Hashgraph) ProcessDecidedRounds() error {
 			}
 
 			lastBlockIndex := h.Store.LastBlockIndex()
-			block, err := NewBlockFromFrame(lastBlockIndex+1, frame)
+			block, err := h.newBlockFromFrame(lastBlockIndex+1, frame)
 			if err != nil {
 				return err
 			}
@@ -1165,6 +1256,8 @@ func (h *Hashgraph) ProcessDecidedRounds() error {
 				err := h.commitCallback(block)
 				if err != nil {
 					h.logger.Warningf("Failed to commit block %d", block.Index())
+				} else {
+					h.recordStage(round, &round.Timeline.Committed, metrics.StageCommitted)
 				}
 			}
 
@@ -1173,7 +1266,12 @@ func (h *Hashgraph) ProcessDecidedRounds() error {
 			h.logger.Debugf("No Events to commit for ConsensusRound %d", r.Index)
 		}
 
+		if err := h.Store.SetRound(r.Index, round); err != nil {
+			return err
+		}
+
 		processedRounds = append(processedRounds, r.Index)
+		metrics.RoundsDecided.Inc()
 
 		if h.LastConsensusRound == nil || r.Index > *h.LastConsensusRound {
 			h.setLastConsensusRound(r.Index)
@@ -1337,6 +1435,9 @@ func (h *Hashgraph) ProcessSigPool() error {
 				"validator": peerSet.ByPubKey[bs.ValidatorHex()],
 				"block":     string(bytesBlock),
 			}).Warning("Verifying Block signature. Invalid signature")
+			if h.invalidSigCallback != nil {
+				h.invalidSigCallback(block, bs)
+			}
 			continue
 		}
 
@@ -1361,6 +1462,18 @@ func (h *Hashgraph) ProcessSigPool() error {
 	return nil
 }
 
+// voterSignatures returns the number of signatures of a block from voters of
+// the peer-set, excluding shadow peers.
+func voterSignatures(block *Block, peerSet *peers.PeerSet) int {
+	c := 0
+	for validator := range block.Signatures {
+		if peerSet.IsVoter(validator) {
+			c++
+		}
+	}
+	return c
+}
+
 /*
 SetAnchorBlock sets the AnchorBlock index if the proposed block has collected
 enough signatures (+1/3) and is above the current AnchorBlock. The AnchorBlock
@@ -1374,15 +1487,17 @@ func (h *Hashgraph) SetAnchorBlock(block *Block) error {
 		return err
 	}
 
-	if len(block.Signatures) > peerSet.TrustCount() &&
+	signatures := voterSignatures(block, peerSet)
+
+	if signatures > h.thresholds.TrustCountOf(peerSet) &&
 		(h.AnchorBlock == nil ||
 			block.Index() > *h.AnchorBlock) {
 
 		h.setAnchorBlock(block.Index())
 		h.logger.WithFields(logrus.Fields{
 			"block_index": block.Index(),
-			"signatures":  len(block.Signatures),
-			"trustCount":  peerSet.TrustCount(),
+			"signatures":  signatures,
+			"trustCount":  h.thresholds.TrustCountOf(peerSet),
 		}).Debug("Setting AnchorBlock")
 	} else {
 		var msg string
@@ -1393,8 +1508,8 @@ func (h *Hashgraph) SetAnchorBlock(block *Block) error {
 		}
 		h.logger.WithFields(logrus.Fields{
 			"index":        block.Index(),
-			"sigs":         len(block.Signatures),
-			"trust_count":  peerSet.TrustCount(),
+			"sigs":         signatures,
+			"trust_count":  h.thresholds.TrustCountOf(peerSet),
 			"anchor_block": msg,
 		}).Debug("Block is not a suitable Anchor")
 	}
@@ -1422,6 +1537,47 @@ func (h *Hashgraph) GetAnchorBlockWithFrame() (*Block, *Frame, error) {
 	return block, frame, nil
 }
 
+// SetGenesisStateHash sets the hash of the initial state of the App, which is
+// recorded in the first Block.
+func (h *Hashgraph) SetGenesisStateHash(hash []byte) {
+	h.genesisStateHash = hash
+}
+
+// newBlockFromFrame assembles a Block from a Frame, and records the genesis
+// state hash, if any, in the first Block.
+func (h *Hashgraph) newBlockFromFrame(blockIndex int, frame *Frame) (*Block, error) {
+	block, err := NewBlockFromFrame(blockIndex, frame)
+	if err != nil {
+		return nil, err
+	}
+
+	if blockIndex == 0 && len(h.genesisStateHash) > 0 {
+		block.Body.GenesisStateHash = h.genesisStateHash
+	}
+
+	return block, nil
+}
+
+// SetInvalidSignatureCallback sets the function called when a validator signed
+// a different version of a Block.
+func (h *Hashgraph) SetInvalidSignatureCallback(callback InvalidSignatureCallback) {
+	h.invalidSigCallback = callback
+}
+
+// SetCacheSize changes the size of the caches used by the consensus methods.
+// They only hold values derived from the Events, so resizing them does not
+// affect the outcome of consensus. The caches of the Store keep their size.
+func (h *Hashgraph) SetCacheSize(size int) {
+	h.cacheSize = size
+	h.ancestorCache.Resize(size)
+	h.selfAncestorCache.Resize(size)
+	h.stronglySeeCache.Resize(size)
+	h.roundCache.Resize(size)
+	h.timestampCache.Resize(size)
+	h.witnessCache.Resize(size)
+	h.signatureCache.Resize(size)
+}
+
 //Reset clears the Hashgraph and resets it from a new base.
 func (h *Hashgraph) Reset(block *Block, frame *Frame) error {
 	//Clear all state
@@ -1434,12 +1590,14 @@ func (h *Hashgraph) Reset(block *Block, frame *Frame) error {
 	h.PendingLoadedEvents = 0
 	h.topologicalIndex = 0
 
-	cacheSize := h.Store.CacheSize()
-	h.ancestorCache = common.NewLRU(cacheSize, nil)
-	h.selfAncestorCache = common.NewLRU(cacheSize, nil)
-	h.stronglySeeCache = common.NewLRU(cacheSize, nil)
-	h.roundCache = common.NewLRU(cacheSize, nil)
-	h.witnessCache = common.NewLRU(cacheSize, nil)
+	h.ancestorCache = common.NewLRU(h.cacheSize, nil)
+	h.selfAncestorCache = common.NewLRU(h.cacheSize, nil)
+	h.stronglySeeCache = common.NewLRU(h.cacheSize, nil)
+	h.roundCache = common.NewLRU(h.cacheSize, nil)
+	h.witnessCache = common.NewLRU(h.cacheSize, nil)
+
+	// The signatureCache is kept, because the signatures remain valid, and the
+	// Events following the frame are likely to be inserted again.
 
 	//Initialize new Roots
 	if err := h.Store.Reset(frame); err != nil {
@@ -1475,56 +1633,86 @@ store is put in maintenance-mode to avoid reinserting items in the database.
 */
 func (h *Hashgraph) Bootstrap() error {
 	if badgerStore, ok := h.Store.(*BadgerStore); ok {
-
-		if !badgerStore.GetMaintenanceMode() {
-			defer badgerStore.SetMaintenanceMode(false)
-		}
-
-		badgerStore.SetMaintenanceMode(true)
-
-		// Load Genesis PeerSet
-		peerSet, err := badgerStore.dbGetPeerSet(0)
-		if err != nil {
-			// return fmt.Errorf("No Genesis PeerSet: %v", err)
+		err := h.replay(badgerStore, nil)
+		if err == errNoGenesisPeerSet {
 			h.logger.Debug("No Genesis PeerSet, skip bootstrap")
 			return nil
 		}
+		return err
+	}
 
-		// Initialize the InmemStore with Genesis PeerSet. This has
-		// side-effects: it will create the corresponding Roots and populate the
-		// Repertoires.
-		badgerStore.inmemStore.SetPeerSet(0, peerSet)
+	return nil
+}
 
-		// Retrieve the Events from the underlying DB, in batches of 100, and
-		// insert them sequentially into the hashgraph.
-		index := 0
-		batchSize := 100
-		for {
-			topologicalEvents, err := badgerStore.dbTopologicalEvents(index*batchSize, batchSize)
-			if err != nil {
-				return err
-			}
+// errNoGenesisPeerSet is returned by replay when the database is empty.
+var errNoGenesisPeerSet = fmt.Errorf("No Genesis PeerSet in the database")
 
-			// Insert the Events in the Hashgraph
-			for _, e := range topologicalEvents {
-				if err := h.InsertEventAndRunConsensus(e, true); err != nil {
-					return err
-				}
-			}
+// Replay re-runs consensus on the Events of the Store's DB like Bootstrap,
+// without modifying the DB, and stops as soon as the done function, if not
+// nil, returns true after inserting an Event. It returns an error if the Store
+// is not a BadgerStore, or if the DB is empty.
+func (h *Hashgraph) Replay(done func() bool) error {
+	badgerStore, ok := h.Store.(*BadgerStore)
+	if !ok {
+		return fmt.Errorf("Replay requires a BadgerStore")
+	}
+
+	return h.replay(badgerStore, done)
+}
 
-			// ProcessSigPool
-			if err := h.ProcessSigPool(); err != nil {
+func (h *Hashgraph) replay(badgerStore *BadgerStore, done func() bool) error {
+	if !badgerStore.GetMaintenanceMode() {
+		defer badgerStore.SetMaintenanceMode(false)
+	}
+
+	badgerStore.SetMaintenanceMode(true)
+
+	h.replayStore = badgerStore
+	defer func() { h.replayStore = nil }()
+
+	// Load Genesis PeerSet
+	peerSet, err := badgerStore.dbGetPeerSet(0)
+	if err != nil {
+		return errNoGenesisPeerSet
+	}
+
+	// Initialize the InmemStore with Genesis PeerSet. This has
+	// side-effects: it will create the corresponding Roots and populate the
+	// Repertoires.
+	badgerStore.inmemStore.SetPeerSet(0, peerSet)
+
+	// Retrieve the Events from the underlying DB, in batches of 100, and
+	// insert them sequentially into the hashgraph.
+	index := 0
+	batchSize := 100
+	for {
+		topologicalEvents, err := badgerStore.dbTopologicalEvents(index*batchSize, batchSize)
+		if err != nil {
+			return err
+		}
+
+		// Insert the Events in the Hashgraph
+		for _, e := range topologicalEvents {
+			if err := h.InsertEventAndRunConsensus(e, true); err != nil {
 				return err
 			}
 
-			// Exit after the last batch
-			if len(topologicalEvents) < batchSize {
-				break
+			if done != nil && done() {
+				return nil
 			}
+		}
 
-			index++
+		// ProcessSigPool
+		if err := h.ProcessSigPool(); err != nil {
+			return err
 		}
 
+		// Exit after the last batch
+		if len(topologicalEvents) < batchSize {
+			break
+		}
+
+		index++
 	}
 
 	return nil
@@ -1533,6 +1721,12 @@ func (h *Hashgraph) Bootstrap() error {
 //ReadWireInfo converts a WireEvent to an Event by replacing int IDs with the
 //corresponding public keys.
 func (h *Hashgraph) ReadWireInfo(wevent WireEvent) (*Event, error) {
+	return h.readWireInfo(wevent, h.Store.ParticipantEvent)
+}
+
+// readWireInfo converts a WireEvent to an Event, and looks up the hashes of its
+// parents with parentHash.
+func (h *Hashgraph) readWireInfo(wevent WireEvent, parentHash func(participant string, index int) (string, error)) (*Event, error) {
 	selfParent := ""
 	otherParent := ""
 	var err error
@@ -1548,7 +1742,7 @@ func (h *Hashgraph) ReadWireInfo(wevent WireEvent) (*Event, error) {
 	}
 
 	if wevent.Body.SelfParentIndex >= 0 {
-		selfParent, err = h.Store.ParticipantEvent(creator.PubKeyString(), wevent.Body.SelfParentIndex)
+		selfParent, err = parentHash(creator.PubKeyString(), wevent.Body.SelfParentIndex)
 		if err != nil {
 			return nil, err
 		}
@@ -1560,7 +1754,7 @@ func (h *Hashgraph) ReadWireInfo(wevent WireEvent) (*Event, error) {
 			return nil, fmt.Errorf("Participant %d not found", wevent.Body.OtherParentCreatorID)
 		}
 
-		otherParent, err = h.Store.ParticipantEvent(otherParentCreator.PubKeyString(), wevent.Body.OtherParentIndex)
+		otherParent, err = parentHash(otherParentCreator.PubKeyString(), wevent.Body.OtherParentIndex)
 		if err != nil {
 			return nil, fmt.Errorf("OtherParent (creator: %d, index: %d) not found", wevent.Body.OtherParentCreatorID, wevent.Body.OtherParentIndex)
 		}
@@ -1588,6 +1782,65 @@ func (h *Hashgraph) ReadWireInfo(wevent WireEvent) (*Event, error) {
 	return event, nil
 }
 
+// VerifyWireEvents verifies the signatures of a batch of WireEvents, received
+// in a single sync, with a pool of workers, and adds the valid signatures to
+// the cache of verified signatures. The Events are then inserted one by one
+// without verifying their signatures again. The parents of the Events are
+// looked up in the batch itself, because they are not inserted yet. Events
+// which cannot be read are skipped; InsertEvent reports the errors.
+func (h *Hashgraph) VerifyWireEvents(wevents []WireEvent, workers int) {
+	type participantIndex struct {
+		participant string
+		index       int
+	}
+
+	batch := make(map[participantIndex]string, len(wevents))
+	parentHash := func(participant string, index int) (string, error) {
+		if hash, ok := batch[participantIndex{participant, index}]; ok {
+			return hash, nil
+		}
+		return h.Store.ParticipantEvent(participant, index)
+	}
+
+	events := make([]*Event, 0, len(wevents))
+	for _, we := range wevents {
+		ev, err := h.readWireInfo(we, parentHash)
+		if err != nil {
+			continue
+		}
+		batch[participantIndex{ev.Creator(), ev.Index()}] = ev.Hex()
+
+		if !h.isVerified(ev) {
+			events = append(events, ev)
+		}
+	}
+
+	if workers > len(events) {
+		workers = len(events)
+	}
+
+	eventCh := make(chan *Event)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ev := range eventCh {
+				if ok, _ := ev.Verify(); ok {
+					h.signatureCache.Add(ev.Hex(), ev.Signature)
+				}
+			}
+		}()
+	}
+
+	for _, ev := range events {
+		eventCh <- ev
+	}
+	close(eventCh)
+
+	wg.Wait()
+}
+
 //CheckBlock returns an error if the Block does not contain valid signatures
 //from MORE than 1/3 of participants
 func (h *Hashgraph) CheckBlock(block *Block, peerSet *peers.PeerSet) error {
@@ -1610,13 +1863,13 @@ func (h *Hashgraph) CheckBlock(block *Block, peerSet *peers.PeerSet) error {
 			continue
 		}
 		ok, _ := block.Verify(s)
-		if ok {
+		if ok && peerSet.IsVoter(validatorHex) {
 			validSignatures++
 		}
 	}
 
-	if validSignatures <= peerSet.TrustCount() {
-		return fmt.Errorf("Not enough valid signatures: got %d, need %d", validSignatures, peerSet.TrustCount())
+	if trustCount := h.thresholds.TrustCountOf(peerSet); validSignatures <= trustCount {
+		return fmt.Errorf("Not enough valid signatures: got %d, need %d", validSignatures, trustCount)
 	}
 
 	h.logger.WithField("valid_signatures", validSignatures).Debug("CheckBlock")
@@ -1677,6 +1930,13 @@ an error or not.
 */
 type InternalCommitCallback func(*Block) error
 
+// InvalidSignatureCallback is called by the Hashgraph when a validator signed a
+// different version of a Block. Since the Blocks result from consensus, the
+// difference is usually the state hash returned by the App of the validator.
+// The signature stays in the pool, so the callback is called again every time
+// the pool is processed.
+type InvalidSignatureCallback func(*Block, BlockSignature)
+
 //DummyInternalCommitCallback is used for testing
 func DummyInternalCommitCallback(b *Block) error {
 	return nil
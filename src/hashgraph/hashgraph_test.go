@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 	"testing"
@@ -358,8 +359,13 @@ func TestFork(t *testing.T) {
 	eventA := NewEvent([][]byte{[]byte("yo")}, nil, nil, []string{"", ""}, nodes[2].PubBytes, 0)
 	eventA.Sign(nodes[2].Key)
 	index["a"] = eventA.Hex()
-	if err := hashgraph.InsertEvent(eventA, true); err == nil {
-		t.Fatal("InsertEvent should return error for 'a'")
+	err := hashgraph.InsertEvent(eventA, true)
+	fork, ok := err.(ForkError)
+	if !ok {
+		t.Fatalf("InsertEvent should return a ForkError for 'a', not %v", err)
+	}
+	if fork.Known != index["e2"] || fork.Event != index["a"] || fork.Index != 0 {
+		t.Fatalf("The ForkError should report e2 and a at index 0, not %#v", fork)
 	}
 
 	event01 := NewEvent(nil, nil, nil,
@@ -670,6 +676,110 @@ func TestWitness(t *testing.T) {
 	}
 }
 
+func TestShadowPeer(t *testing.T) {
+	plays := []play{
+		{0, 0, "", "", "e0", nil, nil},
+		{1, 0, "", "", "e1", nil, nil},
+		{2, 0, "", "", "e2", nil, nil},
+		{3, 0, "", "", "s0", nil, nil},
+		{1, 1, "e1", "e0", "e10", nil, nil},
+		{3, 1, "s0", "e10", "s1", nil, nil},
+		{2, 1, "e2", "s1", "e21", [][]byte{[]byte("e21")}, nil},
+		{2, 2, "e21", "", "e21b", nil, nil},
+		{0, 1, "e0", "e21b", "e02", nil, nil},
+		{1, 2, "e10", "e02", "f1", nil, nil},
+		{1, 3, "f1", "", "f1b", [][]byte{[]byte("f1b")}, nil},
+		{0, 2, "e02", "f1b", "f0", nil, nil},
+		{2, 3, "e21b", "f1b", "f2", nil, nil},
+		{1, 4, "f1b", "f0", "f10", nil, nil},
+		{0, 3, "f0", "e21", "f0x", nil, nil},
+		{2, 4, "f2", "f10", "f21", nil, nil},
+		{0, 4, "f0x", "f21", "f02", nil, nil},
+		{0, 5, "f02", "", "f02b", [][]byte{[]byte("f02b")}, nil},
+		{1, 5, "f10", "f02b", "g1", nil, nil},
+		{0, 6, "f02b", "g1", "g0", nil, nil},
+		{2, 5, "f21", "g1", "g2", nil, nil},
+		{1, 6, "g1", "g0", "g10", [][]byte{[]byte("g10")}, nil},
+		{2, 6, "g2", "g10", "g21", nil, nil},
+		{0, 7, "g0", "g21", "g02", [][]byte{[]byte("g02")}, nil},
+		{1, 7, "g10", "g02", "h1", nil, nil},
+		{0, 8, "g02", "h1", "h0", nil, nil},
+		{2, 7, "g21", "h1", "h2", nil, nil},
+		{1, 8, "h1", "h0", "h10", nil, nil},
+		{2, 8, "h2", "h10", "h21", nil, nil},
+		{0, 9, "h0", "h21", "h02", nil, nil},
+		{1, 9, "h10", "h02", "i1", nil, nil},
+		{0, 10, "h02", "i1", "i0", nil, nil},
+		{2, 9, "h21", "i1", "i2", nil, nil},
+	}
+
+	nodes, index, orderedEvents, peerSet := initHashgraphNodes(4)
+
+	// The voters are counted when the PeerSet is built
+	peerSet.Peers[3].Shadow = true
+	peerSet = peers.NewPeerSet(peerSet.Peers)
+	playEvents(plays, nodes, index, orderedEvents)
+	h := createHashgraph(false, orderedEvents, peerSet, t)
+
+	for _, w := range []string{"e0", "e1", "e2"} {
+		if ok, err := h.witness(index[w]); err != nil || !ok {
+			t.Fatalf("%s should be a witness", w)
+		}
+	}
+	if ok, err := h.witness(index["s0"]); err != nil || ok {
+		t.Fatal("The first event of a shadow peer should not be a witness")
+	}
+
+	h.DivideRounds()
+	h.DecideFame()
+	h.DecideRoundReceived()
+	if err := h.ProcessDecidedRounds(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The consensus of the 3 voters is not delayed by the shadow peer, and its
+	// events are received like the others.
+	consensusEvents := h.Store.ConsensusEvents()
+	if l := len(consensusEvents); l != 18 {
+		t.Fatalf("length of consensus should be 18 not %d", l)
+	}
+	if !contains(consensusEvents, index["s1"]) {
+		t.Fatal("The events of the shadow peer should reach consensus")
+	}
+
+	block0, err := h.Store.GetBlock(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Signatures from the shadow peer do not make the block trusted
+	sig, err := block0.Sign(nodes[3].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block0.SetSignature(sig)
+	if err := h.SetAnchorBlock(block0); err != nil {
+		t.Fatal(err)
+	}
+	if h.AnchorBlock != nil {
+		t.Fatalf("The shadow signature should not anchor block 0")
+	}
+
+	for _, node := range nodes[:2] {
+		sig, err := block0.Sign(node.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block0.SetSignature(sig)
+	}
+	if err := h.SetAnchorBlock(block0); err != nil {
+		t.Fatal(err)
+	}
+	if h.AnchorBlock == nil || *h.AnchorBlock != 0 {
+		t.Fatalf("Two voter signatures should anchor block 0")
+	}
+}
+
 func TestRound(t *testing.T) {
 	h, index := initRoundHashgraph(t)
 
@@ -1536,6 +1646,159 @@ func BenchmarkConsensus(b *testing.B) {
 	}
 }
 
+func TestVerifyEventCache(t *testing.T) {
+	h, index := initConsensusHashgraph(false, t)
+
+	event, err := h.Store.GetEvent(index["f1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := h.signatureCache.Get(event.Hex()); !ok {
+		t.Fatal("The signature of an inserted Event should be cached")
+	}
+
+	// The same body with another signature has the same hash
+	key, _ := bkeys.GenerateECDSAKey()
+	forged := NewEvent(event.Body.Transactions,
+		event.Body.InternalTransactions,
+		event.Body.BlockSignatures,
+		event.Body.Parents,
+		event.Body.Creator,
+		event.Body.Index)
+	forged.Sign(key)
+
+	if forged.Hex() != event.Hex() {
+		t.Fatal("The forged Event should have the same hash")
+	}
+
+	if ok, _ := h.verifyEvent(forged); ok {
+		t.Fatal("A cached hash should not validate another signature")
+	}
+
+	if ok, _ := h.verifyEvent(event); !ok {
+		t.Fatal("The cached signature should still be valid")
+	}
+}
+
+// BenchmarkInsertEventSignatureCache inserts the same Events in a new
+// hashgraph, with an empty cache of verified signatures, and with a cache which
+// already contains the signatures, as when a node inserts Events again after
+// a Reset, or receives them from several peers while catching up.
+func BenchmarkInsertEventSignatureCache(b *testing.B) {
+	peerSet, events, _ := consensusWireEvents(b)
+
+	warm := common.NewLRU(cacheSize, nil)
+	for _, ev := range events {
+		warm.Add(ev.Hex(), ev.Signature)
+	}
+
+	bench := func(b *testing.B, signatureCache *common.LRU) {
+		for n := 0; n < b.N; n++ {
+			b.StopTimer()
+			h := NewHashgraph(NewInmemStore(cacheSize), DummyInternalCommitCallback, testLogger(b))
+			if err := h.Init(peerSet); err != nil {
+				b.Fatal(err)
+			}
+			if signatureCache != nil {
+				h.signatureCache = signatureCache
+			}
+			b.StartTimer()
+
+			for _, ev := range events {
+				if err := h.InsertEvent(ev, true); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	b.Run("Cold", func(b *testing.B) { bench(b, nil) })
+	b.Run("Cached", func(b *testing.B) { bench(b, warm) })
+}
+
+// consensusWireEvents returns the peer-set and the Events of the consensus
+// hashgraph, in the order of insertion, as they would be sent in a sync.
+func consensusWireEvents(t testing.TB) (*peers.PeerSet, []*Event, []WireEvent) {
+	h, _ := initConsensusHashgraph(false, t)
+
+	peerSet, _ := h.Store.GetPeerSet(0)
+
+	// Consensus was not run, so the undetermined events are all the events,
+	// in the order of insertion
+	events := []*Event{}
+	wireEvents := []WireEvent{}
+	for _, hash := range h.UndeterminedEvents {
+		ev, err := h.Store.GetEvent(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev)
+		wireEvents = append(wireEvents, ev.ToWire())
+	}
+
+	return peerSet, events, wireEvents
+}
+
+func TestVerifyWireEvents(t *testing.T) {
+	peerSet, events, wireEvents := consensusWireEvents(t)
+
+	// Swap the signatures of two events
+	wireEvents[3].Signature, wireEvents[4].Signature = wireEvents[4].Signature, wireEvents[3].Signature
+
+	h := NewHashgraph(NewInmemStore(cacheSize), DummyInternalCommitCallback, testLogger(t))
+	if err := h.Init(peerSet); err != nil {
+		t.Fatal(err)
+	}
+
+	h.VerifyWireEvents(wireEvents, 4)
+
+	for i, ev := range events {
+		if verified := h.isVerified(ev); verified != (i != 3 && i != 4) {
+			t.Fatalf("Event %d verified should be %v", i, !verified)
+		}
+	}
+
+	if l := h.signatureCache.Len(); l != len(events)-2 {
+		t.Fatalf("The cache should contain %d signatures, not %d", len(events)-2, l)
+	}
+}
+
+// BenchmarkSyncVerification inserts the Events of a sync in a new hashgraph,
+// verifying the signatures one by one as the Events are inserted, or in
+// parallel before they are inserted.
+func BenchmarkSyncVerification(b *testing.B) {
+	peerSet, _, wireEvents := consensusWireEvents(b)
+
+	bench := func(b *testing.B, workers int) {
+		for n := 0; n < b.N; n++ {
+			b.StopTimer()
+			h := NewHashgraph(NewInmemStore(cacheSize), DummyInternalCommitCallback, testLogger(b))
+			if err := h.Init(peerSet); err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+
+			if workers > 0 {
+				h.VerifyWireEvents(wireEvents, workers)
+			}
+
+			for _, we := range wireEvents {
+				ev, err := h.ReadWireInfo(we)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := h.InsertEvent(ev, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	b.Run("Sequential", func(b *testing.B) { bench(b, 0) })
+	b.Run("Parallel", func(b *testing.B) { bench(b, runtime.NumCPU()) })
+}
+
 func TestKnown(t *testing.T) {
 	h, _ := initConsensusHashgraph(false, t)
 
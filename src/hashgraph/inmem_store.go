@@ -1,7 +1,10 @@
 package hashgraph
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
+	"sync"
 
 	cm "github.com/mosaicnetworks/babble/src/common"
 	"github.com/mosaicnetworks/babble/src/peers"
@@ -16,6 +19,7 @@ type InmemStore struct {
 	eventCache             *cm.LRU          //hash => Event
 	roundCache             *cm.LRU          //round number => Round
 	blockCache             *cm.LRU          //index => Block
+	txCache                *cm.LRU          //tx hash => TxLocation
 	frameCache             *cm.LRU          //round received => Frame
 	consensusCache         *cm.RollingIndex //consensus index => hash
 	totConsensusEvents     int
@@ -25,6 +29,12 @@ type InmemStore struct {
 	lastRound              int
 	lastConsensusEvents    map[string]string //[participant] => hex() of last consensus event
 	lastBlock              int
+	membershipChanges      map[[2]int]MembershipChange //[block, index] => MembershipChange
+	pinLock                sync.RWMutex                //protects the pinned Blocks and Frames, which the API reads and writes
+	pinnedBlocks           map[int]*Block              //index => pinned Block
+	pinnedFrames           map[int]*Frame              //round received => Frame of a pinned Block
+	watermarkLock          sync.Mutex                  //protects the App watermark, which the App advances
+	appWatermark           int                         //index of the last Block acknowledged by the App
 }
 
 // NewInmemStore creates a new InmemStore where all caches are limited by
@@ -35,6 +45,7 @@ func NewInmemStore(cacheSize int) *InmemStore {
 		eventCache:             cm.NewLRU(cacheSize, nil),
 		roundCache:             cm.NewLRU(cacheSize, nil),
 		blockCache:             cm.NewLRU(cacheSize, nil),
+		txCache:                cm.NewLRU(cacheSize, nil),
 		frameCache:             cm.NewLRU(cacheSize, nil),
 		consensusCache:         cm.NewRollingIndex("ConsensusCache", cacheSize),
 		peerSetCache:           NewPeerSetCache(),
@@ -43,6 +54,10 @@ func NewInmemStore(cacheSize int) *InmemStore {
 		lastRound:              -1,
 		lastBlock:              -1,
 		lastConsensusEvents:    map[string]string{},
+		membershipChanges:      make(map[[2]int]MembershipChange),
+		pinnedBlocks:           make(map[int]*Block),
+		pinnedFrames:           make(map[int]*Frame),
+		appWatermark:           -1,
 	}
 	return store
 }
@@ -74,6 +89,20 @@ func (s *InmemStore) SetPeerSet(round int, peerSet *peers.PeerSet) error {
 	return nil
 }
 
+// ReplacePeerSet implements the Store interface.
+func (s *InmemStore) ReplacePeerSet(round int, peerSet *peers.PeerSet) error {
+	err := s.peerSetCache.Replace(round, peerSet)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range peerSet.Peers {
+		s.addParticipant(p)
+	}
+
+	return nil
+}
+
 func (s *InmemStore) addParticipant(p *peers.Peer) error {
 	if _, ok := s.participantEventsCache.participants.ByID[p.ID()]; !ok {
 		if err := s.participantEventsCache.AddPeer(p); err != nil {
@@ -238,6 +267,9 @@ func (s *InmemStore) GetRoot(participant string) (*Root, error) {
 func (s *InmemStore) GetBlock(index int) (*Block, error) {
 	res, ok := s.blockCache.Get(index)
 	if !ok {
+		if pinned, ok := s.pinnedBlock(index); ok {
+			return pinned, nil
+		}
 		return nil, cm.NewStoreErr("BlockCache", cm.KeyNotFound, strconv.Itoa(index))
 	}
 	return res.(*Block), nil
@@ -250,13 +282,69 @@ func (s *InmemStore) SetBlock(block *Block) error {
 	if err != nil && !cm.IsStore(err, cm.KeyNotFound) {
 		return err
 	}
+	if err != nil {
+		s.indexTransactions(block)
+	}
 	s.blockCache.Add(index, block)
+	s.pinLock.Lock()
+	if _, ok := s.pinnedBlocks[index]; ok {
+		s.pinnedBlocks[index] = block
+	}
+	s.pinLock.Unlock()
 	if index > s.lastBlock {
 		s.lastBlock = index
 	}
 	return nil
 }
 
+// indexTransactions records the location of the block's transactions in the
+// txCache, unless they are already indexed.
+func (s *InmemStore) indexTransactions(block *Block) {
+	for i, tx := range block.Transactions() {
+		hash := TxHash(tx)
+		if _, ok := s.txCache.Get(hash); !ok {
+			s.txCache.Add(hash, TxLocation{Block: block.Index(), Index: i})
+		}
+	}
+}
+
+// GetTx implements the Store interface.
+func (s *InmemStore) GetTx(hash string) (TxLocation, error) {
+	res, ok := s.txCache.Get(hash)
+	if !ok {
+		return TxLocation{}, cm.NewStoreErr("TxCache", cm.KeyNotFound, hash)
+	}
+	return res.(TxLocation), nil
+}
+
+// SetMembershipChange implements the Store interface.
+func (s *InmemStore) SetMembershipChange(change MembershipChange) error {
+	s.membershipChanges[[2]int{change.Block, change.Index}] = change
+	return nil
+}
+
+// GetMembershipChanges implements the Store interface. The history is not
+// cleared by Reset, but it only contains the changes processed by this store.
+func (s *InmemStore) GetMembershipChanges() ([]MembershipChange, error) {
+	res := make([]MembershipChange, 0, len(s.membershipChanges))
+	for _, c := range s.membershipChanges {
+		res = append(res, c)
+	}
+	sortMembershipChanges(res)
+	return res, nil
+}
+
+// sortMembershipChanges sorts membership changes in the order of the
+// blockchain.
+func sortMembershipChanges(changes []MembershipChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Block != changes[j].Block {
+			return changes[i].Block < changes[j].Block
+		}
+		return changes[i].Index < changes[j].Index
+	})
+}
+
 // LastBlockIndex implements the Store interface.
 func (s *InmemStore) LastBlockIndex() int {
 	return s.lastBlock
@@ -266,6 +354,12 @@ func (s *InmemStore) LastBlockIndex() int {
 func (s *InmemStore) GetFrame(index int) (*Frame, error) {
 	res, ok := s.frameCache.Get(index)
 	if !ok {
+		s.pinLock.RLock()
+		pinned, ok := s.pinnedFrames[index]
+		s.pinLock.RUnlock()
+		if ok {
+			return pinned, nil
+		}
 		return nil, cm.NewStoreErr("FrameCache", cm.KeyNotFound, strconv.Itoa(index))
 	}
 	return res.(*Frame), nil
@@ -279,6 +373,109 @@ func (s *InmemStore) SetFrame(frame *Frame) error {
 		return err
 	}
 	s.frameCache.Add(index, frame)
+	s.pinLock.Lock()
+	if _, ok := s.pinnedFrames[index]; ok {
+		s.pinnedFrames[index] = frame
+	}
+	s.pinLock.Unlock()
+	return nil
+}
+
+// PinBlock implements the Store interface. Pinned Blocks, and the Frames of
+// their round-received, are kept when they are evicted from the caches, and
+// when the store is reset from a Frame.
+func (s *InmemStore) PinBlock(index int) error {
+	block, err := s.GetBlock(index)
+	if err != nil {
+		return err
+	}
+
+	frame, err := s.GetFrame(block.RoundReceived())
+	if err != nil && !cm.IsStore(err, cm.KeyNotFound) {
+		return err
+	}
+
+	s.pin(block, frame)
+
+	return nil
+}
+
+// pin adds a Block, and the Frame of its round-received if it is known, to the
+// pinned set.
+func (s *InmemStore) pin(block *Block, frame *Frame) {
+	s.pinLock.Lock()
+	defer s.pinLock.Unlock()
+
+	s.pinnedBlocks[block.Index()] = block
+	if frame != nil {
+		s.pinnedFrames[frame.Round] = frame
+	}
+}
+
+// pinnedBlock returns a pinned Block.
+func (s *InmemStore) pinnedBlock(index int) (*Block, bool) {
+	s.pinLock.RLock()
+	defer s.pinLock.RUnlock()
+
+	block, ok := s.pinnedBlocks[index]
+	return block, ok
+}
+
+// UnpinBlock implements the Store interface.
+func (s *InmemStore) UnpinBlock(index int) error {
+	s.pinLock.Lock()
+	defer s.pinLock.Unlock()
+
+	block, ok := s.pinnedBlocks[index]
+	if !ok {
+		return cm.NewStoreErr("PinnedBlocks", cm.KeyNotFound, strconv.Itoa(index))
+	}
+
+	delete(s.pinnedBlocks, index)
+
+	// Another pinned Block can have the same round-received
+	for _, b := range s.pinnedBlocks {
+		if b.RoundReceived() == block.RoundReceived() {
+			return nil
+		}
+	}
+	delete(s.pinnedFrames, block.RoundReceived())
+
+	return nil
+}
+
+// PinnedBlocks implements the Store interface.
+func (s *InmemStore) PinnedBlocks() ([]int, error) {
+	s.pinLock.RLock()
+	defer s.pinLock.RUnlock()
+
+	res := make([]int, 0, len(s.pinnedBlocks))
+	for index := range s.pinnedBlocks {
+		res = append(res, index)
+	}
+	sort.Ints(res)
+	return res, nil
+}
+
+// AppWatermark implements the AppWatermarkStore interface.
+func (s *InmemStore) AppWatermark() (int, error) {
+	s.watermarkLock.Lock()
+	defer s.watermarkLock.Unlock()
+
+	return s.appWatermark, nil
+}
+
+// SetAppWatermark implements the AppWatermarkStore interface.
+func (s *InmemStore) SetAppWatermark(index int) error {
+	s.watermarkLock.Lock()
+	defer s.watermarkLock.Unlock()
+
+	if index < s.appWatermark {
+		return fmt.Errorf("App watermark %d is lower than %d", index, s.appWatermark)
+	}
+
+	s.appWatermark = index
+
 	return nil
 }
 
@@ -289,6 +486,7 @@ func (s *InmemStore) Reset(frame *Frame) error {
 	s.eventCache = cm.NewLRU(s.cacheSize, nil)
 	s.roundCache = cm.NewLRU(s.cacheSize, nil)
 	s.blockCache = cm.NewLRU(s.cacheSize, nil)
+	s.txCache = cm.NewLRU(s.cacheSize, nil)
 	s.frameCache = cm.NewLRU(s.cacheSize, nil)
 	s.participantEventsCache = NewParticipantEventsCache(s.cacheSize)
 	s.roots = make(map[string]*Root)
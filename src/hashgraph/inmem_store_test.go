@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"testing"
 
+	cm "github.com/mosaicnetworks/babble/src/common"
 	"github.com/mosaicnetworks/babble/src/crypto/keys"
 	"github.com/mosaicnetworks/babble/src/peers"
 )
@@ -268,4 +269,104 @@ func TestInmemBlocks(t *testing.T) {
 			t.Fatal("Validator2 block signatures differ")
 		}
 	})
+
+	t.Run("Check transaction index", func(t *testing.T) {
+		// storing a duplicate transaction in a later block must not move it
+		dup := NewBlock(index+1, roundReceived+1, frameHash, []*peers.Peer{}, transactions[:1], nil)
+		if err := store.SetBlock(dup); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, tx := range transactions {
+			loc, err := store.GetTx(TxHash(tx))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if loc.Block != index || loc.Index != i {
+				t.Fatalf("tx %d should be located at (%d, %d), not %+v", i, index, i, loc)
+			}
+		}
+
+		if _, err := store.GetTx(TxHash([]byte("unknown"))); !cm.IsStore(err, cm.KeyNotFound) {
+			t.Fatalf("unknown tx should return KeyNotFound, not %v", err)
+		}
+	})
+}
+
+func TestInmemMembershipChanges(t *testing.T) {
+	store := NewInmemStore(10)
+
+	peer := *peers.NewPeer("0X0400", "127.0.0.1:1340", "new")
+
+	changes := []MembershipChange{
+		{Block: 5, Index: 1, RoundReceived: 10, Round: 16, Type: PEER_REMOVE, Peer: peer},
+		{Block: 2, Index: 0, RoundReceived: 4, Round: 10, Type: PEER_ADD, Peer: peer},
+		{Block: 5, Index: 0, RoundReceived: 10, Round: 16, Type: PEER_ADD, Peer: peer},
+		{Block: 2, Index: 0, RoundReceived: 4, Round: 10, Type: PEER_ADD, Peer: peer},
+	}
+
+	for _, c := range changes {
+		if err := store.SetMembershipChange(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := store.GetMembershipChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []MembershipChange{changes[1], changes[2], changes[0]}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("Membership changes should be %v, not %v", expected, res)
+	}
+}
+
+func TestInmemPinnedBlocks(t *testing.T) {
+	store := NewInmemStore(2)
+
+	for i := 0; i < 5; i++ {
+		block := NewBlock(i, i+1, []byte("frame"), []*peers.Peer{}, [][]byte{[]byte(fmt.Sprintf("block%d", i))}, nil)
+		if err := store.SetBlock(block); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetFrame(&Frame{Round: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := store.PinBlock(0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Block 0 and its Frame are evicted from the caches, but pinned
+	if _, err := store.GetBlock(1); !cm.IsStore(err, cm.KeyNotFound) {
+		t.Fatalf("Block 1 should be evicted, not %v", err)
+	}
+	if _, err := store.GetBlock(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetFrame(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.PinBlock(1); !cm.IsStore(err, cm.KeyNotFound) {
+		t.Fatalf("Pinning an evicted block should fail with KeyNotFound, not %v", err)
+	}
+
+	if pinned, _ := store.PinnedBlocks(); !reflect.DeepEqual(pinned, []int{0}) {
+		t.Fatalf("Pinned blocks should be [0], not %v", pinned)
+	}
+
+	if err := store.UnpinBlock(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetBlock(0); !cm.IsStore(err, cm.KeyNotFound) {
+		t.Fatalf("Block 0 should be evicted once unpinned, not %v", err)
+	}
+	if err := store.UnpinBlock(0); !cm.IsStore(err, cm.KeyNotFound) {
+		t.Fatalf("Unpinning a block twice should fail with KeyNotFound, not %v", err)
+	}
 }
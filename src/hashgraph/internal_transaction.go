@@ -22,6 +22,13 @@ const (
 	PEER_ADD TransactionType = iota
 	// PEER_REMOVE is used to remove a peer.
 	PEER_REMOVE
+	// PROTOCOL_UPGRADE is used by a validator to signal that it is ready for a
+	// new version of the protocol.
+	PROTOCOL_UPGRADE
+	// PEER_RENAME is used by a validator to change its moniker.
+	PEER_RENAME
+	// PEER_ADDRESS is used by a validator to announce a new network address.
+	PEER_ADDRESS
 )
 
 // String returns the string representation of a TransactionType.
@@ -31,15 +38,25 @@ func (t TransactionType) String() string {
 		return "PEER_ADD"
 	case PEER_REMOVE:
 		return "PEER_REMOVE"
+	case PROTOCOL_UPGRADE:
+		return "PROTOCOL_UPGRADE"
+	case PEER_RENAME:
+		return "PEER_RENAME"
+	case PEER_ADDRESS:
+		return "PEER_ADDRESS"
 	default:
 		return "Unknown TransactionType"
 	}
 }
 
-// InternalTransactionBody contains the payload of an InternalTransaction.
+// InternalTransactionBody contains the payload of an InternalTransaction. In
+// a PROTOCOL_UPGRADE, Peer is the signalling validator and ProtocolVersion the
+// version that it is ready for. ProtocolVersion is omitted when empty, so that
+// the hashes of the other InternalTransactions do not change.
 type InternalTransactionBody struct {
-	Type TransactionType
-	Peer peers.Peer
+	Type            TransactionType
+	Peer            peers.Peer
+	ProtocolVersion int `json:",omitempty"`
 }
 
 // Marshal returns the JSON encoding of an InternalTransaction.
@@ -92,6 +109,31 @@ func NewInternalTransactionLeave(peer peers.Peer) InternalTransaction {
 	return NewInternalTransaction(PEER_REMOVE, peer)
 }
 
+// NewInternalTransactionUpgrade creates a new InternalTransaction to signal
+// that a validator is ready for a version of the protocol.
+func NewInternalTransactionUpgrade(peer peers.Peer, protocolVersion int) InternalTransaction {
+	itx := NewInternalTransaction(PROTOCOL_UPGRADE, peer)
+	itx.Body.ProtocolVersion = protocolVersion
+	return itx
+}
+
+// NewInternalTransactionRename creates a new InternalTransaction to change the
+// moniker of a peer. The Peer of the body carries the new moniker.
+func NewInternalTransactionRename(peer peers.Peer, moniker string) InternalTransaction {
+	peer.Moniker = moniker
+	return NewInternalTransaction(PEER_RENAME, peer)
+}
+
+// NewInternalTransactionAddress creates a new InternalTransaction to announce
+// the new network address of a peer. The Peer of the body carries the new
+// address in its NetAddr, and the next AddressSequence, which binds the signed
+// announcement to this change only.
+func NewInternalTransactionAddress(peer peers.Peer, netAddr string) InternalTransaction {
+	peer.NetAddr = netAddr
+	peer.AddressSequence++
+	return NewInternalTransaction(PEER_ADDRESS, peer)
+}
+
 // Marshal returns the JSON encoding of an InternalTransaction.
 func (t *InternalTransaction) Marshal() ([]byte, error) {
 	var b bytes.Buffer
@@ -0,0 +1,37 @@
+package hashgraph
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// MembershipChange records an accepted PEER_ADD, PEER_REMOVE, PEER_RENAME or
+// PEER_ADDRESS InternalTransaction. Block and Index locate its receipt in the blockchain,
+// and Round is the round from which the new validator-set is effective.
+type MembershipChange struct {
+	Block         int
+	Index         int
+	RoundReceived int
+	Round         int
+	Type          TransactionType
+	Peer          peers.Peer
+}
+
+// Marshal produces the JSON encoding of a MembershipChange.
+func (c *MembershipChange) Marshal() ([]byte, error) {
+	bf := bytes.NewBuffer([]byte{})
+	enc := json.NewEncoder(bf)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	return bf.Bytes(), nil
+}
+
+// Unmarshal parses a JSON encoded MembershipChange.
+func (c *MembershipChange) Unmarshal(data []byte) error {
+	bf := bytes.NewBuffer(data)
+	dec := json.NewDecoder(bf)
+	return dec.Decode(c)
+}
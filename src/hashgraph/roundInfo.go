@@ -4,7 +4,6 @@ import (
 	"bytes"
 
 	"github.com/mosaicnetworks/babble/src/common"
-	"github.com/mosaicnetworks/babble/src/peers"
 	"github.com/ugorji/go/codec"
 )
 
@@ -25,8 +24,11 @@ type RoundInfo struct {
 	CreatedEvents map[string]roundEvent
 	// ReceivedEvents collects the events that were "received" in this round.
 	ReceivedEvents []string
-	queued         bool
-	decided        bool
+	// Timeline records when the round went through the stages of consensus.
+	Timeline RoundTimeline
+
+	queued  bool
+	decided bool
 }
 
 // NewRoundInfo creates a new RoundInfo.
@@ -75,7 +77,9 @@ func (r *RoundInfo) SetFame(x string, f bool) {
 // witness that is not yet known when a super-majority of witnesses are already
 // decided, has no chance of ever being famous. Once a Round is decided it stays
 // decided, even if new witnesses are added after it was first decided.
-func (r *RoundInfo) WitnessesDecided(peerSet *peers.PeerSet) bool {
+// superMajority is the number of witnesses that forms a super-majority in the
+// PeerSet of the Round.
+func (r *RoundInfo) WitnessesDecided(superMajority int) bool {
 	//if the round was already decided, it stays decided no matter what.
 	if r.decided {
 		return true
@@ -90,7 +94,7 @@ func (r *RoundInfo) WitnessesDecided(peerSet *peers.PeerSet) bool {
 		}
 	}
 
-	r.decided = c >= peerSet.SuperMajority()
+	r.decided = c >= superMajority
 
 	return r.decided
 }
@@ -11,6 +11,9 @@ type Store interface {
 	GetPeerSet(round int) (*peers.PeerSet, error)
 	// SetPeerSet sets the peer-set effective at a given round.
 	SetPeerSet(round int, peers *peers.PeerSet) error
+	// ReplacePeerSet replaces the peer-set set at a given round, which is not
+	// reached yet.
+	ReplacePeerSet(round int, peers *peers.PeerSet) error
 	// GetAllPeerSets returns the entire history of peer-sets.
 	GetAllPeerSets() (map[int][]*peers.Peer, error)
 	// FirstRound returns the index of the first round to which a participant
@@ -58,6 +61,23 @@ type Store interface {
 	GetBlock(int) (*Block, error)
 	// SetBlock store a block.
 	SetBlock(*Block) error
+	// GetTx returns the location of a committed transaction by hash. It is
+	// indexed when the block containing it is stored.
+	GetTx(hash string) (TxLocation, error)
+	// SetMembershipChange records an accepted PEER_ADD, PEER_REMOVE,
+	// PEER_RENAME or PEER_ADDRESS.
+	// Recording the same change twice has no effect.
+	SetMembershipChange(MembershipChange) error
+	// GetMembershipChanges returns the recorded history of membership changes,
+	// in the order of the blockchain.
+	GetMembershipChanges() ([]MembershipChange, error)
+	// PinBlock protects a block, and the frame of its round-received, from
+	// being evicted from the store, like an audit checkpoint.
+	PinBlock(index int) error
+	// UnpinBlock lifts the protection of a pinned block.
+	UnpinBlock(index int) error
+	// PinnedBlocks returns the sorted indexes of the pinned blocks.
+	PinnedBlocks() ([]int, error)
 	// LastBlockIndex returns the last block index.
 	LastBlockIndex() int
 	// GetFrame retrieves the frame associated to a round received.
@@ -71,3 +91,24 @@ type Store interface {
 	// StorePath returns the filepath of the underlying database.
 	StorePath() string
 }
+
+// HealthChecker is implemented by Stores that can verify that they are able to
+// persist data. Stores that do not implement it are assumed to be writable.
+type HealthChecker interface {
+	// CheckWritable returns an error if the store cannot be written to.
+	CheckWritable() error
+}
+
+// AppWatermarkStore is implemented by Stores that record the App watermark: the
+// index of the last Block that the App acknowledged, once it persisted the
+// Block's effects. A node bootstrapping from such a Store does not deliver the
+// Blocks up to the watermark to the App again. Stores that do not implement it
+// have no watermark.
+type AppWatermarkStore interface {
+	// AppWatermark returns the index of the last Block acknowledged by the App,
+	// or -1.
+	AppWatermark() (int, error)
+	// SetAppWatermark advances the App watermark to a Block index. It fails if
+	// the index is lower than the current watermark.
+	SetAppWatermark(index int) error
+}
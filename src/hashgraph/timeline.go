@@ -0,0 +1,57 @@
+package hashgraph
+
+import (
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+)
+
+// RoundTimeline records when a round went through the stages of consensus, as
+// seen by the local node, in Unix nanoseconds. The stages that are not reached
+// yet are 0.
+type RoundTimeline struct {
+	// Created is when the first Event of the round was inserted.
+	Created int64
+	// Decided is when the fame of all the round's witnesses was decided.
+	Decided int64
+	// Received is when the round was processed as a round-received, and the
+	// Events received in it reached consensus.
+	Received int64
+	// Committed is when the Block of the round was committed to the App.
+	// Rounds without transactions do not produce a Block, and are never
+	// committed.
+	Committed int64
+}
+
+// newRoundInfo creates the RoundInfo of a round which is not in the Store yet.
+// When the hashgraph is replayed from a database, the timeline recorded before
+// is restored, so that bootstrapping a node does not forget when past rounds
+// went through consensus.
+func (h *Hashgraph) newRoundInfo(index int) *RoundInfo {
+	roundInfo := NewRoundInfo()
+
+	if h.replayStore != nil {
+		if persisted, err := h.replayStore.dbGetRound(index); err == nil {
+			roundInfo.Timeline = persisted.Timeline
+		}
+	}
+
+	return roundInfo
+}
+
+// recordStage records the current time in a stage of a round's timeline,
+// unless it is already recorded, and observes the latency of the stage since
+// the creation of the round. Nothing is recorded during a replay, where the
+// timelines are restored from the database.
+func (h *Hashgraph) recordStage(round *RoundInfo, stage *int64, label string) {
+	if h.replayStore != nil || *stage != 0 {
+		return
+	}
+
+	now := time.Now()
+	*stage = now.UnixNano()
+
+	if label != "" && round.Timeline.Created != 0 {
+		metrics.RoundLatency.WithLabelValues(label).Observe(now.Sub(time.Unix(0, round.Timeline.Created)).Seconds())
+	}
+}
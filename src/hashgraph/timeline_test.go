@@ -0,0 +1,65 @@
+package hashgraph
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRoundTimeline(t *testing.T) {
+	h, _ := initConsensusHashgraph(true, t)
+	defer os.RemoveAll(badgerDir)
+
+	h.DivideRounds()
+	h.DecideFame()
+	h.DecideRoundReceived()
+	if err := h.ProcessDecidedRounds(); err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := h.Store.GetBlock(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	round, err := h.Store.GetRound(block.RoundReceived())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl := round.Timeline
+	if tl.Created == 0 || tl.Decided < tl.Created || tl.Received < tl.Decided || tl.Committed < tl.Received {
+		t.Fatalf("Round %d should go through all the stages in order: %+v", block.RoundReceived(), tl)
+	}
+
+	undecided, err := h.Store.GetRound(h.Store.LastRound())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undecided.Timeline.Created == 0 || undecided.Timeline.Decided != 0 {
+		t.Fatalf("The last round should be created and undecided: %+v", undecided.Timeline)
+	}
+
+	h.Store.Close()
+
+	// Bootstrapping restores the timelines from the database
+	recycledStore, err := NewBadgerStore(cacheSize, badgerDir, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recycledStore.Close()
+
+	nh := NewHashgraph(recycledStore, DummyInternalCommitCallback, logrus.New().WithField("id", "bootstrapped"))
+	if err := nh.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+
+	bootstrapped, err := nh.Store.GetRound(block.RoundReceived())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bootstrapped.Timeline != tl {
+		t.Fatalf("The bootstrapped timeline should be %+v, not %+v", tl, bootstrapped.Timeline)
+	}
+}
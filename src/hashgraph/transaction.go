@@ -0,0 +1,39 @@
+package hashgraph
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto"
+)
+
+// TxHash returns the hex encoded SHA256 hash of a transaction, which is used
+// to look it up in the Store.
+func TxHash(tx []byte) string {
+	return common.EncodeToString(crypto.SHA256(tx))
+}
+
+// TxLocation is the position of a transaction in the blockchain. When the same
+// transaction is included more than once, the Store keeps the first location.
+type TxLocation struct {
+	Block int
+	Index int
+}
+
+// Marshal produces the JSON encoding of a TxLocation.
+func (l *TxLocation) Marshal() ([]byte, error) {
+	bf := bytes.NewBuffer([]byte{})
+	enc := json.NewEncoder(bf)
+	if err := enc.Encode(l); err != nil {
+		return nil, err
+	}
+	return bf.Bytes(), nil
+}
+
+// Unmarshal parses a JSON encoded TxLocation.
+func (l *TxLocation) Unmarshal(data []byte) error {
+	bf := bytes.NewBuffer(data)
+	dec := json.NewDecoder(bf)
+	return dec.Decode(l)
+}
@@ -0,0 +1,10 @@
+// Package logging manages the loggers used by the different modules of Babble.
+//
+// Every module, like the node, the transport, or the HTTP service, obtains its
+// logger from a Registry. The loggers share the same output and format (text
+// or JSON), but each module has its own level, which defaults to the global
+// level unless it is overridden. Levels can be changed at runtime, for example
+// through the /admin/loglevel endpoint of the HTTP service, without restarting
+// the node. When the logs are written to a file, the file can also be rotated
+// at runtime, through the /admin/logrotate endpoint.
+package logging
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// rotationTimeFormat is the suffix appended to the name of rotated log files.
+const rotationTimeFormat = "20060102-150405"
+
+// FileWriter writes logs to a file, which can be rotated without restarting
+// the node.
+type FileWriter struct {
+	sync.Mutex
+
+	path string
+	file *os.File
+}
+
+// NewFileWriter opens, or creates, a log file in append mode.
+func NewFileWriter(path string) (*FileWriter, error) {
+	file, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileWriter{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// Write implements the io.Writer interface.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	return w.file.Write(p)
+}
+
+// Path returns the path of the current log file.
+func (w *FileWriter) Path() string {
+	return w.path
+}
+
+// Rotate renames the current log file with a timestamp suffix, and continues
+// writing to a new file at the original path. It returns the path of the
+// rotated file.
+func (w *FileWriter) Rotate() (string, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	rotated := fmt.Sprintf("%s.%s", w.path, time.Now().Format(rotationTimeFormat))
+
+	if err := os.Rename(w.path, rotated); err != nil {
+		return "", err
+	}
+
+	file, err := openLogFile(w.path)
+	if err != nil {
+		// Keep writing to the rotated file rather than losing logs
+		return "", err
+	}
+
+	w.file.Close()
+	w.file = file
+
+	return rotated, nil
+}
+
+// Close closes the log file.
+func (w *FileWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.file.Close()
+}
+
+func openLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
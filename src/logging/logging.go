@@ -0,0 +1,201 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	prefixed "github.com/x-cray/logrus-prefixed-formatter"
+)
+
+// Supported log formats.
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// NewFormatter returns the logrus Formatter corresponding to a log format.
+func NewFormatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "", TextFormat:
+		return new(prefixed.TextFormatter), nil
+	case JSONFormat:
+		return new(logrus.JSONFormatter), nil
+	default:
+		return nil, fmt.Errorf("Unknown log format %q", format)
+	}
+}
+
+// ParseLevel parses a string into a logrus level.
+func ParseLevel(level string) (logrus.Level, error) {
+	return logrus.ParseLevel(level)
+}
+
+// ParseModuleLevels parses a comma-separated list of module=level pairs, like
+// "node=debug,transport=warn".
+func ParseModuleLevels(s string) (map[string]logrus.Level, error) {
+	res := make(map[string]logrus.Level)
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("Invalid module level %q, expected module=level", item)
+		}
+
+		level, err := ParseLevel(kv[1])
+		if err != nil {
+			return nil, err
+		}
+
+		res[kv[0]] = level
+	}
+
+	return res, nil
+}
+
+// syncWriter serializes the writes of the module loggers to their common
+// output.
+type syncWriter struct {
+	sync.Mutex
+	out io.Writer
+}
+
+// Write implements the io.Writer interface.
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	return w.out.Write(p)
+}
+
+// Registry creates and keeps track of the module loggers. Module loggers are
+// created from a base logger whose output, formatter, and hooks they share.
+type Registry struct {
+	sync.Mutex
+
+	base      *logrus.Logger
+	out       io.Writer
+	level     logrus.Level
+	overrides map[string]logrus.Level
+	modules   map[string]*logrus.Logger
+}
+
+// NewRegistry creates a Registry from a base logger. The base logger's level
+// is the default level of the modules, and overrides sets the level of
+// specific modules.
+func NewRegistry(base *logrus.Logger, overrides map[string]logrus.Level) *Registry {
+	r := &Registry{
+		base:      base,
+		out:       &syncWriter{out: base.Out},
+		level:     base.GetLevel(),
+		overrides: make(map[string]logrus.Level),
+		modules:   make(map[string]*logrus.Logger),
+	}
+
+	for m, l := range overrides {
+		r.overrides[m] = l
+	}
+
+	return r
+}
+
+// Logger returns the logger of a module, creating it if necessary.
+func (r *Registry) Logger(module string) *logrus.Logger {
+	r.Lock()
+	defer r.Unlock()
+
+	if l, ok := r.modules[module]; ok {
+		return l
+	}
+
+	level, ok := r.overrides[module]
+	if !ok {
+		level = r.level
+	}
+
+	l := &logrus.Logger{
+		Out:          r.out,
+		Formatter:    r.base.Formatter,
+		Hooks:        r.base.Hooks,
+		Level:        level,
+		ExitFunc:     r.base.ExitFunc,
+		ReportCaller: r.base.ReportCaller,
+	}
+
+	r.modules[module] = l
+
+	return l
+}
+
+// SetLevel changes the level of a module. If module is empty, it changes the
+// default level, and the level of all the modules that do not have a specific
+// level.
+func (r *Registry) SetLevel(module string, level logrus.Level) {
+	r.Lock()
+	defer r.Unlock()
+
+	if module == "" {
+		r.level = level
+		r.base.SetLevel(level)
+		for m, l := range r.modules {
+			if _, ok := r.overrides[m]; !ok {
+				l.SetLevel(level)
+			}
+		}
+		return
+	}
+
+	r.overrides[module] = level
+	if l, ok := r.modules[module]; ok {
+		l.SetLevel(level)
+	}
+}
+
+// ResetLevel removes the specific level of a module, which reverts to the
+// default level.
+func (r *Registry) ResetLevel(module string) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.overrides, module)
+	if l, ok := r.modules[module]; ok {
+		l.SetLevel(r.level)
+	}
+}
+
+// Levels returns the default level, and the current level of every module
+// that has been created or configured.
+func (r *Registry) Levels() (logrus.Level, map[string]logrus.Level) {
+	r.Lock()
+	defer r.Unlock()
+
+	res := make(map[string]logrus.Level)
+	for m, l := range r.modules {
+		res[m] = l.GetLevel()
+	}
+	for m, l := range r.overrides {
+		res[m] = l
+	}
+
+	return r.level, res
+}
+
+// Rotate rotates the log file shared by the loggers. It returns the path of the
+// rotated file, or an error if the logs are not written to a file.
+func (r *Registry) Rotate() (string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	fw, ok := r.base.Out.(*FileWriter)
+	if !ok {
+		return "", fmt.Errorf("Logs are not written to a file")
+	}
+
+	return fw.Rotate()
+}
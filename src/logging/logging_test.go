@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseModuleLevels(t *testing.T) {
+	levels, err := ParseModuleLevels(" node=debug, transport=warn ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]logrus.Level{
+		"node":      logrus.DebugLevel,
+		"transport": logrus.WarnLevel,
+	}
+
+	if !reflect.DeepEqual(levels, expected) {
+		t.Fatalf("Levels should be %v, not %v", expected, levels)
+	}
+
+	for _, s := range []string{"node", "=debug", "node=loud"} {
+		if _, err := ParseModuleLevels(s); err == nil {
+			t.Fatalf("ParseModuleLevels(%q) should return an error", s)
+		}
+	}
+}
+
+func TestRegistryLevels(t *testing.T) {
+	base := logrus.New()
+	base.Level = logrus.InfoLevel
+
+	r := NewRegistry(base, map[string]logrus.Level{"node": logrus.DebugLevel})
+
+	node := r.Logger("node")
+	service := r.Logger("service")
+
+	if node.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("node level should be debug, not %s", node.GetLevel())
+	}
+
+	if service.GetLevel() != logrus.InfoLevel {
+		t.Fatalf("service level should be info, not %s", service.GetLevel())
+	}
+
+	if r.Logger("node") != node {
+		t.Fatalf("Logger should return the same logger for the same module")
+	}
+
+	// Changing the default level does not affect modules with a specific level
+	r.SetLevel("", logrus.ErrorLevel)
+
+	if service.GetLevel() != logrus.ErrorLevel {
+		t.Fatalf("service level should be error, not %s", service.GetLevel())
+	}
+
+	if node.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("node level should still be debug, not %s", node.GetLevel())
+	}
+
+	r.SetLevel("service", logrus.TraceLevel)
+
+	if service.GetLevel() != logrus.TraceLevel {
+		t.Fatalf("service level should be trace, not %s", service.GetLevel())
+	}
+
+	r.ResetLevel("node")
+
+	if node.GetLevel() != logrus.ErrorLevel {
+		t.Fatalf("node level should be reset to error, not %s", node.GetLevel())
+	}
+
+	level, modules := r.Levels()
+
+	if level != logrus.ErrorLevel {
+		t.Fatalf("Default level should be error, not %s", level)
+	}
+
+	expected := map[string]logrus.Level{
+		"node":    logrus.ErrorLevel,
+		"service": logrus.TraceLevel,
+	}
+
+	if !reflect.DeepEqual(modules, expected) {
+		t.Fatalf("Module levels should be %v, not %v", expected, modules)
+	}
+}
+
+func TestRegistryJSONOutput(t *testing.T) {
+	var buf bytes.Buffer
+
+	formatter, err := NewFormatter(JSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := logrus.New()
+	base.Out = &buf
+	base.Formatter = formatter
+
+	r := NewRegistry(base, nil)
+
+	r.Logger("node").WithField("component", "node").Info("hello")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Output should be JSON: %v", err)
+	}
+
+	if entry["msg"] != "hello" || entry["component"] != "node" {
+		t.Fatalf("Unexpected log entry %v", entry)
+	}
+
+	if _, err := NewFormatter("xml"); err == nil {
+		t.Fatalf("NewFormatter should not accept unknown formats")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "babble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "babble.log")
+
+	fw, err := NewFileWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+
+	base := logrus.New()
+	base.Out = fw
+
+	r := NewRegistry(base, nil)
+
+	r.Logger("node").Info("before")
+
+	rotated, err := r.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Logger("node").Info("after")
+
+	old, err := ioutil.ReadFile(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cur, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(old, []byte("before")) || bytes.Contains(old, []byte("after")) {
+		t.Fatalf("unexpected rotated file: %s", old)
+	}
+
+	if !bytes.Contains(cur, []byte("after")) || bytes.Contains(cur, []byte("before")) {
+		t.Fatalf("unexpected log file: %s", cur)
+	}
+
+	if _, err := NewRegistry(logrus.New(), nil).Rotate(); err == nil {
+		t.Fatal("Rotate should fail when logs are not written to a file")
+	}
+}
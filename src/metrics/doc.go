@@ -0,0 +1,12 @@
+// Package metrics defines the Prometheus collectors that expose Babble's
+// internal operation.
+//
+// The collectors are registered with the default Prometheus registry when the
+// package is loaded, and are updated by the hashgraph and node packages. The
+// service package exposes them in the Prometheus text format on the /metrics
+// endpoint.
+//
+// Collectors are process-wide. When multiple Babble nodes run in the same
+// process, counters and histograms aggregate the activity of all the nodes,
+// whilst gauges reflect the state of the node that updated them last.
+package metrics
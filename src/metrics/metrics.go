@@ -0,0 +1,407 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "babble"
+
+// Labels used to identify the type of RPC in the RPC collectors.
+const (
+	RPCSync        = "sync"
+	RPCEagerSync   = "eager_sync"
+	RPCFastForward = "fast_forward"
+	RPCJoin        = "join"
+	RPCPing        = "ping"
+	RPCInfo        = "info"
+	RPCKeepalive   = "keepalive"
+)
+
+// Labels used to identify the consensus phases in ConsensusPhaseDuration.
+const (
+	PhaseDivideRounds         = "divide_rounds"
+	PhaseDecideFame           = "decide_fame"
+	PhaseDecideRoundReceived  = "decide_round_received"
+	PhaseProcessDecidedRounds = "process_decided_rounds"
+)
+
+// Labels used to identify the stages of a round in RoundLatency.
+const (
+	StageDecided   = "decided"
+	StageReceived  = "received"
+	StageCommitted = "committed"
+)
+
+// latencyBuckets are the buckets of the histograms that measure operations
+// that usually take less than a millisecond, from 100µs to about 26s.
+var latencyBuckets = prometheus.ExponentialBuckets(0.0001, 4, 10)
+
+/*******************************************************************************
+Hashgraph
+*******************************************************************************/
+
+var (
+	// EventsInserted counts the Events inserted in the hashgraph.
+	EventsInserted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "hashgraph",
+		Name:      "events_inserted_total",
+		Help:      "Number of Events inserted in the hashgraph.",
+	})
+
+	// SignatureCacheHits counts the Event signatures which were not verified
+	// again because they were found in the cache of verified signatures.
+	SignatureCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "hashgraph",
+		Name:      "signature_cache_hits_total",
+		Help:      "Number of Event signatures found in the cache of verified signatures.",
+	})
+
+	// RoundsDecided counts the rounds whose witnesses have been decided and
+	// processed.
+	RoundsDecided = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "hashgraph",
+		Name:      "rounds_decided_total",
+		Help:      "Number of decided rounds processed by the hashgraph.",
+	})
+
+	// ConsensusPhaseDuration measures the time spent in each phase of the
+	// consensus methods, which run every time an Event is inserted.
+	ConsensusPhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "hashgraph",
+		Name:      "consensus_phase_duration_seconds",
+		Help:      "Time spent in each phase of the consensus methods.",
+		Buckets:   latencyBuckets,
+	}, []string{"phase"})
+
+	// RoundLatency measures the time from the first Event of a round to the
+	// decision of its witnesses' fame, to its processing as a round-received,
+	// and to the commit of its Block.
+	RoundLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "hashgraph",
+		Name:      "round_latency_seconds",
+		Help:      "Time from the creation of a round to each stage of consensus.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"stage"})
+)
+
+/*******************************************************************************
+Node
+*******************************************************************************/
+
+var (
+	// BlocksCommitted counts the Blocks successfully committed to the App.
+	BlocksCommitted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "blocks_committed_total",
+		Help:      "Number of Blocks committed to the application.",
+	})
+
+	// CommitLatency measures the time taken by the App to process Blocks.
+	CommitLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "commit_duration_seconds",
+		Help:      "Time taken by the application to process a Block.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// GossipLoopDuration measures the iterations of the babbling loop, from the
+	// heartbeat to the selection of the next peer, including monologues but
+	// not gossip, which runs in the background.
+	GossipLoopDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "gossip_loop_iteration_seconds",
+		Help:      "Duration of the iterations of the babbling loop.",
+		Buckets:   latencyBuckets,
+	})
+
+	// GossipDuration measures complete pull-push gossip operations with a
+	// peer.
+	GossipDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "gossip_duration_seconds",
+		Help:      "Duration of pull-push gossip operations.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// TimerDrift measures the delay between the expiry of the heartbeat timer
+	// and the moment the babbling loop picks up the tick. It grows when the
+	// loop is too busy to keep up with the heartbeat.
+	TimerDrift = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "timer_drift_seconds",
+		Help:      "Delay between the expiry of the heartbeat timer and its processing.",
+		Buckets:   latencyBuckets,
+	})
+
+	// SyncLatency measures the duration of outgoing RPCs, by RPC type.
+	SyncLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "rpc_duration_seconds",
+		Help:      "Duration of outgoing RPCs.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"rpc"})
+
+	// RPCFailures counts outgoing RPCs that returned an error, by RPC type.
+	RPCFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "rpc_failures_total",
+		Help:      "Number of outgoing RPCs that failed.",
+	}, []string{"rpc"})
+
+	// EventsSkipped counts the events left out of syncs because they were
+	// recently sent to the same peer.
+	EventsSkipped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "events_skipped_total",
+		Help:      "Number of events not resent to a peer within the dedup window.",
+	})
+
+	// EventsRepaired counts the events exchanged by the anti-entropy task.
+	EventsRepaired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "events_repaired_total",
+		Help:      "Number of events sent or received by anti-entropy repairs.",
+	})
+
+	// AuditFailures counts the Blocks that differ from those re-derived by the
+	// audit from the consensus Events in the store.
+	AuditFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "audit_failures_total",
+		Help:      "Number of Blocks that failed the integrity audit.",
+	})
+
+	// HistoryRPCsRefused counts the RPCs serving history which were refused
+	// because the history workers and their queue were full.
+	HistoryRPCsRefused = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "history_rpcs_refused_total",
+		Help:      "Number of RPCs serving history refused because the history workers were busy.",
+	})
+
+	// JoinRequestsDeferred counts the JoinRequests which were not admitted
+	// yet, and whose requesters were told to retry later.
+	JoinRequestsDeferred = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "join_requests_deferred_total",
+		Help:      "Number of JoinRequests deferred because too many nodes were joining.",
+	})
+
+	// TransactionsExpired counts the transactions evicted from the
+	// transaction-pool, or from the offline log, because their TTL expired.
+	TransactionsExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "transactions_expired_total",
+		Help:      "Number of transactions evicted because their TTL expired before they were included in an Event.",
+	})
+
+	// PeerTraffic counts the bytes exchanged with each peer, by peer ID and
+	// direction: in or out.
+	PeerTraffic = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "peer_traffic_bytes_total",
+		Help:      "Number of bytes sent to and received from each peer.",
+	}, []string{"peer", "direction"})
+
+	// PeerQuotaViolations counts the peers which exceeded the hourly traffic
+	// quota, by action taken against them: throttle or ban.
+	PeerQuotaViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "peer_quota_violations_total",
+		Help:      "Number of times a peer exceeded the hourly traffic quota.",
+	}, []string{"action"})
+
+	// FastForwardCacheHits counts the FastForwardResponses served from the
+	// cache of the anchor block.
+	FastForwardCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "fast_forward_cache_hits_total",
+		Help:      "Number of FastForwardResponses served from the cache of the anchor block.",
+	})
+
+	// Suspensions counts the suspensions of the node, by reason.
+	Suspensions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "suspensions_total",
+		Help:      "Number of times the node entered the Suspended state.",
+	}, []string{"reason"})
+
+	// ConsensusStalls counts the stalls of consensus detected by the watchdog.
+	ConsensusStalls = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "consensus_stalls_total",
+		Help:      "Number of periods without new consensus rounds while transactions were pending.",
+	})
+
+	// SignaturesWithheld counts the Blocks that the node did not sign, in
+	// commit-barrier mode, because the App did not apply them or a previous
+	// Block.
+	SignaturesWithheld = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "block_signatures_withheld_total",
+		Help:      "Number of Blocks not signed because the App did not apply them.",
+	})
+
+	// TransactionPool is the number of transactions waiting to be included in
+	// an Event.
+	TransactionPool = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "transaction_pool",
+		Help:      "Number of transactions waiting to be included in an Event.",
+	})
+
+	// OfflineTransactions is the number of transactions held in the offline
+	// log, in offline-mode.
+	OfflineTransactions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "offline_transactions",
+		Help:      "Number of transactions held in the offline log until the node is back in contact with the quorum.",
+	})
+
+	// InternalTransactionPool is the number of internal transactions waiting to
+	// be included in an Event.
+	InternalTransactionPool = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "internal_transaction_pool",
+		Help:      "Number of internal transactions waiting to be included in an Event.",
+	})
+
+	// UndeterminedEvents is the number of Events whose consensus order is not
+	// yet decided.
+	UndeterminedEvents = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "undetermined_events",
+		Help:      "Number of Events whose consensus order is not yet decided.",
+	})
+
+	// LastConsensusRound is the index of the last consensus round.
+	LastConsensusRound = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "last_consensus_round",
+		Help:      "Index of the last consensus round.",
+	})
+)
+
+/*******************************************************************************
+Store
+*******************************************************************************/
+
+var (
+	// StoreConsensusEvents is the number of consensus Events in the Store.
+	StoreConsensusEvents = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "store",
+		Name:      "consensus_events",
+		Help:      "Number of consensus Events in the Store.",
+	})
+
+	// StoreBlocks is the number of Blocks in the Store.
+	StoreBlocks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "store",
+		Name:      "blocks",
+		Help:      "Number of Blocks in the Store.",
+	})
+)
+
+/*******************************************************************************
+Service
+*******************************************************************************/
+
+var (
+	// ServiceRateLimited counts the requests to the HTTP service rejected by
+	// the rate limits, by limit.
+	ServiceRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "service",
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of requests rejected by the rate limits of the HTTP service.",
+	}, []string{"limit"})
+
+	// TxUnauthorized counts the submitted transactions rejected by the
+	// TxAuthorizer, by source: service or proxy.
+	TxUnauthorized = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "service",
+		Name:      "unauthorized_transactions_total",
+		Help:      "Number of submitted transactions rejected by the transaction authorizer.",
+	}, []string{"source"})
+
+	// TxDuplicates counts the transactions resubmitted with the idempotency
+	// key of a previous transaction, and not submitted again, by source:
+	// service or proxy.
+	TxDuplicates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "service",
+		Name:      "duplicate_transactions_total",
+		Help:      "Number of transactions resubmitted with the idempotency key of a previous transaction.",
+	}, []string{"source"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		EventsInserted,
+		SignatureCacheHits,
+		RoundsDecided,
+		ConsensusPhaseDuration,
+		RoundLatency,
+		BlocksCommitted,
+		CommitLatency,
+		GossipLoopDuration,
+		GossipDuration,
+		TimerDrift,
+		SyncLatency,
+		RPCFailures,
+		EventsSkipped,
+		EventsRepaired,
+		AuditFailures,
+		Suspensions,
+		HistoryRPCsRefused,
+		JoinRequestsDeferred,
+		TransactionsExpired,
+		PeerTraffic,
+		PeerQuotaViolations,
+		FastForwardCacheHits,
+		ConsensusStalls,
+		SignaturesWithheld,
+		TransactionPool,
+		OfflineTransactions,
+		InternalTransactionPool,
+		UndeterminedEvents,
+		LastConsensusRound,
+		StoreConsensusEvents,
+		StoreBlocks,
+		ServiceRateLimited,
+		TxUnauthorized,
+		TxDuplicates,
+	)
+}
@@ -22,3 +22,40 @@ type ExceptionHandler interface {
 type StateChangeHandler interface {
 	OnStateChanged(state int32)
 }
+
+// StatusHandler wraps callbacks that report the status of the node, so that
+// mobile UIs can display it. It is optional, and is set with
+// Node.SetStatusHandler.
+type StatusHandler interface {
+	// OnPeersChanged is called when a new validator-set is recorded, with the
+	// list of validators serialized with JSON.
+	OnPeersChanged(peers string)
+
+	// OnStateTransition is called when the node changes state, with the names
+	// of the previous and the new states (Babbling, CatchingUp, Joining,
+	// Leaving, Suspended, or Shutdown).
+	OnStateTransition(from string, to string)
+
+	// OnSyncProgress is called when the percentage of the events reported by
+	// other nodes which are in the node's hashgraph changes. It is 100 when the
+	// node has caught up.
+	OnSyncProgress(percent int32)
+
+	// OnError is called when the node fails to commit a block, to
+	// fast-forward, or to join, when it suspends itself, and when it exceeds
+	// its data budget.
+	OnError(err string)
+}
+
+// StorageHandler wraps callbacks to save the checkpoints of an in-memory node
+// in the storage of the app, so that the node can restart without a Badger
+// database. It is optional, and is set with Node.SetStorageHandler.
+type StorageHandler interface {
+	// SaveCheckpoint is called periodically with the last checkpoint of the
+	// node, serialized with JSON. It replaces the previous checkpoint.
+	SaveCheckpoint(checkpoint []byte)
+
+	// LoadCheckpoint returns the last checkpoint saved, or nil if there is
+	// none.
+	LoadCheckpoint() []byte
+}
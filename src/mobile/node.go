@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/mosaicnetworks/babble/src/babble"
 	"github.com/mosaicnetworks/babble/src/config"
 	"github.com/mosaicnetworks/babble/src/node"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
 	"github.com/mosaicnetworks/babble/src/proxy"
 	"github.com/mosaicnetworks/babble/src/proxy/inmem"
 	"github.com/sirupsen/logrus"
@@ -23,8 +26,48 @@ type Node struct {
 	node   *node.Node
 	proxy  proxy.AppProxy
 	logger *logrus.Entry
+
+	// config and exceptionHandler are kept to create a new engine when the
+	// node resumes.
+	config           *config.Config
+	exceptionHandler ExceptionHandler
+
+	// heartbeat and slowHeartbeat are the configured heartbeat timeouts, which
+	// are restored when the battery saver is turned off.
+	heartbeat     time.Duration
+	slowHeartbeat time.Duration
+
+	// running, paused, shutdown, and batterySaver are protected by lock, which
+	// also protects node while the node is paused or resumed.
+	running      bool
+	paused       bool
+	shutdown     bool
+	batterySaver bool
+	lock         sync.Mutex
+
+	// statusHandler, state, and progress are protected by statusLock. state
+	// and progress are the last ones reported to the statusHandler.
+	statusHandler StatusHandler
+	state         string
+	progress      int32
+	statusLock    sync.Mutex
+
+	// storageHandler saves a checkpoint every checkpointInterval.
+	// lastCheckpoint is the index of the block of the last checkpoint saved.
+	// They are protected by checkpointLock.
+	storageHandler     StorageHandler
+	checkpointInterval time.Duration
+	lastCheckpoint     int
+	checkpointLock     sync.Mutex
 }
 
+// batterySaverFactor is the factor by which the battery saver slows down the
+// heartbeat timeouts.
+const batterySaverFactor = 10
+
+// progressInterval is the interval at which the sync progress is checked.
+const progressInterval = time.Second
+
 // New creates a new mobile node from a set of handlers. The configDir
 // parameter points to the directory where Babble configuration files reside.
 func New(
@@ -66,7 +109,7 @@ func New(
 		stateChangeHandler,
 		exceptionHandler,
 		babbleConfig.Logger())
-	babbleConfig.Proxy = inmem.NewInmemProxy(mobileApp, babbleConfig.Logger())
+	babbleConfig.Proxy = inmem.NewInmemProxy(mobileApp, babbleConfig.ModuleLogger("proxy"))
 
 	engine := babble.NewBabble(babbleConfig)
 
@@ -75,37 +118,223 @@ func New(
 		return nil
 	}
 
-	return &Node{
-		node:   engine.Node,
-		proxy:  babbleConfig.Proxy,
-		nodeID: engine.Node.GetID(),
-		logger: babbleConfig.Logger(),
+	mobileNode := &Node{
+		node:             engine.Node,
+		proxy:            babbleConfig.Proxy,
+		nodeID:           engine.Node.GetID(),
+		logger:           babbleConfig.Logger(),
+		config:           babbleConfig,
+		exceptionHandler: exceptionHandler,
+		heartbeat:        babbleConfig.HeartbeatTimeout,
+		slowHeartbeat:    babbleConfig.SlowHeartbeatTimeout,
+		progress:         100,
+		lastCheckpoint:   -1,
 	}
+
+	go mobileNode.watch(engine.Node)
+
+	return mobileNode
 }
 
 // Run runs the Babble node.
 func (n *Node) Run(async bool) {
+	n.lock.Lock()
+	babbleNode := n.node
+	n.running = true
+	n.lock.Unlock()
+
+	if n.getStorageHandler() != nil {
+		go n.checkpoints(babbleNode)
+	}
+
 	if async {
-		n.node.RunAsync(true)
+		babbleNode.RunAsync(true)
 	} else {
-		n.node.Run(true)
+		babbleNode.Run(true)
 	}
 }
 
 // Leave instructs the node to leave politely (get removed from validator-set)
-// before shutting down.
+// before shutting down. A paused node is only shut down, because it cannot
+// reach the other validators.
 func (n *Node) Leave() {
-	n.node.Leave()
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if !n.paused && !n.shutdown {
+		n.node.Leave()
+	}
+	n.shutdown = true
 }
 
 // Shutdown shutsdown the node without requesting to be removed from the
 // validator-set
 func (n *Node) Shutdown() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if !n.paused && !n.shutdown {
+		n.saveCheckpoint(n.node)
+		n.node.Shutdown()
+	}
+	n.shutdown = true
+}
+
+// Pause stops the node when the app goes to the background. It parks the gossip
+// loop, closes the transport, and closes the store, which persists the
+// hashgraph if the store is enabled. Resume starts the node again from the
+// persisted state. Without the store, the node resumes from a checkpoint if
+// there is a StorageHandler, or by fast-forwarding, so Pause returns an error if
+// neither the store, a StorageHandler, nor fast-sync are enabled.
+func (n *Node) Pause() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.shutdown {
+		return fmt.Errorf("Node is shut down")
+	}
+
+	if n.paused {
+		return nil
+	}
+
+	if !n.config.Store && !n.config.EnableFastSync && n.getStorageHandler() == nil {
+		return fmt.Errorf("Pausing requires the store, a StorageHandler, or fast-sync")
+	}
+
+	n.logger.Info("Pausing mobile node")
+
+	n.saveCheckpoint(n.node)
 	n.node.Shutdown()
+	n.paused = true
+
+	return nil
 }
 
-// SubmitTx submits a transaction to Babble for consensus ordering.
+// Resume restarts a paused node in the background, when the app returns to the
+// foreground. The node is bootstrapped from the store if it is enabled, which
+// commits the Blocks of the store to the app again, restored from the last
+// checkpoint if there is a StorageHandler, or fast-forwards otherwise.
+// The battery saver, if it was on, stays on.
+func (n *Node) Resume() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.shutdown {
+		return fmt.Errorf("Node is shut down")
+	}
+
+	if !n.paused {
+		return nil
+	}
+
+	n.logger.Info("Resuming mobile node")
+
+	// Restore the configured heartbeat timeouts, which are shared with the
+	// previous engine, before applying the battery saver to the new one.
+	n.config.HeartbeatTimeout = n.heartbeat
+	n.config.SlowHeartbeatTimeout = n.slowHeartbeat
+
+	if n.config.Store {
+		n.config.Bootstrap = true
+	}
+
+	engine := babble.NewBabble(n.config)
+
+	if err := engine.Init(); err != nil {
+		n.exceptionHandler.OnException(fmt.Sprintf("Cannot initialize engine: %s", err))
+		return err
+	}
+
+	if err := n.restoreCheckpoint(engine.Node); err != nil {
+		n.exceptionHandler.OnException(fmt.Sprintf("Cannot restore checkpoint: %s", err))
+		engine.Node.Shutdown()
+		return err
+	}
+
+	n.node = engine.Node
+	n.paused = false
+
+	go n.watch(engine.Node)
+
+	if n.getStorageHandler() != nil {
+		go n.checkpoints(engine.Node)
+	}
+
+	if n.batterySaver {
+		n.setHeartbeatTimeouts()
+	}
+
+	n.node.RunAsync(true)
+
+	return nil
+}
+
+// IsPaused returns true if the node is paused.
+func (n *Node) IsPaused() bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.paused
+}
+
+// SetBatterySaver turns the battery saver on or off. The battery saver reduces
+// the gossip frequency of the node, by slowing down its heartbeat timeouts
+// batterySaverFactor times, at the expense of a longer commit latency.
+func (n *Node) SetBatterySaver(enabled bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.batterySaver = enabled
+
+	if !n.paused && !n.shutdown {
+		n.setHeartbeatTimeouts()
+	}
+}
+
+// setHeartbeatTimeouts applies the heartbeat timeouts corresponding to the
+// battery saver mode. It must be called with the lock held.
+func (n *Node) setHeartbeatTimeouts() {
+	heartbeat, slowHeartbeat := n.heartbeat, n.slowHeartbeat
+	if n.batterySaver {
+		heartbeat *= batterySaverFactor
+		slowHeartbeat *= batterySaverFactor
+	}
+
+	n.node.SetHeartbeatTimeouts(heartbeat, slowHeartbeat)
+}
+
+// SetMetered turns the metered mode on or off, when the app detects that the
+// device switches between a cellular network and Wi-Fi. On a metered
+// connection, the node gossips less frequently, and defers FastForward, which
+// downloads a snapshot, until the connection is not metered anymore. The mode
+// is kept when the node is paused and resumed.
+func (n *Node) SetMetered(metered bool) {
+	n.getNode().SetMetered(metered)
+}
+
+// SetDataBudget changes the maximum number of bytes that the node sends and
+// receives in an hour. When the budget is used up, the node stops initiating
+// gossip until the next hour. 0 means unlimited.
+func (n *Node) SetDataBudget(maxBytesPerHour int64) {
+	n.getNode().SetMaxBytesPerHour(maxBytesPerHour)
+}
+
+// GetDataUsage returns the number of bytes sent and received by the node in
+// the current hour.
+func (n *Node) GetDataUsage() int64 {
+	return n.getNode().GetDataUsage()
+}
+
+// SubmitTx submits a transaction to Babble for consensus ordering. The
+// transactions submitted while the node is paused are dropped, and reported to
+// the ExceptionHandler.
 func (n *Node) SubmitTx(tx []byte) {
+	if n.IsPaused() {
+		n.exceptionHandler.OnException("Cannot submit transaction: node is paused")
+		return
+	}
+
 	// have to make a copy or the tx will be garbage collected and weird stuff
 	// happens in transaction pool
 	t := make([]byte, len(tx), len(tx))
@@ -113,14 +342,257 @@ func (n *Node) SubmitTx(tx []byte) {
 	n.proxy.SubmitCh() <- t
 }
 
+// SetStorageHandler sets the StorageHandler which saves a checkpoint of the
+// node every intervalSeconds, and restores the node from the last checkpoint
+// saved, if there is one. It is used to run nodes with the in-memory store,
+// without a Badger database, and must be called before Run. Blocks are then
+// committed from the block following the checkpoint, so the app should skip
+// the ones that it has already processed, by their index.
+func (n *Node) SetStorageHandler(handler StorageHandler, intervalSeconds int) error {
+	if n.config.Store {
+		return fmt.Errorf("Checkpoints require the in-memory store")
+	}
+
+	if intervalSeconds <= 0 {
+		return fmt.Errorf("The checkpoint interval must be positive")
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.running || n.shutdown {
+		return fmt.Errorf("The StorageHandler must be set before Run")
+	}
+
+	n.checkpointLock.Lock()
+	n.storageHandler = handler
+	n.checkpointInterval = time.Duration(intervalSeconds) * time.Second
+	n.checkpointLock.Unlock()
+
+	return n.restoreCheckpoint(n.node)
+}
+
+// getStorageHandler returns the StorageHandler, or nil.
+func (n *Node) getStorageHandler() StorageHandler {
+	n.checkpointLock.Lock()
+	defer n.checkpointLock.Unlock()
+
+	return n.storageHandler
+}
+
+// restoreCheckpoint restores a Babble node, which is not running yet, from the
+// last checkpoint of the StorageHandler, if there is one.
+func (n *Node) restoreCheckpoint(babbleNode *node.Node) error {
+	handler := n.getStorageHandler()
+	if handler == nil {
+		return nil
+	}
+
+	data := handler.LoadCheckpoint()
+	if len(data) == 0 {
+		return nil
+	}
+
+	var checkpoint node.Checkpoint
+	if err := checkpoint.Unmarshal(data); err != nil {
+		return err
+	}
+
+	if err := babbleNode.RestoreCheckpoint(&checkpoint); err != nil {
+		return err
+	}
+
+	n.checkpointLock.Lock()
+	n.lastCheckpoint = checkpoint.Block.Index()
+	n.checkpointLock.Unlock()
+
+	return nil
+}
+
+// checkpoints saves a checkpoint of a Babble node periodically, until it shuts
+// down.
+func (n *Node) checkpoints(babbleNode *node.Node) {
+	n.checkpointLock.Lock()
+	interval := n.checkpointInterval
+	n.checkpointLock.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if babbleNode.GetState() == _state.Shutdown {
+			return
+		}
+		n.saveCheckpoint(babbleNode)
+	}
+}
+
+// saveCheckpoint passes the checkpoint of a Babble node to the StorageHandler,
+// if it is newer than the last one saved.
+func (n *Node) saveCheckpoint(babbleNode *node.Node) {
+	n.checkpointLock.Lock()
+	defer n.checkpointLock.Unlock()
+
+	if n.storageHandler == nil {
+		return
+	}
+
+	checkpoint, err := babbleNode.GetCheckpoint()
+	if err != nil {
+		n.logger.WithError(err).Debug("No checkpoint")
+		return
+	}
+
+	if checkpoint.Block.Index() <= n.lastCheckpoint {
+		return
+	}
+
+	data, err := checkpoint.Marshal()
+	if err != nil {
+		n.logger.WithError(err).Error("Marshalling checkpoint")
+		return
+	}
+
+	n.storageHandler.SaveCheckpoint(data)
+	n.lastCheckpoint = checkpoint.Block.Index()
+}
+
+// SetStatusHandler sets the StatusHandler which is notified of the changes of
+// peer-set, of state, and of sync progress, and of the errors of the node. It
+// is immediately called with the current state and sync progress. The
+// callbacks are called from a Babble goroutine, and must not call
+// SetStatusHandler.
+func (n *Node) SetStatusHandler(handler StatusHandler) {
+	n.statusLock.Lock()
+	defer n.statusLock.Unlock()
+
+	n.statusHandler = handler
+
+	if handler == nil {
+		return
+	}
+
+	if n.state != "" {
+		handler.OnStateTransition(n.state, n.state)
+	}
+	handler.OnSyncProgress(n.progress)
+}
+
+// watch relays the notifications of a Babble node, and its sync progress, to
+// the StatusHandler, until the node shuts down.
+func (n *Node) watch(babbleNode *node.Node) {
+	sub := babbleNode.Subscribe(100,
+		node.PeerSetNotification,
+		node.StateNotification,
+		node.ErrorNotification)
+	defer sub.Unsubscribe()
+
+	n.setState(babbleNode.GetState().String())
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case note, ok := <-sub.C():
+			if !ok {
+				// The subscription is closed when the node shuts down, or
+				// when the StatusHandler does not keep up.
+				if babbleNode.GetState() == _state.Shutdown {
+					n.setState(_state.Shutdown.String())
+					return
+				}
+				sub = babbleNode.Subscribe(100,
+					node.PeerSetNotification,
+					node.StateNotification,
+					node.ErrorNotification)
+				continue
+			}
+			n.notify(note)
+		case <-ticker.C:
+			n.setProgress(int32(babbleNode.SyncProgress()))
+		}
+	}
+}
+
+// notify relays a notification to the StatusHandler.
+func (n *Node) notify(note node.Notification) {
+	switch note.Type {
+	case node.StateNotification:
+		n.setState(note.State)
+	case node.PeerSetNotification:
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(note.PeerSet.Peers); err != nil {
+			return
+		}
+
+		n.statusLock.Lock()
+		defer n.statusLock.Unlock()
+
+		if n.statusHandler != nil {
+			n.statusHandler.OnPeersChanged(buf.String())
+		}
+	case node.ErrorNotification:
+		n.statusLock.Lock()
+		defer n.statusLock.Unlock()
+
+		if n.statusHandler != nil {
+			n.statusHandler.OnError(note.Error)
+		}
+	}
+}
+
+// setState records the state of the node, and reports it to the StatusHandler
+// if it changed.
+func (n *Node) setState(state string) {
+	n.statusLock.Lock()
+	defer n.statusLock.Unlock()
+
+	if state == n.state {
+		return
+	}
+
+	from := n.state
+	n.state = state
+
+	if n.statusHandler != nil {
+		n.statusHandler.OnStateTransition(from, state)
+	}
+}
+
+// setProgress records the sync progress of the node, and reports it to the
+// StatusHandler if it changed.
+func (n *Node) setProgress(progress int32) {
+	n.statusLock.Lock()
+	defer n.statusLock.Unlock()
+
+	if progress == n.progress {
+		return
+	}
+
+	n.progress = progress
+
+	if n.statusHandler != nil {
+		n.statusHandler.OnSyncProgress(progress)
+	}
+}
+
+// getNode returns the current Babble node, which changes when the node resumes.
+func (n *Node) getNode() *node.Node {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.node
+}
+
 // GetPubKey returns the validator's public key in Hex format.
 func (n *Node) GetPubKey() string {
-	return n.node.GetPubKey()
+	return n.getNode().GetPubKey()
 }
 
 // GetPeers returns the current list of peers.
 func (n *Node) GetPeers() string {
-	peers := n.node.GetPeers()
+	peers := n.getNode().GetPeers()
 
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
@@ -133,7 +605,7 @@ func (n *Node) GetPeers() string {
 
 // GetGenesisPeers returns the genesis peers.
 func (n *Node) GetGenesisPeers() string {
-	peers, err := n.node.GetValidatorSet(0)
+	peers, err := n.getNode().GetValidatorSet(0)
 
 	if err != nil {
 		return ""
@@ -150,7 +622,7 @@ func (n *Node) GetGenesisPeers() string {
 
 // GetStats returns consensus stats.
 func (n *Node) GetStats() string {
-	stats := n.node.GetStats()
+	stats := n.getNode().GetStats()
 
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
@@ -0,0 +1,289 @@
+package net
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrUnknownChannel is returned to the peers that send RPCs to a channel
+	// which is not open on the node.
+	ErrUnknownChannel = errors.New("unknown channel")
+
+	// ErrChannelOpen is returned when opening a channel twice.
+	ErrChannelOpen = errors.New("channel already open")
+)
+
+// maxChannelIDLength is the maximum length of a channel ID, which is sent with
+// every RPC.
+const maxChannelIDLength = 64
+
+/*
+ChannelMux runs several independent Babble nodes, the channels, over a single
+Transport. Each channel has its own hashgraph, peer-set, store and AppProxy,
+and is given its own ChannelTransport by the Channel method. The
+ChannelTransports set their channel ID in the requests they send, and the
+ChannelMux dispatches the inbound RPCs to the channel they name, such that a
+platform hosting many small consensus groups only needs one listening address.
+
+The RPCs that do not name a channel, like those of the nodes that do not use
+channels, go to the channel with the empty ID, if it is open. The RPCs of other
+unknown channels are answered with ErrUnknownChannel.
+*/
+type ChannelMux struct {
+	trans Transport
+
+	channels     map[string]*ChannelTransport
+	channelsLock sync.RWMutex
+
+	listenOnce sync.Once
+
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+
+	logger *logrus.Entry
+}
+
+// NewChannelMux creates a ChannelMux over a Transport. The Transport is started
+// by the Listen method of the first ChannelTransport, and closed with the
+// ChannelMux.
+func NewChannelMux(trans Transport, logger *logrus.Entry) *ChannelMux {
+	if logger == nil {
+		log := logrus.New()
+		log.Level = logrus.DebugLevel
+		logger = logrus.NewEntry(log)
+	}
+
+	return &ChannelMux{
+		trans:      trans,
+		channels:   make(map[string]*ChannelTransport),
+		shutdownCh: make(chan struct{}),
+		logger:     logger,
+	}
+}
+
+// Channel opens a channel, and returns its Transport. It fails if the channel
+// is already open.
+func (m *ChannelMux) Channel(id string) (*ChannelTransport, error) {
+	if len(id) > maxChannelIDLength {
+		return nil, fmt.Errorf("channel ID %q is longer than %d bytes", id, maxChannelIDLength)
+	}
+
+	m.channelsLock.Lock()
+	defer m.channelsLock.Unlock()
+
+	if _, ok := m.channels[id]; ok {
+		return nil, fmt.Errorf("%v: %q", ErrChannelOpen, id)
+	}
+
+	c := &ChannelTransport{
+		id:         id,
+		mux:        m,
+		consumeCh:  make(chan RPC),
+		shutdownCh: make(chan struct{}),
+	}
+
+	m.channels[id] = c
+
+	return c, nil
+}
+
+// Channels returns the IDs of the open channels, sorted.
+func (m *ChannelMux) Channels() []string {
+	m.channelsLock.RLock()
+	defer m.channelsLock.RUnlock()
+
+	ids := make([]string, 0, len(m.channels))
+	for id := range m.channels {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Listen starts the wrapped Transport, and the dispatching of the inbound
+// RPCs. It only has an effect the first time it is called.
+func (m *ChannelMux) Listen() {
+	m.listenOnce.Do(func() {
+		go m.trans.Listen()
+		go m.dispatch()
+	})
+}
+
+// Close closes all the channels, and the wrapped Transport.
+func (m *ChannelMux) Close() error {
+	var err error
+
+	m.shutdownOnce.Do(func() {
+		close(m.shutdownCh)
+
+		m.channelsLock.Lock()
+		for id, c := range m.channels {
+			c.shutdown()
+			delete(m.channels, id)
+		}
+		m.channelsLock.Unlock()
+
+		err = m.trans.Close()
+	})
+
+	return err
+}
+
+// dispatch delivers the inbound RPCs to the Consumer of their channel.
+func (m *ChannelMux) dispatch() {
+	for {
+		select {
+		case rpc := <-m.trans.Consumer():
+			id := rpcChannel(rpc.Command)
+
+			m.channelsLock.RLock()
+			c, ok := m.channels[id]
+			m.channelsLock.RUnlock()
+
+			if !ok {
+				m.logger.WithField("channel", id).Debug("RPC for unknown channel")
+				rpc.Respond(nil, fmt.Errorf("%v: %q", ErrUnknownChannel, id))
+				continue
+			}
+
+			// A busy channel must not hold up the others
+			go c.deliver(rpc)
+		case <-m.shutdownCh:
+			return
+		}
+	}
+}
+
+// close removes a channel from the ChannelMux.
+func (m *ChannelMux) close(c *ChannelTransport) {
+	m.channelsLock.Lock()
+	defer m.channelsLock.Unlock()
+
+	if m.channels[c.id] == c {
+		delete(m.channels, c.id)
+	}
+}
+
+// rpcChannel returns the channel to which an RPC is sent.
+func rpcChannel(command interface{}) string {
+	switch cmd := command.(type) {
+	case *SyncRequest:
+		return cmd.Channel
+	case *EagerSyncRequest:
+		return cmd.Channel
+	case *FastForwardRequest:
+		return cmd.Channel
+	case *JoinRequest:
+		return cmd.Channel
+	case *PingRequest:
+		return cmd.Channel
+	case *InfoRequest:
+		return cmd.Channel
+	default:
+		return ""
+	}
+}
+
+// ChannelTransport is the Transport of a channel of a ChannelMux. Closing it
+// closes the channel, but not the Transport shared by the other channels.
+type ChannelTransport struct {
+	id  string
+	mux *ChannelMux
+
+	consumeCh chan RPC
+
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+}
+
+// ID returns the ID of the channel.
+func (c *ChannelTransport) ID() string {
+	return c.id
+}
+
+// Listen implements the Transport interface. It starts the ChannelMux.
+func (c *ChannelTransport) Listen() {
+	c.mux.Listen()
+}
+
+// Consumer implements the Transport interface.
+func (c *ChannelTransport) Consumer() <-chan RPC {
+	return c.consumeCh
+}
+
+// LocalAddr implements the Transport interface.
+func (c *ChannelTransport) LocalAddr() string {
+	return c.mux.trans.LocalAddr()
+}
+
+// AdvertiseAddr implements the Transport interface.
+func (c *ChannelTransport) AdvertiseAddr() string {
+	return c.mux.trans.AdvertiseAddr()
+}
+
+// Sync implements the Transport interface.
+func (c *ChannelTransport) Sync(target string, args *SyncRequest, resp *SyncResponse) error {
+	args.Channel = c.id
+	return c.mux.trans.Sync(target, args, resp)
+}
+
+// EagerSync implements the Transport interface.
+func (c *ChannelTransport) EagerSync(target string, args *EagerSyncRequest, resp *EagerSyncResponse) error {
+	args.Channel = c.id
+	return c.mux.trans.EagerSync(target, args, resp)
+}
+
+// FastForward implements the Transport interface.
+func (c *ChannelTransport) FastForward(target string, args *FastForwardRequest, resp *FastForwardResponse) error {
+	args.Channel = c.id
+	return c.mux.trans.FastForward(target, args, resp)
+}
+
+// Join implements the Transport interface.
+func (c *ChannelTransport) Join(target string, args *JoinRequest, resp *JoinResponse) error {
+	args.Channel = c.id
+	return c.mux.trans.Join(target, args, resp)
+}
+
+// Ping implements the Transport interface.
+func (c *ChannelTransport) Ping(target string, args *PingRequest, resp *PingResponse) error {
+	args.Channel = c.id
+	return c.mux.trans.Ping(target, args, resp)
+}
+
+// Info implements the Transport interface.
+func (c *ChannelTransport) Info(target string, args *InfoRequest, resp *InfoResponse) error {
+	args.Channel = c.id
+	return c.mux.trans.Info(target, args, resp)
+}
+
+// Close implements the Transport interface. The channel can be opened again
+// afterwards.
+func (c *ChannelTransport) Close() error {
+	c.mux.close(c)
+	c.shutdown()
+	return nil
+}
+
+// shutdown stops the delivery of RPCs to the channel.
+func (c *ChannelTransport) shutdown() {
+	c.shutdownOnce.Do(func() {
+		close(c.shutdownCh)
+	})
+}
+
+// deliver passes an RPC on to the Consumer, unless the channel is closed
+// first.
+func (c *ChannelTransport) deliver(rpc RPC) {
+	select {
+	case c.consumeCh <- rpc:
+	case <-c.shutdownCh:
+		rpc.Respond(nil, fmt.Errorf("%v: %q", ErrUnknownChannel, c.id))
+	}
+}
@@ -0,0 +1,100 @@
+package net
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+// respondPings answers the PingRequests of a channel with the given FromID.
+func respondPings(c *ChannelTransport, id uint32) {
+	for rpc := range c.Consumer() {
+		rpc.Respond(&PingResponse{FromID: id}, nil)
+	}
+}
+
+func TestChannelMux(t *testing.T) {
+	_, trans1 := NewInmemTransport("")
+	addr2, trans2 := NewInmemTransport("")
+	trans1.Connect(addr2, trans2)
+
+	mux1 := NewChannelMux(trans1, common.NewTestEntry(t, common.TestLogLevel))
+	mux2 := NewChannelMux(trans2, common.NewTestEntry(t, common.TestLogLevel))
+	defer mux1.Close()
+	defer mux2.Close()
+
+	a1, err := mux1.Channel("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1, err := mux1.Channel("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2, err := mux2.Channel("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := mux2.Channel("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mux2.Channel("b"); err == nil {
+		t.Fatal("Opening a channel twice should fail")
+	}
+
+	if ids := mux2.Channels(); !reflect.DeepEqual(ids, []string{"a", "b"}) {
+		t.Fatalf("The open channels should be [a b], not %v", ids)
+	}
+
+	a2.Listen()
+	go respondPings(a2, 1)
+	go respondPings(b2, 2)
+
+	// Each channel reaches the same channel on the other node
+	for c, expected := range map[*ChannelTransport]uint32{a1: 1, b1: 2} {
+		var resp PingResponse
+		if err := c.Ping(addr2, &PingRequest{}, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.FromID != expected {
+			t.Fatalf("Channel %q should reach node %d, not %d", c.ID(), expected, resp.FromID)
+		}
+	}
+
+	// Closing a channel does not affect the others
+	b2.Close()
+
+	var resp PingResponse
+	err = b1.Ping(addr2, &PingRequest{}, &resp)
+	if err == nil || !strings.Contains(err.Error(), ErrUnknownChannel.Error()) {
+		t.Fatalf("A closed channel should be unknown, not %v", err)
+	}
+
+	if err := a1.Ping(addr2, &PingRequest{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// The RPCs without a channel are only accepted by the default channel
+	err = trans1.Ping(addr2, &PingRequest{}, &resp)
+	if err == nil || !strings.Contains(err.Error(), ErrUnknownChannel.Error()) {
+		t.Fatalf("The default channel should be unknown, not %v", err)
+	}
+
+	d2, err := mux2.Channel("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go respondPings(d2, 3)
+
+	if err := trans1.Ping(addr2, &PingRequest{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.FromID != 3 {
+		t.Fatalf("The RPCs without a channel should reach the default channel, not %d", resp.FromID)
+	}
+}
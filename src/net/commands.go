@@ -1,66 +1,209 @@
 package net
 
 import (
+	"time"
+
 	"github.com/mosaicnetworks/babble/src/hashgraph"
 	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/version"
 )
 
 // SyncRequest corresponds to  the pull part of the pull-push gossip protocol.
 // It is used to retrieve unknown Events from another node. The Known map
 // represents how much the requester currently knows about the hashgraph. The
-// SyncLimit indicates the max number of Events to include in the response.
+// SyncLimit indicates the max number of Events to include in the response. Like
+// the other requests, it carries the NetworkID of the requester, which must
+// match the one of the responder, and its Protocol, which must be compatible
+// with the one of the responder. The Channel, set by the ChannelTransports,
+// selects the node that handles the request when several nodes share a
+// transport. Responses also carry the Protocol of the responder. With push-pull
+// gossip, the requester also includes the Events that it thinks the responder
+// does not know, which saves the EagerSyncRequest that would otherwise follow.
+// ChunkSize is the max size in bytes of the Events of the response, 0 meaning
+// unlimited.
 type SyncRequest struct {
 	FromID    uint32
+	NetworkID string
+	Channel   string `json:",omitempty"`
+	Protocol  version.Protocol
 	Known     map[uint32]int
 	SyncLimit int
+	ChunkSize int                   `json:",omitempty"`
+	Events    []hashgraph.WireEvent `json:",omitempty"`
 }
 
 // SyncResponse returns a list of Events as requested by a SyncRequest. The
 // known map indicates how much the responder knows about the hashgraph. Events
-// are encoded in light-weight wire format to take less space.
+// are encoded in light-weight wire format to take less space. PushAccepted
+// indicates that the responder inserted the Events of the request. It is false
+// when the request did not contain any, or when the responder does not support
+// push-pull gossip, in which case the requester pushes them with an
+// EagerSyncRequest. More is the continuation flag of chunked responses: it
+// indicates that the Events were cut to the chunk size, and that the requester
+// should send another SyncRequest, with its updated known map, to get the
+// rest.
 type SyncResponse struct {
-	FromID uint32
-	Events []hashgraph.WireEvent
-	Known  map[uint32]int
+	FromID       uint32
+	Protocol     version.Protocol
+	Events       []hashgraph.WireEvent
+	Known        map[uint32]int
+	PushAccepted bool `json:",omitempty"`
+	More         bool `json:",omitempty"`
 }
 
 // EagerSyncRequest corresponds to the push part of the pull-push gossip
 // protocol. It is used to actively push Events to a node without it being
 // requested.
 type EagerSyncRequest struct {
-	FromID uint32
-	Events []hashgraph.WireEvent
+	FromID    uint32
+	NetworkID string
+	Channel   string `json:",omitempty"`
+	Protocol  version.Protocol
+	Events    []hashgraph.WireEvent
 }
 
 // EagerSyncResponse indicates the success or failure of an EagerSyncRequest.
 type EagerSyncResponse struct {
-	FromID  uint32
-	Success bool
+	FromID   uint32
+	Protocol version.Protocol
+	Success  bool
 }
 
 // FastForwardRequest is used to request a Block, Frame, and Snapshot, from
-// which to fast-forward.
+// which to fast-forward. The responder returns its anchor block, unless the
+// request names the BlockIndex of a trusted block. A node whose App is at an
+// older Block names it in SnapshotBase, to receive the difference between the
+// snapshots instead of the full snapshot.
 type FastForwardRequest struct {
-	FromID uint32
+	FromID       uint32
+	NetworkID    string
+	Channel      string `json:",omitempty"`
+	Protocol     version.Protocol
+	BlockIndex   *int `json:",omitempty"`
+	SnapshotBase *int `json:",omitempty"`
 }
 
-// FastForwardResponse encapsulates the response to a FastForwardRequest.
+// FastForwardResponse encapsulates the response to a FastForwardRequest. If
+// SnapshotDeltaBase is set, Snapshot is the difference between the snapshot of
+// that Block and the snapshot of Block.
 type FastForwardResponse struct {
-	FromID   uint32
-	Block    hashgraph.Block
-	Frame    hashgraph.Frame
-	Snapshot []byte
+	FromID            uint32
+	Protocol          version.Protocol
+	Block             hashgraph.Block
+	Frame             hashgraph.Frame
+	Snapshot          []byte
+	SnapshotDeltaBase *int `json:",omitempty"`
 }
 
 // JoinRequest is used to submit an InternalTransaction to join a Babble group.
 type JoinRequest struct {
+	NetworkID           string
+	Channel             string `json:",omitempty"`
+	Protocol            version.Protocol
 	InternalTransaction hashgraph.InternalTransaction
 }
 
 // JoinResponse contains the response to a JoinRequest.
 type JoinResponse struct {
 	FromID        uint32
+	Protocol      version.Protocol
 	Accepted      bool
 	AcceptedRound int
 	Peers         []*peers.Peer
+	// RetryAfter, when not zero, means that the peer did not admit the
+	// JoinRequest yet, because too many nodes are joining, and that the
+	// requester should send it again after this duration.
+	RetryAfter time.Duration `json:",omitempty"`
+}
+
+// PingRequest is a lightweight request, without any payload, used to measure
+// the round-trip time to a peer.
+type PingRequest struct {
+	FromID    uint32
+	NetworkID string
+	Channel   string `json:",omitempty"`
+	Protocol  version.Protocol
+}
+
+// KeepaliveRequest checks that a connection is alive. It is answered by the
+// transport, and never reaches the node, so it carries no payload.
+type KeepaliveRequest struct{}
+
+// KeepaliveResponse is the response to a KeepaliveRequest.
+type KeepaliveResponse struct{}
+
+// PingResponse is the response to a PingRequest.
+type PingResponse struct {
+	FromID   uint32
+	Protocol version.Protocol
+	// GenesisStateHash is the genesis state hash of the App, when the node
+	// records it.
+	GenesisStateHash []byte `json:",omitempty"`
+}
+
+// InfoRequest asks a peer to describe itself.
+type InfoRequest struct {
+	FromID    uint32
+	NetworkID string
+	Channel   string `json:",omitempty"`
+	Protocol  version.Protocol
+}
+
+// InfoResponse is the response to an InfoRequest.
+type InfoResponse struct {
+	FromID   uint32
+	Protocol version.Protocol
+	Info     NodeInfo
+}
+
+// NodeInfo describes a node: its identity, the build that it runs, the
+// optional features that it has enabled, its transports, and the backend of its
+// store. It is returned by the Info RPC, and by the /info endpoint of the
+// service.
+type NodeInfo struct {
+	ID         uint32           `json:"id"`
+	PubKey     string           `json:"pub_key"`
+	Moniker    string           `json:"moniker"`
+	Protocol   version.Protocol `json:"protocol"`
+	GitCommit  string           `json:"git_commit,omitempty"`
+	Features   []string         `json:"features"`
+	Transports []string         `json:"transports"`
+	Store      string           `json:"store"`
+}
+
+// SenderID returns the ID of the peer that sent a request or a response. The
+// sender of a JoinRequest is the joining peer. It returns false for the other
+// messages.
+func SenderID(msg interface{}) (uint32, bool) {
+	switch m := msg.(type) {
+	case *SyncRequest:
+		return m.FromID, true
+	case *SyncResponse:
+		return m.FromID, true
+	case *EagerSyncRequest:
+		return m.FromID, true
+	case *EagerSyncResponse:
+		return m.FromID, true
+	case *FastForwardRequest:
+		return m.FromID, true
+	case *FastForwardResponse:
+		return m.FromID, true
+	case *JoinRequest:
+		// The ID is computed from a copy, so that the peer of the
+		// InternalTransaction is not modified.
+		peer := m.InternalTransaction.Body.Peer
+		return peer.ID(), true
+	case *JoinResponse:
+		return m.FromID, true
+	case *PingRequest:
+		return m.FromID, true
+	case *PingResponse:
+		return m.FromID, true
+	case *InfoRequest:
+		return m.FromID, true
+	case *InfoResponse:
+		return m.FromID, true
+	default:
+		return 0, false
+	}
 }
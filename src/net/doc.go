@@ -52,4 +52,19 @@
 // specified by the SignalAddr configuration value. The SignalRealm defines a
 // domain within the signaling server, such that signaling messages are only
 // routed withing this domain.
+//
+// Relay
+//
+// A RelayTransport bridges a TCP and a WebRTC transport, such that one node is
+// reachable from both networks. Other nodes reach the peers that they cannot
+// reach directly through the relay, with routes from the address of the target
+// to the address of the relay, set with the RelayRoutes configuration value.
+// The relay forwards their RPCs to the target, and returns the responses.
+//
+// Channels
+//
+// A ChannelMux runs several independent nodes, the channels, over a single
+// transport. Each node is given the ChannelTransport of its channel, which sets
+// the channel ID in the requests it sends. The ChannelMux dispatches the
+// inbound RPCs to the channel that they name.
 package net
@@ -0,0 +1,331 @@
+package net
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+)
+
+// FilterList identifies one of the lists of a Filter.
+type FilterList string
+
+const (
+	// AllowIPs lists the IP addresses or CIDR ranges allowed to connect. All
+	// the addresses are allowed when it is empty.
+	AllowIPs FilterList = "allow-ips"
+	// BlockIPs lists the IP addresses or CIDR ranges that cannot connect.
+	BlockIPs FilterList = "block-ips"
+	// AllowPubKeys lists the public keys of the peers allowed to send RPCs.
+	// All the peers are allowed when it is empty.
+	AllowPubKeys FilterList = "allow-pubkeys"
+	// BlockPubKeys lists the public keys of the peers that cannot send RPCs.
+	BlockPubKeys FilterList = "block-pubkeys"
+)
+
+// FilterRules are the entries of the lists of a Filter.
+type FilterRules struct {
+	AllowIPs     []string `json:"allow_ips"`
+	BlockIPs     []string `json:"block_ips"`
+	AllowPubKeys []string `json:"allow_pubkeys"`
+	BlockPubKeys []string `json:"block_pubkeys"`
+}
+
+// Filter decides which peers can connect to a transport and send it RPCs,
+// with allowlists and blocklists of IP addresses and public keys. A peer is
+// refused if it matches a blocklist, or if an allowlist is not empty and it
+// does not match it. IP addresses are only known with TCP. Public keys are
+// checked against the ID claimed by the sender of an RPC, or the signed peer of
+// a JoinRequest. Filter is safe for concurrent use, so that the lists can
+// change at runtime.
+type Filter struct {
+	sync.RWMutex
+
+	allowIPs map[string]*net.IPNet
+	blockIPs map[string]*net.IPNet
+
+	// the public key lists are indexed by peer ID
+	allowPubKeys map[uint32]string
+	blockPubKeys map[uint32]string
+}
+
+// NewFilter creates a Filter from lists of IP addresses, CIDR ranges, and
+// public keys.
+func NewFilter(rules FilterRules) (*Filter, error) {
+	f := &Filter{}
+
+	if err := f.SetRules(rules); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// SetRules replaces all the lists of the Filter. The Filter is left unchanged
+// if an entry is invalid.
+func (f *Filter) SetRules(rules FilterRules) error {
+	next := &Filter{
+		allowIPs:     make(map[string]*net.IPNet),
+		blockIPs:     make(map[string]*net.IPNet),
+		allowPubKeys: make(map[uint32]string),
+		blockPubKeys: make(map[uint32]string),
+	}
+
+	lists := []struct {
+		list    FilterList
+		entries []string
+	}{
+		{AllowIPs, rules.AllowIPs},
+		{BlockIPs, rules.BlockIPs},
+		{AllowPubKeys, rules.AllowPubKeys},
+		{BlockPubKeys, rules.BlockPubKeys},
+	}
+
+	for _, l := range lists {
+		for _, entry := range l.entries {
+			if err := next.Add(l.list, entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	f.allowIPs = next.allowIPs
+	f.blockIPs = next.blockIPs
+	f.allowPubKeys = next.allowPubKeys
+	f.blockPubKeys = next.blockPubKeys
+
+	return nil
+}
+
+// ParseFilterList splits a comma-separated list of filter entries, as found in
+// the configuration.
+func ParseFilterList(list string) []string {
+	res := []string{}
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+// Add adds an entry to a list.
+func (f *Filter) Add(list FilterList, entry string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	switch list {
+	case AllowIPs, BlockIPs:
+		ipNet, err := parseIPNet(entry)
+		if err != nil {
+			return err
+		}
+		f.ipList(list)[ipNet.String()] = ipNet
+	case AllowPubKeys, BlockPubKeys:
+		pub, id, err := parsePubKey(entry)
+		if err != nil {
+			return err
+		}
+		f.pubKeyList(list)[id] = pub
+	default:
+		return fmt.Errorf("Unknown filter list %q", list)
+	}
+
+	return nil
+}
+
+// Remove removes an entry from a list. It returns false if the entry was not
+// in the list.
+func (f *Filter) Remove(list FilterList, entry string) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	switch list {
+	case AllowIPs, BlockIPs:
+		ipNet, err := parseIPNet(entry)
+		if err != nil {
+			return false
+		}
+		ips := f.ipList(list)
+		if _, ok := ips[ipNet.String()]; !ok {
+			return false
+		}
+		delete(ips, ipNet.String())
+	case AllowPubKeys, BlockPubKeys:
+		_, id, err := parsePubKey(entry)
+		if err != nil {
+			return false
+		}
+		pubKeys := f.pubKeyList(list)
+		if _, ok := pubKeys[id]; !ok {
+			return false
+		}
+		delete(pubKeys, id)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Rules returns the sorted entries of the lists.
+func (f *Filter) Rules() FilterRules {
+	f.RLock()
+	defer f.RUnlock()
+
+	ips := func(m map[string]*net.IPNet) []string {
+		res := make([]string, 0, len(m))
+		for s := range m {
+			res = append(res, s)
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	pubKeys := func(m map[uint32]string) []string {
+		res := make([]string, 0, len(m))
+		for _, pub := range m {
+			res = append(res, pub)
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	return FilterRules{
+		AllowIPs:     ips(f.allowIPs),
+		BlockIPs:     ips(f.blockIPs),
+		AllowPubKeys: pubKeys(f.allowPubKeys),
+		BlockPubKeys: pubKeys(f.blockPubKeys),
+	}
+}
+
+// AllowsAddr returns false if the IP address of a remote network address is
+// refused. Addresses without an IP, like the ones of WebRTC connections, are
+// allowed.
+func (f *Filter) AllowsAddr(addr net.Addr) bool {
+	if addr == nil {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return true
+	}
+
+	return f.AllowsIP(ip)
+}
+
+// AllowsIP returns false if an IP address is refused.
+func (f *Filter) AllowsIP(ip net.IP) bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	for _, ipNet := range f.blockIPs {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+
+	if len(f.allowIPs) == 0 {
+		return true
+	}
+
+	for _, ipNet := range f.allowIPs {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsID returns false if the peer with a given ID is refused.
+func (f *Filter) AllowsID(id uint32) bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	if _, ok := f.blockPubKeys[id]; ok {
+		return false
+	}
+
+	if len(f.allowPubKeys) == 0 {
+		return true
+	}
+
+	_, ok := f.allowPubKeys[id]
+
+	return ok
+}
+
+// AllowsCommand returns false if the sender of an RPC command is refused.
+func (f *Filter) AllowsCommand(command interface{}) bool {
+	id, ok := SenderID(command)
+	if !ok {
+		return true
+	}
+	return f.AllowsID(id)
+}
+
+func (f *Filter) ipList(list FilterList) map[string]*net.IPNet {
+	if list == AllowIPs {
+		return f.allowIPs
+	}
+	return f.blockIPs
+}
+
+func (f *Filter) pubKeyList(list FilterList) map[uint32]string {
+	if list == AllowPubKeys {
+		return f.allowPubKeys
+	}
+	return f.blockPubKeys
+}
+
+// parseIPNet parses an IP address, which is a range of a single address, or a
+// CIDR range.
+func parseIPNet(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CIDR range %q", entry)
+		}
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("Invalid IP address %q", entry)
+	}
+
+	bits := 8 * net.IPv6len
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+		bits = 8 * net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// parsePubKey parses a hexadecimal public key, and returns it in the format
+// used by the peers, with the corresponding peer ID.
+func parsePubKey(entry string) (string, uint32, error) {
+	pub := "0X" + strings.TrimPrefix(strings.ToUpper(entry), "0X")
+
+	pubBytes, err := common.DecodeFromString(pub)
+	if err != nil || keys.ToPublicKey(pubBytes) == nil {
+		return "", 0, fmt.Errorf("Invalid public key %q", entry)
+	}
+
+	return pub, keys.PublicKeyID(pubBytes), nil
+}
@@ -0,0 +1,157 @@
+package net
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+)
+
+func TestFilter(t *testing.T) {
+	key, _ := keys.GenerateECDSAKey()
+	pub := keys.PublicKeyHex(&key.PublicKey)
+	id := keys.PublicKeyID(keys.FromPublicKey(&key.PublicKey))
+
+	filter, err := NewFilter(FilterRules{
+		BlockIPs:     []string{"10.0.0.0/24"},
+		BlockPubKeys: []string{strings.ToLower(pub)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.AllowsIP(net.ParseIP("10.0.0.5")) {
+		t.Fatal("10.0.0.5 should be blocked")
+	}
+
+	if !filter.AllowsIP(net.ParseIP("10.0.1.5")) {
+		t.Fatal("10.0.1.5 should be allowed")
+	}
+
+	if filter.AllowsID(id) {
+		t.Fatal("The public key should be blocked")
+	}
+
+	if !filter.AllowsAddr(nil) {
+		t.Fatal("Addresses without an IP should be allowed")
+	}
+
+	// A non-empty allowlist refuses the addresses it does not match
+	if err := filter.Add(AllowIPs, "192.168.1.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.AllowsIP(net.ParseIP("10.0.1.5")) {
+		t.Fatal("10.0.1.5 should not be allowed")
+	}
+
+	if !filter.AllowsIP(net.ParseIP("192.168.1.1")) {
+		t.Fatal("192.168.1.1 should be allowed")
+	}
+
+	if !filter.Remove(BlockPubKeys, pub) {
+		t.Fatal("Removing the public key should succeed")
+	}
+
+	if filter.Remove(BlockPubKeys, pub) {
+		t.Fatal("The public key should not be in the blocklist anymore")
+	}
+
+	if !filter.AllowsID(id) {
+		t.Fatal("The public key should be allowed")
+	}
+
+	expectedRules := FilterRules{
+		AllowIPs:     []string{"192.168.1.1/32"},
+		BlockIPs:     []string{"10.0.0.0/24"},
+		AllowPubKeys: []string{},
+		BlockPubKeys: []string{},
+	}
+
+	if rules := filter.Rules(); !reflect.DeepEqual(rules, expectedRules) {
+		t.Fatalf("Rules should be %#v, not %#v", expectedRules, rules)
+	}
+
+	for _, entry := range []struct {
+		list  FilterList
+		entry string
+	}{
+		{BlockIPs, "10.0.0"},
+		{AllowIPs, "10.0.0.0/33"},
+		{BlockPubKeys, "0XZZ"},
+		{"block-pancakes", "10.0.0.1"},
+	} {
+		if err := filter.Add(entry.list, entry.entry); err == nil {
+			t.Fatalf("Adding %q to %s should fail", entry.entry, entry.list)
+		}
+	}
+
+	// SetRules leaves the filter unchanged on error
+	if err := filter.SetRules(FilterRules{BlockIPs: []string{"bad"}}); err == nil {
+		t.Fatal("SetRules should fail")
+	}
+
+	if rules := filter.Rules(); !reflect.DeepEqual(rules, expectedRules) {
+		t.Fatalf("Rules should still be %#v, not %#v", expectedRules, rules)
+	}
+}
+
+func TestTCPTransport_Filter(t *testing.T) {
+	trans1, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, 2*time.Second, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go trans1.Listen()
+	defer trans1.Close()
+	rpcCh := trans1.Consumer()
+
+	go func() {
+		for rpc := range rpcCh {
+			rpc.Respond(&SyncResponse{FromID: 1}, nil)
+		}
+	}()
+
+	trans2, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, 2*time.Second, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer trans2.Close()
+
+	key, _ := keys.GenerateECDSAKey()
+	pub := keys.PublicKeyHex(&key.PublicKey)
+	args := SyncRequest{FromID: keys.PublicKeyID(keys.FromPublicKey(&key.PublicKey))}
+
+	var out SyncResponse
+	if err := trans2.Sync(trans1.LocalAddr(), &args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The connection is pooled, so the blocklist applies to open connections
+	if err := trans1.Filter().Add(BlockPubKeys, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := trans2.Sync(trans1.LocalAddr(), &args, &out); err == nil {
+		t.Fatal("The RPC of a blocked public key should be refused")
+	}
+
+	trans1.Filter().Remove(BlockPubKeys, pub)
+
+	if err := trans1.Filter().Add(BlockIPs, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := trans2.Sync(trans1.LocalAddr(), &args, &out); err == nil {
+		t.Fatal("The RPC of a blocked address should be refused")
+	}
+
+	trans1.Filter().Remove(BlockIPs, "127.0.0.1")
+
+	if err := trans2.Sync(trans1.LocalAddr(), &args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
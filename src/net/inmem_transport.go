@@ -6,6 +6,8 @@ import (
 	"io"
 	"sync"
 	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
 )
 
 // newInmemAddr returns a new in-memory addr with a randomly generate UUID as
@@ -37,6 +39,11 @@ type InmemTransport struct {
 	localAddr  string
 	peers      map[string]*InmemTransport
 	timeout    time.Duration
+	clock      common.Clock
+
+	// shutdownCh is closed by Close, to abort the RPCs waiting to be sent.
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
 }
 
 // NewInmemTransport is used to initialize a new InmemTransport and generates a
@@ -50,10 +57,21 @@ func NewInmemTransport(addr string) (string, *InmemTransport) {
 		localAddr:  addr,
 		peers:      make(map[string]*InmemTransport),
 		timeout:    50 * time.Millisecond,
+		clock:      common.RealClock{},
+		shutdownCh: make(chan struct{}),
 	}
 	return addr, trans
 }
 
+// SetClock sets the clock which measures the timeouts of the RPCs. Tests and
+// simulations use a common.FakeClock to control when the RPCs time out.
+func (i *InmemTransport) SetClock(clock common.Clock) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.clock = clock
+}
+
 // Consumer implements the Transport interface.
 func (i *InmemTransport) Consumer() <-chan RPC {
 	return i.consumerCh
@@ -121,9 +139,49 @@ func (i *InmemTransport) Join(target string, args *JoinRequest, resp *JoinRespon
 	return nil
 }
 
+// Ping implements the Transport interface
+func (i *InmemTransport) Ping(target string, args *PingRequest, resp *PingResponse) error {
+	rpcResp, err := i.makeRPC(target, args, nil, i.timeout)
+	if err != nil {
+		return err
+	}
+
+	// Copy the result back
+	out := rpcResp.Response.(*PingResponse)
+	*resp = *out
+	return nil
+}
+
+// Keepalive implements the KeepaliveTransport interface. The target is alive
+// if it is connected.
+func (i *InmemTransport) Keepalive(target string) (time.Duration, error) {
+	i.RLock()
+	defer i.RUnlock()
+
+	if _, ok := i.peers[target]; !ok {
+		return 0, fmt.Errorf("failed to connect to peer: %v", target)
+	}
+
+	return 0, nil
+}
+
+// Info implements the Transport interface
+func (i *InmemTransport) Info(target string, args *InfoRequest, resp *InfoResponse) error {
+	rpcResp, err := i.makeRPC(target, args, nil, i.timeout)
+	if err != nil {
+		return err
+	}
+
+	// Copy the result back
+	out := rpcResp.Response.(*InfoResponse)
+	*resp = *out
+	return nil
+}
+
 func (i *InmemTransport) makeRPC(target string, args interface{}, r io.Reader, timeout time.Duration) (rpcResp RPCResponse, err error) {
 	i.RLock()
 	peer, ok := i.peers[target]
+	clock := i.clock
 	i.RUnlock()
 
 	if !ok {
@@ -131,11 +189,24 @@ func (i *InmemTransport) makeRPC(target string, args interface{}, r io.Reader, t
 		return
 	}
 
+	// The timeout covers both sending the RPC and waiting for the response.
+	// The response channel is buffered, so that the peer does not block on
+	// responding to an RPC which already timed out.
+	timeoutCh := clock.After(timeout)
+	respCh := make(chan RPCResponse, 1)
+
 	// Send the RPC over
-	respCh := make(chan RPCResponse)
-	peer.consumerCh <- RPC{
+	select {
+	case peer.consumerCh <- RPC{
 		Command:  args,
 		RespChan: respCh,
+	}:
+	case <-timeoutCh:
+		err = fmt.Errorf("command timed out")
+		return
+	case <-i.shutdownCh:
+		err = fmt.Errorf("transport is closed")
+		return
 	}
 
 	// Wait for a response
@@ -144,8 +215,10 @@ func (i *InmemTransport) makeRPC(target string, args interface{}, r io.Reader, t
 		if rpcResp.Error != nil {
 			err = rpcResp.Error
 		}
-	case <-time.After(timeout):
+	case <-timeoutCh:
 		err = fmt.Errorf("command timed out")
+	case <-i.shutdownCh:
+		err = fmt.Errorf("transport is closed")
 	}
 	return
 }
@@ -175,6 +248,7 @@ func (i *InmemTransport) DisconnectAll() {
 
 // Close is used to permanently disable the transport
 func (i *InmemTransport) Close() error {
+	i.shutdownOnce.Do(func() { close(i.shutdownCh) })
 	i.DisconnectAll()
 	return nil
 }
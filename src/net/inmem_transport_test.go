@@ -0,0 +1,43 @@
+package net
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestInmemTransportTimeout(t *testing.T) {
+	clock := common.NewFakeClock(time.Unix(0, 0))
+
+	_, trans1 := NewInmemTransport("")
+	addr2, trans2 := NewInmemTransport("")
+	trans1.SetClock(clock)
+	trans1.Connect(addr2, trans2)
+
+	errCh := make(chan error, 1)
+	go func() {
+		var out SyncResponse
+		errCh <- trans1.Sync(addr2, &SyncRequest{FromID: 1}, &out)
+	}()
+
+	// The second transport receives the request, but never responds
+	rpc := <-trans2.Consumer()
+	if _, ok := rpc.Command.(*SyncRequest); !ok {
+		t.Fatalf("The request should be a SyncRequest, not %T", rpc.Command)
+	}
+
+	clock.BlockUntil(1)
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("The RPC should not time out before the clock is advanced: %v", err)
+	default:
+	}
+
+	clock.Advance(trans1.timeout)
+
+	if err := <-errCh; err == nil {
+		t.Fatal("The RPC should time out")
+	}
+}
@@ -0,0 +1,91 @@
+package nat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// defaultGateway returns the IPv4 address of the default gateway. It reads the
+// routing table on Linux, and otherwise guesses the first address of the
+// network of each interface, which is the convention of home routers.
+func defaultGateway() (net.IP, error) {
+	if ip, err := linuxGateway("/proc/net/route"); err == nil {
+		return ip, nil
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil || !isPrivate(ipNet.IP) {
+				continue
+			}
+
+			gateway := ipNet.IP.Mask(ipNet.Mask).To4()
+			gateway[3]++
+			return gateway, nil
+		}
+	}
+
+	return nil, fmt.Errorf("No default gateway found")
+}
+
+// linuxGateway parses the route of the default destination in a Linux routing
+// table.
+func linuxGateway(path string) (net.IP, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+
+		b, err := hex.DecodeString(fields[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+
+		// The addresses are in the byte order of the host, which is little
+		// endian on the common architectures
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(b))
+		return ip, nil
+	}
+
+	return nil, fmt.Errorf("No default route in %s", path)
+}
+
+func isPrivate(ip net.IP) bool {
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+// Package nat maps the port of a Babble node on the router of a home network,
+// with UPnP or NAT-PMP, and discovers the public address of the router, so
+// that the node can advertise an address which is reachable from the internet
+// without manual router configuration.
+package nat
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultLifetime is the lifetime requested for port mappings. Mappings
+	// are renewed before they expire.
+	DefaultLifetime = 20 * time.Minute
+
+	// discoveryTimeout limits the time spent looking for a router.
+	discoveryTimeout = 3 * time.Second
+
+	mappingDescription = "babble"
+)
+
+// NAT is a router which can map ports and report its external address.
+type NAT interface {
+	// ExternalIP returns the public IP address of the router.
+	ExternalIP() (net.IP, error)
+
+	// AddMapping maps an external TCP port of the router to an internal port
+	// of this machine, and returns the external port, which may differ from
+	// the requested one.
+	AddMapping(internalPort, externalPort int, description string, lifetime time.Duration) (int, error)
+
+	// DeleteMapping removes a mapping created by AddMapping.
+	DeleteMapping(internalPort, externalPort int) error
+
+	// String returns the name of the protocol and the address of the router.
+	String() string
+}
+
+// Parse returns the NAT described by an option: "none" or "" for no port
+// mapping, "upnp", "pmp" for NAT-PMP with the default gateway, "pmp:<ip>" for
+// NAT-PMP with a given gateway, or "any" to use whichever protocol the router
+// supports. The router is discovered on the local network, which takes a few
+// seconds if it does not respond.
+func Parse(spec string) (NAT, error) {
+	if err := Check(spec); err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(spec, ":", 2)
+
+	switch strings.ToLower(parts[0]) {
+	case "any":
+		return Discover(discoveryTimeout)
+	case "upnp":
+		return DiscoverUPnP(discoveryTimeout)
+	case "pmp":
+		if len(parts) == 2 {
+			return NewPMP(net.ParseIP(parts[1])), nil
+		}
+
+		gateway, err := defaultGateway()
+		if err != nil {
+			return nil, err
+		}
+		return NewPMP(gateway), nil
+	default:
+		return nil, nil
+	}
+}
+
+// Check returns an error if a NAT option is invalid, without looking for the
+// router.
+func Check(spec string) error {
+	parts := strings.SplitN(spec, ":", 2)
+
+	switch strings.ToLower(parts[0]) {
+	case "", "none", "any", "upnp":
+		if len(parts) == 2 {
+			return fmt.Errorf("Unknown NAT mechanism %q", spec)
+		}
+		return nil
+	case "pmp":
+		if len(parts) == 2 && net.ParseIP(parts[1]) == nil {
+			return fmt.Errorf("Invalid NAT-PMP gateway %q", parts[1])
+		}
+		return nil
+	default:
+		return fmt.Errorf("Unknown NAT mechanism %q", spec)
+	}
+}
+
+// Discover looks for a router supporting UPnP or NAT-PMP, and returns the
+// first one that answers.
+func Discover(timeout time.Duration) (NAT, error) {
+	found := make(chan NAT, 2)
+	errs := make(chan error, 2)
+
+	go func() {
+		n, err := DiscoverUPnP(timeout)
+		if err != nil {
+			errs <- err
+			return
+		}
+		found <- n
+	}()
+
+	go func() {
+		gateway, err := defaultGateway()
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		// NAT-PMP has no discovery, so ask the gateway for its address
+		n := NewPMP(gateway)
+		n.timeout = timeout
+		if _, err := n.ExternalIP(); err != nil {
+			errs <- err
+			return
+		}
+		found <- n
+	}()
+
+	var lastErr error
+	for i := 0; i < 2; i++ {
+		select {
+		case n := <-found:
+			return n, nil
+		case lastErr = <-errs:
+		}
+	}
+
+	return nil, fmt.Errorf("No UPnP or NAT-PMP router found: %v", lastErr)
+}
+
+// Mapping is a port mapping which is renewed in the background until it is
+// closed.
+type Mapping struct {
+	nat          NAT
+	internalPort int
+	externalPort int
+	externalIP   net.IP
+	lifetime     time.Duration
+	logger       *logrus.Entry
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+// Map maps the port of a local IP:PORT address to the same external port if
+// possible, finds the external address of the router, and keeps the mapping
+// alive until the Mapping is closed.
+func Map(n NAT, bindAddr string, lifetime time.Duration, logger *logrus.Entry) (*Mapping, error) {
+	_, port, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	internalPort, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid port in %s", bindAddr)
+	}
+
+	ip, err := n.ExternalIP()
+	if err != nil {
+		return nil, fmt.Errorf("Getting external address from %s: %v", n, err)
+	}
+
+	externalPort, err := n.AddMapping(internalPort, internalPort, mappingDescription, lifetime)
+	if err != nil {
+		return nil, fmt.Errorf("Mapping port %d with %s: %v", internalPort, n, err)
+	}
+
+	m := &Mapping{
+		nat:          n,
+		internalPort: internalPort,
+		externalPort: externalPort,
+		externalIP:   ip,
+		lifetime:     lifetime,
+		logger:       logger,
+		closeCh:      make(chan struct{}),
+	}
+
+	logger.WithFields(logrus.Fields{
+		"nat":      n.String(),
+		"internal": internalPort,
+		"external": m.Addr(),
+	}).Info("Mapped port")
+
+	go m.renew()
+
+	return m, nil
+}
+
+// Addr returns the external IP:PORT of the mapping.
+func (m *Mapping) Addr() string {
+	return net.JoinHostPort(m.externalIP.String(), strconv.Itoa(m.externalPort))
+}
+
+// Close stops renewing the mapping and removes it from the router.
+func (m *Mapping) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.closeCh)
+		err = m.nat.DeleteMapping(m.internalPort, m.externalPort)
+	})
+	return err
+}
+
+// renew refreshes the mapping at half its lifetime. If the router gives
+// another external port, the advertised address becomes stale, which is
+// logged.
+func (m *Mapping) renew() {
+	ticker := time.NewTicker(m.lifetime / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			port, err := m.nat.AddMapping(m.internalPort, m.externalPort, mappingDescription, m.lifetime)
+			if err != nil {
+				m.logger.WithError(err).Warn("Renewing port mapping")
+				continue
+			}
+			if port != m.externalPort {
+				m.logger.WithFields(logrus.Fields{
+					"previous": m.externalPort,
+					"port":     port,
+				}).Warn("Router changed the external port")
+			}
+		case <-m.closeCh:
+			return
+		}
+	}
+}
+
+// localIPFor returns the IP address of this machine on the route to a remote
+// address.
+func localIPFor(remote net.IP) (net.IP, error) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(remote.String(), "1"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,199 @@
+package nat
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+// fakePMP answers NAT-PMP requests like a router with the external address
+// 203.0.113.7, which maps port 1337 to 41337.
+func fakePMP(t *testing.T) *PMP {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			resp := make([]byte, 16)
+			resp[1] = buf[1] + 128
+
+			switch {
+			case n == 2 && buf[1] == pmpOpExternalAddr:
+				copy(resp[8:12], net.ParseIP("203.0.113.7").To4())
+				conn.WriteTo(resp[:12], addr)
+			case n == 12 && buf[1] == pmpOpMapTCP:
+				internal := binary.BigEndian.Uint16(buf[4:6])
+				if internal != 1337 {
+					// Not authorized
+					binary.BigEndian.PutUint16(resp[2:4], 2)
+				}
+				copy(resp[8:10], buf[4:6])
+				binary.BigEndian.PutUint16(resp[10:12], internal+40000)
+				copy(resp[12:16], buf[8:12])
+				conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	t.Cleanup(func() { conn.Close() })
+
+	p := NewPMP(net.ParseIP("127.0.0.1"))
+	p.port = conn.LocalAddr().(*net.UDPAddr).Port
+	p.timeout = time.Second
+
+	return p
+}
+
+func TestPMP(t *testing.T) {
+	p := fakePMP(t)
+
+	mapping, err := Map(p, "0.0.0.0:1337", time.Minute, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapping.Close()
+
+	if addr := mapping.Addr(); addr != "203.0.113.7:41337" {
+		t.Fatalf("The external address should be 203.0.113.7:41337, not %s", addr)
+	}
+
+	if _, err := p.AddMapping(1338, 1338, "babble", time.Minute); err == nil {
+		t.Fatal("The router error should be reported")
+	}
+}
+
+func TestUPnP(t *testing.T) {
+	var actions []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/desc.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/ctl</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`))
+	})
+	mux.HandleFunc("/ctl", func(w http.ResponseWriter, r *http.Request) {
+		action := r.Header.Get("SOAPAction")
+		actions = append(actions, action)
+
+		body, _ := ioutil.ReadAll(r.Body)
+
+		switch {
+		case strings.HasSuffix(action, `#GetExternalIPAddress"`):
+			w.Write([]byte(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">
+      <NewExternalIPAddress>203.0.113.7</NewExternalIPAddress>
+    </u:GetExternalIPAddressResponse>
+  </s:Body>
+</s:Envelope>`))
+		case strings.HasSuffix(action, `#AddPortMapping"`):
+			if !strings.Contains(string(body), "<NewExternalPort>1337</NewExternalPort>") {
+				http.Error(w, "Bad port", http.StatusInternalServerError)
+			}
+		case strings.HasSuffix(action, `#DeletePortMapping"`):
+		default:
+			http.Error(w, "Unknown action", http.StatusInternalServerError)
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	u, err := newUPnP(server.URL+"/desc.xml", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping, err := Map(u, "127.0.0.1:1337", time.Minute, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr := mapping.Addr(); addr != "203.0.113.7:1337" {
+		t.Fatalf("The external address should be 203.0.113.7:1337, not %s", addr)
+	}
+
+	if err := mapping.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actions) != 3 || !strings.HasSuffix(actions[2], `#DeletePortMapping"`) {
+		t.Fatalf("The mapping should be deleted on close, actions: %v", actions)
+	}
+}
+
+func TestLinuxGateway(t *testing.T) {
+	dir, err := ioutil.TempDir("", "babble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "route")
+	table := "Iface\tDestination\tGateway \tFlags\n" +
+		"eth0\t0000A8C0\t00000000\t0001\n" +
+		"eth0\t00000000\t0101A8C0\t0003\n"
+
+	if err := ioutil.WriteFile(path, []byte(table), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ip, err := linuxGateway(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ip.Equal(net.ParseIP("192.168.1.1")) {
+		t.Fatalf("The gateway should be 192.168.1.1, not %s", ip)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, spec := range []string{"", "none", "any", "upnp", "pmp", "pmp:192.168.1.1"} {
+		if err := Check(spec); err != nil {
+			t.Fatalf("%q should be valid: %v", spec, err)
+		}
+	}
+
+	for _, spec := range []string{"stun", "upnp:1.2.3.4", "pmp:router"} {
+		if err := Check(spec); err == nil {
+			t.Fatalf("%q should be invalid", spec)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package nat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	pmpPort = 5351
+
+	pmpOpExternalAddr = 0
+	pmpOpMapTCP       = 2
+
+	// pmpInitialDelay is the delay before the first retransmission, which is
+	// doubled with every attempt, as specified by RFC 6886.
+	pmpInitialDelay = 250 * time.Millisecond
+)
+
+// PMP is a router supporting NAT-PMP (RFC 6886).
+type PMP struct {
+	gateway net.IP
+	port    int
+	timeout time.Duration
+}
+
+// NewPMP creates a NAT-PMP client for a gateway.
+func NewPMP(gateway net.IP) *PMP {
+	return &PMP{
+		gateway: gateway,
+		port:    pmpPort,
+		timeout: discoveryTimeout,
+	}
+}
+
+// String implements the NAT interface.
+func (p *PMP) String() string {
+	return fmt.Sprintf("NAT-PMP(%s)", p.gateway)
+}
+
+// ExternalIP implements the NAT interface.
+func (p *PMP) ExternalIP() (net.IP, error) {
+	resp, err := p.call([]byte{0, pmpOpExternalAddr}, 12)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.IPv4(resp[8], resp[9], resp[10], resp[11]), nil
+}
+
+// AddMapping implements the NAT interface.
+func (p *PMP) AddMapping(internalPort, externalPort int, description string, lifetime time.Duration) (int, error) {
+	resp, err := p.call(pmpMapRequest(internalPort, externalPort, lifetime), 16)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(binary.BigEndian.Uint16(resp[10:12])), nil
+}
+
+// DeleteMapping implements the NAT interface. A mapping is deleted by
+// requesting it with a lifetime of zero.
+func (p *PMP) DeleteMapping(internalPort, externalPort int) error {
+	_, err := p.call(pmpMapRequest(internalPort, 0, 0), 16)
+	return err
+}
+
+func pmpMapRequest(internalPort, externalPort int, lifetime time.Duration) []byte {
+	req := make([]byte, 12)
+	req[1] = pmpOpMapTCP
+	binary.BigEndian.PutUint16(req[4:6], uint16(internalPort))
+	binary.BigEndian.PutUint16(req[6:8], uint16(externalPort))
+	binary.BigEndian.PutUint32(req[8:12], uint32(lifetime/time.Second))
+	return req
+}
+
+// call sends a request to the gateway, retransmitting it until a response
+// arrives or the timeout expires, and checks the result code.
+func (p *PMP) call(req []byte, respLen int) ([]byte, error) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(p.gateway.String(), strconv.Itoa(p.port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(p.timeout)
+	delay := pmpInitialDelay
+	resp := make([]byte, 16)
+
+	for time.Now().Before(deadline) {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+
+		wait := time.Now().Add(delay)
+		if wait.After(deadline) {
+			wait = deadline
+		}
+		conn.SetReadDeadline(wait)
+		delay *= 2
+
+		n, err := conn.Read(resp)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return nil, err
+		}
+
+		// Responses have the opcode of the request plus 128
+		if n < respLen || resp[0] != 0 || resp[1] != req[1]+128 {
+			continue
+		}
+
+		if code := binary.BigEndian.Uint16(resp[2:4]); code != 0 {
+			return nil, fmt.Errorf("NAT-PMP error code %d", code)
+		}
+
+		return resp[:n], nil
+	}
+
+	return nil, fmt.Errorf("No NAT-PMP response from %s", p.gateway)
+}
@@ -0,0 +1,251 @@
+package nat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ssdpAddr   = "239.255.255.250:1900"
+	ssdpTarget = "urn:schemas-upnp-org:device:InternetGatewayDevice:1"
+)
+
+// UPnP is a router supporting the WANIPConnection or WANPPPConnection service
+// of the UPnP Internet Gateway Device protocol.
+type UPnP struct {
+	controlURL  string
+	serviceType string
+	localIP     net.IP
+	client      *http.Client
+}
+
+// DiscoverUPnP looks for an Internet Gateway Device on the local network with
+// SSDP.
+func DiscoverUPnP(timeout time.Duration) (*UPnP, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dst, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"ST: " + ssdpTarget + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+
+	if _, err := conn.WriteTo([]byte(search), dst); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, fmt.Errorf("No UPnP router found: %v", err)
+		}
+
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+
+		location := resp.Header.Get("Location")
+		if location == "" {
+			continue
+		}
+
+		if u, err := newUPnP(location, timeout); err == nil {
+			return u, nil
+		}
+	}
+}
+
+// upnpDevice is the part of a device description that leads to the services.
+type upnpDevice struct {
+	Services []struct {
+		ServiceType string `xml:"serviceType"`
+		ControlURL  string `xml:"controlURL"`
+	} `xml:"serviceList>service"`
+	Devices []upnpDevice `xml:"deviceList>device"`
+}
+
+// newUPnP reads the description of a device, and finds its WAN connection
+// service.
+func newUPnP(location string, timeout time.Duration) (*UPnP, error) {
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var root struct {
+		URLBase string     `xml:"URLBase"`
+		Device  upnpDevice `xml:"device"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return nil, fmt.Errorf("Parsing device description: %v", err)
+	}
+
+	serviceType, controlURL := findWANService(root.Device)
+	if controlURL == "" {
+		return nil, fmt.Errorf("No WAN connection service in %s", location)
+	}
+
+	base := location
+	if root.URLBase != "" {
+		base = root.URLBase
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl, err := baseURL.Parse(controlURL)
+	if err != nil {
+		return nil, err
+	}
+
+	localIP, err := localIPFor(net.ParseIP(ctrl.Hostname()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &UPnP{
+		controlURL:  ctrl.String(),
+		serviceType: serviceType,
+		localIP:     localIP,
+		client:      client,
+	}, nil
+}
+
+func findWANService(d upnpDevice) (string, string) {
+	for _, s := range d.Services {
+		if strings.Contains(s.ServiceType, ":WANIPConnection:") ||
+			strings.Contains(s.ServiceType, ":WANPPPConnection:") {
+			return s.ServiceType, s.ControlURL
+		}
+	}
+
+	for _, child := range d.Devices {
+		if serviceType, controlURL := findWANService(child); controlURL != "" {
+			return serviceType, controlURL
+		}
+	}
+
+	return "", ""
+}
+
+// String implements the NAT interface.
+func (u *UPnP) String() string {
+	return fmt.Sprintf("UPnP(%s)", u.controlURL)
+}
+
+// ExternalIP implements the NAT interface.
+func (u *UPnP) ExternalIP() (net.IP, error) {
+	var resp struct {
+		IP string `xml:"Body>GetExternalIPAddressResponse>NewExternalIPAddress"`
+	}
+
+	if err := u.soap("GetExternalIPAddress", "", &resp); err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(resp.IP))
+	if ip == nil {
+		return nil, fmt.Errorf("Invalid external address %q", resp.IP)
+	}
+
+	return ip, nil
+}
+
+// AddMapping implements the NAT interface. UPnP routers map the requested
+// port, or fail.
+func (u *UPnP) AddMapping(internalPort, externalPort int, description string, lifetime time.Duration) (int, error) {
+	args := "<NewRemoteHost></NewRemoteHost>" +
+		"<NewExternalPort>" + strconv.Itoa(externalPort) + "</NewExternalPort>" +
+		"<NewProtocol>TCP</NewProtocol>" +
+		"<NewInternalPort>" + strconv.Itoa(internalPort) + "</NewInternalPort>" +
+		"<NewInternalClient>" + u.localIP.String() + "</NewInternalClient>" +
+		"<NewEnabled>1</NewEnabled>" +
+		"<NewPortMappingDescription>" + xmlEscape(description) + "</NewPortMappingDescription>" +
+		"<NewLeaseDuration>" + strconv.Itoa(int(lifetime/time.Second)) + "</NewLeaseDuration>"
+
+	if err := u.soap("AddPortMapping", args, nil); err != nil {
+		return 0, err
+	}
+
+	return externalPort, nil
+}
+
+// DeleteMapping implements the NAT interface.
+func (u *UPnP) DeleteMapping(internalPort, externalPort int) error {
+	args := "<NewRemoteHost></NewRemoteHost>" +
+		"<NewExternalPort>" + strconv.Itoa(externalPort) + "</NewExternalPort>" +
+		"<NewProtocol>TCP</NewProtocol>"
+
+	return u.soap("DeletePortMapping", args, nil)
+}
+
+// soap calls an action of the WAN connection service, and decodes the
+// response in resp if it is not nil.
+func (u *UPnP) soap(action string, args string, resp interface{}) error {
+	body := `<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:` + action + ` xmlns:u="` + u.serviceType + `">` + args +
+		`</u:` + action + `></s:Body></s:Envelope>`
+
+	req, err := http.NewRequest(http.MethodPost, u.controlURL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+u.serviceType+"#"+action+`"`)
+
+	r, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s failed with status %d", action, r.StatusCode)
+	}
+
+	if resp == nil {
+		return nil
+	}
+
+	return xml.Unmarshal(data, resp)
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -9,6 +9,7 @@ import (
 	"math"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -23,6 +24,10 @@ const (
 	rpcSync
 	rpcEagerSync
 	rpcFastForward
+	rpcRelay
+	rpcPing
+	rpcInfo
+	rpcKeepalive
 )
 
 const (
@@ -65,6 +70,27 @@ type NetworkTransport struct {
 
 	timeout     time.Duration
 	joinTimeout time.Duration
+
+	// bytesSent and bytesReceived count the traffic of all the connections.
+	// They are updated atomically.
+	bytesSent     uint64
+	bytesReceived uint64
+
+	// filter refuses the connections and RPCs of unwanted peers.
+	filter *Filter
+
+	// trafficHook, if set, is called with the traffic of each RPC.
+	trafficHook     func(RPCTraffic)
+	trafficHookLock sync.RWMutex
+
+	// routes maps the targets that cannot be reached directly to the address
+	// of the relay that forwards RPCs to them.
+	routes     map[string]string
+	routesLock sync.RWMutex
+
+	// relay forwards the RPCs that other peers send through this transport.
+	// Relayed RPCs are refused when it is nil.
+	relay func(target string, command interface{}) (interface{}, error)
 }
 
 type netConn struct {
@@ -81,6 +107,43 @@ func (n *netConn) Release() error {
 	return n.conn.Close()
 }
 
+// countingConn wraps a net.Conn to count the bytes read and written, in the
+// counters of the transport, and in its own counters, connSent and
+// connReceived, which are only used by the goroutine of the connection.
+type countingConn struct {
+	net.Conn
+	sent     *uint64
+	received *uint64
+
+	connSent     uint64
+	connReceived uint64
+}
+
+// Read implements the net.Conn interface.
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddUint64(c.received, uint64(n))
+	c.connReceived += uint64(n)
+	return n, err
+}
+
+// Write implements the net.Conn interface.
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddUint64(c.sent, uint64(n))
+	c.connSent += uint64(n)
+	return n, err
+}
+
+// connTraffic returns the bytes sent and received so far on a connection
+// created by countConn.
+func connTraffic(conn net.Conn) (sent, received uint64) {
+	if c, ok := conn.(*countingConn); ok {
+		return c.connSent, c.connReceived
+	}
+	return 0, 0
+}
+
 // NewNetworkTransport creates a new network transport with the given
 // StreamLayer. The maxPool controls how many connections we will pool (per
 // target). The timeout is used to apply I/O deadlines.
@@ -109,6 +172,9 @@ func NewNetworkTransport(
 		joinTimeout: joinTimeout,
 	}
 
+	// a Filter without rules cannot fail
+	trans.filter, _ = NewFilter(FilterRules{})
+
 	return trans
 }
 
@@ -126,6 +192,74 @@ func (n *NetworkTransport) Close() error {
 	return nil
 }
 
+// CloseConns implements the PooledTransport interface. The connections in use
+// are released normally when their RPC completes.
+func (n *NetworkTransport) CloseConns() {
+	n.connPoolLock.Lock()
+	defer n.connPoolLock.Unlock()
+
+	for target, conns := range n.connPool {
+		for _, conn := range conns {
+			conn.Release()
+		}
+		delete(n.connPool, target)
+	}
+}
+
+// Traffic implements the TrafficCounter interface.
+func (n *NetworkTransport) Traffic() (sent, received uint64) {
+	return atomic.LoadUint64(&n.bytesSent), atomic.LoadUint64(&n.bytesReceived)
+}
+
+// SetTrafficHook implements the PeerTrafficCounter interface.
+func (n *NetworkTransport) SetTrafficHook(hook func(RPCTraffic)) {
+	n.trafficHookLock.Lock()
+	defer n.trafficHookLock.Unlock()
+
+	n.trafficHook = hook
+}
+
+// reportTraffic calls the traffic hook, if any, with the bytes exchanged with a
+// peer on a connection since it had sent and received the given counts. msg is
+// the request or the response that identifies the peer.
+func (n *NetworkTransport) reportTraffic(conn net.Conn, inbound bool, msg interface{}, sent, received uint64) {
+	n.trafficHookLock.RLock()
+	hook := n.trafficHook
+	n.trafficHookLock.RUnlock()
+
+	if hook == nil {
+		return
+	}
+
+	id, ok := SenderID(msg)
+	if !ok {
+		return
+	}
+
+	connSent, connReceived := connTraffic(conn)
+
+	hook(RPCTraffic{
+		PeerID:   id,
+		Inbound:  inbound,
+		Sent:     connSent - sent,
+		Received: connReceived - received,
+	})
+}
+
+// Filter implements the FilteredTransport interface.
+func (n *NetworkTransport) Filter() *Filter {
+	return n.filter
+}
+
+// countConn wraps a connection to count its traffic.
+func (n *NetworkTransport) countConn(conn net.Conn) net.Conn {
+	return &countingConn{
+		Conn:     conn,
+		sent:     &n.bytesSent,
+		received: &n.bytesReceived,
+	}
+}
+
 // Consumer implements the Transport interface.
 func (n *NetworkTransport) Consumer() <-chan RPC {
 	return n.consumeCh
@@ -186,6 +320,7 @@ func (n *NetworkTransport) getConn(target string, timeout time.Duration) (*netCo
 	if err != nil {
 		return nil, err
 	}
+	conn = n.countConn(conn)
 
 	// Wrap the conn
 	netConn := &netConn{
@@ -237,10 +372,94 @@ func (n *NetworkTransport) Join(target string, args *JoinRequest, resp *JoinResp
 	return n.genericRPC(target, rpcJoin, n.joinTimeout, args, resp)
 }
 
+// Ping implements the Transport interface.
+func (n *NetworkTransport) Ping(target string, args *PingRequest, resp *PingResponse) error {
+	return n.genericRPC(target, rpcPing, n.timeout, args, resp)
+}
+
+// Info implements the Transport interface.
+func (n *NetworkTransport) Info(target string, args *InfoRequest, resp *InfoResponse) error {
+	return n.genericRPC(target, rpcInfo, n.timeout, args, resp)
+}
+
+// Keepalive implements the KeepaliveTransport interface. The keepalives to a
+// target reached through a relay check the connections to the relay.
+func (n *NetworkTransport) Keepalive(target string) (time.Duration, error) {
+	if via, relayed := n.route(target); relayed {
+		target = via
+	}
+
+	// Take all the pooled connections, so that they are checked one by one
+	n.connPoolLock.Lock()
+	conns := n.connPool[target]
+	delete(n.connPool, target)
+	n.connPoolLock.Unlock()
+
+	if len(conns) == 0 {
+		conn, err := n.getConn(target, n.timeout)
+		if err != nil {
+			return 0, err
+		}
+		conns = []*netConn{conn}
+	}
+
+	var rtt time.Duration
+	var err error
+	alive := false
+
+	for _, conn := range conns {
+		start := time.Now()
+		if kerr := n.keepalive(conn); kerr != nil {
+			n.logger.WithError(kerr).WithField("target", target).Debug("Evicting dead connection")
+			err = kerr
+			continue
+		}
+
+		if !alive {
+			rtt = time.Since(start)
+			alive = true
+		}
+
+		n.returnConn(conn)
+	}
+
+	if !alive {
+		return 0, err
+	}
+
+	return rtt, nil
+}
+
+// keepalive sends a keepalive on a connection. The connection is released if
+// it fails.
+func (n *NetworkTransport) keepalive(conn *netConn) error {
+	if n.timeout > 0 {
+		conn.conn.SetDeadline(time.Now().Add(n.timeout))
+	}
+
+	if err := sendRPC(conn, rpcKeepalive, &KeepaliveRequest{}); err != nil {
+		return err
+	}
+
+	// An error response still means that the peer answered
+	if canReturn, err := decodeResponse(conn, &KeepaliveResponse{}); !canReturn {
+		return err
+	}
+
+	return nil
+}
+
 // genericRPC handles a simple request/response RPC.
 func (n *NetworkTransport) genericRPC(target string, rpcType uint8, timeout time.Duration, args interface{}, resp interface{}) error {
+	// Targets with a route are reached through their relay
+	via, relayed := n.route(target)
+
 	// Get a conn
-	conn, err := n.getConn(target, timeout)
+	dialTarget := target
+	if relayed {
+		dialTarget = via
+	}
+	conn, err := n.getConn(dialTarget, timeout)
 	if err != nil {
 		return err
 	}
@@ -250,14 +469,22 @@ func (n *NetworkTransport) genericRPC(target string, rpcType uint8, timeout time
 		conn.conn.SetDeadline(time.Now().Add(timeout))
 	}
 
+	sent, received := connTraffic(conn.conn)
+
 	// Send the RPC
-	if err = sendRPC(conn, rpcType, args); err != nil {
+	if relayed {
+		err = sendRelayedRPC(conn, target, rpcType, args)
+	} else {
+		err = sendRPC(conn, rpcType, args)
+	}
+	if err != nil {
 		return err
 	}
 
 	// Decode the response
 	canReturn, err := decodeResponse(conn, resp)
 	if canReturn {
+		n.reportTraffic(conn.conn, false, resp, sent, received)
 		n.returnConn(conn)
 	}
 
@@ -321,13 +548,20 @@ func (n *NetworkTransport) Listen() {
 			n.logger.WithField("error", err).Error("Failed to accept connection")
 			continue
 		}
+
+		if !n.filter.AllowsAddr(conn.RemoteAddr()) {
+			n.logger.WithField("from", conn.RemoteAddr()).Debug("refused connection")
+			conn.Close()
+			continue
+		}
+
 		n.logger.WithFields(logrus.Fields{
 			"node": conn.LocalAddr(),
 			"from": conn.RemoteAddr(),
 		}).Debug("accepted connection")
 
 		// Handle the connection in dedicated routine
-		go n.handleConn(conn)
+		go n.handleConn(n.countConn(conn))
 	}
 }
 
@@ -340,7 +574,10 @@ func (n *NetworkTransport) handleConn(conn net.Conn) {
 	enc := json.NewEncoder(w)
 
 	for {
-		if err := n.handleCommand(r, dec, enc); err != nil {
+		sent, received := connTraffic(conn)
+
+		command, err := n.handleCommand(conn.RemoteAddr(), r, dec, enc)
+		if err != nil {
 
 			if err == ErrTransportShutdown {
 				n.logger.WithField("error", err).Warn("Failed to decode incoming command")
@@ -355,15 +592,29 @@ func (n *NetworkTransport) handleConn(conn net.Conn) {
 			n.logger.WithField("error", err).Error("Failed to flush response")
 			return
 		}
+
+		n.reportTraffic(conn, true, command, sent, received)
 	}
 }
 
-// handleCommand is used to decode and dispatch a single command.
-func (n *NetworkTransport) handleCommand(r *bufio.Reader, dec *json.Decoder, enc *json.Encoder) error {
+// handleCommand is used to decode and dispatch a single command, which it
+// returns. Commands from peers refused by the filter are answered with an error,
+// without reaching the consumer.
+func (n *NetworkTransport) handleCommand(from net.Addr, r *bufio.Reader, dec *json.Decoder, enc *json.Encoder) (interface{}, error) {
 	// Get the rpc type
 	rpcType, err := r.ReadByte()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// A relayed command is preceded by the header giving its target
+	var header *relayHeader
+	if rpcType == rpcRelay {
+		header = &relayHeader{}
+		if err := dec.Decode(header); err != nil {
+			return nil, err
+		}
+		rpcType = header.Type
 	}
 
 	// Create the RPC object
@@ -377,57 +628,97 @@ func (n *NetworkTransport) handleCommand(r *bufio.Reader, dec *json.Decoder, enc
 	case rpcSync:
 		var req SyncRequest
 		if err := dec.Decode(&req); err != nil {
-			return err
+			return nil, err
 		}
 		rpc.Command = &req
 	case rpcEagerSync:
 		var req EagerSyncRequest
 		if err := dec.Decode(&req); err != nil {
-			return err
+			return nil, err
 		}
 		rpc.Command = &req
 	case rpcFastForward:
 		var req FastForwardRequest
 		if err := dec.Decode(&req); err != nil {
-			return err
+			return nil, err
 		}
 		rpc.Command = &req
 	case rpcJoin:
 		var req JoinRequest
 		if err := dec.Decode(&req); err != nil {
-			return err
+			return nil, err
+		}
+		rpc.Command = &req
+	case rpcPing:
+		var req PingRequest
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		rpc.Command = &req
+	case rpcInfo:
+		var req InfoRequest
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		rpc.Command = &req
+	case rpcKeepalive:
+		var req KeepaliveRequest
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
 		}
 		rpc.Command = &req
 	default:
-		return fmt.Errorf("unknown rpc type %d", rpcType)
+		return nil, fmt.Errorf("unknown rpc type %d", rpcType)
+	}
+
+	// The filter can change while connections are open, so the address is
+	// checked again with every command.
+	if !n.filter.AllowsAddr(from) || !n.filter.AllowsCommand(rpc.Command) {
+		n.logger.WithField("from", from).Debug("refused rpc")
+		if err := enc.Encode("refused by filter"); err != nil {
+			return rpc.Command, err
+		}
+		return rpc.Command, enc.Encode(struct{}{})
+	}
+
+	// Keepalives are answered by the transport itself
+	if _, ok := rpc.Command.(*KeepaliveRequest); ok {
+		return rpc.Command, encodeResponse(enc, &KeepaliveResponse{}, nil)
+	}
+
+	if header != nil {
+		resp, err := n.relayCommand(header.Target, rpc.Command)
+		return rpc.Command, encodeResponse(enc, resp, err)
 	}
 
 	// Dispatch the RPC
 	select {
 	case n.consumeCh <- rpc:
 	case <-n.shutdownCh:
-		return ErrTransportShutdown
+		return rpc.Command, ErrTransportShutdown
 	}
 
 	// Wait for response
 	select {
 	case resp := <-respCh:
-		// Send the error first
-		respErr := ""
-		if resp.Error != nil {
-			respErr = resp.Error.Error()
-		}
-		if err := enc.Encode(respErr); err != nil {
-			return err
-		}
-
-		// Send the response
-		if err := enc.Encode(resp.Response); err != nil {
-			return err
-		}
+		return rpc.Command, encodeResponse(enc, resp.Response, resp.Error)
 	case <-n.shutdownCh:
-		return ErrTransportShutdown
+		return rpc.Command, ErrTransportShutdown
 	}
+}
 
-	return nil
+// encodeResponse sends the error of an RPC, or an empty string, followed by
+// its response.
+func encodeResponse(enc *json.Encoder, resp interface{}, err error) error {
+	// Send the error first
+	respErr := ""
+	if err != nil {
+		respErr = err.Error()
+	}
+	if err := enc.Encode(respErr); err != nil {
+		return err
+	}
+
+	// Send the response
+	return enc.Encode(resp)
 }
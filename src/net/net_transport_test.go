@@ -65,9 +65,6 @@ func TestNetworkTransport_PooledConn(t *testing.T) {
 				rpc.Respond(&resp, nil)
 			case <-stopCh:
 				return
-			case <-time.After(200 * time.Millisecond):
-				t.Log("Fatal Error TIMEOUT in TestNetworkTransport_PooledConn")
-				t.Fatal("TIMEOUT")
 			}
 		}
 	}()
@@ -111,3 +108,136 @@ func TestNetworkTransport_PooledConn(t *testing.T) {
 		t.Fatalf("Expected 3 pooled conns!")
 	}
 }
+
+func TestNetworkTransport_Traffic(t *testing.T) {
+	trans1, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, 2*time.Second, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go trans1.Listen()
+	defer trans1.Close()
+
+	trans2, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, 2*time.Second, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer trans2.Close()
+
+	go func() {
+		rpc := <-trans1.Consumer()
+		rpc.Respond(&SyncResponse{FromID: 1}, nil)
+	}()
+
+	var out SyncResponse
+	if err := trans2.Sync(trans1.LocalAddr(), &SyncRequest{FromID: 2}, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sent1, received1 := trans1.Traffic()
+	sent2, received2 := trans2.Traffic()
+
+	if sent2 == 0 || sent2 != received1 {
+		t.Fatalf("trans1 should have received the %d bytes sent by trans2, not %d", sent2, received1)
+	}
+
+	if sent1 == 0 || sent1 != received2 {
+		t.Fatalf("trans2 should have received the %d bytes sent by trans1, not %d", sent1, received2)
+	}
+}
+
+func TestNetworkTransport_TrafficHook(t *testing.T) {
+	trans1, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, 2*time.Second, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go trans1.Listen()
+	defer trans1.Close()
+
+	trans2, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, 2*time.Second, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer trans2.Close()
+
+	inbound := make(chan RPCTraffic, 1)
+	trans1.SetTrafficHook(func(rpc RPCTraffic) { inbound <- rpc })
+
+	outbound := make(chan RPCTraffic, 1)
+	trans2.SetTrafficHook(func(rpc RPCTraffic) { outbound <- rpc })
+
+	go func() {
+		rpc := <-trans1.Consumer()
+		rpc.Respond(&SyncResponse{FromID: 1}, nil)
+	}()
+
+	var out SyncResponse
+	if err := trans2.Sync(trans1.LocalAddr(), &SyncRequest{FromID: 2}, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	in := <-inbound
+	if in.PeerID != 2 || !in.Inbound {
+		t.Fatalf("trans1 should report an inbound RPC from peer 2, not %+v", in)
+	}
+
+	o := <-outbound
+	if o.PeerID != 1 || o.Inbound {
+		t.Fatalf("trans2 should report an outbound RPC to peer 1, not %+v", o)
+	}
+
+	if o.Sent == 0 || o.Sent != in.Received || in.Sent != o.Received {
+		t.Fatalf("The traffic reported by both ends should match: %+v, %+v", in, o)
+	}
+
+	if sent, received := trans2.Traffic(); sent != o.Sent || received != o.Received {
+		t.Fatalf("The RPC should account for all the traffic of trans2: %d/%d, not %d/%d", sent, received, o.Sent, o.Received)
+	}
+}
+
+func TestNetworkTransport_Keepalive(t *testing.T) {
+	trans1, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, 2*time.Second, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go trans1.Listen()
+	defer trans1.Close()
+
+	trans2, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, 2*time.Second, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer trans2.Close()
+
+	addr := trans1.LocalAddr()
+
+	// Keepalives are answered by the transport, without reaching the consumer
+	if _, err := trans2.Keepalive(addr); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	select {
+	case rpc := <-trans1.Consumer():
+		t.Fatalf("The keepalive should not reach the consumer: %#v", rpc.Command)
+	default:
+	}
+
+	if len(trans2.connPool[addr]) != 1 {
+		t.Fatal("The connection should be pooled")
+	}
+
+	// A dead connection is evicted
+	trans2.connPool[addr][0].conn.Close()
+
+	if _, err := trans2.Keepalive(addr); err == nil {
+		t.Fatal("The keepalive on a dead connection should fail")
+	}
+
+	if len(trans2.connPool[addr]) != 0 {
+		t.Fatal("The dead connection should be evicted")
+	}
+
+	// The next keepalive dials the peer again
+	if _, err := trans2.Keepalive(addr); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -0,0 +1,541 @@
+package net
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/sirupsen/logrus"
+)
+
+// Directions of the recorded RPCs.
+const (
+	// Inbound RPCs are sent to the node by its peers.
+	Inbound = "in"
+	// Outbound RPCs are sent by the node to its peers.
+	Outbound = "out"
+)
+
+// RPCRecord is an RPC recorded by a RecordingTransport. Type is the name of
+// the RPC: Sync, EagerSync, FastForward, Join, Ping or Info. Target is the address
+// of the peer of an outbound RPC. Time is the time at which the request was
+// sent or received.
+type RPCRecord struct {
+	Time      time.Time
+	Direction string
+	Target    string `json:",omitempty"`
+	Type      string
+	Request   json.RawMessage
+	Response  json.RawMessage `json:",omitempty"`
+	Error     string          `json:",omitempty"`
+}
+
+// rpcTypeName returns the name of the RPC of a request.
+func rpcTypeName(args interface{}) string {
+	switch args.(type) {
+	case *SyncRequest:
+		return "Sync"
+	case *EagerSyncRequest:
+		return "EagerSync"
+	case *FastForwardRequest:
+		return "FastForward"
+	case *JoinRequest:
+		return "Join"
+	case *PingRequest:
+		return "Ping"
+	case *InfoRequest:
+		return "Info"
+	default:
+		return fmt.Sprintf("%T", args)
+	}
+}
+
+// newRPCMessages returns an empty request and response for an RPC name.
+func newRPCMessages(rpcType string) (interface{}, interface{}, error) {
+	switch rpcType {
+	case "Sync":
+		return &SyncRequest{}, &SyncResponse{}, nil
+	case "EagerSync":
+		return &EagerSyncRequest{}, &EagerSyncResponse{}, nil
+	case "FastForward":
+		return &FastForwardRequest{}, &FastForwardResponse{}, nil
+	case "Join":
+		return &JoinRequest{}, &JoinResponse{}, nil
+	case "Ping":
+		return &PingRequest{}, &PingResponse{}, nil
+	case "Info":
+		return &InfoRequest{}, &InfoResponse{}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown RPC type %q", rpcType)
+	}
+}
+
+// ReadRPCRecords reads a file written by a RecordingTransport. The records are
+// sorted by time, since they are written when the RPCs complete.
+func ReadRPCRecords(path string) ([]RPCRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := []RPCRecord{}
+
+	dec := json.NewDecoder(bufio.NewReader(file))
+	for dec.More() {
+		var r RPCRecord
+		if err := dec.Decode(&r); err != nil {
+			return nil, fmt.Errorf("record %d: %v", len(records), err)
+		}
+		records = append(records, r)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	return records, nil
+}
+
+/*
+RecordingTransport wraps a Transport, and records every inbound and outbound
+RPC, with its response and the time of the request, to a file. The file
+contains one JSON RPCRecord per line. A ReplayTransport feeds the recording
+back into a node, to reproduce locally a bug observed on a remote node.
+
+The recording contains all the Events exchanged by the node, so it grows
+quickly, and should only be enabled while investigating a problem.
+*/
+type RecordingTransport struct {
+	Transport
+
+	consumeCh chan RPC
+
+	fileLock sync.Mutex
+	file     *os.File
+	enc      *json.Encoder
+
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+
+	logger *logrus.Entry
+}
+
+// NewRecordingTransport wraps a Transport in a RecordingTransport which appends
+// the RPCs to the file at path.
+func NewRecordingTransport(trans Transport, path string, logger *logrus.Entry) (*RecordingTransport, error) {
+	if logger == nil {
+		log := logrus.New()
+		log.Level = logrus.DebugLevel
+		logger = logrus.NewEntry(log)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RecordingTransport{
+		Transport:  trans,
+		consumeCh:  make(chan RPC),
+		file:       file,
+		enc:        json.NewEncoder(file),
+		shutdownCh: make(chan struct{}),
+		logger:     logger,
+	}
+
+	go r.consume()
+
+	return r, nil
+}
+
+// consume delivers the RPCs of the wrapped transport to the Consumer, and
+// records them when the node responds.
+func (r *RecordingTransport) consume() {
+	for {
+		select {
+		case rpc := <-r.Transport.Consumer():
+			respCh := make(chan RPCResponse, 1)
+
+			go r.recordInbound(rpc, respCh, time.Now())
+
+			select {
+			case r.consumeCh <- RPC{Command: rpc.Command, RespChan: respCh}:
+			case <-r.shutdownCh:
+				return
+			}
+		case <-r.shutdownCh:
+			return
+		}
+	}
+}
+
+// recordInbound waits for the response to an inbound RPC, records it, and
+// passes it on to the peer.
+func (r *RecordingTransport) recordInbound(rpc RPC, respCh chan RPCResponse, start time.Time) {
+	select {
+	case resp := <-respCh:
+		r.record(Inbound, "", rpc.Command, resp.Response, resp.Error, start)
+		rpc.RespChan <- resp
+	case <-r.shutdownCh:
+	}
+}
+
+// record appends an RPC to the file. Failures are only logged, because they
+// must not affect the node.
+func (r *RecordingTransport) record(direction string, target string, args interface{}, resp interface{}, rpcErr error, start time.Time) {
+	rec := RPCRecord{
+		Time:      start,
+		Direction: direction,
+		Target:    target,
+		Type:      rpcTypeName(args),
+	}
+
+	var err error
+
+	if rec.Request, err = json.Marshal(args); err != nil {
+		r.logger.WithError(err).Warn("Encoding recorded request")
+		return
+	}
+
+	if rpcErr != nil {
+		rec.Error = rpcErr.Error()
+	} else if resp != nil {
+		if rec.Response, err = json.Marshal(resp); err != nil {
+			r.logger.WithError(err).Warn("Encoding recorded response")
+			return
+		}
+	}
+
+	r.fileLock.Lock()
+	defer r.fileLock.Unlock()
+
+	if err := r.enc.Encode(rec); err != nil {
+		r.logger.WithError(err).Warn("Recording RPC")
+	}
+}
+
+// Consumer implements the Transport interface.
+func (r *RecordingTransport) Consumer() <-chan RPC {
+	return r.consumeCh
+}
+
+// Sync implements the Transport interface.
+func (r *RecordingTransport) Sync(target string, args *SyncRequest, resp *SyncResponse) error {
+	start := time.Now()
+	err := r.Transport.Sync(target, args, resp)
+	r.record(Outbound, target, args, resp, err, start)
+	return err
+}
+
+// EagerSync implements the Transport interface.
+func (r *RecordingTransport) EagerSync(target string, args *EagerSyncRequest, resp *EagerSyncResponse) error {
+	start := time.Now()
+	err := r.Transport.EagerSync(target, args, resp)
+	r.record(Outbound, target, args, resp, err, start)
+	return err
+}
+
+// FastForward implements the Transport interface.
+func (r *RecordingTransport) FastForward(target string, args *FastForwardRequest, resp *FastForwardResponse) error {
+	start := time.Now()
+	err := r.Transport.FastForward(target, args, resp)
+	r.record(Outbound, target, args, resp, err, start)
+	return err
+}
+
+// Join implements the Transport interface.
+func (r *RecordingTransport) Join(target string, args *JoinRequest, resp *JoinResponse) error {
+	start := time.Now()
+	err := r.Transport.Join(target, args, resp)
+	r.record(Outbound, target, args, resp, err, start)
+	return err
+}
+
+// Ping implements the Transport interface.
+func (r *RecordingTransport) Ping(target string, args *PingRequest, resp *PingResponse) error {
+	start := time.Now()
+	err := r.Transport.Ping(target, args, resp)
+	r.record(Outbound, target, args, resp, err, start)
+	return err
+}
+
+// Info implements the Transport interface.
+func (r *RecordingTransport) Info(target string, args *InfoRequest, resp *InfoResponse) error {
+	start := time.Now()
+	err := r.Transport.Info(target, args, resp)
+	r.record(Outbound, target, args, resp, err, start)
+	return err
+}
+
+// Traffic implements the TrafficCounter interface, if the wrapped transport
+// counts its traffic.
+func (r *RecordingTransport) Traffic() (sent, received uint64) {
+	if tc, ok := r.Transport.(TrafficCounter); ok {
+		return tc.Traffic()
+	}
+	return 0, 0
+}
+
+// SetTrafficHook implements the PeerTrafficCounter interface, if the wrapped
+// transport reports the traffic of its RPCs.
+func (r *RecordingTransport) SetTrafficHook(hook func(RPCTraffic)) {
+	if pt, ok := r.Transport.(PeerTrafficCounter); ok {
+		pt.SetTrafficHook(hook)
+	}
+}
+
+// Keepalive implements the KeepaliveTransport interface, if the wrapped
+// transport sends keepalives. Keepalives are not recorded.
+func (r *RecordingTransport) Keepalive(target string) (time.Duration, error) {
+	if kt, ok := r.Transport.(KeepaliveTransport); ok {
+		return kt.Keepalive(target)
+	}
+	return 0, fmt.Errorf("keepalives not supported")
+}
+
+// CloseConns implements the PooledTransport interface, if the wrapped
+// transport pools its connections.
+func (r *RecordingTransport) CloseConns() {
+	if pt, ok := r.Transport.(PooledTransport); ok {
+		pt.CloseConns()
+	}
+}
+
+// Filter implements the FilteredTransport interface. It returns nil if the
+// wrapped transport does not support filtering.
+func (r *RecordingTransport) Filter() *Filter {
+	if ft, ok := r.Transport.(FilteredTransport); ok {
+		return ft.Filter()
+	}
+	return nil
+}
+
+// Close implements the Transport interface. It closes the wrapped transport
+// and the file.
+func (r *RecordingTransport) Close() error {
+	r.shutdownOnce.Do(func() {
+		close(r.shutdownCh)
+
+		r.fileLock.Lock()
+		r.file.Close()
+		r.fileLock.Unlock()
+	})
+	return r.Transport.Close()
+}
+
+/*
+ReplayTransport is a Transport which replays a recording of a
+RecordingTransport, instead of communicating with other nodes. A node created
+with a ReplayTransport, from the same initial state as the recorded node,
+receives the recorded inbound RPCs, in order and at the recorded pace. Its
+outbound RPCs receive the recorded responses of the RPCs of the same type to
+the same target, in order, or an error once they are used up.
+
+The node does not necessarily take the same decisions as the recorded node,
+since the timers and the selection of the gossip peers are random, but the
+Events it receives are the same, so bugs in the processing of the Events are
+reproduced.
+*/
+type ReplayTransport struct {
+	localAddr string
+
+	inbound []RPCRecord
+
+	outboundLock sync.Mutex
+	outbound     map[string][]RPCRecord
+
+	consumeCh chan RPC
+	clock     common.Clock
+
+	doneCh chan struct{}
+
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+
+	logger *logrus.Entry
+}
+
+// NewReplayTransport creates a ReplayTransport from the recording at path.
+// localAddr is the address of the recorded node.
+func NewReplayTransport(path string, localAddr string, logger *logrus.Entry) (*ReplayTransport, error) {
+	if logger == nil {
+		log := logrus.New()
+		log.Level = logrus.DebugLevel
+		logger = logrus.NewEntry(log)
+	}
+
+	records, err := ReadRPCRecords(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ReplayTransport{
+		localAddr:  localAddr,
+		outbound:   make(map[string][]RPCRecord),
+		consumeCh:  make(chan RPC),
+		clock:      common.RealClock{},
+		doneCh:     make(chan struct{}),
+		shutdownCh: make(chan struct{}),
+		logger:     logger,
+	}
+
+	for _, rec := range records {
+		switch rec.Direction {
+		case Inbound:
+			r.inbound = append(r.inbound, rec)
+		case Outbound:
+			key := rec.Type + "/" + rec.Target
+			r.outbound[key] = append(r.outbound[key], rec)
+		default:
+			return nil, fmt.Errorf("unknown RPC direction %q", rec.Direction)
+		}
+	}
+
+	return r, nil
+}
+
+// SetClock sets the clock which paces the inbound RPCs. Tests use a
+// common.FakeClock to replay a recording without waiting.
+func (r *ReplayTransport) SetClock(clock common.Clock) {
+	r.clock = clock
+}
+
+// Done returns a channel which is closed when all the inbound RPCs have been
+// delivered and answered, or when the transport is closed.
+func (r *ReplayTransport) Done() <-chan struct{} {
+	return r.doneCh
+}
+
+// Listen implements the Transport interface. It starts delivering the inbound
+// RPCs to the Consumer.
+func (r *ReplayTransport) Listen() {
+	go r.replay()
+}
+
+// replay delivers the inbound RPCs, with the same delays between them as in
+// the recording, and waits for the response to each one before delivering the
+// next.
+func (r *ReplayTransport) replay() {
+	defer close(r.doneCh)
+
+	for i, rec := range r.inbound {
+		if i > 0 {
+			select {
+			case <-r.clock.After(rec.Time.Sub(r.inbound[i-1].Time)):
+			case <-r.shutdownCh:
+				return
+			}
+		}
+
+		args, _, err := newRPCMessages(rec.Type)
+		if err != nil {
+			r.logger.WithError(err).Warn("Skipping recorded RPC")
+			continue
+		}
+
+		if err := json.Unmarshal(rec.Request, args); err != nil {
+			r.logger.WithError(err).Warn("Skipping recorded RPC")
+			continue
+		}
+
+		respCh := make(chan RPCResponse, 1)
+
+		select {
+		case r.consumeCh <- RPC{Command: args, RespChan: respCh}:
+		case <-r.shutdownCh:
+			return
+		}
+
+		select {
+		case resp := <-respCh:
+			r.logger.WithFields(logrus.Fields{
+				"index": i,
+				"type":  rec.Type,
+				"error": resp.Error,
+			}).Debug("Replayed RPC")
+		case <-r.shutdownCh:
+			return
+		}
+	}
+}
+
+// respond decodes the next recorded response of an outbound RPC into resp.
+func (r *ReplayTransport) respond(target string, args interface{}, resp interface{}) error {
+	key := rpcTypeName(args) + "/" + target
+
+	r.outboundLock.Lock()
+	defer r.outboundLock.Unlock()
+
+	recs := r.outbound[key]
+	if len(recs) == 0 {
+		return fmt.Errorf("no recorded response to %s", key)
+	}
+
+	rec := recs[0]
+	r.outbound[key] = recs[1:]
+
+	if rec.Error != "" {
+		return fmt.Errorf("%s", rec.Error)
+	}
+
+	return json.Unmarshal(rec.Response, resp)
+}
+
+// Consumer implements the Transport interface.
+func (r *ReplayTransport) Consumer() <-chan RPC {
+	return r.consumeCh
+}
+
+// LocalAddr implements the Transport interface.
+func (r *ReplayTransport) LocalAddr() string {
+	return r.localAddr
+}
+
+// AdvertiseAddr implements the Transport interface.
+func (r *ReplayTransport) AdvertiseAddr() string {
+	return r.localAddr
+}
+
+// Sync implements the Transport interface.
+func (r *ReplayTransport) Sync(target string, args *SyncRequest, resp *SyncResponse) error {
+	return r.respond(target, args, resp)
+}
+
+// EagerSync implements the Transport interface.
+func (r *ReplayTransport) EagerSync(target string, args *EagerSyncRequest, resp *EagerSyncResponse) error {
+	return r.respond(target, args, resp)
+}
+
+// FastForward implements the Transport interface.
+func (r *ReplayTransport) FastForward(target string, args *FastForwardRequest, resp *FastForwardResponse) error {
+	return r.respond(target, args, resp)
+}
+
+// Join implements the Transport interface.
+func (r *ReplayTransport) Join(target string, args *JoinRequest, resp *JoinResponse) error {
+	return r.respond(target, args, resp)
+}
+
+// Ping implements the Transport interface.
+func (r *ReplayTransport) Ping(target string, args *PingRequest, resp *PingResponse) error {
+	return r.respond(target, args, resp)
+}
+
+// Info implements the Transport interface.
+func (r *ReplayTransport) Info(target string, args *InfoRequest, resp *InfoResponse) error {
+	return r.respond(target, args, resp)
+}
+
+// Close implements the Transport interface.
+func (r *ReplayTransport) Close() error {
+	r.shutdownOnce.Do(func() {
+		close(r.shutdownCh)
+	})
+	return nil
+}
@@ -0,0 +1,101 @@
+package net
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpc.log")
+
+	addr1, trans1 := NewInmemTransport("")
+	addr2, trans2 := NewInmemTransport("")
+	trans1.Connect(addr2, trans2)
+	trans2.Connect(addr1, trans1)
+
+	recorder, err := NewRecordingTransport(trans1, path, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for rpc := range recorder.Consumer() {
+			req := rpc.Command.(*SyncRequest)
+			rpc.Respond(&SyncResponse{FromID: 1, Known: req.Known}, nil)
+		}
+	}()
+
+	go func() {
+		for rpc := range trans2.Consumer() {
+			rpc.Respond(&PingResponse{FromID: 2}, nil)
+		}
+	}()
+
+	// An inbound Sync and an outbound Ping
+	syncReq := &SyncRequest{FromID: 2, Known: map[uint32]int{0: 5, 1: 7}}
+	var syncResp SyncResponse
+	if err := trans2.Sync(addr1, syncReq, &syncResp); err != nil {
+		t.Fatal(err)
+	}
+
+	var pingResp PingResponse
+	if err := recorder.Ping(addr2, &PingRequest{FromID: 1}, &pingResp); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.Close()
+
+	records, err := ReadRPCRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("There should be 2 records, not %d", len(records))
+	}
+	if r := records[0]; r.Direction != Inbound || r.Type != "Sync" {
+		t.Fatalf("The first record should be the inbound Sync, not %s %s", r.Direction, r.Type)
+	}
+	if r := records[1]; r.Direction != Outbound || r.Type != "Ping" || r.Target != addr2 {
+		t.Fatalf("The second record should be the outbound Ping to %s, not %s %s to %s", addr2, r.Direction, r.Type, r.Target)
+	}
+
+	replay, err := NewReplayTransport(path, addr1, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	replay.SetClock(common.NewFakeClock(time.Unix(0, 0)))
+	replay.Listen()
+
+	// The recorded inbound Sync is delivered to the consumer
+	rpc := <-replay.Consumer()
+	req, ok := rpc.Command.(*SyncRequest)
+	if !ok {
+		t.Fatalf("The replayed RPC should be a SyncRequest, not %T", rpc.Command)
+	}
+	if !reflect.DeepEqual(req, syncReq) {
+		t.Fatalf("The replayed request should be %#v, not %#v", syncReq, req)
+	}
+	rpc.Respond(&SyncResponse{FromID: 1}, nil)
+
+	<-replay.Done()
+
+	// The outbound Ping receives the recorded response, once
+	var replayedPing PingResponse
+	if err := replay.Ping(addr2, &PingRequest{FromID: 1}, &replayedPing); err != nil {
+		t.Fatal(err)
+	}
+	if replayedPing.FromID != 2 {
+		t.Fatalf("The replayed response should come from 2, not %d", replayedPing.FromID)
+	}
+
+	if err := replay.Ping(addr2, &PingRequest{FromID: 1}, &replayedPing); err == nil {
+		t.Fatal("The recorded responses should be used up")
+	}
+}
@@ -0,0 +1,346 @@
+package net
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrRelayDisabled is returned to the peers that send relayed RPCs to a
+	// transport which does not relay them.
+	ErrRelayDisabled = errors.New("relaying is disabled")
+)
+
+// RoutedTransport is implemented by the transports that can reach some
+// targets through a relay, when they cannot reach them directly.
+type RoutedTransport interface {
+	// SetRoutes replaces the routes of the transport. They map the targets
+	// to the addresses of the relays that forward RPCs to them.
+	SetRoutes(routes map[string]string)
+}
+
+// relayHeader precedes a relayed RPC. It gives the target to which the relay
+// forwards the RPC, and the type of the RPC.
+type relayHeader struct {
+	Target string
+	Type   uint8
+}
+
+// ParseRoutes parses a comma-separated list of target=relay pairs, like
+// "0X04AB...=10.0.0.1:1337", where target is the address of a peer that cannot
+// be reached directly, and relay the address of the node that forwards RPCs to
+// it.
+func ParseRoutes(list string) (map[string]string, error) {
+	routes := make(map[string]string)
+	for _, entry := range ParseFilterList(list) {
+		parts := strings.Split(entry, "=")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("route %q is not a target=relay pair", entry)
+		}
+		routes[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return routes, nil
+}
+
+// SetRoutes implements the RoutedTransport interface.
+func (n *NetworkTransport) SetRoutes(routes map[string]string) {
+	n.routesLock.Lock()
+	defer n.routesLock.Unlock()
+
+	n.routes = routes
+}
+
+// route returns the address of the relay through which to reach a target,
+// if it has a route.
+func (n *NetworkTransport) route(target string) (string, bool) {
+	n.routesLock.RLock()
+	defer n.routesLock.RUnlock()
+
+	via, ok := n.routes[target]
+	return via, ok
+}
+
+// sendRelayedRPC is used to encode and send an RPC to a relay, which forwards
+// it to the target.
+func sendRelayedRPC(conn *netConn, target string, rpcType uint8, args interface{}) error {
+	// Write the request type
+	if err := conn.w.WriteByte(rpcRelay); err != nil {
+		conn.Release()
+		return err
+	}
+
+	// Send the header and the request
+	header := relayHeader{
+		Target: target,
+		Type:   rpcType,
+	}
+	if err := conn.enc.Encode(header); err != nil {
+		conn.Release()
+		return err
+	}
+	if err := conn.enc.Encode(args); err != nil {
+		conn.Release()
+		return err
+	}
+
+	// Flush
+	if err := conn.w.Flush(); err != nil {
+		conn.Release()
+		return err
+	}
+	return nil
+}
+
+// relayCommand forwards a relayed command to its target, and returns the
+// response of the target.
+func (n *NetworkTransport) relayCommand(target string, command interface{}) (interface{}, error) {
+	if n.relay == nil {
+		return struct{}{}, ErrRelayDisabled
+	}
+	return n.relay(target, command)
+}
+
+/*
+RelayTransport bridges a TCP and a WebRTC NetworkTransport within a single
+node, such that the node is reachable by the peers of both networks, and
+relays the RPCs of the peers that cannot reach each other directly, like the
+nodes of a WebRTC mesh and the nodes of a datacenter which only accept TCP
+connections.
+
+A peer reaches a target through the relay with a route, given to the
+SetRoutes method of its own transport, from the address of the target to the
+address of the relay on the network of the peer. The relay forwards the RPC to
+the target over TCP if the address of the target is an IP:PORT or HOST:PORT
+address, and over WebRTC otherwise, since WebRTC addresses are public keys. The
+routes of the relay itself apply to the RPCs it forwards, so relays can be
+chained.
+
+The RPCs that peers send to the relay itself, through either transport, are
+delivered to the same Consumer. The LocalAddr and AdvertiseAddr are those of
+the primary transport, and both transports share its Filter.
+*/
+type RelayTransport struct {
+	tcp     *NetworkTransport
+	webRTC  *NetworkTransport
+	primary *NetworkTransport
+
+	consumeCh chan RPC
+
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+
+	logger *logrus.Entry
+}
+
+// NewRelayTransport creates a RelayTransport from a TCP and a WebRTC
+// NetworkTransport. The primary transport is the WebRTC one if webRTCPrimary is
+// true, and the TCP one otherwise.
+func NewRelayTransport(
+	tcp *NetworkTransport,
+	webRTC *NetworkTransport,
+	webRTCPrimary bool,
+	logger *logrus.Entry,
+) *RelayTransport {
+
+	if logger == nil {
+		log := logrus.New()
+		log.Level = logrus.DebugLevel
+		logger = logrus.NewEntry(log)
+	}
+
+	r := &RelayTransport{
+		tcp:        tcp,
+		webRTC:     webRTC,
+		primary:    tcp,
+		consumeCh:  make(chan RPC),
+		shutdownCh: make(chan struct{}),
+		logger:     logger,
+	}
+
+	if webRTCPrimary {
+		r.primary = webRTC
+	}
+
+	tcp.filter = r.primary.filter
+	webRTC.filter = r.primary.filter
+
+	tcp.relay = r.forward
+	webRTC.relay = r.forward
+
+	go r.consume(tcp)
+	go r.consume(webRTC)
+
+	return r
+}
+
+// consume delivers the RPCs of a transport to the Consumer of the relay.
+func (r *RelayTransport) consume(trans *NetworkTransport) {
+	for {
+		select {
+		case rpc := <-trans.Consumer():
+			select {
+			case r.consumeCh <- rpc:
+			case <-r.shutdownCh:
+				return
+			}
+		case <-r.shutdownCh:
+			return
+		}
+	}
+}
+
+// transportFor returns the transport that reaches a target: TCP for network
+// addresses, and WebRTC for public keys.
+func (r *RelayTransport) transportFor(target string) *NetworkTransport {
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return r.tcp
+	}
+	return r.webRTC
+}
+
+// forward sends a relayed command to its target, and returns the response.
+func (r *RelayTransport) forward(target string, command interface{}) (interface{}, error) {
+	trans := r.transportFor(target)
+
+	r.logger.WithFields(logrus.Fields{
+		"target":  target,
+		"command": fmt.Sprintf("%T", command),
+	}).Debug("Relaying RPC")
+
+	switch cmd := command.(type) {
+	case *SyncRequest:
+		var resp SyncResponse
+		err := trans.Sync(target, cmd, &resp)
+		return &resp, err
+	case *EagerSyncRequest:
+		var resp EagerSyncResponse
+		err := trans.EagerSync(target, cmd, &resp)
+		return &resp, err
+	case *FastForwardRequest:
+		var resp FastForwardResponse
+		err := trans.FastForward(target, cmd, &resp)
+		return &resp, err
+	case *JoinRequest:
+		var resp JoinResponse
+		err := trans.Join(target, cmd, &resp)
+		return &resp, err
+	case *PingRequest:
+		var resp PingResponse
+		err := trans.Ping(target, cmd, &resp)
+		return &resp, err
+	case *InfoRequest:
+		var resp InfoResponse
+		err := trans.Info(target, cmd, &resp)
+		return &resp, err
+	default:
+		return struct{}{}, fmt.Errorf("cannot relay %T", command)
+	}
+}
+
+// Listen implements the Transport interface. It listens on both transports.
+func (r *RelayTransport) Listen() {
+	go r.tcp.Listen()
+	r.webRTC.Listen()
+}
+
+// Consumer implements the Transport interface.
+func (r *RelayTransport) Consumer() <-chan RPC {
+	return r.consumeCh
+}
+
+// LocalAddr implements the Transport interface.
+func (r *RelayTransport) LocalAddr() string {
+	return r.primary.LocalAddr()
+}
+
+// AdvertiseAddr implements the Transport interface.
+func (r *RelayTransport) AdvertiseAddr() string {
+	return r.primary.AdvertiseAddr()
+}
+
+// Addrs returns the advertise addresses of the TCP and WebRTC transports.
+func (r *RelayTransport) Addrs() (tcp string, webRTC string) {
+	return r.tcp.AdvertiseAddr(), r.webRTC.AdvertiseAddr()
+}
+
+// Sync implements the Transport interface.
+func (r *RelayTransport) Sync(target string, args *SyncRequest, resp *SyncResponse) error {
+	return r.transportFor(target).Sync(target, args, resp)
+}
+
+// EagerSync implements the Transport interface.
+func (r *RelayTransport) EagerSync(target string, args *EagerSyncRequest, resp *EagerSyncResponse) error {
+	return r.transportFor(target).EagerSync(target, args, resp)
+}
+
+// FastForward implements the Transport interface.
+func (r *RelayTransport) FastForward(target string, args *FastForwardRequest, resp *FastForwardResponse) error {
+	return r.transportFor(target).FastForward(target, args, resp)
+}
+
+// Join implements the Transport interface.
+func (r *RelayTransport) Join(target string, args *JoinRequest, resp *JoinResponse) error {
+	return r.transportFor(target).Join(target, args, resp)
+}
+
+// Ping implements the Transport interface.
+func (r *RelayTransport) Ping(target string, args *PingRequest, resp *PingResponse) error {
+	return r.transportFor(target).Ping(target, args, resp)
+}
+
+// Info implements the Transport interface.
+func (r *RelayTransport) Info(target string, args *InfoRequest, resp *InfoResponse) error {
+	return r.transportFor(target).Info(target, args, resp)
+}
+
+// SetRoutes implements the RoutedTransport interface. The routes apply to
+// both transports.
+func (r *RelayTransport) SetRoutes(routes map[string]string) {
+	r.tcp.SetRoutes(routes)
+	r.webRTC.SetRoutes(routes)
+}
+
+// Traffic implements the TrafficCounter interface.
+func (r *RelayTransport) Traffic() (sent, received uint64) {
+	tcpSent, tcpReceived := r.tcp.Traffic()
+	webRTCSent, webRTCReceived := r.webRTC.Traffic()
+	return tcpSent + webRTCSent, tcpReceived + webRTCReceived
+}
+
+// SetTrafficHook implements the PeerTrafficCounter interface. The hook is set
+// on both transports.
+func (r *RelayTransport) SetTrafficHook(hook func(RPCTraffic)) {
+	r.tcp.SetTrafficHook(hook)
+	r.webRTC.SetTrafficHook(hook)
+}
+
+// Keepalive implements the KeepaliveTransport interface.
+func (r *RelayTransport) Keepalive(target string) (time.Duration, error) {
+	return r.transportFor(target).Keepalive(target)
+}
+
+// CloseConns implements the PooledTransport interface.
+func (r *RelayTransport) CloseConns() {
+	r.tcp.CloseConns()
+	r.webRTC.CloseConns()
+}
+
+// Filter implements the FilteredTransport interface.
+func (r *RelayTransport) Filter() *Filter {
+	return r.primary.Filter()
+}
+
+// Close implements the Transport interface.
+func (r *RelayTransport) Close() error {
+	r.shutdownOnce.Do(func() {
+		close(r.shutdownCh)
+	})
+	r.tcp.Close()
+	return r.webRTC.Close()
+}
@@ -0,0 +1,100 @@
+package net
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes(" 0X04AB=10.0.0.1:1337, 10.0.0.2:1337=0X04CD ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 || routes["0X04AB"] != "10.0.0.1:1337" || routes["10.0.0.2:1337"] != "0X04CD" {
+		t.Fatalf("Unexpected routes %v", routes)
+	}
+
+	for _, list := range []string{"0X04AB", "=10.0.0.1:1337", "0X04AB=", "a=b=c"} {
+		if _, err := ParseRoutes(list); err == nil {
+			t.Fatalf("ParseRoutes(%q) should fail", list)
+		}
+	}
+}
+
+func TestRelayTransport(t *testing.T) {
+	newTransport := func() *NetworkTransport {
+		trans, err := NewTCPTransport("127.0.0.1:0", "", 2, time.Second, time.Second, common.NewTestEntry(t, common.TestLogLevel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trans
+	}
+
+	// The relay bridges two transports. Both are TCP here, but the target has
+	// a TCP address, so the relay reaches it through its TCP side.
+	relayTCP := newTransport()
+	relayOther := newTransport()
+	relay := NewRelayTransport(relayTCP, relayOther, false, common.NewTestEntry(t, common.TestLogLevel))
+	defer relay.Close()
+	go relay.Listen()
+
+	target := newTransport()
+	defer target.Close()
+	go target.Listen()
+
+	sender := newTransport()
+	defer sender.Close()
+	go sender.Listen()
+
+	go func() {
+		for rpc := range target.Consumer() {
+			switch req := rpc.Command.(type) {
+			case *SyncRequest:
+				rpc.Respond(&SyncResponse{FromID: 2, Known: req.Known}, nil)
+			case *PingRequest:
+				rpc.Respond(&PingResponse{FromID: 2}, nil)
+			}
+		}
+	}()
+
+	// Without a route, a relayed RPC is refused by the target, which does not
+	// relay
+	sender.SetRoutes(map[string]string{"unknown": target.LocalAddr()})
+	var resp SyncResponse
+	if err := sender.Sync("unknown", &SyncRequest{FromID: 1}, &resp); err == nil || err.Error() != ErrRelayDisabled.Error() {
+		t.Fatalf("A transport without relay should refuse relayed RPCs, not %v", err)
+	}
+
+	sender.SetRoutes(map[string]string{target.LocalAddr(): relayOther.LocalAddr()})
+	args := &SyncRequest{FromID: 1, Known: map[uint32]int{1: 3}}
+	if err := sender.Sync(target.LocalAddr(), args, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.FromID != 2 || resp.Known[1] != 3 {
+		t.Fatalf("The response of the target should be relayed, not %+v", resp)
+	}
+
+	var pingResp PingResponse
+	if err := sender.Ping(target.LocalAddr(), &PingRequest{FromID: 1}, &pingResp); err != nil {
+		t.Fatal(err)
+	}
+	if pingResp.FromID != 2 {
+		t.Fatalf("Pings should be relayed too, not %+v", pingResp)
+	}
+
+	// The RPCs to the relay itself reach its Consumer, from both sides
+	go func() {
+		rpc := <-relay.Consumer()
+		rpc.Respond(&EagerSyncResponse{FromID: 3, Success: true}, nil)
+	}()
+	sender.SetRoutes(nil)
+	var eagerResp EagerSyncResponse
+	if err := sender.EagerSync(relayOther.LocalAddr(), &EagerSyncRequest{FromID: 1}, &eagerResp); err != nil {
+		t.Fatal(err)
+	}
+	if !eagerResp.Success || eagerResp.FromID != 3 {
+		t.Fatalf("The relay should respond to its own RPCs, not %+v", eagerResp)
+	}
+}
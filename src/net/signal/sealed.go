@@ -0,0 +1,247 @@
+package signal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/pion/webrtc/v2"
+)
+
+// sealedPrefix marks the SDPs encrypted by a SealedSignal.
+const sealedPrefix = "babble-sealed:"
+
+// sealedMaxAge is the age beyond which a sealed SDP is refused, so that the
+// signaling server cannot replay old offers and answers.
+const sealedMaxAge = time.Minute
+
+// SealedSignal wraps a Signal to encrypt the SDP offers and answers end-to-end,
+// so that the operator of the signaling server can neither read nor tamper with
+// them. The IDs of the peers are the hex-encoded public keys of their Babble
+// identities, as in the peer set, so no other key exchange is needed: each SDP
+// is encrypted with AES-256-GCM under a key derived from the ECDH secret of the
+// sender's private key and the recipient's public key, which also
+// authenticates the sender.
+type SealedSignal struct {
+	Signal
+
+	key      *ecdsa.PrivateKey
+	consumer chan OfferPromise
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSealedSignal wraps a Signal, whose ID must be the hex-encoded public key
+// of the private key.
+func NewSealedSignal(s Signal, key *ecdsa.PrivateKey) *SealedSignal {
+	sealed := &SealedSignal{
+		Signal:   s,
+		key:      key,
+		consumer: make(chan OfferPromise),
+		closeCh:  make(chan struct{}),
+	}
+
+	go sealed.unsealOffers()
+
+	return sealed
+}
+
+// Consumer implements the Signal interface. The offers are decrypted.
+func (s *SealedSignal) Consumer() <-chan OfferPromise {
+	return s.consumer
+}
+
+// Offer implements the Signal interface. The offer is encrypted for the target
+// and the answer decrypted.
+func (s *SealedSignal) Offer(target string, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	sealed, err := s.seal(target, offer)
+	if err != nil {
+		return nil, err
+	}
+
+	answer, err := s.Signal.Offer(target, sealed)
+	if err != nil || answer == nil {
+		return answer, err
+	}
+
+	opened, err := s.open(target, *answer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &opened, nil
+}
+
+// Close implements the Signal interface.
+func (s *SealedSignal) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+	})
+	return s.Signal.Close()
+}
+
+// unsealOffers decrypts the offers of the wrapped Signal and forwards them to
+// the consumer, until the Signal is closed. The offers which cannot be
+// decrypted are refused. The answers are encrypted for the sender.
+func (s *SealedSignal) unsealOffers() {
+	for {
+		select {
+		case p := <-s.Signal.Consumer():
+			offer, err := s.open(p.From, p.Offer)
+			if err != nil {
+				p.Respond(nil, err)
+				continue
+			}
+
+			respCh := make(chan OfferPromiseResponse, 1)
+
+			select {
+			case s.consumer <- OfferPromise{From: p.From, Offer: offer, RespChan: respCh}:
+			case <-s.closeCh:
+				return
+			}
+
+			go func(p OfferPromise) {
+				resp := <-respCh
+				if resp.Error != nil || resp.Answer == nil {
+					p.Respond(resp.Answer, resp.Error)
+					return
+				}
+
+				answer, err := s.seal(p.From, *resp.Answer)
+				if err != nil {
+					p.Respond(nil, err)
+					return
+				}
+
+				p.Respond(&answer, nil)
+			}(p)
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+// sealedSDP is the plaintext of a sealed SDP.
+type sealedSDP struct {
+	SDP  string `json:"sdp"`
+	Time int64  `json:"time"`
+}
+
+// seal encrypts the SDP of a SessionDescription for a peer.
+func (s *SealedSignal) seal(peer string, desc webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	aead, err := s.aead(peer)
+	if err != nil {
+		return desc, err
+	}
+
+	plaintext, err := json.Marshal(sealedSDP{SDP: desc.SDP, Time: time.Now().UnixNano()})
+	if err != nil {
+		return desc, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return desc, err
+	}
+
+	ciphertext := aead.Seal(nonce, nonce, plaintext, sealedData(s.ID(), peer, desc.Type))
+
+	return webrtc.SessionDescription{
+		Type: desc.Type,
+		SDP:  sealedPrefix + base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// open decrypts the SDP of a SessionDescription sealed by a peer.
+func (s *SealedSignal) open(peer string, desc webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	if !strings.HasPrefix(desc.SDP, sealedPrefix) {
+		return desc, fmt.Errorf("Unencrypted SDP from %s", peer)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(desc.SDP[len(sealedPrefix):])
+	if err != nil {
+		return desc, err
+	}
+
+	aead, err := s.aead(peer)
+	if err != nil {
+		return desc, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return desc, fmt.Errorf("Sealed SDP too short")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, sealedData(peer, s.ID(), desc.Type))
+	if err != nil {
+		return desc, fmt.Errorf("Cannot decrypt SDP from %s: %v", peer, err)
+	}
+
+	var sealed sealedSDP
+	if err := json.Unmarshal(plaintext, &sealed); err != nil {
+		return desc, err
+	}
+
+	if age := time.Since(time.Unix(0, sealed.Time)); age > sealedMaxAge || age < -sealedMaxAge {
+		return desc, fmt.Errorf("Stale SDP from %s", peer)
+	}
+
+	return webrtc.SessionDescription{
+		Type: desc.Type,
+		SDP:  sealed.SDP,
+	}, nil
+}
+
+// aead returns the cipher shared with a peer, from the ECDH secret of this
+// node's private key and the public key of the peer.
+func (s *SealedSignal) aead(peer string) (cipher.AEAD, error) {
+	pubBytes, err := common.DecodeFromString(peer)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid peer public key %s: %v", peer, err)
+	}
+
+	pub := keys.ToPublicKey(pubBytes)
+	if pub == nil || pub.X == nil || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("Invalid peer public key %s", peer)
+	}
+
+	x, _ := s.key.Curve.ScalarMult(pub.X, pub.Y, s.key.D.Bytes())
+	if x == nil || x.Sign() == 0 {
+		return nil, fmt.Errorf("Invalid shared secret")
+	}
+
+	secret := make([]byte, (s.key.Curve.Params().BitSize+7)/8)
+	xBytes := x.Bytes()
+	copy(secret[len(secret)-len(xBytes):], xBytes)
+
+	key := sha256.Sum256(secret)
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// sealedData is the additional data authenticated with a sealed SDP, which
+// binds it to its sender, its recipient, and its type, so that an offer cannot
+// be passed off as an answer, or sent back to its sender.
+func sealedData(from, to string, sdpType webrtc.SDPType) []byte {
+	return []byte(strings.ToUpper(from) + "|" + strings.ToUpper(to) + "|" + sdpType.String())
+}
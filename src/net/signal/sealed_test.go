@@ -0,0 +1,153 @@
+package signal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/pion/webrtc/v2"
+)
+
+// relaySignal is a Signal which delivers the offers to another relaySignal in
+// memory, and lets the test tamper with them, like a signaling server.
+type relaySignal struct {
+	id       string
+	peer     *relaySignal
+	consumer chan OfferPromise
+	tamper   func(*webrtc.SessionDescription)
+	offers   []webrtc.SessionDescription
+}
+
+func (r *relaySignal) ID() string {
+	return r.id
+}
+
+func (r *relaySignal) Listen() error {
+	return nil
+}
+
+func (r *relaySignal) Consumer() <-chan OfferPromise {
+	return r.consumer
+}
+
+func (r *relaySignal) Offer(target string, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	r.offers = append(r.offers, offer)
+	if r.tamper != nil {
+		r.tamper(&offer)
+	}
+
+	respCh := make(chan OfferPromiseResponse, 1)
+	r.peer.consumer <- OfferPromise{From: r.id, Offer: offer, RespChan: respCh}
+
+	resp := <-respCh
+	return resp.Answer, resp.Error
+}
+
+func (r *relaySignal) Close() error {
+	return nil
+}
+
+func newSealedPair(t *testing.T) (*SealedSignal, *SealedSignal, *relaySignal) {
+	aliceKey, _ := keys.GenerateECDSAKey()
+	bobKey, _ := keys.GenerateECDSAKey()
+
+	alice := &relaySignal{id: keys.PublicKeyHex(&aliceKey.PublicKey), consumer: make(chan OfferPromise)}
+	bob := &relaySignal{id: keys.PublicKeyHex(&bobKey.PublicKey), consumer: make(chan OfferPromise)}
+	alice.peer, bob.peer = bob, alice
+
+	return NewSealedSignal(alice, aliceKey), NewSealedSignal(bob, bobKey), alice
+}
+
+// answer answers the next offer received by the signal, echoing its SDP.
+func answer(s Signal, received chan<- string) {
+	p := <-s.Consumer()
+	received <- p.Offer.SDP
+	p.Respond(&webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "answer to " + p.Offer.SDP}, nil)
+}
+
+func TestSealedSignal(t *testing.T) {
+	alice, bob, relay := newSealedPair(t)
+	defer alice.Close()
+	defer bob.Close()
+
+	received := make(chan string, 1)
+	go answer(bob, received)
+
+	resp, err := alice.Offer(bob.ID(), webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "v=0 offer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sdp := <-received; sdp != "v=0 offer" {
+		t.Fatalf("Bob should receive the decrypted offer, not %q", sdp)
+	}
+
+	if resp.SDP != "answer to v=0 offer" {
+		t.Fatalf("Alice should receive the decrypted answer, not %q", resp.SDP)
+	}
+
+	// The signaling server only sees ciphertext
+	if sent := relay.offers[0].SDP; !strings.HasPrefix(sent, sealedPrefix) || strings.Contains(sent, "v=0") {
+		t.Fatalf("The offer should be encrypted on the signal, not %q", sent)
+	}
+
+	// A tampered offer is refused
+	relay.tamper = func(desc *webrtc.SessionDescription) {
+		desc.SDP = desc.SDP[:len(desc.SDP)-4] + "AAAA"
+	}
+	if _, err := alice.Offer(bob.ID(), webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "v=0 offer"}); err == nil {
+		t.Fatal("A tampered offer should be refused")
+	}
+
+	// And so is an unencrypted one
+	relay.tamper = func(desc *webrtc.SessionDescription) {
+		desc.SDP = "v=0 injected"
+	}
+	if _, err := alice.Offer(bob.ID(), webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "v=0 offer"}); err == nil {
+		t.Fatal("An unencrypted offer should be refused")
+	}
+}
+
+func TestSealedSignalAuthentication(t *testing.T) {
+	alice, bob, _ := newSealedPair(t)
+	defer alice.Close()
+	defer bob.Close()
+
+	offer := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "v=0 offer"}
+
+	sealed, err := alice.seal(bob.ID(), offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the recipient can open it, and only as coming from the sender
+	if _, err := bob.open(alice.ID(), sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	eveKey, _ := keys.GenerateECDSAKey()
+	if _, err := bob.open(keys.PublicKeyHex(&eveKey.PublicKey), sealed); err == nil {
+		t.Fatal("The offer should not open as coming from another peer")
+	}
+
+	// An offer cannot be passed off as an answer
+	sealed.Type = webrtc.SDPTypeAnswer
+	if _, err := bob.open(alice.ID(), sealed); err == nil {
+		t.Fatal("The offer should not open as an answer")
+	}
+
+	// Nor replayed after a while
+	aead, _ := alice.aead(bob.ID())
+	plaintext, _ := json.Marshal(sealedSDP{SDP: offer.SDP, Time: time.Now().Add(-2 * sealedMaxAge).UnixNano()})
+	nonce := make([]byte, aead.NonceSize())
+	stale := webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  sealedPrefix + base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, sealedData(alice.ID(), bob.ID(), webrtc.SDPTypeOffer))),
+	}
+	if _, err := bob.open(alice.ID(), stale); err == nil {
+		t.Fatal("A stale offer should be refused")
+	}
+}
@@ -1,14 +1,23 @@
 package net
 
 import (
+	"errors"
 	"net"
 	"time"
+
+	"github.com/mosaicnetworks/babble/src/net/tor"
 )
 
+var errNoTorProxy = errors.New("cannot dial an onion address without a Tor proxy")
+
 // tcpStreamLayer implements StreamLayer interface for plain TCP.
 type tcpStreamLayer struct {
 	advertise string
 	listener  *net.TCPListener
+
+	// torProxy is the address of the SOCKS5 proxy of a Tor client, through
+	// which onion addresses are dialed.
+	torProxy string
 }
 
 // Accept implements the net.Listener interface.
@@ -40,6 +49,14 @@ func (t *tcpStreamLayer) Addr() net.Addr {
 
 // Dial implements the StreamLayer interface.
 func (t *tcpStreamLayer) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	// Onion addresses are never resolved locally, which would leak them to
+	// the DNS resolver
+	if tor.IsOnion(address) {
+		if t.torProxy == "" {
+			return nil, errNoTorProxy
+		}
+		return tor.Dial(t.torProxy, address, timeout)
+	}
 	return net.DialTimeout("tcp", address, timeout)
 }
 
@@ -5,6 +5,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/mosaicnetworks/babble/src/net/tor"
 	"github.com/sirupsen/logrus"
 )
 
@@ -13,6 +14,18 @@ var (
 	errNotTCP          = errors.New("local address is not a TCP address")
 )
 
+// SetTorProxy makes a TCP NetworkTransport dial the onion addresses, like
+// xyz.onion:1337, through the SOCKS5 proxy of a Tor client. Other addresses
+// are still dialed directly.
+func (n *NetworkTransport) SetTorProxy(proxyAddr string) error {
+	stream, ok := n.stream.(*tcpStreamLayer)
+	if !ok {
+		return errNotTCP
+	}
+	stream.torProxy = proxyAddr
+	return nil
+}
+
 // NewTCPTransport returns a NetworkTransport that is built on top of a TCP
 // StreamLayer.
 func NewTCPTransport(
@@ -41,12 +54,38 @@ func newTCPTransport(bindAddr string,
 		return nil, err
 	}
 
+	// Onion addresses cannot be resolved, their format is only checked
+	if tor.IsOnion(advertiseAddr) {
+		if _, _, err := net.SplitHostPort(advertiseAddr); err != nil {
+			list.Close()
+			return nil, err
+		}
+	} else if err := checkAdvertise(list, advertiseAddr); err != nil {
+		list.Close()
+		return nil, err
+	}
+
+	// Create stream
+	stream := &tcpStreamLayer{
+		advertise: advertiseAddr,
+		listener:  list.(*net.TCPListener),
+	}
+
+	// Create the network transport
+	trans := transportCreator(stream)
+	return trans, nil
+}
+
+// checkAdvertise verifies that the advertise address, or the address of the
+// listener if it is empty, is a TCP address that other peers can reach.
+func checkAdvertise(list net.Listener, advertiseAddr string) error {
 	// Try to resolve the advertise address
 	var resolvedAdvertise net.Addr
 	if advertiseAddr != "" {
+		var err error
 		resolvedAdvertise, err = net.ResolveTCPAddr("tcp", advertiseAddr)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
@@ -57,21 +96,11 @@ func newTCPTransport(bindAddr string,
 	// Verify that we have a usable advertise address
 	addr, ok := resolvedAdvertise.(*net.TCPAddr)
 	if !ok {
-		list.Close()
-		return nil, errNotTCP
+		return errNotTCP
 	}
 	if addr.IP.IsUnspecified() {
-		list.Close()
-		return nil, errNotAdvertisable
-	}
-
-	// Create stream
-	stream := &tcpStreamLayer{
-		advertise: advertiseAddr,
-		listener:  list.(*net.TCPListener),
+		return errNotAdvertisable
 	}
 
-	// Create the network transport
-	trans := transportCreator(stream)
-	return trans, nil
+	return nil
 }
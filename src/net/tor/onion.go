@@ -0,0 +1,243 @@
+package tor
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OnionService is an onion service published through the control port of a
+// Tor client, which forwards the connections to a local TCP listener. Tor
+// removes the service when the control connection is closed.
+type OnionService struct {
+	// ServiceID is the onion address without the .onion suffix.
+	ServiceID string
+
+	port   int
+	conn   net.Conn
+	r      *bufio.Reader
+	logger *logrus.Entry
+}
+
+// Publish publishes the TCP listener at bindAddr as an onion service, through
+// the control port of a Tor client at controlAddr. The virtual port of the
+// service is the port of bindAddr. The key of the service is read from keyFile,
+// or generated by Tor and saved to keyFile, so that the onion address is the
+// same across restarts. The control port is authenticated with the password
+// if it is not empty, and otherwise with the cookie file of Tor, or without
+// authentication if Tor allows it.
+func Publish(controlAddr string,
+	password string,
+	keyFile string,
+	bindAddr string,
+	timeout time.Duration,
+	logger *logrus.Entry) (*OnionService, error) {
+
+	host, portStr, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("Invalid port in %q", bindAddr)
+	}
+
+	// Tor connects to the listener locally
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	target := net.JoinHostPort(host, portStr)
+
+	conn, err := net.DialTimeout("tcp", controlAddr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OnionService{
+		port:   port,
+		conn:   conn,
+		r:      bufio.NewReader(conn),
+		logger: logger,
+	}
+
+	if err := o.publish(password, keyFile, target, timeout); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func (o *OnionService) publish(password, keyFile, target string, timeout time.Duration) error {
+	if timeout > 0 {
+		o.conn.SetDeadline(time.Now().Add(timeout))
+		defer o.conn.SetDeadline(time.Time{})
+	}
+
+	if err := o.authenticate(password); err != nil {
+		return err
+	}
+
+	keySpec := "NEW:ED25519-V3"
+	savedKey, err := ioutil.ReadFile(keyFile)
+	if err == nil {
+		keySpec = strings.TrimSpace(string(savedKey))
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	lines, err := o.command(fmt.Sprintf("ADD_ONION %s Port=%d,%s", keySpec, o.port, target))
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "ServiceID="):
+			o.ServiceID = strings.TrimPrefix(line, "ServiceID=")
+		case strings.HasPrefix(line, "PrivateKey="):
+			key := strings.TrimPrefix(line, "PrivateKey=")
+			if err := ioutil.WriteFile(keyFile, []byte(key+"\n"), 0600); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.ServiceID == "" {
+		return fmt.Errorf("Tor did not return the ID of the onion service")
+	}
+
+	if o.logger != nil {
+		o.logger.WithFields(logrus.Fields{
+			"addr":   o.Addr(),
+			"target": target,
+		}).Info("Published onion service")
+	}
+
+	return nil
+}
+
+// authenticate authenticates the control connection with a password, the
+// cookie file of Tor, or nothing, depending on the methods Tor accepts.
+func (o *OnionService) authenticate(password string) error {
+	if password != "" {
+		_, err := o.command(fmt.Sprintf("AUTHENTICATE %s", quote(password)))
+		return err
+	}
+
+	lines, err := o.command("PROTOCOLINFO 1")
+	if err != nil {
+		return err
+	}
+
+	methods, cookieFile := parseAuthMethods(lines)
+
+	switch {
+	case methods["NULL"]:
+		_, err := o.command("AUTHENTICATE")
+		return err
+	case methods["COOKIE"] && cookieFile != "":
+		cookie, err := ioutil.ReadFile(cookieFile)
+		if err != nil {
+			return err
+		}
+		_, err = o.command("AUTHENTICATE " + hex.EncodeToString(cookie))
+		return err
+	default:
+		return fmt.Errorf("Tor control port requires a password")
+	}
+}
+
+// parseAuthMethods parses the authentication methods and the path of the
+// cookie file in a PROTOCOLINFO reply, like
+// AUTH METHODS=COOKIE,SAFECOOKIE COOKIEFILE="/run/tor/control.authcookie".
+func parseAuthMethods(lines []string) (map[string]bool, string) {
+	methods := make(map[string]bool)
+	cookieFile := ""
+
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "AUTH ") {
+			continue
+		}
+
+		for _, field := range strings.Fields(strings.TrimPrefix(line, "AUTH ")) {
+			switch {
+			case strings.HasPrefix(field, "METHODS="):
+				for _, m := range strings.Split(strings.TrimPrefix(field, "METHODS="), ",") {
+					methods[m] = true
+				}
+			case strings.HasPrefix(field, "COOKIEFILE="):
+				cookieFile = unquote(strings.TrimPrefix(field, "COOKIEFILE="))
+			}
+		}
+	}
+
+	return methods, cookieFile
+}
+
+// command sends a command to the control port, and returns the lines of a
+// successful reply, without their status codes.
+func (o *OnionService) command(cmd string) ([]string, error) {
+	if _, err := fmt.Fprintf(o.conn, "%s\r\n", cmd); err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for {
+		line, err := o.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if len(line) < 4 {
+			return nil, fmt.Errorf("Malformed reply from Tor: %q", line)
+		}
+
+		code, sep, text := line[:3], line[3], line[4:]
+		if code != "250" {
+			return nil, fmt.Errorf("Tor refused %q: %s", strings.Fields(cmd)[0], line)
+		}
+
+		lines = append(lines, text)
+
+		if sep == ' ' {
+			return lines, nil
+		}
+	}
+}
+
+// Addr returns the address of the onion service, like xyz.onion:1337.
+func (o *OnionService) Addr() string {
+	return net.JoinHostPort(o.ServiceID+".onion", strconv.Itoa(o.port))
+}
+
+// Close removes the onion service and closes the control connection.
+func (o *OnionService) Close() error {
+	if _, err := o.command("DEL_ONION " + o.ServiceID); err != nil && o.logger != nil {
+		o.logger.WithError(err).Warn("Removing onion service")
+	}
+	return o.conn.Close()
+}
+
+// quote quotes a string for the control protocol.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// unquote removes the quotes of a string of the control protocol.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	return strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(s[1 : len(s)-1])
+}
@@ -0,0 +1,145 @@
+// Package tor connects Babble nodes through the Tor network. It dials .onion
+// addresses through the SOCKS5 proxy of a Tor client, and publishes the TCP
+// listener of a node as an onion service through the control port of the Tor
+// client, so that nodes can gossip without revealing their IP addresses, or
+// from networks that block direct connections.
+package tor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IsOnion returns true if an address, with or without a port, belongs to an
+// onion service.
+func IsOnion(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	return strings.HasSuffix(strings.ToLower(host), ".onion")
+}
+
+// SOCKS5 constants, from RFC 1928.
+const (
+	socksVersion   = 5
+	socksNoAuth    = 0
+	socksConnect   = 1
+	socksIPv4      = 1
+	socksDomain    = 3
+	socksIPv6      = 4
+	socksSucceeded = 0
+)
+
+var socksErrors = map[byte]string{
+	1: "general SOCKS server failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// Dial connects to an address through the SOCKS5 proxy of a Tor client. The
+// host name is sent to the proxy, which resolves it, so that .onion addresses
+// can be reached and no DNS request leaves the machine.
+func Dial(proxyAddr string, address string, timeout time.Duration) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("Invalid port in address %q", address)
+	}
+
+	if len(host) > 255 {
+		return nil, fmt.Errorf("Host name too long in address %q", address)
+	}
+
+	conn, err := net.DialTimeout("tcp", proxyAddr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	if err := socksConnectTo(conn, host, port); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Connecting to %s through Tor: %v", address, err)
+	}
+
+	// The deadlines of the connection are managed by the caller
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+// socksConnectTo negotiates a SOCKS5 CONNECT, without authentication, to a host
+// name and port.
+func socksConnectTo(conn net.Conn, host string, port int) error {
+	if _, err := conn.Write([]byte{socksVersion, 1, socksNoAuth}); err != nil {
+		return err
+	}
+
+	method := make([]byte, 2)
+	if _, err := io.ReadFull(conn, method); err != nil {
+		return err
+	}
+	if method[0] != socksVersion || method[1] != socksNoAuth {
+		return fmt.Errorf("SOCKS proxy requires an unsupported authentication method")
+	}
+
+	req := []byte{socksVersion, socksConnect, 0, socksDomain, byte(len(host))}
+	req = append(req, host...)
+	req = append(req, 0, 0)
+	binary.BigEndian.PutUint16(req[len(req)-2:], uint16(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socksVersion {
+		return fmt.Errorf("Unexpected SOCKS version %d", reply[0])
+	}
+	if reply[1] != socksSucceeded {
+		if msg, ok := socksErrors[reply[1]]; ok {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("SOCKS error %d", reply[1])
+	}
+
+	// Skip the bound address and port
+	var skip int
+	switch reply[3] {
+	case socksIPv4:
+		skip = net.IPv4len + 2
+	case socksIPv6:
+		skip = net.IPv6len + 2
+	case socksDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		skip = int(l[0]) + 2
+	default:
+		return fmt.Errorf("Unexpected SOCKS address type %d", reply[3])
+	}
+
+	_, err := io.ReadFull(conn, make([]byte, skip))
+	return err
+}
@@ -0,0 +1,222 @@
+package tor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsOnion(t *testing.T) {
+	cases := map[string]bool{
+		"abcdef.onion:1337": true,
+		"ABCDEF.ONION":      true,
+		"10.0.0.1:1337":     false,
+		"onion.com:1337":    false,
+		"0X04ABCD":          false,
+	}
+
+	for addr, expected := range cases {
+		if res := IsOnion(addr); res != expected {
+			t.Fatalf("IsOnion(%q) should be %v", addr, expected)
+		}
+	}
+}
+
+// fakeProxy is a SOCKS5 proxy which only accepts CONNECT requests for a host
+// name, and connects them to a local listener whatever the target.
+func fakeProxy(t *testing.T, backend string, targets chan<- string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+
+				greeting := make([]byte, 3)
+				if _, err := io.ReadFull(conn, greeting); err != nil {
+					return
+				}
+				conn.Write([]byte{socksVersion, socksNoAuth})
+
+				header := make([]byte, 5)
+				if _, err := io.ReadFull(conn, header); err != nil || header[3] != socksDomain {
+					return
+				}
+				addr := make([]byte, int(header[4])+2)
+				if _, err := io.ReadFull(conn, addr); err != nil {
+					return
+				}
+				port := binary.BigEndian.Uint16(addr[len(addr)-2:])
+				targets <- net.JoinHostPort(string(addr[:len(addr)-2]), strconv.Itoa(int(port)))
+
+				up, err := net.Dial("tcp", backend)
+				if err != nil {
+					conn.Write([]byte{socksVersion, 5, 0, socksIPv4, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer up.Close()
+				conn.Write([]byte{socksVersion, socksSucceeded, 0, socksIPv4, 127, 0, 0, 1, 0, 0})
+
+				go io.Copy(up, conn)
+				io.Copy(conn, up)
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestDial(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("hello\n"))
+	}()
+
+	targets := make(chan string, 1)
+	proxy := fakeProxy(t, backend.Addr().String(), targets)
+	defer proxy.Close()
+
+	conn, err := Dial(proxy.Addr().String(), "abcdef.onion:7", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if target := <-targets; target != "abcdef.onion:7" {
+		t.Fatalf("The proxy should receive the onion address, not %s", target)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "hello\n" {
+		t.Fatalf("The connection should reach the backend, read %q, %v", line, err)
+	}
+
+	if _, err := Dial(proxy.Addr().String(), "abcdef.onion", time.Second); err == nil {
+		t.Fatal("An address without a port should be refused")
+	}
+}
+
+// fakeControl is a Tor control port which accepts null authentication and
+// ADD_ONION commands, and records the commands it receives.
+func fakeControl(t *testing.T, commands chan<- string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd := strings.TrimSpace(line)
+					commands <- cmd
+
+					switch {
+					case strings.HasPrefix(cmd, "PROTOCOLINFO"):
+						conn.Write([]byte("250-PROTOCOLINFO 1\r\n250-AUTH METHODS=NULL\r\n250 OK\r\n"))
+					case strings.HasPrefix(cmd, "ADD_ONION NEW:"):
+						conn.Write([]byte("250-ServiceID=abcdef\r\n250-PrivateKey=ED25519-V3:secret\r\n250 OK\r\n"))
+					case strings.HasPrefix(cmd, "ADD_ONION ED25519-V3:secret "):
+						conn.Write([]byte("250-ServiceID=abcdef\r\n250 OK\r\n"))
+					case strings.HasPrefix(cmd, "ADD_ONION"):
+						conn.Write([]byte("512 Bad arguments\r\n"))
+					default:
+						conn.Write([]byte("250 OK\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestPublish(t *testing.T) {
+	dir, err := ioutil.TempDir("", "babble-tor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "onion_key")
+
+	commands := make(chan string, 10)
+	control := fakeControl(t, commands)
+	defer control.Close()
+
+	onion, err := Publish(control.Addr().String(), "", keyFile, "0.0.0.0:1337", time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr := onion.Addr(); addr != "abcdef.onion:1337" {
+		t.Fatalf("The onion address should be abcdef.onion:1337, not %s", addr)
+	}
+
+	expected := []string{"PROTOCOLINFO 1", "AUTHENTICATE", "ADD_ONION NEW:ED25519-V3 Port=1337,127.0.0.1:1337"}
+	for _, exp := range expected {
+		if cmd := <-commands; cmd != exp {
+			t.Fatalf("Command should be %q, not %q", exp, cmd)
+		}
+	}
+
+	if err := onion.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if cmd := <-commands; cmd != "DEL_ONION abcdef" {
+		t.Fatalf("Closing should remove the onion service, not send %q", cmd)
+	}
+
+	// The key is saved, and used again
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil || strings.TrimSpace(string(key)) != "ED25519-V3:secret" {
+		t.Fatalf("The key should be saved, read %q, %v", key, err)
+	}
+
+	onion, err = Publish(control.Addr().String(), "pass\"word", keyFile, "127.0.0.1:1337", time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer onion.Close()
+
+	if cmd := <-commands; cmd != `AUTHENTICATE "pass\"word"` {
+		t.Fatalf("The password should be sent quoted, not %q", cmd)
+	}
+	if cmd := <-commands; !strings.HasPrefix(cmd, "ADD_ONION ED25519-V3:secret ") {
+		t.Fatalf("The saved key should be used, not %q", cmd)
+	}
+}
@@ -1,5 +1,7 @@
 package net
 
+import "time"
+
 // Transport provides an interface for network transports to allow a node to
 // communicate with other nodes.
 type Transport interface {
@@ -18,8 +20,8 @@ type Transport interface {
 	// can reach us
 	AdvertiseAddr() string
 
-	// Sync, EagerSync, FastForward, and Join send the appropriate RPC to the
-	// target node.
+	// Sync, EagerSync, FastForward, Join, Ping, and Info send the appropriate
+	// RPC to the target node.
 
 	Sync(target string, args *SyncRequest, resp *SyncResponse) error
 
@@ -29,7 +31,66 @@ type Transport interface {
 
 	Join(target string, args *JoinRequest, resp *JoinResponse) error
 
+	Ping(target string, args *PingRequest, resp *PingResponse) error
+
+	Info(target string, args *InfoRequest, resp *InfoResponse) error
+
 	// Close permanently closes a transport, stopping any associated goroutines
 	// and freeing other resources.
 	Close() error
 }
+
+// TrafficCounter is implemented by the transports that count the bytes they
+// send and receive over the network.
+type TrafficCounter interface {
+	// Traffic returns the number of bytes sent and received since the
+	// transport was created.
+	Traffic() (sent, received uint64)
+}
+
+// PeerTrafficCounter is implemented by the transports that report the traffic
+// of each RPC, so that it can be accounted per peer.
+type PeerTrafficCounter interface {
+	// SetTrafficHook sets the function called with the traffic of each RPC.
+	// It is called by the goroutine of the RPC, so it must not block.
+	SetTrafficHook(func(RPCTraffic))
+}
+
+// RPCTraffic is the number of bytes sent and received by a transport for a
+// single RPC. The peer is identified by the ID in its request, for inbound
+// RPCs, or in its response, for outbound RPCs. The outbound RPCs that fail
+// before a response is decoded are not reported.
+type RPCTraffic struct {
+	PeerID   uint32
+	Inbound  bool
+	Sent     uint64
+	Received uint64
+}
+
+// KeepaliveTransport is implemented by the transports that can check their
+// connection to a peer with keepalives, which the transport of the peer answers
+// itself, without involving the node. A peer which answers keepalives but not
+// the other RPCs is reachable, but its node is busy or stuck.
+type KeepaliveTransport interface {
+	// Keepalive sends a keepalive on every pooled connection to the target,
+	// or on a new connection if none is pooled, and evicts the connections
+	// which do not answer. It returns the round-trip time of the first
+	// keepalive answered, or an error if none was.
+	Keepalive(target string) (time.Duration, error)
+}
+
+// FilteredTransport is implemented by the transports that refuse connections
+// and RPCs from the peers rejected by a Filter.
+type FilteredTransport interface {
+	// Filter returns the Filter of the transport, which can be modified while
+	// the transport is running.
+	Filter() *Filter
+}
+
+// PooledTransport is implemented by the transports that keep a pool of
+// outbound connections.
+type PooledTransport interface {
+	// CloseConns closes the pooled connections, so that the next RPCs dial the
+	// peers again.
+	CloseConns()
+}
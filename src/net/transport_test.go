@@ -56,6 +56,7 @@ func NewTestTransport(ttype int, addr string, wampserver string, t *testing.T) T
 		wt, err := NewWebRTCTransport(
 			signal,
 			config.DefaultICEServers(),
+			DataChannelConfig{},
 			1,
 			signalTimeout,
 			signalTimeout,
@@ -1,22 +1,68 @@
 package net
 
 import (
+	"fmt"
+	"math"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/pion/datachannel"
 )
 
+// webRTCMaxMessageSize is the maximum size of a message sent on a DataChannel.
+const webRTCMaxMessageSize = math.MaxUint16
+
+// bufferedChannel is the part of a WebRTC DataChannel that reports the amount
+// of data waiting to be sent, used to apply backpressure to the writes.
+type bufferedChannel interface {
+	BufferedAmount() uint64
+	SetBufferedAmountLowThreshold(th uint64)
+	OnBufferedAmountLow(f func())
+}
+
 // webRTCConn implements net.Conn around a webrtc datachannel.
 type webRTCConn struct {
 	dataChannel datachannel.ReadWriteCloser
+
+	// buffered, when not nil, makes the writes wait while more than
+	// bufferedHigh bytes are waiting to be sent, until the amount falls under
+	// the low threshold and lowCh is signalled.
+	buffered     bufferedChannel
+	bufferedHigh uint64
+	lowCh        chan struct{}
+	closeCh      chan struct{}
+	closeOnce    sync.Once
+
+	// writeDeadline bounds the wait for the buffer to drain.
+	writeDeadline     time.Time
+	writeDeadlineLock sync.Mutex
 }
 
-// newWebRTCConn instantiates a webRTCConn from a datachannel.
-func newWebRTCConn(dataChannel datachannel.ReadWriteCloser) *webRTCConn {
-	return &webRTCConn{
+// newWebRTCConn instantiates a webRTCConn from a datachannel. The writes wait
+// for the buffered amount of the channel to drain, if the config sets a
+// threshold.
+func newWebRTCConn(dataChannel datachannel.ReadWriteCloser, buffered bufferedChannel, conf DataChannelConfig) *webRTCConn {
+	c := &webRTCConn{
 		dataChannel: dataChannel,
+		closeCh:     make(chan struct{}),
+	}
+
+	if buffered != nil && conf.BufferedAmountHigh > 0 {
+		c.buffered = buffered
+		c.bufferedHigh = conf.BufferedAmountHigh
+		c.lowCh = make(chan struct{}, 1)
+
+		buffered.SetBufferedAmountLowThreshold(conf.BufferedAmountLow)
+		buffered.OnBufferedAmountLow(func() {
+			select {
+			case c.lowCh <- struct{}{}:
+			default:
+			}
+		})
 	}
+
+	return c
 }
 
 // Read implements the Conn Read method.
@@ -24,13 +70,65 @@ func (c *webRTCConn) Read(p []byte) (int, error) {
 	return c.dataChannel.Read(p)
 }
 
-// Write implements the Conn Write method.
+// Write implements the Conn Write method. The data is split into messages of
+// at most webRTCMaxMessageSize bytes, and each message waits while the
+// buffered amount of the channel is above the high threshold, so that a slow
+// peer does not make the buffer grow without bounds.
 func (c *webRTCConn) Write(p []byte) (int, error) {
-	return c.dataChannel.Write(p)
+	written := 0
+
+	for written < len(p) {
+		end := written + webRTCMaxMessageSize
+		if end > len(p) {
+			end = len(p)
+		}
+
+		if err := c.waitBuffered(); err != nil {
+			return written, err
+		}
+
+		n, err := c.dataChannel.Write(p[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// waitBuffered waits for the buffered amount of the channel to fall under the
+// low threshold, if it is above the high threshold, until the write deadline.
+func (c *webRTCConn) waitBuffered() error {
+	if c.buffered == nil {
+		return nil
+	}
+
+	for c.buffered.BufferedAmount() > c.bufferedHigh {
+		var timeout <-chan time.Time
+		if deadline := c.getWriteDeadline(); !deadline.IsZero() {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case <-c.lowCh:
+		case <-timeout:
+			return fmt.Errorf("write timeout: %d bytes buffered", c.buffered.BufferedAmount())
+		case <-c.closeCh:
+			return fmt.Errorf("connection closed")
+		}
+	}
+
+	return nil
 }
 
 // Close implements the Conn Close method.
 func (c *webRTCConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
 	return c.dataChannel.Close()
 }
 
@@ -44,9 +142,10 @@ func (c *webRTCConn) RemoteAddr() net.Addr {
 	return nil
 }
 
-// SetDeadline is a stub
+// SetDeadline implements the Conn SetDeadline method. Only the write deadline
+// is applied, to the wait for the buffer to drain.
 func (c *webRTCConn) SetDeadline(t time.Time) error {
-	return nil
+	return c.SetWriteDeadline(t)
 }
 
 // SetReadDeadline is a stub
@@ -54,7 +153,19 @@ func (c *webRTCConn) SetReadDeadline(t time.Time) error {
 	return nil
 }
 
-// SetWriteDeadline is a stub
+// SetWriteDeadline implements the Conn SetWriteDeadline method. The deadline
+// applies to the wait for the buffer to drain.
 func (c *webRTCConn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadlineLock.Lock()
+	defer c.writeDeadlineLock.Unlock()
+
+	c.writeDeadline = t
 	return nil
 }
+
+func (c *webRTCConn) getWriteDeadline() time.Time {
+	c.writeDeadlineLock.Lock()
+	defer c.writeDeadlineLock.Unlock()
+
+	return c.writeDeadline
+}
@@ -0,0 +1,106 @@
+package net
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDataChannel records the writes, and lets the test set the buffered
+// amount and fire the low-threshold event.
+type fakeDataChannel struct {
+	sync.Mutex
+	written  int
+	buffered uint64
+	low      func()
+}
+
+func (f *fakeDataChannel) Read(p []byte) (int, error) { return 0, nil }
+
+func (f *fakeDataChannel) ReadDataChannel(p []byte) (int, bool, error) { return 0, false, nil }
+
+func (f *fakeDataChannel) Write(p []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.written += len(p)
+	f.buffered += uint64(len(p))
+	return len(p), nil
+}
+
+func (f *fakeDataChannel) WriteDataChannel(p []byte, isString bool) (int, error) { return f.Write(p) }
+
+func (f *fakeDataChannel) Close() error { return nil }
+
+func (f *fakeDataChannel) BufferedAmount() uint64 {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.buffered
+}
+
+func (f *fakeDataChannel) SetBufferedAmountLowThreshold(th uint64) {}
+
+func (f *fakeDataChannel) OnBufferedAmountLow(low func()) { f.low = low }
+
+// drain empties the buffer and fires the low-threshold event.
+func (f *fakeDataChannel) drain() {
+	f.Lock()
+	f.buffered = 0
+	f.Unlock()
+
+	f.low()
+}
+
+func TestWebRTCConnBackpressure(t *testing.T) {
+	dc := &fakeDataChannel{}
+	conn := newWebRTCConn(dc, dc, DataChannelConfig{BufferedAmountHigh: 10, BufferedAmountLow: 5})
+
+	// Under the threshold, writes go through
+	if _, err := conn.Write(make([]byte, 20)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Above it, they wait for the buffer to drain
+	done := make(chan error)
+	go func() {
+		_, err := conn.Write(make([]byte, 5))
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("The write should wait for the buffer to drain")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	dc.drain()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if dc.written != 25 {
+		t.Fatalf("25 bytes should have been written, not %d", dc.written)
+	}
+
+	// The wait ends at the write deadline
+	dc.Write(make([]byte, 20))
+	conn.SetDeadline(time.Now().Add(50 * time.Millisecond))
+
+	if _, err := conn.Write(make([]byte, 5)); err == nil {
+		t.Fatal("The write should time out while the buffer is full")
+	}
+
+	// Or when the connection is closed
+	conn.SetDeadline(time.Time{})
+	go func() {
+		_, err := conn.Write(make([]byte, 5))
+		done <- err
+	}()
+
+	conn.Close()
+
+	if err := <-done; err == nil {
+		t.Fatal("The write should fail when the connection is closed")
+	}
+}
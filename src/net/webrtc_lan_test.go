@@ -0,0 +1,198 @@
+package net
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/net/signal"
+	webrtc "github.com/pion/webrtc/v2"
+)
+
+// memSignal is a Signal that exchanges SDP offers and answers in memory, so
+// that WebRTC can be tested without a signaling server. It records the SDPs
+// that it carries.
+type memSignal struct {
+	id       string
+	network  *memSignalNetwork
+	consumer chan signal.OfferPromise
+}
+
+type memSignalNetwork struct {
+	sync.Mutex
+	signals map[string]*memSignal
+	sdps    []string
+}
+
+func (n *memSignalNetwork) newSignal(id string) *memSignal {
+	n.Lock()
+	defer n.Unlock()
+
+	s := &memSignal{
+		id:       id,
+		network:  n,
+		consumer: make(chan signal.OfferPromise),
+	}
+	n.signals[id] = s
+
+	return s
+}
+
+func (n *memSignalNetwork) record(sdp string) {
+	n.Lock()
+	defer n.Unlock()
+
+	n.sdps = append(n.sdps, sdp)
+}
+
+func (s *memSignal) ID() string {
+	return s.id
+}
+
+func (s *memSignal) Listen() error {
+	return nil
+}
+
+func (s *memSignal) Consumer() <-chan signal.OfferPromise {
+	return s.consumer
+}
+
+func (s *memSignal) Offer(target string, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	s.network.Lock()
+	peer, ok := s.network.signals[target]
+	s.network.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown target %s", target)
+	}
+
+	s.network.record(offer.SDP)
+
+	respCh := make(chan signal.OfferPromiseResponse, 1)
+	peer.consumer <- signal.OfferPromise{
+		From:     s.id,
+		Offer:    offer,
+		RespChan: respCh,
+	}
+
+	select {
+	case resp := <-respCh:
+		if resp.Answer != nil {
+			s.network.record(resp.Answer.SDP)
+		}
+		return resp.Answer, resp.Error
+	case <-time.After(signalTimeout):
+		return nil, fmt.Errorf("offer timeout")
+	}
+}
+
+func (s *memSignal) Close() error {
+	return nil
+}
+
+func TestWebRTCHostOnly(t *testing.T) {
+	conf := config.NewDefaultConfig()
+	conf.ICEHostOnly = true
+
+	if len(conf.ICEServers()) != 0 {
+		t.Fatal("There should be no ICE servers in host-only mode")
+	}
+
+	network := &memSignalNetwork{signals: make(map[string]*memSignal)}
+
+	trans1, err := NewWebRTCTransport(network.newSignal("alice"), conf.ICEServers(), DataChannelConfig{}, 1, signalTimeout, signalTimeout, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go trans1.Listen()
+	defer trans1.Close()
+
+	trans2, err := NewWebRTCTransport(network.newSignal("bob"), conf.ICEServers(), DataChannelConfig{}, 1, signalTimeout, signalTimeout, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go trans2.Listen()
+	defer trans2.Close()
+
+	go func() {
+		rpc := <-trans1.Consumer()
+		rpc.Respond(&SyncResponse{FromID: 1}, nil)
+	}()
+
+	var out SyncResponse
+	if err := trans2.Sync("alice", &SyncRequest{FromID: 2}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.FromID != 1 {
+		t.Fatalf("The response should come from alice, not %d", out.FromID)
+	}
+
+	// Only host candidates were exchanged
+	network.Lock()
+	defer network.Unlock()
+
+	if len(network.sdps) != 2 {
+		t.Fatalf("An offer and an answer should have been exchanged, not %d SDPs", len(network.sdps))
+	}
+
+	for _, sdp := range network.sdps {
+		if !strings.Contains(sdp, "typ host") {
+			t.Fatalf("The SDP should contain host candidates: %s", sdp)
+		}
+		if strings.Contains(sdp, "typ srflx") || strings.Contains(sdp, "typ relay") {
+			t.Fatalf("The SDP should only contain host candidates: %s", sdp)
+		}
+	}
+}
+
+func TestWebRTCLargeResponseBackpressure(t *testing.T) {
+	conf := config.NewDefaultConfig()
+	conf.ICEHostOnly = true
+
+	// Thresholds far below the size of the response
+	dataChannel := DataChannelConfig{
+		BufferedAmountHigh: 64 << 10,
+		BufferedAmountLow:  16 << 10,
+	}
+
+	network := &memSignalNetwork{signals: make(map[string]*memSignal)}
+
+	trans1, err := NewWebRTCTransport(network.newSignal("alice"), conf.ICEServers(), dataChannel, 1, signalTimeout, signalTimeout, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go trans1.Listen()
+	defer trans1.Close()
+
+	trans2, err := NewWebRTCTransport(network.newSignal("bob"), conf.ICEServers(), dataChannel, 1, signalTimeout, signalTimeout, common.NewTestEntry(t, common.TestLogLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go trans2.Listen()
+	defer trans2.Close()
+
+	snapshot := make([]byte, 4<<20)
+	for i := range snapshot {
+		snapshot[i] = byte(i)
+	}
+
+	go func() {
+		rpc := <-trans1.Consumer()
+		rpc.Respond(&FastForwardResponse{FromID: 1, Snapshot: snapshot}, nil)
+	}()
+
+	var out FastForwardResponse
+	if err := trans2.FastForward("alice", &FastForwardRequest{FromID: 2}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out.Snapshot, snapshot) {
+		t.Fatal("The snapshot should be received intact")
+	}
+}
@@ -24,6 +24,8 @@ type webRTCStreamLayer struct {
 
 	iceServers []webrtc.ICEServer
 
+	dataChannelConfig DataChannelConfig
+
 	incomingConnAggregator chan net.Conn
 
 	logger *logrus.Entry
@@ -33,6 +35,7 @@ type webRTCStreamLayer struct {
 // background connection aggregator (signaling process)
 func newWebRTCStreamLayer(signal signal.Signal,
 	iceServers []webrtc.ICEServer,
+	dataChannelConfig DataChannelConfig,
 	logger *logrus.Entry) *webRTCStreamLayer {
 
 	stream := &webRTCStreamLayer{
@@ -40,6 +43,7 @@ func newWebRTCStreamLayer(signal signal.Signal,
 		dataChannels:           make(map[uint16]datachannel.ReadWriteCloser),
 		signal:                 signal,
 		iceServers:             iceServers,
+		dataChannelConfig:      dataChannelConfig,
 		incomingConnAggregator: make(chan net.Conn),
 		logger:                 logger,
 	}
@@ -138,7 +142,7 @@ func (w *webRTCStreamLayer) newPeerConnection(
 
 	if createDataChannel {
 		// Create a datachannel with label 'data'
-		dataChannel, err := peerConnection.CreateDataChannel("data", nil)
+		dataChannel, err := peerConnection.CreateDataChannel("data", w.dataChannelConfig.init())
 		if err != nil {
 			return nil, err
 		}
@@ -167,7 +171,7 @@ func (w *webRTCStreamLayer) pipeDataChannel(dataChannel *webrtc.DataChannel, con
 		// keep track of channel so we can close it later
 		w.setDataChannel(*dataChannel.ID(), raw)
 
-		connCh <- newWebRTCConn(raw)
+		connCh <- newWebRTCConn(raw, dataChannel, w.dataChannelConfig)
 	})
 
 	return nil
@@ -31,7 +31,7 @@ func TestWebRTCStreamLayerWithWampSignal(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	stream1 := newWebRTCStreamLayer(wampSignal1, config.DefaultICEServers(), common.NewTestEntry(t, common.TestLogLevel))
+	stream1 := newWebRTCStreamLayer(wampSignal1, config.DefaultICEServers(), DataChannelConfig{}, common.NewTestEntry(t, common.TestLogLevel))
 	defer stream1.Close()
 
 	go func() {
@@ -41,7 +41,7 @@ func TestWebRTCStreamLayerWithWampSignal(t *testing.T) {
 		}
 	}()
 
-	stream2 := newWebRTCStreamLayer(wampSignal2, config.DefaultICEServers(), common.NewTestEntry(t, common.TestLogLevel))
+	stream2 := newWebRTCStreamLayer(wampSignal2, config.DefaultICEServers(), DataChannelConfig{}, common.NewTestEntry(t, common.TestLogLevel))
 	defer stream2.Close()
 
 	_, err = stream2.Dial("alice", 5*time.Second)
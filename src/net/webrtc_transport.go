@@ -8,18 +8,47 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// DataChannelConfig configures the WebRTC DataChannels. The zero value is an
+// ordered and reliable channel, without backpressure.
+type DataChannelConfig struct {
+	// Unordered lets the messages of the channel be delivered out of order,
+	// and MaxRetransmits, when not nil, limits the number of retransmissions
+	// of a lost message. The RPCs are streamed over the channel, so an RPC
+	// whose messages are reordered or lost fails, instead of waiting for
+	// retransmissions. They are chosen by the node which opens the channel.
+	Unordered      bool
+	MaxRetransmits *uint16
+
+	// BufferedAmountHigh, when not 0, is the number of bytes waiting to be
+	// sent above which the writes to the channel wait, until the amount falls
+	// under BufferedAmountLow or the write deadline passes.
+	BufferedAmountHigh uint64
+	BufferedAmountLow  uint64
+}
+
+// init returns the parameters of a new DataChannel.
+func (c DataChannelConfig) init() *webrtc.DataChannelInit {
+	ordered := !c.Unordered
+
+	return &webrtc.DataChannelInit{
+		Ordered:        &ordered,
+		MaxRetransmits: c.MaxRetransmits,
+	}
+}
+
 // NewWebRTCTransport returns a NetworkTransport that is built on top of a
 // WebRTC StreamLayer. The signal is a mechanism for peers to exchange
 // connection information prior to establishing a direct p2p link.
 func NewWebRTCTransport(
 	signal signal.Signal,
 	iceServers []webrtc.ICEServer,
+	dataChannel DataChannelConfig,
 	maxPool int,
 	timeout time.Duration,
 	joinTimeout time.Duration,
 	logger *logrus.Entry,
 ) (*NetworkTransport, error) {
-	return newWebRTCTransport(signal, iceServers, maxPool, timeout, joinTimeout, logger, func(stream StreamLayer) *NetworkTransport {
+	return newWebRTCTransport(signal, iceServers, dataChannel, maxPool, timeout, joinTimeout, logger, func(stream StreamLayer) *NetworkTransport {
 		return NewNetworkTransport(stream, maxPool, timeout, joinTimeout, logger)
 	})
 }
@@ -27,6 +56,7 @@ func NewWebRTCTransport(
 func newWebRTCTransport(
 	signal signal.Signal,
 	iceServers []webrtc.ICEServer,
+	dataChannel DataChannelConfig,
 	maxPool int,
 	timeout time.Duration,
 	joinTimeout time.Duration,
@@ -34,7 +64,7 @@ func newWebRTCTransport(
 	transportCreator func(stream StreamLayer) *NetworkTransport) (*NetworkTransport, error) {
 
 	// Create stream
-	stream := newWebRTCStreamLayer(signal, iceServers, logger)
+	stream := newWebRTCStreamLayer(signal, iceServers, dataChannel, logger)
 
 	go stream.listen()
 
@@ -0,0 +1,81 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+// ChangeAddress submits an InternalTransaction, signed by the validator, to
+// announce a new network address to the other validators, and waits for it to
+// go through consensus. Only the NetAddr of the validator changes; the other
+// fields of its peer are kept.
+func (n *Node) ChangeAddress(addr string) error {
+	if strings.TrimSpace(addr) == "" {
+		return fmt.Errorf("Address cannot be empty")
+	}
+
+	n.coreLock.Lock()
+
+	p, ok := n.core.validators.ByID[n.core.validator.ID()]
+	if !ok {
+		n.coreLock.Unlock()
+		return fmt.Errorf("Only validators can change their address")
+	}
+
+	if p.NetAddr == addr {
+		n.coreLock.Unlock()
+		return fmt.Errorf("Address is already %s", addr)
+	}
+
+	itx := hg.NewInternalTransactionAddress(*p, addr)
+	itx.Sign(n.core.validator.Key)
+
+	promise := n.core.addInternalTransaction(itx)
+
+	n.coreLock.Unlock()
+
+	n.logger.WithField("addr", addr).Info("Changing address")
+
+	select {
+	case resp := <-promise.respCh:
+		if !resp.accepted {
+			return fmt.Errorf("Address change refused")
+		}
+	case <-n.clock.After(n.conf.JoinTimeout):
+		return fmt.Errorf("Timeout waiting for the address change to go through consensus")
+	}
+
+	return nil
+}
+
+// GetAddress returns the address of the validator in the validator-set, or an
+// empty string if it is not a validator.
+func (n *Node) GetAddress() string {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	if p, ok := n.core.validators.ByID[n.core.validator.ID()]; ok {
+		return p.NetAddr
+	}
+
+	return ""
+}
+
+// announceAddress changes the address of the validator to the advertised
+// address of the transport, if they differ.
+func (n *Node) announceAddress() {
+	if n.trans == nil {
+		return
+	}
+
+	addr := n.trans.AdvertiseAddr()
+	if current := n.GetAddress(); addr == "" || current == "" || current == addr {
+		return
+	}
+
+	if err := n.ChangeAddress(addr); err != nil {
+		n.logger.WithError(err).Warn("Announcing address")
+	}
+}
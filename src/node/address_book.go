@@ -0,0 +1,59 @@
+package node
+
+import (
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// newMemAddressBook creates an address book that is only kept in memory.
+func newMemAddressBook() *peers.AddressBook {
+	// an address book without a directory cannot fail
+	book, _ := peers.NewAddressBook("")
+	return book
+}
+
+// SetAddressBook replaces the in-memory address book of the node, usually with
+// one backed by a file in the data directory. It must be called before Init.
+func (n *Node) SetAddressBook(book *peers.AddressBook) {
+	n.addrBook = book
+}
+
+// GetAddressBook returns the entries of the address book of the node.
+func (n *Node) GetAddressBook() []peers.AddressBookEntry {
+	return n.addrBook.Entries()
+}
+
+// peerAddr returns the address at which to contact a peer. It is the NetAddr
+// of the peer, unless another known address of the peer worked more recently,
+// or the NetAddr failed.
+func (n *Node) peerAddr(peer *peers.Peer) string {
+	return n.addrBook.BestAddr(peer.PubKeyString(), peer.NetAddr)
+}
+
+// recordContact records the outcome of an RPC to a peer in the address book.
+func (n *Node) recordContact(peer *peers.Peer, addr string, err error) {
+	if err != nil {
+		n.addrBook.Failed(peer.PubKeyString(), addr)
+		return
+	}
+
+	if err := n.addrBook.Seen(peer.PubKeyString(), addr, n.clock.Now()); err != nil {
+		n.logger.WithError(err).Warn("Saving address book")
+	}
+}
+
+// learnPeers adds the addresses of a PeerSet to the address book.
+func (n *Node) learnPeers(ps *peers.PeerSet) {
+	if ps == nil {
+		return
+	}
+
+	for _, p := range ps.Peers {
+		n.learnPeer(p)
+	}
+}
+
+func (n *Node) learnPeer(peer *peers.Peer) {
+	if err := n.addrBook.Learn(peer); err != nil {
+		n.logger.WithError(err).Warn("Saving address book")
+	}
+}
@@ -0,0 +1,104 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestAddressChange(t *testing.T) {
+	cores, _, _ := initCores(4, t)
+	c := cores[0]
+
+	self := c.validators.ByID[c.validator.ID()]
+	self.Moniker = "node0"
+
+	key, _ := keys.GenerateECDSAKey()
+	stranger := peers.NewPeer(keys.PublicKeyHex(&key.PublicKey), "10.0.0.9:1337", "stranger")
+
+	change := hg.NewInternalTransactionAddress(*self, "203.0.113.7:1337")
+	emptyChange := hg.NewInternalTransactionAddress(*c.validators.Peers[1], "")
+	strangerChange := hg.NewInternalTransactionAddress(*stranger, "10.0.0.10:1337")
+
+	promise := c.addInternalTransaction(strangerChange)
+
+	receipts := []hg.InternalTransactionReceipt{
+		change.AsAccepted(),
+		emptyChange.AsAccepted(),
+		strangerChange.AsAccepted(),
+	}
+
+	if err := c.processAcceptedInternalTransactions(0, 0, receipts); err != nil {
+		t.Fatal(err)
+	}
+
+	p := c.validators.ByID[c.validator.ID()]
+	if p.NetAddr != "203.0.113.7:1337" {
+		t.Fatalf("The address of the validator should be 203.0.113.7:1337, not %s", p.NetAddr)
+	}
+	if p.Moniker != "node0" {
+		t.Fatalf("The moniker of the validator should be kept, not %s", p.Moniker)
+	}
+	if self.NetAddr == p.NetAddr {
+		t.Fatalf("The previous peer-set should not be modified")
+	}
+	if c.peers.ByID[c.validator.ID()].NetAddr != "203.0.113.7:1337" {
+		t.Fatalf("The peers should know the new address")
+	}
+
+	if _, ok := c.validators.ByID[stranger.ID()]; ok {
+		t.Fatalf("Peers that are not validators cannot change their address")
+	}
+
+	resp := <-promise.respCh
+	if resp.accepted {
+		t.Fatalf("The address change of a stranger should be refused")
+	}
+
+	// A later announcement carries the next sequence. Once it is applied, the
+	// first announcement, and a rename signed before it, cannot restore the
+	// previous address.
+	rename := hg.NewInternalTransactionRename(*p, "node0b")
+	newer := hg.NewInternalTransactionAddress(*p, "203.0.113.8:1337")
+	if newer.Body.Peer.AddressSequence != 2 {
+		t.Fatalf("The second announcement should have the sequence 2, not %d", newer.Body.Peer.AddressSequence)
+	}
+
+	if err := c.processAcceptedInternalTransactions(1, 1, []hg.InternalTransactionReceipt{newer.AsAccepted()}); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := c.addInternalTransaction(change)
+
+	receipts = []hg.InternalTransactionReceipt{
+		change.AsAccepted(),
+		rename.AsAccepted(),
+	}
+
+	if err := c.processAcceptedInternalTransactions(2, 2, receipts); err != nil {
+		t.Fatal(err)
+	}
+
+	resp = <-stale.respCh
+	if resp.accepted {
+		t.Fatalf("A stale address announcement should be refused")
+	}
+
+	p = c.validators.ByID[c.validator.ID()]
+	if p.NetAddr != "203.0.113.8:1337" || p.AddressSequence != 2 {
+		t.Fatalf("The address of the validator should still be 203.0.113.8:1337 with sequence 2, not %s with %d", p.NetAddr, p.AddressSequence)
+	}
+	if p.Moniker != "node0b" {
+		t.Fatalf("The rename should be applied, not %s", p.Moniker)
+	}
+
+	changes, err := c.membershipChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes[0].Type != "PEER_ADDRESS" || changes[1].Type != "PEER_ADDRESS" || changes[2].Type != "PEER_RENAME" {
+		t.Fatalf("There should be two PEER_ADDRESS and a PEER_RENAME, not %v", changes)
+	}
+}
@@ -0,0 +1,160 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+)
+
+// lagCheckInterval is the period at which the node compares its last Block
+// with the Blocks signed by the other validators.
+const lagCheckInterval = time.Second
+
+// AlertKind identifies the problem reported by an Alert.
+type AlertKind string
+
+const (
+	// SuspendedAlert is raised when the node suspends itself, because of too
+	// many undetermined events, or because it was evicted.
+	SuspendedAlert AlertKind = "suspended"
+	// ForkAlert is raised when a validator created two different Events with
+	// the same index.
+	ForkAlert AlertKind = "fork"
+	// DivergenceAlert is raised when a validator signed a different version
+	// of a Block, usually because its App returned a different state hash.
+	DivergenceAlert AlertKind = "divergence"
+	// EvictionAlert is raised when a peer is removed from the validator-set.
+	EvictionAlert AlertKind = "eviction"
+	// BehindAlert is raised when the other validators have signed Blocks
+	// further than AlertBlockLag ahead of the last Block of the node.
+	BehindAlert AlertKind = "behind"
+	// StalledAlert is raised by the watchdog when consensus is stalled.
+	StalledAlert AlertKind = "stalled"
+	// DoubleSignAlert is raised when a validator signed two different Blocks
+	// with the same index.
+	DoubleSignAlert AlertKind = "double_sign"
+	// EquivocationAlert is raised when a peer signed two different
+	// InternalTransactions of the same type, accepted in the same Block.
+	EquivocationAlert AlertKind = "equivocation"
+	// CorruptionAlert is raised by the audit when a stored Block, or its
+	// Frame, differs from the one re-derived from the consensus Events.
+	CorruptionAlert AlertKind = "corruption"
+	// GenesisAlert is raised when a peer reports a genesis state hash which
+	// differs from that of the App.
+	GenesisAlert AlertKind = "genesis"
+)
+
+// Alert describes a problem that requires the attention of an operator. It is
+// published in an AlertNotification.
+type Alert struct {
+	Kind    AlertKind `json:"kind"`
+	Message string    `json:"message"`
+	NodeID  uint32    `json:"node_id"`
+	Moniker string    `json:"moniker"`
+	Time    time.Time `json:"time"`
+}
+
+// alert logs and publishes an Alert. It must be called with the coreLock.
+func (c *core) alert(kind AlertKind, format string, args ...interface{}) {
+	alert := &Alert{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+		NodeID:  c.validator.ID(),
+		Moniker: c.validator.Moniker,
+		Time:    time.Now(),
+	}
+
+	c.logger.WithField("kind", kind).Warn(alert.Message)
+
+	c.notifier.publishAlert(alert)
+}
+
+// reportFork raises a ForkAlert, once per fork, since the peers keep sending
+// the forked Events, and records the Evidence of the fork.
+func (c *core) reportFork(fork hg.ForkError, event *hg.Event) {
+	key := fmt.Sprintf("%s/%d", fork.Creator, fork.Index)
+	if c.reportedForks[key] {
+		return
+	}
+	c.reportedForks[key] = true
+
+	if known, err := c.hg.Store.GetEvent(fork.Known); err == nil {
+		if evidence, err := hg.NewForkEvidence(known, event); err == nil {
+			c.recordEvidence(evidence)
+		}
+	}
+
+	creator := fork.Creator
+	if p, ok := c.validators.ByPubKey[fork.Creator]; ok {
+		creator = fmt.Sprintf("%s (%s)", p.Moniker, p.NetAddr)
+	}
+
+	c.alert(ForkAlert, "Validator %s created two Events with index %d: %s and %s",
+		creator, fork.Index, fork.Known, fork.Event)
+}
+
+// reportInvalidSignature is the InvalidSignatureCallback of the hashgraph. It
+// raises a DivergenceAlert, once per signature, since invalid signatures stay
+// in the signature pool.
+func (c *core) reportInvalidSignature(block *hg.Block, bs hg.BlockSignature) {
+	key := bs.Key()
+	if c.reportedSignatures[key] {
+		return
+	}
+	c.reportedSignatures[key] = true
+
+	validator := bs.ValidatorHex()
+	if p, ok := c.validators.ByPubKey[validator]; ok {
+		validator = fmt.Sprintf("%s (%s)", p.Moniker, p.NetAddr)
+	}
+
+	c.alert(DivergenceAlert, "Validator %s signed a different version of Block %d, whose state hash here is %X",
+		validator, block.Index(), block.StateHash())
+}
+
+// blockLag returns the number of Blocks between the last Block of the node and
+// the highest Block signed by another validator, or 0 if none is ahead.
+func (c *core) blockLag() int {
+	highest := c.hg.Store.LastBlockIndex()
+	for _, bs := range c.hg.PendingSignatures.Items() {
+		if bs.Index > highest {
+			highest = bs.Index
+		}
+	}
+	return highest - c.hg.Store.LastBlockIndex()
+}
+
+// monitorLag periodically raises a BehindAlert when the node falls more than
+// maxLag Blocks behind the other validators, until the node shuts down. The
+// alert is raised again once the node has caught up and falls behind again.
+func (n *Node) monitorLag(maxLag int) {
+	ticker := n.clock.NewTicker(lagCheckInterval)
+	defer ticker.Stop()
+
+	behind := false
+
+	for {
+		select {
+		case <-ticker.C():
+			if n.GetState() != _state.Babbling {
+				continue
+			}
+
+			n.coreLock.RLock()
+			lag := n.core.blockLag()
+			switch {
+			case lag > maxLag && !behind:
+				behind = true
+				n.core.alert(BehindAlert, "The node is %d Blocks behind the other validators", lag)
+			case lag <= maxLag && behind:
+				behind = false
+				n.logger.WithField("lag", lag).Info("The node caught up with the other validators")
+			}
+			n.coreLock.RUnlock()
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
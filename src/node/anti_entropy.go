@@ -0,0 +1,122 @@
+package node
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// antiEntropy periodically repairs the events missing on either side of a
+// random peer, until the node shuts down. It is independent of the gossip
+// loop, so that it is not affected by the peer selection, the sync
+// deduplication, or the control timer, which stops when there is nothing to
+// gossip about. Under a lossy transport, this guarantees that every event is
+// eventually delivered.
+func (n *Node) antiEntropy(interval time.Duration) {
+	ticker := n.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			if n.GetState() != _state.Babbling {
+				continue
+			}
+
+			peer := n.antiEntropyPeer()
+			if peer == nil || n.isBanned(peer.NetAddr) {
+				continue
+			}
+
+			if exceeded, _ := n.dataBudget.exceeded(n.clock.Now()); exceeded {
+				continue
+			}
+
+			if err := n.repair(peer); err != nil {
+				n.logger.WithError(err).Debug("Anti-entropy repair")
+			}
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// antiEntropyPeer returns a random peer other than this node, or nil if there
+// is none.
+func (n *Node) antiEntropyPeer() *peers.Peer {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	others := make([]*peers.Peer, 0, n.core.peers.Len())
+	for _, p := range n.core.peers.Peers {
+		if p.ID() != n.core.validator.ID() {
+			others = append(others, p)
+		}
+	}
+
+	if len(others) == 0 {
+		return nil
+	}
+
+	return others[rand.Intn(len(others))]
+}
+
+// repair compares the known events of the node with those of the peer. It
+// inserts the events that the peer knows and the node does not, and pushes
+// the events that the node knows and the peer does not, even if they were
+// recently sent to it.
+func (n *Node) repair(peer *peers.Peer) (err error) {
+	ctx, span := tracing.Start(context.Background(), "node.repair")
+	defer func() { tracing.End(span, err) }()
+
+	addr := n.peerAddr(peer)
+	defer func() { n.recordContact(peer, addr, err) }()
+
+	n.coreLock.RLock()
+	knownEvents := n.core.knownEvents()
+	n.coreLock.RUnlock()
+
+	resp, err := n.requestSync(ctx, addr, knownEvents, n.conf.SyncLimit, nil)
+	if err != nil {
+		return err
+	}
+
+	n.updatePeerKnown(resp.Known)
+
+	n.coreLock.Lock()
+	err = n.sync(ctx, peer.ID(), resp.Events)
+	n.coreLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	eventDiff, wireEvents, err := n.eventsToPush(peer.ID(), resp.Known, false)
+	if err != nil {
+		return err
+	}
+
+	if len(wireEvents) > 0 {
+		if _, err := n.requestEagerSync(ctx, addr, wireEvents); err != nil {
+			return err
+		}
+		n.sentEvents.record(peer.ID(), eventDiff, n.clock.Now())
+	}
+
+	if repaired := len(resp.Events) + len(wireEvents); repaired > 0 {
+		metrics.EventsRepaired.Add(float64(repaired))
+		n.recordEvents(peer.ID(), len(wireEvents), len(resp.Events))
+		n.logger.WithFields(logrus.Fields{
+			"peer_id":  peer.ID(),
+			"received": len(resp.Events),
+			"sent":     len(wireEvents),
+		}).Debug("Anti-entropy repair")
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+func TestAntiEntropyRepair(t *testing.T) {
+	keys, peers := initPeers(t, 2)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 10*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	// Without gossip, the nodes only exchange events through repairs
+	for _, n := range nodes {
+		n.RunAsync(false)
+
+		n.coreLock.Lock()
+		err := n.core.addSelfEvent("")
+		n.coreLock.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Events recently sent are not skipped by repairs
+	nodes[0].sentEvents.setWindow(time.Hour)
+	nodes[0].coreLock.Lock()
+	head, err := nodes[0].core.getHead()
+	nodes[0].coreLock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes[0].sentEvents.record(nodes[1].GetID(), []*hg.Event{head}, time.Now())
+
+	if err := nodes[0].repair(peers.Peers[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, n := range nodes {
+		known := n.core.knownEvents()
+		for _, p := range peers.Peers {
+			if known[p.ID()] < 0 {
+				t.Fatalf("Node %d should know the first event of %d, known: %v", i, p.ID(), known)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package node
+
+import (
+	"time"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/metrics"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+)
+
+// audit periodically re-derives the last blocks Blocks, and their Frames, from
+// the consensus Events in the store, until the node shuts down. It raises a
+// CorruptionAlert, once per Block, when a Block differs from the one
+// re-derived, which reveals a silent corruption of the store, or a bug.
+func (n *Node) audit(interval time.Duration, blocks int) {
+	ticker := n.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	reported := make(map[int]bool)
+
+	for {
+		select {
+		case <-ticker.C():
+			if n.GetState() != _state.Babbling {
+				continue
+			}
+
+			n.auditBlocks(blocks, reported)
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// auditBlocks audits the last blocks Blocks, and raises a CorruptionAlert for
+// those which fail the audit and are not already reported. The Blocks that
+// cannot be re-derived, because their Events were evicted from the caches or
+// were received by fast-forward, are skipped.
+func (n *Node) auditBlocks(blocks int, reported map[int]bool) {
+	last := n.GetLastBlockIndex()
+
+	for i := last; i > last-blocks && i >= 0; i-- {
+		if reported[i] {
+			continue
+		}
+
+		// Re-deriving the Events updates the caches of the hashgraph
+		n.coreLock.Lock()
+		err := n.core.hg.AuditBlock(i)
+		if ae, ok := err.(hg.AuditError); ok {
+			reported[i] = true
+			metrics.AuditFailures.Inc()
+			n.core.alert(CorruptionAlert, "%v", ae)
+		} else if err != nil {
+			n.logger.WithError(err).WithField("block", i).Debug("Block cannot be audited")
+		}
+		n.coreLock.Unlock()
+	}
+}
@@ -0,0 +1,46 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditBlocks(t *testing.T) {
+	keys, peers := initPeers(t, 3)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+
+	if err := gossip(nodes, 5, true); err != nil {
+		t.Fatal(err)
+	}
+
+	node := nodes[0]
+	reported := make(map[int]bool)
+
+	node.auditBlocks(10, reported)
+	if len(reported) > 0 {
+		t.Fatalf("No Block should fail the audit: %v", reported)
+	}
+
+	// Drop an Event from the Round of the last Block
+	last := node.GetLastBlockIndex()
+	block, err := node.GetBlock(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	round, err := node.core.hg.Store.GetRound(block.RoundReceived())
+	if err != nil {
+		t.Fatal(err)
+	}
+	round.ReceivedEvents = round.ReceivedEvents[1:]
+	if err := node.core.hg.Store.SetRound(block.RoundReceived(), round); err != nil {
+		t.Fatal(err)
+	}
+
+	node.auditBlocks(10, reported)
+	if len(reported) != 1 || !reported[last] {
+		t.Fatalf("Only Block %d should fail the audit: %v", last, reported)
+	}
+}
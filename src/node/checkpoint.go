@@ -0,0 +1,247 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/net"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/sirupsen/logrus"
+)
+
+// Checkpoint is a point of the hashgraph from which a node can restart without
+// a persistent store: the anchor block, its frame, and the snapshot of the
+// application at that block. It contains the same data as a
+// FastForwardResponse, so restoring a checkpoint is like fast-forwarding from
+// the node itself.
+type Checkpoint struct {
+	Block    hg.Block
+	Frame    hg.Frame
+	Snapshot []byte
+}
+
+// Marshal returns the JSON encoding of a Checkpoint.
+func (c *Checkpoint) Marshal() ([]byte, error) {
+	var b bytes.Buffer
+
+	enc := json.NewEncoder(&b)
+
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Unmarshal parses a JSON encoded Checkpoint.
+func (c *Checkpoint) Unmarshal(data []byte) error {
+	b := bytes.NewBuffer(data)
+
+	dec := json.NewDecoder(b)
+
+	return dec.Decode(c)
+}
+
+// GetCheckpoint returns a Checkpoint at the anchor block of the node, which is
+// the last block with enough signatures to be trusted by other nodes. It
+// returns an error if there is no anchor block yet.
+func (n *Node) GetCheckpoint() (*Checkpoint, error) {
+	n.coreLock.Lock()
+	block, frame, err := n.core.getAnchorBlockWithFrame()
+	n.coreLock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := n.proxy.GetSnapshot(block.Index())
+	if err != nil {
+		return nil, fmt.Errorf("Getting Snapshot: %v", err)
+	}
+
+	return &Checkpoint{
+		Block:    *block,
+		Frame:    *frame,
+		Snapshot: snapshot,
+	}, nil
+}
+
+// RestoreCheckpoint resets the hashgraph and the application from a
+// Checkpoint. It is called after Init, and before Run, to restart a node with
+// an in-memory store from a Checkpoint saved by the application. The node then
+// babbles, or fast-forwards if fast-sync is enabled, from the Checkpoint.
+func (n *Node) RestoreCheckpoint(checkpoint *Checkpoint) error {
+	if n.conf.MaintenanceMode {
+		return fmt.Errorf("Cannot restore a checkpoint in maintenance-mode")
+	}
+
+	n.logger.WithField("block", checkpoint.Block.Index()).Info("Restoring Checkpoint")
+
+	if err := n.restore(&checkpoint.Block, &checkpoint.Frame, checkpoint.Snapshot); err != nil {
+		return err
+	}
+
+	if _, ok := n.core.validators.ByID[n.core.validator.ID()]; ok {
+		n.setBabblingOrCatchingUpState()
+	} else {
+		n.transition(_state.Joining)
+	}
+
+	return nil
+}
+
+// restore resets the application from a snapshot, and the hashgraph from a
+// block and its frame.
+func (n *Node) restore(block *hg.Block, frame *hg.Frame, snapshot []byte) error {
+	if err := n.verifySnapshot(block, frame, snapshot); err != nil {
+		n.logger.WithError(err).Error("Verifying Snapshot")
+		return err
+	}
+
+	err := n.proxy.Restore(snapshot)
+	if err != nil {
+		n.logger.WithError(err).Error("Restoring App from Snapshot")
+		return fmt.Errorf("Restoring App from Snapshot: %v", err)
+	}
+
+	// The App is at the Block again, so it can restore differences from it.
+	n.noSnapshotDelta = false
+
+	return n.restoreHashgraph(block, frame)
+}
+
+// TrustedBlock is a recent block, obtained out of band from a trusted source,
+// with the validator-set that signed it. A new node given a TrustedBlock
+// fast-forwards from that block, and syncs forward from there, without ever
+// downloading the history before it. Unlike a regular fast-forward, where the
+// node trusts the block of whichever peer is the most ahead, the peers can
+// only provide the block whose hash is trusted.
+type TrustedBlock struct {
+	Index      int
+	Hash       []byte
+	Validators *peers.PeerSet
+}
+
+// ParseTrustedBlock parses a trusted block given as INDEX:HASH, where HASH is
+// the hex encoded hash of the block, with the 0X prefix, and validators is the
+// validator-set of the block.
+func ParseTrustedBlock(s string, validators *peers.PeerSet) (*TrustedBlock, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("trusted block %q is not an INDEX:HASH pair", s)
+	}
+
+	index, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || index < 0 {
+		return nil, fmt.Errorf("invalid trusted block index %q", parts[0])
+	}
+
+	hashHex := strings.ToUpper(strings.TrimSpace(parts[1]))
+	if !strings.HasPrefix(hashHex, "0X") {
+		return nil, fmt.Errorf("invalid trusted block hash %q", parts[1])
+	}
+
+	hash, err := common.DecodeFromString(hashHex)
+	if err != nil || len(hash) == 0 {
+		return nil, fmt.Errorf("invalid trusted block hash %q", parts[1])
+	}
+
+	if validators == nil || validators.Len() == 0 {
+		return nil, fmt.Errorf("the trusted block needs a validator-set")
+	}
+
+	return &TrustedBlock{
+		Index:      index,
+		Hash:       hash,
+		Validators: validators,
+	}, nil
+}
+
+// verify checks that a FastForwardResponse contains the trusted block, and
+// that its frame has the trusted validator-set. The signatures of the block
+// and the hash of the frame are then checked against them by the core.
+func (t *TrustedBlock) verify(resp *net.FastForwardResponse) error {
+	if resp.Block.Index() != t.Index {
+		return fmt.Errorf("got block %d instead of trusted block %d", resp.Block.Index(), t.Index)
+	}
+
+	hash, err := resp.Block.Hash()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(hash, t.Hash) {
+		return fmt.Errorf("block %d has hash %s instead of trusted hash %s",
+			t.Index, common.EncodeToString(hash), common.EncodeToString(t.Hash))
+	}
+
+	// The validators are compared regardless of their order
+	frameValidators := peers.NewPeerSet(resp.Frame.Peers)
+	if frameValidators.Len() != t.Validators.Len() {
+		return fmt.Errorf("the frame of block %d does not have the trusted validator-set", t.Index)
+	}
+	for _, pubKey := range t.Validators.PubKeys() {
+		if _, ok := frameValidators.ByPubKey[pubKey]; !ok {
+			return fmt.Errorf("the frame of block %d does not have the trusted validator-set", t.Index)
+		}
+	}
+
+	return nil
+}
+
+// SetTrustedBlock makes the node fast-forward from a trusted block when it
+// starts. It must be called after NewNode, and before Init.
+func (n *Node) SetTrustedBlock(tb *TrustedBlock) {
+	n.trustedBlock = tb
+}
+
+// fastForwardToTrustedBlock requests the trusted block from the peers, and
+// fast-forwards from the first response that matches it. If no peer provides
+// the trusted block, the node remains in the CatchingUp state, and tries again
+// later.
+func (n *Node) fastForwardToTrustedBlock() error {
+	tb := n.trustedBlock
+	index := tb.Index
+
+	for _, p := range n.core.peerSelector.getPeers().Peers {
+		addr := n.peerAddr(p)
+		resp, err := n.requestFastForward(addr, &index)
+		n.recordContact(p, addr, err)
+		if err == nil {
+			err = tb.verify(&resp)
+		}
+		if err != nil {
+			n.logger.WithFields(logrus.Fields{
+				"peer":  p.Moniker,
+				"error": err,
+			}).Warn("Cannot fast-forward to trusted block")
+			continue
+		}
+
+		if err := n.restoreFastForward(&resp); err != nil {
+			n.core.notifier.publishError(err)
+			return err
+		}
+
+		n.logger.WithField("block", index).Info("Fast-forwarded to trusted block")
+
+		n.trustedBlock = nil
+		n.transition(_state.Babbling)
+
+		return nil
+	}
+
+	err := fmt.Errorf("no peer provided trusted block %d", index)
+	n.core.notifier.publishError(fmt.Errorf("FastForward: %v", err))
+
+	n.clock.Sleep(2000 * time.Millisecond)
+
+	return err
+}
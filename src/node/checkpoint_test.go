@@ -0,0 +1,146 @@
+package node
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint(t *testing.T) {
+	keys, peers := initPeers(t, 1)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	node := nodes[0]
+
+	if _, err := node.GetCheckpoint(); err == nil {
+		t.Fatal("There should be no checkpoint without an anchor block")
+	}
+
+	if err := gossip(nodes, 5, false); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err := node.GetCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node.Shutdown()
+
+	data, err := checkpoint.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saved Checkpoint
+	if err := saved.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new in-memory node restarts from the checkpoint
+	restored := recycleNode(node, t)
+	defer restored.Shutdown()
+
+	if err := restored.RestoreCheckpoint(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	if i := restored.GetLastBlockIndex(); i != checkpoint.Block.Index() {
+		t.Fatalf("Last block should be %d, not %d", checkpoint.Block.Index(), i)
+	}
+
+	if err := gossip([]*Node{restored}, checkpoint.Block.Index()+3, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTrustedBlock(t *testing.T) {
+	_, peers := initPeers(t, 2)
+
+	tb, err := ParseTrustedBlock("12:0X01AB", peers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.Index != 12 || !bytes.Equal(tb.Hash, []byte{0x01, 0xAB}) {
+		t.Fatalf("Wrong trusted block %d:%X", tb.Index, tb.Hash)
+	}
+
+	for _, s := range []string{"12", "-1:0X01AB", "a:0X01AB", "12:", "12:0XZZ"} {
+		if _, err := ParseTrustedBlock(s, peers); err == nil {
+			t.Fatalf("Parsing %q should fail", s)
+		}
+	}
+
+	if _, err := ParseTrustedBlock("12:0X01AB", nil); err == nil {
+		t.Fatal("A trusted block should require a validator-set")
+	}
+}
+
+func TestFastForwardToTrustedBlock(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	if err := gossip(nodes[1:], 10, false); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes[1].coreLock.Lock()
+	block, _, err := nodes[1].core.getAnchorBlockWithFrame()
+	nodes[1].coreLock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := block.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The peers cannot provide a block with another hash
+	nodes[0].SetTrustedBlock(&TrustedBlock{
+		Index:      block.Index(),
+		Hash:       []byte("wrong hash"),
+		Validators: peers,
+	})
+
+	if err := nodes[0].fastForward(); err == nil {
+		t.Fatal("Fast-forwarding to an unknown block should fail")
+	}
+	if lbi := nodes[0].core.getLastBlockIndex(); lbi != -1 {
+		t.Fatalf("The node should not have fast-forwarded, but its last block is %d", lbi)
+	}
+
+	// Nor a block of another validator-set
+	_, otherPeers := initPeers(t, 4)
+	nodes[0].SetTrustedBlock(&TrustedBlock{
+		Index:      block.Index(),
+		Hash:       hash,
+		Validators: otherPeers,
+	})
+
+	if err := nodes[0].fastForward(); err == nil {
+		t.Fatal("Fast-forwarding with the wrong validator-set should fail")
+	}
+
+	nodes[0].SetTrustedBlock(&TrustedBlock{
+		Index:      block.Index(),
+		Hash:       hash,
+		Validators: peers,
+	})
+
+	if err := nodes[0].fastForward(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lbi := nodes[0].core.getLastBlockIndex(); lbi != block.Index() {
+		t.Fatalf("The last block should be the trusted block %d, not %d", block.Index(), lbi)
+	}
+
+	if nodes[0].trustedBlock != nil {
+		t.Fatal("The trusted block should be cleared once the node has synced from it")
+	}
+}
@@ -0,0 +1,154 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mosaicnetworks/babble/src/net"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+)
+
+// Resume takes the node out of the Suspended state. The count of undetermined
+// events, which triggers the suspension, is reset. A node that was evicted
+// from the validator-set requests to join again.
+func (n *Node) Resume() error {
+	if n.conf.MaintenanceMode {
+		return fmt.Errorf("Cannot resume in maintenance-mode")
+	}
+
+	if state := n.GetState(); state != _state.Suspended {
+		return fmt.Errorf("Cannot resume from %s state", state)
+	}
+
+	n.logger.Info("RESUME")
+
+	n.coreLock.Lock()
+	n.initialUndeterminedEvents = len(n.core.getUndeterminedEvents())
+	evicted := n.core.removedRound > 0 && n.core.removedRound > n.core.acceptedRound
+	n.coreLock.Unlock()
+
+	// suspendCh was closed by Suspend
+	n.suspendCh = make(chan struct{})
+
+	n.suspensions.clear()
+
+	if evicted {
+		n.logger.Debug("Node was evicted => Joining")
+		n.transition(_state.Joining)
+	} else {
+		n.setBabblingOrCatchingUpState()
+	}
+
+	return nil
+}
+
+// ForceFastForward causes a Babbling node to fast-forward to the tip of the
+// hashgraph, from the peer with the most advanced block, even if fast-sync is
+// not enabled.
+func (n *Node) ForceFastForward() error {
+	if state := n.GetState(); state != _state.Babbling {
+		return fmt.Errorf("Cannot fast-forward from %s state", state)
+	}
+
+	select {
+	case n.fastForwardCh <- struct{}{}:
+	default:
+		// A fast-forward is already pending
+	}
+
+	return nil
+}
+
+// BanPeer prevents the node from gossiping with the peer at the given address.
+// The node stops selecting the peer for gossip, and refuses its RPCs.
+func (n *Node) BanPeer(addr string) {
+	n.bannedLock.Lock()
+	defer n.bannedLock.Unlock()
+
+	n.logger.WithField("peer", addr).Info("Banning peer")
+
+	n.bannedAddrs[addr] = struct{}{}
+}
+
+// UnbanPeer lifts the ban on a peer address. It returns false if the address
+// was not banned.
+func (n *Node) UnbanPeer(addr string) bool {
+	n.bannedLock.Lock()
+	defer n.bannedLock.Unlock()
+
+	if _, ok := n.bannedAddrs[addr]; !ok {
+		return false
+	}
+
+	n.logger.WithField("peer", addr).Info("Unbanning peer")
+
+	delete(n.bannedAddrs, addr)
+
+	return true
+}
+
+// GetBannedPeers returns the sorted list of banned peer addresses.
+func (n *Node) GetBannedPeers() []string {
+	n.bannedLock.Lock()
+	defer n.bannedLock.Unlock()
+
+	res := make([]string, 0, len(n.bannedAddrs))
+	for addr := range n.bannedAddrs {
+		res = append(res, addr)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// GetFilter returns the Filter of the transport, which refuses the connections
+// and RPCs of unwanted peers before they reach the node, or nil if the
+// transport does not support filtering.
+func (n *Node) GetFilter() *net.Filter {
+	if ft, ok := n.trans.(net.FilteredTransport); ok {
+		return ft.Filter()
+	}
+	return nil
+}
+
+func (n *Node) isBanned(addr string) bool {
+	n.bannedLock.Lock()
+	defer n.bannedLock.Unlock()
+
+	_, ok := n.bannedAddrs[addr]
+
+	return ok
+}
+
+// rpcSender returns the address of the peer that sent an RPC, or an empty
+// string if it is unknown. JoinRequests carry the address of the joining peer;
+// the other requests are identified by the ID of a known peer.
+func (n *Node) rpcSender(rpc net.RPC) string {
+	var fromID uint32
+
+	switch cmd := rpc.Command.(type) {
+	case *net.JoinRequest:
+		return cmd.InternalTransaction.Body.Peer.NetAddr
+	case *net.SyncRequest:
+		fromID = cmd.FromID
+	case *net.EagerSyncRequest:
+		fromID = cmd.FromID
+	case *net.FastForwardRequest:
+		fromID = cmd.FromID
+	case *net.PingRequest:
+		fromID = cmd.FromID
+	case *net.InfoRequest:
+		fromID = cmd.FromID
+	default:
+		return ""
+	}
+
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	if peer, ok := n.core.peers.ByID[fromID]; ok {
+		return peer.NetAddr
+	}
+
+	return ""
+}
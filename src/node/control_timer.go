@@ -2,28 +2,37 @@ package node
 
 import (
 	"math/rand"
+	"sync"
 	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/metrics"
 )
 
 type timerFactory func(time.Duration) <-chan time.Time
 
 // controlTimer controls the node's heartbeat.
 type controlTimer struct {
+	clock        common.Clock
 	timerFactory timerFactory
 	tickCh       chan struct{}      // sends a signal to listening process
 	resetCh      chan time.Duration // receives instruction to reset the heartbeatTimer
 	stopCh       chan struct{}      // receives instruction to stop the heartbeatTimer
 	shutdownCh   chan struct{}      // receives instruction to exit Run loop
-	isSet        bool
 	isShutdown   bool
+
+	lock    sync.Mutex    // guards isSet and timeout
+	isSet   bool          // true while the timer is running
+	timeout time.Duration // minimum timeout of the timer, when it is set
 }
 
 // newControlTimer is a controlTimer factory method.
-func newControlTimer(timerFactory timerFactory) *controlTimer {
+func newControlTimer(clock common.Clock, timerFactory timerFactory) *controlTimer {
 	return &controlTimer{
+		clock:        clock,
 		timerFactory: timerFactory,
 		tickCh:       make(chan struct{}),
-		resetCh:      make(chan time.Duration),
+		resetCh:      make(chan time.Duration, 1),
 		stopCh:       make(chan struct{}),
 		shutdownCh:   make(chan struct{}),
 	}
@@ -31,44 +40,73 @@ func newControlTimer(timerFactory timerFactory) *controlTimer {
 
 // newRandomcontrolTimer creates a new controlTimer that ticks at random
 // intervals between min and 2*min, where min is a specified minimum time
-// interval.
-func newRandomControlTimer() *controlTimer {
+// interval, as measured by the clock.
+func newRandomControlTimer(clock common.Clock) *controlTimer {
 	randomTimeout := func(min time.Duration) <-chan time.Time {
 		if min == 0 {
 			return nil
 		}
 		extra := (time.Duration(rand.Int63()) % min)
-		return time.After(min + extra)
+		return clock.After(min + extra)
 	}
-	return newControlTimer(randomTimeout)
+	return newControlTimer(clock, randomTimeout)
 }
 
 // run starts the Control Timer
 func (c *controlTimer) run(init time.Duration) {
 
 	setTimer := func(t time.Duration) <-chan time.Time {
-		c.isSet = true
+		c.setState(true, t)
 		return c.timerFactory(t)
 	}
 
 	timer := setTimer(init)
 	for {
 		select {
-		case <-timer:
+		case expiry := <-timer:
 			c.tickCh <- struct{}{}
-			c.isSet = false
+			c.setState(false, 0)
+			metrics.TimerDrift.Observe(c.clock.Since(expiry).Seconds())
 		case t := <-c.resetCh:
 			timer = setTimer(t)
 		case <-c.stopCh:
 			timer = nil
-			c.isSet = false
+			c.setState(false, 0)
 		case <-c.shutdownCh:
-			c.isSet = false
+			c.setState(false, 0)
 			return
 		}
 	}
 }
 
+// setState records whether the timer is running, and its timeout.
+func (c *controlTimer) setState(isSet bool, timeout time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.isSet = isSet
+	c.timeout = timeout
+}
+
+// reset sets the timer to t, without blocking. If a reset is already pending,
+// the timer is set by that one, and the heartbeat resets it again after the
+// next tick.
+func (c *controlTimer) reset(t time.Duration) {
+	select {
+	case c.resetCh <- t:
+	default:
+	}
+}
+
+// needsReset returns true if the timer is not running, or if its timeout is
+// longer than t.
+func (c *controlTimer) needsReset(t time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return !c.isSet || t < c.timeout
+}
+
 // shutdown shuts down the controlTimer
 func (c *controlTimer) shutdown() {
 	if !c.isShutdown {
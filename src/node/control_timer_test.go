@@ -0,0 +1,37 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestControlTimerResetDoesNotBlock(t *testing.T) {
+	timer := newRandomControlTimer(common.RealClock{})
+
+	// The timer is not running, like when it is blocked on a tick that the
+	// babble loop has not consumed yet
+	done := make(chan struct{})
+	go func() {
+		timer.reset(time.Second)
+		timer.reset(time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Resetting the control timer should not block")
+	}
+
+	// The pending reset sets the timer once it runs
+	go timer.run(time.Hour)
+	defer timer.shutdown()
+
+	select {
+	case <-timer.tickCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The pending reset should set the timer")
+	}
+}
@@ -1,17 +1,23 @@
 package node
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"runtime"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/envelope"
 	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/metrics"
 	"github.com/mosaicnetworks/babble/src/peers"
 	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/mosaicnetworks/babble/src/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // core is the object that is used by Node to manipulate the hashgraph
@@ -41,6 +47,10 @@ type core struct {
 	peerSelector peerSelector
 	selectorLock sync.Mutex
 
+	// latencies are the round-trip times to the peers. When they are set,
+	// the peer selector favours the peers with the lowest latency.
+	latencies *latencies
+
 	// Hash and Index of this instance's head Event
 	head string
 	seq  int
@@ -63,6 +73,25 @@ type core struct {
 	// accepted
 	lastPeerChangeRound int
 
+	// peerSetInterval, when set, delays the membership changes to the next
+	// round that is a multiple of it. It must be the same for all the
+	// validators.
+	peerSetInterval int
+
+	// upgrades records the signals of the validators for new versions of the
+	// protocol, and the activated versions.
+	upgrades *upgrades
+
+	// reportedForks and reportedSignatures record the forks and the invalid
+	// Block signatures that were already alerted about.
+	reportedForks      map[string]bool
+	reportedSignatures map[string]bool
+
+	// evidence holds the last maxEvidence proofs of misbehaviour collected by
+	// the node, signed by its validator, and reportedEvidence their keys.
+	evidence         []*hg.SignedEvidence
+	reportedEvidence map[string]bool
+
 	// Events that are not tied to this node's Head. This is managed by the Sync
 	// method. If the gossip condition is false (there is nothing interesting to
 	// record), items are added to heads; if the gossip condition is true, items
@@ -75,6 +104,10 @@ type core struct {
 	// still haven't made it into the hashgraph.
 	transactionPool [][]byte
 
+	// transactionDeadlines holds the deadline of each transaction of the
+	// transaction pool, or nil for the transactions submitted without a TTL.
+	transactionDeadlines []*txDeadline
+
 	// internalTransactionPool is the same as transactionPool but for
 	// InternalTransactions
 	internalTransactionPool []hg.InternalTransaction
@@ -86,6 +119,19 @@ type core struct {
 	// proxyCommitCallback is called by the hashgraph when a block is committed
 	proxyCommitCallback proxy.CommitCallback
 
+	// commitBarrier withholds the signature of a block until the app has
+	// applied it and returned a state hash. Once a block is not applied, the
+	// signatures of the following blocks are withheld too.
+	commitBarrier bool
+
+	// unappliedBlock is the index of the first block that the app failed to
+	// apply in commitBarrier mode, or -1.
+	unappliedBlock int
+
+	// openEnvelopes replaces the envelopes addressed to this node with their
+	// payloads in the blocks committed to the app.
+	openEnvelopes bool
+
 	// maintenanceMode is passed through the constructor to indicate whether the
 	// user of core is in maintenance mode. This is used here to disable leave
 	// requests when a node is in maintenance mode
@@ -95,6 +141,16 @@ type core struct {
 	// InternalTransactions go through consensus asynchronously.
 	promises map[string]*joinPromise
 
+	// traceCtx carries the tracing span of the operation that is currently
+	// driving the core. The hashgraph commits blocks through a callback, so
+	// this is how commit spans are attached to the sync that triggered them.
+	// It is protected by the same lock as the rest of the core.
+	traceCtx context.Context
+
+	// notifier publishes committed blocks, transactions, and validator-set
+	// changes to the subscribers of the node.
+	notifier *notifier
+
 	logger *logrus.Entry
 }
 
@@ -129,10 +185,18 @@ func newCore(
 		removedRound:            -1,
 		targetRound:             -1,
 		lastPeerChangeRound:     -1,
+		unappliedBlock:          -1,
+		upgrades:                newUpgrades(),
+		reportedForks:           make(map[string]bool),
+		reportedSignatures:      make(map[string]bool),
+		reportedEvidence:        make(map[string]bool),
 		maintenanceMode:         maintenanceMode,
+		traceCtx:                context.Background(),
+		notifier:                newNotifier(),
 	}
 
 	core.hg = hg.NewHashgraph(store, core.commit, logger)
+	core.hg.SetInvalidSignatureCallback(core.reportInvalidSignature)
 
 	core.hg.Init(genesisPeers)
 
@@ -182,10 +246,20 @@ func (c *core) bootstrap() error {
 	return c.hg.Bootstrap()
 }
 
-// setPeers sets the peers property and a New RandomPeerSelector
+// setPeers sets the peers property and a new peer selector
 func (c *core) setPeers(ps *peers.PeerSet) {
 	c.peers = ps
-	c.peerSelector = newRandomPeerSelector(c.peers, c.validator.ID())
+	c.peerSelector = c.newPeerSelector()
+}
+
+// newPeerSelector creates a peer selector for the current peers. It is a
+// latencyPeerSelector if the latencies are set, and a randomPeerSelector
+// otherwise.
+func (c *core) newPeerSelector() peerSelector {
+	if c.latencies != nil {
+		return newLatencyPeerSelector(c.peers, c.validator.ID(), c.latencies)
+	}
+	return newRandomPeerSelector(c.peers, c.validator.ID())
 }
 
 /*******************************************************************************
@@ -194,22 +268,42 @@ Busy
 
 // busy indicates whether there is some unfinished work.
 func (c *core) busy() bool {
+	return c.pending() ||
+		(c.hg.LastConsensusRound != nil && *c.hg.LastConsensusRound < c.targetRound)
+}
+
+// pending indicates whether there are events, transactions or block
+// signatures which did not go through consensus yet.
+func (c *core) pending() bool {
 	return c.hg.PendingLoadedEvents > 0 ||
 		len(c.transactionPool) > 0 ||
 		len(c.internalTransactionPool) > 0 ||
-		c.selfBlockSignatures.Len() > 0 ||
-		(c.hg.LastConsensusRound != nil && *c.hg.LastConsensusRound < c.targetRound)
+		c.selfBlockSignatures.Len() > 0
 }
 
 /*******************************************************************************
 Sync
 *******************************************************************************/
 
+// catchUpSyncSize is the number of Events above which a sync is considered to
+// be catching up with a backlog.
+const catchUpSyncSize = 100
+
 // sync decodes and inserts new Events into the Hashgraph. UnknownEvents are
-// expected to be in topoligical order.
+// expected to be in topoligical order. When catching up with a backlog, the
+// block signatures are processed as soon as the Events carrying them are
+// inserted, so that blocks are signed without waiting for the whole batch.
 func (c *core) sync(fromID uint32, unknownEvents []hg.WireEvent) error {
 	c.logger.WithField("unknown_events", len(unknownEvents)).Debug("Sync")
 
+	catchingUp := len(unknownEvents) > catchUpSyncSize
+
+	// Verify the signatures in parallel, so that inserting the events one by
+	// one does not verify them again
+	if len(unknownEvents) > 1 {
+		c.hg.VerifyWireEvents(unknownEvents, runtime.NumCPU())
+	}
+
 	var otherHead *hg.Event
 	for _, we := range unknownEvents {
 		ev, err := c.hg.ReadWireInfo(we)
@@ -227,11 +321,20 @@ func (c *core) sync(fromID uint32, unknownEvents []hg.WireEvent) error {
 			if hg.IsNormalSelfParentError(err) {
 				continue
 			} else {
+				if fork, ok := err.(hg.ForkError); ok {
+					c.reportFork(fork, ev)
+				}
 				c.logger.WithError(err).Errorf("Inserting Event")
 				return err
 			}
 		}
 
+		if catchingUp && len(we.Body.BlockSignatures) > 0 {
+			if err := c.hg.ProcessSigPool(); err != nil {
+				return err
+			}
+		}
+
 		if we.Body.CreatorID == fromID {
 			otherHead = ev
 		}
@@ -326,6 +429,7 @@ func (c *core) addSelfEvent(otherHead string) error {
 
 	// do not remove pool elements that were added by CommitCallback
 	c.transactionPool = c.transactionPool[txs:]
+	c.transactionDeadlines = c.transactionDeadlines[txs:]
 	c.internalTransactionPool = c.internalTransactionPool[itxs:]
 	c.selfBlockSignatures.RemoveSlice(sigs)
 
@@ -342,7 +446,18 @@ func (c *core) signAndInsertSelfEvent(event *hg.Event) error {
 }
 
 // insertEventAndRunConsensus Inserts a hashgraph event and runs consensus
-func (c *core) insertEventAndRunConsensus(event *hg.Event, setWireInfo bool) error {
+func (c *core) insertEventAndRunConsensus(event *hg.Event, setWireInfo bool) (err error) {
+	ctx, span := tracing.Start(c.traceCtx, "hashgraph.InsertEventAndRunConsensus")
+	span.SetAttributes(
+		attribute.String("event", event.Hex()),
+		attribute.Int("transactions", len(event.Transactions())),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	parentCtx := c.traceCtx
+	c.traceCtx = ctx
+	defer func() { c.traceCtx = parentCtx }()
+
 	if err := c.hg.InsertEventAndRunConsensus(event, setWireInfo); err != nil {
 		return err
 	}
@@ -366,37 +481,47 @@ FastForward
 // hashgraph from a Block and associated Frame.
 func (c *core) fastForward(block *hg.Block, frame *hg.Frame) error {
 	c.logger.Debug("Fast Forward", frame.Round)
-	peerSet := peers.NewPeerSet(frame.Peers)
 
-	// Check Block Signatures
-	err := c.hg.CheckBlock(block, peerSet)
+	err := c.checkAnchor(block, frame, peers.NewPeerSet(frame.Peers))
 	if err != nil {
 		return err
 	}
 
-	// Check Frame Hash
-	frameHash, err := frame.Hash()
+	err = c.hg.Reset(block, frame)
 	if err != nil {
 		return err
 	}
 
-	if !reflect.DeepEqual(block.FrameHash(), frameHash) {
-		return fmt.Errorf("Invalid Frame Hash")
+	err = c.setHeadAndSeq()
+	if err != nil {
+		return err
 	}
 
-	err = c.hg.Reset(block, frame)
+	// Update peer-selector and validators
+	c.setPeers(peers.NewPeerSet(frame.Peers))
+	c.validators = peers.NewPeerSet(frame.Peers)
+
+	return nil
+}
+
+// checkAnchor checks that a Block is signed by the validators, and that the
+// Frame is the one of the Block.
+func (c *core) checkAnchor(block *hg.Block, frame *hg.Frame, validators *peers.PeerSet) error {
+	// Check Block Signatures
+	err := c.hg.CheckBlock(block, validators)
 	if err != nil {
 		return err
 	}
 
-	err = c.setHeadAndSeq()
+	// Check Frame Hash
+	frameHash, err := frame.Hash()
 	if err != nil {
 		return err
 	}
 
-	// Update peer-selector and validators
-	c.setPeers(peers.NewPeerSet(frame.Peers))
-	c.validators = peers.NewPeerSet(frame.Peers)
+	if !reflect.DeepEqual(block.FrameHash(), frameHash) {
+		return fmt.Errorf("Invalid Frame Hash")
+	}
 
 	return nil
 }
@@ -406,6 +531,21 @@ func (c *core) getAnchorBlockWithFrame() (*hg.Block, *hg.Frame, error) {
 	return c.hg.GetAnchorBlockWithFrame()
 }
 
+// getBlockWithFrame returns a block and the frame of its round-received.
+func (c *core) getBlockWithFrame(index int) (*hg.Block, *hg.Frame, error) {
+	block, err := c.hg.Store.GetBlock(index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	frame, err := c.hg.GetFrame(block.RoundReceived())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return block, frame, nil
+}
+
 /*******************************************************************************
 Leave
 *******************************************************************************/
@@ -483,19 +623,41 @@ Commit
 *******************************************************************************/
 
 // commit the Block to the App using the proxyCommitCallback
-func (c *core) commit(block *hg.Block) error {
+func (c *core) commit(block *hg.Block) (err error) {
+	ctx, span := tracing.Start(c.traceCtx, "core.commit")
+	span.SetAttributes(
+		attribute.Int("block", block.Index()),
+		attribute.Int("transactions", len(block.Transactions())),
+		attribute.Int("internal_transactions", len(block.InternalTransactions())),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	c.logger.WithFields(logrus.Fields{
 		"block":        block.Index(),
 		"txs":          len(block.Transactions()),
 		"internal_txs": len(block.InternalTransactions()),
 	}).Info("Commit")
 
+	c.checkEquivocations(block)
+
+	appBlock := *block
+	if c.openEnvelopes {
+		appBlock = c.openBlockEnvelopes(block)
+	}
+
 	// Commit the Block to the App
-	commitResponse, err := c.proxyCommitCallback(*block)
+	_, proxySpan := tracing.Start(ctx, "proxy.CommitBlock")
+	start := time.Now()
+	commitResponse, err := c.proxyCommitCallback(appBlock)
+	metrics.CommitLatency.Observe(time.Since(start).Seconds())
+	tracing.End(proxySpan, err)
 	if err != nil {
 		c.logger.WithError(err).Error("Commit response")
+		c.notifier.publishError(fmt.Errorf("Committing block %d: %v", block.Index(), err))
 	}
 
+	applied := c.checkApplied(block, commitResponse, err)
+
 	c.logger.WithFields(logrus.Fields{
 		"block":                 block.Index(),
 		"internal_txs_receipts": len(commitResponse.InternalTransactionReceipts),
@@ -514,7 +676,7 @@ func (c *core) commit(block *hg.Block) error {
 			return err
 		}
 
-		if _, ok := blockPeerSet.ByID[c.validator.ID()]; ok {
+		if _, ok := blockPeerSet.ByID[c.validator.ID()]; ok && applied {
 			sig, err := c.signBlock(block)
 			if err != nil {
 				return err
@@ -527,15 +689,77 @@ func (c *core) commit(block *hg.Block) error {
 			return err
 		}
 
-		err = c.processAcceptedInternalTransactions(block.RoundReceived(), commitResponse.InternalTransactionReceipts)
+		err = c.processAcceptedInternalTransactions(block.Index(), block.RoundReceived(), commitResponse.InternalTransactionReceipts)
 		if err != nil {
 			return err
 		}
+
+		metrics.BlocksCommitted.Inc()
+
+		c.notifier.publishBlock(block)
 	}
 
 	return err
 }
 
+// checkApplied returns false if the node must withhold its signature of a
+// committed block, in commitBarrier mode, because the app did not apply this
+// block, or a previous one.
+func (c *core) checkApplied(block *hg.Block, response proxy.CommitResponse, err error) bool {
+	if !c.commitBarrier {
+		return true
+	}
+
+	if c.unappliedBlock < 0 && (err != nil || len(response.StateHash) == 0) {
+		c.unappliedBlock = block.Index()
+		c.logger.WithField("block", block.Index()).Error("The app did not apply the block, withholding block signatures")
+		c.notifier.publishError(fmt.Errorf("Withholding block signatures: the app did not apply block %d", block.Index()))
+	}
+
+	if c.unappliedBlock < 0 {
+		return true
+	}
+
+	metrics.SignaturesWithheld.Inc()
+	c.logger.WithFields(logrus.Fields{
+		"block":           block.Index(),
+		"unapplied_block": c.unappliedBlock,
+	}).Debug("Withholding block signature")
+
+	return false
+}
+
+// openBlockEnvelopes returns a copy of the block in which the envelopes
+// addressed to this node are replaced with their payloads. The other envelopes
+// are left sealed. The block itself, which is hashed and signed, is unchanged.
+func (c *core) openBlockEnvelopes(block *hg.Block) hg.Block {
+	appBlock := *block
+	appBlock.Body.Transactions = make([][]byte, len(block.Transactions()))
+
+	for i, tx := range block.Transactions() {
+		appBlock.Body.Transactions[i] = tx
+
+		if !envelope.IsEnvelope(tx) {
+			continue
+		}
+
+		payload, err := envelope.Open(tx, c.validator.Key)
+		if err != nil {
+			if err != envelope.ErrNotRecipient {
+				c.logger.WithError(err).WithFields(logrus.Fields{
+					"block": block.Index(),
+					"tx":    i,
+				}).Warn("Opening envelope")
+			}
+			continue
+		}
+
+		appBlock.Body.Transactions[i] = payload
+	}
+
+	return appBlock
+}
+
 // signBlock signs the block and saves it.
 func (c *core) signBlock(block *hg.Block) (hg.BlockSignature, error) {
 	sig, err := block.Sign(c.validator.Key)
@@ -558,18 +782,29 @@ func (c *core) signBlock(block *hg.Block) (hg.BlockSignature, error) {
 
 // processAcceptedInternalTransactions processes a list of
 // InternalTransactionReceipts from a block, updates the PeerSet for the
-// corresponding round (round-received + 6), and responds to eventual promises.
-func (c *core) processAcceptedInternalTransactions(roundReceived int, receipts []hg.InternalTransactionReceipt) error {
+// corresponding round (round-received + 6, or the next multiple of
+// peerSetInterval), records the membership changes, and responds to eventual
+// promises.
+func (c *core) processAcceptedInternalTransactions(blockIndex int, roundReceived int, receipts []hg.InternalTransactionReceipt) error {
 	currentPeers := c.peers
 	validators := c.validators
 
 	// Why +6? According to lemmas 5.15 and 5.17 of the original whitepaper, all
 	// consistent hashgraphs will have decided the fame of round r witnesses by
 	// round r+5 or before; so it is safe to set the new peer-set at round r+6.
-	effectiveRound := roundReceived + 6
+	// Upgrades take effect then too.
+	upgradeRound := roundReceived + 6
+
+	// Membership changes take effect at the following round boundary, if any,
+	// together with the other changes scheduled for it.
+	effectiveRound := c.peerSetRound(upgradeRound)
+
+	// refused contains the accepted InternalTransactions that Babble ignores,
+	// because they conflict with the validator-set.
+	refused := make(map[string]bool)
 
 	changed := false
-	for _, r := range receipts {
+	for i, r := range receipts {
 		txBody := r.InternalTransaction.Body
 
 		if r.Accepted {
@@ -581,6 +816,12 @@ func (c *core) processAcceptedInternalTransactions(roundReceived int, receipts [
 
 			switch txBody.Type {
 			case hg.PEER_ADD:
+				if monikerTaken(validators, &txBody.Peer) {
+					c.logger.WithField("moniker", txBody.Peer.Moniker).Warn("Moniker already used, ignoring PEER_ADD")
+					refused[r.InternalTransaction.HashString()] = true
+					continue
+				}
+
 				validators = validators.WithNewPeer(&txBody.Peer)
 				currentPeers = currentPeers.WithNewPeer(&txBody.Peer)
 			case hg.PEER_REMOVE:
@@ -592,11 +833,77 @@ func (c *core) processAcceptedInternalTransactions(roundReceived int, receipts [
 					c.logger.Debugf("Update RemovedRound from %d to %d", c.removedRound, effectiveRound)
 					c.removedRound = effectiveRound
 				}
+
+				c.alert(EvictionAlert, "Peer %s (%s) is removed from the validator-set from round %d",
+					txBody.Peer.Moniker, txBody.Peer.NetAddr, effectiveRound)
+			case hg.PEER_RENAME:
+				if _, ok := validators.ByID[txBody.Peer.ID()]; !ok || monikerTaken(validators, &txBody.Peer) {
+					c.logger.WithField("moniker", txBody.Peer.Moniker).Warn("Moniker already used or not a validator, ignoring PEER_RENAME")
+					refused[r.InternalTransaction.HashString()] = true
+					continue
+				}
+
+				// Only the moniker changes. In particular, a rename cannot
+				// promote a shadow peer to a voter, nor restore an older
+				// address.
+				renamed := *validators.ByID[txBody.Peer.ID()]
+				renamed.Moniker = txBody.Peer.Moniker
+
+				validators = validators.WithRenamedPeer(&renamed)
+				currentPeers = currentPeers.WithRenamedPeer(&renamed)
+
+				if txBody.Peer.ID() == c.validator.ID() {
+					c.validator.Moniker = txBody.Peer.Moniker
+				}
+			case hg.PEER_ADDRESS:
+				current, ok := validators.ByID[txBody.Peer.ID()]
+				if !ok || txBody.Peer.NetAddr == "" {
+					c.logger.WithField("addr", txBody.Peer.NetAddr).Warn("Empty address or not a validator, ignoring PEER_ADDRESS")
+					refused[r.InternalTransaction.HashString()] = true
+					continue
+				}
+
+				// An announcement that does not follow the last one applied is
+				// stale, or a replay of an old one.
+				if txBody.Peer.AddressSequence <= current.AddressSequence {
+					c.logger.WithFields(logrus.Fields{
+						"addr":     txBody.Peer.NetAddr,
+						"sequence": txBody.Peer.AddressSequence,
+						"current":  current.AddressSequence,
+					}).Warn("Stale address announcement, ignoring PEER_ADDRESS")
+					refused[r.InternalTransaction.HashString()] = true
+					continue
+				}
+
+				// Only the address changes, the other fields of the peer are
+				// kept.
+				validators = validators.WithRenamedPeer(withAddress(current, &txBody.Peer))
+				if p, ok := currentPeers.ByID[txBody.Peer.ID()]; ok {
+					currentPeers = currentPeers.WithRenamedPeer(withAddress(p, &txBody.Peer))
+				}
+			case hg.PROTOCOL_UPGRADE:
+				// Upgrades do not change the validator-set
+				if err := c.processUpgrade(txBody, roundReceived, upgradeRound); err != nil {
+					return err
+				}
+				continue
 			default:
 				c.logger.Errorf("Unknown InternalTransactionType %s", txBody.Type)
 				continue
 			}
 
+			err := c.hg.Store.SetMembershipChange(hg.MembershipChange{
+				Block:         blockIndex,
+				Index:         i,
+				RoundReceived: roundReceived,
+				Round:         effectiveRound,
+				Type:          txBody.Type,
+				Peer:          txBody.Peer,
+			})
+			if err != nil {
+				return fmt.Errorf("Recording membership change: %s", err)
+			}
+
 			changed = true
 		} else {
 			c.logger.WithField("peer", txBody.Peer).Debug("InternalTransaction not accepted")
@@ -605,16 +912,34 @@ func (c *core) processAcceptedInternalTransactions(roundReceived int, receipts [
 
 	if changed {
 		// Record the new validator-set in the underlying Hashgraph and in the
-		// core's validators field
-		c.lastPeerChangeRound = effectiveRound
+		// core's validators field. When other changes were scheduled for the
+		// same round, their validator-set is replaced.
+		peerSets, err := c.hg.Store.GetAllPeerSets()
+		if err != nil {
+			return fmt.Errorf("Getting Store PeerSets: %s", err)
+		}
 
-		err := c.hg.Store.SetPeerSet(effectiveRound, validators)
+		if _, ok := peerSets[effectiveRound]; ok {
+			err = c.hg.Store.ReplacePeerSet(effectiveRound, validators)
+		} else {
+			err = c.hg.Store.SetPeerSet(effectiveRound, validators)
+		}
 		if err != nil {
 			return fmt.Errorf("Updating Store PeerSet: %s", err)
 		}
 
+		c.lastPeerChangeRound = effectiveRound
+
 		c.validators = validators
 
+		c.notifier.publish(Notification{
+			Type: PeerSetNotification,
+			PeerSet: &PeerSetChange{
+				Round: effectiveRound,
+				Peers: validators.Peers,
+			},
+		})
+
 		c.logger.WithFields(logrus.Fields{
 			"effective_round": effectiveRound,
 			"validators":      len(validators.Peers),
@@ -637,7 +962,7 @@ func (c *core) processAcceptedInternalTransactions(roundReceived int, receipts [
 	for _, r := range receipts {
 		//respond to the corresponding promise
 		if p, ok := c.promises[r.InternalTransaction.HashString()]; ok {
-			if r.Accepted {
+			if r.Accepted && !refused[r.InternalTransaction.HashString()] {
 				p.respond(true, effectiveRound, c.validators.Peers)
 			} else {
 				p.respond(false, 0, []*peers.Peer{})
@@ -649,6 +974,35 @@ func (c *core) processAcceptedInternalTransactions(roundReceived int, receipts [
 	return nil
 }
 
+// peerSetRound returns the round at which the membership changes, which could
+// take effect from a given round, are scheduled: the round itself, or the next
+// multiple of peerSetInterval when it is set.
+func (c *core) peerSetRound(round int) int {
+	if c.peerSetInterval <= 0 || round%c.peerSetInterval == 0 {
+		return round
+	}
+	return round + c.peerSetInterval - round%c.peerSetInterval
+}
+
+// monikerTaken returns true if another peer of the validator-set already uses
+// the moniker of peer. Monikers are compared without case, and empty monikers
+// are not unique. The peers are compared by public key, because Peer.ID would
+// cache the ID in the peer of a JoinRequest, which then differs from the one
+// committed by the other nodes.
+func monikerTaken(validators *peers.PeerSet, peer *peers.Peer) bool {
+	other, ok := validators.ByMoniker(peer.Moniker)
+	return ok && other.PubKeyString() != peer.PubKeyString()
+}
+
+// withAddress returns a copy of a peer with the NetAddr and AddressSequence of
+// an address announcement.
+func withAddress(peer *peers.Peer, announced *peers.Peer) *peers.Peer {
+	p := *peer
+	p.NetAddr = announced.NetAddr
+	p.AddressSequence = announced.AddressSequence
+	return &p
+}
+
 /*******************************************************************************
 Diff
 *******************************************************************************/
@@ -740,7 +1094,51 @@ func (c *core) processSigPool() error {
 
 // addTransactions appends transactions to the transaction pool
 func (c *core) addTransactions(txs [][]byte) {
+	c.addExpiringTransactions(txs, make([]*txDeadline, len(txs)))
+}
+
+// addExpiringTransactions appends transactions to the transaction pool, with
+// their deadlines.
+func (c *core) addExpiringTransactions(txs [][]byte, deadlines []*txDeadline) {
 	c.transactionPool = append(c.transactionPool, txs...)
+	c.transactionDeadlines = append(c.transactionDeadlines, deadlines...)
+}
+
+// evictExpiredTransactions removes the transactions whose deadline has passed
+// from the transaction pool, and returns them.
+func (c *core) evictExpiredTransactions(now time.Time) [][]byte {
+	lastRound := c.hg.Store.LastRound()
+
+	var expired [][]byte
+	var pool [][]byte
+	var deadlines []*txDeadline
+
+	for i, tx := range c.transactionPool {
+		d := c.transactionDeadlines[i]
+
+		if d != nil && d.expired(lastRound, now) {
+			if expired == nil {
+				// Copy the transactions kept so far, so as not to modify
+				// the pool in place
+				pool = append([][]byte{}, c.transactionPool[:i]...)
+				deadlines = append([]*txDeadline{}, c.transactionDeadlines[:i]...)
+			}
+			expired = append(expired, tx)
+			continue
+		}
+
+		if expired != nil {
+			pool = append(pool, tx)
+			deadlines = append(deadlines, d)
+		}
+	}
+
+	if expired != nil {
+		c.transactionPool = pool
+		c.transactionDeadlines = deadlines
+	}
+
+	return expired
 }
 
 // addInternalTransaction adds an InternalTransaction to the  pool, and creates
@@ -9,10 +9,15 @@ import (
 	"testing"
 
 	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/envelope"
 	"github.com/mosaicnetworks/babble/src/crypto/keys"
 	hg "github.com/mosaicnetworks/babble/src/hashgraph"
 	"github.com/mosaicnetworks/babble/src/peers"
 	"github.com/mosaicnetworks/babble/src/proxy"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func initCores(n int, t *testing.T) ([]*core, map[uint32]*ecdsa.PrivateKey, map[string]string) {
@@ -489,6 +494,108 @@ func TestConsensusFF(t *testing.T) {
 	}
 }
 
+func TestCommitTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	cores, _, _ := initCores(4, t)
+	initFFHashgraph(cores, t)
+
+	spans := make(map[trace.SpanID]*sdktrace.SpanSnapshot)
+	for _, s := range exporter.GetSpans() {
+		spans[s.SpanContext.SpanID()] = s
+	}
+
+	commits := 0
+	for _, s := range spans {
+		if s.Name != "proxy.CommitBlock" {
+			continue
+		}
+
+		commit, ok := spans[s.Parent.SpanID()]
+		if !ok || commit.Name != "core.commit" {
+			t.Fatalf("proxy.CommitBlock should be a child of core.commit")
+		}
+
+		insert, ok := spans[commit.Parent.SpanID()]
+		if !ok || insert.Name != "hashgraph.InsertEventAndRunConsensus" {
+			t.Fatalf("core.commit should be a child of hashgraph.InsertEventAndRunConsensus")
+		}
+
+		commits++
+	}
+
+	if commits == 0 {
+		t.Fatalf("No proxy.CommitBlock spans were recorded")
+	}
+}
+
+func TestCommitBarrier(t *testing.T) {
+	cores, _, _ := initCores(2, t)
+	c := cores[0]
+	c.commitBarrier = true
+
+	// The app does not return a state hash for block 1
+	stateHashes := [][]byte{[]byte("state0"), nil, []byte("state2")}
+	c.proxyCommitCallback = func(block hg.Block) (proxy.CommitResponse, error) {
+		return proxy.CommitResponse{StateHash: stateHashes[block.Index()]}, nil
+	}
+
+	for i := range stateHashes {
+		block := hg.NewBlock(i, 0, []byte("frame"), c.peers.Peers, [][]byte{[]byte("tx")}, nil)
+		if err := c.commit(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Block 2 is applied, but not the blockchain before it
+	if l := c.selfBlockSignatures.Len(); l != 1 {
+		t.Fatalf("Only block 0 should be signed, not %d blocks", l)
+	}
+	if c.unappliedBlock != 1 {
+		t.Fatalf("The unapplied block should be 1, not %d", c.unappliedBlock)
+	}
+}
+
+func TestOpenEnvelopes(t *testing.T) {
+	cores, _, _ := initCores(3, t)
+	c := cores[0]
+	c.openEnvelopes = true
+
+	toSelf, err := envelope.Seal([]byte("private"), []*ecdsa.PublicKey{&c.validator.Key.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	toOther, err := envelope.Seal([]byte("other"), []*ecdsa.PublicKey{&cores[1].validator.Key.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var committed [][]byte
+	c.proxyCommitCallback = func(block hg.Block) (proxy.CommitResponse, error) {
+		committed = block.Transactions()
+		return proxy.CommitResponse{StateHash: []byte("state")}, nil
+	}
+
+	txs := [][]byte{[]byte("public"), toSelf, toOther}
+	block := hg.NewBlock(0, 0, []byte("frame"), c.peers.Peers, txs, nil)
+	if err := c.commit(block); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]byte{[]byte("public"), []byte("private"), toOther}
+	if !reflect.DeepEqual(committed, expected) {
+		t.Fatalf("The app should receive %q, not %q", expected, committed)
+	}
+
+	// The block that is signed keeps the envelopes
+	if !reflect.DeepEqual(block.Transactions(), txs) {
+		t.Fatalf("The block should keep its envelopes")
+	}
+}
+
 func TestCoreFastForward(t *testing.T) {
 	cores, _, _ := initCores(4, t)
 	initFFHashgraph(cores, t)
@@ -0,0 +1,103 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/net"
+)
+
+// dataBudgetWindow is the period over which the data budget is measured.
+const dataBudgetWindow = time.Hour
+
+// meteredHeartbeatFactor is the factor by which the heartbeat timeouts are
+// slowed down on metered connections.
+const meteredHeartbeatFactor = 5
+
+// dataBudget limits the traffic of the node to a maximum number of bytes per
+// hour. The traffic is measured by the transport from the beginning of each
+// hour-long window. A budget without a TrafficCounter, or with a maximum of 0,
+// is never exceeded.
+type dataBudget struct {
+	sync.Mutex
+
+	counter         net.TrafficCounter
+	maxBytesPerHour int64
+
+	// windowStart is the beginning of the current window, and windowTraffic
+	// the traffic of the transport at that time.
+	windowStart   time.Time
+	windowTraffic uint64
+
+	// notified records that the exceeded budget was reported in the current
+	// window.
+	notified bool
+}
+
+func newDataBudget(trans net.Transport, maxBytesPerHour int64, now time.Time) *dataBudget {
+	counter, _ := trans.(net.TrafficCounter)
+
+	b := &dataBudget{
+		counter:         counter,
+		maxBytesPerHour: maxBytesPerHour,
+	}
+	b.resetWindow(now)
+
+	return b
+}
+
+// traffic returns the total traffic of the transport.
+func (b *dataBudget) traffic() uint64 {
+	if b.counter == nil {
+		return 0
+	}
+
+	sent, received := b.counter.Traffic()
+	return sent + received
+}
+
+// resetWindow starts a new window. It must be called with the lock held.
+func (b *dataBudget) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.windowTraffic = b.traffic()
+	b.notified = false
+}
+
+// setMax changes the maximum number of bytes per hour.
+func (b *dataBudget) setMax(maxBytesPerHour int64) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.maxBytesPerHour = maxBytesPerHour
+}
+
+// used returns the number of bytes used in the current window.
+func (b *dataBudget) used(now time.Time) int64 {
+	b.Lock()
+	defer b.Unlock()
+
+	if now.Sub(b.windowStart) >= dataBudgetWindow {
+		b.resetWindow(now)
+	}
+
+	return int64(b.traffic() - b.windowTraffic)
+}
+
+// exceeded returns true if the traffic of the current window exceeds the
+// budget. The second value is true the first time it is exceeded in the
+// window, so that it is only reported once.
+func (b *dataBudget) exceeded(now time.Time) (exceeded bool, first bool) {
+	used := b.used(now)
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.maxBytesPerHour <= 0 || used < b.maxBytesPerHour {
+		return false, false
+	}
+
+	first = !b.notified
+	b.notified = true
+
+	return true, first
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeTrafficCounter struct {
+	sent, received uint64
+}
+
+func (c *fakeTrafficCounter) Traffic() (uint64, uint64) {
+	return c.sent, c.received
+}
+
+func TestDataBudget(t *testing.T) {
+	counter := &fakeTrafficCounter{sent: 500, received: 500}
+	now := time.Now()
+
+	b := &dataBudget{counter: counter, maxBytesPerHour: 1000}
+	b.resetWindow(now)
+
+	counter.sent += 400
+	counter.received += 500
+
+	if used := b.used(now); used != 900 {
+		t.Fatalf("Used should be 900, not %d", used)
+	}
+	if exceeded, _ := b.exceeded(now); exceeded {
+		t.Fatal("The budget should not be exceeded")
+	}
+
+	counter.received += 100
+
+	exceeded, first := b.exceeded(now)
+	if !exceeded || !first {
+		t.Fatal("The budget should be exceeded for the first time")
+	}
+	if exceeded, first := b.exceeded(now); !exceeded || first {
+		t.Fatal("The budget should still be exceeded, but not for the first time")
+	}
+
+	// The budget is reset in the next window
+	next := now.Add(dataBudgetWindow)
+	if exceeded, _ := b.exceeded(next); exceeded {
+		t.Fatal("The budget should be reset in the next window")
+	}
+	if used := b.used(next); used != 0 {
+		t.Fatalf("Used should be 0 in the next window, not %d", used)
+	}
+
+	// 0 means unlimited
+	counter.sent += 10000
+	b.setMax(0)
+	if exceeded, _ := b.exceeded(next); exceeded {
+		t.Fatal("A budget of 0 should never be exceeded")
+	}
+
+	// Without a TrafficCounter, the budget is never exceeded
+	if exceeded, _ := newDataBudget(nil, 1, now).exceeded(now); exceeded {
+		t.Fatal("A budget without a TrafficCounter should never be exceeded")
+	}
+}
@@ -0,0 +1,173 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/version"
+)
+
+const (
+	// diagnosticsRounds is the number of rounds, counting back from the last
+	// one, included in a Diagnostics bundle.
+	diagnosticsRounds = 10
+
+	// diagnosticsMaxEvents caps the number of undetermined events included in
+	// a Diagnostics bundle.
+	diagnosticsMaxEvents = 1000
+
+	// diagnosticsTimeout bounds the wait for the core lock when collecting
+	// Diagnostics. After a panic, the lock may never be released.
+	diagnosticsTimeout = 5 * time.Second
+)
+
+// Diagnostics is a snapshot of the state of a node, written to the data
+// directory when the node panics or its consensus stalls, and collected by
+// `babble support-bundle` to be attached to bug reports. The state of the core
+// is missing, and CoreError is set, if the core lock could not be acquired.
+type Diagnostics struct {
+	Time    time.Time `json:"time"`
+	Reason  string    `json:"reason"`
+	Stack   string    `json:"stack,omitempty"`
+	Version string    `json:"version"`
+
+	// Config is the configuration of the node, without its secrets.
+	Config map[string]interface{} `json:"config"`
+
+	CoreError string `json:"core_error,omitempty"`
+
+	Stats      *Stats        `json:"stats,omitempty"`
+	Validators []*peers.Peer `json:"validators,omitempty"`
+	PeerStats  []PeerStats   `json:"peer_stats,omitempty"`
+
+	// Rounds are the last rounds of the hashgraph, by index.
+	Rounds map[int]*hg.RoundInfo `json:"rounds,omitempty"`
+
+	// UndeterminedEvents are the events which have not reached consensus
+	// yet, up to diagnosticsMaxEvents.
+	UndeterminedEvents []*hg.Event `json:"undetermined_events,omitempty"`
+}
+
+// coreDiagnostics is the part of the Diagnostics that requires the core lock.
+type coreDiagnostics struct {
+	stats      Stats
+	validators []*peers.Peer
+	peerStats  []PeerStats
+	rounds     map[int]*hg.RoundInfo
+	events     []*hg.Event
+}
+
+// GetDiagnostics returns a snapshot of the state of the node. The reason is
+// recorded in the snapshot, with the stack trace if it is not empty.
+func (n *Node) GetDiagnostics(reason string, stack []byte) *Diagnostics {
+	d := &Diagnostics{
+		Time:    time.Now(),
+		Reason:  reason,
+		Stack:   string(stack),
+		Version: version.Version,
+		Config:  n.conf.Redacted(),
+	}
+
+	// Collect the state of the core in another goroutine, so that a lock held
+	// by a goroutine that panicked does not block the bundle.
+	coreCh := make(chan coreDiagnostics, 1)
+	go func() {
+		coreCh <- n.getCoreDiagnostics()
+	}()
+
+	select {
+	case c := <-coreCh:
+		d.Stats = &c.stats
+		d.Validators = c.validators
+		d.PeerStats = c.peerStats
+		d.Rounds = c.rounds
+		d.UndeterminedEvents = c.events
+	case <-time.After(diagnosticsTimeout):
+		d.CoreError = "Timeout waiting for the core lock"
+	}
+
+	return d
+}
+
+func (n *Node) getCoreDiagnostics() coreDiagnostics {
+	c := coreDiagnostics{
+		stats:     n.Stats(),
+		peerStats: n.GetPeerStats(),
+		rounds:    make(map[int]*hg.RoundInfo),
+	}
+
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	c.validators = n.core.validators.Peers
+
+	last := n.core.hg.Store.LastRound()
+	for i := last; i >= 0 && i > last-diagnosticsRounds; i-- {
+		if round, err := n.core.hg.Store.GetRound(i); err == nil {
+			c.rounds[i] = round
+		}
+	}
+
+	for _, hash := range n.core.getUndeterminedEvents() {
+		if len(c.events) == diagnosticsMaxEvents {
+			break
+		}
+		if ev, err := n.core.hg.Store.GetEvent(hash); err == nil {
+			c.events = append(c.events, ev)
+		}
+	}
+
+	return c
+}
+
+// WriteDiagnostics writes a Diagnostics bundle to the diagnostics directory of
+// the data directory, and returns the path of the file.
+func (n *Node) WriteDiagnostics(reason string, stack []byte) (string, error) {
+	d := n.GetDiagnostics(reason, stack)
+
+	dir := n.conf.DiagnosticsDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("diagnostics-%s.json", d.Time.UTC().Format("20060102-150405.000")))
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// writeDiagnostics writes a Diagnostics bundle and logs where it was written.
+// Failures are only logged.
+func (n *Node) writeDiagnostics(reason string, stack []byte) {
+	path, err := n.WriteDiagnostics(reason, stack)
+	if err != nil {
+		n.logger.WithError(err).Error("Writing diagnostics")
+		return
+	}
+	n.logger.WithField("path", path).Error("Wrote diagnostics: " + reason)
+}
+
+// recoverPanic writes a Diagnostics bundle when the calling goroutine panics,
+// and panics again, so that the process still crashes. It must be deferred at
+// the top of the goroutines of the node.
+func (n *Node) recoverPanic() {
+	if r := recover(); r != nil {
+		n.writeDiagnostics(fmt.Sprintf("panic: %v", r), debug.Stack())
+		panic(r)
+	}
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 100000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	if err := gossip(nodes, 3, false); err != nil {
+		t.Fatal(err)
+	}
+
+	node := nodes[0]
+	node.conf.AdminToken = "secret"
+
+	path, err := node.WriteDiagnostics("test", []byte("stack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dir := filepath.Dir(path); dir != node.conf.DiagnosticsDir() {
+		t.Fatalf("Diagnostics should be written to %s, not %s", node.conf.DiagnosticsDir(), dir)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d Diagnostics
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Reason != "test" || d.Stack != "stack" {
+		t.Fatalf("Reason and Stack should be recorded, not %q and %q", d.Reason, d.Stack)
+	}
+
+	if d.CoreError != "" {
+		t.Fatalf("The state of the core should be collected: %s", d.CoreError)
+	}
+
+	if len(d.Validators) != 4 {
+		t.Fatalf("There should be 4 validators, not %d", len(d.Validators))
+	}
+
+	if len(d.Rounds) == 0 || len(d.Rounds) > diagnosticsRounds {
+		t.Fatalf("There should be between 1 and %d rounds, not %d", diagnosticsRounds, len(d.Rounds))
+	}
+
+	if token := d.Config["admin-token"]; token != "REDACTED" {
+		t.Fatalf("The admin token should be redacted, not %v", token)
+	}
+}
@@ -0,0 +1,110 @@
+package node
+
+import (
+	"fmt"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/sirupsen/logrus"
+)
+
+// maxEvidence is the number of proofs of misbehaviour kept by the node.
+const maxEvidence = 1000
+
+// GetEvidence returns the last proofs of misbehaviour collected by the node,
+// oldest first, signed by its validator: the forks of Events, the Blocks signed
+// twice, and the equivocating InternalTransactions.
+func (n *Node) GetEvidence() []*hg.SignedEvidence {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	return n.core.getEvidence()
+}
+
+// recordEvidence signs a proof of misbehaviour with the key of the validator,
+// and keeps it for export, once per offence. It returns false if the offence
+// was already recorded. It must be called with the coreLock.
+func (c *core) recordEvidence(evidence *hg.Evidence) bool {
+	key := fmt.Sprintf("%s/%s/%d", evidence.Kind, evidence.Offender, evidence.Index)
+	if c.reportedEvidence[key] {
+		return false
+	}
+
+	signed, err := evidence.Sign(c.validator.Key)
+	if err != nil {
+		c.logger.WithError(err).Error("Signing evidence")
+		return false
+	}
+
+	c.reportedEvidence[key] = true
+
+	c.evidence = append(c.evidence, signed)
+	if len(c.evidence) > maxEvidence {
+		c.evidence = c.evidence[len(c.evidence)-maxEvidence:]
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"kind":     evidence.Kind,
+		"offender": evidence.Offender,
+		"index":    evidence.Index,
+	}).Warn("Recorded evidence of misbehaviour")
+
+	return true
+}
+
+// checkEquivocations records the Evidence, and raises an EquivocationAlert,
+// when a Block contains two different InternalTransactions of the same type
+// signed by the same peer.
+func (c *core) checkEquivocations(block *hg.Block) {
+	seen := make(map[string]hg.InternalTransaction)
+
+	for _, itx := range block.InternalTransactions() {
+		key := fmt.Sprintf("%s/%s", itx.Body.Peer.PubKeyString(), itx.Body.Type)
+
+		first, ok := seen[key]
+		if !ok {
+			seen[key] = itx
+			continue
+		}
+
+		evidence, err := hg.NewEquivocationEvidence(block.Index(), first, itx)
+		if err != nil || !c.recordEvidence(evidence) {
+			continue
+		}
+
+		c.alert(EquivocationAlert, "Peer %s signed two different %s InternalTransactions in Block %d",
+			evidence.Offender, itx.Body.Type, block.Index())
+	}
+}
+
+// checkDoubleSign compares a Block received from a peer with the Block of the
+// same index in the store. It records the Evidence, and raises a
+// DoubleSignAlert, for each validator that validly signed both versions.
+func (c *core) checkDoubleSign(block *hg.Block) {
+	local, err := c.hg.Store.GetBlock(block.Index())
+	if err != nil {
+		return
+	}
+
+	for validator := range block.Signatures {
+		if _, ok := local.Signatures[validator]; !ok {
+			continue
+		}
+
+		// The evidence is only valid if the versions differ and both
+		// signatures are valid
+		evidence, err := hg.NewDoubleSignEvidence(validator, local, block)
+		if err != nil || !c.recordEvidence(evidence) {
+			continue
+		}
+
+		c.alert(DoubleSignAlert, "Validator %s signed two different versions of Block %d",
+			validator, block.Index())
+	}
+}
+
+// getEvidence returns a copy of the recorded Evidence, oldest first.
+func (c *core) getEvidence() []*hg.SignedEvidence {
+	res := make([]*hg.SignedEvidence, len(c.evidence))
+	copy(res, c.evidence)
+	return res
+}
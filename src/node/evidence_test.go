@@ -0,0 +1,86 @@
+package node
+
+import (
+	"testing"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestCheckEquivocations(t *testing.T) {
+	cores, keys, _ := initCores(2, t)
+	c := cores[0]
+
+	// The other validator announces two different addresses in one Block
+	other := cores[1].validator
+	peer := peers.NewPeer(other.PublicKeyHex(), "paris", other.Moniker)
+
+	itxs := []hg.InternalTransaction{
+		hg.NewInternalTransactionAddress(*peer, "london"),
+		hg.NewInternalTransactionAddress(*peer, "berlin"),
+		hg.NewInternalTransactionRename(*peer, "renamed"),
+	}
+	for i := range itxs {
+		itxs[i].Sign(keys[other.ID()])
+	}
+
+	block := hg.NewBlock(0, 0, []byte("frame"), c.peers.Peers, nil, itxs)
+
+	c.checkEquivocations(block)
+	c.checkEquivocations(block)
+
+	evidence := c.getEvidence()
+	if len(evidence) != 1 {
+		t.Fatalf("There should be 1 evidence, not %d", len(evidence))
+	}
+
+	if err := evidence[0].Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if evidence[0].Evidence.Kind != hg.EquivocationEvidence || evidence[0].Reporter != c.validator.PublicKeyHex() {
+		t.Fatalf("The evidence should be an equivocation reported by %s: %+v", c.validator.PublicKeyHex(), evidence[0])
+	}
+}
+
+func TestCheckDoubleSign(t *testing.T) {
+	cores, keys, _ := initCores(2, t)
+	c := cores[0]
+	other := cores[1].validator
+
+	local := hg.NewBlock(0, 0, []byte("frame"), c.peers.Peers, [][]byte{[]byte("tx")}, nil)
+	remote := hg.NewBlock(0, 0, []byte("frame"), c.peers.Peers, [][]byte{[]byte("other tx")}, nil)
+
+	for _, block := range []*hg.Block{local, remote} {
+		sig, err := block.Sign(keys[other.ID()])
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.SetSignature(sig)
+	}
+
+	// The remote version is not signed by this node
+	sig, err := local.Sign(c.validator.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local.SetSignature(sig)
+
+	if err := c.hg.Store.SetBlock(local); err != nil {
+		t.Fatal(err)
+	}
+
+	c.checkDoubleSign(local)
+	if len(c.getEvidence()) != 0 {
+		t.Fatal("The local version of the Block is not a double sign")
+	}
+
+	c.checkDoubleSign(remote)
+
+	evidence := c.getEvidence()
+	if len(evidence) != 1 || evidence[0].Evidence.Offender != other.PublicKeyHex() {
+		t.Fatalf("There should be 1 evidence against %s: %+v", other.PublicKeyHex(), evidence)
+	}
+	if err := evidence[0].Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+	"github.com/mosaicnetworks/babble/src/net"
+)
+
+// fastForwardCache keeps the last FastForwardResponse built for the anchor
+// block, with its Frame and the snapshot of the App, so that the nodes which
+// fast-forward at the same time, like many nodes joining together, do not each
+// read them again from the store and the App. The block, the Frame and the
+// snapshot of a given index never change, so the response is only replaced
+// when the anchor block changes.
+type fastForwardCache struct {
+	sync.Mutex
+
+	anchor int
+	resp   *net.FastForwardResponse
+}
+
+// clear drops the cached response, to free its memory.
+func (c *fastForwardCache) clear() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.resp = nil
+}
+
+// anchorFastForwardResponse returns the FastForwardResponse for the anchor
+// block, from the cache if the anchor block has not changed. The cache stays
+// locked while the response is built, so that concurrent requests wait for it
+// instead of building it too.
+func (n *Node) anchorFastForwardResponse() (*net.FastForwardResponse, error) {
+	n.coreLock.RLock()
+	var anchor *int
+	if n.core.hg.AnchorBlock != nil {
+		index := *n.core.hg.AnchorBlock
+		anchor = &index
+	}
+	n.coreLock.RUnlock()
+
+	if anchor == nil {
+		return n.newFastForwardResponse(nil)
+	}
+
+	n.fastForwardCache.Lock()
+	defer n.fastForwardCache.Unlock()
+
+	if n.fastForwardCache.resp != nil && n.fastForwardCache.anchor == *anchor {
+		metrics.FastForwardCacheHits.Inc()
+		return n.fastForwardCache.resp, nil
+	}
+
+	resp, err := n.newFastForwardResponse(anchor)
+	if err != nil {
+		return resp, err
+	}
+
+	n.fastForwardCache.anchor = *anchor
+	n.fastForwardCache.resp = resp
+
+	return resp, nil
+}
@@ -0,0 +1,52 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFastForwardCache(t *testing.T) {
+	keys, peers := initPeers(t, 3)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+
+	if err := gossip(nodes, 5, true); err != nil {
+		t.Fatal(err)
+	}
+
+	node := nodes[0]
+
+	if node.core.hg.AnchorBlock == nil {
+		t.Fatal("The node should have an anchor block")
+	}
+	anchor := *node.core.hg.AnchorBlock
+
+	first, err := node.anchorFastForwardResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Block.Index() != anchor {
+		t.Fatalf("The response should contain the anchor block %d, not %d", anchor, first.Block.Index())
+	}
+
+	second, err := node.anchorFastForwardResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Fatal("The second response should be served from the cache")
+	}
+
+	// A new anchor block replaces the cached response
+	previous := anchor - 1
+	node.core.hg.AnchorBlock = &previous
+
+	third, err := node.anchorFastForwardResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first || third.Block.Index() != previous {
+		t.Fatalf("The response should contain the new anchor block %d, not %d", previous, third.Block.Index())
+	}
+}
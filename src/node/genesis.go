@@ -0,0 +1,125 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/proxy"
+)
+
+// genesisCheckInterval is the period at which the node pings the peers that
+// have not yet reported their genesis state hash.
+const genesisCheckInterval = 2 * time.Second
+
+// initGenesis checks that the genesis state hash of the App matches the
+// app_hash of the genesis document, and passes it to the hashgraph, which
+// records it in the first Block. Since all the validators share the genesis
+// document, they record the same hash. When the first Block is already in the
+// store, because the node was bootstrapped, its genesis state hash must match
+// as well.
+func (n *Node) initGenesis() error {
+	hash, err := common.DecodeFromString(n.conf.GenesisAppHash)
+	if err != nil {
+		return fmt.Errorf("decoding the app_hash of the genesis document: %v", err)
+	}
+
+	if len(hash) == 0 {
+		return fmt.Errorf("the app_hash of the genesis document is empty")
+	}
+
+	provider, ok := n.proxy.(proxy.GenesisProvider)
+	if !ok {
+		return fmt.Errorf("app_hash requires an AppProxy which provides the genesis state hash")
+	}
+
+	appHash, err := provider.GenesisStateHash()
+	if err != nil {
+		return fmt.Errorf("getting the genesis state hash: %v", err)
+	}
+
+	if !bytes.Equal(appHash, hash) {
+		return fmt.Errorf("the genesis state hash of the App, %s, differs from the app_hash of the genesis document, %s",
+			common.EncodeToString(appHash),
+			n.conf.GenesisAppHash)
+	}
+
+	if block, err := n.core.hg.Store.GetBlock(0); err == nil &&
+		!bytes.Equal(block.GenesisStateHash(), hash) {
+		return fmt.Errorf("the genesis state hash of the genesis document, %s, differs from that of Block 0, %s",
+			common.EncodeToString(hash),
+			common.EncodeToString(block.GenesisStateHash()))
+	}
+
+	n.genesisStateHash = hash
+	n.core.hg.SetGenesisStateHash(hash)
+
+	n.logger.WithField("genesis_state_hash", common.EncodeToString(hash)).Debug("Genesis state")
+
+	return nil
+}
+
+// checkGenesis pings the other peers until each of them has reported its
+// genesis state hash, or until the node shuts down. It raises a GenesisAlert
+// for each peer whose hash differs from that of the App.
+func (n *Node) checkGenesis() {
+	checked := make(map[uint32]bool)
+
+	ticker := n.clock.NewTicker(genesisCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if n.checkGenesisPeers(checked) {
+			return
+		}
+
+		select {
+		case <-ticker.C():
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// checkGenesisPeers pings the peers which are not checked yet, and compares
+// the genesis state hashes that they report with that of the App. It returns
+// true when all the peers are checked.
+func (n *Node) checkGenesisPeers(checked map[uint32]bool) bool {
+	n.coreLock.RLock()
+	_, others := peers.ExcludePeer(n.core.peers.Peers, n.core.validator.ID())
+	n.coreLock.RUnlock()
+
+	done := true
+
+	for _, p := range others {
+		if checked[p.ID()] {
+			continue
+		}
+
+		resp, err := n.requestPing(n.peerAddr(p))
+		if err != nil {
+			n.logger.WithError(err).WithField("peer", p.Moniker).Debug("Genesis check")
+			done = false
+			continue
+		}
+
+		checked[p.ID()] = true
+
+		if !bytes.Equal(resp.GenesisStateHash, n.genesisStateHash) {
+			err := fmt.Errorf("Peer %s has genesis state hash %s, not %s",
+				p.Moniker,
+				common.EncodeToString(resp.GenesisStateHash),
+				common.EncodeToString(n.genesisStateHash))
+
+			n.coreLock.Lock()
+			n.core.alert(GenesisAlert, "%v", err)
+			n.coreLock.Unlock()
+
+			n.core.notifier.publishError(err)
+		}
+	}
+
+	return done
+}
@@ -0,0 +1,88 @@
+package node
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto"
+)
+
+func TestGenesisState(t *testing.T) {
+	keys, peers := initPeers(t, 3)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	appHash := common.EncodeToString(crypto.SHA256([]byte{}))
+
+	for _, n := range nodes {
+		n.conf.GenesisAppHash = appHash
+		if err := n.initGenesis(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := nodes[0].genesisStateHash
+
+	// The last node reports a different genesis state
+	nodes[2].genesisStateHash = []byte("other")
+
+	if err := gossip(nodes, 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, n := range nodes {
+		block, err := n.GetBlock(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(block.GenesisStateHash(), expected) {
+			t.Fatalf("Block 0 of node %d should record the genesis state hash %X, not %X", i, expected, block.GenesisStateHash())
+		}
+	}
+
+	sub := nodes[0].core.notifier.subscribe(10, AlertNotification)
+	defer sub.Unsubscribe()
+
+	checked := make(map[uint32]bool)
+	if !nodes[0].checkGenesisPeers(checked) {
+		t.Fatal("All the peers should be checked")
+	}
+
+	select {
+	case note := <-sub.C():
+		if note.Alert.Kind != GenesisAlert {
+			t.Fatalf("The alert should be a %s alert, not %s", GenesisAlert, note.Alert.Kind)
+		}
+	default:
+		t.Fatal("The node should raise an alert for the last node")
+	}
+
+	select {
+	case note := <-sub.C():
+		t.Fatalf("The node should raise a single alert, not %+v", note.Alert)
+	default:
+	}
+}
+
+func TestGenesisAppHashMismatch(t *testing.T) {
+	keys, peers := initPeers(t, 1)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	// The dummy App reports the hash of an empty state
+	nodes[0].conf.GenesisAppHash = common.EncodeToString(crypto.SHA256([]byte("other")))
+
+	if err := nodes[0].initGenesis(); err == nil {
+		t.Fatal("initGenesis should fail when the App differs from the genesis document")
+	}
+
+	if nodes[0].genesisStateHash != nil {
+		t.Fatal("The node should not record the genesis state hash of the App")
+	}
+}
@@ -0,0 +1,215 @@
+package node
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+// Types of the edges of a GraphExport
+const (
+	SelfParentEdge  = "self-parent"
+	OtherParentEdge = "other-parent"
+)
+
+// GraphEvent is an event of a GraphExport, annotated with its consensus
+// properties. RoundReceived is nil until the event is received by a round.
+type GraphEvent struct {
+	ID            string `json:"id"`
+	Creator       string `json:"creator"`
+	Moniker       string `json:"moniker"`
+	Index         int    `json:"index"`
+	Round         int    `json:"round"`
+	Lamport       int    `json:"lamport"`
+	Witness       bool   `json:"witness"`
+	Famous        string `json:"famous"`
+	RoundReceived *int   `json:"round_received"`
+	Transactions  int    `json:"transactions"`
+}
+
+// GraphEdge links an event, the target, to one of its parents, the source.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// GraphExport is a window of the hashgraph, with the events created in a range
+// of rounds and the edges to their parents. Edges to parents outside of the
+// window are omitted. The nodes and links fields follow the layout expected
+// by D3 force-directed graphs.
+type GraphExport struct {
+	FromRound int          `json:"from_round"`
+	ToRound   int          `json:"to_round"`
+	Nodes     []GraphEvent `json:"nodes"`
+	Links     []GraphEdge  `json:"links"`
+}
+
+// Export returns the window of the hashgraph between rounds fromRound and
+// toRound, inclusive. toRound is capped at the last round.
+func (g *Graph) Export(fromRound, toRound int) (*GraphExport, error) {
+	g.Node.coreLock.RLock()
+	defer g.Node.coreLock.RUnlock()
+
+	store := g.Node.core.hg.Store
+
+	if last := store.LastRound(); toRound > last {
+		toRound = last
+	}
+
+	monikers := make(map[string]string)
+	for pub, p := range store.RepertoireByPubKey() {
+		monikers[pub] = p.Moniker
+	}
+	// The repertoire keeps the first moniker of each peer
+	for _, p := range g.Node.core.validators.Peers {
+		monikers[p.PubKeyString()] = p.Moniker
+	}
+
+	res := &GraphExport{
+		FromRound: fromRound,
+		ToRound:   toRound,
+		Nodes:     []GraphEvent{},
+		Links:     []GraphEdge{},
+	}
+
+	// parents of the events in the window, by hash
+	parents := make(map[string][2]string)
+
+	for r := fromRound; r <= toRound; r++ {
+		round, err := store.GetRound(r)
+		if err != nil {
+			return nil, fmt.Errorf("Round %d: %v", r, err)
+		}
+
+		for hash, re := range round.CreatedEvents {
+			event, err := store.GetEvent(hash)
+			if err != nil {
+				return nil, fmt.Errorf("Event %s: %v", hash, err)
+			}
+
+			lamport := -1
+			if l := event.GetLamportTimestamp(); l != nil {
+				lamport = *l
+			}
+
+			famous := common.Undefined
+			if re.Witness {
+				famous = re.Famous
+			}
+
+			res.Nodes = append(res.Nodes, GraphEvent{
+				ID:            hash,
+				Creator:       event.Creator(),
+				Moniker:       monikers[event.Creator()],
+				Index:         event.Index(),
+				Round:         r,
+				Lamport:       lamport,
+				Witness:       re.Witness,
+				Famous:        famous.String(),
+				RoundReceived: event.GetRoundReceived(),
+				Transactions:  len(event.Transactions()),
+			})
+
+			parents[hash] = [2]string{event.SelfParent(), event.OtherParent()}
+		}
+	}
+
+	sort.Slice(res.Nodes, func(i, j int) bool {
+		a, b := res.Nodes[i], res.Nodes[j]
+		if a.Creator != b.Creator {
+			return a.Creator < b.Creator
+		}
+		return a.Index < b.Index
+	})
+
+	for _, ev := range res.Nodes {
+		sp, op := parents[ev.ID][0], parents[ev.ID][1]
+
+		if _, ok := parents[sp]; ok {
+			res.Links = append(res.Links, GraphEdge{
+				Source: sp,
+				Target: ev.ID,
+				Type:   SelfParentEdge,
+			})
+		}
+
+		if _, ok := parents[op]; ok {
+			res.Links = append(res.Links, GraphEdge{
+				Source: op,
+				Target: ev.ID,
+				Type:   OtherParentEdge,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. The events of each
+// participant are drawn in a separate column, from bottom to top. Witnesses
+// are drawn as double circles, filled in gold when famous, and in grey when
+// not famous. Other-parent edges are dashed.
+func (e *GraphExport) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "digraph hashgraph {\n")
+	fmt.Fprintf(bw, "\trankdir=BT;\n")
+	fmt.Fprintf(bw, "\tnode [shape=circle, style=filled, fillcolor=white, fontsize=10];\n")
+
+	creator := ""
+	for _, ev := range e.Nodes {
+		if ev.Creator != creator {
+			if creator != "" {
+				fmt.Fprintf(bw, "\t}\n")
+			}
+			creator = ev.Creator
+			fmt.Fprintf(bw, "\tsubgraph %q {\n", "cluster_"+ev.Creator)
+			fmt.Fprintf(bw, "\t\tlabel=%q;\n", dotParticipant(ev))
+			fmt.Fprintf(bw, "\t\tcolor=lightgrey;\n")
+		}
+
+		attrs := fmt.Sprintf("label=%q", fmt.Sprintf("%d\nr%d", ev.Index, ev.Round))
+		if ev.Witness {
+			attrs += ", shape=doublecircle"
+			switch ev.Famous {
+			case common.True.String():
+				attrs += ", fillcolor=gold"
+			case common.False.String():
+				attrs += ", fillcolor=lightgrey"
+			}
+		}
+
+		fmt.Fprintf(bw, "\t\t%q [%s];\n", ev.ID, attrs)
+	}
+	if creator != "" {
+		fmt.Fprintf(bw, "\t}\n")
+	}
+
+	for _, l := range e.Links {
+		if l.Type == OtherParentEdge {
+			fmt.Fprintf(bw, "\t%q -> %q [style=dashed];\n", l.Source, l.Target)
+		} else {
+			fmt.Fprintf(bw, "\t%q -> %q;\n", l.Source, l.Target)
+		}
+	}
+
+	fmt.Fprintf(bw, "}\n")
+
+	return bw.Flush()
+}
+
+// dotParticipant returns the label of the column of a participant, which is
+// its moniker if known, or the beginning of its public key.
+func dotParticipant(ev GraphEvent) string {
+	if ev.Moniker != "" {
+		return ev.Moniker
+	}
+	if len(ev.Creator) > 10 {
+		return ev.Creator[:10]
+	}
+	return ev.Creator
+}
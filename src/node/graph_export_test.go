@@ -0,0 +1,86 @@
+package node
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGraphExport(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 100000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+
+	if err := gossip(nodes, 5, true); err != nil {
+		t.Fatal(err)
+	}
+
+	node := nodes[0]
+
+	export, err := NewGraph(node).Export(1, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if export.ToRound != node.GetLastRound() {
+		t.Fatalf("ToRound should be capped at %d, not %d", node.GetLastRound(), export.ToRound)
+	}
+
+	ids := make(map[string]GraphEvent)
+	witnesses := 0
+	for _, ev := range export.Nodes {
+		if ev.Round < 1 || ev.Round > export.ToRound {
+			t.Fatalf("Event %s of round %d is outside of the window", ev.ID, ev.Round)
+		}
+		if ev.Moniker == "" {
+			t.Fatalf("Event %s should have the moniker of its creator", ev.ID)
+		}
+		if ev.Witness {
+			witnesses++
+		}
+		ids[ev.ID] = ev
+	}
+
+	if witnesses == 0 {
+		t.Fatal("The export should contain witnesses")
+	}
+
+	if len(export.Links) == 0 {
+		t.Fatal("The export should contain edges")
+	}
+
+	for _, l := range export.Links {
+		source, ok := ids[l.Source]
+		if !ok {
+			t.Fatalf("Edge source %s is outside of the window", l.Source)
+		}
+		target, ok := ids[l.Target]
+		if !ok {
+			t.Fatalf("Edge target %s is outside of the window", l.Target)
+		}
+		if l.Type == SelfParentEdge &&
+			(source.Creator != target.Creator || source.Index != target.Index-1) {
+			t.Fatalf("Self-parent edge %s -> %s should link consecutive events of the same creator", l.Source, l.Target)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dot := buf.String()
+
+	if !strings.HasPrefix(dot, "digraph hashgraph {") {
+		t.Fatalf("DOT output should start with the digraph, not %q", dot[:20])
+	}
+	if c := strings.Count(dot, " -> "); c != len(export.Links) {
+		t.Fatalf("DOT output should contain %d edges, not %d", len(export.Links), c)
+	}
+	if c := strings.Count(dot, "subgraph "); c != len(peers.Peers) {
+		t.Fatalf("DOT output should contain %d columns, not %d", len(peers.Peers), c)
+	}
+}
@@ -0,0 +1,66 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+	"github.com/mosaicnetworks/babble/src/net"
+)
+
+// historyRPC returns true if an RPC serves the history of the hashgraph to a
+// node that is behind: a FastForwardRequest, or a SyncRequest from a peer that
+// does not know more than SyncLimit of the events known by this node, which
+// receives a full chunk of old events.
+func (n *Node) historyRPC(rpc net.RPC) bool {
+	switch cmd := rpc.Command.(type) {
+	case *net.FastForwardRequest:
+		return true
+	case *net.SyncRequest:
+		n.coreLock.RLock()
+		known := n.core.knownEvents()
+		n.coreLock.RUnlock()
+
+		lag := 0
+		for id, index := range known {
+			if d := index - cmd.Known[id]; d > 0 {
+				lag += d
+			}
+		}
+
+		return lag > n.conf.SyncLimit
+	default:
+		return false
+	}
+}
+
+// queueHistoryRPC queues an RPC serving history for the history workers. When
+// the queue is full, the RPC is refused right away, so that the requester tries
+// another peer instead of waiting.
+func (n *Node) queueHistoryRPC(rpc net.RPC) {
+	select {
+	case n.historyCh <- rpc:
+	default:
+		n.logger.WithField("cmd", fmt.Sprintf("%T", rpc.Command)).Debug("History workers busy => refusing RPC")
+		metrics.HistoryRPCsRefused.Inc()
+		rpc.Respond(nil, fmt.Errorf("Busy serving history"))
+	}
+}
+
+// processHistoryRPCs processes the RPCs serving history one at a time, until
+// the node shuts down. conf.HistoryWorkers of them run in parallel, apart from
+// the RPC workers, so that nodes catching up cannot take all the workers from
+// the RPCs that drive consensus.
+func (n *Node) processHistoryRPCs() {
+	for {
+		select {
+		case rpc := <-n.historyCh:
+			n.TrackFunc(func() {
+				defer n.recoverPanic()
+				n.processRPC(rpc)
+				n.resetTimer()
+			})
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
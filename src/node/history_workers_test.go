@@ -0,0 +1,64 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/net"
+)
+
+func TestHistoryWorkers(t *testing.T) {
+	keys, peers := initPeers(t, 3)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 5, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+
+	if err := gossip(nodes, 2, true); err != nil {
+		t.Fatal(err)
+	}
+
+	node := nodes[0]
+
+	// A peer that knows nothing is served by the history workers, unlike a
+	// peer that is up to date
+	empty := map[uint32]int{}
+	for _, p := range peers.Peers {
+		empty[p.ID()] = -1
+	}
+
+	cases := []struct {
+		command interface{}
+		history bool
+	}{
+		{&net.FastForwardRequest{}, true},
+		{&net.SyncRequest{Known: empty}, true},
+		{&net.SyncRequest{Known: node.core.knownEvents()}, false},
+		{&net.PingRequest{}, false},
+	}
+
+	for i, c := range cases {
+		if history := node.historyRPC(net.RPC{Command: c.command}); history != c.history {
+			t.Fatalf("Case %d: %T should be served by the history workers: %v", i, c.command, c.history)
+		}
+	}
+
+	// RPCs are refused when the queue is full
+	node.historyCh = make(chan net.RPC, 1)
+
+	respCh := make(chan net.RPCResponse, 2)
+	node.queueHistoryRPC(net.RPC{Command: &net.FastForwardRequest{}, RespChan: respCh})
+	node.queueHistoryRPC(net.RPC{Command: &net.FastForwardRequest{}, RespChan: respCh})
+
+	if len(node.historyCh) != 1 {
+		t.Fatalf("The first RPC should be queued")
+	}
+
+	select {
+	case resp := <-respCh:
+		if resp.Error == nil {
+			t.Fatal("The second RPC should be refused")
+		}
+	default:
+		t.Fatal("The second RPC should be answered right away")
+	}
+}
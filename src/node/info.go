@@ -0,0 +1,111 @@
+package node
+
+import (
+	"fmt"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/version"
+)
+
+// GetInfo returns the description of the node: its identity, the build that
+// it runs, the optional features that it has enabled, its transports, and the
+// backend of its store.
+func (n *Node) GetInfo() net.NodeInfo {
+	return net.NodeInfo{
+		ID:         n.GetID(),
+		PubKey:     n.GetPubKey(),
+		Moniker:    n.GetMoniker(),
+		Protocol:   version.LocalProtocol(),
+		GitCommit:  version.GitCommit,
+		Features:   n.features(),
+		Transports: n.transports(),
+		Store:      storeBackend(n.core.hg.Store),
+	}
+}
+
+// GetPeerInfo sends an InfoRequest to the peer at the given address, and
+// returns its description.
+func (n *Node) GetPeerInfo(addr string) (net.NodeInfo, error) {
+	resp, err := n.requestInfo(addr)
+	if err != nil {
+		return net.NodeInfo{}, err
+	}
+	return resp.Info, nil
+}
+
+// features returns the names of the options that change how the node works
+// with its peers, which are enabled.
+func (n *Node) features() []string {
+	enabled := []struct {
+		name string
+		on   bool
+	}{
+		{"fast-sync", n.conf.EnableFastSync},
+		{"commit-barrier", n.conf.CommitBarrier},
+		{"genesis-state", n.conf.GenesisAppHash != ""},
+		{"snapshot-delta", n.conf.SnapshotDelta},
+		{"verify-snapshots", n.conf.VerifySnapshots},
+		{"anti-entropy", n.conf.AntiEntropyInterval > 0},
+		{"sync-dedup", n.conf.SyncDedupWindow > 0},
+		{"latency-probes", n.conf.PingInterval > 0},
+		{"audit", n.conf.AuditInterval > 0},
+		{"watchdog", n.conf.WatchdogTimeout > 0},
+		{"metered", n.IsMetered()},
+		{"offline-mode", n.conf.OfflineMode},
+	}
+
+	features := []string{}
+	for _, f := range enabled {
+		if f.on {
+			features = append(features, f.name)
+		}
+	}
+
+	return features
+}
+
+// transports returns the names of the transports of the node.
+func (n *Node) transports() []string {
+	transports := []string{}
+
+	switch n.trans.(type) {
+	case *net.InmemTransport:
+		return append(transports, "inmem")
+	case *net.ChannelTransport:
+		transports = append(transports, "channel")
+	}
+
+	switch {
+	case n.conf.ReplayRPC != "":
+		transports = append(transports, "replay")
+	case n.conf.Relay:
+		transports = append(transports, "tcp", "webrtc", "relay")
+	case n.conf.WebRTC:
+		transports = append(transports, "webrtc")
+	default:
+		transports = append(transports, "tcp")
+	}
+
+	if n.conf.TorProxy != "" {
+		transports = append(transports, "tor")
+	}
+
+	if n.conf.RecordRPC != "" {
+		transports = append(transports, "record")
+	}
+
+	return transports
+}
+
+// storeBackend returns the name of the backend of a Store.
+func storeBackend(store hg.Store) string {
+	switch store.(type) {
+	case *hg.InmemStore:
+		return "inmem"
+	case *hg.BadgerStore:
+		return "badger"
+	default:
+		return fmt.Sprintf("%T", store)
+	}
+}
@@ -0,0 +1,34 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetPeerInfo(t *testing.T) {
+	keys, peers := initPeers(t, 2)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	runNodes(nodes, false)
+
+	info, err := nodes[0].GetPeerInfo(nodes[1].trans.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(info, nodes[1].GetInfo()) {
+		t.Fatalf("The info of the peer should be %+v, not %+v", nodes[1].GetInfo(), info)
+	}
+
+	if info.PubKey != nodes[1].GetPubKey() || info.Moniker != nodes[1].GetMoniker() {
+		t.Fatalf("The info should describe node %s, not %s", nodes[1].GetPubKey(), info.PubKey)
+	}
+
+	if info.Store != "inmem" || !reflect.DeepEqual(info.Transports, []string{"tcp"}) {
+		t.Fatalf("The peer should use an inmem store and a tcp transport: %+v", info)
+	}
+}
@@ -0,0 +1,89 @@
+package node
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// joinAdmissionWindows is the number of windows, after the current one,
+	// to which requesters can be queued. The others are not recorded, so that
+	// requests signed with new keys cannot grow the queue without bounds.
+	joinAdmissionWindows = 16
+
+	// maxJoinRetryAfter caps the duration, given by the peer, that a joining
+	// node waits before sending its JoinRequest again.
+	maxJoinRetryAfter = time.Minute
+)
+
+// joinAdmission limits the number of JoinRequests admitted per window of time,
+// when many nodes join at once. The requesters which do not fit in the current
+// window are given the first window with room left, in the order of their
+// requests, and are admitted when they come back in that window. The
+// requesters are forgotten once their window is over.
+type joinAdmission struct {
+	sync.Mutex
+
+	limit  int
+	window time.Duration
+	start  time.Time
+
+	// slots is the window given to each requester, by public key, and counts
+	// the number of requesters given to each window.
+	slots  map[string]int64
+	counts map[int64]int
+}
+
+func newJoinAdmission(limit int, window time.Duration, now time.Time) *joinAdmission {
+	return &joinAdmission{
+		limit:  limit,
+		window: window,
+		start:  now,
+		slots:  make(map[string]int64),
+		counts: make(map[int64]int),
+	}
+}
+
+// admit returns 0 if the JoinRequest of pubKey is admitted now, or the
+// duration after which it should be sent again.
+func (a *joinAdmission) admit(pubKey string, now time.Time) time.Duration {
+	a.Lock()
+	defer a.Unlock()
+
+	current := int64(now.Sub(a.start) / a.window)
+
+	// Forget the windows that are over
+	for key, w := range a.slots {
+		if w < current {
+			delete(a.slots, key)
+		}
+	}
+	for w := range a.counts {
+		if w < current {
+			delete(a.counts, w)
+		}
+	}
+
+	w, ok := a.slots[pubKey]
+	if !ok {
+		w = current
+		for a.counts[w] >= a.limit {
+			w++
+		}
+
+		// The queue is full. The requester comes back after the last queued
+		// window.
+		if w > current+joinAdmissionWindows {
+			return a.start.Add(time.Duration(w) * a.window).Sub(now)
+		}
+
+		a.slots[pubKey] = w
+		a.counts[w]++
+	}
+
+	if w == current {
+		return 0
+	}
+
+	return a.start.Add(time.Duration(w) * a.window).Sub(now)
+}
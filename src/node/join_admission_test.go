@@ -0,0 +1,75 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestJoinAdmission(t *testing.T) {
+	start := time.Unix(0, 0)
+	a := newJoinAdmission(2, 10*time.Second, start)
+
+	now := start.Add(time.Second)
+
+	expected := []time.Duration{0, 0, 9 * time.Second, 9 * time.Second, 19 * time.Second}
+	for i, e := range expected {
+		if r := a.admit(string(rune('a'+i)), now); r != e {
+			t.Fatalf("Request %d should retry after %v, not %v", i, e, r)
+		}
+	}
+
+	// A requester asking again keeps its window
+	if r := a.admit("c", now.Add(time.Second)); r != 8*time.Second {
+		t.Fatalf("Request c should retry after 8s, not %v", r)
+	}
+
+	// In the next window, the queued requesters are admitted, and the new
+	// ones come after the last of them
+	now = start.Add(12 * time.Second)
+
+	for _, key := range []string{"c", "d"} {
+		if r := a.admit(key, now); r != 0 {
+			t.Fatalf("Request %s should be admitted, not retry after %v", key, r)
+		}
+	}
+
+	if r := a.admit("f", now); r != 8*time.Second {
+		t.Fatalf("Request f should retry after 8s, not %v", r)
+	}
+
+	if _, ok := a.slots["a"]; ok {
+		t.Fatal("The requesters of past windows should be forgotten")
+	}
+}
+
+func TestJoinAdmissionFull(t *testing.T) {
+	start := time.Unix(0, 0)
+	a := newJoinAdmission(1, 10*time.Second, start)
+
+	for i := 0; i <= joinAdmissionWindows; i++ {
+		expected := time.Duration(i) * 10 * time.Second
+		if r := a.admit(fmt.Sprintf("key%d", i), start); r != expected {
+			t.Fatalf("Request %d should retry after %v, not %v", i, expected, r)
+		}
+	}
+
+	// Once all the windows are taken, the requesters are not recorded
+	expected := time.Duration(joinAdmissionWindows+1) * 10 * time.Second
+	for i := 0; i < 10; i++ {
+		if r := a.admit(fmt.Sprintf("extra%d", i), start); r != expected {
+			t.Fatalf("Extra request %d should retry after %v, not %v", i, expected, r)
+		}
+	}
+
+	if l := len(a.slots); l != joinAdmissionWindows+1 {
+		t.Fatalf("The queue should hold %d requesters, not %d", joinAdmissionWindows+1, l)
+	}
+
+	// The requesters are forgotten once their window is over
+	a.admit("late", start.Add(time.Duration(joinAdmissionWindows+1)*10*time.Second))
+
+	if l := len(a.slots); l != 1 {
+		t.Fatalf("Only the last requester should be recorded, not %d", l)
+	}
+}
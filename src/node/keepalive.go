@@ -0,0 +1,67 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// keepalive periodically checks the connection to every peer with keepalives,
+// until the node shuts down. Unlike the latency probes, keepalives are answered
+// by the transport of the peer, so they tell connectivity problems apart from
+// problems with the node of the peer.
+func (n *Node) keepalive(kt net.KeepaliveTransport, interval time.Duration) {
+	ticker := n.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			if exceeded, _ := n.dataBudget.exceeded(n.clock.Now()); exceeded {
+				continue
+			}
+
+			n.keepalivePeers(kt)
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// keepalivePeers sends keepalives to all the peers other than this node in
+// parallel, and records their outcome.
+func (n *Node) keepalivePeers(kt net.KeepaliveTransport) {
+	n.coreLock.RLock()
+	_, others := peers.ExcludePeer(n.core.peers.Peers, n.core.validator.ID())
+	n.coreLock.RUnlock()
+
+	var wg sync.WaitGroup
+
+	for _, p := range others {
+		if n.isBanned(p.NetAddr) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(p *peers.Peer) {
+			defer wg.Done()
+
+			addr := n.peerAddr(p)
+
+			start := time.Now()
+			rtt, err := kt.Keepalive(addr)
+			observeRPC(metrics.RPCKeepalive, start, err)
+
+			if err != nil {
+				n.logger.WithError(err).WithField("peer", addr).Debug("Keepalive")
+			}
+
+			n.recordKeepalive(p.ID(), rtt, err)
+		}(p)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,40 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/net"
+)
+
+func TestKeepalive(t *testing.T) {
+	keys, peers := initPeers(t, 3)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	node := nodes[0]
+
+	nodes[2].Shutdown()
+
+	node.keepalivePeers(node.trans.(net.KeepaliveTransport))
+
+	stats := make(map[uint32]PeerStats)
+	for _, ps := range node.GetPeerStats() {
+		stats[ps.ID] = ps
+	}
+
+	if ps := stats[nodes[1].GetID()]; ps.LastKeepalive == nil || ps.KeepaliveError != "" {
+		t.Fatalf("The keepalive to node 1 should succeed: %+v", ps)
+	}
+
+	if ps := stats[nodes[2].GetID()]; ps.LastKeepalive != nil || ps.KeepaliveError == "" {
+		t.Fatalf("The keepalive to the stopped node 2 should fail: %+v", ps)
+	}
+
+	// Without gossip, the peers are reachable, but not synced with
+	if ps := stats[nodes[1].GetID()]; ps.LastSync != nil {
+		t.Fatal("Keepalives should not count as gossip")
+	}
+}
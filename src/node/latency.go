@@ -0,0 +1,108 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// latencyWeight is the weight of a new measurement in the moving average of
+// the round-trip time to a peer.
+const latencyWeight = 0.2
+
+// latencies records the round-trip time to each peer, as an exponential moving
+// average of the latency probes. It is shared by the probes and the peer
+// selector, so it has its own lock.
+type latencies struct {
+	sync.Mutex
+	rtts map[uint32]time.Duration
+}
+
+func newLatencies() *latencies {
+	return &latencies{
+		rtts: make(map[uint32]time.Duration),
+	}
+}
+
+// record adds a measurement of the round-trip time to a peer.
+func (l *latencies) record(id uint32, rtt time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	if avg, ok := l.rtts[id]; ok {
+		rtt = time.Duration(latencyWeight*float64(rtt) + (1-latencyWeight)*float64(avg))
+	}
+	l.rtts[id] = rtt
+}
+
+// get returns the round-trip time to a peer, if it was measured.
+func (l *latencies) get(id uint32) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	rtt, ok := l.rtts[id]
+	return rtt, ok
+}
+
+// probeLatency periodically pings every peer to measure the round-trip time,
+// until the node shuts down.
+func (n *Node) probeLatency(interval time.Duration) {
+	ticker := n.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			if n.GetState() != _state.Babbling {
+				continue
+			}
+
+			if exceeded, _ := n.dataBudget.exceeded(n.clock.Now()); exceeded {
+				continue
+			}
+
+			n.pingPeers()
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// pingPeers pings all the peers other than this node in parallel, and records
+// the round-trip times of the successful pings.
+func (n *Node) pingPeers() {
+	n.coreLock.RLock()
+	_, others := peers.ExcludePeer(n.core.peers.Peers, n.core.validator.ID())
+	n.coreLock.RUnlock()
+
+	var wg sync.WaitGroup
+
+	for _, p := range others {
+		if n.isBanned(p.NetAddr) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(p *peers.Peer) {
+			defer wg.Done()
+
+			addr := n.peerAddr(p)
+
+			start := time.Now()
+			_, err := n.requestPing(addr)
+			rtt := time.Since(start)
+
+			n.recordContact(p, addr, err)
+			if err != nil {
+				n.logger.WithError(err).WithField("peer", addr).Debug("Ping")
+				return
+			}
+
+			n.latencies.record(p.ID(), rtt)
+		}(p)
+	}
+
+	wg.Wait()
+}
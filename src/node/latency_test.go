@@ -0,0 +1,104 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestLatencies(t *testing.T) {
+	l := newLatencies()
+
+	if _, ok := l.get(1); ok {
+		t.Fatal("Peer 1 should not have a latency yet")
+	}
+
+	l.record(1, 100*time.Millisecond)
+	l.record(1, 200*time.Millisecond)
+
+	if rtt, _ := l.get(1); rtt != 120*time.Millisecond {
+		t.Fatalf("The latency of peer 1 should be 120ms, not %v", rtt)
+	}
+}
+
+func TestLatencyPeerSelector(t *testing.T) {
+	ps := peers.NewPeerSet([]*peers.Peer{
+		peers.NewPeer("0X01", "self", "self"),
+		peers.NewPeer("0X02", "near", "near"),
+		peers.NewPeer("0X03", "mid", "mid"),
+		peers.NewPeer("0X04", "far", "far"),
+		peers.NewPeer("0X05", "unknown", "unknown"),
+	})
+	self, near, mid, far, unknown := ps.Peers[0].ID(), ps.Peers[1].ID(), ps.Peers[2].ID(), ps.Peers[3].ID(), ps.Peers[4].ID()
+
+	l := newLatencies()
+	l.record(near, 10*time.Millisecond)
+	l.record(mid, 50*time.Millisecond)
+	l.record(far, time.Second)
+
+	selector := newLatencyPeerSelector(ps, self, l)
+
+	counts := make(map[uint32]int)
+	lastSeen := make(map[uint32]int)
+	maxGap := 0
+
+	for i := 0; i < 10000; i++ {
+		peer := selector.next()
+		selector.updateLast(peer.ID(), true)
+
+		if gap := i - lastSeen[peer.ID()]; gap > maxGap {
+			maxGap = gap
+		}
+		lastSeen[peer.ID()] = i
+		counts[peer.ID()]++
+	}
+
+	if counts[self] != 0 {
+		t.Fatal("The selector should never select this node")
+	}
+
+	if counts[near] <= counts[mid] || counts[mid] <= counts[far] {
+		t.Fatalf("Peers with a lower latency should be selected more often: %v", counts)
+	}
+
+	// The unmeasured peer is weighted like the median peer
+	if counts[unknown] <= counts[far] || counts[unknown] >= counts[near] {
+		t.Fatalf("The unmeasured peer should be selected like the median peer: %v", counts)
+	}
+
+	// Every peer is selected at least once in starvationFactor times the
+	// number of selectable peers, plus the other starved peers
+	if limit := (starvationFactor + 1) * 4; maxGap > limit {
+		t.Fatalf("Every peer should be selected within %d selections, not %d", limit, maxGap)
+	}
+}
+
+func TestPingPeers(t *testing.T) {
+	keys, peers := initPeers(t, 3)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 10*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	for _, n := range nodes {
+		n.RunAsync(false)
+	}
+
+	nodes[0].pingPeers()
+
+	for _, p := range peers.Peers {
+		if p.ID() == nodes[0].GetID() {
+			continue
+		}
+		if _, ok := nodes[0].latencies.get(p.ID()); !ok {
+			t.Fatalf("The latency of peer %d should be measured", p.ID())
+		}
+	}
+
+	for _, ps := range nodes[0].GetPeerStats() {
+		if ps.Latency <= 0 {
+			t.Fatalf("The peer stats of %d should report the latency", ps.ID)
+		}
+	}
+}
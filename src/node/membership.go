@@ -0,0 +1,61 @@
+package node
+
+import (
+	"sort"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// MembershipChange is an accepted PEER_ADD, PEER_REMOVE, PEER_RENAME or
+// PEER_ADDRESS. Round is the round from which it took effect, and Signers are
+// the public keys of the validators that signed the Block that accepted it, as
+// far as this node knows.
+type MembershipChange struct {
+	Block         int         `json:"block"`
+	RoundReceived int         `json:"round_received"`
+	Round         int         `json:"round"`
+	Type          string      `json:"type"`
+	Peer          *peers.Peer `json:"peer"`
+	Signers       []string    `json:"signers"`
+}
+
+// GetMembershipChanges returns the history of the validator-set, change by
+// change, in the order of the blockchain. Nodes that fast-forwarded only know
+// the changes from the Block they fast-forwarded to.
+func (n *Node) GetMembershipChanges() ([]MembershipChange, error) {
+	return n.core.membershipChanges()
+}
+
+// membershipChanges resolves the signers of the membership changes recorded in
+// the store.
+func (c *core) membershipChanges() ([]MembershipChange, error) {
+	changes, err := c.hg.Store.GetMembershipChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]MembershipChange, 0, len(changes))
+	for _, change := range changes {
+		peer := change.Peer
+
+		signers := []string{}
+		if block, err := c.hg.Store.GetBlock(change.Block); err == nil {
+			for _, sig := range block.GetSignatures() {
+				signers = append(signers, common.EncodeToString(sig.Validator))
+			}
+			sort.Strings(signers)
+		}
+
+		res = append(res, MembershipChange{
+			Block:         change.Block,
+			RoundReceived: change.RoundReceived,
+			Round:         change.Round,
+			Type:          change.Type.String(),
+			Peer:          &peer,
+			Signers:       signers,
+		})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestMembershipChanges(t *testing.T) {
+	cores, participantKeys, _ := initCores(4, t)
+	c := cores[0]
+
+	validators := c.validators.Peers
+
+	key, _ := keys.GenerateECDSAKey()
+	newPeer := peers.NewPeer(keys.PublicKeyHex(&key.PublicKey), "", "new")
+
+	join := hg.NewInternalTransactionJoin(*newPeer)
+	refused := hg.NewInternalTransactionLeave(*validators[1])
+	upgrade := hg.NewInternalTransactionUpgrade(*validators[2], 2)
+
+	// The block which accepts the join is signed by two validators
+	block := hg.NewBlock(3, 4, []byte("frame"), validators, [][]byte{}, []hg.InternalTransaction{join, refused, upgrade})
+
+	signers := []string{}
+	for _, v := range validators[:2] {
+		sig, err := block.Sign(participantKeys[v.ID()])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := block.SetSignature(sig); err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, common.EncodeToString(v.PubKeyBytes()))
+	}
+	sort.Strings(signers)
+
+	if err := c.hg.Store.SetBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	receipts := []hg.InternalTransactionReceipt{
+		join.AsAccepted(),
+		refused.AsRefused(),
+		upgrade.AsAccepted(),
+	}
+
+	if err := c.processAcceptedInternalTransactions(3, 4, receipts); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := c.membershipChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the accepted join is a membership change
+	if len(changes) != 1 {
+		t.Fatalf("There should be 1 membership change, not %d", len(changes))
+	}
+
+	change := changes[0]
+	if change.Block != 3 || change.RoundReceived != 4 || change.Round != 10 {
+		t.Fatalf("The change should be accepted in block 3 at round 4, effective from round 10, not %+v", change)
+	}
+	if change.Type != "PEER_ADD" || change.Peer.PubKeyString() != newPeer.PubKeyString() {
+		t.Fatalf("The change should add %s, not %+v", newPeer.PubKeyString(), change)
+	}
+	if len(change.Signers) != 2 || change.Signers[0] != signers[0] || change.Signers[1] != signers[1] {
+		t.Fatalf("Signers should be %v, not %v", signers, change.Signers)
+	}
+}
+
+func TestPeerSetInterval(t *testing.T) {
+	cores, _, _ := initCores(4, t)
+	c := cores[0]
+	c.peerSetInterval = 10
+
+	newPeer := func(moniker string) *peers.Peer {
+		key, _ := keys.GenerateECDSAKey()
+		return peers.NewPeer(keys.PublicKeyHex(&key.PublicKey), "", moniker)
+	}
+
+	first := hg.NewInternalTransactionJoin(*newPeer("first"))
+	second := hg.NewInternalTransactionJoin(*newPeer("second"))
+
+	// Both joins are accepted in different rounds, but take effect together
+	// at round 20
+	if err := c.processAcceptedInternalTransactions(1, 8, []hg.InternalTransactionReceipt{first.AsAccepted()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.processAcceptedInternalTransactions(2, 11, []hg.InternalTransactionReceipt{second.AsAccepted()}); err != nil {
+		t.Fatal(err)
+	}
+
+	peerSets, err := c.hg.Store.GetAllPeerSets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(peerSets) != 2 {
+		t.Fatalf("There should be 2 peer-sets, at rounds 0 and 20, not %v", peerSets)
+	}
+	if ps, ok := peerSets[20]; !ok || len(ps) != 6 {
+		t.Fatalf("The peer-set of round 20 should have 6 validators, not %v", ps)
+	}
+
+	changes, err := c.membershipChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range changes {
+		if change.Round != 20 {
+			t.Fatalf("The change should take effect from round 20, not %+v", change)
+		}
+	}
+
+	if c.targetRound != 20 {
+		t.Fatalf("TargetRound should be 20, not %d", c.targetRound)
+	}
+}
@@ -0,0 +1,167 @@
+package node
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// memoryCheckInterval is the period at which the memory usage is sampled.
+const memoryCheckInterval = time.Second
+
+// The node sheds load when its memory usage reaches memoryPressureRatio of the
+// budget, and stops when it falls under memoryReliefRatio, so that it does not
+// switch back and forth around the limit.
+const (
+	memoryPressureRatio = 0.9
+	memoryReliefRatio   = 0.75
+)
+
+// memoryCacheFactor is the factor by which the consensus caches are shrunk
+// under memory pressure.
+const memoryCacheFactor = 4
+
+// memoryBudget limits the memory used by the node to a maximum number of bytes.
+// When the usage approaches the maximum, the node is under pressure, and sheds
+// load rather than being killed by the operating system. The usage is sampled
+// periodically, because reading it stops the world. A budget with a maximum of
+// 0 is never exceeded.
+type memoryBudget struct {
+	sync.Mutex
+
+	max      int64
+	usage    int64
+	pressure bool
+
+	// readUsage returns the memory used by the process.
+	readUsage func() int64
+}
+
+func newMemoryBudget(max int64) *memoryBudget {
+	return &memoryBudget{
+		max:       max,
+		readUsage: runtimeMemory,
+	}
+}
+
+// runtimeMemory returns the memory obtained from the operating system by the
+// Go runtime, minus the memory that it released.
+func runtimeMemory() int64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return int64(m.Sys - m.HeapReleased)
+}
+
+// setMax changes the maximum number of bytes. The pressure is evaluated again
+// at the next sample.
+func (b *memoryBudget) setMax(max int64) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.max = max
+}
+
+// status returns the last sampled usage, the maximum, and whether the node is
+// under pressure.
+func (b *memoryBudget) status() (usage int64, max int64, pressure bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.usage, b.max, b.pressure
+}
+
+// underPressure returns true if the node should shed load.
+func (b *memoryBudget) underPressure() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.pressure
+}
+
+// sample reads the memory usage and updates the pressure. The second value is
+// true if the pressure changed.
+func (b *memoryBudget) sample() (pressure bool, changed bool) {
+	usage := b.readUsage()
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.usage = usage
+	was := b.pressure
+
+	switch {
+	case b.max <= 0:
+		b.pressure = false
+	case float64(usage) >= memoryPressureRatio*float64(b.max):
+		b.pressure = true
+	case float64(usage) < memoryReliefRatio*float64(b.max):
+		b.pressure = false
+	}
+
+	return b.pressure, b.pressure != was
+}
+
+// monitorMemory periodically samples the memory usage of the node, until the
+// node shuts down.
+func (n *Node) monitorMemory() {
+	n.checkMemory()
+
+	ticker := n.clock.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			n.checkMemory()
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// checkMemory samples the memory usage, and shrinks the consensus caches when
+// the node comes under pressure, or restores them when the pressure is
+// relieved. While under pressure, the node also rejects new transactions and
+// FastForward requests.
+func (n *Node) checkMemory() {
+	pressure, changed := n.memoryBudget.sample()
+	if !changed {
+		return
+	}
+
+	n.coreLock.Lock()
+	n.core.hg.SetCacheSize(n.consensusCacheSize())
+	n.coreLock.Unlock()
+
+	usage, max, _ := n.memoryBudget.status()
+	fields := logrus.Fields{
+		"usage":  usage,
+		"budget": max,
+	}
+
+	if pressure {
+		n.fastForwardCache.clear()
+
+		// Return the memory of the evicted cache entries to the system
+		debug.FreeOSMemory()
+		n.logger.WithFields(fields).Warn("Memory budget almost exhausted, shedding load")
+	} else {
+		n.logger.WithFields(fields).Info("Memory usage back under budget")
+	}
+}
+
+// consensusCacheSize returns the size of the consensus caches, which is reduced
+// under memory pressure.
+func (n *Node) consensusCacheSize() int {
+	size := n.conf.CacheSize
+	if n.memoryBudget.underPressure() {
+		size /= memoryCacheFactor
+		if size < 1 {
+			size = 1
+		}
+	}
+	return size
+}
@@ -0,0 +1,93 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/net"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	usage := int64(0)
+
+	b := newMemoryBudget(1000)
+	b.readUsage = func() int64 { return usage }
+
+	usage = 800
+	if pressure, changed := b.sample(); pressure || changed {
+		t.Fatal("The node should not be under pressure under 90% of the budget")
+	}
+
+	usage = 900
+	if pressure, changed := b.sample(); !pressure || !changed {
+		t.Fatal("The node should come under pressure at 90% of the budget")
+	}
+
+	// The pressure is only relieved under 75% of the budget
+	usage = 800
+	if pressure, changed := b.sample(); !pressure || changed {
+		t.Fatal("The node should still be under pressure above 75% of the budget")
+	}
+
+	usage = 700
+	if pressure, changed := b.sample(); pressure || !changed {
+		t.Fatal("The pressure should be relieved under 75% of the budget")
+	}
+
+	if u, max, _ := b.status(); u != 700 || max != 1000 {
+		t.Fatalf("The status should report 700 of 1000 bytes, not %d of %d", u, max)
+	}
+
+	// 0 means unlimited
+	usage = 1 << 40
+	b.setMax(0)
+	if pressure, _ := b.sample(); pressure {
+		t.Fatal("A budget of 0 should never be exceeded")
+	}
+}
+
+func TestMemoryPressure(t *testing.T) {
+	keys, peers := initPeers(t, 2)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 10*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	node := nodes[0]
+
+	usage := int64(950)
+	node.memoryBudget.readUsage = func() int64 { return usage }
+	node.SetMaxMemory(1000)
+
+	node.checkMemory()
+
+	if err := node.SubmitTx([]byte("tx")); err == nil {
+		t.Fatal("Transactions should be rejected under memory pressure")
+	}
+
+	if size := node.consensusCacheSize(); size != node.conf.CacheSize/memoryCacheFactor {
+		t.Fatalf("The consensus caches should be shrunk to %d, not %d", node.conf.CacheSize/memoryCacheFactor, size)
+	}
+
+	respCh := make(chan net.RPCResponse, 1)
+	node.processFastForwardRequest(net.RPC{RespChan: respCh}, &net.FastForwardRequest{FromID: nodes[1].GetID()})
+	if resp := <-respCh; resp.Error == nil {
+		t.Fatal("FastForward requests should be deferred under memory pressure")
+	}
+
+	stats := node.Stats()
+	if !stats.MemoryPressure || stats.MemoryUsage != 950 || stats.MemoryBudget != 1000 {
+		t.Fatalf("The stats should report the memory pressure, not %+v", stats)
+	}
+
+	usage = 500
+	node.checkMemory()
+
+	if node.memoryBudget.underPressure() {
+		t.Fatal("The pressure should be relieved")
+	}
+
+	if size := node.consensusCacheSize(); size != node.conf.CacheSize {
+		t.Fatalf("The consensus caches should be restored to %d, not %d", node.conf.CacheSize, size)
+	}
+}
@@ -0,0 +1,64 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+// ChangeMoniker submits an InternalTransaction, signed by the validator, to
+// change its moniker, and waits for it to go through consensus. Monikers are
+// unique within the validator-set, ignoring case, so the change is refused if
+// another validator already uses the moniker by the time it is processed.
+func (n *Node) ChangeMoniker(moniker string) error {
+	if strings.TrimSpace(moniker) == "" {
+		return fmt.Errorf("Moniker cannot be empty")
+	}
+
+	n.coreLock.Lock()
+
+	p, ok := n.core.validators.ByID[n.core.validator.ID()]
+	if !ok {
+		n.coreLock.Unlock()
+		return fmt.Errorf("Only validators can change their moniker")
+	}
+
+	if p.Moniker == moniker {
+		n.coreLock.Unlock()
+		return fmt.Errorf("Moniker is already %s", moniker)
+	}
+
+	if other, ok := n.core.validators.ByMoniker(moniker); ok && other.ID() != p.ID() {
+		n.coreLock.Unlock()
+		return fmt.Errorf("Moniker %s is already used by %s", moniker, other.PubKeyString())
+	}
+
+	itx := hg.NewInternalTransactionRename(*p, moniker)
+	itx.Sign(n.core.validator.Key)
+
+	promise := n.core.addInternalTransaction(itx)
+
+	n.coreLock.Unlock()
+
+	n.logger.WithField("moniker", moniker).Info("Changing moniker")
+
+	select {
+	case resp := <-promise.respCh:
+		if !resp.accepted {
+			return fmt.Errorf("Moniker change refused")
+		}
+	case <-n.clock.After(n.conf.JoinTimeout):
+		return fmt.Errorf("Timeout waiting for the moniker change to go through consensus")
+	}
+
+	return nil
+}
+
+// GetMoniker returns the moniker of the validator.
+func (n *Node) GetMoniker() string {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	return n.core.validator.Moniker
+}
@@ -0,0 +1,83 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestMonikerUniqueness(t *testing.T) {
+	cores, _, _ := initCores(4, t)
+	c := cores[0]
+
+	validators := c.validators.Peers
+
+	newPeer := func(moniker string) *peers.Peer {
+		key, _ := keys.GenerateECDSAKey()
+		return peers.NewPeer(keys.PublicKeyHex(&key.PublicKey), "", moniker)
+	}
+
+	alice := newPeer("alice")
+	otherAlice := newPeer("Alice")
+
+	join := hg.NewInternalTransactionJoin(*alice)
+	duplicateJoin := hg.NewInternalTransactionJoin(*otherAlice)
+	rename := hg.NewInternalTransactionRename(*c.validators.ByID[c.validator.ID()], "bob")
+	duplicateRename := hg.NewInternalTransactionRename(*validators[1], "ALICE")
+	strangerRename := hg.NewInternalTransactionRename(*newPeer(""), "carol")
+
+	promise := c.addInternalTransaction(duplicateJoin)
+
+	receipts := []hg.InternalTransactionReceipt{
+		join.AsAccepted(),
+		duplicateJoin.AsAccepted(),
+		rename.AsAccepted(),
+		duplicateRename.AsAccepted(),
+		strangerRename.AsAccepted(),
+	}
+
+	if err := c.processAcceptedInternalTransactions(0, 0, receipts); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.validators.ByID[alice.ID()]; !ok {
+		t.Fatalf("alice should have joined")
+	}
+	if _, ok := c.validators.ByID[otherAlice.ID()]; ok {
+		t.Fatalf("Alice should not have joined, the moniker is already used")
+	}
+
+	resp := <-promise.respCh
+	if resp.accepted {
+		t.Fatalf("The duplicate join should be refused")
+	}
+
+	if m := c.validators.ByID[c.validator.ID()].Moniker; m != "bob" {
+		t.Fatalf("The validator should be renamed bob, not %s", m)
+	}
+	if c.validator.Moniker != "bob" {
+		t.Fatalf("The moniker of the validator should be bob, not %s", c.validator.Moniker)
+	}
+	if _, ok := c.peers.ByMoniker("bob"); !ok {
+		t.Fatalf("The peers should know the validator as bob")
+	}
+
+	if m := c.validators.ByID[validators[1].ID()].Moniker; m != validators[1].Moniker {
+		t.Fatalf("validators[1] should not be renamed, not %s", m)
+	}
+	if _, ok := c.validators.ByMoniker("carol"); ok {
+		t.Fatalf("Peers that are not validators cannot be renamed")
+	}
+
+	// The rename is recorded like the other membership changes, and the
+	// refused transactions are not
+	changes, err := c.membershipChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Type != "PEER_ADD" || changes[1].Type != "PEER_RENAME" {
+		t.Fatalf("There should be a PEER_ADD and a PEER_RENAME, not %v", changes)
+	}
+}
@@ -1,23 +1,31 @@
 package node
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
-	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/mosaicnetworks/babble/src/common"
 	"github.com/mosaicnetworks/babble/src/config"
 	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/metrics"
 	"github.com/mosaicnetworks/babble/src/net"
 	_state "github.com/mosaicnetworks/babble/src/node/state"
 	"github.com/mosaicnetworks/babble/src/peers"
 	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/mosaicnetworks/babble/src/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// maxSyncChunks is the max number of chunked SyncResponses requested in a
+// single pull. The following chunks are requested in the next gossip rounds.
+const maxSyncChunks = 10
+
 // Node defines a babble node
 type Node struct {
 	// The node is implemented as a state-machine. The embedded state Manager
@@ -30,15 +38,30 @@ type Node struct {
 
 	// core is the link between the node and the underlying hashgraph. It
 	// controls some higher-level operations like inserting a list of events,
-	// keeping track of the peers list, fast-forwarding, etc.
+	// keeping track of the peers list, fast-forwarding, etc. The coreLock is
+	// held exclusively to modify the core, and shared to read it, so that the
+	// stats, the service queries, and the SyncResponses do not stall each
+	// other.
 	core     *core
-	coreLock sync.Mutex
+	coreLock sync.RWMutex
 
 	// transport is the object used to transmit and receive commands to other
 	// nodes.
 	trans net.Transport
 	netCh <-chan net.RPC
 
+	// historyCh queues the RPCs serving history for the history workers, when
+	// conf.HistoryWorkers is set.
+	historyCh chan net.RPC
+
+	// joinAdmission limits the JoinRequests admitted per window, when
+	// conf.JoinAdmissionLimit is set.
+	joinAdmission *joinAdmission
+
+	// offlineLog holds the transactions submitted while the node is cut off
+	// from the quorum, in offline-mode.
+	offlineLog offlineLog
+
 	// proxy is the link between the node and the application. It is used to
 	// commit blocks from Babble to the application, and relay submitted
 	// transactions from the application to Babble.
@@ -48,6 +71,10 @@ type Node struct {
 	// submitted to Babble
 	submitCh chan []byte
 
+	// ttlSubmitCh is where the node listens for the transactions submitted
+	// with a TTL, by SubmitTxWithTTL.
+	ttlSubmitCh chan ttlTx
+
 	// sigCh is where the node listens for signals to politely leave the Babble
 	// network. It listens to SIGINT and SIGTERM
 	sigCh chan os.Signal
@@ -58,14 +85,83 @@ type Node struct {
 	// suspendCh is used to signal the node to enter the Suspended state.
 	suspendCh chan struct{}
 
+	// fastForwardCh is used to signal the node to leave the Babbling state
+	// and fast-forward.
+	fastForwardCh chan struct{}
+
+	// bannedAddrs contains the addresses of the peers that the node refuses
+	// to gossip with. It is protected by bannedLock.
+	bannedAddrs map[string]struct{}
+	bannedLock  sync.Mutex
+
 	// The node runs the controlTimer in the background to periodically receive
 	// signals to initiate gossip routines. It is paused, reset, etc., based on
 	// the node's current state.
 	controlTimer *controlTimer
 
-	start        time.Time
-	syncRequests int
-	syncErrors   int
+	// clock is the clock of the timers, timeouts and time windows of the node.
+	// The durations observed by the metrics are measured in real time.
+	clock common.Clock
+
+	start time.Time
+
+	// peerStats records the connectivity of the node with each peer, and
+	// syncRequests and syncErrors count the gossip attempts of the node, and
+	// the ones that failed. They are protected by the peerStatsLock.
+	peerStats     map[uint32]*PeerStats
+	syncRequests  int
+	syncErrors    int
+	peerStatsLock sync.Mutex
+
+	// peerKnown records, for each participant, the highest event index
+	// reported by other nodes in sync requests and responses. It is compared
+	// with the node's own known events to evaluate how far behind the node is.
+	// It is protected by the peerKnownLock, because it is updated by readers
+	// of the core.
+	peerKnown     map[uint32]int
+	peerKnownLock sync.Mutex
+
+	// latencies records the round-trip times measured by the latency probes.
+	latencies *latencies
+
+	// dataBudget limits the traffic of the node to conf.MaxBytesPerHour.
+	dataBudget *dataBudget
+
+	// peerTraffic counts the traffic with each peer, and enforces
+	// conf.PeerQuota.
+	peerTraffic *peerTraffic
+
+	// memoryBudget limits the memory of the node to conf.MaxMemory.
+	memoryBudget *memoryBudget
+
+	// fastForwardCache keeps the FastForwardResponse of the anchor block.
+	fastForwardCache fastForwardCache
+
+	// suspensions records why the node is suspended, and counts the
+	// suspensions by reason.
+	suspensions *suspensions
+
+	// sentEvents records the events recently sent to each peer, to avoid
+	// sending them again within conf.SyncDedupWindow.
+	sentEvents *sentEvents
+
+	// addrBook records the addresses of the peers and when they were last
+	// reached. It is kept in memory unless it is replaced with SetAddressBook.
+	addrBook *peers.AddressBook
+
+	// trustedBlock, if set by SetTrustedBlock, is the block from which the
+	// node fast-forwards when it starts, instead of the anchor block of its
+	// peers. It is cleared once the node has synced from it.
+	trustedBlock *TrustedBlock
+
+	// genesisStateHash is the genesis state hash of the App, when the genesis
+	// document defines it. It is reported to the peers in PingResponses.
+	genesisStateHash []byte
+
+	// noSnapshotDelta is set when the App failed to restore a snapshot delta,
+	// with conf.SnapshotDelta, so that the node requests the full snapshot the
+	// next time it fast-forwards.
+	noSnapshotDelta bool
 
 	// initialUndeterminedEvents keeps a record of how many undetermined events
 	// there were upon initalizing the node. This value is regularly compared
@@ -95,7 +191,23 @@ func NewNode(conf *config.Config,
 		store,
 		proxy.CommitBlock,
 		conf.MaintenanceMode,
-		conf.Logger())
+		conf.ModuleLogger("node"))
+
+	core.commitBarrier = conf.CommitBarrier
+	core.peerSetInterval = conf.PeerSetInterval
+	core.openEnvelopes = conf.PrivateTransactions
+	core.proxyCommitCallback = core.skipAcknowledged(proxy.CommitBlock)
+
+	latencies := newLatencies()
+	if conf.PeerSelector == "latency" {
+		core.latencies = latencies
+		core.peerSelector = core.newPeerSelector()
+	}
+
+	clock := conf.Clock
+	if clock == nil {
+		clock = common.RealClock{}
+	}
 
 	netCh := make(<-chan net.RPC)
 	if trans != nil {
@@ -103,17 +215,43 @@ func NewNode(conf *config.Config,
 	}
 
 	node := Node{
-		conf:         conf,
-		logger:       conf.Logger(),
-		core:         core,
-		trans:        trans,
-		netCh:        netCh,
-		proxy:        proxy,
-		submitCh:     proxy.SubmitCh(),
-		sigCh:        sigCh,
-		shutdownCh:   make(chan struct{}),
-		suspendCh:    make(chan struct{}),
-		controlTimer: newRandomControlTimer(),
+		conf:          conf,
+		logger:        conf.ModuleLogger("node"),
+		core:          core,
+		trans:         trans,
+		netCh:         netCh,
+		proxy:         proxy,
+		submitCh:      proxy.SubmitCh(),
+		ttlSubmitCh:   make(chan ttlTx),
+		sigCh:         sigCh,
+		shutdownCh:    make(chan struct{}),
+		suspendCh:     make(chan struct{}),
+		fastForwardCh: make(chan struct{}, 1),
+		controlTimer:  newRandomControlTimer(clock),
+		clock:         clock,
+		start:         clock.Now(),
+		peerKnown:     make(map[uint32]int),
+		peerStats:     make(map[uint32]*PeerStats),
+		bannedAddrs:   make(map[string]struct{}),
+		latencies:     latencies,
+		dataBudget:    newDataBudget(trans, conf.MaxBytesPerHour, clock.Now()),
+		peerTraffic:   newPeerTraffic(conf.PeerQuota, conf.PeerQuotaAction, clock.Now()),
+		memoryBudget:  newMemoryBudget(conf.MaxMemory),
+		suspensions:   newSuspensions(),
+		addrBook:      newMemAddressBook(),
+		sentEvents:    newSentEvents(conf.SyncDedupWindow),
+	}
+
+	if conf.HistoryWorkers > 0 {
+		node.historyCh = make(chan net.RPC, conf.HistoryQueue)
+	}
+
+	if conf.JoinAdmissionLimit > 0 {
+		node.joinAdmission = newJoinAdmission(conf.JoinAdmissionLimit, conf.JoinAdmissionWindow, node.clock.Now())
+	}
+
+	if pt, ok := trans.(net.PeerTrafficCounter); ok {
+		pt.SetTrafficHook(node.recordTraffic)
 	}
 
 	return &node
@@ -127,6 +265,17 @@ Public Methods
 // on configuration (Babbling, CatchingUp, Joining, or Suspended).
 func (n *Node) Init() error {
 
+	// the thresholds of the genesis document must be set before any Event is
+	// inserted, including by the bootstrap.
+	thresholds, err := peers.ParseThresholds(n.conf.SuperMajority, n.conf.Trust)
+	if err != nil {
+		return err
+	}
+	n.core.hg.SetThresholds(thresholds)
+
+	n.learnPeers(n.core.genesisPeers)
+	n.learnPeers(n.core.peers)
+
 	// if the bootstrap option is set, load the hashgraph from an existing
 	// database (if bootstrap option is set in config).
 	if n.conf.Bootstrap {
@@ -137,6 +286,30 @@ func (n *Node) Init() error {
 		n.logger.Debug("Bootstrap completed")
 	}
 
+	// if the genesis document defines the hash of the initial state of the
+	// App, check it against the App before answering the peers.
+	if n.conf.GenesisAppHash != "" {
+		if err := n.initGenesis(); err != nil {
+			return err
+		}
+	}
+
+	// if the snapshot-delta option is set, check that the App can provide the
+	// differences between snapshots.
+	if n.conf.SnapshotDelta {
+		if err := n.initSnapshotDelta(); err != nil {
+			return err
+		}
+	}
+
+	// if the verify-snapshots option is set, check that the App can hash
+	// snapshots.
+	if n.conf.VerifySnapshots {
+		if err := n.initVerifySnapshots(); err != nil {
+			return err
+		}
+	}
+
 	// if the maintenance-mode option is not enabled, open the network transport
 	// and decide wether to babble normally, fast-forward, or join. Otherwise
 	// enter the suspended state.
@@ -153,6 +326,11 @@ func (n *Node) Init() error {
 			n.transition(_state.Joining)
 		}
 	} else {
+		n.suspensions.record(Suspension{
+			Reason:  SuspendMaintenance,
+			Message: "Started in maintenance-mode",
+			Since:   n.clock.Now(),
+		})
 		n.transition(_state.Suspended)
 	}
 
@@ -166,6 +344,8 @@ func (n *Node) Init() error {
 // Run invokes the main loop of the node. The gossip parameter controls whether
 // to actively participate in gossip or not.
 func (n *Node) Run(gossip bool) {
+	defer n.recoverPanic()
+
 	if n.conf.MaintenanceMode {
 		return
 	}
@@ -178,6 +358,64 @@ func (n *Node) Run(gossip bool) {
 	// Execute some background work regardless of the state of the node.
 	go n.doBackgroundWork()
 
+	// Process incoming RPCs with a fixed pool of workers, regardless of the
+	// state of the node.
+	for i := 0; i < n.conf.RPCWorkers; i++ {
+		go n.processRPCs()
+	}
+
+	// Serve the history to the nodes that are behind with a separate pool of
+	// workers, so that it does not hold up consensus.
+	for i := 0; i < n.conf.HistoryWorkers; i++ {
+		go n.processHistoryRPCs()
+	}
+
+	// Shed load when the memory usage approaches the budget.
+	go n.monitorMemory()
+
+	// Repair the gaps left by lost syncs, independently of the gossip loop.
+	if gossip && n.conf.AntiEntropyInterval > 0 {
+		go n.antiEntropy(n.conf.AntiEntropyInterval)
+	}
+
+	// Measure the latency of the peers, for the stats and the peer selector.
+	if gossip && n.conf.PingInterval > 0 {
+		go n.probeLatency(n.conf.PingInterval)
+	}
+
+	// Check the connections to the peers independently of the gossip.
+	if gossip && n.conf.KeepaliveInterval > 0 {
+		if kt, ok := n.trans.(net.KeepaliveTransport); ok {
+			go n.keepalive(kt, n.conf.KeepaliveInterval)
+		}
+	}
+
+	// Alert when the node falls behind the other validators.
+	if gossip && n.conf.AlertBlockLag > 0 {
+		go n.monitorLag(n.conf.AlertBlockLag)
+	}
+
+	// Check the stored Blocks against the consensus Events.
+	if n.conf.AuditInterval > 0 {
+		go n.audit(n.conf.AuditInterval, n.conf.AuditBlocks)
+	}
+
+	// Check that the peers agree on the genesis state of the App.
+	if gossip && n.conf.GenesisAppHash != "" {
+		go n.checkGenesis()
+	}
+
+	// Resubmit the transactions held while the node was cut off from the
+	// quorum.
+	if gossip && n.conf.OfflineMode {
+		go n.reconcileOffline()
+	}
+
+	// Report the stalls of consensus, and try to recover from them.
+	if gossip && n.conf.WatchdogTimeout > 0 {
+		go n.watchdog(n.conf.WatchdogTimeout)
+	}
+
 	// Execute Node State Machine
 	for {
 		// Run different routines depending on node state
@@ -187,11 +425,18 @@ func (n *Node) Run(gossip bool) {
 		case _state.Babbling:
 			n.babble(gossip)
 		case _state.CatchingUp:
+			if n.IsMetered() {
+				// FastForward downloads a snapshot, which waits until the
+				// connection is not metered anymore.
+				n.logger.Debug("Metered connection => deferring FastForward")
+				n.clock.Sleep(2000 * time.Millisecond)
+				continue
+			}
 			n.fastForward()
 		case _state.Joining:
 			n.join()
 		case _state.Suspended:
-			time.Sleep(2000 * time.Millisecond)
+			n.clock.Sleep(2000 * time.Millisecond)
 		case _state.Shutdown:
 			return
 		}
@@ -243,16 +488,30 @@ func (n *Node) Shutdown() {
 		}
 
 		n.core.hg.Store.Close()
+
+		n.core.notifier.close()
 	}
 }
 
 // Suspend puts the node in Suspended mode. It doesn't close the transport
-// because it needs to respond to incoming SyncRequests.
+// because it needs to respond to incoming SyncRequests. The reason of the
+// Suspension is SuspendManual.
 func (n *Node) Suspend() {
+	n.suspend(Suspension{
+		Reason:  SuspendManual,
+		Message: "Suspended on request",
+	})
+}
+
+// suspend puts the node in Suspended mode, and records the Suspension.
+func (n *Node) suspend(suspension Suspension) {
 	if n.GetState() != _state.Suspended &&
 		n.GetState() != _state.Shutdown {
 
-		n.logger.Info("SUSPEND")
+		n.logger.WithField("reason", suspension.Reason).Info("SUSPEND")
+
+		suspension.Since = n.clock.Now()
+		n.suspensions.record(suspension)
 
 		n.transition(_state.Suspended)
 
@@ -274,61 +533,36 @@ func (n *Node) GetPubKey() string {
 	return n.core.validator.PublicKeyHex()
 }
 
-// GetStats returns information about the node.
-func (n *Node) GetStats() map[string]string {
-	toString := func(i *int) string {
-		if i == nil {
-			return "nil"
-		}
-
-		return strconv.Itoa(*i)
-	}
-
-	timeElapsed := time.Since(n.start)
-
-	consensusEvents := n.core.getConsensusEventsCount()
-
-	consensusEventsPerSecond := float64(consensusEvents) / timeElapsed.Seconds()
-
-	lastConsensusRound := n.core.getLastConsensusRoundIndex()
-
-	var consensusRoundsPerSecond float64
-
-	if lastConsensusRound != nil {
-		consensusRoundsPerSecond = float64(*lastConsensusRound) / timeElapsed.Seconds()
-	}
-
-	s := map[string]string{
-		"last_consensus_round":   toString(lastConsensusRound),
-		"last_block_index":       strconv.Itoa(n.core.getLastBlockIndex()),
-		"consensus_events":       strconv.Itoa(consensusEvents),
-		"consensus_transactions": strconv.Itoa(n.core.getConsensusTransactionsCount()),
-		"undetermined_events":    strconv.Itoa(len(n.core.getUndeterminedEvents())),
-		"transaction_pool":       strconv.Itoa(len(n.core.transactionPool)),
-		"num_peers":              strconv.Itoa(n.core.peerSelector.getPeers().Len()),
-		"last_peer_change":       strconv.Itoa(n.core.lastPeerChangeRound),
-		"sync_rate":              strconv.FormatFloat(n.syncRate(), 'f', 2, 64),
-		"events_per_second":      strconv.FormatFloat(consensusEventsPerSecond, 'f', 2, 64),
-		"rounds_per_second":      strconv.FormatFloat(consensusRoundsPerSecond, 'f', 2, 64),
-		"round_events":           strconv.Itoa(n.core.getLastCommitedRoundEventsCount()),
-		"id":                     fmt.Sprint(n.core.validator.ID()),
-		"state":                  n.GetState().String(),
-		"moniker":                n.core.validator.Moniker,
-		"time":                   strconv.FormatInt(time.Now().UnixNano(), 10),
-	}
-	return s
-}
-
 // GetBlock returns a block by index.
 func (n *Node) GetBlock(blockIndex int) (*hg.Block, error) {
 	return n.core.hg.Store.GetBlock(blockIndex)
 }
 
+// GetEvent returns an event by hash.
+func (n *Node) GetEvent(hash string) (*hg.Event, error) {
+	return n.core.hg.Store.GetEvent(hash)
+}
+
+// GetTx returns the location of a committed transaction by hash.
+func (n *Node) GetTx(hash string) (hg.TxLocation, error) {
+	return n.core.hg.Store.GetTx(hash)
+}
+
 // GetLastBlockIndex returns the index of the last known block.
 func (n *Node) GetLastBlockIndex() int {
 	return n.core.getLastBlockIndex()
 }
 
+// GetRound returns a hashgraph round by index.
+func (n *Node) GetRound(roundIndex int) (*hg.RoundInfo, error) {
+	return n.core.hg.Store.GetRound(roundIndex)
+}
+
+// GetLastRound returns the index of the last known round.
+func (n *Node) GetLastRound() int {
+	return n.core.hg.Store.LastRound()
+}
+
 // GetLastConsensusRoundIndex returns the index of the last consensus round.
 func (n *Node) GetLastConsensusRoundIndex() int {
 	lcr := n.core.getLastConsensusRoundIndex()
@@ -359,24 +593,160 @@ func (n *Node) GetAllValidatorSets() (map[int][]*peers.Peer, error) {
 	return n.core.hg.Store.GetAllPeerSets()
 }
 
+// SubmitTx submits a transaction to the node as if it came from the App,
+// through the AppProxy's submit channel. It returns an error if the node is
+// shut down, or if its memory budget is almost exhausted.
+func (n *Node) SubmitTx(tx []byte) error {
+	if n.memoryBudget.underPressure() {
+		return fmt.Errorf("memory budget exceeded")
+	}
+
+	t := make([]byte, len(tx), len(tx))
+
+	copy(t, tx)
+
+	select {
+	case n.submitCh <- t:
+		return nil
+	case <-n.shutdownCh:
+		return fmt.Errorf("node is shut down")
+	}
+}
+
+// SubmitTxWithTTL submits a transaction like SubmitTx, with a time-to-live. If
+// the transaction is not included in an Event before the TTL expires, it is
+// evicted, and an ExpiredNotification is published instead. A zero TTL is the
+// same as SubmitTx.
+func (n *Node) SubmitTxWithTTL(tx []byte, ttl TxTTL) error {
+	if ttl.Rounds < 0 || ttl.Duration < 0 {
+		return fmt.Errorf("negative TTL")
+	}
+
+	if ttl.Rounds == 0 && ttl.Duration == 0 {
+		return n.SubmitTx(tx)
+	}
+
+	if n.memoryBudget.underPressure() {
+		return fmt.Errorf("memory budget exceeded")
+	}
+
+	t := make([]byte, len(tx), len(tx))
+
+	copy(t, tx)
+
+	select {
+	case n.ttlSubmitCh <- ttlTx{tx: t, ttl: ttl}:
+		return nil
+	case <-n.shutdownCh:
+		return fmt.Errorf("node is shut down")
+	}
+}
+
+// Subscribe returns a Subscription that receives notifications of the given
+// types, or of all types if none are specified. Notifications are buffered up to
+// the buffer size; a subscriber that does not keep up is unsubscribed.
+func (n *Node) Subscribe(buffer int, types ...NotificationType) *Subscription {
+	return n.core.notifier.subscribe(buffer, types...)
+}
+
+// GetEventLag returns the number of events that other nodes have reported
+// knowing about, but which are not yet in this node's hashgraph.
+func (n *Node) GetEventLag() int {
+	n.coreLock.RLock()
+	known := n.core.knownEvents()
+	n.coreLock.RUnlock()
+
+	n.peerKnownLock.Lock()
+	defer n.peerKnownLock.Unlock()
+
+	lag := 0
+	for id, index := range n.peerKnown {
+		if d := index - known[id]; d > 0 {
+			lag += d
+		}
+	}
+
+	return lag
+}
+
+// SyncProgress returns the percentage of the events reported by other nodes
+// which are in this node's hashgraph. It is 100 when the node is not behind,
+// and lower while it catches up with the other nodes.
+func (n *Node) SyncProgress() int {
+	n.coreLock.RLock()
+	known := n.core.knownEvents()
+	n.coreLock.RUnlock()
+
+	n.peerKnownLock.Lock()
+	defer n.peerKnownLock.Unlock()
+
+	// Known events are indexes, starting at 0, so they are offset by one to
+	// count events.
+	have, total := 0, 0
+	for id, index := range known {
+		have += index + 1
+		if other, ok := n.peerKnown[id]; ok && other > index {
+			total += other + 1
+		} else {
+			total += index + 1
+		}
+	}
+
+	if total == 0 {
+		return 100
+	}
+
+	return have * 100 / total
+}
+
+// GetRoundLag returns the number of rounds in the hashgraph which are not
+// decided yet.
+func (n *Node) GetRoundLag() int {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	lag := n.core.hg.Store.LastRound() - n.GetLastConsensusRoundIndex()
+	if lag < 0 {
+		return 0
+	}
+
+	return lag
+}
+
+// CheckProxy returns an error if the AppProxy reports that the application
+// cannot be reached. AppProxies that do not implement proxy.HealthChecker are
+// always considered connected.
+func (n *Node) CheckProxy() error {
+	if hc, ok := n.proxy.(proxy.HealthChecker); ok {
+		return hc.CheckHealth()
+	}
+	return nil
+}
+
+// CheckStore returns an error if the Store cannot be written to. Stores that do
+// not implement hashgraph.HealthChecker are always considered writable.
+func (n *Node) CheckStore() error {
+	if hc, ok := n.core.hg.Store.(hg.HealthChecker); ok {
+		return hc.CheckWritable()
+	}
+	return nil
+}
+
 /*******************************************************************************
 Background
 *******************************************************************************/
 
 // doBackgroundWork coninuously listens to incoming transactions, and the sigint
-// signal, regardless of the node's state. It also listens to incoming gossip.
+// signal, regardless of the node's state.
 func (n *Node) doBackgroundWork() {
+	defer n.recoverPanic()
+
 	for {
 		select {
-		case rpc := <-n.netCh:
-			n.GoFunc(func() {
-				n.processRPC(rpc)
-				n.resetTimer()
-			})
 		case t := <-n.submitCh:
-			n.logger.Debug("Adding Transaction")
-			n.addTransaction(t)
-			n.resetTimer()
+			n.submitTransaction(t, nil)
+		case t := <-n.ttlSubmitCh:
+			n.submitTransaction(t.tx, n.newTxDeadline(t.ttl))
 		case <-n.shutdownCh:
 			return
 		case s := <-n.sigCh:
@@ -387,32 +757,140 @@ func (n *Node) doBackgroundWork() {
 	}
 }
 
+// processRPCs processes incoming RPCs one at a time until the node shuts down.
+// Several of them run in parallel, so that inbound RPCs are neither held up by
+// the outbound gossip routines started by GoFunc, nor by the transactions
+// waiting for the core. The RPCs are still counted by
+// WaitRoutines, so that a state transition waits for the ones in progress. The
+// RPCs serving history are handed over to the history workers, if any.
+func (n *Node) processRPCs() {
+	for {
+		select {
+		case rpc := <-n.netCh:
+			if n.historyCh != nil && n.historyRPC(rpc) {
+				n.queueHistoryRPC(rpc)
+				continue
+			}
+
+			n.TrackFunc(func() {
+				defer n.recoverPanic()
+				n.processRPC(rpc)
+				n.resetTimer()
+			})
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
 // resetTimer resets the control timer to the configured hearbeat timeout, or
-// slows it down if the node is not busy.
+// slows it down if the node is not busy. A slow timer is reset too when the
+// node becomes busy, so that new transactions do not wait for it. The timer is
+// reset after releasing the coreLock, so that the control timer never waits for
+// it.
 func (n *Node) resetTimer() {
 	n.coreLock.Lock()
-	defer n.coreLock.Unlock()
 
-	if !n.controlTimer.isSet {
-		ts := n.conf.HeartbeatTimeout
+	ts := n.conf.HeartbeatTimeout
 
-		//Slow gossip if nothing interesting to say
-		if !n.core.busy() {
-			ts = time.Duration(n.conf.SlowHeartbeatTimeout)
-		}
+	//Slow gossip if nothing interesting to say
+	if !n.core.busy() {
+		ts = time.Duration(n.conf.SlowHeartbeatTimeout)
+	}
+
+	if n.conf.Metered {
+		ts *= meteredHeartbeatFactor
+	}
+
+	n.coreLock.Unlock()
 
-		n.controlTimer.resetCh <- ts
+	if n.controlTimer.needsReset(ts) {
+		n.controlTimer.reset(ts)
 	}
 }
 
+// SetHeartbeatTimeouts changes the heartbeat timeouts of the node, which take
+// effect the next time the control timer is reset.
+func (n *Node) SetHeartbeatTimeouts(heartbeat, slowHeartbeat time.Duration) {
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	n.conf.HeartbeatTimeout = heartbeat
+	n.conf.SlowHeartbeatTimeout = slowHeartbeat
+}
+
+// SetMetered turns the metered mode on or off. On a metered connection, the
+// node gossips less frequently, and defers FastForward until the connection is
+// not metered anymore.
+func (n *Node) SetMetered(metered bool) {
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	n.conf.Metered = metered
+}
+
+// IsMetered returns true if the node is in metered mode.
+func (n *Node) IsMetered() bool {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	return n.conf.Metered
+}
+
+// SetMaxBytesPerHour changes the data budget of the node. 0 means unlimited.
+func (n *Node) SetMaxBytesPerHour(maxBytesPerHour int64) {
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	n.conf.MaxBytesPerHour = maxBytesPerHour
+	n.dataBudget.setMax(maxBytesPerHour)
+}
+
+// SetMaxMemory changes the memory budget of the node. 0 means unlimited.
+func (n *Node) SetMaxMemory(maxMemory int64) {
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	n.conf.MaxMemory = maxMemory
+	n.memoryBudget.setMax(maxMemory)
+}
+
+// SetSyncDedupWindow changes the period during which events sent to a peer are
+// not sent to it again. 0 disables it.
+func (n *Node) SetSyncDedupWindow(window time.Duration) {
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	n.conf.SyncDedupWindow = window
+	n.sentEvents.setWindow(window)
+}
+
+// GetDataUsage returns the number of bytes sent and received by the node in
+// the current hour of its data budget.
+func (n *Node) GetDataUsage() int64 {
+	return n.dataBudget.used(n.clock.Now())
+}
+
+// SetCacheSize changes the size of the consensus caches of the hashgraph. They
+// are smaller while the memory budget is almost exhausted.
+func (n *Node) SetCacheSize(size int) {
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	n.conf.CacheSize = size
+	n.core.hg.SetCacheSize(n.consensusCacheSize())
+}
+
 // checkSuspend suspends the node if the number of undetermined events in the
 // hashgraph exceeds initialUndeterminedEvents by n*SuspendLimit (where n is the
 // the size of the current validator set), or if the validator has been evicted.
 func (n *Node) checkSuspend() {
+	n.coreLock.RLock()
 
 	// check too many undetermined events
 	newUndeterminedEvents := len(n.core.getUndeterminedEvents()) - n.initialUndeterminedEvents
-	tooManyUndeterminedEvents := newUndeterminedEvents > n.conf.SuspendLimit*n.core.validators.Len()
+	validators := n.core.validators.Len()
+	tooManyUndeterminedEvents := newUndeterminedEvents > n.conf.SuspendLimit*validators
 
 	// check evicted
 	evicted := n.core.hg.LastConsensusRound != nil &&
@@ -420,17 +898,46 @@ func (n *Node) checkSuspend() {
 		n.core.removedRound > n.core.acceptedRound &&
 		*n.core.hg.LastConsensusRound >= n.core.removedRound
 
+	removedRound, acceptedRound := n.core.removedRound, n.core.acceptedRound
+
+	if evicted {
+		n.core.alert(SuspendedAlert, "Suspended: evicted from the validator-set")
+	} else if tooManyUndeterminedEvents {
+		n.core.alert(SuspendedAlert, "Suspended: too many undetermined events (%d)", newUndeterminedEvents)
+	}
+
+	n.coreLock.RUnlock()
+
 	// suspend if too many undetermined events or evicted
 	if tooManyUndeterminedEvents || evicted {
 		n.logger.WithFields(logrus.Fields{
 			"evicted":                   evicted,
 			"tooManyUndeterminedEvents": tooManyUndeterminedEvents,
 			"id":                        n.GetID(),
-			"removedRound":              n.core.removedRound,
-			"acceptedRound":             n.core.acceptedRound,
+			"removedRound":              removedRound,
+			"acceptedRound":             acceptedRound,
 		}).Debugf("SUSPEND")
 
-		n.Suspend()
+		suspension := Suspension{
+			Reason:  SuspendEvicted,
+			Message: "Evicted from the validator-set",
+		}
+
+		if evicted {
+			n.core.notifier.publishError(fmt.Errorf("Suspended: evicted from the validator-set"))
+		} else {
+			suspension = Suspension{
+				Reason:             SuspendUndeterminedEvents,
+				Message:            "Too many undetermined events",
+				UndeterminedEvents: newUndeterminedEvents,
+				SuspendLimit:       n.conf.SuspendLimit * validators,
+			}
+
+			n.core.notifier.publishError(fmt.Errorf("Suspended: too many undetermined events"))
+			n.writeDiagnostics("Suspended: too many undetermined events", nil)
+		}
+
+		n.suspend(suspension)
 	}
 }
 
@@ -443,21 +950,43 @@ Babbling
 func (n *Node) babble(gossip bool) {
 	n.logger.Info("BABBLING")
 
+	if n.conf.AnnounceAddr {
+		go n.announceAddress()
+	}
+
 	for {
 		select {
 		case <-n.controlTimer.tickCh:
+			start := time.Now()
+			n.expireTransactions()
 			if gossip {
 				peer := n.core.peerSelector.next()
-				if peer != nil {
+				if peer == nil {
+					n.monologue()
+				} else if n.isBanned(peer.NetAddr) {
+					n.logger.WithField("peer", peer.NetAddr).Debug("Skipping banned peer")
+				} else if err := n.peerTraffic.allow(peer.ID(), n.clock.Now()); err != nil {
+					n.logger.WithField("peer", peer.NetAddr).Debug("Skipping peer over quota")
+				} else if exceeded, first := n.dataBudget.exceeded(n.clock.Now()); exceeded {
+					if first {
+						n.logger.Warn("Data budget exceeded => not gossiping until the next hour")
+						n.core.notifier.publishError(fmt.Errorf("Data budget exceeded"))
+					}
+				} else {
 					n.GoFunc(func() {
+						defer n.recoverPanic()
 						n.gossip(peer)
 					})
-				} else {
-					n.monologue()
 				}
 			}
 			n.resetTimer()
 			n.checkSuspend()
+			n.updateMetrics()
+			metrics.GossipLoopDuration.Observe(time.Since(start).Seconds())
+		case <-n.fastForwardCh:
+			n.logger.Info("Forcing FastForward")
+			n.transition(_state.CatchingUp)
+			return
 		case <-n.suspendCh:
 			return
 		case <-n.shutdownCh:
@@ -466,13 +995,25 @@ func (n *Node) babble(gossip bool) {
 	}
 }
 
+// monologueMaxEvents is the maximum number of events that a lone node creates
+// in a single heartbeat.
+const monologueMaxEvents = 10
+
 // monologue is called when the node is alone in the network but wants to record
-// some events anyway.
+// some events anyway. A lone node decides consensus by itself, so it creates
+// events until its transactions are committed, and the blocks signed, instead
+// of one event per heartbeat. It stops early when it only waits for a round
+// that depends on other validators, like the round from which a joining node
+// takes part in consensus.
 func (n *Node) monologue() error {
 	n.coreLock.Lock()
 	defer n.coreLock.Unlock()
 
-	if n.core.busy() {
+	for i := 0; i < monologueMaxEvents && n.core.busy(); i++ {
+		if i > 0 && !n.core.pending() {
+			break
+		}
+
 		err := n.core.addSelfEvent("")
 		if err != nil {
 			n.logger.WithError(err).Error("monologue, AddSelfEvent()")
@@ -490,10 +1031,25 @@ func (n *Node) monologue() error {
 }
 
 // gossip performs a pull-push gossip operation with the selected peer.
-func (n *Node) gossip(peer *peers.Peer) error {
+func (n *Node) gossip(peer *peers.Peer) (err error) {
+	ctx, span := tracing.Start(context.Background(), "node.gossip")
+	span.SetAttributes(
+		attribute.Int64("peer_id", int64(peer.ID())),
+		attribute.String("peer_moniker", peer.Moniker),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+
 	var connected bool
 
+	addr := n.peerAddr(peer)
+
 	defer func() {
+		metrics.GossipDuration.Observe(time.Since(start).Seconds())
+		n.recordGossip(peer.ID(), err)
+		n.recordContact(peer, addr, err)
+
 		// update peer selector
 		n.core.selectorLock.Lock()
 		newConnection := n.core.peerSelector.updateLast(peer.ID(), connected)
@@ -507,17 +1063,19 @@ func (n *Node) gossip(peer *peers.Peer) error {
 	}()
 
 	// pull
-	otherKnownEvents, err := n.pull(peer)
+	otherKnownEvents, pushed, err := n.pull(ctx, peer, addr)
 	if err != nil {
 		n.logger.WithError(err).Warn("gossip pull")
 		return err
 	}
 
-	// push
-	err = n.push(peer, otherKnownEvents)
-	if err != nil {
-		n.logger.WithError(err).Warn("gossip push")
-		return err
+	// push, unless the events were already included in the pull
+	if !pushed {
+		err = n.push(ctx, peer, addr, otherKnownEvents)
+		if err != nil {
+			n.logger.WithError(err).Warn("gossip push")
+			return err
+		}
 	}
 
 	n.logStats()
@@ -527,83 +1085,108 @@ func (n *Node) gossip(peer *peers.Peer) error {
 	return nil
 }
 
-// pull performs a SyncRequest and processes the response.
-func (n *Node) pull(peer *peers.Peer) (otherKnownEvents map[uint32]int, err error) {
+// pull performs a SyncRequest, at the given address of the peer, and processes
+// the response. With push-pull gossip, the request includes the events that the
+// peer did not know at the last sync, and pushed indicates that the peer
+// accepted them. When the peer splits its events in chunks, pull requests the
+// following chunks, up to maxSyncChunks in total.
+func (n *Node) pull(ctx context.Context, peer *peers.Peer, addr string) (otherKnownEvents map[uint32]int, pushed bool, err error) {
+	ctx, span := tracing.Start(ctx, "node.pull")
+	defer func() { tracing.End(span, err) }()
+
 	//Compute Known
-	n.coreLock.Lock()
+	n.coreLock.RLock()
 	knownEvents := n.core.knownEvents()
-	n.coreLock.Unlock()
+	n.coreLock.RUnlock()
+
+	var eventDiff []*hg.Event
+	var wireEvents []hg.WireEvent
+	if n.conf.PushPull {
+		if lastKnown := n.lastKnown(peer.ID()); lastKnown != nil {
+			eventDiff, wireEvents, err = n.eventsToPush(peer.ID(), lastKnown, true)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+	}
 
-	//Send SyncRequest
-	start := time.Now()
-	resp, err := n.requestSync(peer.NetAddr, knownEvents, n.conf.SyncLimit)
-	elapsed := time.Since(start)
-	n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("requestSync()")
+	for chunk := 1; ; chunk++ {
+		//Send SyncRequest
+		start := time.Now()
+		resp, err := n.requestSync(ctx, addr, knownEvents, n.conf.SyncLimit, wireEvents)
+		elapsed := time.Since(start)
+		n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("requestSync()")
 
-	if err != nil {
-		n.logger.WithField("error", err).Warn("requestSync()")
-		return nil, err
-	}
+		if err != nil {
+			n.logger.WithField("error", err).Warn("requestSync()")
+			return nil, false, err
+		}
 
-	n.logger.WithFields(logrus.Fields{
-		"from_id": resp.FromID,
-		"events":  len(resp.Events),
-		"known":   resp.Known,
-	}).Debug("SyncResponse")
+		n.recordSyncResponse(peer.ID(), elapsed, len(resp.Events), resp.Known)
 
-	//Add Events to Hashgraph and create new Head if necessary
-	n.coreLock.Lock()
-	err = n.sync(peer.ID(), resp.Events)
-	n.coreLock.Unlock()
+		if resp.PushAccepted {
+			pushed = true
+			n.sentEvents.record(peer.ID(), eventDiff, n.clock.Now())
+			n.recordEvents(peer.ID(), len(wireEvents), 0)
+		}
 
-	if err != nil {
-		n.logger.WithField("error", err).Error("sync()")
-		return nil, err
-	}
+		n.logger.WithFields(logrus.Fields{
+			"from_id":       resp.FromID,
+			"events":        len(resp.Events),
+			"known":         resp.Known,
+			"pushed":        len(wireEvents),
+			"push_accepted": resp.PushAccepted,
+			"more":          resp.More,
+		}).Debug("SyncResponse")
+
+		//Add Events to Hashgraph and create new Head if necessary
+		n.updatePeerKnown(resp.Known)
+
+		n.coreLock.Lock()
+		err = n.sync(ctx, peer.ID(), resp.Events)
+		if err == nil && resp.More && len(resp.Events) > 0 && chunk < maxSyncChunks {
+			knownEvents = n.core.knownEvents()
+		}
+		n.coreLock.Unlock()
+
+		if err != nil {
+			n.logger.WithField("error", err).Error("sync()")
+			return nil, false, err
+		}
+
+		if !resp.More || len(resp.Events) == 0 || chunk >= maxSyncChunks {
+			return resp.Known, pushed, nil
+		}
 
-	return resp.Known, nil
+		// The next request continues from the updated known map, and does not
+		// push the events again
+		eventDiff, wireEvents = nil, nil
+	}
 }
 
-// push preforms an EagerSyncRequest
-func (n *Node) push(peer *peers.Peer, knownEvents map[uint32]int) error {
-	// Compute Diff
-	start := time.Now()
-	n.coreLock.Lock()
-	eventDiff, err := n.core.eventDiff(knownEvents)
-	n.coreLock.Unlock()
-	elapsed := time.Since(start)
-	n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("Diff()")
+// push preforms an EagerSyncRequest at the given address of the peer.
+func (n *Node) push(ctx context.Context, peer *peers.Peer, addr string, knownEvents map[uint32]int) (err error) {
+	ctx, span := tracing.Start(ctx, "node.push")
+	defer func() { tracing.End(span, err) }()
+
+	eventDiff, wireEvents, err := n.eventsToPush(peer.ID(), knownEvents, true)
 	if err != nil {
-		n.logger.WithField("error", err).Error("Calculating Diff")
 		return err
 	}
 
-	if len(eventDiff) > 0 {
-		// do not push more than sync_limit events
-		if n.conf.SyncLimit < len(eventDiff) {
-			n.logger.WithFields(logrus.Fields{
-				"sync_limit":  n.conf.SyncLimit,
-				"diff_length": len(eventDiff),
-			}).Debug("Push sync_limit")
-			eventDiff = eventDiff[:n.conf.SyncLimit]
-		}
-
-		// Convert to WireEvents
-		wireEvents, err := n.core.toWire(eventDiff)
-		if err != nil {
-			n.logger.WithField("error", err).Debug("Converting to WireEvent")
-			return err
-		}
-
+	if len(wireEvents) > 0 {
 		// Create and Send EagerSyncRequest
-		start = time.Now()
-		resp2, err := n.requestEagerSync(peer.NetAddr, wireEvents)
-		elapsed = time.Since(start)
+		start := time.Now()
+		resp2, err := n.requestEagerSync(ctx, addr, wireEvents)
+		elapsed := time.Since(start)
 		n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("requestEagerSync()")
 		if err != nil {
 			n.logger.WithField("error", err).Warn("requestEagerSync()")
 			return err
 		}
+
+		n.sentEvents.record(peer.ID(), eventDiff, n.clock.Now())
+		n.recordEvents(peer.ID(), len(wireEvents), 0)
 		n.logger.WithFields(logrus.Fields{
 			"from_id": resp2.FromID,
 			"success": resp2.Success,
@@ -613,12 +1196,66 @@ func (n *Node) push(peer *peers.Peer, knownEvents map[uint32]int) error {
 	return nil
 }
 
+// eventsToPush computes the events that a peer does not know, according to its
+// known map, limited to sync_limit events. With dedup, the events recently sent
+// to the peer are left out. It returns the events along with their wire format.
+func (n *Node) eventsToPush(peerID uint32, knownEvents map[uint32]int, dedup bool) ([]*hg.Event, []hg.WireEvent, error) {
+	// Compute Diff
+	start := time.Now()
+	n.coreLock.RLock()
+	eventDiff, err := n.core.eventDiff(knownEvents)
+	n.coreLock.RUnlock()
+	elapsed := time.Since(start)
+	n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("Diff()")
+	if err != nil {
+		n.logger.WithField("error", err).Error("Calculating Diff")
+		return nil, nil, err
+	}
+
+	if dedup {
+		eventDiff = n.skipSentEvents(peerID, eventDiff)
+	}
+
+	if len(eventDiff) == 0 {
+		return nil, nil, nil
+	}
+
+	// do not push more than sync_limit events
+	if n.conf.SyncLimit < len(eventDiff) {
+		n.logger.WithFields(logrus.Fields{
+			"sync_limit":  n.conf.SyncLimit,
+			"diff_length": len(eventDiff),
+		}).Debug("Push sync_limit")
+		eventDiff = limitEvents(eventDiff, n.conf.SyncLimit)
+	}
+
+	// Convert to WireEvents
+	wireEvents, err := n.core.toWire(eventDiff)
+	if err != nil {
+		n.logger.WithField("error", err).Debug("Converting to WireEvent")
+		return nil, nil, err
+	}
+
+	return eventDiff, wireEvents, nil
+}
+
 // sync attempts to insert a list of events into the hashgraph, record a new
-// sync event, and process the signature pool.
-func (n *Node) sync(fromID uint32, events []hg.WireEvent) error {
+// sync event, and process the signature pool. The caller must hold the
+// coreLock.
+func (n *Node) sync(ctx context.Context, fromID uint32, events []hg.WireEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "node.sync")
+	span.SetAttributes(
+		attribute.Int64("from_id", int64(fromID)),
+		attribute.Int("events", len(events)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	n.core.traceCtx = ctx
+	defer func() { n.core.traceCtx = context.Background() }()
+
 	//Insert Events in Hashgraph and create new Head if necessary
 	start := time.Now()
-	err := n.core.sync(fromID, events)
+	err = n.core.sync(fromID, events)
 	elapsed := time.Since(start)
 	n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("Sync()")
 	if err != nil {
@@ -654,6 +1291,12 @@ func (n *Node) fastForward() error {
 
 	var err error
 
+	// A node with a trusted block only syncs from it, and never from the
+	// history before it, so it waits until a peer can provide it.
+	if n.trustedBlock != nil {
+		return n.fastForwardToTrustedBlock()
+	}
+
 	// loop through all peers to check who is the most ahead, then fast-forward
 	// from them. If no-one is ready to fast-forward, transition to the Babbling
 	// state.
@@ -661,30 +1304,17 @@ func (n *Node) fastForward() error {
 	if resp == nil {
 		n.logger.Error("getBestFastForwardResponse returned nil => Babbling")
 		n.transition(_state.Babbling)
-		return fmt.Errorf("getBestFastForwardResponse returned nil")
-	}
-
-	//update app from snapshot
-	err = n.proxy.Restore(resp.Snapshot)
-	if err != nil {
-		n.logger.WithError(err).Error("Restoring App from Snapshot")
+		err = fmt.Errorf("getBestFastForwardResponse returned nil")
+		n.core.notifier.publishError(fmt.Errorf("FastForward: %v", err))
 		return err
 	}
 
-	//prepare core. ie: fresh hashgraph
-	n.coreLock.Lock()
-	err = n.core.fastForward(&resp.Block, &resp.Frame)
-	n.coreLock.Unlock()
+	err = n.restoreFastForward(resp)
 	if err != nil {
-		n.logger.WithError(err).Error("Fast Forwarding Hashgraph")
+		n.core.notifier.publishError(err)
 		return err
 	}
 
-	err = n.core.processAcceptedInternalTransactions(resp.Block.RoundReceived(), resp.Block.InternalTransactionReceipts())
-	if err != nil {
-		n.logger.WithError(err).Error("Processing AnchorBlock InternalTransactionReceipts")
-	}
-
 	n.logger.Debug("FastForward OK")
 
 	n.transition(_state.Babbling)
@@ -700,8 +1330,10 @@ func (n *Node) getBestFastForwardResponse() *net.FastForwardResponse {
 
 	for _, p := range n.core.peerSelector.getPeers().Peers {
 		start := time.Now()
-		resp, err := n.requestFastForward(p.NetAddr)
+		addr := n.peerAddr(p)
+		resp, err := n.requestFastForward(addr, nil)
 		elapsed := time.Since(start)
+		n.recordContact(p, addr, err)
 		n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("requestFastForward()")
 		if err != nil {
 			n.logger.WithField("error", err).Error("requestFastForward()")
@@ -718,6 +1350,12 @@ func (n *Node) getBestFastForwardResponse() *net.FastForwardResponse {
 			"snapshot":             resp.Snapshot,
 		}).Debug("FastForwardResponse")
 
+		// A peer may serve a version of a Block signed by a validator which
+		// also signed the local version
+		n.coreLock.Lock()
+		n.core.checkDoubleSign(&resp.Block)
+		n.coreLock.Unlock()
+
 		if resp.Block.Index() > maxBlock {
 			bestResponse = &resp
 			maxBlock = resp.Block.Index()
@@ -743,23 +1381,43 @@ func (n *Node) join() error {
 	peer := n.core.peerSelector.next()
 
 	start := time.Now()
-	resp, err := n.requestJoin(peer.NetAddr)
+	addr := n.peerAddr(peer)
+	resp, err := n.requestJoin(addr)
 	elapsed := time.Since(start)
 	n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("requestJoin()")
+	n.recordContact(peer, addr, err)
 
 	if err != nil {
-		n.logger.Error("Cannot join:", peer.NetAddr, err)
+		n.logger.Error("Cannot join:", addr, err)
+		n.core.notifier.publishError(fmt.Errorf("Cannot join %s: %v", addr, err))
 		return err
 	}
 
+	n.learnPeers(peers.NewPeerSet(resp.Peers))
+
 	n.logger.WithFields(logrus.Fields{
 		"from_id":        resp.FromID,
 		"accepted":       resp.Accepted,
 		"accepted_round": resp.AcceptedRound,
 		"peers":          len(resp.Peers),
+		"retry_after":    resp.RetryAfter,
 	}).Debug("JoinResponse")
 
-	if resp.Accepted {
+	if resp.RetryAfter > 0 {
+		// Too many nodes are joining. The peer queued the JoinRequest for a
+		// later window, and admits it when it is sent again then.
+		retryAfter := resp.RetryAfter
+		if retryAfter > maxJoinRetryAfter {
+			retryAfter = maxJoinRetryAfter
+		}
+
+		n.logger.WithField("retry_after", retryAfter).Info("JoinRequest deferred")
+
+		select {
+		case <-n.clock.After(retryAfter):
+		case <-n.shutdownCh:
+		}
+	} else if resp.Accepted {
 		// Set AcceptedRound, which is the next round at which the node is
 		// allowed to create SelfEvents, and reset RemovedRound to "unsuspend" a
 		// node if had been evicted prior to rejoining.
@@ -768,8 +1426,9 @@ func (n *Node) join() error {
 
 		n.setBabblingOrCatchingUpState()
 	} else {
-		// Then JoinRequest was explicitly refused by the curren peer-set. This
-		// is not an error.
+		// Then JoinRequest was explicitly refused by the curren peer-set, by
+		// the application or because the moniker is already used. This is not
+		// an error.
 		n.logger.Info("JoinRequest rejected")
 		n.Shutdown()
 	}
@@ -786,6 +1445,11 @@ Utils
 func (n *Node) transition(state _state.State) {
 	n.SetState(state)
 
+	n.core.notifier.publish(Notification{
+		Type:  StateNotification,
+		State: state.String(),
+	})
+
 	if err := n.proxy.OnStateChanged(state); err != nil {
 		n.logger.Error(err)
 	}
@@ -794,7 +1458,10 @@ func (n *Node) transition(state _state.State) {
 // setBabblingOrCatchingUpState sets the node's state to CatchingUp if fast-sync
 // is enabled, or to Babbling if fast-sync is not enabled.
 func (n *Node) setBabblingOrCatchingUpState() {
-	if n.conf.EnableFastSync {
+	if n.trustedBlock != nil {
+		n.logger.Debug("Trusted block => CatchingUp")
+		n.transition(_state.CatchingUp)
+	} else if n.conf.EnableFastSync {
 		n.logger.Debug("FastSync enabled => CatchingUp")
 		n.transition(_state.CatchingUp)
 	} else {
@@ -806,44 +1473,83 @@ func (n *Node) setBabblingOrCatchingUpState() {
 	}
 }
 
+// submitTransaction adds an incoming transaction, with its deadline if it has
+// a TTL, to the transaction-pool, or to the offline log if the node is
+// partitioned in offline-mode.
+func (n *Node) submitTransaction(tx []byte, deadline *txDeadline) {
+	if n.memoryBudget.underPressure() {
+		n.logger.Warn("Memory budget exceeded => dropping Transaction")
+		return
+	}
+	if n.submitOffline(tx, deadline) {
+		return
+	}
+	n.logger.Debug("Adding Transaction")
+	n.addTransaction(tx, deadline)
+	n.resetTimer()
+}
+
 // addTransaction is a thread-safe function to add and incoming transaction to
 // the core's transaction-pool.
-func (n *Node) addTransaction(tx []byte) {
+func (n *Node) addTransaction(tx []byte, deadline *txDeadline) {
 	n.coreLock.Lock()
 	defer n.coreLock.Unlock()
 
-	n.core.addTransactions([][]byte{tx})
+	n.core.addExpiringTransactions([][]byte{tx}, []*txDeadline{deadline})
 }
 
-// logStats logs the output returned by GetStats()
+// logStats logs the output returned by Stats()
 func (n *Node) logStats() {
-	stats := n.GetStats()
+	stats := n.Stats()
+
+	lastConsensusRound := -1
+	if stats.LastConsensusRound != nil {
+		lastConsensusRound = *stats.LastConsensusRound
+	}
 
 	n.logger.WithFields(logrus.Fields{
-		"last_consensus_round":   stats["last_consensus_round"],
-		"last_block_index":       stats["last_block_index"],
-		"consensus_events":       stats["consensus_events"],
-		"consensus_transactions": stats["consensus_transactions"],
-		"undetermined_events":    stats["undetermined_events"],
-		"transaction_pool":       stats["transaction_pool"],
-		"num_peers":              stats["num_peers"],
-		"sync_rate":              stats["sync_rate"],
-		"events/s":               stats["events_per_second"],
-		"rounds/s":               stats["rounds_per_second"],
-		"round_events":           stats["round_events"],
-		"id":                     stats["id"],
-		"state":                  stats["state"],
-		"moniker":                stats["moniker"],
+		"last_consensus_round":   lastConsensusRound,
+		"last_block_index":       stats.LastBlockIndex,
+		"consensus_events":       stats.ConsensusEvents,
+		"consensus_transactions": stats.ConsensusTransactions,
+		"undetermined_events":    stats.UndeterminedEvents,
+		"transaction_pool":       stats.TransactionPool,
+		"num_peers":              stats.NumPeers,
+		"sync_rate":              stats.SyncRate,
+		"events/s":               stats.EventsPerSecond,
+		"rounds/s":               stats.RoundsPerSecond,
+		"round_events":           stats.RoundEvents,
+		"id":                     stats.ID,
+		"state":                  stats.State,
+		"moniker":                stats.Moniker,
 	}).Debug("Stats")
 }
 
-// syncRate computes the ratio of sync-errors over sync-requests
-func (n *Node) syncRate() float64 {
-	var syncErrorRate float64
+// updateMetrics updates the Prometheus gauges that reflect the state of the
+// node.
+func (n *Node) updateMetrics() {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	metrics.TransactionPool.Set(float64(len(n.core.transactionPool)))
+	metrics.OfflineTransactions.Set(float64(n.offlineLog.len()))
+	metrics.InternalTransactionPool.Set(float64(len(n.core.internalTransactionPool)))
+	metrics.UndeterminedEvents.Set(float64(len(n.core.getUndeterminedEvents())))
+	metrics.LastConsensusRound.Set(float64(n.GetLastConsensusRoundIndex()))
+	metrics.StoreConsensusEvents.Set(float64(n.core.getConsensusEventsCount()))
+	metrics.StoreBlocks.Set(float64(n.core.getLastBlockIndex() + 1))
+}
 
-	if n.syncRequests != 0 {
-		syncErrorRate = float64(n.syncErrors) / float64(n.syncRequests)
-	}
+// updatePeerKnown merges the known events reported by another node into
+// peerKnown.
+func (n *Node) updatePeerKnown(known map[uint32]int) {
+	n.peerKnownLock.Lock()
+	defer n.peerKnownLock.Unlock()
 
-	return 1 - syncErrorRate
+	for id, index := range known {
+		if cur, ok := n.peerKnown[id]; !ok || index > cur {
+			n.peerKnown[id] = index
+		}
+	}
 }
+
@@ -1,6 +1,7 @@
 package node
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"reflect"
@@ -34,6 +35,98 @@ func TestMonologue(t *testing.T) {
 	checkGossip(nodes, 0, t)
 }
 
+func TestMonologueFinality(t *testing.T) {
+	keys, peers := initPeers(t, 1)
+
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	node := nodes[0]
+	node.core.addTransactions([][]byte{[]byte("tx")})
+
+	// A single heartbeat commits and signs the transaction
+	if err := node.monologue(); err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := node.GetBlock(0)
+	if err != nil {
+		t.Fatalf("Block 0 should be committed: %v", err)
+	}
+	if len(block.GetSignatures()) != 1 {
+		t.Fatalf("Block 0 should be signed, not have %d signatures", len(block.GetSignatures()))
+	}
+	if node.core.busy() {
+		t.Fatal("The node should not be busy anymore")
+	}
+
+	// Without anything to commit, the node does not create events
+	seq := node.core.seq
+	if err := node.monologue(); err != nil {
+		t.Fatal(err)
+	}
+	if node.core.seq != seq {
+		t.Fatalf("The node should not create events when it is not busy")
+	}
+}
+
+func TestGrowFromSingleNode(t *testing.T) {
+	keys, peerSet := initPeers(t, 1)
+	genesisPeerSet := clonePeerSet(t, peerSet.Peers)
+
+	node0 := newNode(peerSet.Peers[0], keys[0], peerSet, genesisPeerSet, 10000, 400, 5, false, "inmem", 10*time.Millisecond, false, "", t)
+	defer node0.Shutdown()
+	node0.RunAsync(true)
+
+	if err := bombardAndWait([]*Node{node0}, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := bkeys.GenerateECDSAKey()
+	peer := peers.NewPeer(
+		bkeys.PublicKeyHex(&key.PublicKey),
+		"127.0.0.1:4250",
+		"monika",
+	)
+
+	newNode := newNode(peer, key, peers.NewPeerSet(node0.GetPeers()), genesisPeerSet, 10000, 400, 5, false, "inmem", 10*time.Millisecond, false, "", t)
+	defer newNode.Shutdown()
+	newNode.RunAsync(true)
+
+	nodes := []*Node{node0, newNode}
+
+	if err := bombardAndWait(nodes, 15); err != nil {
+		t.Fatal(err)
+	}
+
+	checkPeerSets(nodes, t)
+	verifyNewPeerSet(nodes, newNode.core.acceptedRound, 2, t)
+
+	// Both nodes produce the same blocks after the join
+	start := newNode.core.getLastBlockIndex()
+	if err := bombardAndWait(nodes, start+5); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := start; i < start+5; i++ {
+		b0, err := node0.GetBlock(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b1, err := newNode.GetBlock(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h0, _ := b0.Hash()
+		h1, _ := b1.Hash()
+		if !bytes.Equal(h0, h1) {
+			t.Fatalf("Block %d differs between the nodes", i)
+		}
+	}
+}
+
 func TestJoinRequest(t *testing.T) {
 
 	keys, peerSet := initPeers(t, 4)
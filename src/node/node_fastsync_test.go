@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mosaicnetworks/babble/src/dummy"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
 	_state "github.com/mosaicnetworks/babble/src/node/state"
 )
 
@@ -173,3 +175,155 @@ func TestFastSync(t *testing.T) {
 	start := node0.core.hg.FirstConsensusRound
 	checkGossip(nodes, *start, t)
 }
+
+func TestFastForwardSnapshotDelta(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	for _, n := range nodes {
+		n.conf.SnapshotDelta = true
+	}
+
+	// The first fast-forward restores the full snapshot, because node0 has no
+	// Block yet.
+	err := gossip(nodes[1:], 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base := nodes[0].snapshotBase(); base != nil {
+		t.Fatalf("node0 should not have a snapshot base, not %d", *base)
+	}
+
+	if err := nodes[0].fastForward(); err != nil {
+		t.Fatalf("Fatal Error FastForwarding: %s", err)
+	}
+
+	app := nodes[0].proxy.(*dummy.InmemDummyClient)
+	if txs := app.GetCommittedTransactions(); len(txs) != 0 {
+		t.Fatalf("The full snapshot should not carry transactions, not %d", len(txs))
+	}
+
+	// The second fast-forward only transfers the transactions committed since
+	// the Block of the first.
+	base := nodes[0].core.getLastBlockIndex()
+
+	err = bombardAndWait(nodes[1:], base+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nodes[0].fastForward(); err != nil {
+		t.Fatalf("Fatal Error FastForwarding: %s", err)
+	}
+
+	lbi := nodes[0].core.getLastBlockIndex()
+	if lbi <= base {
+		t.Fatalf("node0 should have fast-forwarded beyond Block %d, not to %d", base, lbi)
+	}
+
+	var expectedTxs [][]byte
+	for i := base + 1; i <= lbi; i++ {
+		block, err := nodes[1].GetBlock(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedTxs = append(expectedTxs, block.Transactions()...)
+	}
+
+	txs := app.GetCommittedTransactions()
+	if len(expectedTxs) == 0 || !reflect.DeepEqual(txs, expectedTxs) {
+		t.Fatalf("node0 should have applied the %d transactions of Blocks %d to %d, not %d", len(expectedTxs), base+1, lbi, len(txs))
+	}
+
+	if nodes[0].noSnapshotDelta {
+		t.Fatalf("node0 should have restored the Snapshot delta")
+	}
+}
+
+func TestFastForwardVerifySnapshots(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	nodes[0].conf.VerifySnapshots = true
+
+	err := gossip(nodes[1:], 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := nodes[0].getBestFastForwardResponse()
+	if resp == nil {
+		t.Fatal("No FastForwardResponse")
+	}
+
+	// A snapshot that does not match the state hash of the Block is not
+	// restored
+	poisoned := *resp
+	poisoned.Snapshot = []byte("poisoned state")
+
+	if err := nodes[0].restoreFastForward(&poisoned); err == nil {
+		t.Fatal("A snapshot which does not match the Block should be rejected")
+	}
+
+	if lbi := nodes[0].core.getLastBlockIndex(); lbi != -1 {
+		t.Fatalf("node0 should not have fast-forwarded, but it is at Block %d", lbi)
+	}
+
+	// Nor is the snapshot of a Block that the validators did not sign
+	forged := *resp
+	forged.Block.Signatures = nil
+	if err := nodes[0].restoreFastForward(&forged); err == nil {
+		t.Fatal("The snapshot of an unsigned Block should be rejected")
+	}
+
+	// Nor is the snapshot of a Block signed by a validator-set that the peer
+	// made up, and put in the Frame
+	fakeKeys, fakePeers := initPeers(t, 4)
+
+	fake := *resp
+	fake.Snapshot = []byte("poisoned state")
+	fake.Frame.Peers = fakePeers.Peers
+
+	frameHash, err := fake.Frame.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fakeBlock := hg.NewBlock(resp.Block.Index(),
+		resp.Block.RoundReceived(),
+		frameHash,
+		fakePeers.Peers,
+		resp.Block.Transactions(),
+		resp.Block.InternalTransactions())
+	fakeBlock.Body.StateHash = fake.Snapshot
+
+	for _, k := range fakeKeys {
+		sig, err := fakeBlock.Sign(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fakeBlock.SetSignature(sig)
+	}
+	fake.Block = *fakeBlock
+
+	if err := nodes[0].restoreFastForward(&fake); err == nil {
+		t.Fatal("The snapshot of a Block signed by unknown validators should be rejected")
+	}
+
+	if err := nodes[0].restoreFastForward(resp); err != nil {
+		t.Fatalf("Fatal Error FastForwarding: %s", err)
+	}
+
+	if lbi := nodes[0].core.getLastBlockIndex(); lbi != resp.Block.Index() {
+		t.Fatalf("node0 should have fast-forwarded to Block %d, not %d", resp.Block.Index(), lbi)
+	}
+}
@@ -1,79 +1,177 @@
 package node
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/mosaicnetworks/babble/src/hashgraph"
 	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/metrics"
 	"github.com/mosaicnetworks/babble/src/net"
 	_state "github.com/mosaicnetworks/babble/src/node/state"
 	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/tracing"
+	"github.com/mosaicnetworks/babble/src/version"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func (n *Node) requestSync(target string, known map[uint32]int, syncLimit int) (net.SyncResponse, error) {
+func (n *Node) requestSync(ctx context.Context, target string, known map[uint32]int, syncLimit int, events []hg.WireEvent) (net.SyncResponse, error) {
 	args := net.SyncRequest{
 		FromID:    n.core.validator.ID(),
+		NetworkID: n.conf.NetworkID,
+		Protocol:  version.LocalProtocol(),
 		SyncLimit: syncLimit,
+		ChunkSize: n.conf.SyncChunkSize,
 		Known:     known,
+		Events:    events,
 	}
 
 	var out net.SyncResponse
 
+	_, span := tracing.Start(ctx, "node.requestSync")
+	start := time.Now()
 	err := n.trans.Sync(target, &args, &out)
+	if err == nil {
+		err = n.checkProtocol(out.FromID, out.Protocol)
+	}
+	observeRPC(metrics.RPCSync, start, err)
+	tracing.End(span, err)
 
 	return out, err
 }
 
-func (n *Node) requestEagerSync(target string, events []hg.WireEvent) (net.EagerSyncResponse, error) {
+func (n *Node) requestEagerSync(ctx context.Context, target string, events []hg.WireEvent) (net.EagerSyncResponse, error) {
 	args := net.EagerSyncRequest{
-		FromID: n.core.validator.ID(),
-		Events: events,
+		FromID:    n.core.validator.ID(),
+		NetworkID: n.conf.NetworkID,
+		Protocol:  version.LocalProtocol(),
+		Events:    events,
 	}
 
 	var out net.EagerSyncResponse
 
+	_, span := tracing.Start(ctx, "node.requestEagerSync")
+	start := time.Now()
 	err := n.trans.EagerSync(target, &args, &out)
+	if err == nil {
+		err = n.checkProtocol(out.FromID, out.Protocol)
+	}
+	observeRPC(metrics.RPCEagerSync, start, err)
+	tracing.End(span, err)
 
 	return out, err
 }
 
-func (n *Node) requestFastForward(target string) (net.FastForwardResponse, error) {
+func (n *Node) requestFastForward(target string, blockIndex *int) (net.FastForwardResponse, error) {
 	n.logger.WithFields(logrus.Fields{
 		"target": target,
 	}).Debug("RequestFastForward()")
 
 	args := net.FastForwardRequest{
-		FromID: n.core.validator.ID(),
+		FromID:       n.core.validator.ID(),
+		NetworkID:    n.conf.NetworkID,
+		Protocol:     version.LocalProtocol(),
+		BlockIndex:   blockIndex,
+		SnapshotBase: n.snapshotBase(),
 	}
 
 	var out net.FastForwardResponse
 
+	start := time.Now()
 	err := n.trans.FastForward(target, &args, &out)
+	if err == nil {
+		err = n.checkProtocol(out.FromID, out.Protocol)
+	}
+	observeRPC(metrics.RPCFastForward, start, err)
 
 	return out, err
 }
 
 func (n *Node) requestJoin(target string) (net.JoinResponse, error) {
 
-	joinTx := hashgraph.NewInternalTransactionJoin(*peers.NewPeer(
+	peer := peers.NewPeer(
 		n.core.validator.PublicKeyHex(),
 		n.trans.AdvertiseAddr(),
-		n.core.validator.Moniker))
+		n.core.validator.Moniker)
+
+	// A shadow validator announces itself as such in its join request
+	peer.Shadow = n.conf.Shadow
+
+	joinTx := hashgraph.NewInternalTransactionJoin(*peer)
 
 	joinTx.Sign(n.core.validator.Key)
 
-	args := net.JoinRequest{InternalTransaction: joinTx}
+	args := net.JoinRequest{
+		NetworkID:           n.conf.NetworkID,
+		Protocol:            version.LocalProtocol(),
+		InternalTransaction: joinTx,
+	}
 
 	var out net.JoinResponse
 
+	start := time.Now()
 	err := n.trans.Join(target, &args, &out)
+	if err == nil {
+		err = n.checkProtocol(out.FromID, out.Protocol)
+	}
+	observeRPC(metrics.RPCJoin, start, err)
+
+	return out, err
+}
+
+func (n *Node) requestPing(target string) (net.PingResponse, error) {
+	args := net.PingRequest{
+		FromID:    n.core.validator.ID(),
+		NetworkID: n.conf.NetworkID,
+		Protocol:  version.LocalProtocol(),
+	}
+
+	var out net.PingResponse
+
+	start := time.Now()
+	err := n.trans.Ping(target, &args, &out)
+	if err == nil {
+		err = n.checkProtocol(out.FromID, out.Protocol)
+	}
+	observeRPC(metrics.RPCPing, start, err)
+
+	return out, err
+}
+
+func (n *Node) requestInfo(target string) (net.InfoResponse, error) {
+	args := net.InfoRequest{
+		FromID:    n.core.validator.ID(),
+		NetworkID: n.conf.NetworkID,
+		Protocol:  version.LocalProtocol(),
+	}
+
+	var out net.InfoResponse
+
+	start := time.Now()
+	err := n.trans.Info(target, &args, &out)
+	if err == nil {
+		err = n.checkProtocol(out.FromID, out.Protocol)
+	}
+	observeRPC(metrics.RPCInfo, start, err)
 
 	return out, err
 }
 
+// observeRPC records the duration and outcome of an outgoing RPC.
+func observeRPC(rpc string, start time.Time, err error) {
+	metrics.SyncLatency.WithLabelValues(rpc).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.RPCFailures.WithLabelValues(rpc).Inc()
+	}
+}
+
 func (n *Node) processRPC(rpc net.RPC) {
+	ctx, span := tracing.Start(context.Background(), "node.processRPC")
+	span.SetAttributes(attribute.String("command", fmt.Sprintf("%T", rpc.Command)))
+	defer span.End()
 
 	// Notify others that we are not in Babbling state to prevent
 	// them from hitting timeouts. We also allow SyncRequests while Suspended
@@ -88,39 +186,155 @@ func (n *Node) processRPC(rpc net.RPC) {
 		return
 	}
 
+	if addr := n.rpcSender(rpc); addr != "" && n.isBanned(addr) {
+		n.logger.WithField("peer", addr).Debug("Refusing RPC from banned peer")
+		rpc.Respond(nil, fmt.Errorf("Banned"))
+		return
+	}
+
+	if id, ok := net.SenderID(rpc.Command); ok {
+		if err := n.peerTraffic.allow(id, n.clock.Now()); err != nil {
+			n.logger.WithField("peer", id).Debug("Refusing RPC from peer over quota")
+			rpc.Respond(nil, err)
+			return
+		}
+	}
+
+	if networkID := rpcNetworkID(rpc); networkID != n.conf.NetworkID {
+		n.logger.WithFields(logrus.Fields{
+			"peer":       n.rpcSender(rpc),
+			"network_id": networkID,
+		}).Debug("Refusing RPC from another network")
+		rpc.Respond(nil, fmt.Errorf("Wrong network ID %q, expected %q", networkID, n.conf.NetworkID))
+		return
+	}
+
+	id, protocol := rpcProtocol(rpc)
+	if err := n.checkProtocol(id, protocol); err != nil {
+		n.logger.WithFields(logrus.Fields{
+			"peer":     n.rpcSender(rpc),
+			"protocol": protocol.Version,
+			"babble":   protocol.Babble,
+		}).Debug("Refusing RPC from incompatible peer")
+		rpc.Respond(nil, err)
+		return
+	}
+
 	switch cmd := rpc.Command.(type) {
 	case *net.SyncRequest:
-		n.processSyncRequest(rpc, cmd)
+		n.processSyncRequest(ctx, rpc, cmd)
 	case *net.EagerSyncRequest:
-		n.processEagerSyncRequest(rpc, cmd)
+		n.processEagerSyncRequest(ctx, rpc, cmd)
 	case *net.FastForwardRequest:
 		n.processFastForwardRequest(rpc, cmd)
 	case *net.JoinRequest:
 		n.processJoinRequest(rpc, cmd)
+	case *net.PingRequest:
+		n.processPingRequest(rpc, cmd)
+	case *net.InfoRequest:
+		n.processInfoRequest(rpc, cmd)
 	default:
 		n.logger.WithField("cmd", rpc.Command).Error("Unexpected RPC command")
 		rpc.Respond(nil, fmt.Errorf("unexpected command"))
 	}
 }
 
-func (n *Node) processSyncRequest(rpc net.RPC, cmd *net.SyncRequest) {
+// rpcNetworkID returns the network ID of the sender of an RPC.
+func rpcNetworkID(rpc net.RPC) string {
+	switch cmd := rpc.Command.(type) {
+	case *net.SyncRequest:
+		return cmd.NetworkID
+	case *net.EagerSyncRequest:
+		return cmd.NetworkID
+	case *net.FastForwardRequest:
+		return cmd.NetworkID
+	case *net.PingRequest:
+		return cmd.NetworkID
+	case *net.InfoRequest:
+		return cmd.NetworkID
+	case *net.JoinRequest:
+		return cmd.NetworkID
+	default:
+		return ""
+	}
+}
+
+// rpcProtocol returns the ID and the Protocol of the sender of an RPC.
+func rpcProtocol(rpc net.RPC) (uint32, version.Protocol) {
+	switch cmd := rpc.Command.(type) {
+	case *net.SyncRequest:
+		return cmd.FromID, cmd.Protocol
+	case *net.EagerSyncRequest:
+		return cmd.FromID, cmd.Protocol
+	case *net.FastForwardRequest:
+		return cmd.FromID, cmd.Protocol
+	case *net.PingRequest:
+		return cmd.FromID, cmd.Protocol
+	case *net.InfoRequest:
+		return cmd.FromID, cmd.Protocol
+	case *net.JoinRequest:
+		// The ID is computed from a copy, because Peer.ID caches it in the
+		// Peer, which must remain identical to the one of the other nodes
+		// once the InternalTransaction is committed.
+		peer := cmd.InternalTransaction.Body.Peer
+		return peer.ID(), cmd.Protocol
+	default:
+		return 0, version.Protocol{}
+	}
+}
+
+// checkProtocol records the Protocol of a peer, and returns an error if it is
+// not compatible with the Protocol of this node. The Protocol is only recorded
+// for the current peers, so that the IDs sent by others do not grow the stats.
+func (n *Node) checkProtocol(id uint32, protocol version.Protocol) error {
+	n.coreLock.RLock()
+	_, ok := n.core.peers.ByID[id]
+	n.coreLock.RUnlock()
+
+	if ok {
+		n.recordProtocol(id, protocol)
+	}
+
+	return version.Compatible(protocol)
+}
+
+func (n *Node) processSyncRequest(ctx context.Context, rpc net.RPC, cmd *net.SyncRequest) {
 	n.logger.WithFields(logrus.Fields{
 		"from_id":    cmd.FromID,
 		"sync_limit": cmd.SyncLimit,
 		"known":      cmd.Known,
+		"events":     len(cmd.Events),
 	}).Debug("process SyncRequest")
 
 	resp := &net.SyncResponse{
-		FromID: n.core.validator.ID(),
+		FromID:   n.core.validator.ID(),
+		Protocol: version.LocalProtocol(),
 	}
 
 	var respErr error
 
+	// Insert the events pushed with the request. A failure is not returned to
+	// the requester, which pushes them again with an EagerSyncRequest.
+	if len(cmd.Events) > 0 {
+		n.coreLock.Lock()
+		err := n.sync(ctx, cmd.FromID, cmd.Events)
+		n.coreLock.Unlock()
+
+		if err != nil {
+			n.logger.WithField("error", err).Error("sync()")
+		} else {
+			resp.PushAccepted = true
+			n.recordEvents(cmd.FromID, 0, len(cmd.Events))
+		}
+	}
+
 	//Compute Diff
 	start := time.Now()
-	n.coreLock.Lock()
+	n.updatePeerKnown(cmd.Known)
+
+	n.coreLock.RLock()
 	eventDiff, err := n.core.eventDiff(cmd.Known)
-	n.coreLock.Unlock()
+	n.coreLock.RUnlock()
 	elapsed := time.Since(start)
 
 	n.logger.WithField("duration", elapsed.Nanoseconds()).Debug("Diff()")
@@ -130,6 +344,8 @@ func (n *Node) processSyncRequest(rpc net.RPC, cmd *net.SyncRequest) {
 		respErr = err
 	}
 
+	eventDiff = n.skipSentEvents(cmd.FromID, eventDiff)
+
 	if len(eventDiff) > 0 {
 
 		//select min(cmd.SyncLimit, this.SyncLimit) events
@@ -141,9 +357,7 @@ func (n *Node) processSyncRequest(rpc net.RPC, cmd *net.SyncRequest) {
 			"diff_length":    len(eventDiff),
 		}).Debugf("Selecting max %d events", limit)
 
-		if limit < len(eventDiff) {
-			eventDiff = eventDiff[:limit]
-		}
+		eventDiff = limitEvents(eventDiff, limit)
 
 		//Convert to WireEvents
 		wireEvents, err := n.core.toWire(eventDiff)
@@ -151,17 +365,26 @@ func (n *Node) processSyncRequest(rpc net.RPC, cmd *net.SyncRequest) {
 			n.logger.WithField("error", err).Debug("Converting to WireEvent")
 			respErr = err
 		} else {
+			//Split in chunks of chunk_size bytes, the smallest non-zero of
+			//the requester's and the node's
+			if chunkSize := minChunkSize(cmd.ChunkSize, n.conf.SyncChunkSize); chunkSize > 0 {
+				wireEvents, resp.More = chunkEvents(wireEvents, chunkSize)
+			}
+
 			resp.Events = wireEvents
+			n.sentEvents.record(cmd.FromID, eventDiff[:len(wireEvents)], n.clock.Now())
 		}
 	}
 
 	//Get Self Known
-	n.coreLock.Lock()
+	n.coreLock.RLock()
 	knownEvents := n.core.knownEvents()
-	n.coreLock.Unlock()
+	n.coreLock.RUnlock()
 
 	resp.Known = knownEvents
 
+	n.recordSyncRequest(cmd.FromID, cmd.Known, len(resp.Events))
+
 	n.logger.WithFields(logrus.Fields{
 		"events":  len(resp.Events),
 		"known":   resp.Known,
@@ -178,7 +401,38 @@ func min(a, b int) int {
 	return b
 }
 
-func (n *Node) processEagerSyncRequest(rpc net.RPC, cmd *net.EagerSyncRequest) {
+// minChunkSize returns the smallest of two chunk sizes, where 0 means
+// unlimited.
+func minChunkSize(a, b int) int {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 {
+		return a
+	}
+	return min(a, b)
+}
+
+// chunkEvents returns the first events whose encoded size fits in chunkSize
+// bytes, and whether some events were left out. The first event is always
+// included, even if it is larger than chunkSize, so that syncs make progress.
+func chunkEvents(events []hg.WireEvent, chunkSize int) ([]hg.WireEvent, bool) {
+	size := 0
+	for i, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return events, false
+		}
+
+		size += len(data)
+		if size > chunkSize && i > 0 {
+			return events[:i], true
+		}
+	}
+	return events, false
+}
+
+func (n *Node) processEagerSyncRequest(ctx context.Context, rpc net.RPC, cmd *net.EagerSyncRequest) {
 	n.logger.WithFields(logrus.Fields{
 		"from_id": cmd.FromID,
 		"events":  len(cmd.Events),
@@ -187,17 +441,20 @@ func (n *Node) processEagerSyncRequest(rpc net.RPC, cmd *net.EagerSyncRequest) {
 	success := true
 
 	n.coreLock.Lock()
-	err := n.sync(cmd.FromID, cmd.Events)
+	err := n.sync(ctx, cmd.FromID, cmd.Events)
 	n.coreLock.Unlock()
 
 	if err != nil {
 		n.logger.WithField("error", err).Error("sync()")
 		success = false
+	} else {
+		n.recordEvents(cmd.FromID, 0, len(cmd.Events))
 	}
 
 	resp := &net.EagerSyncResponse{
-		FromID:  n.core.validator.ID(),
-		Success: success,
+		FromID:   n.core.validator.ID(),
+		Protocol: version.LocalProtocol(),
+		Success:  success,
 	}
 
 	rpc.Respond(resp, err)
@@ -208,33 +465,33 @@ func (n *Node) processFastForwardRequest(rpc net.RPC, cmd *net.FastForwardReques
 		"from": cmd.FromID,
 	}).Debug("process FastForwardRequest")
 
-	resp := &net.FastForwardResponse{
-		FromID: n.core.validator.ID(),
+	// A FastForwardResponse holds a frame and a snapshot in memory, so it is
+	// deferred while the memory budget is almost exhausted. The other node
+	// tries another peer.
+	if n.memoryBudget.underPressure() {
+		n.logger.WithField("from", cmd.FromID).Warn("Memory budget exceeded => deferring FastForwardResponse")
+		rpc.Respond(&net.FastForwardResponse{
+			FromID:   n.core.validator.ID(),
+			Protocol: version.LocalProtocol(),
+		}, fmt.Errorf("memory budget exceeded"))
+		return
 	}
 
+	var resp *net.FastForwardResponse
 	var respErr error
 
-	// Get latest Frame
-	n.coreLock.Lock()
-	block, frame, err := n.core.getAnchorBlockWithFrame()
-	n.coreLock.Unlock()
-
-	if err != nil {
-		n.logger.WithError(err).Error("Getting Frame")
-		respErr = err
+	// The response for the anchor block is the same for every node, so it is
+	// cached until the anchor block changes.
+	if cmd.BlockIndex != nil {
+		resp, respErr = n.newFastForwardResponse(cmd.BlockIndex)
 	} else {
-		resp.Block = *block
-		resp.Frame = *frame
-
-		//Get snapshot
-		snapshot, err := n.proxy.GetSnapshot(block.Index())
+		resp, respErr = n.anchorFastForwardResponse()
+	}
 
-		if err != nil {
-			n.logger.WithField("error", err).Error("Getting Snapshot")
-			respErr = err
-		} else {
-			resp.Snapshot = snapshot
-		}
+	// A node whose App is at an older Block only receives the difference
+	// between the snapshots, if the App can provide it.
+	if respErr == nil && cmd.SnapshotBase != nil {
+		resp = n.snapshotDeltaResponse(resp, *cmd.SnapshotBase)
 	}
 
 	n.logger.WithFields(logrus.Fields{
@@ -247,6 +504,59 @@ func (n *Node) processFastForwardRequest(rpc net.RPC, cmd *net.FastForwardReques
 	rpc.Respond(resp, respErr)
 }
 
+// newFastForwardResponse builds a FastForwardResponse with the requested block,
+// or the anchor block if blockIndex is nil, its Frame, and the snapshot of the
+// App.
+func (n *Node) newFastForwardResponse(blockIndex *int) (*net.FastForwardResponse, error) {
+	resp := &net.FastForwardResponse{
+		FromID:   n.core.validator.ID(),
+		Protocol: version.LocalProtocol(),
+	}
+
+	// Get the requested block, or the anchor block, with its Frame
+	var block *hg.Block
+	var frame *hg.Frame
+	var err error
+
+	n.coreLock.Lock()
+	if blockIndex != nil {
+		block, frame, err = n.core.getBlockWithFrame(*blockIndex)
+	} else {
+		block, frame, err = n.core.getAnchorBlockWithFrame()
+	}
+	n.coreLock.Unlock()
+
+	if err != nil {
+		n.logger.WithError(err).Error("Getting Frame")
+		return resp, err
+	}
+
+	resp.Block = *block
+	resp.Frame = *frame
+
+	//Get snapshot
+	snapshot, err := n.proxy.GetSnapshot(block.Index())
+
+	if err != nil {
+		n.logger.WithField("error", err).Error("Getting Snapshot")
+		return resp, err
+	}
+
+	resp.Snapshot = snapshot
+
+	return resp, nil
+}
+
+// joinMonikerTaken returns true if the moniker of a joining peer is used by
+// another validator. The validator-set is read under the coreLock, because the
+// babble loop replaces it when it processes Blocks.
+func (n *Node) joinMonikerTaken(peer *peers.Peer) bool {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	return monikerTaken(n.core.validators, peer)
+}
+
 func (n *Node) processJoinRequest(rpc net.RPC, cmd *net.JoinRequest) {
 	n.logger.WithFields(logrus.Fields{
 		"peer": cmd.InternalTransaction.Body.Peer,
@@ -256,6 +566,7 @@ func (n *Node) processJoinRequest(rpc net.RPC, cmd *net.JoinRequest) {
 	var accepted bool
 	var acceptedRound int
 	var peers []*peers.Peer
+	var retryAfter time.Duration
 
 	if ok, _ := cmd.InternalTransaction.Verify(); !ok {
 
@@ -277,6 +588,16 @@ func (n *Node) processJoinRequest(rpc net.RPC, cmd *net.JoinRequest) {
 
 		peers = n.core.peers.Peers
 
+	} else if n.joinMonikerTaken(&cmd.InternalTransaction.Body.Peer) {
+
+		// The same check is done when the InternalTransaction is processed,
+		// but there is no need to wait for consensus to refuse it.
+		n.logger.WithField("moniker", cmd.InternalTransaction.Body.Peer.Moniker).Warn("JoinRequest moniker is already used")
+
+	} else if retryAfter = n.admitJoin(cmd); retryAfter > 0 {
+
+		n.logger.WithField("retry_after", retryAfter).Debug("JoinRequest deferred")
+
 	} else {
 		// Dispatch the InternalTransaction
 		n.coreLock.Lock()
@@ -284,7 +605,7 @@ func (n *Node) processJoinRequest(rpc net.RPC, cmd *net.JoinRequest) {
 		n.coreLock.Unlock()
 
 		//Wait for the InternalTransaction to go through consensus
-		timeout := time.After(n.conf.JoinTimeout)
+		timeout := n.clock.After(n.conf.JoinTimeout)
 		select {
 		case resp := <-promise.respCh:
 			accepted = resp.accepted
@@ -297,19 +618,62 @@ func (n *Node) processJoinRequest(rpc net.RPC, cmd *net.JoinRequest) {
 		}
 	}
 
+	if accepted {
+		n.learnPeer(&cmd.InternalTransaction.Body.Peer)
+	}
+
 	resp := &net.JoinResponse{
 		FromID:        n.core.validator.ID(),
+		Protocol:      version.LocalProtocol(),
 		Accepted:      accepted,
 		AcceptedRound: acceptedRound,
 		Peers:         peers,
+		RetryAfter:    retryAfter,
 	}
 
 	n.logger.WithFields(logrus.Fields{
 		"accepted":       resp.Accepted,
 		"accepted_round": resp.AcceptedRound,
 		"peers":          len(resp.Peers),
+		"retry_after":    resp.RetryAfter,
 		"rpc_err":        respErr,
 	}).Debug("Responding to JoinRequest")
 
 	rpc.Respond(resp, respErr)
 }
+
+// admitJoin returns 0 if a JoinRequest is admitted, or the duration after which
+// the requester should send it again, when conf.JoinAdmissionLimit requests
+// were already admitted in the current window.
+func (n *Node) admitJoin(cmd *net.JoinRequest) time.Duration {
+	if n.joinAdmission == nil {
+		return 0
+	}
+
+	retryAfter := n.joinAdmission.admit(cmd.InternalTransaction.Body.Peer.PubKeyString(), n.clock.Now())
+	if retryAfter > 0 {
+		metrics.JoinRequestsDeferred.Inc()
+	}
+
+	return retryAfter
+}
+
+// processPingRequest answers a PingRequest right away, without touching the
+// core, so that the round-trip time measured by the peer reflects the network
+// rather than the load of the node.
+func (n *Node) processPingRequest(rpc net.RPC, cmd *net.PingRequest) {
+	rpc.Respond(&net.PingResponse{
+		FromID:           n.core.validator.ID(),
+		Protocol:         version.LocalProtocol(),
+		GenesisStateHash: n.genesisStateHash,
+	}, nil)
+}
+
+// processInfoRequest answers an InfoRequest with the description of the node.
+func (n *Node) processInfoRequest(rpc net.RPC, cmd *net.InfoRequest) {
+	rpc.Respond(&net.InfoResponse{
+		FromID:   n.core.validator.ID(),
+		Protocol: version.LocalProtocol(),
+		Info:     n.GetInfo(),
+	}, nil)
+}
@@ -1,7 +1,9 @@
 package node
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +12,8 @@ import (
 	dummy "github.com/mosaicnetworks/babble/src/dummy"
 	hg "github.com/mosaicnetworks/babble/src/hashgraph"
 	"github.com/mosaicnetworks/babble/src/net"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/version"
 )
 
 func TestProcessSync(t *testing.T) {
@@ -203,6 +207,102 @@ func TestProcessEagerSync(t *testing.T) {
 	node1.Shutdown()
 }
 
+func TestProcessPushPullSync(t *testing.T) {
+	keys, p := initPeers(t, 2)
+	config := config.NewTestConfig(t, common.TestLogLevel)
+
+	//Start two nodes
+
+	peers := p.Peers
+
+	peer0Trans, err := net.NewTCPTransport(peers[0].NetAddr, "", 2, time.Second, time.Second, config.Logger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go peer0Trans.Listen()
+	defer peer0Trans.Close()
+
+	genesisPeerSet := clonePeerSet(t, p.Peers)
+
+	node0 := NewNode(config,
+		NewValidator(keys[0], peers[0].Moniker),
+		p,
+		genesisPeerSet,
+		hg.NewInmemStore(config.CacheSize),
+		peer0Trans,
+		dummy.NewInmemDummyClient(common.NewTestEntry(t, common.TestLogLevel)))
+	node0.Init()
+
+	node0.RunAsync(false)
+
+	peer1Trans, err := net.NewTCPTransport(peers[1].NetAddr, "", 2, time.Second, time.Second, config.Logger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go peer1Trans.Listen()
+	defer peer1Trans.Close()
+
+	node1 := NewNode(config,
+		NewValidator(keys[1], peers[1].Moniker),
+		p,
+		genesisPeerSet,
+		hg.NewInmemStore(config.CacheSize),
+		peer1Trans,
+		dummy.NewInmemDummyClient(common.NewTestEntry(t, common.TestLogLevel)))
+	node1.Init()
+
+	node1.RunAsync(false)
+
+	//Manually prepare a SyncRequest carrying the events unknown to node1
+
+	node0.coreLock.Lock()
+	err = node0.core.addSelfEvent("")
+	node0.coreLock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node0KnownEvents := node0.core.knownEvents()
+	node1KnownEvents := node1.core.knownEvents()
+
+	unknownEvents, err := node0.core.eventDiff(node1KnownEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknownWireEvents, err := node0.core.toWire(unknownEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := net.SyncRequest{
+		FromID:    node0.core.validator.ID(),
+		SyncLimit: node0.conf.SyncLimit,
+		Known:     node0KnownEvents,
+		Events:    unknownWireEvents,
+	}
+
+	//Make actual SyncRequest and check SyncResponse
+
+	var out net.SyncResponse
+	if err := peer0Trans.Sync(peers[1].NetAddr, &args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !out.PushAccepted {
+		t.Fatal("SyncResponse.PushAccepted should be true")
+	}
+
+	// node1 should have inserted the event of node0
+	node0ID := node0.core.validator.ID()
+	if k := node1.core.knownEvents()[node0ID]; k != 0 {
+		t.Fatalf("node1 should know event 0 of node0, not %d", k)
+	}
+
+	node0.Shutdown()
+	node1.Shutdown()
+}
+
 func TestProcessFastForward(t *testing.T) {
 	keys, p := initPeers(t, 2)
 	config := config.NewTestConfig(t, common.TestLogLevel)
@@ -267,3 +367,233 @@ func TestProcessFastForward(t *testing.T) {
 	node0.Shutdown()
 	node1.Shutdown()
 }
+
+func TestNetworkID(t *testing.T) {
+	keys, p := initPeers(t, 2)
+	config := config.NewTestConfig(t, common.TestLogLevel)
+	config.NetworkID = "0XAA"
+
+	peers := p.Peers
+
+	peer0Trans, err := net.NewTCPTransport(peers[0].NetAddr, "", 2, time.Second, time.Second, config.Logger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go peer0Trans.Listen()
+	defer peer0Trans.Close()
+
+	peer1Trans, err := net.NewTCPTransport(peers[1].NetAddr, "", 2, time.Second, time.Second, config.Logger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go peer1Trans.Listen()
+	defer peer1Trans.Close()
+
+	node1 := NewNode(config,
+		NewValidator(keys[1], peers[1].Moniker),
+		p,
+		clonePeerSet(t, p.Peers),
+		hg.NewInmemStore(config.CacheSize),
+		peer1Trans,
+		dummy.NewInmemDummyClient(common.NewTestEntry(t, common.TestLogLevel)))
+	node1.Init()
+
+	node1.RunAsync(false)
+	defer node1.Shutdown()
+
+	args := net.SyncRequest{
+		FromID:    peers[0].ID(),
+		NetworkID: "0XBB",
+		SyncLimit: config.SyncLimit,
+		Known:     map[uint32]int{},
+	}
+
+	var out net.SyncResponse
+	if err := peer0Trans.Sync(peers[1].NetAddr, &args, &out); err == nil {
+		t.Fatal("A SyncRequest from another network should be refused")
+	}
+
+	args.NetworkID = config.NetworkID
+	if err := peer0Trans.Sync(peers[1].NetAddr, &args, &out); err != nil {
+		t.Fatalf("A SyncRequest from the same network should be accepted: %v", err)
+	}
+}
+
+func TestProcessRPCWhileGossiping(t *testing.T) {
+	keys, p := initPeers(t, 2)
+	config := config.NewTestConfig(t, common.TestLogLevel)
+
+	peers := p.Peers
+
+	peer0Trans, err := net.NewTCPTransport(peers[0].NetAddr, "", 2, time.Second, time.Second, config.Logger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go peer0Trans.Listen()
+	defer peer0Trans.Close()
+
+	peer1Trans, err := net.NewTCPTransport(peers[1].NetAddr, "", 2, time.Second, time.Second, config.Logger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go peer1Trans.Listen()
+	defer peer1Trans.Close()
+
+	node1 := NewNode(config,
+		NewValidator(keys[1], peers[1].Moniker),
+		p,
+		clonePeerSet(t, p.Peers),
+		hg.NewInmemStore(config.CacheSize),
+		peer1Trans,
+		dummy.NewInmemDummyClient(common.NewTestEntry(t, common.TestLogLevel)))
+	node1.Init()
+
+	node1.RunAsync(false)
+	defer node1.Shutdown()
+
+	// Keep gossip routines busy, like slow outbound syncs would
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < _state.WGLIMIT; i++ {
+		node1.GoFunc(func() { <-release })
+	}
+
+	args := net.SyncRequest{
+		FromID:    peers[0].ID(),
+		SyncLimit: config.SyncLimit,
+		Known:     map[uint32]int{},
+	}
+
+	var out net.SyncResponse
+	if err := peer0Trans.Sync(peers[1].NetAddr, &args, &out); err != nil {
+		t.Fatalf("Incoming RPCs should be processed while gossip routines are busy: %v", err)
+	}
+	if out.FromID != peers[1].ID() {
+		t.Fatalf("The response should come from node 1, not %d", out.FromID)
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	keys, p := initPeers(t, 2)
+	config := config.NewTestConfig(t, common.TestLogLevel)
+
+	peers := p.Peers
+
+	peer0Trans, err := net.NewTCPTransport(peers[0].NetAddr, "", 2, time.Second, time.Second, config.Logger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go peer0Trans.Listen()
+	defer peer0Trans.Close()
+
+	peer1Trans, err := net.NewTCPTransport(peers[1].NetAddr, "", 2, time.Second, time.Second, config.Logger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go peer1Trans.Listen()
+	defer peer1Trans.Close()
+
+	node1 := NewNode(config,
+		NewValidator(keys[1], peers[1].Moniker),
+		p,
+		clonePeerSet(t, p.Peers),
+		hg.NewInmemStore(config.CacheSize),
+		peer1Trans,
+		dummy.NewInmemDummyClient(common.NewTestEntry(t, common.TestLogLevel)))
+	node1.Init()
+
+	node1.RunAsync(false)
+	defer node1.Shutdown()
+
+	args := net.SyncRequest{
+		FromID: peers[0].ID(),
+		Protocol: version.Protocol{
+			Version:    version.ProtocolVersion + 2,
+			MinVersion: version.ProtocolVersion + 1,
+			Babble:     "99.0.0",
+		},
+		SyncLimit: config.SyncLimit,
+		Known:     map[uint32]int{},
+	}
+
+	var out net.SyncResponse
+	err = peer0Trans.Sync(peers[1].NetAddr, &args, &out)
+	if err == nil || !strings.Contains(err.Error(), "Incompatible protocol") {
+		t.Fatalf("A SyncRequest with an incompatible protocol should be refused, not %v", err)
+	}
+
+	// Requests without a Protocol come from nodes that predate version
+	// negotiation
+	args.Protocol = version.Protocol{}
+	if err := peer0Trans.Sync(peers[1].NetAddr, &args, &out); err != nil {
+		t.Fatalf("A SyncRequest without a protocol should be accepted: %v", err)
+	}
+
+	args.Protocol = version.LocalProtocol()
+	if err := peer0Trans.Sync(peers[1].NetAddr, &args, &out); err != nil {
+		t.Fatalf("A SyncRequest with the same protocol should be accepted: %v", err)
+	}
+
+	if out.Protocol != version.LocalProtocol() {
+		t.Fatalf("SyncResponse.Protocol should be %+v, not %+v", version.LocalProtocol(), out.Protocol)
+	}
+
+	peerStats := node1.GetPeerStats()
+	if len(peerStats) != 1 {
+		t.Fatalf("node1 should report 1 peer, not %d", len(peerStats))
+	}
+
+	if ps := peerStats[0]; ps.Version != version.Version || ps.ProtocolVersion != version.ProtocolVersion {
+		t.Fatalf("PeerStats should report version %s and protocol %d, not %s and %d",
+			version.Version, version.ProtocolVersion, ps.Version, ps.ProtocolVersion)
+	}
+
+	// The requests of unknown peers do not add stats
+	args.FromID = 12345
+	if err := peer0Trans.Sync(peers[1].NetAddr, &args, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if peerStats := node1.GetPeerStats(); len(peerStats) != 1 {
+		t.Fatalf("node1 should still report 1 peer, not %d", len(peerStats))
+	}
+}
+
+func TestChunkEvents(t *testing.T) {
+	events := make([]hg.WireEvent, 10)
+	for i := range events {
+		events[i] = hg.WireEvent{
+			Body: hg.WireBody{
+				Transactions: [][]byte{make([]byte, 100)},
+				Index:        i,
+			},
+		}
+	}
+
+	data, err := json.Marshal(events[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := len(data)
+
+	chunk, more := chunkEvents(events, 3*size+size/2)
+	if len(chunk) != 3 || !more {
+		t.Fatalf("The chunk should contain 3 events and announce more, not %d, %v", len(chunk), more)
+	}
+
+	// The first event is included even if it is larger than the chunk size
+	if chunk, more := chunkEvents(events, 1); len(chunk) != 1 || !more {
+		t.Fatalf("The chunk should contain 1 event and announce more, not %d, %v", len(chunk), more)
+	}
+
+	if chunk, more := chunkEvents(events, 100*size); len(chunk) != 10 || more {
+		t.Fatalf("The chunk should contain all the events, not %d, %v", len(chunk), more)
+	}
+
+	if c := minChunkSize(0, 10); c != 10 {
+		t.Fatalf("The chunk size should be 10, not %d", c)
+	}
+	if c := minChunkSize(20, 10); c != 10 {
+		t.Fatalf("The chunk size should be 10, not %d", c)
+	}
+}
@@ -49,6 +49,15 @@ func TestAutoSuspend(t *testing.T) {
 			s, len(nodes[1].core.getUndeterminedEvents()))
 	}
 
+	suspension := nodes[0].GetSuspension()
+	if suspension == nil || suspension.Reason != SuspendUndeterminedEvents ||
+		suspension.UndeterminedEvents <= suspension.SuspendLimit {
+		t.Fatalf("nodes[0] should be suspended for too many undetermined events: %+v", suspension)
+	}
+	if count := nodes[0].Stats().Suspensions[SuspendUndeterminedEvents]; count != 1 {
+		t.Fatalf("nodes[0] should count 1 suspension, not %d", count)
+	}
+
 	node0FirstUE := len(nodes[0].core.getUndeterminedEvents())
 	t.Logf("nodes[0].UndeterminedEvents = %d", node0FirstUE)
 	node1FirstUE := len(nodes[1].core.getUndeterminedEvents())
@@ -89,6 +98,51 @@ func TestAutoSuspend(t *testing.T) {
 	}
 }
 
+func TestResume(t *testing.T) {
+	os.RemoveAll("test_data")
+	os.Mkdir("test_data", os.ModeDir|0777)
+
+	// define 3 validators, but only run 2 of them, so that no events are
+	// ever decided and the nodes suspend themselves.
+	keys, peers := initPeers(t, 3)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := []*Node{
+		newNode(peers.Peers[0], keys[0], genesisPeerSet, peers, 1000, 1000, 10, false, "badger", 10*time.Millisecond, false, "", t),
+		newNode(peers.Peers[1], keys[1], genesisPeerSet, peers, 1000, 1000, 10, false, "badger", 10*time.Millisecond, false, "", t),
+	}
+	defer shutdownNodes(nodes)
+
+	if err := nodes[0].Resume(); err == nil {
+		t.Fatal("Resume should fail when the node is not suspended")
+	}
+
+	runNodes(nodes, true)
+	submitTransaction(nodes[0], []byte("the tx that will never be committed"))
+	waitSuspend(nodes, 10*time.Second, t)
+
+	firstUE := len(nodes[0].core.getUndeterminedEvents())
+
+	// Resume without restarting the nodes
+	for i, n := range nodes {
+		if err := n.Resume(); err != nil {
+			t.Fatalf("nodes[%d] Resume: %v", i, err)
+		}
+		if s := n.GetState(); s != _state.Babbling {
+			t.Fatalf("nodes[%d] should be Babbling, not %v", i, s)
+		}
+	}
+
+	submitTransaction(nodes[0], []byte("another tx that will never be committed"))
+	waitSuspend(nodes, 10*time.Second, t)
+
+	secondUE := len(nodes[0].core.getUndeterminedEvents())
+
+	if secondUE-firstUE < nodes[0].conf.SuspendLimit {
+		t.Fatalf("nodes[0] should have produced some events after resuming")
+	}
+}
+
 func waitSuspend(nodes []*Node, timeout time.Duration, t *testing.T) {
 	stopper := time.After(timeout)
 	for {
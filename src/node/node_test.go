@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -68,15 +70,17 @@ func TestAddTransaction(t *testing.T) {
 	node0KnownEvents := nodes[0].core.knownEvents()
 
 	resp, err := nodes[0].requestSync(
+		context.Background(),
 		peers.Peers[1].NetAddr,
 		node0KnownEvents,
-		500)
+		500,
+		nil)
 	if err != nil {
 		t.Error("Fatal Error 2", err)
 		t.Fatal(err)
 	}
 
-	if err := nodes[0].sync(peers.Peers[1].ID(), resp.Events); err != nil {
+	if err := nodes[0].sync(context.Background(), peers.Peers[1].ID(), resp.Events); err != nil {
 		t.Error("Fatal Error 3", err)
 		t.Fatal(err)
 	}
@@ -97,6 +101,25 @@ func TestAddTransaction(t *testing.T) {
 	}
 }
 
+func TestInitInvalidThresholds(t *testing.T) {
+	conf := config.NewTestConfig(t, common.TestLogLevel)
+	conf.SuperMajority = "1/2"
+
+	key, _ := bkeys.GenerateECDSAKey()
+	peerSet := peers.NewPeerSet([]*peers.Peer{})
+
+	node := NewNode(conf,
+		NewValidator(key, "node0"),
+		peerSet,
+		peerSet,
+		hg.NewInmemStore(conf.CacheSize),
+		nil,
+		dummy.NewInmemDummyClient(common.NewTestEntry(t, common.TestLogLevel)))
+	if err := node.Init(); err == nil {
+		t.Fatal("Init should fail with an unsafe super-majority")
+	}
+}
+
 func TestGossip(t *testing.T) {
 	keys, peers := initPeers(t, 4)
 
@@ -115,6 +138,49 @@ func TestGossip(t *testing.T) {
 	checkGossip(nodes, 0, t)
 }
 
+// TestGossipWithReaders queries the stats of the nodes while they gossip, like
+// the HTTP service does. The queries only take the coreLock for reading, so the
+// nodes keep reaching consensus.
+func TestGossipWithReaders(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 100000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *Node) {
+			defer wg.Done()
+			ticker := time.NewTicker(time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-quit:
+					return
+				case <-ticker.C:
+					n.Stats()
+					n.GetPeerStats()
+					n.GetEventLag()
+					n.SyncProgress()
+				}
+			}
+		}(n)
+	}
+
+	err := gossip(nodes, 10, false)
+	close(quit)
+	wg.Wait()
+	shutdownNodes(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkGossip(nodes, 0, t)
+}
+
 func TestWebRTCGossip(t *testing.T) {
 	keys, peers := initPeers(t, 4)
 
@@ -336,6 +402,8 @@ func newNode(peer *peers.Peer,
 	conf.CacheSize = cacheSize
 	conf.SyncLimit = syncLimit
 	conf.EnableFastSync = enableSyncLimit
+	// Keep the diagnostics of suspended nodes out of the home directory
+	conf.SetDataDir(t.TempDir())
 
 	t.Logf("Starting node on %s", peer.NetAddr)
 
@@ -372,6 +440,7 @@ func newNode(peer *peers.Peer,
 		trans, err = net.NewWebRTCTransport(
 			signal,
 			conf.ICEServers(),
+			net.DataChannelConfig{},
 			conf.MaxPool,
 			conf.TCPTimeout,
 			conf.JoinTimeout,
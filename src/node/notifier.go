@@ -0,0 +1,218 @@
+package node
+
+import (
+	"sync"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// NotificationType identifies the kind of Notification published to
+// subscribers.
+type NotificationType string
+
+const (
+	// BlockNotification is published when a block is committed.
+	BlockNotification NotificationType = "block"
+	// TransactionNotification is published for each transaction of a committed
+	// block.
+	TransactionNotification NotificationType = "tx"
+	// PeerSetNotification is published when a new validator-set is recorded.
+	PeerSetNotification NotificationType = "peers"
+	// StateNotification is published when the node changes state.
+	StateNotification NotificationType = "state"
+	// ErrorNotification is published when the node fails to commit a block,
+	// to fast-forward, or to join, when it suspends itself, and when it exceeds
+	// its data budget.
+	ErrorNotification NotificationType = "error"
+	// AlertNotification is published when the node detects a problem that
+	// requires the attention of an operator.
+	AlertNotification NotificationType = "alert"
+	// ExpiredNotification is published for each transaction whose TTL expired
+	// before it was included in an Event.
+	ExpiredNotification NotificationType = "expired"
+)
+
+// ConsensusTransaction is a transaction that has gone through consensus,
+// with its position in the blockchain.
+type ConsensusTransaction struct {
+	Block int    `json:"block"`
+	Index int    `json:"index"`
+	Data  []byte `json:"data"`
+}
+
+// ExpiredTransaction is a transaction evicted from the node because its TTL
+// expired. It was never included in an Event, so it will not be committed.
+type ExpiredTransaction struct {
+	Hash string `json:"hash"`
+	Data []byte `json:"data"`
+}
+
+// PeerSetChange describes a new validator-set and the round from which it is
+// effective.
+type PeerSetChange struct {
+	Round int           `json:"round"`
+	Peers []*peers.Peer `json:"peers"`
+}
+
+// Notification is an event published by the node to its subscribers. Only the
+// field corresponding to the Type is set.
+type Notification struct {
+	Type        NotificationType      `json:"type"`
+	Block       *hg.Block             `json:"block,omitempty"`
+	Transaction *ConsensusTransaction `json:"tx,omitempty"`
+	PeerSet     *PeerSetChange        `json:"peers,omitempty"`
+	State       string                `json:"state,omitempty"`
+	Error       string                `json:"error,omitempty"`
+	Alert       *Alert                `json:"alert,omitempty"`
+	Expired     *ExpiredTransaction   `json:"expired,omitempty"`
+}
+
+// Subscription receives the notifications of the types it subscribed to. The
+// node never blocks on a subscriber; if the buffer of a subscription is full
+// when a notification is published, the subscription is closed.
+type Subscription struct {
+	ch       chan Notification
+	types    map[NotificationType]bool
+	notifier *notifier
+}
+
+// C returns the channel where notifications are delivered. It is closed when
+// the subscription is cancelled or when the subscriber falls behind.
+func (s *Subscription) C() <-chan Notification {
+	return s.ch
+}
+
+// Unsubscribe cancels the subscription and closes its channel. It is safe to
+// call it more than once.
+func (s *Subscription) Unsubscribe() {
+	s.notifier.unsubscribe(s)
+}
+
+// notifier dispatches notifications to subscriptions.
+type notifier struct {
+	sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// subscribe registers a subscription with the given buffer size. If no types
+// are specified, the subscription receives all notifications.
+func (n *notifier) subscribe(buffer int, types ...NotificationType) *Subscription {
+	sub := &Subscription{
+		ch:       make(chan Notification, buffer),
+		types:    make(map[NotificationType]bool),
+		notifier: n,
+	}
+
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	n.Lock()
+	n.subs[sub] = struct{}{}
+	n.Unlock()
+
+	return sub
+}
+
+func (n *notifier) unsubscribe(sub *Subscription) {
+	n.Lock()
+	defer n.Unlock()
+
+	if _, ok := n.subs[sub]; ok {
+		delete(n.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// close cancels all the subscriptions.
+func (n *notifier) close() {
+	n.Lock()
+	defer n.Unlock()
+
+	for sub := range n.subs {
+		delete(n.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// publish delivers a notification to the interested subscriptions without
+// blocking.
+func (n *notifier) publish(note Notification) {
+	n.Lock()
+	defer n.Unlock()
+
+	for sub := range n.subs {
+		if len(sub.types) > 0 && !sub.types[note.Type] {
+			continue
+		}
+
+		select {
+		case sub.ch <- note:
+		default:
+			delete(n.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// publishError publishes an error.
+func (n *notifier) publishError(err error) {
+	n.publish(Notification{
+		Type:  ErrorNotification,
+		Error: err.Error(),
+	})
+}
+
+// publishAlert publishes an alert.
+func (n *notifier) publishAlert(alert *Alert) {
+	n.publish(Notification{
+		Type:  AlertNotification,
+		Alert: alert,
+	})
+}
+
+// publishExpired publishes a transaction whose TTL expired.
+func (n *notifier) publishExpired(tx []byte) {
+	n.publish(Notification{
+		Type: ExpiredNotification,
+		Expired: &ExpiredTransaction{
+			Hash: hg.TxHash(tx),
+			Data: tx,
+		},
+	})
+}
+
+// publishBlock publishes a committed block and its transactions. The block is
+// copied because its signatures are still updated after it is committed.
+func (n *notifier) publishBlock(block *hg.Block) {
+	signatures := make(map[string]string, len(block.Signatures))
+	for k, v := range block.Signatures {
+		signatures[k] = v
+	}
+
+	n.publish(Notification{
+		Type: BlockNotification,
+		Block: &hg.Block{
+			Body:       block.Body,
+			Signatures: signatures,
+		},
+	})
+
+	for i, tx := range block.Transactions() {
+		n.publish(Notification{
+			Type: TransactionNotification,
+			Transaction: &ConsensusTransaction{
+				Block: block.Index(),
+				Index: i,
+				Data:  tx,
+			},
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"testing"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+func TestNotifierFilter(t *testing.T) {
+	n := newNotifier()
+
+	all := n.subscribe(10)
+	states := n.subscribe(10, StateNotification)
+
+	block := hg.NewBlock(3, 1, []byte{}, nil, [][]byte{[]byte("tx0"), []byte("tx1")}, nil)
+	n.publishBlock(block)
+	n.publish(Notification{Type: StateNotification, State: "Babbling"})
+
+	if l := len(all.C()); l != 4 {
+		t.Fatalf("all should have received 4 notifications, not %d", l)
+	}
+
+	if l := len(states.C()); l != 1 {
+		t.Fatalf("states should have received 1 notification, not %d", l)
+	}
+
+	note := <-all.C()
+	if note.Type != BlockNotification || note.Block.Index() != 3 {
+		t.Fatalf("first notification should be block 3, not %+v", note)
+	}
+
+	note = <-all.C()
+	if note.Type != TransactionNotification ||
+		note.Transaction.Block != 3 ||
+		note.Transaction.Index != 0 ||
+		string(note.Transaction.Data) != "tx0" {
+		t.Fatalf("second notification should be tx0 of block 3, not %+v", note)
+	}
+
+	if note := <-states.C(); note.State != "Babbling" {
+		t.Fatalf("state should be Babbling, not %s", note.State)
+	}
+}
+
+func TestNotifierSlowSubscriber(t *testing.T) {
+	n := newNotifier()
+
+	sub := n.subscribe(1)
+
+	n.publish(Notification{Type: StateNotification, State: "Babbling"})
+	n.publish(Notification{Type: StateNotification, State: "Suspended"})
+
+	if _, ok := <-sub.C(); !ok {
+		t.Fatal("buffered notification should be delivered")
+	}
+
+	if _, ok := <-sub.C(); ok {
+		t.Fatal("subscription should be closed after overflowing")
+	}
+
+	// must not panic on a closed subscription
+	sub.Unsubscribe()
+}
@@ -0,0 +1,176 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+)
+
+// offlineLogLimit is the maximum number of transactions held in the offline
+// log. Further transactions are dropped.
+const offlineLogLimit = 10000
+
+// offlineCheckInterval is the period of the checks that the node is back in
+// contact with a super-majority of the validators, in offline-mode.
+const offlineCheckInterval = time.Second
+
+// offlineLog holds the transactions submitted while the node is cut off from a
+// super-majority of the validators. They are not added to Events, which could
+// not reach consensus anyway, and are resubmitted when the node is back in
+// contact with the quorum.
+type offlineLog struct {
+	sync.Mutex
+
+	txs       [][]byte
+	deadlines []*txDeadline
+}
+
+// add appends a transaction, with its deadline if it has a TTL, to the log. It
+// returns false if the log is full.
+func (l *offlineLog) add(tx []byte, deadline *txDeadline) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	if len(l.txs) >= offlineLogLimit {
+		return false
+	}
+
+	l.txs = append(l.txs, tx)
+	l.deadlines = append(l.deadlines, deadline)
+
+	return true
+}
+
+// drain empties the log and returns its transactions, in submission order,
+// with their deadlines.
+func (l *offlineLog) drain() ([][]byte, []*txDeadline) {
+	l.Lock()
+	defer l.Unlock()
+
+	txs, deadlines := l.txs, l.deadlines
+	l.txs, l.deadlines = nil, nil
+
+	return txs, deadlines
+}
+
+// evict removes the transactions whose deadline has passed from the log, and
+// returns them.
+func (l *offlineLog) evict(lastRound int, now time.Time) [][]byte {
+	l.Lock()
+	defer l.Unlock()
+
+	var expired [][]byte
+	txs := l.txs[:0]
+	deadlines := l.deadlines[:0]
+
+	for i, tx := range l.txs {
+		if d := l.deadlines[i]; d != nil && d.expired(lastRound, now) {
+			expired = append(expired, tx)
+			continue
+		}
+		txs = append(txs, tx)
+		deadlines = append(deadlines, l.deadlines[i])
+	}
+
+	l.txs, l.deadlines = txs, deadlines
+
+	return expired
+}
+
+// len returns the number of transactions in the log.
+func (l *offlineLog) len() int {
+	l.Lock()
+	defer l.Unlock()
+
+	return len(l.txs)
+}
+
+// partitioned returns true if the node, and the validators with which it
+// gossiped successfully within conf.OfflineTimeout, do not form a
+// super-majority of the validator-set.
+func (n *Node) partitioned() bool {
+	n.coreLock.RLock()
+	validators := n.core.validators
+	thresholds := n.core.hg.Thresholds()
+	n.coreLock.RUnlock()
+
+	now := n.clock.Now()
+
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	reachable := 0
+	for _, p := range validators.Peers {
+		if p.Shadow {
+			continue
+		}
+
+		if p.ID() == n.GetID() {
+			reachable++
+			continue
+		}
+
+		if ps, ok := n.peerStats[p.ID()]; ok && ps.LastSync != nil && now.Sub(*ps.LastSync) <= n.conf.OfflineTimeout {
+			reachable++
+		}
+	}
+
+	return reachable < thresholds.SuperMajorityOf(validators)
+}
+
+// submitOffline adds a transaction to the offline log, if the node is in
+// offline-mode and partitioned from the quorum. It returns false if the
+// transaction should go to the transaction-pool instead.
+func (n *Node) submitOffline(tx []byte, deadline *txDeadline) bool {
+	if !n.conf.OfflineMode || !n.partitioned() {
+		return false
+	}
+
+	if !n.offlineLog.add(tx, deadline) {
+		n.logger.Warn("Offline log full => dropping Transaction")
+		n.core.notifier.publishError(fmt.Errorf("Offline log full"))
+		return true
+	}
+
+	n.logger.WithField("offline_transactions", n.offlineLog.len()).Debug("Partitioned => holding Transaction in offline log")
+
+	return true
+}
+
+// reconcileOffline resubmits the transactions of the offline log, once the
+// node is Babbling with a super-majority of the validators again, until the
+// node shuts down.
+func (n *Node) reconcileOffline() {
+	ticker := n.clock.NewTicker(offlineCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			n.expireTransactions()
+			n.resubmitOffline()
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// resubmitOffline moves the transactions of the offline log to the
+// transaction-pool, if the node is Babbling and not partitioned. They go into
+// an Event at the next heartbeat.
+func (n *Node) resubmitOffline() {
+	if n.offlineLog.len() == 0 || n.GetState() != _state.Babbling || n.partitioned() {
+		return
+	}
+
+	txs, deadlines := n.offlineLog.drain()
+
+	n.logger.WithField("transactions", len(txs)).Info("Back in contact with the quorum => resubmitting offline transactions")
+
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	n.core.addExpiringTransactions(txs, deadlines)
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOfflineMode(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	node := nodes[0]
+	node.conf.OfflineMode = true
+	node.conf.OfflineTimeout = time.Minute
+
+	// Without any gossip, the node is cut off from the quorum
+	if !node.submitOffline([]byte("tx1"), nil) || !node.submitOffline([]byte("tx2"), nil) {
+		t.Fatal("The transactions should be held in the offline log")
+	}
+
+	if s := node.Stats(); s.OfflineTransactions != 2 || s.TransactionPool != 0 {
+		t.Fatalf("2 transactions should be offline, and none in the pool, not %d and %d", s.OfflineTransactions, s.TransactionPool)
+	}
+
+	// A single reachable peer does not form a super-majority with the node
+	node.recordGossip(nodes[1].GetID(), nil)
+
+	node.resubmitOffline()
+	if node.offlineLog.len() != 2 {
+		t.Fatal("The transactions should stay in the offline log")
+	}
+
+	// Nor does a peer whose last gossip is older than the timeout
+	node.recordGossip(nodes[2].GetID(), nil)
+	node.peerStatsLock.Lock()
+	stale := time.Now().Add(-2 * time.Minute)
+	node.peerStats[nodes[2].GetID()].LastSync = &stale
+	node.peerStatsLock.Unlock()
+
+	if !node.partitioned() {
+		t.Fatal("The node should still be partitioned")
+	}
+
+	// Back in contact with the quorum, the transactions are resubmitted in
+	// order, and new ones go to the pool
+	node.recordGossip(nodes[2].GetID(), nil)
+
+	node.resubmitOffline()
+
+	if s := node.Stats(); s.OfflineTransactions != 0 || s.TransactionPool != 2 {
+		t.Fatalf("The 2 transactions should be in the pool, not %d, with %d offline", s.TransactionPool, s.OfflineTransactions)
+	}
+	if string(node.core.transactionPool[0]) != "tx1" || string(node.core.transactionPool[1]) != "tx2" {
+		t.Fatal("The transactions should be resubmitted in order")
+	}
+
+	if node.submitOffline([]byte("tx3"), nil) {
+		t.Fatal("The transaction should not be held when the node is in contact with the quorum")
+	}
+}
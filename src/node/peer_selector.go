@@ -2,6 +2,8 @@ package node
 
 import (
 	"math/rand"
+	"sort"
+	"time"
 
 	"github.com/mosaicnetworks/babble/src/peers"
 )
@@ -101,3 +103,118 @@ func (ps *randomPeerSelector) next() *peers.Peer {
 
 	return peer
 }
+
+// starvationFactor bounds the number of selections, in multiples of the
+// number of selectable peers, that a latencyPeerSelector makes without
+// selecting a given peer.
+const starvationFactor = 4
+
+// minLatency is the round-trip time below which all peers are weighted the
+// same by a latencyPeerSelector.
+const minLatency = time.Millisecond
+
+// latencyPeerSelector implements the peerSelector interface and selects the
+// next peer at random, with a probability inversely proportional to its
+// round-trip time. Peers that have not been measured are weighted like the
+// median of the measured peers. To guarantee that every peer is eventually
+// selected, a peer that has not been selected in starvationFactor times the
+// number of selectable peers is selected next.
+type latencyPeerSelector struct {
+	*randomPeerSelector
+	latencies *latencies
+	skipped   map[uint32]int
+}
+
+// newLatencyPeerSelector creates a new latencyPeerSelector from a PeerSet and
+// the round-trip times of the peers, and excludes any peer identified by
+// selfID.
+func newLatencyPeerSelector(peerSet *peers.PeerSet, selfID uint32, latencies *latencies) *latencyPeerSelector {
+	return &latencyPeerSelector{
+		randomPeerSelector: newRandomPeerSelector(peerSet, selfID),
+		latencies:          latencies,
+		skipped:            make(map[uint32]int),
+	}
+}
+
+// next returns the next peer.
+func (ps *latencyPeerSelector) next() *peers.Peer {
+	if len(ps.selectablePeersSlice) == 0 {
+		return nil
+	}
+
+	// Like the randomPeerSelector, avoid selecting the last peer twice in a
+	// row
+	candidates := ps.selectablePeersSlice
+	if len(candidates) > 1 {
+		candidates = make([]uint32, 0, len(ps.selectablePeersSlice))
+		for _, pid := range ps.selectablePeersSlice {
+			if pid != ps.last {
+				candidates = append(candidates, pid)
+			}
+		}
+	}
+
+	nextID := ps.pick(candidates)
+
+	for _, pid := range ps.selectablePeersSlice {
+		ps.skipped[pid]++
+	}
+	ps.skipped[nextID] = 0
+
+	return ps.selectablePeersMap[nextID].peer
+}
+
+// pick returns the most starved candidate if there is one, and otherwise a
+// candidate selected at random according to its round-trip time.
+func (ps *latencyPeerSelector) pick(candidates []uint32) uint32 {
+	limit := starvationFactor * len(ps.selectablePeersSlice)
+
+	starved, maxSkipped := uint32(0), -1
+	for _, pid := range candidates {
+		if s := ps.skipped[pid]; s >= limit && s > maxSkipped {
+			starved, maxSkipped = pid, s
+		}
+	}
+	if maxSkipped >= 0 {
+		return starved
+	}
+
+	rtts := make([]time.Duration, len(candidates))
+	measured := []time.Duration{}
+	for i, pid := range candidates {
+		if rtt, ok := ps.latencies.get(pid); ok {
+			if rtt < minLatency {
+				rtt = minLatency
+			}
+			rtts[i] = rtt
+			measured = append(measured, rtt)
+		}
+	}
+
+	if len(measured) == 0 {
+		return candidates[rand.Intn(len(candidates))]
+	}
+
+	sort.Slice(measured, func(i, j int) bool { return measured[i] < measured[j] })
+	median := measured[len(measured)/2]
+
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, rtt := range rtts {
+		if rtt == 0 {
+			rtt = median
+		}
+		weights[i] = 1 / rtt.Seconds()
+		total += weights[i]
+	}
+
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return candidates[i]
+		}
+		r -= w
+	}
+
+	return candidates[len(candidates)-1]
+}
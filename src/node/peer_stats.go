@@ -0,0 +1,254 @@
+package node
+
+import (
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/version"
+)
+
+// PeerStats reports the connectivity of the node with a peer, and how far the
+// peer has advanced in the hashgraph, to help diagnose which peers are holding
+// back consensus.
+type PeerStats struct {
+	ID      uint32 `json:"id"`
+	Moniker string `json:"moniker"`
+	NetAddr string `json:"net_addr"`
+
+	// Version is the version of Babble run by the peer, and ProtocolVersion
+	// the latest version of the protocol that it speaks, as reported in its
+	// last RPC. They are empty until the first RPC with the peer, and for
+	// peers that predate version negotiation.
+	Version         string `json:"version,omitempty"`
+	ProtocolVersion int    `json:"protocol_version,omitempty"`
+
+	// LastSync is the time of the last successful gossip with the peer. It is
+	// nil if the node has never gossiped successfully with the peer.
+	LastSync *time.Time `json:"last_sync"`
+
+	// RTT is the duration, in milliseconds, of the last successful
+	// SyncRequest to the peer.
+	RTT float64 `json:"rtt_ms"`
+
+	// Latency is the moving average, in milliseconds, of the round-trip times
+	// of the pings to the peer. It is 0 until a ping succeeds, and when
+	// pings are disabled.
+	Latency float64 `json:"latency_ms,omitempty"`
+
+	// LastKeepalive is the time of the last keepalive answered by the
+	// transport of the peer, and KeepaliveRTT its round-trip time, in
+	// milliseconds. KeepaliveError is the error of the last keepalive, if it
+	// failed. They are empty when keepalives are disabled.
+	LastKeepalive  *time.Time `json:"last_keepalive,omitempty"`
+	KeepaliveRTT   float64    `json:"keepalive_rtt_ms,omitempty"`
+	KeepaliveError string     `json:"keepalive_error,omitempty"`
+
+	// EventsReceived and EventsSent count the events exchanged with the peer,
+	// in both outgoing and incoming syncs.
+	EventsReceived int `json:"events_received"`
+	EventsSent     int `json:"events_sent"`
+
+	// Failures counts the failed gossip attempts with the peer, and
+	// ConsecutiveFailures the ones since the last successful gossip.
+	Failures            int    `json:"failures"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty"`
+
+	// Known contains the last-known heights of the peer, ie. the index of the
+	// last event of each participant that the peer reported knowing about in
+	// its last sync. Lag is the number of events known by this node, but not
+	// by the peer, according to Known.
+	Known map[uint32]int `json:"known"`
+	Lag   int            `json:"lag"`
+
+	// BytesIn and BytesOut count the bytes received from, and sent to, the
+	// peer in the current hour. QuotaExceeded is true if they exceed the peer
+	// quota, and the peer is throttled or banned until the next hour.
+	BytesIn       uint64 `json:"bytes_in"`
+	BytesOut      uint64 `json:"bytes_out"`
+	QuotaExceeded bool   `json:"quota_exceeded,omitempty"`
+}
+
+// GetPeerStats returns the PeerStats of the node's current peers, excluding
+// itself.
+func (n *Node) GetPeerStats() []PeerStats {
+	n.coreLock.RLock()
+	known := n.core.knownEvents()
+	peers := n.core.peers.Peers
+	n.coreLock.RUnlock()
+
+	now := n.clock.Now()
+
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	res := []PeerStats{}
+
+	for _, p := range peers {
+		if p.ID() == n.GetID() {
+			continue
+		}
+
+		var ps PeerStats
+		if s, ok := n.peerStats[p.ID()]; ok {
+			ps = *s
+			ps.Known = make(map[uint32]int, len(s.Known))
+			for id, index := range s.Known {
+				ps.Known[id] = index
+			}
+		}
+
+		ps.ID = p.ID()
+		ps.Moniker = p.Moniker
+		ps.NetAddr = p.NetAddr
+
+		if rtt, ok := n.latencies.get(p.ID()); ok {
+			ps.Latency = float64(rtt) / float64(time.Millisecond)
+		}
+
+		ps.BytesOut, ps.BytesIn, ps.QuotaExceeded = n.peerTraffic.usage(p.ID(), now)
+
+		if ps.Known != nil {
+			for id, index := range known {
+				if d := index - ps.Known[id]; d > 0 {
+					ps.Lag += d
+				}
+			}
+		}
+
+		res = append(res, ps)
+	}
+
+	return res
+}
+
+// peerStatsFor returns the PeerStats of a peer, creating it if needed. It must
+// be called with the peerStatsLock held.
+func (n *Node) peerStatsFor(id uint32) *PeerStats {
+	ps, ok := n.peerStats[id]
+	if !ok {
+		ps = &PeerStats{ID: id}
+		n.peerStats[id] = ps
+	}
+	return ps
+}
+
+// recordGossip records the outcome of a gossip attempt with a peer.
+func (n *Node) recordGossip(id uint32, err error) {
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	ps := n.peerStatsFor(id)
+
+	n.syncRequests++
+
+	if err != nil {
+		n.syncErrors++
+		ps.Failures++
+		ps.ConsecutiveFailures++
+		ps.LastError = err.Error()
+		return
+	}
+
+	now := n.clock.Now()
+	ps.LastSync = &now
+	ps.ConsecutiveFailures = 0
+}
+
+// recordKeepalive records the outcome of a keepalive to a peer.
+func (n *Node) recordKeepalive(id uint32, rtt time.Duration, err error) {
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	ps := n.peerStatsFor(id)
+
+	if err != nil {
+		ps.KeepaliveError = err.Error()
+		return
+	}
+
+	now := n.clock.Now()
+	ps.LastKeepalive = &now
+	ps.KeepaliveRTT = float64(rtt) / float64(time.Millisecond)
+	ps.KeepaliveError = ""
+}
+
+// recordProtocol records the Protocol reported by a peer in an RPC.
+func (n *Node) recordProtocol(id uint32, protocol version.Protocol) {
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	ps := n.peerStatsFor(id)
+
+	ps.Version = protocol.Babble
+	ps.ProtocolVersion = protocol.Version
+}
+
+// recordSyncResponse records the round-trip time of a SyncRequest to a peer,
+// along with the events that it returned and the peer's known events.
+func (n *Node) recordSyncResponse(id uint32, rtt time.Duration, events int, known map[uint32]int) {
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	ps := n.peerStatsFor(id)
+
+	ps.RTT = float64(rtt) / float64(time.Millisecond)
+	ps.EventsReceived += events
+	ps.Known = known
+}
+
+// recordSyncRequest records a SyncRequest from a peer, with the events that it
+// reported knowing about, and the number of events sent to it in response.
+func (n *Node) recordSyncRequest(id uint32, known map[uint32]int, sent int) {
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	ps := n.peerStatsFor(id)
+
+	ps.EventsSent += sent
+	ps.Known = known
+}
+
+// lastKnown returns a copy of the known map reported by a peer in its last
+// sync, or nil if there was none.
+func (n *Node) lastKnown(id uint32) map[uint32]int {
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	ps, ok := n.peerStats[id]
+	if !ok || ps.Known == nil {
+		return nil
+	}
+
+	known := make(map[uint32]int, len(ps.Known))
+	for id, index := range ps.Known {
+		known[id] = index
+	}
+
+	return known
+}
+
+// recordEvents adds the events sent to, and received from, a peer in an
+// EagerSync.
+func (n *Node) recordEvents(id uint32, sent int, received int) {
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	ps := n.peerStatsFor(id)
+
+	ps.EventsSent += sent
+	ps.EventsReceived += received
+}
+
+// syncRate returns the ratio of successful gossip attempts.
+func (n *Node) syncRate() float64 {
+	n.peerStatsLock.Lock()
+	defer n.peerStatsLock.Unlock()
+
+	var syncErrorRate float64
+
+	if n.syncRequests != 0 {
+		syncErrorRate = float64(n.syncErrors) / float64(n.syncRequests)
+	}
+
+	return 1 - syncErrorRate
+}
@@ -0,0 +1,86 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestPeerStats(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 100000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+
+	if err := gossip(nodes, 5, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range nodes {
+		peerStats := n.GetPeerStats()
+
+		if len(peerStats) != len(peers.Peers)-1 {
+			t.Fatalf("%s should report %d peers, not %d", n.core.validator.Moniker, len(peers.Peers)-1, len(peerStats))
+		}
+
+		received := 0
+		for _, ps := range peerStats {
+			if ps.ID == n.GetID() {
+				t.Fatalf("%s should not report stats about itself", n.core.validator.Moniker)
+			}
+			if ps.Moniker == "" || ps.NetAddr == "" {
+				t.Fatalf("peer %d should have a moniker and an address", ps.ID)
+			}
+			received += ps.EventsReceived
+		}
+
+		if received == 0 {
+			t.Fatalf("%s should have received events from its peers", n.core.validator.Moniker)
+		}
+
+		stats := n.Stats()
+		if stats.SyncRequests == 0 {
+			t.Fatalf("%s should have counted its gossip attempts", n.core.validator.Moniker)
+		}
+	}
+}
+
+func TestRecordGossip(t *testing.T) {
+	n := &Node{
+		peerStats: make(map[uint32]*PeerStats),
+		clock:     common.RealClock{},
+	}
+
+	n.recordSyncResponse(1, 20*time.Millisecond, 5, map[uint32]int{1: 10, 2: 3})
+	n.recordGossip(1, nil)
+	n.recordGossip(1, fmt.Errorf("timeout"))
+	n.recordGossip(1, fmt.Errorf("timeout"))
+
+	ps := n.peerStats[1]
+
+	if ps.LastSync == nil {
+		t.Fatal("LastSync should be set after a successful gossip")
+	}
+	if ps.RTT != 20 {
+		t.Fatalf("RTT should be 20 ms, not %f", ps.RTT)
+	}
+	if ps.EventsReceived != 5 {
+		t.Fatalf("EventsReceived should be 5, not %d", ps.EventsReceived)
+	}
+	if ps.Failures != 2 || ps.ConsecutiveFailures != 2 || ps.LastError != "timeout" {
+		t.Fatalf("peer should have 2 consecutive failures, not %+v", ps)
+	}
+
+	n.recordGossip(1, nil)
+
+	if ps.ConsecutiveFailures != 0 || ps.Failures != 2 {
+		t.Fatalf("a successful gossip should reset the consecutive failures, not %+v", ps)
+	}
+
+	if rate := n.syncRate(); rate != 0.5 {
+		t.Fatalf("sync rate should be 0.5, not %f", rate)
+	}
+}
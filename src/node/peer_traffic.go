@@ -0,0 +1,160 @@
+package node
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/sirupsen/logrus"
+)
+
+// peerThrottleInterval is the minimum period between two RPCs with a peer that
+// is throttled for exceeding the peer quota.
+const peerThrottleInterval = time.Second
+
+// peerTraffic counts the bytes exchanged with each peer, as reported by the
+// transport, over the same hour-long windows as the data budget. A peer which
+// exceeds the quota is throttled, or banned, until the end of the window. A
+// quota of 0 is never exceeded.
+type peerTraffic struct {
+	sync.Mutex
+
+	quota int64
+	ban   bool
+
+	windowStart time.Time
+	peers       map[uint32]*peerTrafficCount
+}
+
+// peerTrafficCount is the traffic with a peer in the current window.
+type peerTrafficCount struct {
+	sent     uint64
+	received uint64
+
+	// exceeded records that the peer went over the quota, and lastRPC is the
+	// time of the last RPC allowed with the peer since then.
+	exceeded bool
+	lastRPC  time.Time
+}
+
+func newPeerTraffic(quota int64, action string, now time.Time) *peerTraffic {
+	return &peerTraffic{
+		quota:       quota,
+		ban:         action == "ban",
+		windowStart: now,
+		peers:       make(map[uint32]*peerTrafficCount),
+	}
+}
+
+// action returns the action taken against the peers which exceed the quota.
+func (t *peerTraffic) action() string {
+	if t.ban {
+		return "ban"
+	}
+	return "throttle"
+}
+
+// resetWindow starts a new window if the current one is over. It must be
+// called with the lock held.
+func (t *peerTraffic) resetWindow(now time.Time) {
+	if now.Sub(t.windowStart) >= dataBudgetWindow {
+		t.windowStart = now
+		t.peers = make(map[uint32]*peerTrafficCount)
+	}
+}
+
+// add counts the bytes sent to, and received from, a peer. It returns true the
+// first time the peer exceeds the quota in the window, so that it is only
+// reported once.
+func (t *peerTraffic) add(id uint32, sent, received uint64, now time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	t.resetWindow(now)
+
+	c, ok := t.peers[id]
+	if !ok {
+		c = &peerTrafficCount{}
+		t.peers[id] = c
+	}
+
+	c.sent += sent
+	c.received += received
+
+	if t.quota <= 0 || c.exceeded || int64(c.sent+c.received) < t.quota {
+		return false
+	}
+
+	c.exceeded = true
+
+	return true
+}
+
+// allow returns an error if an RPC with the peer is not allowed now, because
+// it exceeded the quota in the current window.
+func (t *peerTraffic) allow(id uint32, now time.Time) error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.resetWindow(now)
+
+	c, ok := t.peers[id]
+	if !ok || !c.exceeded {
+		return nil
+	}
+
+	if t.ban {
+		return fmt.Errorf("Peer quota exceeded")
+	}
+
+	if now.Sub(c.lastRPC) < peerThrottleInterval {
+		return fmt.Errorf("Throttled")
+	}
+
+	c.lastRPC = now
+
+	return nil
+}
+
+// usage returns the traffic with a peer in the current window, and whether it
+// exceeded the quota.
+func (t *peerTraffic) usage(id uint32, now time.Time) (sent, received uint64, exceeded bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.resetWindow(now)
+
+	c, ok := t.peers[id]
+	if !ok {
+		return 0, 0, false
+	}
+
+	return c.sent, c.received, c.exceeded
+}
+
+// recordTraffic is the traffic hook of the transport. It accounts the traffic
+// of an RPC to the peer, and reports the peers which exceed the quota.
+func (n *Node) recordTraffic(rpc net.RPCTraffic) {
+	peer := strconv.FormatUint(uint64(rpc.PeerID), 10)
+	metrics.PeerTraffic.WithLabelValues(peer, "out").Add(float64(rpc.Sent))
+	metrics.PeerTraffic.WithLabelValues(peer, "in").Add(float64(rpc.Received))
+
+	if !n.peerTraffic.add(rpc.PeerID, rpc.Sent, rpc.Received, n.clock.Now()) {
+		return
+	}
+
+	action := n.peerTraffic.action()
+
+	n.logger.WithFields(logrus.Fields{
+		"peer":   rpc.PeerID,
+		"quota":  n.conf.PeerQuota,
+		"action": action,
+	}).Warn("Peer quota exceeded")
+
+	metrics.PeerQuotaViolations.WithLabelValues(action).Inc()
+
+	n.core.notifier.publishError(fmt.Errorf("Peer %d exceeded the peer quota => %s until the next hour", rpc.PeerID, action))
+}
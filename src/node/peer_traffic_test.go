@@ -0,0 +1,66 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerTraffic(t *testing.T) {
+	start := time.Unix(0, 0)
+	pt := newPeerTraffic(1000, "throttle", start)
+
+	now := start.Add(time.Second)
+
+	if pt.add(1, 400, 500, now) {
+		t.Fatal("Peer 1 should not exceed the quota yet")
+	}
+	if !pt.add(1, 50, 50, now) {
+		t.Fatal("Peer 1 should exceed the quota")
+	}
+	if pt.add(1, 10, 10, now) {
+		t.Fatal("The exceeded quota should only be reported once")
+	}
+
+	if sent, received, exceeded := pt.usage(1, now); sent != 460 || received != 560 || !exceeded {
+		t.Fatalf("Peer 1 should have used 460/560 bytes and exceeded the quota, not %d/%d, %v", sent, received, exceeded)
+	}
+
+	// Throttled peers get one RPC per interval, the others are not limited
+	if err := pt.allow(1, now); err != nil {
+		t.Fatalf("The first RPC with a throttled peer should be allowed: %v", err)
+	}
+	if err := pt.allow(1, now.Add(peerThrottleInterval/2)); err == nil {
+		t.Fatal("A throttled peer should not be allowed another RPC within the interval")
+	}
+	if err := pt.allow(1, now.Add(peerThrottleInterval)); err != nil {
+		t.Fatalf("A throttled peer should be allowed an RPC after the interval: %v", err)
+	}
+	if err := pt.allow(2, now); err != nil {
+		t.Fatalf("Peer 2 should not be limited: %v", err)
+	}
+
+	// The next window starts afresh
+	now = start.Add(dataBudgetWindow)
+
+	if err := pt.allow(1, now); err != nil {
+		t.Fatalf("Peer 1 should not be limited in the next window: %v", err)
+	}
+	if sent, received, _ := pt.usage(1, now); sent != 0 || received != 0 {
+		t.Fatalf("The traffic should be reset in the next window, not %d/%d", sent, received)
+	}
+
+	// Banned peers are refused until the end of the window
+	pt = newPeerTraffic(1000, "ban", start)
+
+	pt.add(1, 1000, 0, now)
+	if err := pt.allow(1, now.Add(time.Minute)); err == nil {
+		t.Fatal("A banned peer should not be allowed any RPC")
+	}
+
+	// Without a quota, the traffic is counted but never exceeded
+	pt = newPeerTraffic(0, "ban", start)
+
+	if pt.add(1, 1<<40, 0, now) || pt.allow(1, now) != nil {
+		t.Fatal("The quota should never be exceeded when it is 0")
+	}
+}
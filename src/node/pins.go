@@ -0,0 +1,31 @@
+package node
+
+// PinBlock protects a committed block, and the frame of its round-received,
+// from being evicted from the store, so that it remains available, for example
+// as an audit checkpoint. The InmemStore otherwise forgets old blocks when its
+// caches are full. With a BadgerStore, the pin is persisted.
+func (n *Node) PinBlock(index int) error {
+	if err := n.core.hg.Store.PinBlock(index); err != nil {
+		return err
+	}
+
+	n.logger.WithField("block", index).Info("Pinned block")
+
+	return nil
+}
+
+// UnpinBlock lifts the protection of a pinned block.
+func (n *Node) UnpinBlock(index int) error {
+	if err := n.core.hg.Store.UnpinBlock(index); err != nil {
+		return err
+	}
+
+	n.logger.WithField("block", index).Info("Unpinned block")
+
+	return nil
+}
+
+// GetPinnedBlocks returns the sorted indexes of the pinned blocks.
+func (n *Node) GetPinnedBlocks() ([]int, error) {
+	return n.core.hg.Store.PinnedBlocks()
+}
@@ -0,0 +1,207 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/sirupsen/logrus"
+)
+
+// ReplayedBlock is a Block committed during a replay, compared with the Block
+// of the same index in the database.
+type ReplayedBlock struct {
+	Index                int
+	RoundReceived        int
+	Transactions         int
+	InternalTransactions int
+
+	// Divergence describes how the Block differs from the one in the
+	// database. It is empty if they are identical.
+	Divergence string
+}
+
+// Replay re-runs consensus on the Events of a database, from the genesis
+// peer-set, and commits the resulting Blocks to an AppProxy, without modifying
+// the database. Every Block, and the response of the AppProxy, is compared with
+// the Block of the same index in the database, so that a replay detects
+// changes in the ordering of Events, like an ordering bug or a faulty store
+// migration. If appProxy is nil, the Blocks are committed to a no-op AppProxy
+// which replies with the state hashes and receipts recorded in the database. If
+// untilBlock is not negative, the replay stops after the Block with that index
+// is committed. The thresholds and the peer-set interval must be the ones of
+// the genesis document.
+func Replay(store *hg.BadgerStore, appProxy proxy.AppProxy, untilBlock int, thresholds peers.Thresholds, peerSetInterval int, logger *logrus.Entry) ([]ReplayedBlock, error) {
+	genesisPeers, err := store.PersistedPeerSet(0)
+	if err != nil {
+		return nil, fmt.Errorf("No genesis peer-set in the database: %v", err)
+	}
+
+	// The replay does not belong to the validator-set, so it never signs
+	// Blocks.
+	key, err := keys.GenerateECDSAKey()
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []ReplayedBlock{}
+
+	commitCallback := func(block hg.Block) (proxy.CommitResponse, error) {
+		replayed := ReplayedBlock{
+			Index:                block.Index(),
+			RoundReceived:        block.RoundReceived(),
+			Transactions:         len(block.Transactions()),
+			InternalTransactions: len(block.InternalTransactions()),
+		}
+
+		persisted, err := store.PersistedBlock(block.Index())
+		if err != nil {
+			replayed.Divergence = "not in the database"
+		} else {
+			replayed.Divergence = blockDivergence(&block, persisted)
+		}
+
+		var response proxy.CommitResponse
+
+		switch {
+		case appProxy != nil:
+			response, err = appProxy.CommitBlock(block)
+		case persisted != nil:
+			response = proxy.CommitResponse{
+				StateHash:                   persisted.StateHash(),
+				InternalTransactionReceipts: persisted.InternalTransactionReceipts(),
+			}
+		default:
+			response, err = proxy.DummyCommitCallback(block)
+		}
+
+		if err != nil {
+			replayed.Divergence = appendDivergence(replayed.Divergence, fmt.Sprintf("commit failed: %v", err))
+		} else if persisted != nil {
+			replayed.Divergence = appendDivergence(replayed.Divergence, responseDivergence(response, persisted))
+		}
+
+		if replayed.Divergence != "" {
+			logger.WithFields(logrus.Fields{
+				"block":      replayed.Index,
+				"divergence": replayed.Divergence,
+			}).Warn("Replayed block differs from the database")
+		}
+
+		blocks = append(blocks, replayed)
+
+		return response, err
+	}
+
+	core := newCore(NewValidator(key, "replay"),
+		genesisPeers,
+		genesisPeers,
+		store,
+		commitCallback,
+		true,
+		logger)
+
+	core.hg.SetThresholds(thresholds)
+	core.peerSetInterval = peerSetInterval
+
+	done := func() bool {
+		return untilBlock >= 0 &&
+			len(blocks) > 0 &&
+			blocks[len(blocks)-1].Index >= untilBlock
+	}
+
+	if err := core.hg.Replay(done); err != nil {
+		return blocks, err
+	}
+
+	return blocks, nil
+}
+
+// blockDivergence compares the content of a replayed Block, which does not
+// have a state hash and receipts yet, with a Block from the database.
+func blockDivergence(replayed *hg.Block, persisted *hg.Block) string {
+	divergences := []string{}
+
+	if replayed.RoundReceived() != persisted.RoundReceived() {
+		divergences = append(divergences,
+			fmt.Sprintf("round received %d instead of %d", replayed.RoundReceived(), persisted.RoundReceived()))
+	}
+
+	if !bytes.Equal(replayed.FrameHash(), persisted.FrameHash()) {
+		divergences = append(divergences, "different frame hash")
+	}
+
+	if !bytes.Equal(replayed.PeersHash(), persisted.PeersHash()) {
+		divergences = append(divergences, "different peers hash")
+	}
+
+	if !equalTransactions(replayed.Transactions(), persisted.Transactions()) {
+		divergences = append(divergences,
+			fmt.Sprintf("different transactions (%d instead of %d)", len(replayed.Transactions()), len(persisted.Transactions())))
+	}
+
+	if len(replayed.InternalTransactions()) != len(persisted.InternalTransactions()) {
+		divergences = append(divergences,
+			fmt.Sprintf("%d internal transactions instead of %d", len(replayed.InternalTransactions()), len(persisted.InternalTransactions())))
+	} else {
+		for i, itx := range replayed.InternalTransactions() {
+			if itx.HashString() != persisted.InternalTransactions()[i].HashString() {
+				divergences = append(divergences, "different internal transactions")
+				break
+			}
+		}
+	}
+
+	return strings.Join(divergences, ", ")
+}
+
+// responseDivergence compares the response of the AppProxy to a replayed Block
+// with the state hash and receipts of the Block from the database.
+func responseDivergence(response proxy.CommitResponse, persisted *hg.Block) string {
+	divergences := []string{}
+
+	if !bytes.Equal(response.StateHash, persisted.StateHash()) {
+		divergences = append(divergences, "different state hash")
+	}
+
+	receipts := persisted.InternalTransactionReceipts()
+	if len(response.InternalTransactionReceipts) != len(receipts) {
+		divergences = append(divergences,
+			fmt.Sprintf("%d receipts instead of %d", len(response.InternalTransactionReceipts), len(receipts)))
+	} else {
+		for i, r := range response.InternalTransactionReceipts {
+			if r.Accepted != receipts[i].Accepted {
+				divergences = append(divergences, "different receipts")
+				break
+			}
+		}
+	}
+
+	return strings.Join(divergences, ", ")
+}
+
+func appendDivergence(divergence string, other string) string {
+	if divergence == "" {
+		return other
+	}
+	if other == "" {
+		return divergence
+	}
+	return divergence + ", " + other
+}
+
+func equalTransactions(a [][]byte, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
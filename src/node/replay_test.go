@@ -0,0 +1,68 @@
+package node
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/dummy"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/proxy"
+)
+
+func TestReplay(t *testing.T) {
+	os.RemoveAll("test_data")
+	os.Mkdir("test_data", os.ModeDir|0777)
+	defer os.RemoveAll("test_data")
+
+	keys, peers := initPeers(t, 4)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 100000, 1000, 10, false, "badger", 10*time.Millisecond, false, "", t)
+
+	if err := gossip(nodes, 5, true); err != nil {
+		t.Fatal(err)
+	}
+
+	path := nodes[0].core.hg.Store.StorePath()
+	lastBlock := nodes[0].GetLastBlockIndex()
+
+	replay := func(appProxy proxy.AppProxy, untilBlock int) []ReplayedBlock {
+		store, err := hg.NewReadOnlyBadgerStore(1000, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		blocks, err := Replay(store, appProxy, untilBlock, nodes[0].core.hg.Thresholds(), 0, common.NewTestEntry(t, common.TestLogLevel))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, b := range blocks {
+			if b.Index != i {
+				t.Fatalf("Block %d should have index %d", b.Index, i)
+			}
+			if b.Divergence != "" {
+				t.Fatalf("Block %d differs from the database: %s", b.Index, b.Divergence)
+			}
+		}
+
+		return blocks
+	}
+
+	// Without an AppProxy, the recorded responses are used
+	if blocks := replay(nil, -1); len(blocks) < lastBlock+1 {
+		t.Fatalf("The replay should commit at least %d blocks, not %d", lastBlock+1, len(blocks))
+	}
+
+	// A new instance of the App reaches the same states
+	if blocks := replay(dummy.NewInmemDummyClient(common.NewTestEntry(t, common.TestLogLevel)), -1); len(blocks) < lastBlock+1 {
+		t.Fatalf("The replay should commit at least %d blocks, not %d", lastBlock+1, len(blocks))
+	}
+
+	if blocks := replay(nil, 2); len(blocks) != 3 {
+		t.Fatalf("The replay should stop after block 2, not %d", len(blocks)-1)
+	}
+}
@@ -0,0 +1,110 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// sentEvents records the Events recently sent to each peer, so that they are
+// not sent again while the first copy is still in flight. Without it, a
+// SyncResponse computed from the known map of a peer resends the Events it is
+// about to receive from another node, or from this node in a concurrent push.
+// A window of 0 disables the tracking.
+type sentEvents struct {
+	sync.Mutex
+
+	window time.Duration
+
+	// peers maps the ID of each peer to the hashes of the Events sent to it,
+	// and the time they were sent.
+	peers map[uint32]map[string]time.Time
+}
+
+func newSentEvents(window time.Duration) *sentEvents {
+	return &sentEvents{
+		window: window,
+		peers:  make(map[uint32]map[string]time.Time),
+	}
+}
+
+// setWindow changes the window. Disabling the tracking forgets all the
+// recorded Events.
+func (s *sentEvents) setWindow(window time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.window = window
+	if window <= 0 {
+		s.peers = make(map[uint32]map[string]time.Time)
+	}
+}
+
+// filter removes from events those that were sent to the peer in the last
+// window, and returns the remaining events with the number of skipped ones.
+// The order of the remaining events is preserved.
+func (s *sentEvents) filter(peerID uint32, events []*hg.Event, now time.Time) ([]*hg.Event, int) {
+	s.Lock()
+	defer s.Unlock()
+
+	sent, ok := s.peers[peerID]
+	if s.window <= 0 || !ok {
+		return events, 0
+	}
+
+	res := make([]*hg.Event, 0, len(events))
+	for _, ev := range events {
+		if t, ok := sent[ev.Hex()]; ok && now.Sub(t) < s.window {
+			continue
+		}
+		res = append(res, ev)
+	}
+
+	return res, len(events) - len(res)
+}
+
+// record marks events as sent to the peer, and forgets the Events sent to it
+// before the window.
+func (s *sentEvents) record(peerID uint32, events []*hg.Event, now time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.window <= 0 {
+		return
+	}
+
+	sent, ok := s.peers[peerID]
+	if !ok {
+		sent = make(map[string]time.Time)
+		s.peers[peerID] = sent
+	}
+
+	for hash, t := range sent {
+		if now.Sub(t) >= s.window {
+			delete(sent, hash)
+		}
+	}
+
+	for _, ev := range events {
+		sent[ev.Hex()] = now
+	}
+}
+
+// skipSentEvents removes from an event diff the events recently sent to the
+// peer.
+func (n *Node) skipSentEvents(peerID uint32, eventDiff []*hg.Event) []*hg.Event {
+	eventDiff, skipped := n.sentEvents.filter(peerID, eventDiff, n.clock.Now())
+
+	if skipped > 0 {
+		metrics.EventsSkipped.Add(float64(skipped))
+		n.logger.WithFields(logrus.Fields{
+			"peer_id": peerID,
+			"skipped": skipped,
+		}).Debug("Skipping events recently sent")
+	}
+
+	return eventDiff
+}
@@ -0,0 +1,48 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+func TestSentEvents(t *testing.T) {
+	events := make([]*hg.Event, 4)
+	for i := range events {
+		events[i] = hg.NewEvent(nil, nil, nil, []string{"", ""}, []byte("creator"), i)
+	}
+
+	now := time.Now()
+	s := newSentEvents(time.Second)
+
+	s.record(1, events[:2], now)
+
+	res, skipped := s.filter(1, events, now.Add(500*time.Millisecond))
+	if skipped != 2 || len(res) != 2 || res[0] != events[2] || res[1] != events[3] {
+		t.Fatalf("The events sent to peer 1 should be skipped, skipped: %d", skipped)
+	}
+
+	// Other peers are not affected
+	if _, skipped := s.filter(2, events, now); skipped != 0 {
+		t.Fatalf("No events should be skipped for peer 2, not %d", skipped)
+	}
+
+	// The events are sent again after the window
+	if _, skipped := s.filter(1, events, now.Add(time.Second)); skipped != 0 {
+		t.Fatalf("No events should be skipped after the window, not %d", skipped)
+	}
+
+	// Recording prunes the expired events
+	s.record(1, events[3:], now.Add(2*time.Second))
+	if len(s.peers[1]) != 1 {
+		t.Fatalf("Peer 1 should have 1 recorded event, not %d", len(s.peers[1]))
+	}
+
+	// A window of 0 disables the tracking
+	s.setWindow(0)
+	s.record(1, events, now)
+	if _, skipped := s.filter(1, events, now); skipped != 0 {
+		t.Fatalf("No events should be skipped when disabled, not %d", skipped)
+	}
+}
@@ -0,0 +1,134 @@
+package node
+
+import (
+	"fmt"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/sirupsen/logrus"
+)
+
+// initSnapshotDelta checks that the AppProxy can provide and restore the
+// differences between snapshots, when the snapshot-delta option is set.
+func (n *Node) initSnapshotDelta() error {
+	if _, ok := n.proxy.(proxy.SnapshotDeltaProvider); !ok {
+		return fmt.Errorf("snapshot-delta requires an AppProxy which provides snapshot deltas")
+	}
+
+	return nil
+}
+
+// snapshotDeltaProvider returns the AppProxy as a SnapshotDeltaProvider, or nil
+// if the snapshot-delta option is not set, or the AppProxy does not implement
+// it.
+func (n *Node) snapshotDeltaProvider() proxy.SnapshotDeltaProvider {
+	if !n.conf.SnapshotDelta {
+		return nil
+	}
+
+	provider, _ := n.proxy.(proxy.SnapshotDeltaProvider)
+
+	return provider
+}
+
+// snapshotBase returns the index of the last Block of the node, to which the
+// App is expected to correspond, for the peers to send the difference from its
+// snapshot in their FastForwardResponses. It returns nil if the node has no
+// Block, or if it must request a full snapshot, because the App failed to
+// restore the last difference, or because snapshots are verified before they
+// are restored, which is not possible with a difference.
+func (n *Node) snapshotBase() *int {
+	if n.snapshotDeltaProvider() == nil || n.noSnapshotDelta || n.conf.VerifySnapshots {
+		return nil
+	}
+
+	n.coreLock.RLock()
+	base := n.core.hg.Store.LastBlockIndex()
+	n.coreLock.RUnlock()
+
+	if base < 0 {
+		return nil
+	}
+
+	return &base
+}
+
+// snapshotDeltaResponse returns a copy of a FastForwardResponse, which may be
+// cached, with the difference between the snapshot of the base Block and the
+// snapshot of the response. It returns the response unchanged if the App
+// cannot provide the difference, so that the peer receives the full snapshot.
+func (n *Node) snapshotDeltaResponse(resp *net.FastForwardResponse, base int) *net.FastForwardResponse {
+	provider := n.snapshotDeltaProvider()
+	if provider == nil || base < 0 || base >= resp.Block.Index() {
+		return resp
+	}
+
+	delta, err := provider.SnapshotDelta(base, resp.Block.Index())
+	if err != nil {
+		n.logger.WithFields(logrus.Fields{
+			"from":  base,
+			"to":    resp.Block.Index(),
+			"error": err,
+		}).Warn("Cannot get Snapshot delta => sending full Snapshot")
+		return resp
+	}
+
+	deltaResp := *resp
+	deltaResp.Snapshot = delta
+	deltaResp.SnapshotDeltaBase = &base
+
+	return &deltaResp
+}
+
+// restoreFastForward resets the application and the hashgraph from a
+// FastForwardResponse, which holds either a full snapshot, or the difference
+// from the snapshot of an older Block. If the App fails to apply a difference,
+// the next FastForwardRequests ask for the full snapshot.
+func (n *Node) restoreFastForward(resp *net.FastForwardResponse) error {
+	if resp.SnapshotDeltaBase == nil {
+		return n.restore(&resp.Block, &resp.Frame, resp.Snapshot)
+	}
+
+	provider := n.snapshotDeltaProvider()
+	if provider == nil {
+		return fmt.Errorf("Restoring App from Snapshot delta: snapshot-delta is disabled")
+	}
+
+	if n.conf.VerifySnapshots {
+		return fmt.Errorf("Restoring App from Snapshot delta: a delta cannot be verified")
+	}
+
+	if err := provider.RestoreDelta(*resp.SnapshotDeltaBase, resp.Snapshot); err != nil {
+		n.logger.WithError(err).Error("Restoring App from Snapshot delta")
+		n.noSnapshotDelta = true
+		return fmt.Errorf("Restoring App from Snapshot delta: %v", err)
+	}
+
+	n.logger.WithFields(logrus.Fields{
+		"from":  *resp.SnapshotDeltaBase,
+		"to":    resp.Block.Index(),
+		"delta": len(resp.Snapshot),
+	}).Debug("Restored App from Snapshot delta")
+
+	return n.restoreHashgraph(&resp.Block, &resp.Frame)
+}
+
+// restoreHashgraph resets the hashgraph from a block and its frame, once the
+// application was restored.
+func (n *Node) restoreHashgraph(block *hg.Block, frame *hg.Frame) error {
+	n.coreLock.Lock()
+	err := n.core.fastForward(block, frame)
+	n.coreLock.Unlock()
+	if err != nil {
+		n.logger.WithError(err).Error("Fast Forwarding Hashgraph")
+		return fmt.Errorf("FastForward: %v", err)
+	}
+
+	err = n.core.processAcceptedInternalTransactions(block.Index(), block.RoundReceived(), block.InternalTransactionReceipts())
+	if err != nil {
+		n.logger.WithError(err).Error("Processing AnchorBlock InternalTransactionReceipts")
+	}
+
+	return nil
+}
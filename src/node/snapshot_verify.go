@@ -0,0 +1,59 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/proxy"
+)
+
+// initVerifySnapshots checks that the AppProxy can hash snapshots, when the
+// verify-snapshots option is set.
+func (n *Node) initVerifySnapshots() error {
+	if _, ok := n.proxy.(proxy.SnapshotHasher); !ok {
+		return fmt.Errorf("verify-snapshots requires an AppProxy which hashes snapshots")
+	}
+
+	return nil
+}
+
+// verifySnapshot checks, when the verify-snapshots option is set, that a
+// snapshot matches the state hash of its Block, before the App restores it.
+// The Block is checked against the validators that the node knows for its
+// round first, because its state hash is only trusted once enough of them
+// signed it. The validators of the Frame cannot be used, because the peer that
+// sends the snapshot also sends the Frame.
+func (n *Node) verifySnapshot(block *hg.Block, frame *hg.Frame, snapshot []byte) error {
+	if !n.conf.VerifySnapshots {
+		return nil
+	}
+
+	hasher, ok := n.proxy.(proxy.SnapshotHasher)
+	if !ok {
+		return fmt.Errorf("Verifying Snapshot: the AppProxy does not hash snapshots")
+	}
+
+	n.coreLock.RLock()
+	validators, err := n.core.hg.Store.GetPeerSet(block.RoundReceived())
+	if err == nil {
+		err = n.core.checkAnchor(block, frame, validators)
+	}
+	n.coreLock.RUnlock()
+	if err != nil {
+		return fmt.Errorf("Verifying Snapshot: %v", err)
+	}
+
+	stateHash, err := hasher.HashSnapshot(snapshot)
+	if err != nil {
+		return fmt.Errorf("Verifying Snapshot: %v", err)
+	}
+
+	if !bytes.Equal(stateHash, block.StateHash()) {
+		return fmt.Errorf("Verifying Snapshot: the snapshot does not match the state hash of block %d", block.Index())
+	}
+
+	n.logger.WithField("block", block.Index()).Debug("Verified Snapshot")
+
+	return nil
+}
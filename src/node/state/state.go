@@ -96,6 +96,14 @@ func (b *Manager) GoFunc(f func()) {
 	}
 }
 
+// TrackFunc runs a function in the calling goroutine and counts it in the
+// waitgroup, without taking one of the WGLIMIT slots of GoFunc.
+func (b *Manager) TrackFunc(f func()) {
+	b.wg.Add(1)
+	defer b.wg.Done()
+	f()
+}
+
 // WaitRoutines waits for all the goroutines in the waitgroup.
 func (b *Manager) WaitRoutines() {
 	b.wg.Wait()
@@ -0,0 +1,202 @@
+package node
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/version"
+)
+
+// Stats is a snapshot of the internal state of a node.
+type Stats struct {
+	ID      uint32 `json:"id"`
+	Moniker string `json:"moniker"`
+	State   string `json:"state"`
+
+	// NetworkID is the hash of the genesis document, if there is one.
+	NetworkID string `json:"network_id,omitempty"`
+
+	// Version is the version of Babble, and ProtocolVersion the latest version
+	// of the protocol spoken with the peers. The versions of the peers are
+	// reported in their PeerStats.
+	Version         string `json:"version"`
+	ProtocolVersion int    `json:"protocol_version"`
+
+	// ActiveProtocolVersion is the version of the protocol agreed by the
+	// validators at the last consensus round.
+	ActiveProtocolVersion int `json:"active_protocol_version"`
+
+	// LastBlockIndex is -1 until the first block is committed.
+	LastBlockIndex int `json:"last_block_index"`
+
+	// LastRound is the last round of the hashgraph, decided or not.
+	LastRound int `json:"last_round"`
+
+	// LastConsensusRound is nil until the first round is decided.
+	LastConsensusRound *int `json:"last_consensus_round"`
+
+	// LastFrame is the round-received of the frame of the last block, or -1 if
+	// there are no blocks.
+	LastFrame int `json:"last_frame"`
+
+	// LastPeerChange is the round of the last change of the validator-set.
+	LastPeerChange int `json:"last_peer_change"`
+
+	NumPeers int `json:"num_peers"`
+
+	ConsensusEvents       int `json:"consensus_events"`
+	ConsensusTransactions int `json:"consensus_transactions"`
+	UndeterminedEvents    int `json:"undetermined_events"`
+
+	// RoundEvents is the number of events in the last committed round.
+	RoundEvents int `json:"round_events"`
+
+	TransactionPool         int `json:"transaction_pool"`
+	InternalTransactionPool int `json:"internal_transaction_pool"`
+
+	// OfflineTransactions is the number of transactions held in the offline
+	// log, in offline-mode, until the node is back in contact with the quorum.
+	OfflineTransactions int `json:"offline_transactions"`
+
+	// SyncRequests and SyncErrors count the gossip attempts of the node, and
+	// the ones that failed. SyncRate is the ratio of successful attempts.
+	SyncRequests int     `json:"sync_requests"`
+	SyncErrors   int     `json:"sync_errors"`
+	SyncRate     float64 `json:"sync_rate"`
+
+	// DataUsage is the number of bytes sent and received in the current hour of
+	// the data budget, and Metered is true in metered mode.
+	DataUsage int64 `json:"data_usage"`
+	Metered   bool  `json:"metered"`
+
+	// MemoryUsage is the memory used by the node, in bytes, at the last sample,
+	// MemoryBudget the maximum, and MemoryPressure is true while the node sheds
+	// load because the budget is almost exhausted.
+	MemoryUsage    int64 `json:"memory_usage"`
+	MemoryBudget   int64 `json:"memory_budget"`
+	MemoryPressure bool  `json:"memory_pressure"`
+
+	// Suspension is why, and since when, the node is suspended, or nil if it
+	// is not suspended. Suspensions counts the suspensions by reason since the
+	// node started.
+	Suspension  *Suspension           `json:"suspension,omitempty"`
+	Suspensions map[SuspendReason]int `json:"suspensions,omitempty"`
+
+	// EventsPerSecond and RoundsPerSecond are averaged since the node started.
+	EventsPerSecond float64 `json:"events_per_second"`
+	RoundsPerSecond float64 `json:"rounds_per_second"`
+
+	Time time.Time `json:"time"`
+}
+
+// Stats returns a snapshot of the internal state of the node. It only holds the
+// coreLock for reading, so it does not block other readers of the core.
+func (n *Node) Stats() Stats {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	now := n.clock.Now()
+	timeElapsed := now.Sub(n.start)
+
+	consensusEvents := n.core.getConsensusEventsCount()
+
+	lastConsensusRound := n.core.getLastConsensusRoundIndex()
+
+	var consensusRoundsPerSecond float64
+
+	if lastConsensusRound != nil {
+		consensusRoundsPerSecond = float64(*lastConsensusRound) / timeElapsed.Seconds()
+	}
+
+	activeProtocolVersion := initialProtocolVersion
+	if lastConsensusRound != nil {
+		activeProtocolVersion = n.core.upgrades.versionAt(*lastConsensusRound)
+	}
+
+	lastBlockIndex := n.core.getLastBlockIndex()
+
+	n.peerStatsLock.Lock()
+	syncRequests, syncErrors := n.syncRequests, n.syncErrors
+	n.peerStatsLock.Unlock()
+
+	memoryUsage, memoryBudget, memoryPressure := n.memoryBudget.status()
+
+	suspension, suspensions := n.suspensions.status()
+
+	lastFrame := -1
+	if lastBlockIndex >= 0 {
+		if block, err := n.core.hg.Store.GetBlock(lastBlockIndex); err == nil {
+			lastFrame = block.RoundReceived()
+		}
+	}
+
+	return Stats{
+		ID:                      n.core.validator.ID(),
+		Moniker:                 n.core.validator.Moniker,
+		State:                   n.GetState().String(),
+		NetworkID:               n.conf.NetworkID,
+		Version:                 version.Version,
+		ProtocolVersion:         version.ProtocolVersion,
+		ActiveProtocolVersion:   activeProtocolVersion,
+		LastBlockIndex:          lastBlockIndex,
+		LastRound:               n.core.hg.Store.LastRound(),
+		LastConsensusRound:      lastConsensusRound,
+		LastFrame:               lastFrame,
+		LastPeerChange:          n.core.lastPeerChangeRound,
+		NumPeers:                n.core.peerSelector.getPeers().Len(),
+		ConsensusEvents:         consensusEvents,
+		ConsensusTransactions:   n.core.getConsensusTransactionsCount(),
+		UndeterminedEvents:      len(n.core.getUndeterminedEvents()),
+		RoundEvents:             n.core.getLastCommitedRoundEventsCount(),
+		TransactionPool:         len(n.core.transactionPool),
+		InternalTransactionPool: len(n.core.internalTransactionPool),
+		OfflineTransactions:     n.offlineLog.len(),
+		SyncRequests:            syncRequests,
+		SyncErrors:              syncErrors,
+		SyncRate:                n.syncRate(),
+		DataUsage:               n.GetDataUsage(),
+		Metered:                 n.conf.Metered,
+		MemoryUsage:             memoryUsage,
+		MemoryBudget:            memoryBudget,
+		MemoryPressure:          memoryPressure,
+		Suspension:              suspension,
+		Suspensions:             suspensions,
+		EventsPerSecond:         float64(consensusEvents) / timeElapsed.Seconds(),
+		RoundsPerSecond:         consensusRoundsPerSecond,
+		Time:                    now,
+	}
+}
+
+// GetStats returns the Stats of the node as strings, in the legacy format of
+// the /stats endpoint. New code should use Stats instead.
+func (n *Node) GetStats() map[string]string {
+	return n.Stats().legacy()
+}
+
+// legacy converts Stats to the string map returned by GetStats.
+func (s Stats) legacy() map[string]string {
+	lastConsensusRound := "nil"
+	if s.LastConsensusRound != nil {
+		lastConsensusRound = strconv.Itoa(*s.LastConsensusRound)
+	}
+
+	return map[string]string{
+		"last_consensus_round":   lastConsensusRound,
+		"last_block_index":       strconv.Itoa(s.LastBlockIndex),
+		"consensus_events":       strconv.Itoa(s.ConsensusEvents),
+		"consensus_transactions": strconv.Itoa(s.ConsensusTransactions),
+		"undetermined_events":    strconv.Itoa(s.UndeterminedEvents),
+		"transaction_pool":       strconv.Itoa(s.TransactionPool),
+		"num_peers":              strconv.Itoa(s.NumPeers),
+		"last_peer_change":       strconv.Itoa(s.LastPeerChange),
+		"sync_rate":              strconv.FormatFloat(s.SyncRate, 'f', 2, 64),
+		"events_per_second":      strconv.FormatFloat(s.EventsPerSecond, 'f', 2, 64),
+		"rounds_per_second":      strconv.FormatFloat(s.RoundsPerSecond, 'f', 2, 64),
+		"round_events":           strconv.Itoa(s.RoundEvents),
+		"id":                     fmt.Sprint(s.ID),
+		"state":                  s.State,
+		"moniker":                s.Moniker,
+		"time":                   strconv.FormatInt(s.Time.UnixNano(), 10),
+	}
+}
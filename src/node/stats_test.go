@@ -0,0 +1,85 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLegacyStats(t *testing.T) {
+	round := 17
+
+	stats := Stats{
+		ID:                    1,
+		Moniker:               "node1",
+		State:                 "Babbling",
+		LastBlockIndex:        3,
+		LastConsensusRound:    &round,
+		NumPeers:              3,
+		ConsensusEvents:       180,
+		ConsensusTransactions: 40,
+		UndeterminedEvents:    18,
+		RoundEvents:           7,
+		SyncRate:              1,
+		EventsPerSecond:       12.345,
+		Time:                  time.Unix(0, 42),
+	}
+
+	expected := map[string]string{
+		"last_consensus_round":   "17",
+		"last_block_index":       "3",
+		"consensus_events":       "180",
+		"consensus_transactions": "40",
+		"undetermined_events":    "18",
+		"transaction_pool":       "0",
+		"num_peers":              "3",
+		"last_peer_change":       "0",
+		"sync_rate":              "1.00",
+		"events_per_second":      "12.35",
+		"rounds_per_second":      "0.00",
+		"round_events":           "7",
+		"id":                     "1",
+		"state":                  "Babbling",
+		"moniker":                "node1",
+		"time":                   "42",
+	}
+
+	legacy := stats.legacy()
+
+	if len(legacy) != len(expected) {
+		t.Fatalf("legacy stats should have %d keys, not %d", len(expected), len(legacy))
+	}
+
+	for k, v := range expected {
+		if legacy[k] != v {
+			t.Fatalf("legacy %s should be %s, not %s", k, v, legacy[k])
+		}
+	}
+
+	stats.LastConsensusRound = nil
+
+	if lcr := stats.legacy()["last_consensus_round"]; lcr != "nil" {
+		t.Fatalf("legacy last_consensus_round should be nil, not %s", lcr)
+	}
+}
+
+func TestSyncProgress(t *testing.T) {
+	cores, _, _ := initCores(2, t)
+
+	n := &Node{
+		core:      cores[0],
+		peerKnown: make(map[uint32]int),
+	}
+
+	if p := n.SyncProgress(); p != 100 {
+		t.Fatalf("Progress should be 100 without reports from other nodes, not %d", p)
+	}
+
+	// cores[0] only knows its own initial event, and the other node reports 3
+	// events from cores[1]
+	n.peerKnown[cores[0].validator.ID()] = -1
+	n.peerKnown[cores[1].validator.ID()] = 2
+
+	if p := n.SyncProgress(); p != 25 {
+		t.Fatalf("Progress should be 25, not %d", p)
+	}
+}
@@ -0,0 +1,111 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+)
+
+// SuspendReason identifies why the node entered the Suspended state.
+type SuspendReason string
+
+const (
+	// SuspendUndeterminedEvents is the reason of a node that created more
+	// than SuspendLimit undetermined events per validator, because consensus
+	// is not progressing.
+	SuspendUndeterminedEvents SuspendReason = "undetermined_events"
+	// SuspendEvicted is the reason of a node that was removed from the
+	// validator-set.
+	SuspendEvicted SuspendReason = "evicted"
+	// SuspendMaintenance is the reason of a node started in maintenance-mode.
+	SuspendMaintenance SuspendReason = "maintenance"
+	// SuspendManual is the reason of a node suspended by an operator, or by
+	// the application.
+	SuspendManual SuspendReason = "manual"
+)
+
+// suspendRemediations are the hints given to operators for each SuspendReason.
+var suspendRemediations = map[SuspendReason]string{
+	SuspendUndeterminedEvents: "Consensus is not progressing. Check that more than two thirds of the validators are running and reachable, and see the diagnostics written to the data directory, then resume with POST /admin/resume.",
+	SuspendEvicted:            "The node was removed from the validator-set. Resume with POST /admin/resume to request to join again.",
+	SuspendMaintenance:        "The node runs in maintenance-mode. Restart it without maintenance-mode to rejoin consensus.",
+	SuspendManual:             "The node was suspended on request. Resume with POST /admin/resume.",
+}
+
+// Suspension describes why, and since when, the node is suspended.
+// UndeterminedEvents is the number of undetermined events created since the
+// node started or resumed, and SuspendLimit the number above which it
+// suspends, when the Reason is SuspendUndeterminedEvents. Remediation tells
+// operators how to bring the node back.
+type Suspension struct {
+	Reason             SuspendReason `json:"reason"`
+	Message            string        `json:"message"`
+	Since              time.Time     `json:"since"`
+	UndeterminedEvents int           `json:"undetermined_events,omitempty"`
+	SuspendLimit       int           `json:"suspend_limit,omitempty"`
+	Remediation        string        `json:"remediation"`
+}
+
+// suspensions records the current Suspension of the node, if any, and counts
+// the suspensions by reason.
+type suspensions struct {
+	sync.Mutex
+
+	current *Suspension
+	counts  map[SuspendReason]int
+}
+
+func newSuspensions() *suspensions {
+	return &suspensions{
+		counts: make(map[SuspendReason]int),
+	}
+}
+
+// record sets the current Suspension, and counts it.
+func (s *suspensions) record(suspension Suspension) {
+	s.Lock()
+	defer s.Unlock()
+
+	suspension.Remediation = suspendRemediations[suspension.Reason]
+
+	s.current = &suspension
+	s.counts[suspension.Reason]++
+
+	metrics.Suspensions.WithLabelValues(string(suspension.Reason)).Inc()
+}
+
+// clear removes the current Suspension, when the node resumes.
+func (s *suspensions) clear() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.current = nil
+}
+
+// status returns a copy of the current Suspension, or nil if the node is not
+// suspended, and of the counts.
+func (s *suspensions) status() (*Suspension, map[SuspendReason]int) {
+	s.Lock()
+	defer s.Unlock()
+
+	var current *Suspension
+	if s.current != nil {
+		c := *s.current
+		current = &c
+	}
+
+	counts := make(map[SuspendReason]int, len(s.counts))
+	for reason, count := range s.counts {
+		counts[reason] = count
+	}
+
+	return current, counts
+}
+
+// GetSuspension returns why, and since when, the node is suspended, or nil if
+// it is not suspended.
+func (n *Node) GetSuspension() *Suspension {
+	current, _ := n.suspensions.status()
+	return current
+}
@@ -0,0 +1,84 @@
+package node
+
+import (
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+// limitEvents selects at most limit events of a topologically sorted event
+// diff, to be sent to a peer that is behind. Instead of the oldest events, it
+// first selects the events carrying block signatures, along with their
+// ancestors in the diff, so that the peer can sign and anchor blocks before it
+// receives the whole backlog. The remaining room is filled with the oldest
+// events. The selection is returned in topological order, and every parent
+// of a selected event is either selected or known by the peer, since parents
+// outside the diff are known.
+func limitEvents(eventDiff []*hg.Event, limit int) []*hg.Event {
+	if limit >= len(eventDiff) {
+		return eventDiff
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	position := make(map[string]int, len(eventDiff))
+	for i, ev := range eventDiff {
+		position[ev.Hex()] = i
+	}
+
+	selected := make([]bool, len(eventDiff))
+	count := 0
+
+	// closure returns the positions of the unselected ancestors of an event
+	// in the diff, including itself.
+	closure := func(start int) []int {
+		res := []int{}
+		visited := map[int]bool{start: true}
+		stack := []int{start}
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			res = append(res, i)
+
+			for _, parent := range []string{eventDiff[i].SelfParent(), eventDiff[i].OtherParent()} {
+				if j, ok := position[parent]; ok && !selected[j] && !visited[j] {
+					visited[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		return res
+	}
+
+	for i, ev := range eventDiff {
+		if selected[i] || len(ev.BlockSignatures()) == 0 {
+			continue
+		}
+
+		ancestors := closure(i)
+		if count+len(ancestors) > limit {
+			continue
+		}
+
+		for _, j := range ancestors {
+			selected[j] = true
+		}
+		count += len(ancestors)
+	}
+
+	// The oldest events are topologically closed, and fill the rest
+	for i := 0; i < len(eventDiff) && count < limit; i++ {
+		if !selected[i] {
+			selected[i] = true
+			count++
+		}
+	}
+
+	res := make([]*hg.Event, 0, limit)
+	for i, ev := range eventDiff {
+		if selected[i] {
+			res = append(res, ev)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,62 @@
+package node
+
+import (
+	"testing"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+func TestLimitEvents(t *testing.T) {
+	a := [][]byte{[]byte("a"), []byte("b")}
+
+	// a0 - a1 - a2 - a3 - a4
+	//  \
+	//   b0 (block signature) - b1 (block signature, other-parent a4)
+	a0 := hg.NewEvent(nil, nil, nil, []string{"known", ""}, a[0], 0)
+	a1 := hg.NewEvent(nil, nil, nil, []string{a0.Hex(), ""}, a[0], 1)
+	a2 := hg.NewEvent(nil, nil, nil, []string{a1.Hex(), ""}, a[0], 2)
+	a3 := hg.NewEvent(nil, nil, nil, []string{a2.Hex(), ""}, a[0], 3)
+	a4 := hg.NewEvent(nil, nil, nil, []string{a3.Hex(), ""}, a[0], 4)
+
+	sigs := []hg.BlockSignature{{Validator: a[1], Index: 0, Signature: "sig"}}
+	b0 := hg.NewEvent(nil, nil, sigs, []string{"", a0.Hex()}, a[1], 0)
+	b1 := hg.NewEvent(nil, nil, sigs, []string{b0.Hex(), a4.Hex()}, a[1], 1)
+
+	diff := []*hg.Event{a0, a1, a2, a3, b0, a4, b1}
+
+	// b0 and its ancestor a0 come first, then the oldest events. b1 does not
+	// fit with its ancestors.
+	expected := []*hg.Event{a0, a1, b0}
+	if res := limitEvents(diff, 3); !sameEvents(res, expected) {
+		t.Fatalf("The selection should be %v, not %v", hexes(expected), hexes(res))
+	}
+
+	expected = []*hg.Event{a0, a1, a2, a3, b0}
+	if res := limitEvents(diff, 5); !sameEvents(res, expected) {
+		t.Fatalf("The selection should be %v, not %v", hexes(expected), hexes(res))
+	}
+
+	if res := limitEvents(diff, 10); !sameEvents(res, diff) {
+		t.Fatalf("The whole diff should be selected, not %v", hexes(res))
+	}
+}
+
+func sameEvents(a, b []*hg.Event) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hexes(events []*hg.Event) []string {
+	res := make([]string, len(events))
+	for i, ev := range events {
+		res[i] = ev.Hex()[:8]
+	}
+	return res
+}
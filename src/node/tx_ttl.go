@@ -0,0 +1,84 @@
+package node
+
+import (
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+)
+
+// TxTTL is the time-to-live of a transaction submitted with SubmitTxWithTTL.
+// The transaction expires once the node has seen Rounds more rounds, or after
+// Duration, if it is still waiting to be included in an Event. A zero field
+// sets no limit. Once a transaction is in an Event, it is ordered like any
+// other, so the TTL does not bound the time to commit it.
+type TxTTL struct {
+	Rounds   int
+	Duration time.Duration
+}
+
+// ttlTx is a transaction submitted with a TTL.
+type ttlTx struct {
+	tx  []byte
+	ttl TxTTL
+}
+
+// txDeadline is when a transaction submitted with a TTL expires: once the last
+// round known to the node is above round, if round is not -1, or after time,
+// if time is not zero.
+type txDeadline struct {
+	round int
+	time  time.Time
+}
+
+// expired returns true if the deadline has passed.
+func (d *txDeadline) expired(lastRound int, now time.Time) bool {
+	if d.round >= 0 && lastRound > d.round {
+		return true
+	}
+
+	return !d.time.IsZero() && now.After(d.time)
+}
+
+// newTxDeadline returns the deadline of a transaction submitted now with the
+// given TTL.
+func (n *Node) newTxDeadline(ttl TxTTL) *txDeadline {
+	d := &txDeadline{round: -1}
+
+	if ttl.Rounds > 0 {
+		n.coreLock.RLock()
+		d.round = n.core.hg.Store.LastRound() + ttl.Rounds
+		n.coreLock.RUnlock()
+	}
+
+	if ttl.Duration > 0 {
+		d.time = n.clock.Now().Add(ttl.Duration)
+	}
+
+	return d
+}
+
+// expireTransactions evicts the transactions whose TTL expired from the
+// transaction-pool and from the offline log, and reports them to the
+// subscribers.
+func (n *Node) expireTransactions() {
+	now := n.clock.Now()
+
+	n.coreLock.Lock()
+	lastRound := n.core.hg.Store.LastRound()
+	expired := n.core.evictExpiredTransactions(now)
+	n.coreLock.Unlock()
+
+	expired = append(expired, n.offlineLog.evict(lastRound, now)...)
+
+	if len(expired) == 0 {
+		return
+	}
+
+	n.logger.WithField("transactions", len(expired)).Info("TTL expired => evicting Transactions")
+
+	metrics.TransactionsExpired.Add(float64(len(expired)))
+
+	for _, tx := range expired {
+		n.core.notifier.publishExpired(tx)
+	}
+}
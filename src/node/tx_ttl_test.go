@@ -0,0 +1,67 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransactionTTL(t *testing.T) {
+	keys, peers := initPeers(t, 4)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 5*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	node := nodes[0]
+
+	sub := node.Subscribe(10, ExpiredNotification)
+	defer sub.Unsubscribe()
+
+	node.addTransaction([]byte("no ttl"), nil)
+	node.addTransaction([]byte("expired duration"), &txDeadline{round: -1, time: time.Now().Add(-time.Second)})
+	node.addTransaction([]byte("pending rounds"), node.newTxDeadline(TxTTL{Rounds: 1}))
+	node.addTransaction([]byte("expired both"), &txDeadline{round: 0, time: time.Now().Add(-time.Minute)})
+	node.addTransaction([]byte("pending duration"), node.newTxDeadline(TxTTL{Duration: time.Minute}))
+
+	node.expireTransactions()
+
+	pool := node.core.transactionPool
+	if len(pool) != 3 || len(node.core.transactionDeadlines) != 3 {
+		t.Fatalf("3 transactions should be left in the pool, not %d", len(pool))
+	}
+	for i, e := range []string{"no ttl", "pending rounds", "pending duration"} {
+		if string(pool[i]) != e {
+			t.Fatalf("Transaction %d should be %q, not %q", i, e, pool[i])
+		}
+	}
+
+	for _, e := range []string{"expired duration", "expired both"} {
+		select {
+		case note := <-sub.C():
+			if string(note.Expired.Data) != e {
+				t.Fatalf("The expired transaction should be %q, not %q", e, note.Expired.Data)
+			}
+		default:
+			t.Fatalf("%q should be reported as expired", e)
+		}
+	}
+
+	// The deadlines stay aligned with the transactions once some of them are
+	// included in an Event
+	if err := node.core.addSelfEvent(""); err != nil {
+		t.Fatal(err)
+	}
+	if len(node.core.transactionPool) != 0 || len(node.core.transactionDeadlines) != 0 {
+		t.Fatal("The transactions, and their deadlines, should be removed from the pool")
+	}
+
+	// A round deadline expires once the node knows a later round
+	d := &txDeadline{round: 3}
+	if d.expired(3, time.Now()) || !d.expired(4, time.Now()) {
+		t.Fatal("The deadline should expire at round 4")
+	}
+
+	if err := node.SubmitTxWithTTL([]byte("tx"), TxTTL{Rounds: -1}); err == nil {
+		t.Fatal("A negative TTL should be refused")
+	}
+}
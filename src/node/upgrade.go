@@ -0,0 +1,256 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/version"
+)
+
+// initialProtocolVersion is the version of the protocol that every network
+// starts with. It must not depend on the build, because all the nodes must
+// agree on the active version at every round.
+const initialProtocolVersion = 1
+
+// Upgrade is a version of the protocol agreed by the validators, and the round
+// from which it is active.
+type Upgrade struct {
+	ProtocolVersion int `json:"protocol_version"`
+	Round           int `json:"round"`
+}
+
+// UpgradeStatus reports the upgrades of the protocol, and the pending signals
+// of the validators.
+type UpgradeStatus struct {
+	// ProtocolVersion is the version of the protocol active at the last
+	// consensus round, and SupportedVersion the latest version supported by
+	// this build.
+	ProtocolVersion  int `json:"protocol_version"`
+	SupportedVersion int `json:"supported_version"`
+
+	// Upgrades are the activated upgrades, in order.
+	Upgrades []Upgrade `json:"upgrades"`
+
+	// Signals contains, for every version that is not activated yet, the IDs of
+	// the validators that are ready for it. Quorum is the number of signals
+	// needed to activate a version with the current validator-set.
+	Signals map[int][]uint32 `json:"signals"`
+	Quorum  int              `json:"quorum"`
+}
+
+// upgrades records the PROTOCOL_UPGRADE InternalTransactions accepted in
+// Blocks. A version of the protocol is activated once more than two thirds of
+// the validators have signalled that they are ready for it. Like a change of
+// the validator-set, it takes effect 6 rounds after the round-received of the
+// Block, when all the nodes are guaranteed to have processed it. Upgrades are
+// not included in Frames, so a node that fast-forwards past an upgrade only
+// learns about it from the following signals.
+type upgrades struct {
+	sync.Mutex
+
+	// signals contains, for every version, the IDs of the validators that
+	// signalled it.
+	signals map[int]map[uint32]bool
+
+	active []Upgrade
+}
+
+func newUpgrades() *upgrades {
+	return &upgrades{
+		signals: make(map[int]map[uint32]bool),
+	}
+}
+
+// signal records that a validator is ready for a version of the protocol, and
+// activates the version at effectiveRound if a super-majority of validators,
+// according to the thresholds, have signalled it. It returns the activated
+// Upgrade, if any.
+func (u *upgrades) signal(id uint32, protocolVersion int, validators *peers.PeerSet, thresholds peers.Thresholds, effectiveRound int) *Upgrade {
+	u.Lock()
+	defer u.Unlock()
+
+	if protocolVersion <= u.latest() {
+		return nil
+	}
+
+	if _, ok := u.signals[protocolVersion]; !ok {
+		u.signals[protocolVersion] = make(map[uint32]bool)
+	}
+	u.signals[protocolVersion][id] = true
+
+	// Signals from validators that have left, or from shadow validators, do
+	// not count
+	count := 0
+	for signer := range u.signals[protocolVersion] {
+		if p, ok := validators.ByID[signer]; ok && !p.Shadow {
+			count++
+		}
+	}
+
+	if count < thresholds.SuperMajorityOf(validators) {
+		return nil
+	}
+
+	upgrade := Upgrade{
+		ProtocolVersion: protocolVersion,
+		Round:           effectiveRound,
+	}
+
+	u.active = append(u.active, upgrade)
+
+	for v := range u.signals {
+		if v <= protocolVersion {
+			delete(u.signals, v)
+		}
+	}
+
+	return &upgrade
+}
+
+// latest returns the latest activated version, even if it is not effective
+// yet. It must be called with the lock held.
+func (u *upgrades) latest() int {
+	if len(u.active) == 0 {
+		return initialProtocolVersion
+	}
+	return u.active[len(u.active)-1].ProtocolVersion
+}
+
+// versionAt returns the version of the protocol active at a round.
+func (u *upgrades) versionAt(round int) int {
+	u.Lock()
+	defer u.Unlock()
+
+	v := initialProtocolVersion
+	for _, upgrade := range u.active {
+		if upgrade.Round > round {
+			break
+		}
+		v = upgrade.ProtocolVersion
+	}
+	return v
+}
+
+// status returns the UpgradeStatus at a round.
+func (u *upgrades) status(round int, validators *peers.PeerSet, thresholds peers.Thresholds) UpgradeStatus {
+	protocolVersion := u.versionAt(round)
+
+	u.Lock()
+	defer u.Unlock()
+
+	status := UpgradeStatus{
+		ProtocolVersion:  protocolVersion,
+		SupportedVersion: version.ProtocolVersion,
+		Upgrades:         append([]Upgrade{}, u.active...),
+		Signals:          make(map[int][]uint32, len(u.signals)),
+		Quorum:           thresholds.SuperMajorityOf(validators),
+	}
+
+	for v, signers := range u.signals {
+		ids := make([]uint32, 0, len(signers))
+		for id := range signers {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		status.Signals[v] = ids
+	}
+
+	return status
+}
+
+// processUpgrade records an accepted PROTOCOL_UPGRADE InternalTransaction. The
+// quorum is computed with the validator-set of the round-received of its
+// Block.
+func (c *core) processUpgrade(txBody hg.InternalTransactionBody, roundReceived int, effectiveRound int) error {
+	validators, err := c.hg.Store.GetPeerSet(roundReceived)
+	if err != nil {
+		return err
+	}
+
+	upgrade := c.upgrades.signal(txBody.Peer.ID(), txBody.ProtocolVersion, validators, c.hg.Thresholds(), effectiveRound)
+	if upgrade == nil {
+		return nil
+	}
+
+	logger := c.logger.WithField("protocol_version", upgrade.ProtocolVersion).WithField("effective_round", upgrade.Round)
+	if upgrade.ProtocolVersion > version.ProtocolVersion {
+		logger.Warn("Protocol upgrade not supported by this version of Babble")
+	} else {
+		logger.Info("Protocol upgraded")
+	}
+
+	return nil
+}
+
+// SignalUpgrade submits an InternalTransaction to signal that the node is ready
+// for a version of the protocol, and waits for it to go through consensus. The
+// version is activated once more than two thirds of the validators have
+// signalled it. Only validators can signal upgrades, and only for versions
+// that are supported by this build.
+func (n *Node) SignalUpgrade(protocolVersion int) error {
+	if protocolVersion > version.ProtocolVersion {
+		return fmt.Errorf("Protocol version %d is not supported by this version of Babble", protocolVersion)
+	}
+
+	n.coreLock.Lock()
+
+	p, ok := n.core.validators.ByID[n.core.validator.ID()]
+	if !ok {
+		n.coreLock.Unlock()
+		return fmt.Errorf("Only validators can signal upgrades")
+	}
+
+	n.core.upgrades.Lock()
+	latest := n.core.upgrades.latest()
+	n.core.upgrades.Unlock()
+
+	if protocolVersion <= latest {
+		n.coreLock.Unlock()
+		return fmt.Errorf("Protocol version %d is already activated", protocolVersion)
+	}
+
+	itx := hg.NewInternalTransactionUpgrade(*p, protocolVersion)
+	itx.Sign(n.core.validator.Key)
+
+	promise := n.core.addInternalTransaction(itx)
+
+	n.coreLock.Unlock()
+
+	n.logger.WithField("protocol_version", protocolVersion).Info("Signalling protocol upgrade")
+
+	select {
+	case resp := <-promise.respCh:
+		if !resp.accepted {
+			return fmt.Errorf("Upgrade signal refused by the application")
+		}
+	case <-n.clock.After(n.conf.JoinTimeout):
+		return fmt.Errorf("Timeout waiting for the upgrade signal to go through consensus")
+	}
+
+	return nil
+}
+
+// GetUpgradeStatus returns the upgrades of the protocol, and the pending
+// signals of the validators.
+func (n *Node) GetUpgradeStatus() UpgradeStatus {
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	round := -1
+	if r := n.core.getLastConsensusRoundIndex(); r != nil {
+		round = *r
+	}
+
+	return n.core.upgrades.status(round, n.core.validators, n.core.hg.Thresholds())
+}
+
+// ProtocolVersionAt returns the version of the protocol active at a round.
+// Behaviours introduced by a new version of the protocol must only be enabled
+// from the rounds where it is active, so that all the nodes switch at the same
+// round.
+func (n *Node) ProtocolVersionAt(round int) int {
+	return n.core.upgrades.versionAt(round)
+}
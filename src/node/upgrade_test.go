@@ -0,0 +1,150 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func TestProcessUpgrade(t *testing.T) {
+	cores, _, _ := initCores(4, t)
+	c := cores[0]
+
+	validators := c.validators.Peers
+
+	signal := func(p *peers.Peer) hg.InternalTransactionReceipt {
+		itx := hg.NewInternalTransactionUpgrade(*p, 2)
+		return itx.AsAccepted()
+	}
+
+	// Two signals out of four validators, one of them twice, and one refused
+	refused := hg.NewInternalTransactionUpgrade(*validators[2], 2)
+	receipts := []hg.InternalTransactionReceipt{
+		signal(validators[0]),
+		signal(validators[1]),
+		signal(validators[1]),
+		refused.AsRefused(),
+	}
+
+	if err := c.processAcceptedInternalTransactions(0, 0, receipts); err != nil {
+		t.Fatal(err)
+	}
+
+	status := c.upgrades.status(100, c.validators, peers.DefaultThresholds)
+	if len(status.Upgrades) != 0 {
+		t.Fatalf("No upgrade should be activated, not %v", status.Upgrades)
+	}
+	if l := len(status.Signals[2]); l != 2 {
+		t.Fatalf("There should be 2 signals for version 2, not %d", l)
+	}
+	if status.Quorum != 3 {
+		t.Fatalf("Quorum should be 3, not %d", status.Quorum)
+	}
+
+	// The third signal activates the upgrade 6 rounds after its round-received
+	if err := c.processAcceptedInternalTransactions(1, 3, []hg.InternalTransactionReceipt{signal(validators[3])}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Upgrade{{ProtocolVersion: 2, Round: 9}}
+	if !reflect.DeepEqual(c.upgrades.active, expected) {
+		t.Fatalf("Upgrades should be %v, not %v", expected, c.upgrades.active)
+	}
+
+	if v := c.upgrades.versionAt(8); v != 1 {
+		t.Fatalf("Protocol version at round 8 should be 1, not %d", v)
+	}
+	if v := c.upgrades.versionAt(9); v != 2 {
+		t.Fatalf("Protocol version at round 9 should be 2, not %d", v)
+	}
+
+	if status := c.upgrades.status(9, c.validators, peers.DefaultThresholds); len(status.Signals) != 0 {
+		t.Fatalf("There should be no pending signals, not %v", status.Signals)
+	}
+
+	// Upgrades do not change the validator-set
+	if c.validators.Len() != 4 || c.lastPeerChangeRound != -1 {
+		t.Fatalf("Validators should not change")
+	}
+}
+
+func TestUpgradeThresholds(t *testing.T) {
+	cores, _, _ := initCores(4, t)
+
+	// Each core has its own thresholds: 3 signals out of 4 validators
+	// activate an upgrade with the default super-majority, but not with 3/4
+	thresholds, err := peers.ParseThresholds("3/4", "1/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cores[1].hg.SetThresholds(thresholds)
+
+	for _, c := range cores[:2] {
+		receipts := []hg.InternalTransactionReceipt{}
+		for _, p := range c.validators.Peers[:3] {
+			itx := hg.NewInternalTransactionUpgrade(*p, 2)
+			receipts = append(receipts, itx.AsAccepted())
+		}
+
+		if err := c.processAcceptedInternalTransactions(0, 0, receipts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if l := len(cores[0].upgrades.active); l != 1 {
+		t.Fatalf("The upgrade should be activated with the default thresholds, not %d upgrades", l)
+	}
+	if l := len(cores[1].upgrades.active); l != 0 {
+		t.Fatalf("The upgrade should not be activated with a super-majority of 3/4, not %d upgrades", l)
+	}
+}
+
+func TestUpgradeSignals(t *testing.T) {
+	pirs := []*peers.Peer{}
+	for i := 0; i < 4; i++ {
+		key, _ := keys.GenerateECDSAKey()
+		pirs = append(pirs, peers.NewPeer(keys.PublicKeyHex(&key.PublicKey), "", ""))
+	}
+	validators := peers.NewPeerSet(pirs[:3])
+
+	u := newUpgrades()
+
+	// Signals from non-validators do not count
+	if upgrade := u.signal(pirs[3].ID(), 2, validators, peers.DefaultThresholds, 10); upgrade != nil {
+		t.Fatalf("A signal from a non-validator should not activate an upgrade")
+	}
+	if upgrade := u.signal(pirs[0].ID(), 2, validators, peers.DefaultThresholds, 10); upgrade != nil {
+		t.Fatalf("1 signal out of 3 validators should not activate an upgrade")
+	}
+
+	// Signals for the active version are ignored
+	if upgrade := u.signal(pirs[0].ID(), initialProtocolVersion, validators, peers.DefaultThresholds, 10); upgrade != nil {
+		t.Fatalf("A signal for the initial version should be ignored")
+	}
+
+	if upgrade := u.signal(pirs[1].ID(), 2, validators, peers.DefaultThresholds, 10); upgrade != nil {
+		t.Fatalf("2 signals out of 3 validators should not activate an upgrade")
+	}
+
+	upgrade := u.signal(pirs[2].ID(), 2, validators, peers.DefaultThresholds, 12)
+	if upgrade == nil || upgrade.ProtocolVersion != 2 || upgrade.Round != 12 {
+		t.Fatalf("3 signals out of 3 validators should activate version 2, not %v", upgrade)
+	}
+
+	if upgrade := u.signal(pirs[0].ID(), 2, validators, peers.DefaultThresholds, 14); upgrade != nil {
+		t.Fatalf("A signal for an activated version should be ignored")
+	}
+
+	// Signals from shadow validators do not count
+	shadow := *pirs[3]
+	shadow.Shadow = true
+	withShadow := validators.WithNewPeer(&shadow)
+	u.signal(pirs[0].ID(), 3, withShadow, peers.DefaultThresholds, 16)
+	u.signal(pirs[1].ID(), 3, withShadow, peers.DefaultThresholds, 16)
+	if upgrade := u.signal(shadow.ID(), 3, withShadow, peers.DefaultThresholds, 16); upgrade != nil {
+		t.Fatalf("A signal from a shadow validator should not activate an upgrade")
+	}
+}
@@ -0,0 +1,209 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+	"github.com/mosaicnetworks/babble/src/net"
+	_state "github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/sirupsen/logrus"
+)
+
+// watchdogChecks is the number of times the watchdog checks the progress of
+// consensus per timeout.
+const watchdogChecks = 4
+
+// stallDetector reports when the last consensus round has not changed for a
+// timeout while transactions are pending. Once reported, a stall is reported
+// again after every timeout, until consensus progresses.
+type stallDetector struct {
+	timeout time.Duration
+	round   int
+	since   time.Time
+}
+
+func newStallDetector(timeout time.Duration) *stallDetector {
+	return &stallDetector{
+		timeout: timeout,
+		round:   -1,
+	}
+}
+
+// observe records the last consensus round, and whether transactions are
+// pending, at the given time. It returns how long consensus has been stalled,
+// and true if the stall must be reported.
+func (d *stallDetector) observe(round int, pending bool, now time.Time) (time.Duration, bool) {
+	if d.since.IsZero() || round != d.round || !pending {
+		d.round = round
+		d.since = now
+		return 0, false
+	}
+
+	stalledFor := now.Sub(d.since)
+	if stalledFor < d.timeout {
+		return stalledFor, false
+	}
+
+	// Wait for another timeout before reporting the stall, and taking the
+	// recovery actions, again
+	d.since = now
+
+	return stalledFor, true
+}
+
+// StallDiagnosis describes a stall of consensus, to help determine whether the
+// node, or some of its peers, are holding it back.
+type StallDiagnosis struct {
+	StalledFor          time.Duration `json:"stalled_for"`
+	LastConsensusRound  int           `json:"last_consensus_round"`
+	PendingTransactions int           `json:"pending_transactions"`
+	UndeterminedEvents  int           `json:"undetermined_events"`
+
+	// UnreachablePeers are the peers whose last gossip failed, with the error.
+	UnreachablePeers map[string]string `json:"unreachable_peers"`
+}
+
+// watchdog periodically checks that consensus progresses while transactions
+// are pending, until the node shuts down. When it does not progress for the
+// timeout, the watchdog logs a diagnosis and takes the recovery actions
+// enabled in the configuration.
+func (n *Node) watchdog(timeout time.Duration) {
+	detector := newStallDetector(timeout)
+
+	ticker := n.clock.NewTicker(timeout / watchdogChecks)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			round, pending, _ := n.consensusProgress()
+
+			// Only a Babbling node is expected to make progress
+			if n.GetState() != _state.Babbling {
+				pending = false
+			}
+
+			if stalledFor, stalled := detector.observe(round, pending, n.clock.Now()); stalled {
+				n.handleStall(stalledFor)
+			}
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// consensusProgress returns the last consensus round, or -1, whether
+// transactions are pending, and the number of pending transactions. Pending
+// transactions are either in the transaction pools, or in undetermined events.
+func (n *Node) consensusProgress() (round int, pending bool, count int) {
+	n.coreLock.RLock()
+	defer n.coreLock.RUnlock()
+
+	round = -1
+	if n.core.hg.LastConsensusRound != nil {
+		round = *n.core.hg.LastConsensusRound
+	}
+
+	count = len(n.core.transactionPool) + len(n.core.internalTransactionPool)
+
+	for _, hash := range n.core.getUndeterminedEvents() {
+		ev, err := n.core.hg.Store.GetEvent(hash)
+		if err != nil {
+			continue
+		}
+		count += len(ev.Transactions()) + len(ev.InternalTransactions())
+	}
+
+	return round, count > 0, count
+}
+
+// DiagnoseStall returns a diagnosis of the current state of consensus, for a
+// stall of the given duration.
+func (n *Node) DiagnoseStall(stalledFor time.Duration) *StallDiagnosis {
+	round, _, pending := n.consensusProgress()
+
+	n.coreLock.RLock()
+	undetermined := len(n.core.getUndeterminedEvents())
+	n.coreLock.RUnlock()
+
+	unreachable := make(map[string]string)
+	for _, ps := range n.GetPeerStats() {
+		if ps.ConsecutiveFailures > 0 {
+			unreachable[ps.NetAddr] = ps.LastError
+		}
+	}
+
+	return &StallDiagnosis{
+		StalledFor:          stalledFor,
+		LastConsensusRound:  round,
+		PendingTransactions: pending,
+		UndeterminedEvents:  undetermined,
+		UnreachablePeers:    unreachable,
+	}
+}
+
+// handleStall logs a diagnosis of the stall and takes the enabled recovery
+// actions.
+func (n *Node) handleStall(stalledFor time.Duration) {
+	metrics.ConsensusStalls.Inc()
+
+	diagnosis := n.DiagnoseStall(stalledFor)
+
+	n.logger.WithFields(logrus.Fields{
+		"stalled_for":          diagnosis.StalledFor,
+		"last_consensus_round": diagnosis.LastConsensusRound,
+		"pending_transactions": diagnosis.PendingTransactions,
+		"undetermined_events":  diagnosis.UndeterminedEvents,
+		"unreachable_peers":    diagnosis.UnreachablePeers,
+	}).Warn("Consensus stalled")
+
+	if n.conf.WatchdogAlert {
+		err := fmt.Errorf("Consensus stalled for %s", stalledFor)
+		n.core.notifier.publishError(err)
+
+		n.coreLock.RLock()
+		n.core.alert(StalledAlert, "Consensus stalled for %s", stalledFor)
+		n.coreLock.RUnlock()
+
+		n.writeDiagnostics(err.Error(), nil)
+	}
+
+	if n.conf.WatchdogRedial {
+		n.redial()
+	}
+
+	if n.conf.WatchdogFastForward {
+		if err := n.ForceFastForward(); err != nil {
+			n.logger.WithError(err).Warn("Watchdog FastForward")
+		}
+	}
+}
+
+// redial closes the pooled connections of the transport, if it pools them, so
+// that the stale connections are dialed again, and repairs the missing events
+// with every peer. The repairs count as gossip in the PeerStats.
+func (n *Node) redial() {
+	if pt, ok := n.trans.(net.PooledTransport); ok {
+		pt.CloseConns()
+	}
+
+	n.coreLock.RLock()
+	peers := n.core.peers.Peers
+	selfID := n.core.validator.ID()
+	n.coreLock.RUnlock()
+
+	for _, peer := range peers {
+		if peer.ID() == selfID || n.isBanned(peer.NetAddr) {
+			continue
+		}
+
+		err := n.repair(peer)
+		if err != nil {
+			n.logger.WithError(err).WithField("peer", peer.NetAddr).Debug("Watchdog repair")
+		}
+
+		// The peers that cannot be reached are reported in the next diagnosis
+		n.recordGossip(peer.ID(), err)
+	}
+}
@@ -0,0 +1,72 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStallDetector(t *testing.T) {
+	d := newStallDetector(time.Minute)
+	start := time.Unix(0, 0)
+
+	steps := []struct {
+		round      int
+		pending    bool
+		elapsed    time.Duration
+		stalled    bool
+		stalledFor time.Duration
+	}{
+		// The first observation starts the period
+		{round: 3, pending: true, elapsed: 0},
+		{round: 3, pending: true, elapsed: 30 * time.Second, stalledFor: 30 * time.Second},
+		// A new round restarts the period
+		{round: 4, pending: true, elapsed: 40 * time.Second},
+		{round: 4, pending: true, elapsed: 90 * time.Second, stalledFor: 50 * time.Second},
+		{round: 4, pending: true, elapsed: 100 * time.Second, stalled: true, stalledFor: time.Minute},
+		// A reported stall is reported again after another timeout
+		{round: 4, pending: true, elapsed: 130 * time.Second, stalledFor: 30 * time.Second},
+		{round: 4, pending: true, elapsed: 160 * time.Second, stalled: true, stalledFor: time.Minute},
+		// Consensus does not progress without pending transactions
+		{round: 4, pending: false, elapsed: 300 * time.Second},
+		{round: 4, pending: true, elapsed: 330 * time.Second, stalledFor: 30 * time.Second},
+	}
+
+	for i, s := range steps {
+		stalledFor, stalled := d.observe(s.round, s.pending, start.Add(s.elapsed))
+		if stalled != s.stalled || stalledFor != s.stalledFor {
+			t.Fatalf("Step %d should return (%s, %v), not (%s, %v)", i, s.stalledFor, s.stalled, stalledFor, stalled)
+		}
+	}
+}
+
+func TestDiagnoseStall(t *testing.T) {
+	keys, peers := initPeers(t, 3)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 1000, 1000, 5, false, "inmem", 10*time.Millisecond, false, "", t)
+	defer shutdownNodes(nodes)
+
+	// Without the third node, consensus is never reached
+	nodes[1].RunAsync(false)
+	nodes[2].Shutdown()
+
+	nodes[0].coreLock.Lock()
+	nodes[0].core.addTransactions([][]byte{[]byte("tx")})
+	nodes[0].coreLock.Unlock()
+
+	nodes[0].redial()
+
+	d := nodes[0].DiagnoseStall(time.Minute)
+
+	if d.PendingTransactions != 1 {
+		t.Fatalf("There should be 1 pending transaction, not %d", d.PendingTransactions)
+	}
+
+	if d.LastConsensusRound != -1 {
+		t.Fatalf("There should be no consensus round, not %d", d.LastConsensusRound)
+	}
+
+	if _, ok := d.UnreachablePeers[peers.Peers[2].NetAddr]; !ok || len(d.UnreachablePeers) != 1 {
+		t.Fatalf("Only %s should be unreachable, not %v", peers.Peers[2].NetAddr, d.UnreachablePeers)
+	}
+}
@@ -0,0 +1,104 @@
+package node
+
+import (
+	"fmt"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/proxy"
+)
+
+// GetAppWatermark returns the index of the last block acknowledged by the App
+// with AdvanceAppWatermark, or -1. It returns an error if the store does not
+// record the App watermark.
+func (n *Node) GetAppWatermark() (int, error) {
+	ws, ok := n.core.hg.Store.(hg.AppWatermarkStore)
+	if !ok {
+		return -1, fmt.Errorf("The store does not record the App watermark")
+	}
+
+	return ws.AppWatermark()
+}
+
+// AdvanceAppWatermark records that the App persisted the effects of all the
+// blocks up to index, so that they are not delivered to it again when the node
+// restarts and bootstraps from its database. The blocks after the watermark
+// are delivered again, so the App must acknowledge a block only once its
+// effects survive a restart. The watermark cannot go back, nor beyond the last
+// committed block.
+func (n *Node) AdvanceAppWatermark(index int) error {
+	ws, ok := n.core.hg.Store.(hg.AppWatermarkStore)
+	if !ok {
+		return fmt.Errorf("The store does not record the App watermark")
+	}
+
+	n.coreLock.RLock()
+	last := n.core.getLastBlockIndex()
+	n.coreLock.RUnlock()
+
+	if index > last {
+		return fmt.Errorf("Cannot advance the App watermark to block %d, the last block is %d", index, last)
+	}
+
+	if err := ws.SetAppWatermark(index); err != nil {
+		return err
+	}
+
+	n.logger.WithField("block", index).Debug("Advanced App watermark")
+
+	return nil
+}
+
+// skipAcknowledged wraps the commit callback of the App so that the blocks up
+// to the App watermark are not delivered to the App again, when the node
+// bootstraps from its database. Replays commit to the App directly, and
+// deliver all the blocks.
+func (c *core) skipAcknowledged(commit proxy.CommitCallback) proxy.CommitCallback {
+	return func(block hg.Block) (proxy.CommitResponse, error) {
+		if response, ok := c.acknowledgedResponse(&block); ok {
+			return response, nil
+		}
+		return commit(block)
+	}
+}
+
+// acknowledgedResponse returns the state hash and receipts recorded with a
+// block that the App acknowledged, so that the block is not delivered to the
+// App again. The response is taken from the database, where the block was
+// stored after its first commit. It returns false if the block is after the
+// watermark, or if its response was not recorded, in which case the block is
+// delivered again.
+func (c *core) acknowledgedResponse(block *hg.Block) (proxy.CommitResponse, bool) {
+	ws, ok := c.hg.Store.(hg.AppWatermarkStore)
+	if !ok {
+		return proxy.CommitResponse{}, false
+	}
+
+	watermark, err := ws.AppWatermark()
+	if err != nil {
+		c.logger.WithError(err).Error("Reading App watermark")
+		return proxy.CommitResponse{}, false
+	}
+
+	if block.Index() > watermark {
+		return proxy.CommitResponse{}, false
+	}
+
+	var persisted *hg.Block
+	if bs, ok := c.hg.Store.(*hg.BadgerStore); ok {
+		persisted, err = bs.PersistedBlock(block.Index())
+	} else {
+		persisted, err = c.hg.Store.GetBlock(block.Index())
+	}
+
+	if err != nil || len(persisted.StateHash()) == 0 {
+		c.logger.WithField("block", block.Index()).Warn("No recorded commit response for acknowledged block, delivering it again")
+		return proxy.CommitResponse{}, false
+	}
+
+	c.logger.WithField("block", block.Index()).Debug("Skipping block acknowledged by the App")
+
+	return proxy.CommitResponse{
+		StateHash:                   persisted.StateHash(),
+		InternalTransactionReceipts: persisted.InternalTransactionReceipts(),
+	}, true
+}
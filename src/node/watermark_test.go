@@ -0,0 +1,77 @@
+package node
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/dummy"
+)
+
+func TestBootstrapAppWatermark(t *testing.T) {
+	os.RemoveAll("test_data")
+	os.Mkdir("test_data", os.ModeDir|0777)
+
+	keys, peers := initPeers(t, 4)
+	genesisPeerSet := clonePeerSet(t, peers.Peers)
+
+	nodes := initNodes(keys, peers, genesisPeerSet, 100000, 1000, 10, false, "badger", 10*time.Millisecond, false, "", t)
+
+	if err := gossip(nodes, 5, false); err != nil {
+		shutdownNodes(nodes)
+		t.Fatal(err)
+	}
+
+	watermark := 3
+
+	if err := nodes[0].AdvanceAppWatermark(watermark); err != nil {
+		shutdownNodes(nodes)
+		t.Fatal(err)
+	}
+
+	if err := nodes[0].AdvanceAppWatermark(watermark - 1); err == nil {
+		t.Error("The App watermark should not go back")
+	}
+
+	if err := nodes[0].AdvanceAppWatermark(100000); err == nil {
+		t.Error("The App watermark should not go beyond the last block")
+	}
+
+	shutdownNodes(nodes)
+
+	// The Blocks up to the watermark are not delivered to the App again when
+	// the node bootstraps from its database
+	node := recycleNode(nodes[0], t)
+	defer node.Shutdown()
+
+	if w, err := node.GetAppWatermark(); err != nil || w != watermark {
+		t.Fatalf("The App watermark should be %d, not %d (%v)", watermark, w, err)
+	}
+
+	expected := [][]byte{}
+	for i := watermark + 1; i <= node.GetLastBlockIndex(); i++ {
+		block, err := node.GetBlock(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, block.Transactions()...)
+	}
+
+	committed := node.proxy.(*dummy.InmemDummyClient).GetCommittedTransactions()
+	if len(committed) != len(expected) {
+		t.Fatalf("The App should receive the %d transactions after the watermark, not %d", len(expected), len(committed))
+	}
+	for i := range committed {
+		if string(committed[i]) != string(expected[i]) {
+			t.Fatalf("Committed transaction %d should be %q, not %q", i, expected[i], committed[i])
+		}
+	}
+
+	block, err := node.GetBlock(watermark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.StateHash()) == 0 {
+		t.Fatal("An acknowledged block should keep the state hash of its first commit")
+	}
+}
@@ -0,0 +1,277 @@
+package peers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	addressBookPath = "addrbook.json"
+
+	// addressBookSaveInterval limits how often the file is written when peers
+	// are merely seen again, which happens with every gossip.
+	addressBookSaveInterval = time.Minute
+)
+
+// KnownAddr is an address at which a peer can be reached.
+type KnownAddr struct {
+	Addr string `json:"addr"`
+
+	// LastSeen is the time of the last successful contact with the peer at
+	// this address. It is zero if the address was never used successfully.
+	LastSeen time.Time `json:"last_seen,omitempty"`
+
+	// Failures counts the consecutive failed attempts to reach the peer at
+	// this address.
+	Failures int `json:"failures,omitempty"`
+}
+
+// AddressBookEntry records what is known about the addresses of a peer.
+type AddressBookEntry struct {
+	PubKey  string `json:"pub_key"`
+	Moniker string `json:"moniker,omitempty"`
+
+	// Advertised is the address the peer advertised itself, in its join
+	// request or in a peers file.
+	Advertised string `json:"advertised,omitempty"`
+
+	Addrs []*KnownAddr `json:"addrs"`
+
+	// LastSeen is the time of the last successful contact with the peer.
+	LastSeen time.Time `json:"last_seen,omitempty"`
+}
+
+// AddressBook records the addresses of the peers a node has learned about, and
+// when it last reached them. Unlike the PeerSets, which are decided by
+// consensus, it is local to the node, and it keeps the peers that have left
+// the validator-set. It is persisted in an addrbook.json file, so that a node
+// restarting, or recovering from a long partition, tries the addresses that
+// worked most recently first.
+type AddressBook struct {
+	l        sync.Mutex
+	path     string
+	entries  map[string]*AddressBookEntry
+	lastSave time.Time
+}
+
+// NewAddressBook creates an AddressBook backed by the addrbook.json file of a
+// base directory, loading its content if the file exists. With an empty base
+// directory, the AddressBook is only kept in memory.
+func NewAddressBook(base string) (*AddressBook, error) {
+	book := &AddressBook{
+		entries: make(map[string]*AddressBookEntry),
+	}
+
+	if base == "" {
+		return book, nil
+	}
+
+	book.path = filepath.Join(base, addressBookPath)
+
+	buf, err := ioutil.ReadFile(book.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return book, nil
+		}
+		return nil, err
+	}
+
+	var entries []*AddressBookEntry
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, fmt.Errorf("Parsing %s: %v", book.path, err)
+	}
+
+	for _, e := range entries {
+		if e.PubKey != "" {
+			book.entries[e.PubKey] = e
+		}
+	}
+
+	return book, nil
+}
+
+// Learn records the addresses of a peer, and saves the AddressBook if they
+// were not known yet. The NetAddr of the peer is its advertised address.
+func (a *AddressBook) Learn(peer *Peer) error {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	e := a.entry(peer.PubKeyString())
+
+	changed := false
+
+	if peer.Moniker != "" && peer.Moniker != e.Moniker {
+		e.Moniker = peer.Moniker
+		changed = true
+	}
+
+	if peer.NetAddr != "" && peer.NetAddr != e.Advertised {
+		e.Advertised = peer.NetAddr
+		changed = true
+	}
+
+	for _, addr := range peer.Addrs() {
+		if e.addr(addr) == nil {
+			e.Addrs = append(e.Addrs, &KnownAddr{Addr: addr})
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return a.save()
+}
+
+// Seen records a successful contact with a peer at an address. The file is
+// saved at most once per minute, unless the address was never reached before.
+func (a *AddressBook) Seen(pubKey string, addr string, t time.Time) error {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	e := a.entry(pubKey)
+	e.LastSeen = t
+
+	if addr == "" {
+		return nil
+	}
+
+	ka := e.addr(addr)
+	if ka == nil {
+		ka = &KnownAddr{Addr: addr}
+		e.Addrs = append(e.Addrs, ka)
+	}
+	first := ka.LastSeen.IsZero()
+	ka.LastSeen = t
+	ka.Failures = 0
+
+	if !first && t.Sub(a.lastSave) < addressBookSaveInterval {
+		return nil
+	}
+
+	return a.save()
+}
+
+// Failed records a failed attempt to reach a peer at an address. It is only
+// recorded in memory, and saved with the next change.
+func (a *AddressBook) Failed(pubKey string, addr string) {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	e, ok := a.entries[pubKey]
+	if !ok {
+		return
+	}
+
+	if ka := e.addr(addr); ka != nil {
+		ka.Failures++
+	}
+}
+
+// BestAddr returns the address to try first for a peer: the one with the
+// fewest consecutive failures, and the most recent successful contact. The
+// default address, usually the NetAddr of the peer, is used for unknown peers,
+// and preferred when the known addresses are not better.
+func (a *AddressBook) BestAddr(pubKey string, defaultAddr string) string {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	e, ok := a.entries[pubKey]
+	if !ok || len(e.Addrs) == 0 {
+		return defaultAddr
+	}
+
+	best := e.addr(defaultAddr)
+	for _, ka := range e.Addrs {
+		if best == nil ||
+			ka.Failures < best.Failures ||
+			(ka.Failures == best.Failures && ka.LastSeen.After(best.LastSeen)) {
+			best = ka
+		}
+	}
+
+	return best.Addr
+}
+
+// Entries returns a copy of the entries of the AddressBook, sorted by public
+// key.
+func (a *AddressBook) Entries() []AddressBookEntry {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	return a.sortedEntries()
+}
+
+// Save writes the AddressBook to its file.
+func (a *AddressBook) Save() error {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	return a.save()
+}
+
+func (a *AddressBook) entry(pubKey string) *AddressBookEntry {
+	e, ok := a.entries[pubKey]
+	if !ok {
+		e = &AddressBookEntry{PubKey: pubKey}
+		a.entries[pubKey] = e
+	}
+	return e
+}
+
+func (a *AddressBook) sortedEntries() []AddressBookEntry {
+	res := make([]AddressBookEntry, 0, len(a.entries))
+	for _, e := range a.entries {
+		c := *e
+		c.Addrs = make([]*KnownAddr, len(e.Addrs))
+		for i, ka := range e.Addrs {
+			kac := *ka
+			c.Addrs[i] = &kac
+		}
+		res = append(res, c)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].PubKey < res[j].PubKey
+	})
+
+	return res
+}
+
+// save writes the file through a temporary file, so that a crash cannot leave
+// a truncated address book. The caller must hold the lock.
+func (a *AddressBook) save() error {
+	a.lastSave = time.Now()
+
+	if a.path == "" {
+		return nil
+	}
+
+	buf, err := json.MarshalIndent(a.sortedEntries(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := a.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, a.path)
+}
+
+func (e *AddressBookEntry) addr(addr string) *KnownAddr {
+	for _, ka := range e.Addrs {
+		if ka.Addr == addr {
+			return ka
+		}
+	}
+	return nil
+}
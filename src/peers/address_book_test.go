@@ -0,0 +1,71 @@
+package peers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAddressBook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "babble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	book, err := NewAddressBook(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer := NewPeer("0XABCD", "10.0.0.1:1337", "node1")
+	peer.Addresses = []string{"192.168.0.1:1337"}
+
+	if err := book.Learn(peer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown peers, and peers that were never reached, use their NetAddr
+	if addr := book.BestAddr("0X1234", "10.0.0.9:1337"); addr != "10.0.0.9:1337" {
+		t.Fatalf("BestAddr should be 10.0.0.9:1337, not %s", addr)
+	}
+
+	if addr := book.BestAddr(peer.PubKeyString(), peer.NetAddr); addr != peer.NetAddr {
+		t.Fatalf("BestAddr should be %s, not %s", peer.NetAddr, addr)
+	}
+
+	// After a failure, the alternative address is tried
+	book.Failed(peer.PubKeyString(), peer.NetAddr)
+
+	if addr := book.BestAddr(peer.PubKeyString(), peer.NetAddr); addr != "192.168.0.1:1337" {
+		t.Fatalf("BestAddr should be 192.168.0.1:1337, not %s", addr)
+	}
+
+	seen := time.Now().Round(0)
+
+	if err := book.Seen(peer.PubKeyString(), "192.168.0.1:1337", seen); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first contact at an address is saved, and the reloaded address book
+	// prefers the address that worked
+	book2, err := NewAddressBook(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr := book2.BestAddr(peer.PubKeyString(), peer.NetAddr); addr != "192.168.0.1:1337" {
+		t.Fatalf("Reloaded BestAddr should be 192.168.0.1:1337, not %s", addr)
+	}
+
+	entries := book2.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("There should be 1 entry, not %d", len(entries))
+	}
+
+	e := entries[0]
+	if e.Moniker != "node1" || e.Advertised != peer.NetAddr || !e.LastSeen.Equal(seen) || len(e.Addrs) != 2 {
+		t.Fatalf("Unexpected entry %#v", e)
+	}
+}
@@ -17,7 +17,9 @@
 // non-unique user-friendly name. When WebRTC is not activated, a peer should
 // also specify an IP address and port where it can be reached by other peers.
 // With WebRTC, the public key is enough to indentify a peer within the
-// signaling server, and the network address is not necessary.
+// signaling server, and the network address is not necessary. Peers may also
+// list alternative addresses, a transport hint, a voting weight, and metadata,
+// which are written in a versioned format of the peers files.
 //
 // Upon starting up, Babble expects to find a peers.json file, and optionaly a
 // peers.genesis.json file, in its data directory. The peers.json file
@@ -3,17 +3,32 @@ package peers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
 	"sync"
+
+	"github.com/mosaicnetworks/babble/src/common"
 )
 
 const (
 	jsonPeerSetPath        = "peers.json"
 	jsonGenesisPeerSetPath = "peers.genesis.json"
+
+	// jsonPeerSetVersion is the version of the peers files with an explicit
+	// version. Files without a version are plain JSON arrays of peers.
+	jsonPeerSetVersion = 2
 )
 
+// jsonPeerSetFile is the versioned format of the peers files:
+//
+// {"version": 2, "peers": [...]}
+type jsonPeerSetFile struct {
+	Version int     `json:"version"`
+	Peers   []*Peer `json:"peers"`
+}
+
 // JSONPeerSet is used to provide peer persistence on disk in the form of a JSON
 // file.
 type JSONPeerSet struct {
@@ -55,11 +70,9 @@ func (j *JSONPeerSet) PeerSet() (*PeerSet, error) {
 		return nil, nil
 	}
 
-	// Decode the peers
-	var peers []*Peer
-	dec := json.NewDecoder(bytes.NewReader(buf))
-	if err := dec.Decode(&peers); err != nil {
-		return nil, err
+	peers, err := decodePeers(buf)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing %s: %v", j.path, err)
 	}
 
 	cleansePeerSet(peers)
@@ -67,6 +80,73 @@ func (j *JSONPeerSet) PeerSet() (*PeerSet, error) {
 	return NewPeerSet(peers), nil
 }
 
+// ReadPeerSet reads a PeerSet from a peers file at any path, in either of the
+// formats of the JSONPeerSet.
+func ReadPeerSet(path string) (*PeerSet, error) {
+	peerSet, err := (&JSONPeerSet{path: path}).PeerSet()
+	if err != nil {
+		return nil, err
+	}
+
+	if peerSet == nil {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+
+	return peerSet, nil
+}
+
+// decodePeers decodes a peers file, either in the original format, a JSON array
+// of peers, or in the versioned format.
+func decodePeers(buf []byte) ([]*Peer, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+
+	if trimmed := bytes.TrimSpace(buf); len(trimmed) == 0 || trimmed[0] != '{' {
+		var peers []*Peer
+		if err := dec.Decode(&peers); err != nil {
+			return nil, err
+		}
+		return peers, nil
+	}
+
+	var file jsonPeerSetFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	if file.Version != jsonPeerSetVersion {
+		return nil, fmt.Errorf("Unsupported version %d", file.Version)
+	}
+
+	for _, peer := range file.Peers {
+		// Consensus counts one vote per peer, so the weights, reserved for
+		// weighted voting, can only have the default value
+		if peer.Weight != 0 && peer.Weight != 1 {
+			return nil, fmt.Errorf("Weight %d for peer %s, but weighted voting is not supported", peer.Weight, peer.PubKeyHex)
+		}
+		switch peer.Transport {
+		case "", TransportTCP, TransportWebRTC:
+		default:
+			return nil, fmt.Errorf("Unknown transport %q for peer %s", peer.Transport, peer.PubKeyHex)
+		}
+	}
+
+	return file.Peers, nil
+}
+
+// isVersioned returns true if some peers use fields of the versioned format.
+func isVersioned(peers []*Peer) bool {
+	for _, peer := range peers {
+		if len(peer.Addresses) > 0 ||
+			peer.Transport != "" ||
+			peer.Weight != 0 ||
+			peer.Shadow ||
+			len(peer.Metadata) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // cleansePeerSet standardises the public key strings to match the format Babble
 // derives from a private key.
 func cleansePeerSet(peers []*Peer) {
@@ -75,17 +155,27 @@ func cleansePeerSet(peers []*Peer) {
 	}
 }
 
-// Write persists a PeerSet to a JSON file.
+// Write persists a PeerSet to a JSON file. It uses the original format, which
+// older versions of Babble can read, unless the peers have fields of the
+// versioned format.
 func (j *JSONPeerSet) Write(peers []*Peer) error {
 	j.l.Lock()
 	defer j.l.Unlock()
 
+	var content interface{} = peers
+	if isVersioned(peers) {
+		content = jsonPeerSetFile{
+			Version: jsonPeerSetVersion,
+			Peers:   peers,
+		}
+	}
+
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
-	if err := enc.Encode(peers); err != nil {
+	if err := enc.Encode(content); err != nil {
 		return err
 	}
 
 	// Write out as JSON
-	return ioutil.WriteFile(j.path, buf.Bytes(), 0755)
+	return common.WriteFileAtomic(j.path, buf.Bytes(), 0755)
 }
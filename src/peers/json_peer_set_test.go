@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"crypto/ecdsa"
@@ -87,3 +88,106 @@ func TestJSONPeerSet(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONPeerSetVersioned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "babble")
+	if err != nil {
+		t.Fatalf("err: %v ", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := bkeys.GenerateECDSAKey()
+	pubKeyHex := bkeys.PublicKeyHex(&key.PublicKey)
+
+	// A file in the original format is read with the defaults of the new
+	// fields
+	v1 := fmt.Sprintf(`[{"NetAddr":"addr0","PubKeyHex":"%s","Moniker":"peer0"}]`, pubKeyHex)
+	if err := ioutil.WriteFile(filepath.Join(dir, jsonPeerSetPath), []byte(v1), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewJSONPeerSet(dir, true)
+
+	peerSet, err := store.PeerSet()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	peer := peerSet.Peers[0]
+	if !reflect.DeepEqual(peer.Addrs(), []string{"addr0"}) {
+		t.Fatalf("Addrs should be [addr0], not %v", peer.Addrs())
+	}
+	if peer.TransportHint() != TransportTCP {
+		t.Fatalf("TransportHint should be %s, not %s", TransportTCP, peer.TransportHint())
+	}
+	if peer.VotingWeight() != 1 {
+		t.Fatalf("VotingWeight should be 1, not %d", peer.VotingWeight())
+	}
+	if peer.Meta("region") != "" {
+		t.Fatalf("Meta(region) should be empty, not %s", peer.Meta("region"))
+	}
+
+	// Writing peers without new fields keeps the original format
+	if err := store.Write(peerSet.Peers); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, jsonPeerSetPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != '[' {
+		t.Fatalf("peers file should be a JSON array, not %s", buf)
+	}
+
+	// Peers with new fields are written and read in the versioned format
+	peer.Addresses = []string{"addr1", "addr0", "addr2"}
+	peer.Transport = TransportWebRTC
+	peer.Weight = 1
+	peer.Metadata = map[string]string{"region": "eu"}
+
+	if err := store.Write(peerSet.Peers); err != nil {
+		t.Fatal(err)
+	}
+	buf, err = ioutil.ReadFile(filepath.Join(dir, jsonPeerSetPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != '{' {
+		t.Fatalf("peers file should be a JSON object, not %s", buf)
+	}
+
+	peerSet, err = store.PeerSet()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	peer = peerSet.Peers[0]
+	if !reflect.DeepEqual(peer.Addrs(), []string{"addr0", "addr1", "addr2"}) {
+		t.Fatalf("Addrs should be [addr0 addr1 addr2], not %v", peer.Addrs())
+	}
+	if peer.TransportHint() != TransportWebRTC {
+		t.Fatalf("TransportHint should be %s, not %s", TransportWebRTC, peer.TransportHint())
+	}
+	if peer.VotingWeight() != 1 {
+		t.Fatalf("VotingWeight should be 1, not %d", peer.VotingWeight())
+	}
+	if peer.Meta("region") != "eu" {
+		t.Fatalf("Meta(region) should be eu, not %s", peer.Meta("region"))
+	}
+
+	// Unknown versions and invalid fields are rejected
+	invalid := []string{
+		fmt.Sprintf(`{"version":3,"peers":[{"PubKeyHex":"%s"}]}`, pubKeyHex),
+		fmt.Sprintf(`{"version":2,"peers":[{"PubKeyHex":"%s","Weight":-1}]}`, pubKeyHex),
+		fmt.Sprintf(`{"version":2,"peers":[{"PubKeyHex":"%s","Weight":2}]}`, pubKeyHex),
+		fmt.Sprintf(`{"version":2,"peers":[{"PubKeyHex":"%s","Transport":"udp"}]}`, pubKeyHex),
+	}
+	for _, content := range invalid {
+		if err := ioutil.WriteFile(filepath.Join(dir, jsonPeerSetPath), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.PeerSet(); err == nil {
+			t.Fatalf("store.PeerSet() should fail with %s", content)
+		}
+	}
+}
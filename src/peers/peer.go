@@ -18,10 +18,38 @@ type Peer struct {
 	// Moniker is an optional friendly name for the peer. It does not need to be
 	// unique.
 	Moniker string
+	// Addresses are optional alternative IP:PORT addresses of the Babble node,
+	// tried after NetAddr.
+	Addresses []string `json:",omitempty"`
+	// Transport is an optional hint about the transport used to reach the
+	// peer; TransportTCP or TransportWebRTC.
+	Transport string `json:",omitempty"`
+	// Weight is the optional voting weight of the peer. A zero weight counts
+	// as 1. Weighted voting is not supported yet, so peers files with other
+	// weights are rejected.
+	Weight int `json:",omitempty"`
+	// Shadow marks a peer that participates in gossip and verifies blocks, but
+	// whose witnesses and signatures do not count towards quorums. It is
+	// announced in the peer's join request, to rehearse a new validator before
+	// it affects the liveness of the network.
+	Shadow bool `json:",omitempty"`
+	// Metadata holds arbitrary key-value pairs about the peer.
+	Metadata map[string]string `json:",omitempty"`
+	// AddressSequence counts the PEER_ADDRESS announcements of the peer that
+	// were applied. Each announcement carries the next value, so that an old
+	// announcement, included again by another validator, is refused.
+	AddressSequence int `json:",omitempty"`
 
 	id uint32
 }
 
+const (
+	// TransportTCP is the Transport hint of peers reached over TCP.
+	TransportTCP = "tcp"
+	// TransportWebRTC is the Transport hint of peers reached over WebRTC.
+	TransportWebRTC = "webrtc"
+)
+
 // NewPeer instantiates a Peer.
 func NewPeer(pubKeyHex, netAddr, moniker string) *Peer {
 	peer := &Peer{
@@ -53,6 +81,47 @@ func (p *Peer) PubKeyBytes() []byte {
 	return res
 }
 
+// Addrs returns all the network addresses of the peer, starting with NetAddr,
+// without duplicates.
+func (p *Peer) Addrs() []string {
+	res := []string{}
+	seen := make(map[string]bool)
+	for _, addr := range append([]string{p.NetAddr}, p.Addresses...) {
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		res = append(res, addr)
+	}
+	return res
+}
+
+// TransportHint returns the transport used to reach the peer. Without an
+// explicit hint, it is TransportTCP if the peer has a network address, and
+// TransportWebRTC otherwise.
+func (p *Peer) TransportHint() string {
+	if p.Transport != "" {
+		return p.Transport
+	}
+	if len(p.Addrs()) == 0 {
+		return TransportWebRTC
+	}
+	return TransportTCP
+}
+
+// VotingWeight returns the voting weight of the peer, which defaults to 1.
+func (p *Peer) VotingWeight() int {
+	if p.Weight == 0 {
+		return 1
+	}
+	return p.Weight
+}
+
+// Meta returns the metadata value of the peer for key, or an empty string.
+func (p *Peer) Meta(key string) string {
+	return p.Metadata[key]
+}
+
 // Marshal marshals the Peer object. Note that this excludes the id field,
 // forcing consumers to recalculate it.
 func (p *Peer) Marshal() ([]byte, error) {
@@ -3,7 +3,7 @@ package peers
 import (
 	"bytes"
 	"encoding/json"
-	"math"
+	"strings"
 
 	"github.com/mosaicnetworks/babble/src/common"
 	"github.com/mosaicnetworks/babble/src/crypto"
@@ -15,11 +15,13 @@ type PeerSet struct {
 	ByPubKey map[string]*Peer `json:"-"`
 	ByID     map[uint32]*Peer `json:"-"`
 
+	// voters is the number of Peers that are not shadows. It is counted when
+	// the PeerSet is built, before it is shared.
+	voters int
+
 	// cached values
-	hash          []byte
-	hex           string
-	superMajority *int
-	trustCount    *int
+	hash []byte
+	hex  string
 }
 
 // NewPeerSet creates a new PeerSet from a list of Peers.
@@ -36,10 +38,16 @@ func NewPeerSet(peers []*Peer) *PeerSet {
 func (peerSet *PeerSet) initMaps() {
 	peerSet.ByPubKey = make(map[string]*Peer)
 	peerSet.ByID = make(map[uint32]*Peer)
+	peerSet.voters = 0
 	for _, peer := range peerSet.Peers {
 		peerSet.ByPubKey[peer.PubKeyString()] = peer
 		peerSet.ByID[peer.ID()] = peer
 	}
+	for _, peer := range peerSet.ByPubKey {
+		if !peer.Shadow {
+			peerSet.voters++
+		}
+	}
 }
 
 // WithNewPeer returns a new PeerSet with a list of peers including the new one.
@@ -68,6 +76,35 @@ func (peerSet *PeerSet) WithRemovedPeer(peer *Peer) *PeerSet {
 	return newPeerSet
 }
 
+// WithRenamedPeer returns a new PeerSet where the peer with the same ID as the
+// provided one is replaced by it, which is used to change its moniker or its
+// address.
+func (peerSet *PeerSet) WithRenamedPeer(peer *Peer) *PeerSet {
+	peers := []*Peer{}
+	for _, p := range peerSet.Peers {
+		if p.ID() == peer.ID() {
+			p = peer
+		}
+		peers = append(peers, p)
+	}
+	newPeerSet := NewPeerSet(peers)
+	return newPeerSet
+}
+
+// ByMoniker returns the peer with a given moniker, ignoring case. Empty
+// monikers do not identify any peer.
+func (peerSet *PeerSet) ByMoniker(moniker string) (*Peer, bool) {
+	if moniker == "" {
+		return nil, false
+	}
+	for _, p := range peerSet.Peers {
+		if strings.EqualFold(p.Moniker, moniker) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
 /* ToSlice Methods */
 
 // PubKeys returns the PeerSet's slice of public keys
@@ -99,6 +136,19 @@ func (peerSet *PeerSet) Len() int {
 	return len(peerSet.ByPubKey)
 }
 
+// Voters returns the number of Peers in the PeerSet that are not shadows, and
+// whose witnesses and signatures count towards quorums.
+func (peerSet *PeerSet) Voters() int {
+	return peerSet.voters
+}
+
+// IsVoter returns true if the peer with the given public key belongs to the
+// PeerSet and is not a shadow.
+func (peerSet *PeerSet) IsVoter(pubKey string) bool {
+	p, ok := peerSet.ByPubKey[strings.ToUpper(pubKey)]
+	return ok && !p.Shadow
+}
+
 // Hash uniquely identifies a PeerSet. It is computed by hashing (SHA256) their
 // public keys together, one by one.
 func (peerSet *PeerSet) Hash() ([]byte, error) {
@@ -152,32 +202,7 @@ func (peerSet *PeerSet) Unmarshal(peerSliceBytes []byte) error {
 	return nil
 }
 
-// SuperMajority return the number of peers that forms a strong majortiy (+2/3)
-// in the PeerSet
-func (peerSet *PeerSet) SuperMajority() int {
-	if peerSet.superMajority == nil {
-		val := 2*peerSet.Len()/3 + 1
-		peerSet.superMajority = &val
-	}
-	return *peerSet.superMajority
-}
-
-// TrustCount calculates the minimum number of signatures associated to a state,
-// such that those signatures may represent the finality of such state, given
-// the assumptions of the consensus algorithm.
-func (peerSet *PeerSet) TrustCount() int {
-	if peerSet.trustCount == nil {
-		val := 0
-		if len(peerSet.Peers) > 1 {
-			val = int(math.Ceil(float64(peerSet.Len()) / float64(3)))
-		}
-		peerSet.trustCount = &val
-	}
-	return *peerSet.trustCount
-}
-
 func (peerSet *PeerSet) clearCache() {
 	peerSet.hash = []byte{}
 	peerSet.hex = ""
-	peerSet.superMajority = nil
 }
@@ -0,0 +1,127 @@
+package peers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Threshold is a fraction of a validator-set.
+type Threshold struct {
+	Num int
+	Den int
+}
+
+var (
+	// DefaultSuperMajority is the fraction of validators that a
+	// super-majority must exceed: with 3f+1 validators, it tolerates f faulty
+	// ones.
+	DefaultSuperMajority = Threshold{2, 3}
+
+	// DefaultTrust is the fraction of validators whose signatures a block
+	// must exceed to be trusted, such that at least one of them is honest.
+	DefaultTrust = Threshold{1, 3}
+)
+
+// ParseThreshold parses a fraction like "2/3". An empty string is parsed as the
+// default value.
+func ParseThreshold(s string, def Threshold) (Threshold, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return Threshold{}, fmt.Errorf("threshold %q is not a fraction like 2/3", s)
+	}
+
+	num, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Threshold{}, fmt.Errorf("threshold %q is not a fraction like 2/3", s)
+	}
+
+	den, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || den <= 0 || num < 0 {
+		return Threshold{}, fmt.Errorf("threshold %q is not a fraction like 2/3", s)
+	}
+
+	return Threshold{num, den}, nil
+}
+
+// String returns the fraction in the format read by ParseThreshold.
+func (t Threshold) String() string {
+	return fmt.Sprintf("%d/%d", t.Num, t.Den)
+}
+
+// less returns true if t is strictly less than o.
+func (t Threshold) less(o Threshold) bool {
+	return t.Num*o.Den < o.Num*t.Den
+}
+
+// CheckThresholds verifies that the thresholds preserve the safety of the
+// consensus algorithm. The super-majority must be at least 2/3, so that two
+// super-majorities always have an honest validator in common, and the trust
+// threshold must be at least 1/3, and not above the super-majority. Both must
+// be below 1, otherwise no validator-set could reach them.
+func CheckThresholds(superMajority, trust Threshold) error {
+	one := Threshold{1, 1}
+
+	if superMajority.less(DefaultSuperMajority) || !superMajority.less(one) {
+		return fmt.Errorf("super-majority %s must be at least 2/3 and below 1", superMajority)
+	}
+
+	if trust.less(DefaultTrust) || superMajority.less(trust) {
+		return fmt.Errorf("trust threshold %s must be at least 1/3 and at most the super-majority %s", trust, superMajority)
+	}
+
+	return nil
+}
+
+// Thresholds are the super-majority and trust thresholds of a network. They
+// are defined by the genesis document, and carried by the Hashgraph of each
+// node, so that nodes of different networks can run in the same process.
+type Thresholds struct {
+	SuperMajority Threshold
+	Trust         Threshold
+}
+
+// DefaultThresholds are the thresholds of the networks whose genesis document
+// does not define them.
+var DefaultThresholds = Thresholds{DefaultSuperMajority, DefaultTrust}
+
+// ParseThresholds parses and checks the super-majority and trust thresholds.
+// Empty strings are parsed as the default values.
+func ParseThresholds(superMajority, trust string) (Thresholds, error) {
+	sm, err := ParseThreshold(superMajority, DefaultSuperMajority)
+	if err != nil {
+		return Thresholds{}, err
+	}
+
+	tr, err := ParseThreshold(trust, DefaultTrust)
+	if err != nil {
+		return Thresholds{}, err
+	}
+
+	if err := CheckThresholds(sm, tr); err != nil {
+		return Thresholds{}, err
+	}
+
+	return Thresholds{sm, tr}, nil
+}
+
+// SuperMajorityOf returns the number of voters of the PeerSet that forms a
+// super-majority.
+func (t Thresholds) SuperMajorityOf(peerSet *PeerSet) int {
+	return t.SuperMajority.Num*peerSet.Voters()/t.SuperMajority.Den + 1
+}
+
+// TrustCountOf returns the number of signatures of voters of the PeerSet that
+// a block must exceed to be trusted. It is 0 with a single voter.
+func (t Thresholds) TrustCountOf(peerSet *PeerSet) int {
+	voters := peerSet.Voters()
+	if voters <= 1 {
+		return 0
+	}
+	return int(math.Ceil(float64(t.Trust.Num*voters) / float64(t.Trust.Den)))
+}
@@ -0,0 +1,68 @@
+package peers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestThresholds(t *testing.T) {
+	newPeerSet := func(n int) *PeerSet {
+		ps := []*Peer{}
+		for i := 0; i < n; i++ {
+			ps = append(ps, NewPeer(fmt.Sprintf("0X%02d", i), "", ""))
+		}
+		return NewPeerSet(ps)
+	}
+
+	// Defaults: more than 2/3 and more than 1/3
+	if sm, tc := DefaultThresholds.SuperMajorityOf(newPeerSet(4)), DefaultThresholds.TrustCountOf(newPeerSet(4)); sm != 3 || tc != 2 {
+		t.Fatalf("4 peers should have a super-majority of 3 and a trust count of 2, not %d and %d", sm, tc)
+	}
+
+	th, err := ParseThresholds("3/4", "1/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sm, tc := th.SuperMajorityOf(newPeerSet(4)), th.TrustCountOf(newPeerSet(4)); sm != 4 || tc != 2 {
+		t.Fatalf("4 peers should have a super-majority of 4 and a trust count of 2, not %d and %d", sm, tc)
+	}
+	if sm, tc := th.SuperMajorityOf(newPeerSet(7)), th.TrustCountOf(newPeerSet(7)); sm != 6 || tc != 4 {
+		t.Fatalf("7 peers should have a super-majority of 6 and a trust count of 4, not %d and %d", sm, tc)
+	}
+
+	if _, err := ParseThresholds("1/2", ""); err == nil {
+		t.Fatal("A super-majority of 1/2 should be refused")
+	}
+
+	if th, err := ParseThreshold("3/4", DefaultSuperMajority); err != nil || th != (Threshold{3, 4}) {
+		t.Fatalf("3/4 should be parsed, not %v, %v", th, err)
+	}
+	if th, err := ParseThreshold("", DefaultSuperMajority); err != nil || th != DefaultSuperMajority {
+		t.Fatalf("An empty threshold should be the default, not %v, %v", th, err)
+	}
+}
+
+func TestShadowPeers(t *testing.T) {
+	ps := []*Peer{}
+	for i := 0; i < 6; i++ {
+		p := NewPeer(fmt.Sprintf("0X%02d", i), "", "")
+		p.Shadow = i >= 4
+		ps = append(ps, p)
+	}
+	peerSet := NewPeerSet(ps)
+
+	// The quorums are those of the 4 voters
+	if v, sm, tc := peerSet.Voters(), DefaultThresholds.SuperMajorityOf(peerSet), DefaultThresholds.TrustCountOf(peerSet); v != 4 || sm != 3 || tc != 2 {
+		t.Fatalf("4 voters and 2 shadows should have a super-majority of 3 and a trust count of 2, not %d voters, %d and %d", v, sm, tc)
+	}
+
+	if !peerSet.IsVoter("0x00") || peerSet.IsVoter("0X04") || peerSet.IsVoter("0X10") {
+		t.Fatal("Only the peers of the PeerSet that are not shadows should be voters")
+	}
+
+	// A single voter does not need other signatures, even with shadows
+	if tc := DefaultThresholds.TrustCountOf(NewPeerSet([]*Peer{ps[0], ps[4]})); tc != 0 {
+		t.Fatalf("A single voter should have a trust count of 0, not %d", tc)
+	}
+}
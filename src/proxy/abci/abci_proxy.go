@@ -0,0 +1,186 @@
+// Package abci implements an AppProxy that speaks Tendermint's ABCI socket
+// protocol, so that existing ABCI applications can run on Babble consensus.
+//
+// Only the subset of ABCI required to deliver blocks and query state is
+// supported: Info, BeginBlock, DeliverTx, EndBlock, Commit, and Query. Babble
+// blocks are mapped to ABCI heights by adding one to the block index, because
+// Tendermint heights start at 1. The application's Commit hash is returned to
+// Babble as the block's StateHash.
+//
+// ABCI has no equivalent of Babble's InternalTransactions, so these are
+// automatically accepted. Likewise, the subset does not cover snapshots, so
+// fast-sync is not available to ABCI applications.
+package abci
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/sirupsen/logrus"
+)
+
+// ABCIProxy implements the AppProxy interface on top of an ABCI socket
+// connection.
+type ABCIProxy struct {
+	client   *Client
+	chainID  string
+	submitCh chan []byte
+	guard    proxy.TxGuard
+	logger   *logrus.Entry
+}
+
+// NewABCIProxy creates an ABCIProxy connected to the ABCI application at addr
+// (ex: tcp://127.0.0.1:26658). The chainID is passed to the application in
+// the header of every BeginBlock request. If logger is nil, a new one is
+// created.
+func NewABCIProxy(addr string,
+	chainID string,
+	timeout time.Duration,
+	logger *logrus.Entry) *ABCIProxy {
+
+	if logger == nil {
+		log := logrus.New()
+		log.Level = logrus.DebugLevel
+		logger = logrus.NewEntry(log)
+	}
+
+	return &ABCIProxy{
+		client:   NewClient(addr, timeout, logger),
+		chainID:  chainID,
+		submitCh: make(chan []byte),
+		logger:   logger,
+	}
+}
+
+// SubmitTx submits a transaction to Babble. ABCI applications do not submit
+// transactions through the ABCI connection, so it is up to the host process to
+// relay them here. Transactions rejected by the TxAuthorizer, if any, are
+// dropped.
+func (p *ABCIProxy) SubmitTx(tx []byte) {
+	if err := p.guard.AuthorizeTx(tx); err != nil {
+		p.logger.WithError(err).Warn("Dropping unauthorized transaction")
+		return
+	}
+
+	t := make([]byte, len(tx), len(tx))
+
+	copy(t, tx)
+
+	p.submitCh <- t
+}
+
+// SetTxAuthorizer implements the proxy.TxAuthorizable interface.
+func (p *ABCIProxy) SetTxAuthorizer(a proxy.TxAuthorizer) {
+	p.guard.SetTxAuthorizer(a)
+}
+
+// Info returns information about the ABCI application.
+func (p *ABCIProxy) Info() (ResponseInfo, error) {
+	return p.client.Info("")
+}
+
+// CheckHealth implements the proxy.HealthChecker interface by sending an Info
+// request to the ABCI application.
+func (p *ABCIProxy) CheckHealth() error {
+	_, err := p.Info()
+	return err
+}
+
+// Query queries the state of the ABCI application.
+func (p *ABCIProxy) Query(req RequestQuery) (ResponseQuery, error) {
+	return p.client.Query(req)
+}
+
+// Close closes the connection to the ABCI application.
+func (p *ABCIProxy) Close() error {
+	return p.client.Close()
+}
+
+/*******************************************************************************
+* Implement AppProxy Interface                                                 *
+*******************************************************************************/
+
+// SubmitCh implements the AppProxy interface.
+func (p *ABCIProxy) SubmitCh() chan []byte {
+	return p.submitCh
+}
+
+// CommitBlock implements the AppProxy interface. The block is delivered to the
+// application with the BeginBlock, DeliverTx, EndBlock, Commit sequence.
+// Transactions rejected by the application with a non-zero code remain in the
+// block; as in Tendermint, DeliverTx results do not affect ordering.
+func (p *ABCIProxy) CommitBlock(block hashgraph.Block) (proxy.CommitResponse, error) {
+	height := int64(block.Index() + 1)
+
+	blockHash, err := block.Hash()
+	if err != nil {
+		return proxy.CommitResponse{}, err
+	}
+
+	if err := p.client.BeginBlock(blockHash, p.chainID, height); err != nil {
+		return proxy.CommitResponse{}, fmt.Errorf("BeginBlock: %v", err)
+	}
+
+	for i, tx := range block.Transactions() {
+		res, err := p.client.DeliverTx(tx)
+		if err != nil {
+			return proxy.CommitResponse{}, fmt.Errorf("DeliverTx: %v", err)
+		}
+
+		if res.Code != CodeTypeOK {
+			p.logger.WithFields(logrus.Fields{
+				"block": block.Index(),
+				"tx":    i,
+				"code":  res.Code,
+				"log":   res.Log,
+			}).Debug("ABCIProxy.DeliverTx rejected")
+		}
+	}
+
+	if err := p.client.EndBlock(height); err != nil {
+		return proxy.CommitResponse{}, fmt.Errorf("EndBlock: %v", err)
+	}
+
+	appHash, err := p.client.Commit()
+	if err != nil {
+		return proxy.CommitResponse{}, fmt.Errorf("Commit: %v", err)
+	}
+
+	receipts := []hashgraph.InternalTransactionReceipt{}
+	for _, it := range block.InternalTransactions() {
+		receipts = append(receipts, it.AsAccepted())
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"block":    block.Index(),
+		"height":   height,
+		"txs":      len(block.Transactions()),
+		"app_hash": appHash,
+	}).Debug("ABCIProxy.CommitBlock")
+
+	return proxy.CommitResponse{
+		StateHash:                   appHash,
+		InternalTransactionReceipts: receipts,
+	}, nil
+}
+
+// GetSnapshot implements the AppProxy interface. It is not supported by the
+// ABCI subset.
+func (p *ABCIProxy) GetSnapshot(blockIndex int) ([]byte, error) {
+	return nil, fmt.Errorf("Snapshots are not supported by the ABCI proxy")
+}
+
+// Restore implements the AppProxy interface. It is not supported by the ABCI
+// subset.
+func (p *ABCIProxy) Restore(snapshot []byte) error {
+	return fmt.Errorf("Snapshots are not supported by the ABCI proxy")
+}
+
+// OnStateChanged implements the AppProxy interface. ABCI has no notion of
+// node state, so it does nothing.
+func (p *ABCIProxy) OnStateChanged(state state.State) error {
+	return nil
+}
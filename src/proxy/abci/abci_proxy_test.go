@@ -0,0 +1,235 @@
+package abci
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// kvApp is a minimal ABCI application that stores "key=value" transactions,
+// in the spirit of Tendermint's kvstore example.
+type kvApp struct {
+	listener net.Listener
+	state    map[string]string
+	pending  map[string]string
+	appHash  []byte
+	height   int64
+	heights  []int64
+	chainIDs []string
+}
+
+func newKVApp(t *testing.T) *kvApp {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &kvApp{
+		listener: l,
+		state:    make(map[string]string),
+		pending:  make(map[string]string),
+	}
+
+	go app.serve()
+
+	return app
+}
+
+func (a *kvApp) addr() string {
+	return "tcp://" + a.listener.Addr().String()
+}
+
+func (a *kvApp) serve() {
+	conn, err := a.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return
+		}
+
+		fields, err := decodeFields(msg)
+		if err != nil || len(fields) != 1 {
+			return
+		}
+
+		respField, resp := a.handle(fields[0].num, fields[0].bytes)
+
+		if err := writeMessage(w, wrapRequest(respField, resp)); err != nil {
+			return
+		}
+
+		if respField == fieldFlush {
+			w.Flush()
+		}
+	}
+}
+
+func (a *kvApp) handle(reqField int, body []byte) (int, []byte) {
+	fields, _ := decodeFields(body)
+
+	e := &encoder{}
+
+	switch reqField {
+	case fieldFlush:
+		return fieldFlush, nil
+	case fieldInfo:
+		e.string(1, "kvstore")
+		e.int64(4, a.height)
+		e.bytes(5, a.appHash)
+		return fieldInfo, e.buf
+	case fieldBeginBlock:
+		for _, f := range fields {
+			if f.num == 2 {
+				header, _ := decodeFields(f.bytes)
+				for _, hf := range header {
+					switch hf.num {
+					case 2:
+						a.chainIDs = append(a.chainIDs, string(hf.bytes))
+					case 3:
+						a.heights = append(a.heights, int64(hf.varint))
+					}
+				}
+			}
+		}
+		return fieldBeginBlock, nil
+	case fieldRequestDeliverTx:
+		tx := ""
+		for _, f := range fields {
+			if f.num == 1 {
+				tx = string(f.bytes)
+			}
+		}
+		kv := strings.SplitN(tx, "=", 2)
+		if len(kv) != 2 {
+			e.uvarint(1, 1)
+			e.string(3, "invalid tx")
+			return fieldResponseDeliverTx, e.buf
+		}
+		a.pending[kv[0]] = kv[1]
+		return fieldResponseDeliverTx, e.buf
+	case fieldEndBlock:
+		return fieldEndBlock, nil
+	case fieldCommit:
+		for k, v := range a.pending {
+			a.state[k] = v
+			h := sha256.Sum256(append(a.appHash, []byte(k+"="+v)...))
+			a.appHash = h[:]
+		}
+		a.pending = make(map[string]string)
+		a.height++
+		e.bytes(2, a.appHash)
+		return fieldCommit, e.buf
+	case fieldQuery:
+		key := ""
+		for _, f := range fields {
+			if f.num == 1 {
+				key = string(f.bytes)
+			}
+		}
+		e.bytes(6, []byte(key))
+		e.bytes(7, []byte(a.state[key]))
+		e.int64(9, a.height)
+		return fieldQuery, e.buf
+	default:
+		e.string(1, "unsupported request")
+		return fieldException, e.buf
+	}
+}
+
+func TestABCIProxyCommitBlock(t *testing.T) {
+	app := newKVApp(t)
+	defer app.listener.Close()
+
+	proxy := NewABCIProxy(app.addr(),
+		"test-chain",
+		time.Second,
+		common.NewTestEntry(t, common.TestLogLevel))
+	defer proxy.Close()
+
+	itx := hashgraph.NewInternalTransaction(hashgraph.PEER_ADD,
+		*peers.NewPeer("0XABCDEF", "addr", "monika"))
+
+	block := hashgraph.NewBlock(0,
+		1,
+		[]byte("framehash"),
+		[]*peers.Peer{},
+		[][]byte{[]byte("name=babble"), []byte("garbage"), []byte("consensus=hashgraph")},
+		[]hashgraph.InternalTransaction{itx},
+	)
+
+	resp, err := proxy.CommitBlock(*block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(resp.StateHash, app.appHash) {
+		t.Fatalf("StateHash should be %x, not %x", app.appHash, resp.StateHash)
+	}
+
+	if len(resp.InternalTransactionReceipts) != 1 ||
+		!resp.InternalTransactionReceipts[0].Accepted {
+		t.Fatalf("InternalTransaction should be accepted")
+	}
+
+	if !reflect.DeepEqual(app.heights, []int64{1}) {
+		t.Fatalf("BeginBlock heights should be [1], not %v", app.heights)
+	}
+
+	if !reflect.DeepEqual(app.chainIDs, []string{"test-chain"}) {
+		t.Fatalf("BeginBlock chain-ids should be [test-chain], not %v", app.chainIDs)
+	}
+
+	query, err := proxy.Query(RequestQuery{Data: []byte("name")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(query.Value, []byte("babble")) {
+		t.Fatalf("Query value should be babble, not %s", query.Value)
+	}
+
+	if query.Height != 1 {
+		t.Fatalf("Query height should be 1, not %d", query.Height)
+	}
+
+	info, err := proxy.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Data != "kvstore" || info.LastBlockHeight != 1 {
+		t.Fatalf("Unexpected Info response %+v", info)
+	}
+}
+
+func TestABCIProxySnapshot(t *testing.T) {
+	proxy := NewABCIProxy("tcp://127.0.0.1:0",
+		"test-chain",
+		time.Second,
+		common.NewTestEntry(t, common.TestLogLevel))
+
+	if _, err := proxy.GetSnapshot(0); err == nil {
+		t.Fatalf("GetSnapshot should return an error")
+	}
+
+	if err := proxy.Restore([]byte{}); err == nil {
+		t.Fatalf("Restore should return an error")
+	}
+}
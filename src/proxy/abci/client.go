@@ -0,0 +1,212 @@
+package abci
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Client is a synchronous ABCI socket client. Every request is immediately
+// followed by a Flush, so the application answers each request before the
+// next one is sent.
+type Client struct {
+	sync.Mutex
+
+	addr    string
+	timeout time.Duration
+
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+
+	logger *logrus.Entry
+}
+
+// NewClient creates a Client that connects to an ABCI application at addr.
+// The address follows the Tendermint convention, "tcp://host:port" or
+// "unix:///path/to/socket"; addresses without a scheme are treated as TCP.
+// The connection is established lazily, upon the first request.
+func NewClient(addr string, timeout time.Duration, logger *logrus.Entry) *Client {
+	return &Client{
+		addr:    addr,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// Close closes the underlying connection, if any.
+func (c *Client) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.release()
+}
+
+func (c *Client) release() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+
+	return err
+}
+
+func (c *Client) getConnection() error {
+	if c.conn != nil {
+		return nil
+	}
+
+	network, address := "tcp", c.addr
+	if i := strings.Index(c.addr, "://"); i >= 0 {
+		network, address = c.addr[:i], c.addr[i+3:]
+	}
+
+	conn, err := net.DialTimeout(network, address, c.timeout)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+	c.w = bufio.NewWriter(conn)
+
+	return nil
+}
+
+// call sends a request followed by a Flush, and returns the body of the
+// response. The connection is dropped on any error to resynchronise the
+// request/response stream on the next call.
+func (c *Client) call(reqField int, req []byte, respField int) ([]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.getConnection(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.roundTrip(reqField, req, respField)
+	if err != nil {
+		c.release()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Client) roundTrip(reqField int, req []byte, respField int) ([]byte, error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	if err := writeMessage(c.w, wrapRequest(reqField, req)); err != nil {
+		return nil, err
+	}
+
+	if err := writeMessage(c.w, wrapRequest(fieldFlush, nil)); err != nil {
+		return nil, err
+	}
+
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	msg, err := readMessage(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := unwrapResponse(msg, respField)
+	if err != nil {
+		return nil, err
+	}
+
+	flush, err := readMessage(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := unwrapResponse(flush, fieldFlush); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Info requests information about the application.
+func (c *Client) Info(version string) (ResponseInfo, error) {
+	var info ResponseInfo
+
+	resp, err := c.call(fieldInfo, encodeInfoRequest(version), fieldInfo)
+	if err != nil {
+		return info, err
+	}
+
+	err = info.decode(resp)
+
+	return info, err
+}
+
+// Query queries the application state.
+func (c *Client) Query(req RequestQuery) (ResponseQuery, error) {
+	var query ResponseQuery
+
+	resp, err := c.call(fieldQuery, req.encode(), fieldQuery)
+	if err != nil {
+		return query, err
+	}
+
+	err = query.decode(resp)
+
+	return query, err
+}
+
+// BeginBlock signals the beginning of a new block.
+func (c *Client) BeginBlock(hash []byte, chainID string, height int64) error {
+	_, err := c.call(fieldBeginBlock,
+		encodeBeginBlockRequest(hash, chainID, height),
+		fieldBeginBlock)
+	return err
+}
+
+// DeliverTx delivers a transaction to the application.
+func (c *Client) DeliverTx(tx []byte) (ResponseDeliverTx, error) {
+	var deliverTx ResponseDeliverTx
+
+	resp, err := c.call(fieldRequestDeliverTx,
+		encodeDeliverTxRequest(tx),
+		fieldResponseDeliverTx)
+	if err != nil {
+		return deliverTx, err
+	}
+
+	err = deliverTx.decode(resp)
+
+	return deliverTx, err
+}
+
+// EndBlock signals the end of a block.
+func (c *Client) EndBlock(height int64) error {
+	_, err := c.call(fieldEndBlock, encodeEndBlockRequest(height), fieldEndBlock)
+	return err
+}
+
+// Commit instructs the application to persist its state, and returns the
+// resulting app hash.
+func (c *Client) Commit() ([]byte, error) {
+	var commit responseCommit
+
+	resp, err := c.call(fieldCommit, nil, fieldCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	err = commit.decode(resp)
+
+	return commit.Data, err
+}
@@ -0,0 +1,234 @@
+package abci
+
+import "fmt"
+
+// Field numbers of the Request and Response oneofs, as defined in Tendermint's
+// abci/types/types.proto (v0.33).
+const (
+	fieldException  = 1
+	fieldEcho       = 2
+	fieldFlush      = 3
+	fieldInfo       = 4
+	fieldQuery      = 7
+	fieldBeginBlock = 8
+	fieldEndBlock   = 11
+	fieldCommit     = 12
+
+	// DeliverTx uses a different field number in requests and responses.
+	fieldRequestDeliverTx  = 19
+	fieldResponseDeliverTx = 10
+)
+
+// CodeTypeOK is the ABCI response code indicating success.
+const CodeTypeOK uint32 = 0
+
+// RequestQuery is used to query the application state.
+type RequestQuery struct {
+	Data   []byte
+	Path   string
+	Height int64
+	Prove  bool
+}
+
+func (r RequestQuery) encode() []byte {
+	e := &encoder{}
+	e.bytes(1, r.Data)
+	e.string(2, r.Path)
+	e.int64(3, r.Height)
+	e.bool(4, r.Prove)
+	return e.buf
+}
+
+// ResponseQuery is the application's response to a RequestQuery.
+type ResponseQuery struct {
+	Code      uint32
+	Log       string
+	Info      string
+	Index     int64
+	Key       []byte
+	Value     []byte
+	Height    int64
+	Codespace string
+}
+
+func (r *ResponseQuery) decode(data []byte) error {
+	fields, err := decodeFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			r.Code = uint32(f.varint)
+		case 3:
+			r.Log = string(f.bytes)
+		case 4:
+			r.Info = string(f.bytes)
+		case 5:
+			r.Index = int64(f.varint)
+		case 6:
+			r.Key = f.bytes
+		case 7:
+			r.Value = f.bytes
+		case 9:
+			r.Height = int64(f.varint)
+		case 10:
+			r.Codespace = string(f.bytes)
+		}
+	}
+	return nil
+}
+
+// ResponseInfo contains information about the application, returned by the
+// Info method.
+type ResponseInfo struct {
+	Data             string
+	Version          string
+	AppVersion       uint64
+	LastBlockHeight  int64
+	LastBlockAppHash []byte
+}
+
+func (r *ResponseInfo) decode(data []byte) error {
+	fields, err := decodeFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			r.Data = string(f.bytes)
+		case 2:
+			r.Version = string(f.bytes)
+		case 3:
+			r.AppVersion = f.varint
+		case 4:
+			r.LastBlockHeight = int64(f.varint)
+		case 5:
+			r.LastBlockAppHash = f.bytes
+		}
+	}
+	return nil
+}
+
+// ResponseDeliverTx is the application's response to a DeliverTx request.
+type ResponseDeliverTx struct {
+	Code      uint32
+	Data      []byte
+	Log       string
+	Info      string
+	Codespace string
+}
+
+func (r *ResponseDeliverTx) decode(data []byte) error {
+	fields, err := decodeFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			r.Code = uint32(f.varint)
+		case 2:
+			r.Data = f.bytes
+		case 3:
+			r.Log = string(f.bytes)
+		case 4:
+			r.Info = string(f.bytes)
+		case 8:
+			r.Codespace = string(f.bytes)
+		}
+	}
+	return nil
+}
+
+// responseCommit only carries the application's state hash.
+type responseCommit struct {
+	Data []byte
+}
+
+func (r *responseCommit) decode(data []byte) error {
+	fields, err := decodeFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num == 2 {
+			r.Data = f.bytes
+		}
+	}
+	return nil
+}
+
+func encodeInfoRequest(version string) []byte {
+	e := &encoder{}
+	e.string(1, version)
+	return e.buf
+}
+
+// encodeBeginBlockRequest encodes a RequestBeginBlock with the block hash and
+// a Header that only carries the chain-id and height.
+func encodeBeginBlockRequest(hash []byte, chainID string, height int64) []byte {
+	header := &encoder{}
+	header.string(2, chainID)
+	header.int64(3, height)
+
+	e := &encoder{}
+	e.bytes(1, hash)
+	e.message(2, header.buf)
+	return e.buf
+}
+
+func encodeEndBlockRequest(height int64) []byte {
+	e := &encoder{}
+	e.int64(1, height)
+	return e.buf
+}
+
+func encodeDeliverTxRequest(tx []byte) []byte {
+	e := &encoder{}
+	e.bytes(1, tx)
+	return e.buf
+}
+
+// wrapRequest wraps a typed request in the Request oneof.
+func wrapRequest(fieldNum int, body []byte) []byte {
+	e := &encoder{}
+	e.message(fieldNum, body)
+	return e.buf
+}
+
+// unwrapResponse extracts the typed response from the Response oneof, and
+// checks that it is of the expected type. An Exception response is turned into
+// an error.
+func unwrapResponse(msg []byte, expected int) ([]byte, error) {
+	fields, err := decodeFields(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) != 1 || fields[0].wireType != wireBytes {
+		return nil, fmt.Errorf("abci: malformed response")
+	}
+
+	f := fields[0]
+
+	if f.num == fieldException {
+		exFields, err := decodeFields(f.bytes)
+		if err != nil {
+			return nil, err
+		}
+		for _, ef := range exFields {
+			if ef.num == 1 {
+				return nil, fmt.Errorf("abci: application exception: %s", ef.bytes)
+			}
+		}
+		return nil, fmt.Errorf("abci: application exception")
+	}
+
+	if f.num != expected {
+		return nil, fmt.Errorf("abci: unexpected response type %d, expected %d", f.num, expected)
+	}
+
+	return f.bytes, nil
+}
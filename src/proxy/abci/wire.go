@@ -0,0 +1,169 @@
+package abci
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The ABCI socket protocol exchanges protobuf messages, each prefixed by its
+// length encoded as a signed varint. Only the handful of protobuf wire types
+// used by the supported ABCI messages are implemented here, which spares us a
+// dependency on the entire Tendermint module.
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// maxMessageSize is the largest ABCI message we accept from the application.
+const maxMessageSize = 64 * 1024 * 1024
+
+var errTruncated = errors.New("abci: truncated message")
+
+// encoder accumulates the protobuf encoding of a message.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) putUvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	e.buf = append(e.buf, tmp[:n]...)
+}
+
+func (e *encoder) key(field int, wireType int) {
+	e.putUvarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (e *encoder) uvarint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.key(field, wireVarint)
+	e.putUvarint(v)
+}
+
+func (e *encoder) int64(field int, v int64) {
+	e.uvarint(field, uint64(v))
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uvarint(field, 1)
+	}
+}
+
+func (e *encoder) bytes(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	e.key(field, wireBytes)
+	e.putUvarint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *encoder) string(field int, v string) {
+	e.bytes(field, []byte(v))
+}
+
+// message encodes an embedded message. Unlike scalar fields, embedded messages
+// are always written, even when empty, because their presence selects the
+// branch of a oneof.
+func (e *encoder) message(field int, v []byte) {
+	e.key(field, wireBytes)
+	e.putUvarint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// field is a single decoded protobuf field.
+type field struct {
+	num      int
+	wireType int
+	varint   uint64
+	bytes    []byte
+}
+
+// decodeFields splits a protobuf message into its fields. Fixed-size fields
+// are skipped since none of the ABCI messages we read rely on them.
+func decodeFields(data []byte) ([]field, error) {
+	fields := []field{}
+
+	for len(data) > 0 {
+		k, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		data = data[n:]
+
+		f := field{
+			num:      int(k >> 3),
+			wireType: int(k & 7),
+		}
+
+		switch f.wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errTruncated
+			}
+			f.varint = v
+			data = data[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return nil, errTruncated
+			}
+			f.bytes = data[n : n+int(l)]
+			data = data[n+int(l):]
+		case 1: // 64-bit
+			if len(data) < 8 {
+				return nil, errTruncated
+			}
+			data = data[8:]
+		case 5: // 32-bit
+			if len(data) < 4 {
+				return nil, errTruncated
+			}
+			data = data[4:]
+		default:
+			return nil, fmt.Errorf("abci: unsupported wire type %d", f.wireType)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// writeMessage writes a length-prefixed message.
+func writeMessage(w io.Writer, msg []byte) error {
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(prefix[:], int64(len(msg)))
+	if _, err := w.Write(prefix[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// readMessage reads a length-prefixed message.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if l < 0 || l > maxMessageSize {
+		return nil, fmt.Errorf("abci: invalid message length %d", l)
+	}
+
+	msg := make([]byte, l)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/metrics"
+)
+
+// ErrUnauthorizedTx is returned by the TxAuthorizers that reject a transaction.
+var ErrUnauthorizedTx = errors.New("transaction not authorized")
+
+// TxAuthorizer decides whether a transaction submitted to Babble is accepted,
+// before it takes any space in the transaction pool or in a block. credential
+// is the API key presented with the transaction, or empty when the submission
+// channel does not carry one, like the socket AppProxy.
+type TxAuthorizer interface {
+	AuthorizeTx(tx []byte, credential string) error
+}
+
+// TxAuthorizerFunc adapts a function to the TxAuthorizer interface.
+type TxAuthorizerFunc func(tx []byte, credential string) error
+
+// AuthorizeTx implements the TxAuthorizer interface.
+func (f TxAuthorizerFunc) AuthorizeTx(tx []byte, credential string) error {
+	return f(tx, credential)
+}
+
+// TxAuthorizable is implemented by the AppProxies that check the transactions
+// submitted by the App with a TxAuthorizer.
+type TxAuthorizable interface {
+	SetTxAuthorizer(TxAuthorizer)
+}
+
+// APIKeyAuthorizer accepts the transactions presented with one of its API
+// keys.
+type APIKeyAuthorizer struct {
+	keys []string
+}
+
+// NewAPIKeyAuthorizer creates an APIKeyAuthorizer from a comma-separated list
+// of API keys.
+func NewAPIKeyAuthorizer(list string) *APIKeyAuthorizer {
+	a := &APIKeyAuthorizer{}
+	for _, k := range strings.Split(list, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			a.keys = append(a.keys, k)
+		}
+	}
+	return a
+}
+
+// AuthorizeTx implements the TxAuthorizer interface.
+func (a *APIKeyAuthorizer) AuthorizeTx(tx []byte, credential string) error {
+	if credential == "" {
+		return fmt.Errorf("%v: missing API key", ErrUnauthorizedTx)
+	}
+
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(credential), []byte(k)) == 1 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%v: invalid API key", ErrUnauthorizedTx)
+}
+
+// SignatureAuthorizer accepts the SignedTxs signed by one of the registered
+// client keys.
+type SignatureAuthorizer struct {
+	clients map[string]bool
+}
+
+// NewSignatureAuthorizer creates a SignatureAuthorizer accepting the
+// transactions signed by the given client keys.
+func NewSignatureAuthorizer(clients []*ecdsa.PublicKey) *SignatureAuthorizer {
+	a := &SignatureAuthorizer{
+		clients: make(map[string]bool),
+	}
+	for _, pub := range clients {
+		a.clients[keys.PublicKeyHex(pub)] = true
+	}
+	return a
+}
+
+// ReadClientKeys reads a file of hex encoded client public keys, with the 0X
+// prefix, one per line. Empty lines and lines starting with # are ignored.
+func ReadClientKeys(path string) ([]*ecdsa.PublicKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	clients := []*ecdsa.PublicKey{}
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+
+		pub, err := parsePublicKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+
+		clients = append(clients, pub)
+	}
+
+	return clients, scanner.Err()
+}
+
+// AuthorizeTx implements the TxAuthorizer interface.
+func (a *SignatureAuthorizer) AuthorizeTx(tx []byte, credential string) error {
+	_, pub, err := OpenSignedTx(tx)
+	if err != nil {
+		return fmt.Errorf("%v: %v", ErrUnauthorizedTx, err)
+	}
+
+	if !a.clients[keys.PublicKeyHex(pub)] {
+		return fmt.Errorf("%v: unknown client key", ErrUnauthorizedTx)
+	}
+
+	return nil
+}
+
+// AnyTxAuthorizer accepts the transactions accepted by at least one of the
+// authorizers. It returns the error of the last authorizer otherwise.
+func AnyTxAuthorizer(authorizers ...TxAuthorizer) TxAuthorizer {
+	return TxAuthorizerFunc(func(tx []byte, credential string) error {
+		err := ErrUnauthorizedTx
+		for _, a := range authorizers {
+			if err = a.AuthorizeTx(tx, credential); err == nil {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+// TxGuard holds the TxAuthorizer of an AppProxy, to implement TxAuthorizable.
+// The zero value accepts all the transactions.
+type TxGuard struct {
+	mu         sync.RWMutex
+	authorizer TxAuthorizer
+}
+
+// SetTxAuthorizer implements the TxAuthorizable interface.
+func (g *TxGuard) SetTxAuthorizer(a TxAuthorizer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.authorizer = a
+}
+
+// AuthorizeTx checks a transaction submitted by the App, which carries no
+// credential, with the TxAuthorizer, if any.
+func (g *TxGuard) AuthorizeTx(tx []byte) error {
+	g.mu.RLock()
+	a := g.authorizer
+	g.mu.RUnlock()
+
+	return AuthorizeTx(a, tx, "", "proxy")
+}
+
+// AuthorizeTx checks a transaction with an authorizer, which may be nil, and
+// counts the rejected transactions by source.
+func AuthorizeTx(a TxAuthorizer, tx []byte, credential string, source string) error {
+	if a == nil {
+		return nil
+	}
+
+	if err := a.AuthorizeTx(tx, credential); err != nil {
+		metrics.TxUnauthorized.WithLabelValues(source).Inc()
+		return err
+	}
+
+	return nil
+}
+
+// parsePublicKey parses a hex encoded public key, with the 0X prefix.
+func parsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	s = strings.ToUpper(s)
+	if !strings.HasPrefix(s, "0X") {
+		return nil, fmt.Errorf("invalid public key %q", s)
+	}
+
+	pubBytes, err := common.DecodeFromString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %q", s)
+	}
+
+	pub := keys.ToPublicKey(pubBytes)
+	if pub == nil || pub.X == nil || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("invalid public key %q", s)
+	}
+
+	return pub, nil
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+)
+
+func TestSignedTx(t *testing.T) {
+	priv, err := keys.GenerateECDSAKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := SignTx([]byte("payload"), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, pub, err := OpenSignedTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload, []byte("payload")) {
+		t.Fatalf("The payload should be %q, not %q", "payload", payload)
+	}
+	if keys.PublicKeyHex(pub) != keys.PublicKeyHex(&priv.PublicKey) {
+		t.Fatalf("The signer should be the client key")
+	}
+
+	tampered := bytes.Replace(tx, []byte("cGF5bG9hZA=="), []byte("b3RoZXI="), 1)
+	if _, _, err := OpenSignedTx(tampered); err == nil {
+		t.Fatalf("A tampered transaction should not be opened")
+	}
+}
+
+func TestTxAuthorizers(t *testing.T) {
+	client, _ := keys.GenerateECDSAKey()
+	stranger, _ := keys.GenerateECDSAKey()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clients")
+	content := "# registered clients\n\n" + keys.PublicKeyHex(&client.PublicKey) + "\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	clients, err := ReadClientKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 {
+		t.Fatalf("There should be 1 client, not %d", len(clients))
+	}
+
+	signed, _ := SignTx([]byte("tx"), client)
+	signedByStranger, _ := SignTx([]byte("tx"), stranger)
+
+	a := AnyTxAuthorizer(NewAPIKeyAuthorizer("k3y1, k3y2"), NewSignatureAuthorizer(clients))
+
+	cases := []struct {
+		tx         []byte
+		credential string
+		authorized bool
+	}{
+		{tx: []byte("tx"), credential: "k3y2", authorized: true},
+		{tx: []byte("tx"), credential: "wrong", authorized: false},
+		{tx: []byte("tx"), credential: "", authorized: false},
+		{tx: signed, credential: "", authorized: true},
+		{tx: signedByStranger, credential: "", authorized: false},
+		{tx: signedByStranger, credential: "k3y1", authorized: true},
+	}
+
+	for i, c := range cases {
+		err := a.AuthorizeTx(c.tx, c.credential)
+		if (err == nil) != c.authorized {
+			t.Fatalf("Case %d should be authorized: %v, not %v", i, c.authorized, err)
+		}
+	}
+
+	var g TxGuard
+	if err := g.AuthorizeTx([]byte("tx")); err != nil {
+		t.Fatalf("A TxGuard without authorizer should accept all the transactions: %v", err)
+	}
+
+	g.SetTxAuthorizer(NewSignatureAuthorizer([]*ecdsa.PublicKey{&client.PublicKey}))
+	if err := g.AuthorizeTx([]byte("tx")); err == nil {
+		t.Fatalf("The TxGuard should reject unsigned transactions")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("0X04AB\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadClientKeys(path); err == nil {
+		t.Fatalf("An invalid client key should be refused")
+	}
+}
@@ -1,8 +1,8 @@
 // Package proxy defines and implements AppProxy: the interface between Babble
 // and an application.
 //
-// Babble communicates with the App through an AppProxy interface, which has two
-// implementations:
+// Babble communicates with the App through an AppProxy interface, which has
+// three implementations:
 //
 // - SocketProxy: A SocketProxy connects to an App via TCP sockets. It enables
 // the application to run in a separate process or machine, and to be written in
@@ -10,4 +10,7 @@
 //
 // - InmemProxy: An InmemProxy uses native callback handlers to integrate Babble
 // as a regular Go dependency.
+//
+// - ABCIProxy: An ABCIProxy connects to an application written for
+// Tendermint's ABCI socket protocol, so that it can run on Babble consensus.
 package proxy
@@ -26,3 +26,33 @@ type ProxyHandler interface {
 	// node entered a certain state
 	StateChangeHandler(state.State) error
 }
+
+// GenesisHandler is implemented by the ProxyHandlers of the Apps whose genesis
+// document defines an app_hash.
+type GenesisHandler interface {
+	// GenesisHandler is called by Babble on start to retrieve the hash of the
+	// initial state of the application, before any block was committed
+	GenesisHandler() (stateHash []byte, err error)
+}
+
+// SnapshotDeltaHandler is implemented by the ProxyHandlers of the Apps that use
+// the snapshot-delta option.
+type SnapshotDeltaHandler interface {
+	// SnapshotDeltaHandler is called by Babble to retrieve the difference
+	// between the snapshots corresponding to two blocks
+	SnapshotDeltaHandler(fromIndex, toIndex int) (delta []byte, err error)
+
+	// RestoreDeltaHandler is called by Babble to apply a difference returned
+	// by the SnapshotDeltaHandler to the application, whose state corresponds
+	// to the block fromIndex
+	RestoreDeltaHandler(fromIndex int, delta []byte) (stateHash []byte, err error)
+}
+
+// SnapshotHashHandler is implemented by the ProxyHandlers of the Apps that use
+// the verify-snapshots option.
+type SnapshotHashHandler interface {
+	// HashSnapshotHandler is called by Babble to compute the state hash that
+	// the application would have if it restored a snapshot, without
+	// restoring it
+	HashSnapshotHandler(snapshot []byte) (stateHash []byte, err error)
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+// IdempotencyAware is implemented by the AppProxies that recognise the
+// transactions resubmitted by the App with the same idempotency key.
+type IdempotencyAware interface {
+	SetIdempotencyCache(*IdempotencyCache)
+}
+
+// IdempotencyCache remembers the hash of the transactions submitted with an
+// idempotency key, for a window of time, so that a client retrying a
+// submission, after a timeout or a lost response, gets the result of the
+// original submission instead of creating a duplicate transaction. The body of
+// a resubmission is not compared with the original one; the key alone
+// identifies the transaction. A nil IdempotencyCache remembers nothing.
+type IdempotencyCache struct {
+	sync.Mutex
+
+	window time.Duration
+	now    func() time.Time
+	pruned time.Time
+	hashes map[string]idempotentTx
+}
+
+type idempotentTx struct {
+	hash string
+	time time.Time
+}
+
+// NewIdempotencyCache creates an IdempotencyCache that remembers the keys for
+// the given window.
+func NewIdempotencyCache(window time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{
+		window: window,
+		now:    time.Now,
+		pruned: time.Now(),
+		hashes: make(map[string]idempotentTx),
+	}
+}
+
+// Reserve records the hash of a transaction about to be submitted with an
+// idempotency key. If the key was used within the window, it returns the hash
+// of the original transaction and true instead, and the transaction should not
+// be submitted. An empty key is never remembered.
+func (c *IdempotencyCache) Reserve(key string, tx []byte) (string, bool) {
+	hash := hashgraph.TxHash(tx)
+
+	if c == nil || key == "" {
+		return hash, false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	now := c.now()
+
+	if now.Sub(c.pruned) >= c.window {
+		c.prune(now)
+	}
+
+	if orig, ok := c.hashes[key]; ok && now.Sub(orig.time) < c.window {
+		return orig.hash, true
+	}
+
+	c.hashes[key] = idempotentTx{hash: hash, time: now}
+
+	return hash, false
+}
+
+// Release forgets a key, when the submission of its transaction failed, so
+// that the client can retry it.
+func (c *IdempotencyCache) Release(key string) {
+	if c == nil || key == "" {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.hashes, key)
+}
+
+// prune forgets the keys older than the window. It is called at most once per
+// window, so the cache holds the keys of up to two windows.
+func (c *IdempotencyCache) prune(now time.Time) {
+	for key, tx := range c.hashes {
+		if now.Sub(tx.time) >= c.window {
+			delete(c.hashes, key)
+		}
+	}
+
+	c.pruned = now
+}
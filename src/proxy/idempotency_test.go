@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	c := NewIdempotencyCache(time.Minute)
+	c.now = func() time.Time { return now }
+	c.pruned = now
+
+	hash, duplicate := c.Reserve("k1", []byte("tx1"))
+	if duplicate || hash != hashgraph.TxHash([]byte("tx1")) {
+		t.Fatal("The first transaction with a key should be submitted")
+	}
+
+	// The body of a resubmission is ignored
+	now = now.Add(30 * time.Second)
+	if h, duplicate := c.Reserve("k1", []byte("tx1 again")); !duplicate || h != hash {
+		t.Fatal("A resubmission should return the hash of the original transaction")
+	}
+
+	// Transactions without a key are never duplicates
+	for i := 0; i < 2; i++ {
+		if _, duplicate := c.Reserve("", []byte("tx2")); duplicate {
+			t.Fatal("A transaction without a key should always be submitted")
+		}
+	}
+
+	// A released key can be used again
+	c.Reserve("k2", []byte("tx3"))
+	c.Release("k2")
+	if _, duplicate := c.Reserve("k2", []byte("tx3")); duplicate {
+		t.Fatal("A released key should be forgotten")
+	}
+
+	// Keys are forgotten after the window
+	now = now.Add(time.Minute)
+	if _, duplicate := c.Reserve("k1", []byte("tx4")); duplicate {
+		t.Fatal("A key should be forgotten after the window")
+	}
+	if _, ok := c.hashes["k2"]; ok {
+		t.Fatal("The keys older than the window should be pruned")
+	}
+
+	// A nil cache remembers nothing
+	var nilCache *IdempotencyCache
+	if _, duplicate := nilCache.Reserve("k1", []byte("tx1")); duplicate {
+		t.Fatal("A nil cache should not find duplicates")
+	}
+}
@@ -3,6 +3,8 @@
 package inmem
 
 import (
+	"fmt"
+
 	hg "github.com/mosaicnetworks/babble/src/hashgraph"
 	"github.com/mosaicnetworks/babble/src/node/state"
 	"github.com/mosaicnetworks/babble/src/proxy"
@@ -15,6 +17,7 @@ import (
 type InmemProxy struct {
 	handler  proxy.ProxyHandler
 	submitCh chan []byte
+	guard    proxy.TxGuard
 	logger   *logrus.Entry
 }
 
@@ -41,7 +44,13 @@ func NewInmemProxy(handler proxy.ProxyHandler,
 *******************************************************************************/
 
 // SubmitTx is called by the App to submit a transaction to Babble.
+// Transactions rejected by the TxAuthorizer, if any, are dropped.
 func (p *InmemProxy) SubmitTx(tx []byte) {
+	if err := p.guard.AuthorizeTx(tx); err != nil {
+		p.logger.WithError(err).Warn("Dropping unauthorized transaction")
+		return
+	}
+
 	//have to make a copy, or the tx will be garbage collected and weird stuff
 	//happens in transaction pool
 	t := make([]byte, len(tx), len(tx))
@@ -51,6 +60,11 @@ func (p *InmemProxy) SubmitTx(tx []byte) {
 	p.submitCh <- t
 }
 
+// SetTxAuthorizer implements the proxy.TxAuthorizable interface.
+func (p *InmemProxy) SetTxAuthorizer(a proxy.TxAuthorizer) {
+	p.guard.SetTxAuthorizer(a)
+}
+
 /*******************************************************************************
 * Implement AppProxy Interface                                                 *
 *******************************************************************************/
@@ -104,6 +118,82 @@ func (p *InmemProxy) Restore(snapshot []byte) error {
 	return err
 }
 
+// GenesisStateHash implements the proxy.GenesisProvider interface. It calls the
+// GenesisHandler, and fails if the handler does not implement it.
+func (p *InmemProxy) GenesisStateHash() ([]byte, error) {
+	handler, ok := p.handler.(proxy.GenesisHandler)
+	if !ok {
+		return nil, fmt.Errorf("the ProxyHandler does not implement GenesisHandler")
+	}
+
+	stateHash, err := handler.GenesisHandler()
+
+	p.logger.WithFields(logrus.Fields{
+		"state_hash": stateHash,
+		"err":        err,
+	}).Debug("InmemProxy.GenesisStateHash")
+
+	return stateHash, err
+}
+
+// SnapshotDelta implements the proxy.SnapshotDeltaProvider interface. It calls
+// the SnapshotDeltaHandler, and fails if the handler does not implement it.
+func (p *InmemProxy) SnapshotDelta(fromIndex, toIndex int) ([]byte, error) {
+	handler, ok := p.handler.(proxy.SnapshotDeltaHandler)
+	if !ok {
+		return nil, fmt.Errorf("the ProxyHandler does not implement SnapshotDeltaHandler")
+	}
+
+	delta, err := handler.SnapshotDeltaHandler(fromIndex, toIndex)
+
+	p.logger.WithFields(logrus.Fields{
+		"from":  fromIndex,
+		"to":    toIndex,
+		"delta": len(delta),
+		"err":   err,
+	}).Debug("InmemProxy.SnapshotDelta")
+
+	return delta, err
+}
+
+// RestoreDelta implements the proxy.SnapshotDeltaProvider interface. It calls
+// the RestoreDeltaHandler, and fails if the handler does not implement it.
+func (p *InmemProxy) RestoreDelta(fromIndex int, delta []byte) error {
+	handler, ok := p.handler.(proxy.SnapshotDeltaHandler)
+	if !ok {
+		return fmt.Errorf("the ProxyHandler does not implement SnapshotDeltaHandler")
+	}
+
+	stateHash, err := handler.RestoreDeltaHandler(fromIndex, delta)
+
+	p.logger.WithFields(logrus.Fields{
+		"from":       fromIndex,
+		"state_hash": stateHash,
+		"err":        err,
+	}).Debug("InmemProxy.RestoreDelta")
+
+	return err
+}
+
+// HashSnapshot implements the proxy.SnapshotHasher interface. It calls the
+// HashSnapshotHandler, and fails if the handler does not implement it.
+func (p *InmemProxy) HashSnapshot(snapshot []byte) ([]byte, error) {
+	handler, ok := p.handler.(proxy.SnapshotHashHandler)
+	if !ok {
+		return nil, fmt.Errorf("the ProxyHandler does not implement SnapshotHashHandler")
+	}
+
+	stateHash, err := handler.HashSnapshotHandler(snapshot)
+
+	p.logger.WithFields(logrus.Fields{
+		"snapshot":   len(snapshot),
+		"state_hash": stateHash,
+		"err":        err,
+	}).Debug("InmemProxy.HashSnapshot")
+
+	return stateHash, err
+}
+
 // OnStateChanged calls the StateChangeHandler.
 func (p *InmemProxy) OnStateChanged(state state.State) error {
 	return p.handler.StateChangeHandler(state)
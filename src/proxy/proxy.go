@@ -9,8 +9,53 @@ import (
 // the App
 type AppProxy interface {
 	SubmitCh() chan []byte
+	// CommitBlock is called with the Blocks in order of index, one at a time,
+	// and at most once per Block while the node runs. A node bootstrapping
+	// from its database delivers the Blocks again, from the one after the App
+	// watermark, so the App receives each Block at least once.
 	CommitBlock(block hashgraph.Block) (CommitResponse, error)
 	GetSnapshot(blockIndex int) ([]byte, error)
 	Restore(snapshot []byte) error
 	OnStateChanged(state.State) error
 }
+
+// GenesisProvider is implemented by AppProxies that can return the hash of the
+// initial state of the App, which is recorded in the first Block, and compared
+// with the peers, when the genesis document defines an app_hash.
+type GenesisProvider interface {
+	// GenesisStateHash returns the hash of the initial state of the App. It
+	// must return the same hash after the App has committed Blocks.
+	GenesisStateHash() ([]byte, error)
+}
+
+// HealthChecker is implemented by AppProxies that can verify their connection
+// to the App. AppProxies that do not implement it, like the InmemProxy, are
+// assumed to be connected.
+type HealthChecker interface {
+	// CheckHealth returns an error if the App cannot be reached.
+	CheckHealth() error
+}
+
+// SnapshotDeltaProvider is implemented by AppProxies that can transfer the
+// difference between two snapshots of the App, instead of a full snapshot,
+// when the snapshot-delta option is enabled. A node that fast-forwards from an
+// older Block then only receives what changed in the App since that Block.
+type SnapshotDeltaProvider interface {
+	// SnapshotDelta returns the difference between the snapshots of the App
+	// corresponding to two Blocks.
+	SnapshotDelta(fromIndex, toIndex int) ([]byte, error)
+
+	// RestoreDelta applies a difference returned by SnapshotDelta to the App,
+	// whose state must correspond to the Block fromIndex.
+	RestoreDelta(fromIndex int, delta []byte) error
+}
+
+// SnapshotHasher is implemented by AppProxies that can compute the state hash
+// corresponding to a snapshot, without restoring it, when the verify-snapshots
+// option is enabled. A node that fast-forwards then checks that the snapshot
+// sent by a peer matches the state hash of the Block, before restoring it.
+type SnapshotHasher interface {
+	// HashSnapshot returns the state hash of the App if it restored the
+	// snapshot.
+	HashSnapshot(snapshot []byte) ([]byte, error)
+}
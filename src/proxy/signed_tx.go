@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mosaicnetworks/babble/src/crypto"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+)
+
+// signedTxMagic prefixes the SignedTxs, to distinguish them from other
+// transactions.
+var signedTxMagic = []byte("BBLSTX\x01")
+
+// SignedTx is a transaction signed by a client key, which the
+// SignatureAuthorizer checks against the registered clients. The App receives
+// the SignedTx in the Block, and opens it with OpenSignedTx.
+type SignedTx struct {
+	PubKey    string `json:"pub_key"`
+	Signature string `json:"signature"`
+	Payload   []byte `json:"payload"`
+}
+
+// IsSignedTx returns true if the transaction is a SignedTx.
+func IsSignedTx(tx []byte) bool {
+	return bytes.HasPrefix(tx, signedTxMagic)
+}
+
+// SignTx signs a payload with a client key, and returns the SignedTx.
+func SignTx(payload []byte, priv *ecdsa.PrivateKey) ([]byte, error) {
+	r, s, err := keys.Sign(priv, crypto.SHA256(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(SignedTx{
+		PubKey:    keys.PublicKeyHex(&priv.PublicKey),
+		Signature: keys.EncodeSignature(r, s),
+		Payload:   payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, signedTxMagic...), body...), nil
+}
+
+// OpenSignedTx verifies the signature of a SignedTx, and returns its payload
+// and the public key that signed it.
+func OpenSignedTx(tx []byte) ([]byte, *ecdsa.PublicKey, error) {
+	if !IsSignedTx(tx) {
+		return nil, nil, fmt.Errorf("not a signed transaction")
+	}
+
+	var stx SignedTx
+	if err := json.Unmarshal(tx[len(signedTxMagic):], &stx); err != nil {
+		return nil, nil, err
+	}
+
+	pub, err := parsePublicKey(stx.PubKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, s, err := keys.DecodeSignature(stx.Signature)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r == nil || s == nil || !keys.Verify(pub, crypto.SHA256(stx.Payload), r, s) {
+		return nil, nil, fmt.Errorf("invalid signature")
+	}
+
+	return stx.Payload, pub, nil
+}
@@ -3,6 +3,7 @@
 package app
 
 import (
+	"net"
 	"time"
 
 	"github.com/mosaicnetworks/babble/src/hashgraph"
@@ -59,6 +60,20 @@ func NewSocketAppProxy(clientAddr string,
 	return proxy, nil
 }
 
+// SetTxAuthorizer implements the proxy.TxAuthorizable interface. The
+// transactions submitted by the App are rejected with an error unless the
+// authorizer accepts them.
+func (p *SocketAppProxy) SetTxAuthorizer(a proxy.TxAuthorizer) {
+	p.server.guard.SetTxAuthorizer(a)
+}
+
+// SetIdempotencyCache implements the proxy.IdempotencyAware interface. The
+// transactions submitted by the App with SubmitTxWithKey are not submitted
+// again if their key is in the cache.
+func (p *SocketAppProxy) SetIdempotencyCache(c *proxy.IdempotencyCache) {
+	p.server.setIdempotencyCache(c)
+}
+
 //++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 //Implement AppProxy Interface
 
@@ -86,3 +101,40 @@ func (p *SocketAppProxy) Restore(snapshot []byte) error {
 func (p *SocketAppProxy) OnStateChanged(state state.State) error {
 	return p.client.OnStateChanged(state)
 }
+
+// GenesisStateHash implements the proxy.GenesisProvider interface. It fails if
+// the App does not implement the GenesisHandler.
+func (p *SocketAppProxy) GenesisStateHash() ([]byte, error) {
+	return p.client.GenesisStateHash()
+}
+
+// SnapshotDelta implements the proxy.SnapshotDeltaProvider interface. It fails
+// if the App does not implement the SnapshotDeltaHandler.
+func (p *SocketAppProxy) SnapshotDelta(fromIndex, toIndex int) ([]byte, error) {
+	return p.client.SnapshotDelta(fromIndex, toIndex)
+}
+
+// RestoreDelta implements the proxy.SnapshotDeltaProvider interface. It fails
+// if the App does not implement the SnapshotDeltaHandler.
+func (p *SocketAppProxy) RestoreDelta(fromIndex int, delta []byte) error {
+	return p.client.RestoreDelta(fromIndex, delta)
+}
+
+// HashSnapshot implements the proxy.SnapshotHasher interface. It fails if the
+// App does not implement the SnapshotHashHandler.
+func (p *SocketAppProxy) HashSnapshot(snapshot []byte) ([]byte, error) {
+	return p.client.HashSnapshot(snapshot)
+}
+
+// CheckHealth implements the proxy.HealthChecker interface. It verifies that
+// the App is listening on the client address by opening, and immediately
+// closing, a separate TCP connection. The RPC connection used to commit blocks
+// is left untouched.
+func (p *SocketAppProxy) CheckHealth() error {
+	conn, err := net.DialTimeout("tcp", p.clientAddress, p.client.timeout)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
@@ -109,6 +109,104 @@ func (p *SocketAppProxyClient) Restore(snapshot []byte) error {
 	return nil
 }
 
+// GenesisStateHash implements the proxy.GenesisProvider interface
+func (p *SocketAppProxyClient) GenesisStateHash() ([]byte, error) {
+	if err := p.getConnection(); err != nil {
+		return nil, err
+	}
+
+	var stateHash []byte
+
+	if err := p.rpc.Call("State.GenesisStateHash", struct{}{}, &stateHash); err != nil {
+		p.rpc = nil
+
+		return nil, err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"state_hash": stateHash,
+	}).Debug("AppProxyClient.GenesisStateHash")
+
+	return stateHash, nil
+}
+
+// SnapshotDelta implements the proxy.SnapshotDeltaProvider interface
+func (p *SocketAppProxyClient) SnapshotDelta(fromIndex, toIndex int) ([]byte, error) {
+	if err := p.getConnection(); err != nil {
+		return nil, err
+	}
+
+	var delta []byte
+
+	args := proxy.SnapshotDeltaArgs{
+		FromIndex: fromIndex,
+		ToIndex:   toIndex,
+	}
+
+	if err := p.rpc.Call("State.SnapshotDelta", args, &delta); err != nil {
+		p.rpc = nil
+
+		return nil, err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"from":  fromIndex,
+		"to":    toIndex,
+		"delta": len(delta),
+	}).Debug("AppProxyClient.SnapshotDelta")
+
+	return delta, nil
+}
+
+// RestoreDelta implements the proxy.SnapshotDeltaProvider interface
+func (p *SocketAppProxyClient) RestoreDelta(fromIndex int, delta []byte) error {
+	if err := p.getConnection(); err != nil {
+		return err
+	}
+
+	var stateHash []byte
+
+	args := proxy.RestoreDeltaArgs{
+		FromIndex: fromIndex,
+		Delta:     delta,
+	}
+
+	if err := p.rpc.Call("State.RestoreDelta", args, &stateHash); err != nil {
+		p.rpc = nil
+
+		return err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"from":       fromIndex,
+		"state_hash": stateHash,
+	}).Debug("AppProxyClient.RestoreDelta")
+
+	return nil
+}
+
+// HashSnapshot implements the proxy.SnapshotHasher interface
+func (p *SocketAppProxyClient) HashSnapshot(snapshot []byte) ([]byte, error) {
+	if err := p.getConnection(); err != nil {
+		return nil, err
+	}
+
+	var stateHash []byte
+
+	if err := p.rpc.Call("State.HashSnapshot", snapshot, &stateHash); err != nil {
+		p.rpc = nil
+
+		return nil, err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"snapshot":   len(snapshot),
+		"state_hash": stateHash,
+	}).Debug("AppProxyClient.HashSnapshot")
+
+	return stateHash, nil
+}
+
 // OnStateChanged implements the AppProxy interface
 func (p *SocketAppProxyClient) OnStateChanged(state state.State) error {
 	if err := p.getConnection(); err != nil {
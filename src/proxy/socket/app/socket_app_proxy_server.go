@@ -4,7 +4,10 @@ import (
 	"net"
 	"net/rpc"
 	"net/rpc/jsonrpc"
+	"sync"
 
+	"github.com/mosaicnetworks/babble/src/metrics"
+	"github.com/mosaicnetworks/babble/src/proxy"
 	"github.com/sirupsen/logrus"
 )
 
@@ -14,7 +17,11 @@ type SocketAppProxyServer struct {
 	netListener *net.Listener
 	rpcServer   *rpc.Server
 	submitCh    chan []byte
+	guard       proxy.TxGuard
 	logger      *logrus.Entry
+
+	idempotencyLock sync.RWMutex
+	idempotency     *proxy.IdempotencyCache
 }
 
 // NewSocketAppProxyServer creates a new SocketAppProxyServer
@@ -67,9 +74,54 @@ func (p *SocketAppProxyServer) listen() {
 func (p *SocketAppProxyServer) SubmitTx(tx []byte, ack *bool) error {
 	p.logger.Debug("SubmitTx")
 
+	if err := p.guard.AuthorizeTx(tx); err != nil {
+		p.logger.WithError(err).Debug("Rejecting transaction")
+		*ack = false
+		return err
+	}
+
 	p.submitCh <- tx
 
 	*ack = true
 
 	return nil
 }
+
+// SubmitTxWithKey submits a transaction with an idempotency key. A transaction
+// resubmitted with the key of a previous transaction, within the idempotency
+// window, is not submitted again, and the result is that of the original
+// transaction.
+func (p *SocketAppProxyServer) SubmitTxWithKey(args proxy.KeyedTx, result *proxy.SubmitTxResult) error {
+	p.logger.Debug("SubmitTxWithKey")
+
+	if err := p.guard.AuthorizeTx(args.Tx); err != nil {
+		p.logger.WithError(err).Debug("Rejecting transaction")
+		return err
+	}
+
+	p.idempotencyLock.RLock()
+	cache := p.idempotency
+	p.idempotencyLock.RUnlock()
+
+	hash, duplicate := cache.Reserve(args.Key, args.Tx)
+	if duplicate {
+		metrics.TxDuplicates.WithLabelValues("proxy").Inc()
+		p.logger.WithField("key", args.Key).Debug("Duplicate transaction")
+	} else {
+		p.submitCh <- args.Tx
+	}
+
+	*result = proxy.SubmitTxResult{
+		Hash:      hash,
+		Duplicate: duplicate,
+	}
+
+	return nil
+}
+
+func (p *SocketAppProxyServer) setIdempotencyCache(c *proxy.IdempotencyCache) {
+	p.idempotencyLock.Lock()
+	defer p.idempotencyLock.Unlock()
+
+	p.idempotency = c
+}
@@ -74,3 +74,11 @@ func (p *SocketBabbleProxy) SubmitTx(tx []byte) error {
 
 	return nil
 }
+
+// SubmitTxWithKey submits a transaction to Babble with an idempotency key. If
+// the key was used by a previous transaction, within the idempotency window of
+// the node, the transaction is not submitted again, and the result carries the
+// hash of the original transaction.
+func (p *SocketBabbleProxy) SubmitTxWithKey(key string, tx []byte) (*proxy.SubmitTxResult, error) {
+	return p.client.SubmitTxWithKey(key, tx)
+}
@@ -5,6 +5,8 @@ import (
 	"net/rpc"
 	"net/rpc/jsonrpc"
 	"time"
+
+	"github.com/mosaicnetworks/babble/src/proxy"
 )
 
 // SocketBabbleProxyClient is the client component of the BabbleProxy that sends
@@ -55,3 +57,22 @@ func (p *SocketBabbleProxyClient) SubmitTx(tx []byte) (*bool, error) {
 
 	return &ack, nil
 }
+
+// SubmitTxWithKey submits a transaction to Babble with an idempotency key
+func (p *SocketBabbleProxyClient) SubmitTxWithKey(key string, tx []byte) (*proxy.SubmitTxResult, error) {
+	if err := p.getConnection(); err != nil {
+		return nil, err
+	}
+
+	var result proxy.SubmitTxResult
+
+	err := p.rpc.Call("Babble.SubmitTxWithKey", proxy.KeyedTx{Key: key, Tx: tx}, &result)
+
+	if err != nil {
+		p.rpc = nil
+
+		return nil, err
+	}
+
+	return &result, nil
+}
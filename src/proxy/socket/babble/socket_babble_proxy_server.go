@@ -1,6 +1,7 @@
 package babble
 
 import (
+	"fmt"
 	"net"
 	"net/rpc"
 	"net/rpc/jsonrpc"
@@ -110,6 +111,82 @@ func (p *SocketBabbleProxyServer) Restore(snapshot []byte, stateHash *[]byte) (e
 	return
 }
 
+// GenesisStateHash implements the proxy.GenesisProvider interface. It fails if
+// the handler does not implement the GenesisHandler.
+func (p *SocketBabbleProxyServer) GenesisStateHash(args struct{}, stateHash *[]byte) (err error) {
+	handler, ok := p.handler.(proxy.GenesisHandler)
+	if !ok {
+		return fmt.Errorf("the ProxyHandler does not implement GenesisHandler")
+	}
+
+	*stateHash, err = handler.GenesisHandler()
+
+	p.logger.WithFields(logrus.Fields{
+		"state_hash": stateHash,
+		"err":        err,
+	}).Debug("BabbleProxyServer.GenesisStateHash")
+
+	return
+}
+
+// SnapshotDelta implements the proxy.SnapshotDeltaProvider interface. It fails
+// if the handler does not implement the SnapshotDeltaHandler.
+func (p *SocketBabbleProxyServer) SnapshotDelta(args proxy.SnapshotDeltaArgs, delta *[]byte) (err error) {
+	handler, ok := p.handler.(proxy.SnapshotDeltaHandler)
+	if !ok {
+		return fmt.Errorf("the ProxyHandler does not implement SnapshotDeltaHandler")
+	}
+
+	*delta, err = handler.SnapshotDeltaHandler(args.FromIndex, args.ToIndex)
+
+	p.logger.WithFields(logrus.Fields{
+		"from":  args.FromIndex,
+		"to":    args.ToIndex,
+		"delta": len(*delta),
+		"err":   err,
+	}).Debug("BabbleProxyServer.SnapshotDelta")
+
+	return
+}
+
+// RestoreDelta implements the proxy.SnapshotDeltaProvider interface. It fails
+// if the handler does not implement the SnapshotDeltaHandler.
+func (p *SocketBabbleProxyServer) RestoreDelta(args proxy.RestoreDeltaArgs, stateHash *[]byte) (err error) {
+	handler, ok := p.handler.(proxy.SnapshotDeltaHandler)
+	if !ok {
+		return fmt.Errorf("the ProxyHandler does not implement SnapshotDeltaHandler")
+	}
+
+	*stateHash, err = handler.RestoreDeltaHandler(args.FromIndex, args.Delta)
+
+	p.logger.WithFields(logrus.Fields{
+		"from":       args.FromIndex,
+		"state_hash": stateHash,
+		"err":        err,
+	}).Debug("BabbleProxyServer.RestoreDelta")
+
+	return
+}
+
+// HashSnapshot implements the proxy.SnapshotHasher interface. It fails if the
+// handler does not implement the SnapshotHashHandler.
+func (p *SocketBabbleProxyServer) HashSnapshot(snapshot []byte, stateHash *[]byte) (err error) {
+	handler, ok := p.handler.(proxy.SnapshotHashHandler)
+	if !ok {
+		return fmt.Errorf("the ProxyHandler does not implement SnapshotHashHandler")
+	}
+
+	*stateHash, err = handler.HashSnapshotHandler(snapshot)
+
+	p.logger.WithFields(logrus.Fields{
+		"snapshot":   len(snapshot),
+		"state_hash": stateHash,
+		"err":        err,
+	}).Debug("BabbleProxyServer.HashSnapshot")
+
+	return
+}
+
 // OnStateChanged implements the AppProxy interface
 func (p *SocketBabbleProxyServer) OnStateChanged(state state.State, obj *struct{}) (err error) {
 	err = p.handler.StateChangeHandler(state)
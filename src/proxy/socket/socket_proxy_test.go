@@ -119,6 +119,60 @@ func TestSocketProxyServer(t *testing.T) {
 	}
 }
 
+func TestSocketProxyIdempotency(t *testing.T) {
+	clientAddr := "127.0.0.1:6994"
+	proxyAddr := "127.0.0.1:6995"
+
+	logger := common.NewTestEntry(t, common.TestLogLevel)
+
+	appProxy, err := aproxy.NewSocketAppProxy(clientAddr, proxyAddr, 1*time.Second, logger)
+	if err != nil {
+		t.Fatalf("Cannot create SocketAppProxy: %s", err)
+	}
+
+	appProxy.SetIdempotencyCache(proxy.NewIdempotencyCache(time.Minute))
+
+	babbleProxy, err := bproxy.NewSocketBabbleProxy(proxyAddr, clientAddr, NewTestHandler(t), 1*time.Second, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submitted := make(chan []byte, 2)
+	go func() {
+		for tx := range appProxy.SubmitCh() {
+			submitted <- tx
+		}
+	}()
+
+	tx := []byte("the test transaction")
+
+	first, err := babbleProxy.SubmitTxWithKey("key", tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := babbleProxy.SubmitTxWithKey("key", tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Duplicate || !second.Duplicate || second.Hash != first.Hash {
+		t.Fatalf("The resubmission should be a duplicate of the first transaction: %v, %v", first, second)
+	}
+
+	select {
+	case <-submitted:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("The first transaction should be submitted")
+	}
+
+	select {
+	case <-submitted:
+		t.Fatal("The duplicate transaction should not be submitted")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestSocketProxyClient(t *testing.T) {
 	clientAddr := "127.0.0.1:6992"
 	proxyAddr := "127.0.0.1:6993"
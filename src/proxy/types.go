@@ -26,3 +26,31 @@ func DummyCommitCallback(block hashgraph.Block) (CommitResponse, error) {
 
 	return response, nil
 }
+
+// KeyedTx is a transaction submitted with an idempotency key.
+type KeyedTx struct {
+	Key string
+	Tx  []byte
+}
+
+// SubmitTxResult is the result of the submission of a KeyedTx. If Duplicate is
+// true, the key was used by a previous transaction, whose hash is returned,
+// and the transaction was not submitted again.
+type SubmitTxResult struct {
+	Hash      string
+	Duplicate bool
+}
+
+// SnapshotDeltaArgs are the arguments of the SnapshotDelta RPC of the socket
+// proxies.
+type SnapshotDeltaArgs struct {
+	FromIndex int
+	ToIndex   int
+}
+
+// RestoreDeltaArgs are the arguments of the RestoreDelta RPC of the socket
+// proxies.
+type RestoreDeltaArgs struct {
+	FromIndex int
+	Delta     []byte
+}
@@ -0,0 +1,684 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/logging"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/version"
+	"github.com/sirupsen/logrus"
+)
+
+// makeAdminHandler restricts a handler to requests that are granted the admin
+// role, by the admin token, an admin API key, or a JWT. If no admin credential
+// is configured, the handler is disabled.
+func (s *Service) makeAdminHandler(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.setCORSHeaders(w, r) {
+			return
+		}
+
+		if !s.adminEnabled() {
+			http.Error(w, "Admin endpoints are disabled", http.StatusForbidden)
+			return
+		}
+
+		if !s.authorize(w, r, RoleAdmin) {
+			return
+		}
+
+		fn(w, r)
+	}
+}
+
+// LogLevels is the response of the /admin/loglevel endpoint. Level is the
+// default level, and Modules contains the level of every module that has its
+// own logger.
+type LogLevels struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+// LogLevelRequest is the body of a PUT request to the /admin/loglevel
+// endpoint. If Module is empty, the default level is changed. If Module is set
+// but Level is empty, the module reverts to the default level.
+type LogLevelRequest struct {
+	Module string `json:"module"`
+	Level  string `json:"level"`
+}
+
+// LogLevel returns or changes the log levels at runtime.
+//
+//  GET /admin/loglevel
+//  returns: JSON LogLevels
+//
+//  PUT /admin/loglevel
+//  body: JSON LogLevelRequest
+//  example: {"module":"node","level":"debug"}
+//  returns: JSON LogLevels
+func (s *Service) LogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req LogLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Decoding request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.setLogLevel(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	level, modules := s.logging.Levels()
+
+	res := LogLevels{
+		Level:   level.String(),
+		Modules: make(map[string]string, len(modules)),
+	}
+	for m, l := range modules {
+		res.Modules[m] = l.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s *Service) setLogLevel(req LogLevelRequest) error {
+	if req.Module != "" && req.Level == "" {
+		s.logger.WithField("module", req.Module).Info("Resetting log level")
+		s.logging.ResetLevel(req.Module)
+		return nil
+	}
+
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"module": req.Module,
+		"level":  level.String(),
+	}).Info("Changing log level")
+
+	s.logging.SetLevel(req.Module, level)
+
+	return nil
+}
+
+// NodeState is the response of the node control endpoints. Suspension is set
+// when the node is suspended, with hints to bring it back.
+type NodeState struct {
+	State      string           `json:"state"`
+	Suspension *node.Suspension `json:"suspension,omitempty"`
+}
+
+// nodeState returns the NodeState of the node.
+func (s *Service) nodeState() NodeState {
+	return NodeState{
+		State:      s.node.GetState().String(),
+		Suspension: s.node.GetSuspension(),
+	}
+}
+
+// BanRequest is the body of a POST request to the /admin/bans endpoint.
+type BanRequest struct {
+	Addr string `json:"addr"`
+}
+
+// BanList is the response of the /admin/bans endpoint.
+type BanList struct {
+	Addrs []string `json:"addrs"`
+}
+
+// PinRequest is the body of a POST request to the /admin/pins endpoint.
+type PinRequest struct {
+	Block int `json:"block"`
+}
+
+// PinList is the response of the /admin/pins endpoint.
+type PinList struct {
+	Blocks []int `json:"blocks"`
+}
+
+// Watermark is the body of a POST request to, and the response of, the
+// /admin/watermark endpoint.
+type Watermark struct {
+	Block int `json:"block"`
+}
+
+// FilterRequest is the body of a POST request to the /admin/filter endpoint.
+// List is one of allow-ips, block-ips, allow-pubkeys, or block-pubkeys.
+type FilterRequest struct {
+	List  string `json:"list"`
+	Entry string `json:"entry"`
+}
+
+// UpgradeRequest is the body of a POST request to the /admin/upgrade endpoint.
+// ProtocolVersion defaults to the latest version supported by the node.
+type UpgradeRequest struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// Moniker is the body of the requests and responses of the /admin/moniker
+// endpoint.
+type Moniker struct {
+	Moniker string `json:"moniker"`
+}
+
+// Address is the body of the requests and responses of the /admin/address
+// endpoint.
+type Address struct {
+	Address string `json:"address"`
+}
+
+// LogRotation is the response of the /admin/logrotate endpoint.
+type LogRotation struct {
+	Rotated string `json:"rotated"`
+}
+
+// Leave causes the node to politely leave the network, as it does on SIGINT.
+// The response is sent before the node leaves, which can take up to the
+// join-timeout, after which the node shuts down.
+//
+//  POST /admin/leave
+//  returns: 202 Accepted, JSON NodeState
+func (s *Service) Leave(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	s.logger.Info("Leaving on admin request")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(s.nodeState())
+
+	go s.node.Leave()
+}
+
+// Resume takes the node out of the Suspended state. The response status is
+// 409 if the node is not suspended.
+//
+//  POST /admin/resume
+//  returns: JSON NodeState
+func (s *Service) Resume(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := s.node.Resume(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.nodeState())
+}
+
+// Suspend puts the node in the Suspended state, until it is resumed. The
+// response status is 409 if the node is already suspended.
+//
+//  POST /admin/suspend
+//  returns: JSON NodeState
+func (s *Service) Suspend(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if current := s.node.GetState(); current == state.Suspended || current == state.Shutdown {
+		http.Error(w, fmt.Sprintf("Cannot suspend from %s state", current), http.StatusConflict)
+		return
+	}
+
+	s.logger.Info("Suspending on admin request")
+
+	s.node.Suspend()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.nodeState())
+}
+
+// FastForward forces the node to fast-forward to the tip of the hashgraph.
+// The response status is 409 if the node is not Babbling.
+//
+//  POST /admin/fastforward
+//  returns: 202 Accepted, JSON NodeState
+func (s *Service) FastForward(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := s.node.ForceFastForward(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(s.nodeState())
+}
+
+// Bans lists, adds, or removes banned peer addresses. The node does not gossip
+// with banned peers, and refuses their RPCs. Bans are not persisted across
+// restarts.
+//
+//  GET /admin/bans
+//  returns: JSON BanList
+//
+//  POST /admin/bans
+//  body: JSON BanRequest
+//  example: {"addr":"10.0.0.5:1337"}
+//  returns: JSON BanList
+//
+//  DELETE /admin/bans?addr={addr}
+//  returns: JSON BanList
+func (s *Service) Bans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req BanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Decoding request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if req.Addr == "" {
+			http.Error(w, "Missing addr", http.StatusBadRequest)
+			return
+		}
+
+		s.node.BanPeer(req.Addr)
+	case http.MethodDelete:
+		addr := r.URL.Query().Get("addr")
+		if !s.node.UnbanPeer(addr) {
+			http.Error(w, fmt.Sprintf("%q is not banned", addr), http.StatusNotFound)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BanList{Addrs: s.node.GetBannedPeers()})
+}
+
+// Filter lists, adds, or removes the entries of the allowlists and blocklists
+// of the transport. Refused peers cannot connect nor send RPCs to the node.
+// Changes are not persisted across restarts; the initial lists come from the
+// configuration.
+//
+//  GET /admin/filter
+//  returns: JSON net.FilterRules
+//
+//  POST /admin/filter
+//  body: JSON FilterRequest
+//  example: {"list":"block-ips","entry":"10.0.0.0/24"}
+//  returns: JSON net.FilterRules
+//
+//  DELETE /admin/filter?list={list}&entry={entry}
+//  returns: JSON net.FilterRules
+func (s *Service) Filter(w http.ResponseWriter, r *http.Request) {
+	filter := s.node.GetFilter()
+	if filter == nil {
+		http.Error(w, "The transport does not support filtering", http.StatusConflict)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req FilterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Decoding request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := filter.Add(net.FilterList(req.List), req.Entry); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"list":  req.List,
+			"entry": req.Entry,
+		}).Info("Added filter entry")
+	case http.MethodDelete:
+		list := r.URL.Query().Get("list")
+		entry := r.URL.Query().Get("entry")
+		if !filter.Remove(net.FilterList(list), entry) {
+			http.Error(w, fmt.Sprintf("%q is not in %s", entry, list), http.StatusNotFound)
+			return
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"list":  list,
+			"entry": entry,
+		}).Info("Removed filter entry")
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filter.Rules())
+}
+
+// Pins lists, adds, or removes pinned blocks. A pinned block, and the frame
+// of its round-received, are never evicted from the store, so that they remain
+// available to the application, for example as audit checkpoints. The response
+// status is 404 if the block is not in the store, or is not pinned.
+//
+//  GET /admin/pins
+//  returns: JSON PinList
+//
+//  POST /admin/pins
+//  body: JSON PinRequest
+//  example: {"block":120}
+//  returns: JSON PinList
+//
+//  DELETE /admin/pins?block={index}
+//  returns: JSON PinList
+func (s *Service) Pins(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req PinRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Decoding request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.node.PinBlock(req.Block); err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+	case http.MethodDelete:
+		param := r.URL.Query().Get("block")
+		index, err := strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid block parameter %q", param), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.node.UnpinBlock(index); err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pinned, err := s.node.GetPinnedBlocks()
+	if err != nil {
+		s.logger.WithError(err).Error("Retrieving pinned blocks")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PinList{Blocks: pinned})
+}
+
+// AppWatermark returns or advances the App watermark, the index of the last
+// block that the App acknowledged once it persisted its effects. When the node
+// restarts, it does not deliver the blocks up to the watermark to the App
+// again. The watermark is -1 until the App advances it. The response status is
+// 400 if the watermark would go back, or beyond the last block.
+//
+//  GET /admin/watermark
+//  returns: JSON Watermark
+//
+//  POST /admin/watermark
+//  body: JSON Watermark
+//  example: {"block":120}
+//  returns: JSON Watermark
+func (s *Service) AppWatermark(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req Watermark
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Decoding request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.node.AdvanceAppWatermark(req.Block); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	watermark, err := s.node.GetAppWatermark()
+	if err != nil {
+		s.logger.WithError(err).Error("Retrieving App watermark")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Watermark{Block: watermark})
+}
+
+// storeErrorStatus returns 404 for the errors of missing store items, and 500
+// for the others.
+func storeErrorStatus(err error) int {
+	if common.IsStore(err, common.KeyNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// Upgrade reports the upgrades of the protocol, or signals that the node is
+// ready for a new version of the protocol. A POST waits for the signal to go
+// through consensus; the version is activated once more than two thirds of the
+// validators have signalled it. The response status is 409 if the node cannot
+// signal the version.
+//
+//  GET /admin/upgrade
+//  returns: JSON node.UpgradeStatus
+//
+//  POST /admin/upgrade
+//  body: JSON UpgradeRequest
+//  example: {"protocol_version":2}
+//  returns: JSON node.UpgradeStatus
+func (s *Service) Upgrade(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		req := UpgradeRequest{ProtocolVersion: version.ProtocolVersion}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, fmt.Sprintf("Decoding request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		s.logger.WithField("protocol_version", req.ProtocolVersion).Info("Signalling upgrade on admin request")
+
+		if err := s.node.SignalUpgrade(req.ProtocolVersion); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.node.GetUpgradeStatus())
+}
+
+// ChangeMoniker reports the moniker of the validator, or changes it. A POST
+// waits for the change to go through consensus. The response status is 409 if
+// the moniker cannot be changed, for example because another validator already
+// uses it.
+//
+//  GET /admin/moniker
+//  returns: JSON Moniker
+//
+//  POST /admin/moniker
+//  body: JSON Moniker
+//  example: {"moniker":"node5"}
+//  returns: JSON Moniker
+func (s *Service) ChangeMoniker(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req Moniker
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Decoding request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		s.logger.WithField("moniker", req.Moniker).Info("Changing moniker on admin request")
+
+		if err := s.node.ChangeMoniker(req.Moniker); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Moniker{Moniker: s.node.GetMoniker()})
+}
+
+// ChangeAddress reports the address of the validator in the validator-set, or
+// announces a new one. A POST waits for the change to go through consensus.
+// The response status is 409 if the address cannot be changed, for example
+// because the node is not a validator.
+//
+//  GET /admin/address
+//  returns: JSON Address
+//
+//  POST /admin/address
+//  body: JSON Address
+//  example: {"address":"203.0.113.7:1337"}
+//  returns: JSON Address
+func (s *Service) ChangeAddress(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req Address
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Decoding request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		s.logger.WithField("address", req.Address).Info("Changing address on admin request")
+
+		if err := s.node.ChangeAddress(req.Address); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Address{Address: s.node.GetAddress()})
+}
+
+// RotateLogs renames the log file with a timestamp suffix and continues
+// logging to a new file. The response status is 409 if the logs are not
+// written to a file.
+//
+//  POST /admin/logrotate
+//  returns: JSON LogRotation
+func (s *Service) RotateLogs(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	rotated, err := s.logging.Rotate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	s.logger.WithField("rotated", rotated).Info("Rotated log file")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LogRotation{Rotated: rotated})
+}
+
+// ConfigReload is the response of the /admin/reload endpoint. Applied lists the
+// options that were changed, and Ignored the options that changed in the
+// configuration but require a restart to take effect.
+type ConfigReload struct {
+	Applied []string `json:"applied"`
+	Ignored []string `json:"ignored"`
+}
+
+// SetReloader sets the function called by the /admin/reload endpoint to reload
+// the configuration.
+func (s *Service) SetReloader(reload func() (ConfigReload, error)) {
+	s.reloader = reload
+}
+
+// Reload reads the configuration again and applies the options that can be
+// changed without restarting the node, like the log levels, the heartbeats,
+// the rate limits, and the cache size. It has the same effect as sending a
+// SIGHUP to the process. The response status is 400 if the new configuration
+// is invalid, in which case none of it is applied, and 501 if the node does
+// not know where to reload its configuration from.
+//
+//  POST /admin/reload
+//  returns: JSON ConfigReload
+func (s *Service) Reload(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if s.reloader == nil {
+		http.Error(w, "Config reload is not available", http.StatusNotImplemented)
+		return
+	}
+
+	res, err := s.reloader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// checkMethod writes a 405 response and returns false if the request does not
+// use the expected method.
+func checkMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
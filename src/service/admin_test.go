@@ -0,0 +1,298 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/testapp"
+)
+
+func TestAdminHandler(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	cases := []struct {
+		token    string
+		header   string
+		expected int
+	}{
+		{token: "", header: "", expected: http.StatusForbidden},
+		{token: "", header: "Bearer ", expected: http.StatusForbidden},
+		{token: "secret", header: "", expected: http.StatusUnauthorized},
+		{token: "secret", header: "Bearer wrong", expected: http.StatusUnauthorized},
+		{token: "secret", header: "secret", expected: http.StatusUnauthorized},
+		{token: "secret", header: "Basic secret", expected: http.StatusUnauthorized},
+		{token: "secret", header: "Bearer secret", expected: http.StatusOK},
+	}
+
+	for i, c := range cases {
+		s := &Service{
+			adminToken: c.token,
+			logger:     common.NewTestEntry(t, common.TestLogLevel),
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/debug/gc", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+
+		rec := httptest.NewRecorder()
+		s.makeAdminHandler(handler)(rec, req)
+
+		if rec.Code != c.expected {
+			t.Fatalf("case %d: status should be %d, not %d", i, c.expected, rec.Code)
+		}
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	s := &Service{
+		adminToken: "secret",
+		logger:     common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	cases := map[string]int{
+		"/debug/pprof/":                    http.StatusOK,
+		"/debug/pprof/goroutine?debug=1":   http.StatusOK,
+		"/debug/pprof/heap":                http.StatusOK,
+		"/debug/pprof/unknown":             http.StatusNotFound,
+		"/debug/pprof/profile?seconds=0.1": http.StatusOK,
+		"/debug/pprof/profile?seconds=-1":  http.StatusBadRequest,
+	}
+
+	for path, expected := range cases {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		s.makeAdminHandler(s.GetProfile)(rec, req)
+
+		if rec.Code != expected {
+			t.Fatalf("%s: status should be %d, not %d", path, expected, rec.Code)
+		}
+	}
+}
+
+func TestNodeControl(t *testing.T) {
+	cluster := testapp.NewCluster(t, 2)
+	cluster.Run()
+	defer cluster.Shutdown()
+
+	s := &Service{
+		node:   cluster.Nodes[0],
+		logger: common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	bans := func(method, target, body string) (int, BanList) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.Bans(rec, req)
+
+		var list BanList
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, list
+	}
+
+	addr := "10.0.0.5:1337"
+
+	if code, list := bans(http.MethodPost, "/admin/bans", `{"addr":"`+addr+`"}`); code != http.StatusOK ||
+		len(list.Addrs) != 1 || list.Addrs[0] != addr {
+		t.Fatalf("unexpected ban response: %d %v", code, list)
+	}
+
+	if code, _ := bans(http.MethodDelete, "/admin/bans?addr=unknown", ""); code != http.StatusNotFound {
+		t.Fatalf("unbanning an unknown address should return %d, not %d", http.StatusNotFound, code)
+	}
+
+	if code, list := bans(http.MethodDelete, "/admin/bans?addr="+addr, ""); code != http.StatusOK ||
+		len(list.Addrs) != 0 {
+		t.Fatalf("unexpected unban response: %d %v", code, list)
+	}
+
+	pins := func(method, target, body string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.Pins(rec, req)
+		return rec.Code
+	}
+
+	if code := pins(http.MethodPost, "/admin/pins", `{"block":1000}`); code != http.StatusNotFound {
+		t.Fatalf("pinning an unknown block should return %d, not %d", http.StatusNotFound, code)
+	}
+
+	if code := pins(http.MethodDelete, "/admin/pins?block=1000", ""); code != http.StatusNotFound {
+		t.Fatalf("unpinning an unknown block should return %d, not %d", http.StatusNotFound, code)
+	}
+
+	if code := pins(http.MethodGet, "/admin/pins", ""); code != http.StatusOK {
+		t.Fatalf("listing pins should return %d, not %d", http.StatusOK, code)
+	}
+
+	watermark := func(method, body string) (int, Watermark) {
+		req := httptest.NewRequest(method, "/admin/watermark", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.AppWatermark(rec, req)
+
+		var wm Watermark
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&wm); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, wm
+	}
+
+	if code, wm := watermark(http.MethodGet, ""); code != http.StatusOK || wm.Block != -1 {
+		t.Fatalf("unexpected watermark response: %d %v", code, wm)
+	}
+
+	if code, _ := watermark(http.MethodPost, `{"block":1000}`); code != http.StatusBadRequest {
+		t.Fatalf("advancing the watermark beyond the last block should return %d, not %d", http.StatusBadRequest, code)
+	}
+
+	// The node is Babbling, so it cannot be resumed
+	rec := httptest.NewRecorder()
+	s.Resume(rec, httptest.NewRequest(http.MethodPost, "/admin/resume", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("resume status should be %d, not %d", http.StatusConflict, rec.Code)
+	}
+
+	// A suspended node reports why, and how to resume it
+	rec = httptest.NewRecorder()
+	s.Suspend(rec, httptest.NewRequest(http.MethodPost, "/admin/suspend", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("suspend status should be %d, not %d", http.StatusOK, rec.Code)
+	}
+
+	var suspended NodeState
+	if err := json.NewDecoder(rec.Body).Decode(&suspended); err != nil {
+		t.Fatal(err)
+	}
+	if suspended.Suspension == nil || suspended.Suspension.Reason != node.SuspendManual || suspended.Suspension.Remediation == "" {
+		t.Fatalf("The response should describe a manual suspension: %+v", suspended)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Resume(rec, httptest.NewRequest(http.MethodPost, "/admin/resume", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("resume status should be %d, not %d", http.StatusOK, rec.Code)
+	}
+
+	var resumed NodeState
+	if err := json.NewDecoder(rec.Body).Decode(&resumed); err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Suspension != nil {
+		t.Fatalf("A resumed node should not report a suspension: %+v", resumed)
+	}
+
+	rec = httptest.NewRecorder()
+	s.FastForward(rec, httptest.NewRequest(http.MethodGet, "/admin/fastforward", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("fastforward status should be %d, not %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestChangeMoniker(t *testing.T) {
+	cluster := testapp.NewCluster(t, 2)
+	cluster.Run()
+	defer cluster.Shutdown()
+
+	s := &Service{
+		node:   cluster.Nodes[0],
+		logger: common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	moniker := func(method, body string) (int, Moniker) {
+		req := httptest.NewRequest(method, "/admin/moniker", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.ChangeMoniker(rec, req)
+
+		var res Moniker
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, res
+	}
+
+	if code, res := moniker(http.MethodGet, ""); code != http.StatusOK || res.Moniker != "node0" {
+		t.Fatalf("unexpected moniker response: %d %v", code, res)
+	}
+
+	// Monikers are unique, ignoring case
+	if code, _ := moniker(http.MethodPost, `{"moniker":"NODE1"}`); code != http.StatusConflict {
+		t.Fatalf("taking the moniker of another validator should return %d, not %d", http.StatusConflict, code)
+	}
+
+	if code, res := moniker(http.MethodPost, `{"moniker":"renamed"}`); code != http.StatusOK || res.Moniker != "renamed" {
+		t.Fatalf("unexpected moniker response: %d %v", code, res)
+	}
+
+	// The other node learns the new moniker through consensus
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ps := cluster.Nodes[1].GetPeerStats()
+		if len(ps) == 1 && ps[0].Moniker == "renamed" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("node1 should know node0 as renamed, not %v", ps)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestReload(t *testing.T) {
+	s := &Service{
+		logger: common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	reload := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.Reload(rec, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+		return rec
+	}
+
+	if rec := reload(); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("status without a reloader should be %d, not %d", http.StatusNotImplemented, rec.Code)
+	}
+
+	s.SetReloader(func() (ConfigReload, error) {
+		return ConfigReload{Applied: []string{"heartbeat"}, Ignored: []string{"moniker"}}, nil
+	})
+
+	rec := reload()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status should be %d, not %d", http.StatusOK, rec.Code)
+	}
+
+	var res ConfigReload
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Applied) != 1 || res.Applied[0] != "heartbeat" || len(res.Ignored) != 1 {
+		t.Fatalf("unexpected reload response: %v", res)
+	}
+
+	s.SetReloader(func() (ConfigReload, error) {
+		return ConfigReload{}, fmt.Errorf("invalid config")
+	})
+
+	if rec := reload(); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status with an invalid config should be %d, not %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Role is the level of access granted to a client of the service.
+type Role int
+
+const (
+	// RoleNone is the role of unauthenticated clients.
+	RoleNone Role = iota
+	// RoleRead gives access to the read-only endpoints.
+	RoleRead
+	// RoleAdmin gives access to all the endpoints, including /admin and /debug.
+	RoleAdmin
+)
+
+// String returns the name of the role, as used in the configuration and in JWT
+// claims.
+func (r Role) String() string {
+	switch r {
+	case RoleRead:
+		return "read"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole parses the name of a role.
+func ParseRole(name string) (Role, error) {
+	switch name {
+	case "read":
+		return RoleRead, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleNone, fmt.Errorf("unknown role %q", name)
+	}
+}
+
+// ParseAPIKeys parses a comma-separated list of key:role pairs, like
+// "k3y1:read,k3y2:admin".
+func ParseAPIKeys(list string) (map[string]Role, error) {
+	res := make(map[string]Role)
+
+	if strings.TrimSpace(list) == "" {
+		return res, nil
+	}
+
+	for _, pair := range strings.Split(list, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid API key %q, expected key:role", pair)
+		}
+
+		role, err := ParseRole(kv[1])
+		if err != nil {
+			return nil, err
+		}
+
+		res[kv[0]] = role
+	}
+
+	return res, nil
+}
+
+// ParseCORSOrigins parses a comma-separated list of allowed origins.
+func ParseCORSOrigins(list string) []string {
+	res := []string{}
+	for _, o := range strings.Split(list, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// credential returns the token carried by a request, either in an
+// "Authorization: Bearer <token>" header, or in an "X-API-Key" header. An
+// Authorization header with another scheme carries no credential.
+func credential(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(auth, "Bearer ")
+}
+
+// authenticate returns the role granted by the credential of a request. The
+// credential is compared with the admin token and the API keys, and verified
+// as a JWT if a JWT secret is configured.
+func (s *Service) authenticate(r *http.Request) Role {
+	token := credential(r)
+	if token == "" {
+		return RoleNone
+	}
+
+	if s.adminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1 {
+		return RoleAdmin
+	}
+
+	for key, role := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return role
+		}
+	}
+
+	if s.jwtSecret != "" && strings.Count(token, ".") == 2 {
+		role, err := verifyJWT(token, []byte(s.jwtSecret), time.Now())
+		if err != nil {
+			s.logger.WithError(err).Debug("Invalid JWT")
+			return RoleNone
+		}
+		return role
+	}
+
+	return RoleNone
+}
+
+// adminEnabled returns true if at least one kind of admin credential is
+// configured.
+func (s *Service) adminEnabled() bool {
+	if s.adminToken != "" || s.jwtSecret != "" {
+		return true
+	}
+	for _, role := range s.apiKeys {
+		if role == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks that the request is granted the required role. It writes
+// the error response and returns false otherwise.
+func (s *Service) authorize(w http.ResponseWriter, r *http.Request, required Role) bool {
+	role := s.authenticate(r)
+
+	if role >= required {
+		return true
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"path":     r.URL.Path,
+		"remote":   r.RemoteAddr,
+		"role":     role.String(),
+		"required": required.String(),
+	}).Warn("Unauthorized request")
+
+	if role == RoleNone {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	} else {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}
+
+	return false
+}
+
+// setCORSHeaders sets the CORS headers of the response if the origin of the
+// request is allowed. It returns true if the request is a preflight request,
+// which has been fully handled.
+func (s *Service) setCORSHeaders(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+
+	allowed := s.allowOrigin(origin)
+	if allowed {
+		if s.corsAllowAll() {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+		}
+	}
+
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Idempotency-Key, X-API-Key")
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+
+	return false
+}
+
+func (s *Service) corsAllowAll() bool {
+	for _, o := range s.corsOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin returns true if the origin is in the list of allowed CORS
+// origins. Requests without an Origin header always pass, because they do not
+// come from browsers.
+func (s *Service) allowOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, o := range s.corsOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+/*******************************************************************************
+JWT
+*******************************************************************************/
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Role      string `json:"role"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+}
+
+// verifyJWT verifies a JSON Web Token signed with HMAC-SHA256, and returns the
+// role in its claims. The exp and nbf claims are enforced when present.
+func verifyJWT(token string, secret []byte, now time.Time) (Role, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return RoleNone, fmt.Errorf("malformed token")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return RoleNone, fmt.Errorf("decoding header: %v", err)
+	}
+
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return RoleNone, fmt.Errorf("parsing header: %v", err)
+	}
+
+	if header.Alg != "HS256" {
+		return RoleNone, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return RoleNone, fmt.Errorf("decoding signature: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return RoleNone, fmt.Errorf("invalid signature")
+	}
+
+	claimsBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return RoleNone, fmt.Errorf("decoding claims: %v", err)
+	}
+
+	var claims jwtClaims
+	if err := json.Unmarshal(claimsBytes, &claims); err != nil {
+		return RoleNone, fmt.Errorf("parsing claims: %v", err)
+	}
+
+	if claims.ExpiresAt != 0 && now.Unix() >= claims.ExpiresAt {
+		return RoleNone, fmt.Errorf("token expired")
+	}
+
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return RoleNone, fmt.Errorf("token not valid yet")
+	}
+
+	return ParseRole(claims.Role)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func signJWT(header, claims string, secret []byte) string {
+	enc := base64.RawURLEncoding
+	payload := enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString([]byte(claims))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+
+	return payload + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1000, 0)
+
+	hs256 := `{"alg":"HS256","typ":"JWT"}`
+
+	cases := []struct {
+		token string
+		role  Role
+		ok    bool
+	}{
+		{signJWT(hs256, `{"role":"read"}`, secret), RoleRead, true},
+		{signJWT(hs256, `{"role":"admin","exp":2000,"nbf":500}`, secret), RoleAdmin, true},
+		{signJWT(hs256, `{"role":"admin","exp":1000}`, secret), RoleNone, false},
+		{signJWT(hs256, `{"role":"admin","nbf":1500}`, secret), RoleNone, false},
+		{signJWT(hs256, `{"role":"root"}`, secret), RoleNone, false},
+		{signJWT(hs256, `{"role":"admin"}`, []byte("wrong")), RoleNone, false},
+		{signJWT(`{"alg":"none"}`, `{"role":"admin"}`, secret), RoleNone, false},
+		{"not.a.token", RoleNone, false},
+		{"malformed", RoleNone, false},
+	}
+
+	for i, c := range cases {
+		role, err := verifyJWT(c.token, secret, now)
+		if c.ok != (err == nil) {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		if role != c.role {
+			t.Fatalf("case %d: role should be %s, not %s", i, c.role, role)
+		}
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys(" k1:read, k2:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 || keys["k1"] != RoleRead || keys["k2"] != RoleAdmin {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	for _, list := range []string{"k1", ":read", "k1:root"} {
+		if _, err := ParseAPIKeys(list); err == nil {
+			t.Fatalf("%q should not parse", list)
+		}
+	}
+}
+
+func TestRoles(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	s := &Service{
+		apiKeys:     map[string]Role{"reader": RoleRead, "admin": RoleAdmin},
+		jwtSecret:   "secret",
+		readAuth:    true,
+		corsOrigins: []string{"*"},
+		logger:      common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	jwt := signJWT(`{"alg":"HS256"}`, `{"role":"read"}`, []byte("secret"))
+
+	cases := []struct {
+		key      string
+		admin    bool
+		expected int
+	}{
+		{key: "", admin: false, expected: http.StatusUnauthorized},
+		{key: "wrong", admin: false, expected: http.StatusUnauthorized},
+		{key: "reader", admin: false, expected: http.StatusOK},
+		{key: "reader", admin: true, expected: http.StatusForbidden},
+		{key: "admin", admin: false, expected: http.StatusOK},
+		{key: "admin", admin: true, expected: http.StatusOK},
+		{key: jwt, admin: false, expected: http.StatusOK},
+		{key: jwt, admin: true, expected: http.StatusForbidden},
+	}
+
+	for i, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+		if c.key != "" {
+			req.Header.Set("X-API-Key", c.key)
+		}
+
+		h := s.makeHandler(handler)
+		if c.admin {
+			h = s.makeAdminHandler(handler)
+		}
+
+		rec := httptest.NewRecorder()
+		h(rec, req)
+
+		if rec.Code != c.expected {
+			t.Fatalf("case %d: status should be %d, not %d", i, c.expected, rec.Code)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	s := &Service{
+		corsOrigins: ParseCORSOrigins("https://a.example, https://b.example"),
+		logger:      common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	cases := []struct {
+		origin   string
+		expected string
+	}{
+		{origin: "https://a.example", expected: "https://a.example"},
+		{origin: "https://c.example", expected: ""},
+		{origin: "", expected: ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+
+		rec := httptest.NewRecorder()
+		s.makeHandler(handler)(rec, req)
+
+		if h := rec.Header().Get("Access-Control-Allow-Origin"); h != c.expected {
+			t.Fatalf("%q: Access-Control-Allow-Origin should be %q, not %q", c.origin, c.expected, h)
+		}
+	}
+
+	// Preflight requests are answered without calling the handler
+	req := httptest.NewRequest(http.MethodOptions, "/tx", nil)
+	req.Header.Set("Origin", "https://b.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+
+	rec := httptest.NewRecorder()
+	s.makeHandler(handler)(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("preflight status should be %d, not %d", http.StatusNoContent, rec.Code)
+	}
+
+	if rec.Header().Get("Access-Control-Allow-Headers") == "" {
+		t.Fatal("preflight should set Access-Control-Allow-Headers")
+	}
+}
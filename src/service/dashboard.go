@@ -0,0 +1,282 @@
+package service
+
+import (
+	"net/http"
+)
+
+// GetDashboard serves a single-page dashboard, which polls the API to display
+// the state of the node, its peers, the last blocks, the sync lag, and a live
+// view of the hashgraph. It is embedded in the binary, so that small
+// deployments get some observability without external tooling. When the read
+// endpoints require authentication, the API key is entered in the page and
+// kept in the local storage of the browser.
+//
+//  GET /dashboard
+//  returns: HTML page
+func (s *Service) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write([]byte(dashboardHTML))
+}
+
+// dashboardHTML is the page served by GetDashboard. It only uses the /v1 API,
+// /readyz, and the JSON graph of /v1/graph/export.
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Babble</title>
+<style>
+  body { font-family: sans-serif; margin: 0; background: #f4f5f7; color: #222; }
+  header { background: #1f2d3d; color: #fff; padding: 10px 20px; display: flex; align-items: center; }
+  header h1 { font-size: 18px; margin: 0; flex: 1; }
+  header input { font-size: 12px; padding: 3px; width: 220px; }
+  main { display: grid; grid-template-columns: 1fr 1fr; gap: 16px; padding: 16px; }
+  section { background: #fff; border-radius: 4px; padding: 12px 16px; box-shadow: 0 1px 2px rgba(0,0,0,.1); }
+  section.wide { grid-column: 1 / span 2; }
+  h2 { font-size: 14px; margin: 0 0 8px; text-transform: uppercase; color: #556; }
+  table { border-collapse: collapse; width: 100%; font-size: 13px; }
+  td, th { text-align: left; padding: 3px 6px; border-bottom: 1px solid #eee; }
+  .mono { font-family: monospace; }
+  .ok { color: #2a7; } .ko { color: #c33; }
+  #error { color: #c33; padding: 0 20px; }
+  #graph { overflow: auto; max-height: 600px; }
+  #graph circle { stroke: #333; stroke-width: 1; fill: #fff; }
+  #graph circle.witness { stroke-width: 3; }
+  #graph circle.famous { fill: gold; }
+  #graph circle.notfamous { fill: #ccc; }
+  #graph line.self { stroke: #333; }
+  #graph line.other { stroke: #999; stroke-dasharray: 3 2; }
+  #graph text { font-size: 11px; }
+</style>
+</head>
+<body>
+<header>
+  <h1>Babble <span id="moniker"></span></h1>
+  <input id="apikey" type="password" placeholder="API key">
+</header>
+<div id="error"></div>
+<main>
+  <section>
+    <h2>Node</h2>
+    <table id="stats"></table>
+  </section>
+  <section>
+    <h2>Sync</h2>
+    <table id="sync"></table>
+  </section>
+  <section>
+    <h2>Peers</h2>
+    <table id="peers"></table>
+  </section>
+  <section>
+    <h2>Last blocks</h2>
+    <table id="blocks"></table>
+  </section>
+  <section class="wide">
+    <h2>Hashgraph</h2>
+    <div id="graph"></div>
+  </section>
+</main>
+<script>
+(function () {
+  "use strict";
+
+  var keyInput = document.getElementById("apikey");
+  keyInput.value = localStorage.getItem("babble-api-key") || "";
+  keyInput.addEventListener("change", function () {
+    localStorage.setItem("babble-api-key", keyInput.value);
+    refresh();
+  });
+
+  function get(path, allowError) {
+    var headers = {};
+    if (keyInput.value) {
+      headers["X-API-Key"] = keyInput.value;
+    }
+    return fetch(path, { headers: headers }).then(function (r) {
+      if (!r.ok && !allowError) {
+        throw new Error(path + ": " + r.status + " " + r.statusText);
+      }
+      return r.json();
+    });
+  }
+
+  function esc(v) {
+    return String(v).replace(/[&<>"]/g, function (c) {
+      return { "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;" }[c];
+    });
+  }
+
+  function rows(id, data) {
+    document.getElementById(id).innerHTML = data.map(function (r) {
+      return "<tr>" + r.map(function (c, i) {
+        return (i === 0 ? "<th>" : "<td>") + c + (i === 0 ? "</th>" : "</td>");
+      }).join("") + "</tr>";
+    }).join("");
+  }
+
+  function renderStats(st) {
+    document.getElementById("moniker").textContent = st.moniker || "";
+    rows("stats", [
+      ["State", esc(st.state)],
+      ["ID", esc(st.id)],
+      ["Last block", esc(st.last_block_index)],
+      ["Last round", esc(st.last_round)],
+      ["Last consensus round", st.last_consensus_round === null ? "-" : esc(st.last_consensus_round)],
+      ["Last frame", esc(st.last_frame)],
+      ["Peers", esc(st.num_peers)],
+      ["Transaction pool", esc(st.transaction_pool)],
+      ["Consensus transactions", esc(st.consensus_transactions)],
+      ["Events per second", esc(st.events_per_second.toFixed(2))],
+      ["Rounds per second", esc(st.rounds_per_second.toFixed(2))]
+    ]);
+  }
+
+  function renderSync(st, ready) {
+    var checks = Object.keys(ready.checks || {}).sort().map(function (name) {
+      var c = ready.checks[name];
+      return [esc(name), c.ok ? "<span class=ok>ok</span>" : "<span class=ko>" + esc(c.error) + "</span>"];
+    });
+    rows("sync", [
+      ["Ready", ready.ready ? "<span class=ok>yes</span>" : "<span class=ko>no</span>"],
+      ["Event lag", esc(ready.event_lag)],
+      ["Undecided rounds", esc(ready.round_lag)],
+      ["Undetermined events", esc(st.undetermined_events)],
+      ["Sync rate", esc(st.sync_rate.toFixed(2)) + " (" + esc(st.sync_errors) + " errors)"]
+    ].concat(checks));
+  }
+
+  function renderPeers(peers) {
+    var now = Date.now();
+    rows("peers", [["Moniker", "Address", "Last sync", "RTT", "Events in/out", "Failures", "Lag"]].concat(peers.map(function (p) {
+      var last = p.last_sync ? Math.round((now - Date.parse(p.last_sync)) / 1000) + "s ago" : "never";
+      var failures = p.consecutive_failures > 0 ?
+        "<span class=ko title=\"" + esc(p.last_error) + "\">" + esc(p.failures) + "</span>" : esc(p.failures);
+      return [
+        esc(p.moniker),
+        "<span class=mono>" + esc(p.net_addr) + "</span>",
+        last,
+        esc(p.rtt_ms.toFixed(1)) + " ms",
+        esc(p.events_received) + " / " + esc(p.events_sent),
+        failures,
+        esc(p.lag)
+      ];
+    })));
+  }
+
+  function renderBlocks(page) {
+    var blocks = page.blocks.slice().reverse();
+    rows("blocks", [["Index", "Round received", "Transactions", "Signatures"]].concat(blocks.map(function (b) {
+      return [
+        esc(b.Body.Index),
+        esc(b.Body.RoundReceived),
+        esc((b.Body.Transactions || []).length),
+        esc(Object.keys(b.Signatures || {}).length)
+      ];
+    })));
+  }
+
+  // renderGraph draws the events in one column per creator, ordered by
+  // lamport timestamp from bottom to top, so that the latest events are at
+  // the top of the view.
+  function renderGraph(g) {
+    var creators = [], column = {}, pos = {};
+    var minL = Infinity, maxL = -Infinity;
+
+    g.nodes.forEach(function (n) {
+      if (!(n.creator in column)) {
+        column[n.creator] = creators.length;
+        creators.push(n);
+      }
+      minL = Math.min(minL, n.lamport);
+      maxL = Math.max(maxL, n.lamport);
+    });
+
+    if (!g.nodes.length) {
+      document.getElementById("graph").innerHTML = "<p>No events</p>";
+      return;
+    }
+
+    var dx = 120, dy = 28, top = 30;
+    var width = creators.length * dx + 40;
+    var height = (maxL - minL + 1) * dy + top + 20;
+
+    g.nodes.forEach(function (n) {
+      pos[n.id] = {
+        x: 60 + column[n.creator] * dx,
+        y: top + (maxL - n.lamport) * dy + 10
+      };
+    });
+
+    var svg = '<svg width="' + width + '" height="' + height + '">';
+
+    creators.forEach(function (n, i) {
+      svg += '<text x="' + (60 + i * dx) + '" y="16" text-anchor="middle">' +
+        esc(n.moniker || n.creator.substring(0, 10)) + "</text>";
+    });
+
+    g.links.forEach(function (l) {
+      var a = pos[l.source], b = pos[l.target];
+      svg += '<line class="' + (l.type === "self-parent" ? "self" : "other") +
+        '" x1="' + a.x + '" y1="' + a.y + '" x2="' + b.x + '" y2="' + b.y + '"/>';
+    });
+
+    g.nodes.forEach(function (n) {
+      var p = pos[n.id], cls = [];
+      if (n.witness) {
+        cls.push("witness");
+        if (n.famous === "True") cls.push("famous");
+        if (n.famous === "False") cls.push("notfamous");
+      }
+      svg += '<circle class="' + cls.join(" ") + '" cx="' + p.x + '" cy="' + p.y + '" r="8">' +
+        "<title>" + esc(n.moniker) + " " + esc(n.index) + "\nround " + esc(n.round) +
+        "\nlamport " + esc(n.lamport) +
+        (n.round_received !== null ? "\nreceived " + esc(n.round_received) : "") +
+        "\n" + esc(n.transactions) + " transactions</title></circle>" +
+        '<text x="' + (p.x + 12) + '" y="' + (p.y + 4) + '">' + esc(n.index) +
+        " r" + esc(n.round) + "</text>";
+    });
+
+    document.getElementById("graph").innerHTML = svg + "</svg>";
+  }
+
+  function refresh() {
+    var error = document.getElementById("error");
+
+    Promise.all([get("/v1/stats"), get("/readyz", true)]).then(function (res) {
+      renderStats(res[0]);
+      renderSync(res[0], res[1]);
+
+      var last = res[0].last_block_index;
+      var start = Math.max(0, last - 9);
+
+      return Promise.all([
+        get("/v1/peers/stats"),
+        last >= 0 ? get("/v1/blocks?start=" + start + "&count=10") : { blocks: [] },
+        get("/v1/graph/export?format=json")
+      ]);
+    }).then(function (res) {
+      renderPeers(res[0]);
+      renderBlocks(res[1]);
+      renderGraph(res[2]);
+      error.textContent = "";
+    }).catch(function (err) {
+      error.textContent = err.message;
+    });
+  }
+
+  refresh();
+  setInterval(refresh, 2000);
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestGetDashboard(t *testing.T) {
+	s := &Service{
+		logger: common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	rec := httptest.NewRecorder()
+	s.GetDashboard(rec, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status should be %d, not %d", http.StatusOK, rec.Code)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("Content-Type should be text/html, not %s", ct)
+	}
+
+	// The page should only use routes that exist
+	paths := make(map[string]bool)
+	for _, rt := range s.routes() {
+		if rt.unversioned {
+			paths[rt.pattern] = true
+		} else {
+			paths[APIPrefix+rt.pattern] = true
+		}
+	}
+
+	for _, p := range []string{"/v1/stats", "/v1/peers/stats", "/v1/blocks", "/v1/graph/export", "/readyz"} {
+		if !strings.Contains(rec.Body.String(), `"`+p) {
+			t.Fatalf("dashboard should fetch %s", p)
+		}
+		if !paths[p] {
+			t.Fatalf("%s is not a route of the service", p)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	s.GetDashboard(rec, httptest.NewRequest(http.MethodPost, "/dashboard", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status should be %d, not %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
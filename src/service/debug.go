@@ -0,0 +1,234 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxProfileDuration caps the duration of CPU profiles and execution traces.
+const maxProfileDuration = 5 * time.Minute
+
+// GetProfile serves the runtime profiles in the format expected by the pprof
+// tool. It is implemented directly on top of runtime/pprof rather than
+// net/http/pprof, because importing the latter registers unprotected handlers
+// with the DefaultServerMux.
+//
+//  GET /debug/pprof/
+//  returns: HTML list of available profiles
+//
+//  GET /debug/pprof/{profile}?debug={x}
+//  example: /debug/pprof/heap
+//  returns: profile in pprof format, or in text format if debug > 0
+//
+//  GET /debug/pprof/profile?seconds={x}
+//  returns: CPU profile collected for x seconds (default 30)
+//
+//  GET /debug/pprof/trace?seconds={x}
+//  returns: execution trace collected for x seconds (default 1)
+func (s *Service) GetProfile(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/")
+
+	switch name {
+	case "":
+		s.profileIndex(w)
+	case "profile":
+		s.cpuProfile(w, r)
+	case "trace":
+		s.executionTrace(w, r)
+	default:
+		s.namedProfile(w, r, name)
+	}
+}
+
+func (s *Service) profileIndex(w http.ResponseWriter) {
+	profiles := pprof.Profiles()
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name() < profiles[j].Name()
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	fmt.Fprintln(w, "<html><head><title>/debug/pprof/</title></head><body><table>")
+	for _, p := range profiles {
+		name := html.EscapeString(p.Name())
+		fmt.Fprintf(w, "<tr><td>%d</td><td><a href=\"%s?debug=1\">%s</a></td></tr>\n",
+			p.Count(), name, name)
+	}
+	fmt.Fprintln(w, "<tr><td></td><td><a href=\"profile\">profile</a></td></tr>")
+	fmt.Fprintln(w, "<tr><td></td><td><a href=\"trace\">trace</a></td></tr>")
+	fmt.Fprintln(w, "</table></body></html>")
+}
+
+func (s *Service) namedProfile(w http.ResponseWriter, r *http.Request, name string) {
+	p := pprof.Lookup(name)
+	if p == nil {
+		http.Error(w, fmt.Sprintf("Unknown profile %q", name), http.StatusNotFound)
+		return
+	}
+
+	debugLevel, _ := strconv.Atoi(r.URL.Query().Get("debug"))
+
+	if name == "heap" && r.URL.Query().Get("gc") != "" {
+		runtime.GC()
+	}
+
+	if debugLevel > 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	}
+
+	if err := p.WriteTo(w, debugLevel); err != nil {
+		s.logger.WithError(err).Errorf("Writing profile %s", name)
+	}
+}
+
+func (s *Service) cpuProfile(w http.ResponseWriter, r *http.Request) {
+	duration, err := profileDuration(r, 30*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+
+	if err := pprof.StartCPUProfile(w); err != nil {
+		// Most likely, another CPU profile is already running
+		w.Header().Del("Content-Disposition")
+		http.Error(w, fmt.Sprintf("Could not enable CPU profiling: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.WithField("duration", duration).Info("CPU profile")
+
+	sleep(r, duration)
+	pprof.StopCPUProfile()
+}
+
+func (s *Service) executionTrace(w http.ResponseWriter, r *http.Request) {
+	duration, err := profileDuration(r, time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+
+	if err := trace.Start(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, fmt.Sprintf("Could not enable tracing: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.WithField("duration", duration).Info("Execution trace")
+
+	sleep(r, duration)
+	trace.Stop()
+}
+
+// GetGoroutines returns the stack traces of all the goroutines in text format.
+//
+//  GET /debug/goroutines
+//  returns: text goroutine dump
+func (s *Service) GetGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if err := pprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		s.logger.WithError(err).Error("Writing goroutine dump")
+	}
+}
+
+// GCStats contains information about memory allocation and garbage collection.
+type GCStats struct {
+	Goroutines    int             `json:"goroutines"`
+	HeapAlloc     uint64          `json:"heap_alloc"`
+	HeapSys       uint64          `json:"heap_sys"`
+	HeapObjects   uint64          `json:"heap_objects"`
+	TotalAlloc    uint64          `json:"total_alloc"`
+	Sys           uint64          `json:"sys"`
+	NextGC        uint64          `json:"next_gc"`
+	NumGC         int64           `json:"num_gc"`
+	LastGC        time.Time       `json:"last_gc"`
+	PauseTotal    time.Duration   `json:"pause_total"`
+	RecentPauses  []time.Duration `json:"recent_pauses"`
+	GCCPUFraction float64         `json:"gc_cpu_fraction"`
+}
+
+// GetGCStats returns memory and garbage collection statistics. Durations are
+// expressed in nanoseconds.
+//
+//  GET /debug/gc
+//  returns: JSON GCStats
+func (s *Service) GetGCStats(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var gc debug.GCStats
+	gc.Pause = make([]time.Duration, 0, 10)
+	debug.ReadGCStats(&gc)
+
+	// ReadGCStats returns the most recent pauses first
+	recent := gc.Pause
+	if len(recent) > 10 {
+		recent = recent[:10]
+	}
+
+	stats := GCStats{
+		Goroutines:    runtime.NumGoroutine(),
+		HeapAlloc:     mem.HeapAlloc,
+		HeapSys:       mem.HeapSys,
+		HeapObjects:   mem.HeapObjects,
+		TotalAlloc:    mem.TotalAlloc,
+		Sys:           mem.Sys,
+		NextGC:        mem.NextGC,
+		NumGC:         gc.NumGC,
+		LastGC:        gc.LastGC,
+		PauseTotal:    gc.PauseTotal,
+		RecentPauses:  recent,
+		GCCPUFraction: mem.GCCPUFraction,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// profileDuration parses the seconds parameter of a profiling request.
+func profileDuration(r *http.Request, def time.Duration) (time.Duration, error) {
+	param := r.URL.Query().Get("seconds")
+	if param == "" {
+		return def, nil
+	}
+
+	sec, err := strconv.ParseFloat(param, 64)
+	if err != nil || sec <= 0 {
+		return 0, fmt.Errorf("Invalid seconds parameter %q", param)
+	}
+
+	duration := time.Duration(sec * float64(time.Second))
+	if duration > maxProfileDuration {
+		duration = maxProfileDuration
+	}
+
+	return duration, nil
+}
+
+// sleep waits for d, or until the client goes away.
+func sleep(r *http.Request, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.Context().Done():
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+)
+
+// MAXEVIDENCE is the maximum number of proofs of misbehaviour returned by the
+// /evidence endpoint
+const MAXEVIDENCE = 50
+
+// EvidencePage is a page of the proofs of misbehaviour collected by the node.
+// Next is the start parameter of the following page, or nil if there is no
+// more evidence.
+type EvidencePage struct {
+	Evidence []*hg.SignedEvidence `json:"evidence"`
+	Next     *int                 `json:"next"`
+}
+
+// ListEvidence returns a page of the proofs of misbehaviour collected by the
+// node, oldest first: forks, double Block signatures, and equivocating
+// InternalTransactions. Each proof is signed by the node, and only contains
+// objects signed by the offender, so it can be verified, for example by a
+// staking contract, without access to the hashgraph. The start parameter is
+// the position of the first proof in the list, which only keeps the last
+// proofs.
+//
+//  GET /evidence?start={x}&count={y}
+//  returns: JSON EvidencePage
+func (s *Service) ListEvidence(w http.ResponseWriter, r *http.Request) {
+	start, count, err := parsePage(r, MAXEVIDENCE)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	evidence := s.node.GetEvidence()
+
+	page := EvidencePage{Evidence: []*hg.SignedEvidence{}}
+
+	if start < len(evidence) {
+		end, next := pageEnd(start, count, len(evidence)-1)
+		page.Evidence = evidence[start : end+1]
+		page.Next = next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
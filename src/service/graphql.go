@@ -0,0 +1,516 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"github.com/mosaicnetworks/babble/src/common"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// MAXGRAPHQLBYTES is the maximum size of a GraphQL request body
+const MAXGRAPHQLBYTES = 1 << 16
+
+// GraphQLRequest is the body of a GraphQL POST request.
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQL executes a GraphQL query against the hashgraph and blockchain data.
+// Queries are passed in the body of a POST request, or in the query parameter
+// of a GET request. The schema can be retrieved with an introspection query.
+//
+//  POST /graphql
+//  example: {"query": "{ block(index: 1) { index round { witnesses { creator } } } }"}
+//  returns: JSON graphql.Result
+func (s *Service) GraphQL(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MAXGRAPHQLBYTES))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         s.graphqlSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        r.Context(),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// newGraphQLSchema builds the GraphQL schema. The resolvers fetch the data
+// from the node lazily, so that only the requested relations are loaded.
+func (s *Service) newGraphQLSchema() (graphql.Schema, error) {
+	var eventType, roundType, blockType *graphql.Object
+
+	peerType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Peer",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.Float,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return float64(p.Source.(*peers.Peer).ID()), nil
+				},
+			},
+			"pubKey": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*peers.Peer).PubKeyString(), nil
+				},
+			},
+			"netAddr": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*peers.Peer).NetAddr, nil
+				},
+			},
+			"moniker": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*peers.Peer).Moniker, nil
+				},
+			},
+		},
+	})
+
+	validatorSetType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ValidatorSet",
+		Fields: graphql.Fields{
+			"round": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(ValidatorSet).Round, nil
+				},
+			},
+			"validators": &graphql.Field{
+				Type: graphql.NewList(peerType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(ValidatorSet).Validators, nil
+				},
+			},
+		},
+	})
+
+	signatureType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BlockSignature",
+		Fields: graphql.Fields{
+			"validator": &graphql.Field{Type: graphql.String},
+			"signature": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	// resolveEvent returns the event identified by hash, or nil if the hash is
+	// empty (ex: the parent of a root event).
+	resolveEvent := func(hash string) (interface{}, error) {
+		if hash == "" {
+			return nil, nil
+		}
+		return s.node.GetEvent(hash)
+	}
+
+	resolveEvents := func(hashes []string) (interface{}, error) {
+		sort.Strings(hashes)
+		events := []*hg.Event{}
+		for _, h := range hashes {
+			ev, err := s.node.GetEvent(h)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, ev)
+		}
+		return events, nil
+	}
+
+	resolveRound := func(index int) (interface{}, error) {
+		round, err := s.node.GetRound(index)
+		if err != nil {
+			return nil, err
+		}
+		return Round{Index: index, RoundInfo: round}, nil
+	}
+
+	resolveValidators := func(round int) (interface{}, error) {
+		return s.node.GetValidatorSet(round)
+	}
+
+	eventType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Event",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"hash": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*hg.Event).Hex(), nil
+					},
+				},
+				"creator": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*hg.Event).Creator(), nil
+					},
+				},
+				"index": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*hg.Event).Index(), nil
+					},
+				},
+				"round": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return intOrNil(p.Source.(*hg.Event).GetRound()), nil
+					},
+				},
+				"lamportTimestamp": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return intOrNil(p.Source.(*hg.Event).GetLamportTimestamp()), nil
+					},
+				},
+				"roundReceived": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return intOrNil(p.Source.(*hg.Event).GetRoundReceived()), nil
+					},
+				},
+				"transactions": &graphql.Field{
+					Type:        graphql.NewList(graphql.String),
+					Description: "Base64 encoded transactions",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return encodeTransactions(p.Source.(*hg.Event).Transactions()), nil
+					},
+				},
+				"selfParent": &graphql.Field{
+					Type: eventType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveEvent(p.Source.(*hg.Event).SelfParent())
+					},
+				},
+				"otherParent": &graphql.Field{
+					Type: eventType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveEvent(p.Source.(*hg.Event).OtherParent())
+					},
+				},
+			}
+		}),
+	})
+
+	roundType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Round",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"index": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(Round).Index, nil
+					},
+				},
+				"witnesses": &graphql.Field{
+					Type: graphql.NewList(eventType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveEvents(p.Source.(Round).Witnesses())
+					},
+				},
+				"famousWitnesses": &graphql.Field{
+					Type: graphql.NewList(eventType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveEvents(p.Source.(Round).FamousWitnesses())
+					},
+				},
+				"createdEvents": &graphql.Field{
+					Type: graphql.NewList(eventType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						hashes := []string{}
+						for h := range p.Source.(Round).CreatedEvents {
+							hashes = append(hashes, h)
+						}
+						return resolveEvents(hashes)
+					},
+				},
+				"receivedEvents": &graphql.Field{
+					Type: graphql.NewList(eventType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						// ReceivedEvents are in consensus order; do not sort
+						events := []*hg.Event{}
+						for _, h := range p.Source.(Round).ReceivedEvents {
+							ev, err := s.node.GetEvent(h)
+							if err != nil {
+								return nil, err
+							}
+							events = append(events, ev)
+						}
+						return events, nil
+					},
+				},
+				"validators": &graphql.Field{
+					Type: graphql.NewList(peerType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveValidators(p.Source.(Round).Index)
+					},
+				},
+			}
+		}),
+	})
+
+	blockType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Block",
+		Fields: graphql.Fields{
+			"index": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*hg.Block).Index(), nil
+				},
+			},
+			"roundReceived": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*hg.Block).RoundReceived(), nil
+				},
+			},
+			"stateHash": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return common.EncodeToString(p.Source.(*hg.Block).StateHash()), nil
+				},
+			},
+			"frameHash": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return common.EncodeToString(p.Source.(*hg.Block).FrameHash()), nil
+				},
+			},
+			"peersHash": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return common.EncodeToString(p.Source.(*hg.Block).PeersHash()), nil
+				},
+			},
+			"transactions": &graphql.Field{
+				Type:        graphql.NewList(graphql.String),
+				Description: "Base64 encoded transactions",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return encodeTransactions(p.Source.(*hg.Block).Transactions()), nil
+				},
+			},
+			"signatures": &graphql.Field{
+				Type: graphql.NewList(signatureType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					block := p.Source.(*hg.Block)
+
+					validators := []string{}
+					for v := range block.Signatures {
+						validators = append(validators, v)
+					}
+					sort.Strings(validators)
+
+					res := []map[string]interface{}{}
+					for _, v := range validators {
+						res = append(res, map[string]interface{}{
+							"validator": v,
+							"signature": block.Signatures[v],
+						})
+					}
+					return res, nil
+				},
+			},
+			"round": &graphql.Field{
+				Type: roundType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveRound(p.Source.(*hg.Block).RoundReceived())
+				},
+			},
+			"validators": &graphql.Field{
+				Type: graphql.NewList(peerType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveValidators(p.Source.(*hg.Block).RoundReceived())
+				},
+			},
+		},
+	})
+
+	rangeArgs := graphql.FieldConfigArgument{
+		"start": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+		"count": &graphql.ArgumentConfig{Type: graphql.Int},
+	}
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"block": &graphql.Field{
+				Type: blockType,
+				Args: graphql.FieldConfigArgument{
+					"index": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.node.GetBlock(p.Args["index"].(int))
+				},
+			},
+			"blocks": &graphql.Field{
+				Type:        graphql.NewList(blockType),
+				Description: "At most 50 blocks from start",
+				Args:        rangeArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					start, count := rangeParams(p.Args, MAXBLOCKS)
+					end, _ := pageEnd(start, count, s.node.GetLastBlockIndex())
+
+					blocks := []*hg.Block{}
+					for i := start; i <= end; i++ {
+						block, err := s.node.GetBlock(i)
+						if err != nil {
+							return nil, err
+						}
+						blocks = append(blocks, block)
+					}
+					return blocks, nil
+				},
+			},
+			"round": &graphql.Field{
+				Type: roundType,
+				Args: graphql.FieldConfigArgument{
+					"index": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveRound(p.Args["index"].(int))
+				},
+			},
+			"rounds": &graphql.Field{
+				Type:        graphql.NewList(roundType),
+				Description: "At most 50 rounds from start",
+				Args:        rangeArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					start, count := rangeParams(p.Args, MAXROUNDS)
+					end, _ := pageEnd(start, count, s.node.GetLastRound())
+
+					rounds := []Round{}
+					for i := start; i <= end; i++ {
+						round, err := s.node.GetRound(i)
+						if err != nil {
+							return nil, err
+						}
+						rounds = append(rounds, Round{Index: i, RoundInfo: round})
+					}
+					return rounds, nil
+				},
+			},
+			"event": &graphql.Field{
+				Type: eventType,
+				Args: graphql.FieldConfigArgument{
+					"hash": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.node.GetEvent(p.Args["hash"].(string))
+				},
+			},
+			"validators": &graphql.Field{
+				Type:        graphql.NewList(peerType),
+				Description: "Validator-set of a round; the last consensus round by default",
+				Args: graphql.FieldConfigArgument{
+					"round": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					round, ok := p.Args["round"].(int)
+					if !ok {
+						round = s.node.GetLastConsensusRoundIndex()
+					}
+					return resolveValidators(round)
+				},
+			},
+			"validatorHistory": &graphql.Field{
+				Type: graphql.NewList(validatorSetType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					allPeerSets, err := s.node.GetAllValidatorSets()
+					if err != nil {
+						return nil, err
+					}
+
+					rounds := []int{}
+					for round := range allPeerSets {
+						rounds = append(rounds, round)
+					}
+					sort.Ints(rounds)
+
+					res := []ValidatorSet{}
+					for _, round := range rounds {
+						res = append(res, ValidatorSet{Round: round, Validators: allPeerSets[round]})
+					}
+					return res, nil
+				},
+			},
+			"peers": &graphql.Field{
+				Type: graphql.NewList(peerType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.node.GetPeers(), nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: queryType,
+	})
+}
+
+// rangeParams returns the start and count arguments of a list query. The count
+// defaults to, and is capped at, max.
+func rangeParams(args map[string]interface{}, max int) (start int, count int) {
+	start, _ = args["start"].(int)
+	if start < 0 {
+		start = 0
+	}
+
+	count, ok := args["count"].(int)
+	if !ok || count > max {
+		count = max
+	}
+
+	return start, count
+}
+
+func encodeTransactions(txs [][]byte) []string {
+	res := make([]string, len(txs))
+	for i, tx := range txs {
+		res[i] = base64.StdEncoding.EncodeToString(tx)
+	}
+	return res
+}
+
+func intOrNil(i *int) interface{} {
+	if i == nil {
+		return nil
+	}
+	return *i
+}
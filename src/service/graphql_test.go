@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/testapp"
+)
+
+func TestGraphQL(t *testing.T) {
+	cluster := testapp.NewCluster(t, 2)
+	cluster.Run()
+	defer cluster.Shutdown()
+
+	cluster.Clients[0].Set("key", "value")
+	cluster.AssertConvergence(t, 0, 10*time.Second)
+
+	s := &Service{
+		node:   cluster.Nodes[0],
+		logger: common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	schema, err := s.newGraphQLSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.graphqlSchema = schema
+
+	body, _ := json.Marshal(GraphQLRequest{
+		Query: `query($index: Int!) {
+			block(index: $index) {
+				index
+				transactions
+				validators { moniker }
+				round { index witnesses { hash selfParent { index } } }
+			}
+			peers { pubKey }
+		}`,
+		Variables: map[string]interface{}{"index": 0},
+	})
+
+	rec := httptest.NewRecorder()
+	s.GraphQL(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status should be %d, not %d", http.StatusOK, rec.Code)
+	}
+
+	var resp struct {
+		Data struct {
+			Block struct {
+				Index        int
+				Transactions []string
+				Validators   []struct{ Moniker string }
+				Round        struct {
+					Index     int
+					Witnesses []struct {
+						Hash string
+					}
+				}
+			}
+			Peers []struct{ PubKey string }
+		}
+		Errors []interface{}
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Errors) > 0 {
+		t.Fatalf("query should not return errors: %v", resp.Errors)
+	}
+
+	if len(resp.Data.Block.Transactions) != 1 {
+		t.Fatalf("block 0 should contain 1 transaction, not %d", len(resp.Data.Block.Transactions))
+	}
+
+	if len(resp.Data.Block.Validators) != 2 || len(resp.Data.Peers) != 2 {
+		t.Fatalf("there should be 2 validators and peers, not %d and %d",
+			len(resp.Data.Block.Validators), len(resp.Data.Peers))
+	}
+
+	if len(resp.Data.Block.Round.Witnesses) == 0 {
+		t.Fatalf("round %d should have witnesses", resp.Data.Block.Round.Index)
+	}
+}
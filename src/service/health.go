@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mosaicnetworks/babble/src/node/state"
+)
+
+// Check is the result of a single readiness check.
+type Check struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Readiness is the response of the /readyz endpoint. The node is ready when all
+// the checks pass. EventLag and RoundLag are the values measured by the lag
+// checks.
+type Readiness struct {
+	Ready    bool             `json:"ready"`
+	EventLag int              `json:"event_lag"`
+	RoundLag int              `json:"round_lag"`
+	Checks   map[string]Check `json:"checks"`
+}
+
+// newReadiness returns a Readiness with no checks, which is ready.
+func newReadiness() *Readiness {
+	return &Readiness{
+		Ready:  true,
+		Checks: make(map[string]Check),
+	}
+}
+
+// add records the result of the named check. A non-nil error marks the node as
+// not ready.
+func (r *Readiness) add(name string, err error) {
+	if err != nil {
+		r.Ready = false
+		r.Checks[name] = Check{OK: false, Error: err.Error()}
+		return
+	}
+	r.Checks[name] = Check{OK: true}
+}
+
+// GetHealth is the liveness probe. It responds as long as the process is able
+// to serve HTTP requests.
+//
+//  GET /healthz
+//  returns: JSON {"status":"ok"}
+func (s *Service) GetHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// GetReadiness is the readiness probe. The node is ready when it is Babbling,
+// when it is not lagging behind the other nodes by more than the configured
+// number of events, when it does not have more than the configured number of
+// undecided rounds, when the application is reachable through the AppProxy,
+// and when the Store is writable. The response status is 503 if any of the
+// checks fails.
+//
+//  GET /readyz
+//  returns: JSON Readiness
+func (s *Service) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	readiness := newReadiness()
+
+	var stateErr error
+	if st := s.node.GetState(); st != state.Babbling {
+		stateErr = fmt.Errorf("node is %s", st)
+	}
+	readiness.add("state", stateErr)
+
+	readiness.EventLag = s.node.GetEventLag()
+
+	var eventLagErr error
+	if lag := readiness.EventLag; lag > s.readyMaxEventLag {
+		eventLagErr = fmt.Errorf("%d events behind, max %d", lag, s.readyMaxEventLag)
+	}
+	readiness.add("event_lag", eventLagErr)
+
+	readiness.RoundLag = s.node.GetRoundLag()
+
+	var roundLagErr error
+	if lag := readiness.RoundLag; lag > s.readyMaxRoundLag {
+		roundLagErr = fmt.Errorf("%d undecided rounds, max %d", lag, s.readyMaxRoundLag)
+	}
+	readiness.add("round_lag", roundLagErr)
+
+	readiness.add("proxy", s.node.CheckProxy())
+	readiness.add("store", s.node.CheckStore())
+
+	if !readiness.Ready {
+		s.logger.WithField("checks", readiness.Checks).Debug("Not ready")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !readiness.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(readiness)
+}
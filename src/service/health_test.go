@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestGetHealth(t *testing.T) {
+	s := &Service{
+		logger: common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	rec := httptest.NewRecorder()
+	s.GetHealth(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status should be %d, not %d", http.StatusOK, rec.Code)
+	}
+
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp["status"] != "ok" {
+		t.Fatalf("status should be ok, not %s", resp["status"])
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	r := newReadiness()
+
+	r.add("proxy", nil)
+	if !r.Ready {
+		t.Fatal("readiness should be ready after passing check")
+	}
+
+	r.add("store", fmt.Errorf("read-only"))
+	r.add("state", nil)
+	if r.Ready {
+		t.Fatal("readiness should not be ready after failing check")
+	}
+
+	if c := r.Checks["store"]; c.OK || c.Error != "read-only" {
+		t.Fatalf("store check should fail with read-only, not %+v", c)
+	}
+
+	if c := r.Checks["state"]; !c.OK || c.Error != "" {
+		t.Fatalf("state check should pass, not %+v", c)
+	}
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// MAXROUNDS is the maximum number of rounds returned by the /rounds endpoint
+const MAXROUNDS = 50
+
+// MAXVALIDATORSETS is the maximum number of validator-sets returned by the
+// /validators/history endpoint
+const MAXVALIDATORSETS = 50
+
+// MAXPEERSHASHES is the maximum number of blocks returned by the
+// /validators/hashes endpoint
+const MAXPEERSHASHES = 200
+
+// MAXMEMBERSHIPCHANGES is the maximum number of membership changes returned by
+// the /validators/changes endpoint
+const MAXMEMBERSHIPCHANGES = 50
+
+// BlockPage is a page of blocks. Next is the start parameter of the following
+// page, or nil if there are no more blocks.
+type BlockPage struct {
+	Blocks []*hg.Block `json:"blocks"`
+	Next   *int        `json:"next"`
+}
+
+// Round is a hashgraph round with its index.
+type Round struct {
+	Index int `json:"index"`
+	*hg.RoundInfo
+}
+
+// RoundPage is a page of rounds. Next is the first round of the following
+// page, or nil if there are no more rounds.
+type RoundPage struct {
+	Rounds []Round `json:"rounds"`
+	Next   *int    `json:"next"`
+}
+
+// RoundLatency is the timeline of a round, as seen by the node, with the
+// latencies of its stages since its creation, in seconds. The stages that are
+// not reached yet are omitted.
+type RoundLatency struct {
+	Index            int        `json:"index"`
+	Created          *time.Time `json:"created,omitempty"`
+	Decided          *time.Time `json:"decided,omitempty"`
+	Received         *time.Time `json:"received,omitempty"`
+	Committed        *time.Time `json:"committed,omitempty"`
+	DecidedLatency   float64    `json:"decided_latency,omitempty"`
+	ReceivedLatency  float64    `json:"received_latency,omitempty"`
+	CommittedLatency float64    `json:"committed_latency,omitempty"`
+}
+
+// RoundLatencyPage is a page of round latencies. Next is the first round of the
+// following page, or nil if there are no more rounds.
+type RoundLatencyPage struct {
+	Rounds []RoundLatency `json:"rounds"`
+	Next   *int           `json:"next"`
+}
+
+// NewRoundLatency converts the timeline of a round.
+func NewRoundLatency(index int, timeline hg.RoundTimeline) RoundLatency {
+	res := RoundLatency{Index: index}
+
+	stage := func(ns int64, latency *float64) *time.Time {
+		if ns == 0 {
+			return nil
+		}
+		t := time.Unix(0, ns).UTC()
+		if latency != nil && res.Created != nil {
+			*latency = t.Sub(*res.Created).Seconds()
+		}
+		return &t
+	}
+
+	res.Created = stage(timeline.Created, nil)
+	res.Decided = stage(timeline.Decided, &res.DecidedLatency)
+	res.Received = stage(timeline.Received, &res.ReceivedLatency)
+	res.Committed = stage(timeline.Committed, &res.CommittedLatency)
+
+	return res
+}
+
+// ValidatorSet is a validator-set with the round from which it is effective.
+// Hash is the hash of the validator-set, which is committed as the PeersHash of
+// the blocks of the following rounds.
+type ValidatorSet struct {
+	Round      int           `json:"round"`
+	Hash       string        `json:"hash"`
+	Validators []*peers.Peer `json:"validators"`
+}
+
+// BlockPeersHash is the hash of the validator-set committed in a block, and
+// the hash committed in the previous block. The hashes only differ when a
+// membership change was accepted in between, so comparing consecutive blocks
+// exposes the membership changes that were not accepted by the network.
+// PrevPeersHash is empty for the first block, and when the previous block is
+// not available.
+type BlockPeersHash struct {
+	Block         int    `json:"block"`
+	RoundReceived int    `json:"round_received"`
+	PeersHash     string `json:"peers_hash"`
+	PrevPeersHash string `json:"prev_peers_hash,omitempty"`
+	Changed       bool   `json:"changed"`
+}
+
+// BlockPeersHashPage is a page of block peers hashes. Next is the start
+// parameter of the following page, or nil if there are no more blocks.
+type BlockPeersHashPage struct {
+	Blocks []BlockPeersHash `json:"blocks"`
+	Next   *int             `json:"next"`
+}
+
+// ValidatorSetPage is a page of the validator-set history. Next is the start
+// parameter of the following page, or nil if there are no more validator-sets.
+type ValidatorSetPage struct {
+	ValidatorSets []ValidatorSet `json:"validator_sets"`
+	Next          *int           `json:"next"`
+}
+
+// MembershipChangePage is a page of the history of membership changes. Next is
+// the start parameter of the following page, or nil if there are no more
+// changes.
+type MembershipChangePage struct {
+	Changes []node.MembershipChange `json:"changes"`
+	Next    *int                    `json:"next"`
+}
+
+// ListBlocks returns a page of blocks. The start parameter defaults to 0, and
+// the count parameter to MAXBLOCKS, which is also its maximum value.
+//
+//  GET /blocks?start={x}&count={y}
+//  example: /blocks?start=100&count=20
+//  returns: JSON BlockPage
+func (s *Service) ListBlocks(w http.ResponseWriter, r *http.Request) {
+	start, count, err := parsePage(r, MAXBLOCKS)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := BlockPage{Blocks: []*hg.Block{}}
+
+	end, next := pageEnd(start, count, s.node.GetLastBlockIndex())
+
+	for i := start; i <= end; i++ {
+		block, err := s.node.GetBlock(i)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Retrieving block %d", i)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Blocks = append(page.Blocks, block)
+	}
+
+	page.Next = next
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// ListRounds returns a page of hashgraph rounds. The range parameter is
+// inclusive, and the end may be omitted. At most MAXROUNDS rounds are returned.
+//
+//  GET /rounds?range={start}-{end}
+//  example: /rounds?range=10-20
+//  returns: JSON RoundPage
+func (s *Service) ListRounds(w http.ResponseWriter, r *http.Request) {
+	start, count, err := parseRange(r.URL.Query().Get("range"), MAXROUNDS)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := RoundPage{Rounds: []Round{}}
+
+	end, next := pageEnd(start, count, s.node.GetLastRound())
+
+	for i := start; i <= end; i++ {
+		round, err := s.node.GetRound(i)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Retrieving round %d", i)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Rounds = append(page.Rounds, Round{Index: i, RoundInfo: round})
+	}
+
+	page.Next = next
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// ListRoundLatencies returns the timelines of a page of hashgraph rounds: when
+// each round was created, when the fame of its witnesses was decided, when it
+// was processed as a round-received, and when its Block was committed. The
+// range parameter works like in ListRounds.
+//
+//  GET /rounds/latency?range={start}-{end}
+//  example: /rounds/latency?range=10-20
+//  returns: JSON RoundLatencyPage
+func (s *Service) ListRoundLatencies(w http.ResponseWriter, r *http.Request) {
+	start, count, err := parseRange(r.URL.Query().Get("range"), MAXROUNDS)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := RoundLatencyPage{Rounds: []RoundLatency{}}
+
+	end, next := pageEnd(start, count, s.node.GetLastRound())
+
+	for i := start; i <= end; i++ {
+		round, err := s.node.GetRound(i)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Retrieving round %d", i)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Rounds = append(page.Rounds, NewRoundLatency(i, round.Timeline))
+	}
+
+	page.Next = next
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// ListValidatorSets returns a page of the validator-set history, ordered by
+// round. It starts with the first validator-set effective from a round greater
+// or equal to the start parameter, and returns at most count, or
+// MAXVALIDATORSETS, validator-sets.
+//
+//  GET /validators/history?start={round}&count={x}
+//  returns: JSON ValidatorSetPage
+func (s *Service) ListValidatorSets(w http.ResponseWriter, r *http.Request) {
+	start, count, err := parsePage(r, MAXVALIDATORSETS)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	allPeerSets, err := s.node.GetAllValidatorSets()
+	if err != nil {
+		s.logger.WithError(err).Errorf("Fetching validator-sets")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rounds := []int{}
+	for round := range allPeerSets {
+		if round >= start {
+			rounds = append(rounds, round)
+		}
+	}
+	sort.Ints(rounds)
+
+	page := ValidatorSetPage{ValidatorSets: []ValidatorSet{}}
+
+	for i, round := range rounds {
+		if i == count {
+			next := round
+			page.Next = &next
+			break
+		}
+		page.ValidatorSets = append(page.ValidatorSets, ValidatorSet{
+			Round:      round,
+			Hash:       peers.NewPeerSet(allPeerSets[round]).Hex(),
+			Validators: allPeerSets[round],
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// ListPeersHashes returns a page of the validator-set hashes committed in the
+// blocks, each linked to the hash of the previous block, such that auditors can
+// follow the chain of validator-sets without downloading the blocks. The start
+// and count parameters work like in ListBlocks, with a maximum of
+// MAXPEERSHASHES.
+//
+//  GET /validators/hashes?start={x}&count={y}
+//  returns: JSON BlockPeersHashPage
+func (s *Service) ListPeersHashes(w http.ResponseWriter, r *http.Request) {
+	start, count, err := parsePage(r, MAXPEERSHASHES)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := BlockPeersHashPage{Blocks: []BlockPeersHash{}}
+
+	end, next := pageEnd(start, count, s.node.GetLastBlockIndex())
+
+	prev := ""
+	if start > 0 && start <= end {
+		// The previous block may be missing from a node that fast-forwarded
+		if block, err := s.node.GetBlock(start - 1); err == nil {
+			prev = common.EncodeToString(block.PeersHash())
+		}
+	}
+
+	for i := start; i <= end; i++ {
+		block, err := s.node.GetBlock(i)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Retrieving block %d", i)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		hash := common.EncodeToString(block.PeersHash())
+
+		page.Blocks = append(page.Blocks, BlockPeersHash{
+			Block:         i,
+			RoundReceived: block.RoundReceived(),
+			PeersHash:     hash,
+			PrevPeersHash: prev,
+			Changed:       prev != "" && prev != hash,
+		})
+
+		prev = hash
+	}
+
+	page.Next = next
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// ListMembershipChanges returns a page of the history of membership changes:
+// the accepted PEER_ADD, PEER_REMOVE, PEER_RENAME and PEER_ADDRESS
+// InternalTransactions, the rounds from which they took effect, and the
+// validators that signed the accepting blocks.
+// The start parameter is the position of the first change in the history.
+//
+//  GET /validators/changes?start={x}&count={y}
+//  returns: JSON MembershipChangePage
+func (s *Service) ListMembershipChanges(w http.ResponseWriter, r *http.Request) {
+	start, count, err := parsePage(r, MAXMEMBERSHIPCHANGES)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := s.node.GetMembershipChanges()
+	if err != nil {
+		s.logger.WithError(err).Errorf("Fetching membership changes")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page := MembershipChangePage{Changes: []node.MembershipChange{}}
+
+	if start < len(changes) {
+		end, next := pageEnd(start, count, len(changes)-1)
+		page.Changes = changes[start : end+1]
+		page.Next = next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parsePage parses the start and count query parameters. The count defaults
+// to, and is capped at, max.
+func parsePage(r *http.Request, max int) (start int, count int, err error) {
+	count = max
+
+	if qs := r.URL.Query().Get("start"); qs != "" {
+		start, err = strconv.Atoi(qs)
+		if err != nil || start < 0 {
+			return 0, 0, fmt.Errorf("invalid start parameter %q", qs)
+		}
+	}
+
+	if qc := r.URL.Query().Get("count"); qc != "" {
+		count, err = strconv.Atoi(qc)
+		if err != nil || count < 1 {
+			return 0, 0, fmt.Errorf("invalid count parameter %q", qc)
+		}
+	}
+
+	if count > max {
+		count = max
+	}
+
+	return start, count, nil
+}
+
+// parseRange parses an inclusive range of the form {start}-{end} or {start}-,
+// and returns the start and the number of elements, capped at max.
+func parseRange(param string, max int) (start int, count int, err error) {
+	if param == "" {
+		return 0, max, nil
+	}
+
+	parts := strings.SplitN(param, "-", 2)
+
+	start, err = strconv.Atoi(parts[0])
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q", param)
+	}
+
+	count = max
+
+	if len(parts) == 2 && parts[1] != "" {
+		end, err := strconv.Atoi(parts[1])
+		if err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid range %q", param)
+		}
+		count = end - start + 1
+	}
+
+	if count > max {
+		count = max
+	}
+
+	return start, count, nil
+}
+
+// pageEnd returns the index of the last element of a page of count elements
+// starting at start, when the last available element is last. The returned next
+// is the start of the following page, or nil if the page reaches last.
+func pageEnd(start int, count int, last int) (end int, next *int) {
+	end = start + count - 1
+
+	if end >= last {
+		return last, nil
+	}
+
+	n := end + 1
+
+	return end, &n
+}
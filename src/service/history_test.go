@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/testapp"
+)
+
+func TestParsePage(t *testing.T) {
+	cases := []struct {
+		query string
+		start int
+		count int
+		err   bool
+	}{
+		{query: "", start: 0, count: 50},
+		{query: "start=10", start: 10, count: 50},
+		{query: "start=10&count=5", start: 10, count: 5},
+		{query: "count=500", start: 0, count: 50},
+		{query: "start=-1", err: true},
+		{query: "count=0", err: true},
+		{query: "start=abc", err: true},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/blocks?"+c.query, nil)
+
+		start, count, err := parsePage(r, 50)
+		if c.err {
+			if err == nil {
+				t.Fatalf("%q should return an error", c.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", c.query, err)
+		}
+		if start != c.start || count != c.count {
+			t.Fatalf("%q should return (%d, %d), not (%d, %d)", c.query, c.start, c.count, start, count)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		param string
+		start int
+		count int
+		err   bool
+	}{
+		{param: "", start: 0, count: 50},
+		{param: "10-20", start: 10, count: 11},
+		{param: "10-", start: 10, count: 50},
+		{param: "10", start: 10, count: 50},
+		{param: "0-1000", start: 0, count: 50},
+		{param: "20-10", err: true},
+		{param: "a-b", err: true},
+	}
+
+	for _, c := range cases {
+		start, count, err := parseRange(c.param, 50)
+		if c.err {
+			if err == nil {
+				t.Fatalf("%q should return an error", c.param)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", c.param, err)
+		}
+		if start != c.start || count != c.count {
+			t.Fatalf("%q should return (%d, %d), not (%d, %d)", c.param, c.start, c.count, start, count)
+		}
+	}
+}
+
+func TestPageEnd(t *testing.T) {
+	end, next := pageEnd(0, 10, 25)
+	if end != 9 || next == nil || *next != 10 {
+		t.Fatalf("first page should end at 9 with next 10, not %d, %v", end, next)
+	}
+
+	end, next = pageEnd(20, 10, 25)
+	if end != 25 || next != nil {
+		t.Fatalf("last page should end at 25 without next, not %d, %v", end, next)
+	}
+
+	end, next = pageEnd(30, 10, 25)
+	if end >= 30 || next != nil {
+		t.Fatalf("page beyond last should be empty, not end at %d, %v", end, next)
+	}
+}
+
+func TestNewRoundLatency(t *testing.T) {
+	sec := int64(time.Second)
+
+	l := NewRoundLatency(3, hg.RoundTimeline{Created: 10 * sec, Decided: 12 * sec, Received: 13 * sec})
+
+	if l.Index != 3 || l.Created == nil || l.Committed != nil {
+		t.Fatalf("Only the reached stages should be set: %+v", l)
+	}
+	if l.DecidedLatency != 2 || l.ReceivedLatency != 3 || l.CommittedLatency != 0 {
+		t.Fatalf("The latencies should be 2s, 3s and none, not %v, %v and %v",
+			l.DecidedLatency, l.ReceivedLatency, l.CommittedLatency)
+	}
+}
+
+func TestListPeersHashes(t *testing.T) {
+	cluster := testapp.NewCluster(t, 2)
+	cluster.Run()
+	defer cluster.Shutdown()
+
+	cluster.Clients[0].Set("k1", "v1")
+	cluster.AssertConvergence(t, 0, 10*time.Second)
+	cluster.Clients[0].Set("k2", "v2")
+	cluster.AssertConvergence(t, 1, 10*time.Second)
+
+	s := &Service{
+		node:   cluster.Nodes[0],
+		logger: common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	genesis, err := cluster.Nodes[0].GetValidatorSet(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := peers.NewPeerSet(genesis).Hex()
+
+	rec := httptest.NewRecorder()
+	s.ListPeersHashes(rec, httptest.NewRequest(http.MethodGet, "/validators/hashes?start=0&count=2", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status should be %d, not %d", http.StatusOK, rec.Code)
+	}
+
+	var page BlockPeersHashPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Blocks) != 2 {
+		t.Fatalf("The page should contain 2 blocks, not %d", len(page.Blocks))
+	}
+
+	for i, b := range page.Blocks {
+		if b.Block != i {
+			t.Fatalf("Entry %d should be block %d, not %d", i, i, b.Block)
+		}
+		if b.PeersHash != expected {
+			t.Fatalf("Block %d should commit the genesis validator-set %s, not %s", i, expected, b.PeersHash)
+		}
+		if b.Changed {
+			t.Fatalf("The validator-set should not change in block %d", i)
+		}
+	}
+
+	if page.Blocks[0].PrevPeersHash != "" {
+		t.Fatalf("The first block should not have a previous hash")
+	}
+	if page.Blocks[1].PrevPeersHash != page.Blocks[0].PeersHash {
+		t.Fatalf("Block 1 should be linked to the hash of block 0")
+	}
+}
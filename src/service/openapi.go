@@ -0,0 +1,269 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/version"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification implemented by
+// the document served on /v1/openapi.json.
+const OpenAPIVersion = "3.0.3"
+
+// GetOpenAPI returns the OpenAPI document describing the routes of the
+// service, from which client SDKs can be generated.
+//
+//  GET /v1/openapi.json
+//  returns: JSON OpenAPI 3 document
+func (s *Service) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.openAPIDocument(s.routes()))
+}
+
+// openAPIDocument builds the OpenAPI document of a list of routes. The schemas
+// of the requests and responses are derived from the Go types by reflection.
+func (s *Service) openAPIDocument(routes []route) map[string]interface{} {
+	schemas := newSchemaRegistry()
+
+	paths := make(map[string]interface{})
+
+	for _, rt := range routes {
+		path := rt.path
+		if path == "" {
+			path = rt.pattern
+		}
+		if !rt.unversioned {
+			path = APIPrefix + path
+		}
+
+		item := make(map[string]interface{})
+		for _, op := range rt.operations {
+			item[strings.ToLower(op.method)] = s.openAPIOperation(rt, op, schemas)
+		}
+
+		paths[path] = item
+	}
+
+	return map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "Babble API",
+			"version": version.Version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{
+					"type":   "http",
+					"scheme": "bearer",
+				},
+				"apiKey": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+		},
+	}
+}
+
+func (s *Service) openAPIOperation(rt route, op operation, schemas *schemaRegistry) map[string]interface{} {
+	res := map[string]interface{}{
+		"operationId": op.id,
+		"summary":     op.summary,
+	}
+
+	if rt.role == RoleAdmin || (rt.role == RoleRead && s.readAuth) {
+		res["security"] = []map[string][]string{
+			{"bearer": {}},
+			{"apiKey": {}},
+		}
+	}
+
+	if len(op.params) > 0 {
+		params := []map[string]interface{}{}
+		for _, p := range op.params {
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"required":    p.in == "path",
+				"description": p.description,
+				"schema":      map[string]string{"type": p.typ},
+			})
+		}
+		res["parameters"] = params
+	}
+
+	if op.request != nil {
+		res["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": schemas.schema(reflect.TypeOf(op.request)),
+				},
+			},
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	response := map[string]interface{}{
+		"description": http.StatusText(status),
+	}
+
+	contentType := op.contentType
+	if contentType == "" && op.response != nil {
+		contentType = "application/json"
+	}
+
+	if contentType != "" {
+		schema := map[string]interface{}{"type": "string"}
+		if op.response != nil {
+			schema = schemas.schema(reflect.TypeOf(op.response))
+		}
+		response["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{"schema": schema},
+		}
+	}
+
+	res["responses"] = map[string]interface{}{
+		strconv.Itoa(status): response,
+		"default": map[string]interface{}{
+			"description": "Error, with a plain text message",
+		},
+	}
+
+	return res
+}
+
+// schemaRegistry converts Go types to OpenAPI schemas. Named struct types are
+// registered as components and referenced by name.
+type schemaRegistry struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of a type, following the rules of encoding/json.
+func (sr *schemaRegistry) schema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return sr.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": sr.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": sr.schema(t.Elem()),
+		}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return sr.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + sr.register(t)}
+	default:
+		// interface{} and other types accept any value
+		return map[string]interface{}{}
+	}
+}
+
+// register adds a named struct type to the components, and returns its name.
+// Types with the same name in different packages are prefixed with their
+// package name.
+func (sr *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := sr.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := sr.schemas[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.Title(pkg) + name
+	}
+
+	// Register the name before building the schema, for recursive types
+	sr.names[t] = name
+	sr.schemas[name] = map[string]interface{}{}
+
+	sr.schemas[name] = sr.structSchema(t)
+
+	return name
+}
+
+// structSchema returns the object schema of a struct, with its exported fields
+// named by their json tags. The fields of embedded structs are promoted.
+func (sr *schemaRegistry) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	sr.addFields(t, properties)
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+func (sr *schemaRegistry) addFields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				sr.addFields(ft, properties)
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = sr.schema(f.Type)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	s := &Service{
+		graphql: true,
+		logger:  common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	ids := make(map[string]bool)
+	for _, rt := range s.routes() {
+		if len(rt.operations) == 0 {
+			t.Fatalf("route %s is not documented", rt.pattern)
+		}
+		for _, op := range rt.operations {
+			if ids[op.id] {
+				t.Fatalf("duplicate operation id %s", op.id)
+			}
+			ids[op.id] = true
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.GetOpenAPI(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var doc struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != OpenAPIVersion {
+		t.Fatalf("openapi should be %s, not %s", OpenAPIVersion, doc.OpenAPI)
+	}
+
+	for _, path := range []string{"/v1/stats", "/v1/block/{index}", "/v1/tx", "/v1/graphql", "/healthz"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Fatalf("document should contain %s", path)
+		}
+	}
+
+	if _, ok := doc.Paths["/v1/healthz"]; ok {
+		t.Fatal("probes should not be versioned")
+	}
+
+	if _, ok := doc.Paths["/v1/admin/bans"]["delete"]["security"]; !ok {
+		t.Fatal("admin operations should require credentials")
+	}
+
+	if _, ok := doc.Paths["/v1/stats"]["get"]["security"]; ok {
+		t.Fatal("read operations should be public unless read-auth is set")
+	}
+
+	// Embedded fields are promoted, like encoding/json does
+	round := doc.Components.Schemas["Round"]
+	for _, field := range []string{"index", "CreatedEvents"} {
+		if _, ok := round.Properties[field]; !ok {
+			t.Fatalf("Round schema should contain %s: %v", field, round.Properties)
+		}
+	}
+}
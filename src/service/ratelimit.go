@@ -0,0 +1,160 @@
+package service
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/metrics"
+)
+
+// Labels of the rate limits in the ServiceRateLimited metric.
+const (
+	requestLimit = "request"
+	txLimit      = "tx"
+)
+
+// rateLimiter limits the rate of requests per client IP with token buckets.
+// Each client can make burst requests at once, after which its bucket is
+// refilled at rate requests per second.
+type rateLimiter struct {
+	sync.Mutex
+
+	rate  float64
+	burst float64
+
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a rateLimiter, or nil if rate is not positive, which
+// disables the limit.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow consumes a token from the bucket of the client. If the bucket is
+// empty, it returns false and the time until the next token.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+
+	return true, 0
+}
+
+// sweep removes the buckets that have been idle long enough to be full again,
+// so that the memory used by the limiter does not grow with the number of
+// clients over time.
+func (l *rateLimiter) sweep(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+
+	if now.Sub(l.lastSweep) < refill {
+		return
+	}
+
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+// clientIP returns the IP address of the client of a request. Requests relayed
+// by a reverse proxy all share the address of the proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// SetRateLimits replaces the rate limits of the requests and transactions. A
+// non-positive rate disables the corresponding limit. The clients start again
+// with full buckets.
+func (s *Service) SetRateLimits(rate float64, burst int, txRate float64, txBurst int) {
+	s.limitsLock.Lock()
+	defer s.limitsLock.Unlock()
+
+	s.requestLimiter = newRateLimiter(rate, burst)
+	s.txLimiter = newRateLimiter(txRate, txBurst)
+}
+
+// limiter returns the current rateLimiter of a label, or nil if the limit is
+// disabled.
+func (s *Service) limiter(label string) *rateLimiter {
+	s.limitsLock.RLock()
+	defer s.limitsLock.RUnlock()
+
+	if label == txLimit {
+		return s.txLimiter
+	}
+	return s.requestLimiter
+}
+
+// makeLimitedHandler rejects the requests of clients that exceed the rate
+// limit of the label with 429 Too Many Requests, and caps the size of the
+// request bodies. The limiter is looked up on every request, so that the limits
+// can be changed by SetRateLimits. A nil limiter, or a non-positive maxBytes,
+// disables the corresponding limit.
+func (s *Service) makeLimitedHandler(fn http.HandlerFunc, label string, maxBytes int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if limiter := s.limiter(label); limiter != nil {
+			if ok, wait := limiter.allow(clientIP(r), time.Now()); !ok {
+				metrics.ServiceRateLimited.WithLabelValues(label).Inc()
+
+				s.logger.WithField("remote", r.RemoteAddr).Debug("Rate limited")
+
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		if maxBytes > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		}
+
+		fn(w, r)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if newRateLimiter(0, 10) != nil {
+		t.Fatal("a zero rate should disable the limiter")
+	}
+
+	l := newRateLimiter(2, 3)
+	now := time.Unix(1000, 0)
+
+	// The burst is allowed at once
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+
+	ok, wait := l.allow("a", now)
+	if ok {
+		t.Fatal("request beyond the burst should be rejected")
+	}
+	if wait != 500*time.Millisecond {
+		t.Fatalf("wait should be 500ms, not %v", wait)
+	}
+
+	// Other clients have their own bucket
+	if ok, _ := l.allow("b", now); !ok {
+		t.Fatal("other clients should not be limited")
+	}
+
+	// The bucket is refilled at the rate
+	if ok, _ := l.allow("a", now.Add(500*time.Millisecond)); !ok {
+		t.Fatal("request should be allowed after refill")
+	}
+
+	// Idle buckets are removed once full
+	l.allow("c", now.Add(time.Hour))
+	if len(l.buckets) != 1 {
+		t.Fatalf("idle buckets should be swept, %d remaining", len(l.buckets))
+	}
+}
+
+func TestLimitedHandler(t *testing.T) {
+	s := &Service{
+		requestLimiter: newRateLimiter(1, 1),
+		logger:         common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	h := s.makeLimitedHandler(handler, requestLimit, 10)
+
+	req := httptest.NewRequest(http.MethodPost, "/tx", strings.NewReader("small"))
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status should be %d, not %d", http.StatusOK, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/tx", strings.NewReader("small"))
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status should be %d, not %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("Retry-After should be 1, not %q", rec.Header().Get("Retry-After"))
+	}
+
+	// Without a limiter, only the body size is capped. The limits are looked
+	// up on every request, so the handler is not wrapped again.
+	s.SetRateLimits(0, 0, 0, 0)
+
+	req = httptest.NewRequest(http.MethodPost, "/tx", strings.NewReader("much larger than ten bytes"))
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status should be %d, not %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+}
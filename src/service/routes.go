@@ -0,0 +1,864 @@
+package service
+
+import (
+	"net/http"
+
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// APIPrefix is the path prefix of the current version of the API. A future
+// version with breaking changes would be served under /v2, alongside /v1.
+const APIPrefix = "/v1"
+
+// route is an endpoint of the service. Routes are registered with the
+// DefaultServerMux under APIPrefix, and documented in the OpenAPI document.
+type route struct {
+	// pattern is the ServeMux pattern, relative to APIPrefix.
+	pattern string
+
+	// path is the OpenAPI path template, like /block/{index}. It defaults to
+	// pattern.
+	path string
+
+	// role is the role required by the route. RoleRead is only enforced when
+	// the service requires authentication on the read-only endpoints, and
+	// public routes have RoleNone.
+	role Role
+
+	// locked routes are serialized with the service's lock. Long-running
+	// handlers are not locked, so as not to block the other endpoints.
+	locked bool
+
+	// tx routes submit transactions. They are subject to the transaction rate
+	// limit, and their bodies are capped by the transaction size limit.
+	tx bool
+
+	// unversioned routes are only registered at the root, without APIPrefix.
+	// They are intended for infrastructure, like probes and metrics, which
+	// expect fixed paths.
+	unversioned bool
+
+	handler http.HandlerFunc
+
+	// legacy is the handler of the deprecated alias at the root, for the routes
+	// whose response changed in the versioned API. It defaults to handler.
+	legacy http.HandlerFunc
+
+	operations []operation
+}
+
+// operation documents a method of a route.
+type operation struct {
+	method   string
+	id       string
+	summary  string
+	params   []param
+	request  interface{}
+	response interface{}
+
+	// status is the status of successful responses. It defaults to 200.
+	status int
+
+	// contentType is the media type of successful responses. It defaults to
+	// application/json.
+	contentType string
+}
+
+// param documents a path or query parameter of an operation.
+type param struct {
+	name        string
+	in          string
+	typ         string
+	description string
+}
+
+func pathParam(name, typ, description string) param {
+	return param{name: name, in: "path", typ: typ, description: description}
+}
+
+func queryParam(name, typ, description string) param {
+	return param{name: name, in: "query", typ: typ, description: description}
+}
+
+// routes returns the routes of the service.
+func (s *Service) routes() []route {
+	routes := []route{
+		{
+			pattern: "/stats",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetStats,
+			legacy:  s.GetLegacyStats,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getStats",
+				summary:  "Statistics about the node's internal state",
+				response: node.Stats{},
+			}},
+		},
+		{
+			pattern: "/info",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetInfo,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getInfo",
+				summary:  "Identity, build, features, transports and store backend of the node",
+				response: net.NodeInfo{},
+			}},
+		},
+		{
+			pattern: "/block/",
+			path:    "/block/{index}",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetBlock,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getBlock",
+				summary:  "A block by index",
+				params:   []param{pathParam("index", "integer", "Block index")},
+				response: hg.Block{},
+			}},
+		},
+		{
+			pattern: "/blocks/",
+			path:    "/blocks/{start}",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetBlocks,
+			operations: []operation{{
+				method:  http.MethodGet,
+				id:      "getBlocks",
+				summary: "Consecutive blocks from a start index",
+				params: []param{
+					pathParam("start", "integer", "Index of the first block"),
+					queryParam("count", "integer", "Number of blocks, at most 50"),
+				},
+				response: []*hg.Block{},
+			}},
+		},
+		{
+			pattern: "/blocks",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.ListBlocks,
+			operations: []operation{{
+				method:  http.MethodGet,
+				id:      "listBlocks",
+				summary: "A page of blocks",
+				params: []param{
+					queryParam("start", "integer", "Index of the first block"),
+					queryParam("count", "integer", "Number of blocks, at most 50"),
+				},
+				response: BlockPage{},
+			}},
+		},
+		{
+			pattern: "/rounds",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.ListRounds,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "listRounds",
+				summary:  "A page of hashgraph rounds",
+				params:   []param{queryParam("range", "string", "Inclusive range of rounds, like 10-20 or 10-")},
+				response: RoundPage{},
+			}},
+		},
+		{
+			pattern: "/rounds/latency",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.ListRoundLatencies,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "listRoundLatencies",
+				summary:  "The consensus timelines of a page of rounds",
+				params:   []param{queryParam("range", "string", "Inclusive range of rounds, like 10-20 or 10-")},
+				response: RoundLatencyPage{},
+			}},
+		},
+		{
+			pattern: "/graph",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetGraph,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getGraph",
+				summary:  "The events, rounds and blocks of the hashgraph, for visualisation",
+				response: node.Infos{},
+			}},
+		},
+		{
+			pattern: "/graph/export",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.ExportGraph,
+			operations: []operation{{
+				method:  http.MethodGet,
+				id:      "exportGraph",
+				summary: "A window of the hashgraph as a JSON graph or a Graphviz DOT digraph",
+				params: []param{
+					queryParam("range", "string", "Inclusive range of rounds, like 10-20, at most 20 rounds"),
+					queryParam("format", "string", "json (default) or dot"),
+				},
+				response: node.GraphExport{},
+			}},
+		},
+		{
+			pattern: "/peers",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetPeers,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getPeers",
+				summary:  "The node's current peers",
+				response: []*peers.Peer{},
+			}},
+		},
+		{
+			pattern: "/peers/stats",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetPeerStats,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getPeerStats",
+				summary:  "The connectivity and sync lag of each peer",
+				response: []node.PeerStats{},
+			}},
+		},
+		{
+			pattern: "/genesispeers",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetGenesisPeers,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getGenesisPeers",
+				summary:  "The genesis validator-set",
+				response: []*peers.Peer{},
+			}},
+		},
+		{
+			pattern: "/validators/",
+			path:    "/validators/{round}",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetValidatorSet,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getValidatorSet",
+				summary:  "The validator-set of a round",
+				params:   []param{pathParam("round", "integer", "Round index")},
+				response: []*peers.Peer{},
+			}},
+		},
+		{
+			pattern: "/validators/history",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.ListValidatorSets,
+			operations: []operation{{
+				method:  http.MethodGet,
+				id:      "listValidatorSets",
+				summary: "A page of the validator-set history",
+				params: []param{
+					queryParam("start", "integer", "First round"),
+					queryParam("count", "integer", "Number of validator-sets, at most 50"),
+				},
+				response: ValidatorSetPage{},
+			}},
+		},
+		{
+			pattern: "/validators/hashes",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.ListPeersHashes,
+			operations: []operation{{
+				method:  http.MethodGet,
+				id:      "listPeersHashes",
+				summary: "A page of the validator-set hashes committed in the blocks",
+				params: []param{
+					queryParam("start", "integer", "Index of the first block"),
+					queryParam("count", "integer", "Number of blocks, at most 200"),
+				},
+				response: BlockPeersHashPage{},
+			}},
+		},
+		{
+			pattern: "/validators/changes",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.ListMembershipChanges,
+			operations: []operation{{
+				method:  http.MethodGet,
+				id:      "listMembershipChanges",
+				summary: "A page of the history of membership changes, with the signers of the accepting blocks",
+				params: []param{
+					queryParam("start", "integer", "Position of the first change"),
+					queryParam("count", "integer", "Number of changes, at most 50"),
+				},
+				response: MembershipChangePage{},
+			}},
+		},
+		{
+			pattern: "/evidence",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.ListEvidence,
+			operations: []operation{{
+				method:  http.MethodGet,
+				id:      "listEvidence",
+				summary: "A page of the signed proofs of misbehaviour collected by the node",
+				params: []param{
+					queryParam("start", "integer", "Position of the first proof"),
+					queryParam("count", "integer", "Number of proofs, at most 50"),
+				},
+				response: EvidencePage{},
+			}},
+		},
+		{
+			pattern: "/history",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetAllValidatorSets,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getAllValidatorSets",
+				summary:  "The entire validator-set history, by round",
+				response: map[string][]*peers.Peer{},
+			}},
+		},
+		{
+			pattern: "/ws",
+			role:    RoleRead,
+			handler: s.Subscribe,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "subscribe",
+				summary:  "Upgrade to a WebSocket streaming node notifications",
+				params:   []param{queryParam("subscribe", "string", "Comma-separated notification types: block, tx, peers, state")},
+				response: node.Notification{},
+				status:   http.StatusSwitchingProtocols,
+			}},
+		},
+		{
+			pattern: "/tx",
+			role:    RoleRead,
+			locked:  true,
+			tx:      true,
+			handler: s.SubmitTx,
+			operations: []operation{{
+				method:   http.MethodPost,
+				id:       "submitTx",
+				summary:  "Submit a transaction",
+				params:   []param{queryParam("recipients", "string", "Comma-separated public keys of the validators that can decrypt the transaction")},
+				request:  TxRequest{},
+				response: TxResponse{},
+				status:   http.StatusAccepted,
+			}},
+		},
+		{
+			// /tx/sync waits for consensus
+			pattern: "/tx/sync",
+			role:    RoleRead,
+			tx:      true,
+			handler: s.SubmitTxSync,
+			operations: []operation{{
+				method:   http.MethodPost,
+				id:       "submitTxSync",
+				summary:  "Submit a transaction and wait until it is committed",
+				params:   []param{queryParam("timeout", "string", "Maximum wait, like 5s, at most 30s"), queryParam("recipients", "string", "Comma-separated public keys of the validators that can decrypt the transaction")},
+				request:  TxRequest{},
+				response: TxResponse{},
+			}},
+		},
+		{
+			pattern: "/tx/",
+			path:    "/tx/{hash}",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GetTx,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getTx",
+				summary:  "The location of a committed transaction",
+				params:   []param{pathParam("hash", "string", "Hex encoded SHA256 of the transaction")},
+				response: TxResponse{},
+			}},
+		},
+		{
+			pattern: "/openapi.json",
+			role:    RoleRead,
+			handler: s.GetOpenAPI,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getOpenAPI",
+				summary:  "This OpenAPI document",
+				response: map[string]interface{}{},
+			}},
+		},
+		{
+			pattern:     "/dashboard",
+			unversioned: true,
+			handler:     s.GetDashboard,
+			operations: []operation{{
+				method:      http.MethodGet,
+				id:          "getDashboard",
+				summary:     "The web dashboard of the node",
+				contentType: "text/html",
+			}},
+		},
+	}
+
+	if s.graphql {
+		routes = append(routes, route{
+			pattern: "/graphql",
+			role:    RoleRead,
+			locked:  true,
+			handler: s.GraphQL,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "getGraphQL",
+					summary:  "Execute a GraphQL query",
+					params:   []param{queryParam("query", "string", "GraphQL query")},
+					response: map[string]interface{}{},
+				},
+				{
+					method:   http.MethodPost,
+					id:       "postGraphQL",
+					summary:  "Execute a GraphQL query",
+					request:  GraphQLRequest{},
+					response: map[string]interface{}{},
+				},
+			},
+		})
+	}
+
+	routes = append(routes, s.adminRoutes()...)
+	routes = append(routes, s.debugRoutes()...)
+	routes = append(routes, s.probeRoutes()...)
+
+	return routes
+}
+
+// adminRoutes returns the routes that control the node at runtime.
+// /admin/leave, /admin/upgrade, /admin/moniker and /admin/address are not
+// locked because they wait for consensus.
+func (s *Service) adminRoutes() []route {
+	return []route{
+		{
+			pattern: "/admin/loglevel",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.LogLevel,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "getLogLevels",
+					summary:  "The log levels",
+					response: LogLevels{},
+				},
+				{
+					method:   http.MethodPut,
+					id:       "setLogLevel",
+					summary:  "Change the log level of a module",
+					request:  LogLevelRequest{},
+					response: LogLevels{},
+				},
+			},
+		},
+		{
+			pattern: "/admin/logrotate",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.RotateLogs,
+			operations: []operation{{
+				method:   http.MethodPost,
+				id:       "rotateLogs",
+				summary:  "Rotate the log file",
+				response: LogRotation{},
+			}},
+		},
+		{
+			pattern: "/admin/reload",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.Reload,
+			operations: []operation{{
+				method:   http.MethodPost,
+				id:       "reloadConfig",
+				summary:  "Reload the options that do not require a restart",
+				response: ConfigReload{},
+			}},
+		},
+		{
+			pattern: "/admin/leave",
+			role:    RoleAdmin,
+			handler: s.Leave,
+			operations: []operation{{
+				method:   http.MethodPost,
+				id:       "leave",
+				summary:  "Leave the network and shut down",
+				response: NodeState{},
+				status:   http.StatusAccepted,
+			}},
+		},
+		{
+			pattern: "/admin/upgrade",
+			role:    RoleAdmin,
+			handler: s.Upgrade,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "getUpgrades",
+					summary:  "The upgrades of the protocol and the pending signals",
+					response: node.UpgradeStatus{},
+				},
+				{
+					method:   http.MethodPost,
+					id:       "signalUpgrade",
+					summary:  "Signal that the node is ready for a version of the protocol",
+					request:  UpgradeRequest{},
+					response: node.UpgradeStatus{},
+				},
+			},
+		},
+		{
+			pattern: "/admin/moniker",
+			role:    RoleAdmin,
+			handler: s.ChangeMoniker,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "getMoniker",
+					summary:  "The moniker of the validator",
+					response: Moniker{},
+				},
+				{
+					method:   http.MethodPost,
+					id:       "changeMoniker",
+					summary:  "Change the moniker of the validator",
+					request:  Moniker{},
+					response: Moniker{},
+				},
+			},
+		},
+		{
+			pattern: "/admin/address",
+			role:    RoleAdmin,
+			handler: s.ChangeAddress,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "getAddress",
+					summary:  "The address of the validator in the validator-set",
+					response: Address{},
+				},
+				{
+					method:   http.MethodPost,
+					id:       "changeAddress",
+					summary:  "Announce a new address of the validator",
+					request:  Address{},
+					response: Address{},
+				},
+			},
+		},
+		{
+			pattern: "/admin/resume",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.Resume,
+			operations: []operation{{
+				method:   http.MethodPost,
+				id:       "resume",
+				summary:  "Resume from the Suspended state",
+				response: NodeState{},
+			}},
+		},
+		{
+			pattern: "/admin/suspend",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.Suspend,
+			operations: []operation{{
+				method:   http.MethodPost,
+				id:       "suspend",
+				summary:  "Suspend the node until it is resumed",
+				response: NodeState{},
+			}},
+		},
+		{
+			pattern: "/admin/fastforward",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.FastForward,
+			operations: []operation{{
+				method:   http.MethodPost,
+				id:       "fastForward",
+				summary:  "Fast-forward to the tip of the hashgraph",
+				response: NodeState{},
+				status:   http.StatusAccepted,
+			}},
+		},
+		{
+			pattern: "/admin/bans",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.Bans,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "listBans",
+					summary:  "The banned peer addresses",
+					response: BanList{},
+				},
+				{
+					method:   http.MethodPost,
+					id:       "banPeer",
+					summary:  "Ban a peer address",
+					request:  BanRequest{},
+					response: BanList{},
+				},
+				{
+					method:   http.MethodDelete,
+					id:       "unbanPeer",
+					summary:  "Lift the ban on a peer address",
+					params:   []param{queryParam("addr", "string", "Banned address")},
+					response: BanList{},
+				},
+			},
+		},
+		{
+			pattern: "/admin/pins",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.Pins,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "listPins",
+					summary:  "The pinned blocks",
+					response: PinList{},
+				},
+				{
+					method:   http.MethodPost,
+					id:       "pinBlock",
+					summary:  "Protect a block from being evicted from the store",
+					request:  PinRequest{},
+					response: PinList{},
+				},
+				{
+					method:   http.MethodDelete,
+					id:       "unpinBlock",
+					summary:  "Lift the protection of a pinned block",
+					params:   []param{queryParam("block", "integer", "Pinned block index")},
+					response: PinList{},
+				},
+			},
+		},
+		{
+			pattern: "/admin/watermark",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.AppWatermark,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "getAppWatermark",
+					summary:  "The last block acknowledged by the App",
+					response: Watermark{},
+				},
+				{
+					method:   http.MethodPost,
+					id:       "advanceAppWatermark",
+					summary:  "Acknowledge the blocks up to an index, so that they are not delivered again after a restart",
+					request:  Watermark{},
+					response: Watermark{},
+				},
+			},
+		},
+		{
+			pattern: "/admin/filter",
+			role:    RoleAdmin,
+			locked:  true,
+			handler: s.Filter,
+			operations: []operation{
+				{
+					method:   http.MethodGet,
+					id:       "getFilter",
+					summary:  "The allowlists and blocklists of the transport",
+					response: net.FilterRules{},
+				},
+				{
+					method:   http.MethodPost,
+					id:       "addFilterEntry",
+					summary:  "Add an IP address, CIDR range, or public key to a list",
+					request:  FilterRequest{},
+					response: net.FilterRules{},
+				},
+				{
+					method:  http.MethodDelete,
+					id:      "removeFilterEntry",
+					summary: "Remove an entry from a list",
+					params: []param{
+						queryParam("list", "string", "allow-ips, block-ips, allow-pubkeys, or block-pubkeys"),
+						queryParam("entry", "string", "IP address, CIDR range, or public key"),
+					},
+					response: net.FilterRules{},
+				},
+			},
+		},
+	}
+}
+
+// debugRoutes returns the runtime diagnostics routes. They are not locked
+// because CPU profiles and traces can run for a long time.
+func (s *Service) debugRoutes() []route {
+	return []route{
+		{
+			pattern: "/debug/pprof/",
+			path:    "/debug/pprof/{profile}",
+			role:    RoleAdmin,
+			handler: s.GetProfile,
+			operations: []operation{{
+				method:  http.MethodGet,
+				id:      "getProfile",
+				summary: "A runtime profile, in pprof format",
+				params: []param{
+					pathParam("profile", "string", "Profile name, like heap, goroutine, profile or trace"),
+					queryParam("seconds", "number", "Duration of CPU profiles and traces"),
+					queryParam("debug", "integer", "Text format if greater than 0"),
+				},
+				contentType: "application/octet-stream",
+			}},
+		},
+		{
+			pattern: "/debug/goroutines",
+			role:    RoleAdmin,
+			handler: s.GetGoroutines,
+			operations: []operation{{
+				method:      http.MethodGet,
+				id:          "getGoroutines",
+				summary:     "A dump of all the goroutines",
+				contentType: "text/plain",
+			}},
+		},
+		{
+			pattern: "/debug/gc",
+			role:    RoleAdmin,
+			handler: s.GetGCStats,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getGCStats",
+				summary:  "Memory and garbage collection statistics",
+				response: GCStats{},
+			}},
+		},
+	}
+}
+
+// probeRoutes returns the liveness and readiness probes, intended for
+// Kubernetes and load balancers, and the Prometheus metrics. They are public,
+// unversioned, and not locked so that probes are not delayed by slower
+// requests to the other endpoints.
+func (s *Service) probeRoutes() []route {
+	return []route{
+		{
+			pattern:     "/healthz",
+			unversioned: true,
+			handler:     s.GetHealth,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getHealth",
+				summary:  "Liveness probe",
+				response: map[string]string{},
+			}},
+		},
+		{
+			pattern:     "/readyz",
+			unversioned: true,
+			handler:     s.GetReadiness,
+			operations: []operation{{
+				method:   http.MethodGet,
+				id:       "getReadiness",
+				summary:  "Readiness probe; 503 if the node is not ready",
+				response: Readiness{},
+			}},
+		},
+		{
+			pattern:     "/metrics",
+			unversioned: true,
+			handler:     promhttp.Handler().ServeHTTP,
+			operations: []operation{{
+				method:      http.MethodGet,
+				id:          "getMetrics",
+				summary:     "Prometheus metrics",
+				contentType: "text/plain",
+			}},
+		},
+	}
+}
+
+// registerRoutes registers the routes with the DefaultServerMux of the http
+// package, under APIPrefix. The versioned routes are also registered at the
+// root, without the prefix, for the clients of the original API; these
+// aliases are deprecated.
+func (s *Service) registerRoutes(routes []route) {
+	for _, rt := range routes {
+		handler := s.wrap(rt)
+
+		if !rt.unversioned {
+			// The handlers parse their path without the prefix
+			http.Handle(APIPrefix+rt.pattern, http.StripPrefix(APIPrefix, handler))
+		}
+
+		if rt.legacy != nil {
+			alias := rt
+			alias.handler = rt.legacy
+			handler = s.wrap(alias)
+		}
+
+		http.HandleFunc(rt.pattern, handler)
+	}
+}
+
+// wrap applies the rate limits, the access control and the locking policy of
+// a route to its handler. Public routes are not limited.
+func (s *Service) wrap(rt route) http.HandlerFunc {
+	fn := rt.handler
+
+	if rt.locked {
+		fn = s.makeLockedHandler(fn)
+	}
+
+	switch rt.role {
+	case RoleAdmin:
+		fn = s.makeAdminHandler(fn)
+	case RoleRead:
+		fn = s.makeUnlockedHandler(fn)
+	default:
+		return fn
+	}
+
+	maxBytes := s.maxBodyBytes
+
+	if rt.tx {
+		// The transaction size limit is enforced by readTx
+		fn = s.makeLimitedHandler(fn, txLimit, 0)
+		maxBytes = 0
+	}
+
+	return s.makeLimitedHandler(fn, requestLimit, maxBytes)
+}
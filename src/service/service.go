@@ -6,40 +6,110 @@ import (
 	"strconv"
 	"sync"
 
+	"github.com/graphql-go/graphql"
+	"github.com/mosaicnetworks/babble/src/config"
 	hg "github.com/mosaicnetworks/babble/src/hashgraph"
 
+	"github.com/mosaicnetworks/babble/src/logging"
 	"github.com/mosaicnetworks/babble/src/node"
 	"github.com/mosaicnetworks/babble/src/peers"
+	"github.com/mosaicnetworks/babble/src/proxy"
 	"github.com/sirupsen/logrus"
 )
 
 // MAXBLOCKS is the maximum number of blocks returned by the /blocks/ endpoint
 const MAXBLOCKS = 50
 
+// MAXGRAPHROUNDS is the maximum number of rounds exported by the
+// /graph/export endpoint
+const MAXGRAPHROUNDS = 20
+
 // Service is the object that serves the HTTP Service API.
 type Service struct {
 	sync.Mutex
 
 	bindAddress string
-	node        *node.Node
-	graph       *node.Graph
-	logger      *logrus.Entry
+	tlsCertFile string
+	tlsKeyFile  string
+
+	adminToken  string
+	apiKeys     map[string]Role
+	jwtSecret   string
+	readAuth    bool
+	corsOrigins []string
+
+	limitsLock     sync.RWMutex
+	requestLimiter *rateLimiter
+	txLimiter      *rateLimiter
+	maxBodyBytes   int64
+	maxTxBytes     int64
+
+	txAuthorizer proxy.TxAuthorizer
+	idempotency  *proxy.IdempotencyCache
+
+	readyMaxEventLag int
+	readyMaxRoundLag int
+
+	graphql       bool
+	graphqlSchema graphql.Schema
+
+	reloader func() (ConfigReload, error)
+
+	node    *node.Node
+	graph   *node.Graph
+	logging *logging.Registry
+	logger  *logrus.Entry
 }
 
-// NewService instantiates a Service linked to a Babble node and a bind address.
-func NewService(bindAddress string, n *node.Node, logger *logrus.Entry) *Service {
+// NewService instantiates a Service linked to a Babble node. The bind address,
+// the TLS certificate, the credentials, the CORS origins, the rate limits, the
+// readiness thresholds, and the loggers are taken from the configuration.
+func NewService(conf *config.Config, n *node.Node) *Service {
 	service := Service{
-		bindAddress: bindAddress,
-		node:        n,
-		graph:       node.NewGraph(n),
-		logger:      logger,
+		bindAddress:      conf.ServiceAddr,
+		tlsCertFile:      conf.ServiceTLSCert,
+		tlsKeyFile:       conf.ServiceTLSKey,
+		adminToken:       conf.AdminToken,
+		jwtSecret:        conf.ServiceJWTSecret,
+		readAuth:         conf.ServiceReadAuth,
+		corsOrigins:      ParseCORSOrigins(conf.ServiceCORSOrigins),
+		requestLimiter:   newRateLimiter(conf.ServiceRateLimit, conf.ServiceRateBurst),
+		txLimiter:        newRateLimiter(conf.ServiceTxRateLimit, conf.ServiceTxRateBurst),
+		maxBodyBytes:     conf.ServiceMaxBodyBytes,
+		maxTxBytes:       conf.ServiceMaxTxBytes,
+		readyMaxEventLag: conf.ReadyMaxEventLag,
+		readyMaxRoundLag: conf.ReadyMaxRoundLag,
+		graphql:          conf.GraphQL,
+		node:             n,
+		graph:            node.NewGraph(n),
+		logging:          conf.Logging(),
+		logger:           conf.ModuleLogger("service"),
 	}
 
+	apiKeys, err := ParseAPIKeys(conf.ServiceAPIKeys)
+	if err != nil {
+		service.logger.WithError(err).Error("Parsing API keys")
+	}
+	service.apiKeys = apiKeys
+
 	service.registerHandlers()
 
 	return &service
 }
 
+// SetTxAuthorizer sets the authorizer of the transactions submitted through the
+// /tx endpoints. All the transactions are accepted if it is nil.
+func (s *Service) SetTxAuthorizer(a proxy.TxAuthorizer) {
+	s.txAuthorizer = a
+}
+
+// SetIdempotencyCache sets the cache of the idempotency keys presented with
+// the transactions submitted through the /tx endpoints. Without it, the keys
+// are ignored.
+func (s *Service) SetIdempotencyCache(c *proxy.IdempotencyCache) {
+	s.idempotency = c
+}
+
 // registerHandlers registers the API handlers with the DefaultServerMux of the
 // http package. It is possible that another server in the same process is
 // simultaneously using the DefaultServerMux. In which case, the handlers will
@@ -48,45 +118,94 @@ func NewService(bindAddress string, n *node.Node, logger *logrus.Entry) *Service
 // application's API.
 func (s *Service) registerHandlers() {
 	s.logger.Debug("Registering Babble API handlers")
-	http.HandleFunc("/stats", s.makeHandler(s.GetStats))
-	http.HandleFunc("/block/", s.makeHandler(s.GetBlock))
-	http.HandleFunc("/blocks/", s.makeHandler(s.GetBlocks))
-	http.HandleFunc("/graph", s.makeHandler(s.GetGraph))
-	http.HandleFunc("/peers", s.makeHandler(s.GetPeers))
-	http.HandleFunc("/genesispeers", s.makeHandler(s.GetGenesisPeers))
-	http.HandleFunc("/validators/", s.makeHandler(s.GetValidatorSet))
-	http.HandleFunc("/history", s.makeHandler(s.GetAllValidatorSets))
+
+	if s.graphql {
+		schema, err := s.newGraphQLSchema()
+		if err != nil {
+			s.logger.WithError(err).Error("Building GraphQL schema")
+			s.graphql = false
+		}
+		s.graphqlSchema = schema
+	}
+
+	s.registerRoutes(s.routes())
 }
 
+// makeHandler wraps a handler with CORS and access control, and serializes
+// the requests with the service's lock.
 func (s *Service) makeHandler(fn func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return s.makeUnlockedHandler(s.makeLockedHandler(fn))
+}
+
+// makeLockedHandler serializes the requests to a handler with the service's
+// lock.
+func (s *Service) makeLockedHandler(fn func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		s.Lock()
 		defer s.Unlock()
 
-		// enable CORS
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		fn(w, r)
+	}
+}
+
+// makeUnlockedHandler wraps a handler with CORS and access control, but does
+// not take the service's lock. It is used by long-running handlers which must
+// not block the other endpoints.
+func (s *Service) makeUnlockedHandler(fn func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.setCORSHeaders(w, r) {
+			return
+		}
+
+		if s.readAuth && !s.authorize(w, r, RoleRead) {
+			return
+		}
 
 		fn(w, r)
 	}
 }
 
-// Serve calls ListenAndServe. This is a blocking call. It is not necessary to
-// call Serve when Babble is used in-memory and another server has already been
-// started with the DefaultServerMux and the same address:port combination.
-// Indeed, the service constructor has already registered the API handlers with
+// Serve calls ListenAndServe, or ListenAndServeTLS if a TLS certificate is
+// configured. This is a blocking call. It is not necessary to call Serve when
+// Babble is used in-memory and another server has already been started with
+// the DefaultServerMux and the same address:port combination. Indeed, the
+// service constructor has already registered the API handlers with
 // DefaultServerMux.
 func (s *Service) Serve() {
-	s.logger.WithField("bind_address", s.bindAddress).Debug("Serving Babble API")
+	s.logger.WithFields(logrus.Fields{
+		"bind_address": s.bindAddress,
+		"tls":          s.tlsCertFile != "",
+	}).Debug("Serving Babble API")
+
+	var err error
 
 	// Use the DefaultServerMux
-	err := http.ListenAndServe(s.bindAddress, nil)
+	if s.tlsCertFile != "" {
+		err = http.ListenAndServeTLS(s.bindAddress, s.tlsCertFile, s.tlsKeyFile, nil)
+	} else {
+		err = http.ListenAndServe(s.bindAddress, nil)
+	}
+
 	if err != nil {
 		s.logger.Error(err)
 	}
 }
 
-// GetStats returns a list of stats about the node's internal state.
+// GetStats returns stats about the node's internal state.
+//
+//  GET /stats
+//  returns: JSON node.Stats
 func (s *Service) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats := s.node.Stats()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetLegacyStats returns the stats of the node with string values, for the
+// clients of the deprecated /stats alias.
+func (s *Service) GetLegacyStats(w http.ResponseWriter, r *http.Request) {
 	stats := s.node.GetStats()
 
 	w.Header().Set("Content-Type", "application/json")
@@ -94,6 +213,15 @@ func (s *Service) GetStats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(stats)
 }
 
+// GetInfo returns the description of the node.
+func (s *Service) GetInfo(w http.ResponseWriter, r *http.Request) {
+	info := s.node.GetInfo()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(info)
+}
+
 // GetBlock returns a single Block by block index.
 func (s *Service) GetBlock(w http.ResponseWriter, r *http.Request) {
 	param := r.URL.Path[len("/block/"):]
@@ -201,6 +329,57 @@ func (s *Service) GetGraph(w http.ResponseWriter, r *http.Request) {
 	encoder.Encode(res)
 }
 
+// ExportGraph returns a window of the hashgraph, with the events created in a
+// range of rounds, their parent edges, and their round, witness and fame
+// annotations. The range parameter is inclusive, and defaults to the last
+// MAXGRAPHROUNDS rounds, which is also the maximum size of the window. The
+// format parameter selects a JSON graph, usable by D3, or a Graphviz DOT
+// digraph.
+//
+//  GET /graph/export?range={start}-{end}&format={json|dot}
+//  example: /graph/export?range=10-20&format=dot
+//  returns: JSON node.GraphExport, or DOT text
+func (s *Service) ExportGraph(w http.ResponseWriter, r *http.Request) {
+	var start, count int
+	var err error
+
+	if param := r.URL.Query().Get("range"); param != "" {
+		start, count, err = parseRange(param, MAXGRAPHROUNDS)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		start = s.node.GetLastRound() - MAXGRAPHROUNDS + 1
+		if start < 0 {
+			start = 0
+		}
+		count = MAXGRAPHROUNDS
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "dot" {
+		http.Error(w, "format must be json or dot", http.StatusBadRequest)
+		return
+	}
+
+	export, err := s.graph.Export(start, start+count-1)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Exporting graph")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "dot" {
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		export.WriteDOT(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(export)
+}
+
 // GetPeers returns the node's current peers, which is not necessarily
 // equivalent to the current validator-set.
 //
@@ -210,6 +389,18 @@ func (s *Service) GetPeers(w http.ResponseWriter, r *http.Request) {
 	returnPeerSet(w, r, s.node.GetPeers())
 }
 
+// GetPeerStats returns, for each of the node's current peers, the time of the
+// last successful gossip, the round-trip time, the number of events exchanged,
+// the number of failures, and the last-known heights of the peer.
+//
+//  GET /peers/stats
+//  returns: JSON []node.PeerStats
+func (s *Service) GetPeerStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(s.node.GetPeerStats())
+}
+
 // GetGenesisPeers returns the genesis validator-set
 //
 //  Get /genesispeers
@@ -0,0 +1,386 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/envelope"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/metrics"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/proxy"
+)
+
+const (
+	// MAXTXBYTES is the default maximum size of a request body on the /tx
+	// endpoints
+	MAXTXBYTES = 1 << 20
+
+	// txSyncTimeout is the default, and maximum, time that /tx/sync waits for
+	// a transaction to be committed.
+	txSyncTimeout = 30 * time.Second
+
+	// txSyncBufferSize is the number of consensus transactions buffered while
+	// /tx/sync waits for its transaction.
+	txSyncBufferSize = 10000
+
+	// idempotencyKeyHeader is the header carrying the idempotency key of a
+	// transaction submitted on the /tx endpoints.
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// TxRequest is the JSON body accepted by the /tx endpoints. In JSON, the
+// transaction is encoded in base64.
+type TxRequest struct {
+	Tx []byte `json:"tx"`
+}
+
+// TxResponse describes a submitted transaction. Block and Index are only set
+// once the transaction is committed.
+type TxResponse struct {
+	Hash  string `json:"hash"`
+	Block *int   `json:"block,omitempty"`
+	Index *int   `json:"index,omitempty"`
+}
+
+// SubmitTx submits a transaction to Babble, exactly as if it came from the
+// App. The body is the raw transaction, with Content-Type
+// application/octet-stream, the base64 encoding of the transaction, with
+// Content-Type text/plain, or a JSON TxRequest, with Content-Type
+// application/json. The optional recipients parameter is a comma-separated
+// list of validator public keys; the transaction is then sealed in an
+// envelope that only they can open, and the hash is that of the envelope. The
+// optional ttl and ttl_rounds parameters set a time-to-live, as a duration
+// and as a number of rounds; the transaction is evicted, and reported in an
+// "expired" notification, if it is not included in an Event in time. A
+// transaction presented with the Idempotency-Key header of a transaction
+// submitted within the idempotency-window is not submitted again; the response
+// is then 200 OK, with the hash of the original transaction, and its location
+// if it is committed.
+//
+//  POST /tx?recipients={x}&ttl={y}&ttl_rounds={z}
+//  example: /tx?ttl=30s
+//  returns: 202 Accepted, JSON TxResponse with the transaction hash
+func (s *Service) SubmitTx(w http.ResponseWriter, r *http.Request) {
+	ttl, err := parseTxTTL(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, ok := s.readTx(w, r)
+	if !ok {
+		return
+	}
+
+	key := r.Header.Get(idempotencyKeyHeader)
+
+	hash, duplicate := s.idempotency.Reserve(key, tx)
+	if duplicate {
+		metrics.TxDuplicates.WithLabelValues("service").Inc()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.txResponse(hash))
+		return
+	}
+
+	if err := s.node.SubmitTxWithTTL(tx, ttl); err != nil {
+		s.idempotency.Release(key)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(TxResponse{Hash: hash})
+}
+
+// SubmitTxSync submits a transaction like SubmitTx, and waits until it is
+// committed in a block. Transactions are identified by their content, so two
+// identical transactions are indistinguishable. The optional timeout parameter
+// is capped at 30 seconds; the response status is 504 if it expires first. The
+// response status is 410 if the TTL of the transaction expires before it is
+// included in an Event. A transaction presented with the Idempotency-Key of a
+// previous transaction is not submitted again, and the response is that of
+// the original transaction, waiting for its commit if necessary.
+//
+//  POST /tx/sync?timeout={x}&recipients={y}&ttl={z}&ttl_rounds={w}
+//  example: /tx/sync?timeout=5s
+//  returns: JSON TxResponse
+func (s *Service) SubmitTxSync(w http.ResponseWriter, r *http.Request) {
+	timeout := txSyncTimeout
+	if qt := r.URL.Query().Get("timeout"); qt != "" {
+		d, err := time.ParseDuration(qt)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid timeout parameter %q", qt), http.StatusBadRequest)
+			return
+		}
+		if d < timeout {
+			timeout = d
+		}
+	}
+
+	ttl, err := parseTxTTL(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, ok := s.readTx(w, r)
+	if !ok {
+		return
+	}
+
+	key := r.Header.Get(idempotencyKeyHeader)
+
+	hash, duplicate := s.idempotency.Reserve(key, tx)
+
+	// Subscribe before submitting, so as not to miss the commit
+	sub := s.node.Subscribe(txSyncBufferSize, node.TransactionNotification, node.ExpiredNotification)
+	defer sub.Unsubscribe()
+
+	// The original transaction of a duplicate is only known by its hash
+	match := func(data []byte) bool { return bytes.Equal(data, tx) }
+
+	if duplicate {
+		metrics.TxDuplicates.WithLabelValues("service").Inc()
+
+		if resp := s.txResponse(hash); resp.Block != nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		match = func(data []byte) bool { return hg.TxHash(data) == hash }
+	} else if err := s.node.SubmitTxWithTTL(tx, ttl); err != nil {
+		s.idempotency.Release(key)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case note, ok := <-sub.C():
+			if !ok {
+				http.Error(w, "subscription closed", http.StatusServiceUnavailable)
+				return
+			}
+
+			if note.Type == node.ExpiredNotification {
+				if note.Expired.Hash == hash {
+					http.Error(w, "transaction expired", http.StatusGone)
+					return
+				}
+				continue
+			}
+
+			if !match(note.Transaction.Data) {
+				continue
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(TxResponse{
+				Hash:  hash,
+				Block: &note.Transaction.Block,
+				Index: &note.Transaction.Index,
+			})
+
+			return
+		case <-timer.C:
+			http.Error(w, "timed out waiting for commit", http.StatusGatewayTimeout)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// GetTx returns the location of a committed transaction by hash. The hash is
+// the hex encoded SHA256 of the transaction, with or without the 0X prefix.
+// The response status is 404 if the transaction is not committed, or if it is
+// too old to be in the node's store.
+//
+//  GET /tx/{hash}
+//  returns: JSON TxResponse
+func (s *Service) GetTx(w http.ResponseWriter, r *http.Request) {
+	hash := strings.ToUpper(r.URL.Path[len("/tx/"):])
+	if !strings.HasPrefix(hash, "0X") {
+		hash = "0X" + hash
+	}
+
+	loc, err := s.node.GetTx(hash)
+	if err != nil {
+		if common.IsStore(err, common.KeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		s.logger.WithError(err).Errorf("Retrieving tx %s", hash)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TxResponse{
+		Hash:  hash,
+		Block: &loc.Block,
+		Index: &loc.Index,
+	})
+}
+
+// txResponse returns the TxResponse of a transaction, with its location if it
+// is committed.
+func (s *Service) txResponse(hash string) TxResponse {
+	resp := TxResponse{Hash: hash}
+
+	if loc, err := s.node.GetTx(hash); err == nil {
+		resp.Block = &loc.Block
+		resp.Index = &loc.Index
+	}
+
+	return resp
+}
+
+// readTx reads and decodes the transaction in the body of a POST request. It
+// writes the error response and returns false if the request is invalid.
+func (s *Service) readTx(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	maxBytes := s.maxTxBytes
+	if maxBytes <= 0 {
+		maxBytes = MAXTXBYTES
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+
+	tx, err := decodeTx(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if len(tx) == 0 {
+		http.Error(w, "empty transaction", http.StatusBadRequest)
+		return nil, false
+	}
+
+	err = proxy.AuthorizeTx(s.txAuthorizer, tx, credential(r), "service")
+	if err != nil {
+		s.logger.WithError(err).WithField("remote", r.RemoteAddr).Debug("Unauthorized transaction")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil, false
+	}
+
+	if qr := r.URL.Query().Get("recipients"); qr != "" {
+		recipients, err := parseRecipients(qr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+
+		tx, err = envelope.Seal(tx, recipients)
+		if err != nil {
+			s.logger.WithError(err).Error("Sealing transaction")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return nil, false
+		}
+	}
+
+	return tx, true
+}
+
+// parseTxTTL parses the optional ttl and ttl_rounds query parameters.
+func parseTxTTL(query url.Values) (node.TxTTL, error) {
+	var ttl node.TxTTL
+
+	if qt := query.Get("ttl"); qt != "" {
+		d, err := time.ParseDuration(qt)
+		if err != nil || d <= 0 {
+			return ttl, fmt.Errorf("invalid ttl parameter %q", qt)
+		}
+		ttl.Duration = d
+	}
+
+	if qr := query.Get("ttl_rounds"); qr != "" {
+		rounds, err := strconv.Atoi(qr)
+		if err != nil || rounds <= 0 {
+			return ttl, fmt.Errorf("invalid ttl_rounds parameter %q", qr)
+		}
+		ttl.Rounds = rounds
+	}
+
+	return ttl, nil
+}
+
+// parseRecipients parses a comma-separated list of hex encoded public keys, with
+// the 0X prefix.
+func parseRecipients(list string) ([]*ecdsa.PublicKey, error) {
+	recipients := []*ecdsa.PublicKey{}
+
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		pubBytes, err := common.DecodeFromString(strings.ToUpper(s))
+		if err != nil || !strings.HasPrefix(strings.ToUpper(s), "0X") {
+			return nil, fmt.Errorf("invalid recipient %q", s)
+		}
+
+		pub := keys.ToPublicKey(pubBytes)
+		if pub == nil || pub.X == nil || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("invalid recipient %q", s)
+		}
+
+		recipients = append(recipients, pub)
+	}
+
+	return recipients, nil
+}
+
+// decodeTx decodes a transaction according to the Content-Type of the request.
+func decodeTx(contentType string, body []byte) ([]byte, error) {
+	mediaType := ""
+	if contentType != "" {
+		mt, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, err
+		}
+		mediaType = mt
+	}
+
+	switch mediaType {
+	case "application/json":
+		var req TxRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return req.Tx, nil
+	case "text/plain":
+		return base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+	default:
+		return body, nil
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/mosaicnetworks/babble/src/testapp"
+)
+
+func TestDecodeTx(t *testing.T) {
+	cases := []struct {
+		contentType string
+		body        string
+		expected    string
+		err         bool
+	}{
+		{contentType: "", body: "raw tx", expected: "raw tx"},
+		{contentType: "application/octet-stream", body: "raw tx", expected: "raw tx"},
+		{contentType: "text/plain; charset=utf-8", body: "cmF3IHR4\n", expected: "raw tx"},
+		{contentType: "application/json", body: `{"tx":"cmF3IHR4"}`, expected: "raw tx"},
+		{contentType: "text/plain", body: "not base64!", err: true},
+		{contentType: "application/json", body: `{"tx":`, err: true},
+	}
+
+	for i, c := range cases {
+		tx, err := decodeTx(c.contentType, []byte(c.body))
+		if c.err {
+			if err == nil {
+				t.Fatalf("case %d should return an error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if !bytes.Equal(tx, []byte(c.expected)) {
+			t.Fatalf("case %d: tx should be %q, not %q", i, c.expected, tx)
+		}
+	}
+}
+
+func TestParseRecipients(t *testing.T) {
+	priv, err := keys.GenerateECDSAKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubHex := keys.PublicKeyHex(&priv.PublicKey)
+
+	recipients, err := parseRecipients(pubHex + ", " + strings.ToLower(pubHex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != 2 || keys.PublicKeyHex(recipients[1]) != pubHex {
+		t.Fatalf("Recipients should be parsed case-insensitively")
+	}
+
+	for _, list := range []string{"0X04AB", pubHex[2:], "not hex"} {
+		if _, err := parseRecipients(list); err == nil {
+			t.Fatalf("%q should be refused", list)
+		}
+	}
+}
+
+func TestUnauthorizedTx(t *testing.T) {
+	s := &Service{
+		txAuthorizer: proxy.NewAPIKeyAuthorizer("k3y"),
+		logger:       common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/tx", strings.NewReader("tx"))
+	req.Header.Set("X-API-Key", "wrong")
+	rec := httptest.NewRecorder()
+
+	if _, ok := s.readTx(rec, req); ok || rec.Code != http.StatusForbidden {
+		t.Fatalf("status should be %d, not %d", http.StatusForbidden, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/tx", strings.NewReader("tx"))
+	req.Header.Set("X-API-Key", "k3y")
+	rec = httptest.NewRecorder()
+
+	if tx, ok := s.readTx(rec, req); !ok || string(tx) != "tx" {
+		t.Fatalf("The transaction should be authorized: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSubmitTxSync(t *testing.T) {
+	cluster := testapp.NewCluster(t, 2)
+	cluster.Run()
+	defer cluster.Shutdown()
+
+	s := &Service{
+		node:   cluster.Nodes[0],
+		logger: common.NewTestEntry(t, common.TestLogLevel),
+	}
+
+	tx := testapp.NewSetTx("key", "value")
+
+	req := httptest.NewRequest(http.MethodPost, "/tx/sync?timeout=10s", bytes.NewReader(tx))
+	rec := httptest.NewRecorder()
+
+	s.SubmitTxSync(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status should be %d, not %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp TxResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Block == nil || resp.Index == nil {
+		t.Fatalf("response should contain the location of the transaction: %+v", resp)
+	}
+
+	block, err := cluster.Nodes[0].GetBlock(*resp.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(block.Transactions()[*resp.Index], tx) {
+		t.Fatalf("block %d should contain the transaction at index %d", *resp.Block, *resp.Index)
+	}
+
+	// The location is also available by hash, without the 0X prefix and in
+	// lower case
+	req = httptest.NewRequest(http.MethodGet, "/tx/"+strings.ToLower(resp.Hash[2:]), nil)
+	rec = httptest.NewRecorder()
+
+	s.GetTx(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status should be %d, not %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var txResp TxResponse
+	if err := json.NewDecoder(rec.Body).Decode(&txResp); err != nil {
+		t.Fatal(err)
+	}
+
+	if *txResp.Block != *resp.Block || *txResp.Index != *resp.Index {
+		t.Fatalf("tx should be located at (%d, %d), not (%d, %d)",
+			*resp.Block, *resp.Index, *txResp.Block, *txResp.Index)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tx/0XABCD", nil)
+	rec = httptest.NewRecorder()
+
+	s.GetTx(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status should be %d, not %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestParseTxTTL(t *testing.T) {
+	ttl, err := parseTxTTL(url.Values{"ttl": {"30s"}, "ttl_rounds": {"5"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl.Duration != 30*time.Second || ttl.Rounds != 5 {
+		t.Fatalf("The TTL should be 30s and 5 rounds, not %v and %d", ttl.Duration, ttl.Rounds)
+	}
+
+	for _, q := range []url.Values{
+		{"ttl": {"-1s"}},
+		{"ttl": {"soon"}},
+		{"ttl_rounds": {"0"}},
+		{"ttl_rounds": {"many"}},
+	} {
+		if _, err := parseTxTTL(q); err == nil {
+			t.Fatalf("%v should be refused", q)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mosaicnetworks/babble/src/node"
+)
+
+const (
+	// wsBufferSize is the number of notifications buffered for each WebSocket
+	// subscriber. Subscribers that fall further behind are disconnected.
+	wsBufferSize = 1000
+
+	// wsWriteTimeout is the time allowed to write a message to the peer.
+	wsWriteTimeout = 10 * time.Second
+
+	// wsPingPeriod is the frequency of the pings that keep the connection
+	// alive. It must be shorter than wsPongTimeout.
+	wsPingPeriod = 30 * time.Second
+
+	// wsPongTimeout is the time allowed to receive the next pong.
+	wsPongTimeout = 60 * time.Second
+)
+
+// Subscribe upgrades the connection to a WebSocket and streams the node's
+// notifications as JSON messages. The subscribe parameter is a comma-separated
+// list of notification types: block, tx, peers, state, error, and alert. All
+// types are streamed if it is omitted.
+//
+//  GET /ws?subscribe={types}
+//  example: /ws?subscribe=block,state
+//  returns: stream of JSON node.Notification
+func (s *Service) Subscribe(w http.ResponseWriter, r *http.Request) {
+	types, err := parseNotificationTypes(r.URL.Query().Get("subscribe"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		// Same policy as the CORS headers of the other endpoints
+		CheckOrigin: func(r *http.Request) bool {
+			return s.allowOrigin(r.Header.Get("Origin"))
+		},
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an HTTP error
+		s.logger.WithError(err).Debug("Upgrading to WebSocket")
+		return
+	}
+	defer conn.Close()
+
+	sub := s.node.Subscribe(wsBufferSize, types...)
+	defer sub.Unsubscribe()
+
+	s.logger.WithField("remote", r.RemoteAddr).Debug("WebSocket subscribed")
+
+	// The client is not expected to send anything but control frames, which
+	// are processed by ReadMessage. Reading also detects closed connections.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		})
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case note, ok := <-sub.C():
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscription closed"))
+				return
+			}
+
+			if err := conn.WriteJSON(note); err != nil {
+				s.logger.WithError(err).Debug("Writing to WebSocket")
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			s.logger.WithField("remote", r.RemoteAddr).Debug("WebSocket closed")
+			return
+		}
+	}
+}
+
+// parseNotificationTypes parses a comma-separated list of notification types.
+func parseNotificationTypes(param string) ([]node.NotificationType, error) {
+	var types []node.NotificationType
+
+	if param == "" {
+		return types, nil
+	}
+
+	for _, t := range strings.Split(param, ",") {
+		switch nt := node.NotificationType(strings.TrimSpace(t)); nt {
+		case node.BlockNotification,
+			node.TransactionNotification,
+			node.PeerSetNotification,
+			node.StateNotification,
+			node.ErrorNotification,
+			node.AlertNotification,
+			node.ExpiredNotification:
+			types = append(types, nt)
+		default:
+			return nil, fmt.Errorf("unknown notification type %q", t)
+		}
+	}
+
+	return types, nil
+}
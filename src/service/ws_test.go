@@ -0,0 +1,29 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mosaicnetworks/babble/src/node"
+)
+
+func TestParseNotificationTypes(t *testing.T) {
+	types, err := parseNotificationTypes("")
+	if err != nil || len(types) != 0 {
+		t.Fatalf("empty parameter should select all types, got %v, %v", types, err)
+	}
+
+	types, err = parseNotificationTypes("block, state, error")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []node.NotificationType{node.BlockNotification, node.StateNotification, node.ErrorNotification}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatalf("types should be %v, not %v", expected, types)
+	}
+
+	if _, err := parseNotificationTypes("block,unknown"); err == nil {
+		t.Fatal("unknown type should return an error")
+	}
+}
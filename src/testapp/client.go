@@ -0,0 +1,39 @@
+package testapp
+
+import (
+	"github.com/mosaicnetworks/babble/src/proxy/inmem"
+	"github.com/sirupsen/logrus"
+)
+
+// Client is an in-memory KVStore application. It embeds an InmemProxy so it
+// automatically implements the AppProxy interface, and can be passed in the
+// Babble constructor directly.
+type Client struct {
+	*inmem.InmemProxy
+	state *KVStore
+}
+
+// NewClient creates a Client with an empty KVStore.
+func NewClient(logger *logrus.Entry) *Client {
+	state := NewKVStore(logger)
+
+	return &Client{
+		InmemProxy: inmem.NewInmemProxy(state, logger),
+		state:      state,
+	}
+}
+
+// Set submits a transaction that sets key to value.
+func (c *Client) Set(key, value string) {
+	c.SubmitTx(NewSetTx(key, value))
+}
+
+// Delete submits a transaction that deletes key.
+func (c *Client) Delete(key string) {
+	c.SubmitTx(NewDeleteTx(key))
+}
+
+// State returns the underlying KVStore.
+func (c *Client) State() *KVStore {
+	return c.state
+}
@@ -0,0 +1,132 @@
+package testapp
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/config"
+	"github.com/mosaicnetworks/babble/src/crypto/keys"
+	hg "github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/net"
+	"github.com/mosaicnetworks/babble/src/node"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+// Cluster is a set of Babble nodes running the KVStore application, connected
+// by in-memory transports and backed by in-memory stores.
+type Cluster struct {
+	Nodes   []*node.Node
+	Clients []*Client
+	Peers   *peers.PeerSet
+	Clock   common.Clock
+}
+
+// NewCluster creates and initialises a Cluster of n nodes. The nodes are not
+// running until Run is called.
+func NewCluster(t testing.TB, n int) *Cluster {
+	return NewClusterWithClock(t, n, common.RealClock{})
+}
+
+// NewClusterWithClock creates a Cluster whose nodes and transports measure
+// time with the given clock. With a common.FakeClock, the heartbeats and the
+// timeouts only expire when the clock is advanced, so a simulation controls the
+// pace of the cluster.
+func NewClusterWithClock(t testing.TB, n int, clock common.Clock) *Cluster {
+	keyList := []*ecdsa.PrivateKey{}
+	peerList := []*peers.Peer{}
+	transports := []*net.InmemTransport{}
+
+	for i := 0; i < n; i++ {
+		key, err := keys.GenerateECDSAKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr, trans := net.NewInmemTransport("")
+		trans.SetClock(clock)
+
+		keyList = append(keyList, key)
+		transports = append(transports, trans)
+		peerList = append(peerList, peers.NewPeer(
+			keys.PublicKeyHex(&key.PublicKey),
+			addr,
+			fmt.Sprintf("node%d", i),
+		))
+	}
+
+	// Connect every transport to every other transport
+	for i, trans := range transports {
+		for j, other := range transports {
+			if i != j {
+				trans.Connect(peerList[j].NetAddr, other)
+			}
+		}
+	}
+
+	peerSet := peers.NewPeerSet(peerList)
+
+	cluster := &Cluster{
+		Peers: peerSet,
+		Clock: clock,
+	}
+
+	for i := 0; i < n; i++ {
+		conf := config.NewTestConfig(t, common.TestLogLevel)
+		conf.HeartbeatTimeout = 10 * time.Millisecond
+		conf.Clock = clock
+
+		client := NewClient(common.NewTestEntry(t, common.TestLogLevel))
+
+		nd := node.NewNode(conf,
+			node.NewValidator(keyList[i], peerList[i].Moniker),
+			peerSet,
+			peerSet,
+			hg.NewInmemStore(conf.CacheSize),
+			transports[i],
+			client,
+		)
+
+		if err := nd.Init(); err != nil {
+			t.Fatalf("Failed to initialise node %d: %v", i, err)
+		}
+
+		cluster.Nodes = append(cluster.Nodes, nd)
+		cluster.Clients = append(cluster.Clients, client)
+	}
+
+	return cluster
+}
+
+// Run runs all the nodes of the cluster asynchronously.
+func (c *Cluster) Run() {
+	for _, n := range c.Nodes {
+		n.RunAsync(true)
+	}
+}
+
+// Shutdown shuts down all the nodes of the cluster.
+func (c *Cluster) Shutdown() {
+	for _, n := range c.Nodes {
+		n.Shutdown()
+	}
+}
+
+// States returns the KVStores of all the nodes in the cluster.
+func (c *Cluster) States() []StateHasher {
+	states := make([]StateHasher, len(c.Clients))
+	for i, cl := range c.Clients {
+		states[i] = cl.State()
+	}
+	return states
+}
+
+// AssertConvergence fails the test unless all the nodes of the cluster reach
+// block target within timeout, with identical StateHashes.
+func (c *Cluster) AssertConvergence(t testing.TB, target int, timeout time.Duration) {
+	t.Helper()
+
+	AssertConvergence(t, target, timeout, c.States()...)
+}
@@ -0,0 +1,94 @@
+package testapp
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// StateHasher is implemented by applications that record the StateHash they
+// computed for every committed block. KVStore implements it, and so can any
+// application that wants to use the convergence helpers.
+type StateHasher interface {
+	StateHashes() map[int][]byte
+}
+
+// CheckConvergence verifies that all the states computed identical StateHashes
+// for every block index they have in common. An error is returned if any two
+// states diverge, or if the states have no block in common.
+func CheckConvergence(states ...StateHasher) error {
+	if len(states) < 2 {
+		return nil
+	}
+
+	hashes := make([]map[int][]byte, len(states))
+	for i, s := range states {
+		hashes[i] = s.StateHashes()
+	}
+
+	common := 0
+	for index, reference := range hashes[0] {
+		shared := true
+
+		for i := 1; i < len(hashes); i++ {
+			other, ok := hashes[i][index]
+			if !ok {
+				shared = false
+				continue
+			}
+
+			if !bytes.Equal(reference, other) {
+				return fmt.Errorf("StateHash divergence at block %d: state 0 has %X, state %d has %X",
+					index, reference, i, other)
+			}
+		}
+
+		if shared {
+			common++
+		}
+	}
+
+	if common == 0 {
+		return fmt.Errorf("States have no block in common")
+	}
+
+	return nil
+}
+
+// WaitConvergence waits until every state has recorded a StateHash for block
+// target, and checks their convergence with CheckConvergence. It returns an
+// error if target is not reached within timeout.
+func WaitConvergence(target int, timeout time.Duration, states ...StateHasher) error {
+	deadline := time.After(timeout)
+
+	for {
+		done := true
+		for _, s := range states {
+			if _, ok := s.StateHashes()[target]; !ok {
+				done = false
+				break
+			}
+		}
+
+		if done {
+			return CheckConvergence(states...)
+		}
+
+		select {
+		case <-deadline:
+			return fmt.Errorf("Timeout waiting for all states to reach block %d", target)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// AssertConvergence is a test helper that calls WaitConvergence and fails the
+// test if it returns an error.
+func AssertConvergence(t testing.TB, target int, timeout time.Duration, states ...StateHasher) {
+	t.Helper()
+
+	if err := WaitConvergence(target, timeout, states...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,10 @@
+// Package testapp implements a reference deterministic key-value application,
+// and helpers to verify that the nodes of a test cluster converge to identical
+// application states.
+//
+// The KVStore state machine supports snapshots and restores, so it can be used
+// to exercise fast-sync. Its StateHash only depends on the content of the store,
+// which makes it a good baseline against which to validate other proxy
+// integrations: if a cluster of KVStores converges but a cluster of custom
+// applications does not, the culprit is the application, not Babble.
+package testapp
@@ -0,0 +1,270 @@
+package testapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mosaicnetworks/babble/src/crypto"
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/node/state"
+	"github.com/mosaicnetworks/babble/src/proxy"
+	"github.com/sirupsen/logrus"
+)
+
+// Operations supported by the KVStore.
+const (
+	OpSet    = "set"
+	OpDelete = "delete"
+)
+
+// Command is the JSON-encoded content of a KVStore transaction.
+type Command struct {
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// NewSetTx returns a transaction that sets key to value.
+func NewSetTx(key, value string) []byte {
+	tx, _ := json.Marshal(Command{Op: OpSet, Key: key, Value: value})
+	return tx
+}
+
+// NewDeleteTx returns a transaction that deletes key.
+func NewDeleteTx(key string) []byte {
+	tx, _ := json.Marshal(Command{Op: OpDelete, Key: key})
+	return tx
+}
+
+// snapshot is the serialized form of the KVStore at a given block. The JSON
+// encoder sorts map keys, so snapshots are deterministic.
+type snapshot struct {
+	Block   int               `json:"block"`
+	Entries map[string]string `json:"entries"`
+}
+
+// KVStore is a deterministic key-value state machine. It implements the
+// ProxyHandler interface for use with an InmemProxy.
+//
+// Transactions that cannot be decoded, or that contain an unknown operation,
+// are ignored; since every node ignores the same transactions, this does not
+// affect determinism. The StateHash is computed from the sorted content of the
+// store, so two stores with the same entries always have the same StateHash,
+// regardless of the history that led to them.
+type KVStore struct {
+	sync.RWMutex
+
+	entries     map[string]string
+	stateHash   []byte
+	stateHashes map[int][]byte
+	snapshots   map[int][]byte
+	invalidTxs  int
+	babbleState state.State
+
+	logger *logrus.Entry
+}
+
+// NewKVStore creates an empty KVStore.
+func NewKVStore(logger *logrus.Entry) *KVStore {
+	return &KVStore{
+		entries:     make(map[string]string),
+		stateHash:   hashEntries(map[string]string{}),
+		stateHashes: make(map[int][]byte),
+		snapshots:   make(map[int][]byte),
+		logger:      logger,
+	}
+}
+
+// hashEntries computes the StateHash corresponding to a set of entries.
+func hashEntries(entries map[string]string) []byte {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	hash := []byte{}
+	for _, k := range keys {
+		kv := append(append([]byte(k), 0), []byte(entries[k])...)
+		hash = crypto.SimpleHashFromTwoHashes(hash, crypto.SHA256(kv))
+	}
+
+	return hash
+}
+
+// apply executes a single transaction.
+func (s *KVStore) apply(tx []byte) {
+	var cmd Command
+	if err := json.Unmarshal(tx, &cmd); err != nil {
+		s.invalidTxs++
+		return
+	}
+
+	switch cmd.Op {
+	case OpSet:
+		s.entries[cmd.Key] = cmd.Value
+	case OpDelete:
+		delete(s.entries, cmd.Key)
+	default:
+		s.invalidTxs++
+	}
+}
+
+// CommitHandler implements the ProxyHandler interface. It applies the block's
+// transactions sequentially, records the resulting StateHash and snapshot, and
+// accepts all the internal transactions.
+func (s *KVStore) CommitHandler(block hashgraph.Block) (proxy.CommitResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, tx := range block.Transactions() {
+		s.apply(tx)
+	}
+
+	s.stateHash = hashEntries(s.entries)
+	s.stateHashes[block.Index()] = s.stateHash
+
+	snap, err := json.Marshal(snapshot{Block: block.Index(), Entries: s.entries})
+	if err != nil {
+		return proxy.CommitResponse{}, err
+	}
+	s.snapshots[block.Index()] = snap
+
+	s.logger.WithFields(logrus.Fields{
+		"block":      block.Index(),
+		"txs":        len(block.Transactions()),
+		"entries":    len(s.entries),
+		"state_hash": fmt.Sprintf("%X", s.stateHash),
+	}).Debug("KVStore.CommitHandler")
+
+	receipts := []hashgraph.InternalTransactionReceipt{}
+	for _, it := range block.InternalTransactions() {
+		receipts = append(receipts, it.AsAccepted())
+	}
+
+	return proxy.CommitResponse{
+		StateHash:                   s.stateHash,
+		InternalTransactionReceipts: receipts,
+	}, nil
+}
+
+// SnapshotHandler implements the ProxyHandler interface. It returns the
+// snapshot recorded after committing block blockIndex.
+func (s *KVStore) SnapshotHandler(blockIndex int) ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	snap, ok := s.snapshots[blockIndex]
+	if !ok {
+		return nil, fmt.Errorf("Snapshot %d not found", blockIndex)
+	}
+
+	return snap, nil
+}
+
+// RestoreHandler implements the ProxyHandler interface. It replaces the content
+// of the store with the snapshot's, and returns the resulting StateHash.
+func (s *KVStore) RestoreHandler(snap []byte) ([]byte, error) {
+	var sn snapshot
+	if err := json.Unmarshal(snap, &sn); err != nil {
+		return nil, err
+	}
+
+	if sn.Entries == nil {
+		sn.Entries = make(map[string]string)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.entries = sn.Entries
+	s.stateHash = hashEntries(s.entries)
+	s.stateHashes[sn.Block] = s.stateHash
+	s.snapshots[sn.Block] = snap
+
+	return s.stateHash, nil
+}
+
+// StateChangeHandler implements the ProxyHandler interface.
+func (s *KVStore) StateChangeHandler(state state.State) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.babbleState = state
+
+	return nil
+}
+
+// Get returns the value associated to a key.
+func (s *KVStore) Get(key string) (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	v, ok := s.entries[key]
+	return v, ok
+}
+
+// Len returns the number of entries in the store.
+func (s *KVStore) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return len(s.entries)
+}
+
+// StateHash returns the current StateHash.
+func (s *KVStore) StateHash() []byte {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.stateHash
+}
+
+// StateHashes returns a copy of the StateHashes recorded per block index.
+// Blocks preceding a restore are not included.
+func (s *KVStore) StateHashes() map[int][]byte {
+	s.RLock()
+	defer s.RUnlock()
+
+	res := make(map[int][]byte, len(s.stateHashes))
+	for k, v := range s.stateHashes {
+		res[k] = v
+	}
+
+	return res
+}
+
+// LastBlockIndex returns the index of the last block committed or restored,
+// or -1 if there is none.
+func (s *KVStore) LastBlockIndex() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	last := -1
+	for k := range s.stateHashes {
+		if k > last {
+			last = k
+		}
+	}
+
+	return last
+}
+
+// InvalidTransactions returns the number of transactions that were ignored
+// because they could not be decoded.
+func (s *KVStore) InvalidTransactions() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.invalidTxs
+}
+
+// BabbleState returns the last state notified by Babble.
+func (s *KVStore) BabbleState() state.State {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.babbleState
+}
@@ -0,0 +1,190 @@
+package testapp
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mosaicnetworks/babble/src/common"
+	"github.com/mosaicnetworks/babble/src/hashgraph"
+	"github.com/mosaicnetworks/babble/src/peers"
+)
+
+func newTestBlock(index int, txs ...[]byte) hashgraph.Block {
+	return *hashgraph.NewBlock(index, index+1, []byte{}, []*peers.Peer{}, txs, []hashgraph.InternalTransaction{})
+}
+
+func TestKVStoreCommit(t *testing.T) {
+	s := NewKVStore(common.NewTestEntry(t, common.TestLogLevel))
+
+	_, err := s.CommitHandler(newTestBlock(0,
+		NewSetTx("a", "1"),
+		NewSetTx("b", "2"),
+		[]byte("garbage"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.CommitHandler(newTestBlock(1,
+		NewDeleteTx("a"),
+		NewSetTx("c", "3"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Get("a"); ok {
+		t.Fatalf("a should have been deleted")
+	}
+
+	if v, _ := s.Get("c"); v != "3" {
+		t.Fatalf("c should be 3, not %s", v)
+	}
+
+	if s.InvalidTransactions() != 1 {
+		t.Fatalf("There should be 1 invalid transaction, not %d", s.InvalidTransactions())
+	}
+
+	if !bytes.Equal(resp.StateHash, s.StateHash()) {
+		t.Fatalf("CommitResponse StateHash should match the store's")
+	}
+
+	// The StateHash only depends on the entries, not on the history.
+	other := NewKVStore(common.NewTestEntry(t, common.TestLogLevel))
+	if _, err := other.CommitHandler(newTestBlock(0, NewSetTx("c", "3"), NewSetTx("b", "2"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(other.StateHash(), s.StateHash()) {
+		t.Fatalf("StateHashes should only depend on the entries")
+	}
+}
+
+func TestKVStoreSnapshotRestore(t *testing.T) {
+	s := NewKVStore(common.NewTestEntry(t, common.TestLogLevel))
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.CommitHandler(newTestBlock(i, NewSetTx(fmt.Sprintf("k%d", i), "v"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := s.SnapshotHandler(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.SnapshotHandler(10); err == nil {
+		t.Fatalf("SnapshotHandler should return an error for unknown blocks")
+	}
+
+	restored := NewKVStore(common.NewTestEntry(t, common.TestLogLevel))
+	stateHash, err := restored.RestoreHandler(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(stateHash, s.StateHashes()[1]) {
+		t.Fatalf("Restored StateHash should match block 1")
+	}
+
+	if restored.Len() != 2 || restored.LastBlockIndex() != 1 {
+		t.Fatalf("Restored store should have 2 entries and last block 1")
+	}
+
+	// Both stores must converge after applying the same block
+	if _, err := restored.CommitHandler(newTestBlock(2, NewSetTx("k2", "v"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckConvergence(s, restored); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckConvergenceDivergence(t *testing.T) {
+	a := NewKVStore(common.NewTestEntry(t, common.TestLogLevel))
+	b := NewKVStore(common.NewTestEntry(t, common.TestLogLevel))
+
+	a.CommitHandler(newTestBlock(0, NewSetTx("a", "1")))
+	b.CommitHandler(newTestBlock(0, NewSetTx("a", "2")))
+
+	if err := CheckConvergence(a, b); err == nil {
+		t.Fatalf("CheckConvergence should detect the divergence")
+	}
+
+	if err := WaitConvergence(1, 50*time.Millisecond, a, b); err == nil {
+		t.Fatalf("WaitConvergence should timeout")
+	}
+}
+
+func TestClusterConvergence(t *testing.T) {
+	cluster := NewCluster(t, 4)
+	defer cluster.Shutdown()
+
+	cluster.Run()
+
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			cluster.Clients[i%len(cluster.Clients)].Set(fmt.Sprintf("key%d", i%10), fmt.Sprintf("%d", i))
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	cluster.AssertConvergence(t, 5, 30*time.Second)
+}
+
+func TestClusterFakeClock(t *testing.T) {
+	clock := common.NewFakeClock(time.Unix(0, 0))
+
+	cluster := NewClusterWithClock(t, 4, clock)
+
+	// Drive the cluster by advancing the clock one heartbeat at a time. The
+	// clock keeps moving until the nodes are shut down, so that the RPCs in
+	// flight time out.
+	stopClock := make(chan struct{})
+	defer close(stopClock)
+
+	go func() {
+		for {
+			select {
+			case <-stopClock:
+				return
+			default:
+			}
+			clock.Advance(10 * time.Millisecond)
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	defer cluster.Shutdown()
+
+	cluster.Run()
+
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			cluster.Clients[i%len(cluster.Clients)].Set(fmt.Sprintf("key%d", i%10), fmt.Sprintf("%d", i))
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	cluster.AssertConvergence(t, 5, 30*time.Second)
+}
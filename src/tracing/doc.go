@@ -0,0 +1,12 @@
+// Package tracing instruments Babble with OpenTelemetry.
+//
+// Spans are created along the gossip and commit pipeline: the gossip routine,
+// outgoing and incoming RPCs, the insertion of Events in the hashgraph, and
+// the commitment of Blocks to the application. This makes it possible to
+// follow a slow commit from the reception of a SyncResponse or
+// EagerSyncRequest, all the way to the application's acknowledgement.
+//
+// Until a TracerProvider is installed, with NewTracerProvider or directly with
+// the OpenTelemetry API, the global TracerProvider is a no-op and tracing has
+// virtually no cost.
+package tracing
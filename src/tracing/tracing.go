@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies the spans produced by Babble.
+const InstrumentationName = "github.com/mosaicnetworks/babble"
+
+// Tracer returns the Tracer used to instrument Babble. It is obtained from the
+// global TracerProvider every time, so that installing a new TracerProvider
+// takes effect immediately.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Start creates a span and a context containing it. It is a shortcut for
+// Tracer().Start.
+func Start(ctx context.Context, name string, opts ...trace.SpanOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err on the span, if it is not nil, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// NewTracerProvider creates a TracerProvider that exports spans in batches to
+// the OTLP collector at endpoint, and installs it as the global TracerProvider.
+// serviceName is attached to all the spans as the service.name resource
+// attribute. The caller should call Shutdown on the returned TracerProvider to
+// flush the pending spans before exiting.
+func NewTracerProvider(endpoint string,
+	insecure bool,
+	sampleRatio float64,
+	serviceName string) (*sdktrace.TracerProvider, error) {
+
+	opts := []otlpgrpc.Option{otlpgrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlpgrpc.WithInsecure())
+	}
+
+	exporter, err := otlp.NewExporter(context.Background(), otlpgrpc.NewDriver(opts...))
+	if err != nil {
+		return nil, fmt.Errorf("Creating OTLP exporter: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.ServiceNameKey.String(serviceName))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider, nil
+}
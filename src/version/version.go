@@ -1,6 +1,8 @@
 // Package version manages the version string associated with a Babble build.
 package version
 
+import "fmt"
+
 // Flag contains extra info about the version. It is helpful for tracking
 // versions while developing. It should always be empty on the master branch,
 // and this rule is inforced in a continuous integration test.
@@ -21,3 +23,56 @@ func init() {
 		Version += "-" + GitCommit[:8]
 	}
 }
+
+const (
+	// ProtocolVersion is the version of the protocol spoken between nodes: the
+	// RPCs, the wire format of Events, and the consensus rules. It must be
+	// incremented with every change that prevents a node from working with
+	// nodes of the previous version.
+	ProtocolVersion = 1
+
+	// MinProtocolVersion is the oldest version of the protocol that this build
+	// still speaks. Nodes are compatible if their ranges of protocol versions
+	// overlap.
+	MinProtocolVersion = 1
+)
+
+// Protocol is the range of protocol versions spoken by a node, along with the
+// version of Babble that it runs. It is attached to every RPC so that nodes
+// running incompatible versions refuse each other with a descriptive error,
+// instead of failing to decode each other's messages.
+type Protocol struct {
+	Version    int
+	MinVersion int
+	Babble     string
+}
+
+// LocalProtocol returns the Protocol of this build.
+func LocalProtocol() Protocol {
+	return Protocol{
+		Version:    ProtocolVersion,
+		MinVersion: MinProtocolVersion,
+		Babble:     Version,
+	}
+}
+
+// Compatible returns an error if a peer with the given Protocol cannot work
+// with this build. The Protocol of nodes that predate version negotiation is
+// empty; they speak the first version of the protocol.
+func Compatible(peer Protocol) error {
+	if peer.Version == 0 {
+		peer.Version = 1
+		peer.Babble = "unknown"
+	}
+
+	if peer.MinVersion == 0 {
+		peer.MinVersion = peer.Version
+	}
+
+	if peer.MinVersion <= ProtocolVersion && MinProtocolVersion <= peer.Version {
+		return nil
+	}
+
+	return fmt.Errorf("Incompatible protocol: peer runs Babble %s with protocol versions %d to %d, this node runs Babble %s with protocol versions %d to %d",
+		peer.Babble, peer.MinVersion, peer.Version, Version, MinProtocolVersion, ProtocolVersion)
+}
@@ -12,3 +12,26 @@ func TestFlagEmpty(t *testing.T) {
 		t.Fatalf("Version Flag is not empty: %s", Flag)
 	}
 }
+
+func TestCompatible(t *testing.T) {
+	cases := []struct {
+		peer       Protocol
+		compatible bool
+	}{
+		{peer: LocalProtocol(), compatible: true},
+		// Nodes that predate version negotiation speak the first version
+		{peer: Protocol{}, compatible: MinProtocolVersion <= 1},
+		{peer: Protocol{Version: ProtocolVersion + 1, MinVersion: ProtocolVersion}, compatible: true},
+		{peer: Protocol{Version: ProtocolVersion + 2, MinVersion: ProtocolVersion + 1}, compatible: false},
+	}
+
+	for i, c := range cases {
+		err := Compatible(c.peer)
+		if c.compatible && err != nil {
+			t.Fatalf("Case %d: %+v should be compatible: %v", i, c.peer, err)
+		}
+		if !c.compatible && err == nil {
+			t.Fatalf("Case %d: %+v should not be compatible", i, c.peer)
+		}
+	}
+}